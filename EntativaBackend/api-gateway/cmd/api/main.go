@@ -106,9 +106,12 @@ func main() {
 		})
 	})
 
+	// Access tokens are verified with the user service's published keys
+	jwks := middleware.NewJWKSKeySet(getEnv("USER_SERVICE_JWKS_URL", "http://localhost:8001/.well-known/jwks.json"))
+
 	// API v1 routes (authenticated)
 	v1 := router.Group("/api/v1")
-	v1.Use(middleware.AuthMiddleware())
+	v1.Use(middleware.AuthMiddleware(jwks))
 	{
 		// User routes
 		v1.GET("/users/:id", func(c *gin.Context) {
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksCacheTTL is how long a fetched key set is trusted before it is refreshed
+	jwksCacheTTL = 10 * time.Minute
+	// jwksMinRefreshInterval limits re-fetches triggered by tokens with an unknown kid
+	jwksMinRefreshInterval = 30 * time.Second
+	// oauthAccessTokenType is the "typ" header of access tokens issued to OAuth clients
+	oauthAccessTokenType = "at+jwt"
+)
+
+// jwksValidMethods are the signing algorithms the user service issues tokens with
+var jwksValidMethods = []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+
+// JWKSKeySet verifies access tokens against the user service's published signing keys
+type JWKSKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// NewJWKSKeySet creates a key set backed by the JWKS at url (fetched lazily)
+func NewJWKSKeySet(url string) *JWKSKeySet {
+	return &JWKSKeySet{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+// Keyfunc selects the verification key for a token by its kid header
+func (s *JWKSKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	key, err := s.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm must match the key type, never trust alg on its own
+	switch key.(type) {
+	case ed25519.PublicKey:
+		if token.Method.Alg() != jwt.SigningMethodEdDSA.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+	case *rsa.PublicKey:
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+	default:
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return key, nil
+}
+
+// Parse parses and verifies a session access token. Access and ID tokens the
+// user service issues to OAuth clients are signed by the same keys, so they are
+// told apart by their "typ" header and missing user_id claim.
+func (s *JWKSKeySet) Parse(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, s.Keyfunc, jwt.WithValidMethods(jwksValidMethods))
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := token.Header["typ"].(string); typ == oauthAccessTokenType {
+		return nil, errors.New("oauth access tokens are not accepted here")
+	}
+	if claims, ok := token.Claims.(jwt.MapClaims); !ok || claims["user_id"] == nil || claims["user_id"] == "" {
+		return nil, errors.New("token has no user_id claim")
+	}
+
+	return token, nil
+}
+
+func (s *JWKSKeySet) lookup(kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	fresh := time.Since(s.fetchedAt) < jwksCacheTTL
+	s.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	// Unknown kid (new key after rotation) or stale set: refresh
+	if err := s.refresh(); err != nil && !ok {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+func (s *JWKSKeySet) refresh() error {
+	s.mu.Lock()
+	if time.Since(s.lastAttempt) < jwksMinRefreshInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (s *JWKSKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
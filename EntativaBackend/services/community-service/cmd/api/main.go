@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/entativa/socialink/community-service/internal/handler"
 	"github.com/entativa/socialink/community-service/internal/repository"
 	"github.com/entativa/socialink/community-service/internal/service"
+	"github.com/entativa/socialink/community-service/pkg/kafka"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,9 +42,24 @@ func main() {
 	// Initialize repositories
 	communityRepo := repository.NewCommunityRepository(db)
 	memberRepo := repository.NewMemberRepository(db)
+	purgeRepo := repository.NewUserPurgeRepository(db)
+
+	// Memberships are reported to the user service for friend suggestions
+	affiliationClient := service.NewAffiliationClient(os.Getenv("USER_SERVICE_URL"), os.Getenv("USER_SERVICE_INTERNAL_TOKEN"))
 
 	// Initialize services
-	communityService := service.NewCommunityService(communityRepo, memberRepo)
+	communityService := service.NewCommunityService(communityRepo, memberRepo, affiliationClient)
+
+	// Take part in account deletion: purge users the user service asks about
+	purgeService := service.NewUserPurgeService(purgeRepo, os.Getenv("USER_SERVICE_URL"), os.Getenv("USER_SERVICE_INTERNAL_TOKEN"))
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		userEvents := kafka.NewConsumer(strings.Split(brokers, ","), "community-service", "user-events")
+		defer userEvents.Close()
+		go userEvents.Run(context.Background(), purgeService.HandleUserEvent)
+		log.Println("✅ Listening for account purges")
+	} else {
+		log.Println("KAFKA_BROKERS not set; account purges will not be confirmed")
+	}
 
 	// Initialize handlers
 	communityHandler := handler.NewCommunityHandler(communityService)
@@ -0,0 +1,24 @@
+package model
+
+import "github.com/google/uuid"
+
+// UserDeletionRequestedEvent is published by the user service on "user-events" when
+// a deleted account's grace period is over. Services named in Services purge the
+// user's data and report back; the event is repeated until they do.
+type UserDeletionRequestedEvent struct {
+	EventType  string    `json:"event_type"`
+	DeletionID uuid.UUID `json:"deletion_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Services   []string  `json:"services"`
+}
+
+// UserPurgeResult counts what was removed when purging a deleted user's data
+type UserPurgeResult struct {
+	Memberships        int64 `json:"memberships"`
+	JoinRequests       int64 `json:"join_requests"`
+	Invites            int64 `json:"invites"`
+	Bans               int64 `json:"bans"`
+	Reports            int64 `json:"reports"`
+	TransferredOwners  int64 `json:"transferred_owners"`
+	DeletedCommunities int64 `json:"deleted_communities"`
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/entativa/socialink/community-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// UserPurgeRepository removes a deleted user from every community. Purging is
+// idempotent: running it again for the same user removes nothing more.
+type UserPurgeRepository struct {
+	db *sql.DB
+}
+
+func NewUserPurgeRepository(db *sql.DB) *UserPurgeRepository {
+	return &UserPurgeRepository{db: db}
+}
+
+// PurgeUser hands the user's communities to their longest-standing admin, then
+// moderator, then member, deleting communities nobody else is in, and removes the
+// user's memberships, join requests, invites, bans and reports, in one transaction.
+// Moderation actions taken by or against the user stay as the communities' audit log.
+func (r *UserPurgeRepository) PurgeUser(ctx context.Context, userID uuid.UUID) (*model.UserPurgeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &model.UserPurgeResult{}
+
+	owned, err := ownedCommunities(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, communityID := range owned {
+		transferred, err := transferOwnership(ctx, tx, communityID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if transferred {
+			result.TransferredOwners++
+			continue
+		}
+
+		// Nobody left to run it: the community goes, with everything in it (ON DELETE CASCADE)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM communities WHERE id = $1`, communityID); err != nil {
+			return nil, fmt.Errorf("failed to delete community %s: %w", communityID, err)
+		}
+		result.DeletedCommunities++
+	}
+
+	deletes := []struct {
+		query string
+		count *int64
+		what  string
+	}{
+		// member_count follows through member_count_trigger
+		{`DELETE FROM community_members WHERE user_id = $1`, &result.Memberships, "memberships"},
+		{`DELETE FROM join_requests WHERE user_id = $1`, &result.JoinRequests, "join requests"},
+		{`DELETE FROM member_invites WHERE invited_user_id = $1 OR invited_by = $1`, &result.Invites, "invites"},
+		{`DELETE FROM banned_members WHERE user_id = $1`, &result.Bans, "bans"},
+		{`DELETE FROM reported_content WHERE reporter_id = $1`, &result.Reports, "reports"},
+	}
+	for _, d := range deletes {
+		res, err := tx.ExecContext(ctx, d.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", d.what, err)
+		}
+		*d.count, _ = res.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return result, nil
+}
+
+// ownedCommunities locks and returns the communities the user created
+func ownedCommunities(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM communities WHERE creator_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owned communities: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// transferOwnership makes the longest-standing active admin, moderator or member,
+// in that order, the owner of a community. It reports false if there is nobody.
+func transferOwnership(ctx context.Context, tx *sql.Tx, communityID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT user_id FROM community_members
+		WHERE community_id = $1 AND user_id <> $2 AND status = 'active'
+		ORDER BY CASE role
+			WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'moderator' THEN 2 ELSE 3
+		END, joined_at ASC
+		LIMIT 1
+	`
+
+	var successor uuid.UUID
+	err := tx.QueryRowContext(ctx, query, communityID, userID).Scan(&successor)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to find a new owner for %s: %w", communityID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE community_members SET role = $1, permissions = $2, updated_at = NOW() WHERE community_id = $3 AND user_id = $4`,
+		model.RoleOwner, model.GetDefaultPermissions(model.RoleOwner), communityID, successor,
+	); err != nil {
+		return false, fmt.Errorf("failed to promote new owner of %s: %w", communityID, err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE communities SET creator_id = $1 WHERE id = $2`, successor, communityID); err != nil {
+		return false, fmt.Errorf("failed to transfer %s: %w", communityID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// affiliationKind is how the user service knows a community membership
+const affiliationKind = "community"
+
+// AffiliationClient tells the user service who belongs to which community, so
+// members can be suggested to each other as friends. With no user service URL
+// configured it does nothing.
+type AffiliationClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewAffiliationClient(userServiceURL, internalToken string) *AffiliationClient {
+	return &AffiliationClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Joined reports that a user became a member of a community
+func (c *AffiliationClient) Joined(communityID, userID uuid.UUID) {
+	c.send(http.MethodPut, communityID, userID)
+}
+
+// Left reports that a user is no longer a member of a community
+func (c *AffiliationClient) Left(communityID, userID uuid.UUID) {
+	c.send(http.MethodDelete, communityID, userID)
+}
+
+// send reports in the background; a lost report only makes suggestions less accurate
+func (c *AffiliationClient) send(method string, communityID, userID uuid.UUID) {
+	if c == nil || c.userServiceURL == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/affiliations/%s/%s", c.userServiceURL, userID, affiliationKind, communityID)
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			log.Printf("Failed to report membership of %s in %s: %v", userID, communityID, err)
+			return
+		}
+		req.Header.Set("X-Internal-Token", c.internalToken)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			log.Printf("Failed to report membership of %s in %s: %v", userID, communityID, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Failed to report membership of %s in %s: user service returned %d", userID, communityID, resp.StatusCode)
+		}
+	}()
+}
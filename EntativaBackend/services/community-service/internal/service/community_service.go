@@ -13,15 +13,18 @@ import (
 type CommunityService struct {
 	communityRepo *repository.CommunityRepository
 	memberRepo    *repository.MemberRepository
+	affiliations  *AffiliationClient
 }
 
 func NewCommunityService(
 	communityRepo *repository.CommunityRepository,
 	memberRepo    *repository.MemberRepository,
+	affiliations  *AffiliationClient,
 ) *CommunityService {
 	return &CommunityService{
 		communityRepo: communityRepo,
 		memberRepo:    memberRepo,
+		affiliations:  affiliations,
 	}
 }
 
@@ -40,6 +43,7 @@ func (s *CommunityService) CreateCommunity(ctx context.Context, req *model.Creat
 	if err != nil {
 		return nil, err
 	}
+	s.affiliations.Joined(community.ID, creatorID)
 
 	return community, nil
 }
@@ -131,7 +135,11 @@ func (s *CommunityService) JoinCommunity(ctx context.Context, communityID, userI
 	if community.Privacy == model.PrivacyPublic {
 		// Public: Auto-join
 		_, err = s.memberRepo.AddMember(ctx, communityID, userID, model.RoleMember, nil)
-		return err
+		if err != nil {
+			return err
+		}
+		s.affiliations.Joined(communityID, userID)
+		return nil
 	} else {
 		// Private/Hidden: Create join request
 		_, err = s.memberRepo.CreateJoinRequest(ctx, communityID, userID, "")
@@ -152,7 +160,11 @@ func (s *CommunityService) LeaveCommunity(ctx context.Context, communityID, user
 
 	// Remove member
 	err = s.memberRepo.RemoveMember(ctx, communityID, userID)
-	return err
+	if err != nil {
+		return err
+	}
+	s.affiliations.Left(communityID, userID)
+	return nil
 }
 
 func (s *CommunityService) InviteMember(ctx context.Context, communityID, inviterID, invitedUserID uuid.UUID, message *string) error {
@@ -205,7 +217,11 @@ func (s *CommunityService) RemoveMember(ctx context.Context, communityID, remove
 
 	// Remove member
 	err = s.memberRepo.RemoveMember(ctx, communityID, targetUserID)
-	return err
+	if err != nil {
+		return err
+	}
+	s.affiliations.Left(communityID, targetUserID)
+	return nil
 }
 
 // ============================================
@@ -296,7 +312,11 @@ func (s *CommunityService) BanMember(ctx context.Context, communityID, moderator
 
 	// Ban member
 	err = s.memberRepo.BanMember(ctx, communityID, targetUserID, moderatorID, reason, isPermanent, expiresAt)
-	return err
+	if err != nil {
+		return err
+	}
+	s.affiliations.Left(communityID, targetUserID)
+	return nil
 }
 
 func (s *CommunityService) UnbanMember(ctx context.Context, communityID, moderatorID, targetUserID uuid.UUID) error {
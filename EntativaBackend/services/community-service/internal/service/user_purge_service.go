@@ -0,0 +1,102 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/entativa/socialink/community-service/internal/model"
+	"github.com/entativa/socialink/community-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	userDeletionRequestedEvent = "user.deletion_requested"
+
+	// purgeServiceName is how the user service knows this service in a purge
+	purgeServiceName = "community"
+)
+
+// UserPurgeService takes part in account deletion: when the user service asks,
+// it removes the deleted user from every community and reports back. The user
+// service repeats the request until it hears that the purge succeeded.
+type UserPurgeService struct {
+	purgeRepo      *repository.UserPurgeRepository
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewUserPurgeService(purgeRepo *repository.UserPurgeRepository, userServiceURL, internalToken string) *UserPurgeService {
+	return &UserPurgeService{
+		purgeRepo:      purgeRepo,
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HandleUserEvent purges a user on a user.deletion_requested event naming this
+// service. Other user events are ignored.
+func (s *UserPurgeService) HandleUserEvent(ctx context.Context, key, value []byte) error {
+	var event model.UserDeletionRequestedEvent
+	if err := json.Unmarshal(value, &event); err != nil || event.EventType != userDeletionRequestedEvent {
+		return nil
+	}
+	if !containsString(event.Services, purgeServiceName) {
+		return nil
+	}
+
+	result, purgeErr := s.purgeRepo.PurgeUser(ctx, event.UserID)
+	if purgeErr == nil {
+		log.Printf("Purged user %s: %d memberships, %d join requests, %d invites, %d bans, %d reports, %d communities handed over, %d deleted",
+			event.UserID, result.Memberships, result.JoinRequests, result.Invites, result.Bans, result.Reports,
+			result.TransferredOwners, result.DeletedCommunities)
+	}
+
+	if err := s.reportPurge(ctx, event.DeletionID, purgeErr); err != nil {
+		return fmt.Errorf("failed to report purge of user %s: %w", event.UserID, err)
+	}
+	return purgeErr
+}
+
+// reportPurge tells the user service whether the purge succeeded
+func (s *UserPurgeService) reportPurge(ctx context.Context, deletionID uuid.UUID, purgeErr error) error {
+	report := map[string]string{"status": "completed"}
+	if purgeErr != nil {
+		report = map[string]string{"status": "failed", "error": purgeErr.Error()}
+	}
+	body, _ := json.Marshal(report)
+
+	endpoint := fmt.Sprintf("%s/api/v1/internal/deletions/%s/services/%s", s.userServiceURL, deletionID, purgeServiceName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", s.internalToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -44,7 +44,10 @@ func main() {
 	var mockKafka *service.KafkaProducer
 	var mockRedis *service.RedisClient
 
-	eventService := service.NewEventService(eventRepo, rsvpRepo, mockKafka, mockRedis)
+	// RSVPs are reported to the user service for friend suggestions
+	affiliationClient := service.NewAffiliationClient(os.Getenv("USER_SERVICE_URL"), os.Getenv("USER_SERVICE_INTERNAL_TOKEN"))
+
+	eventService := service.NewEventService(eventRepo, rsvpRepo, mockKafka, mockRedis, affiliationClient)
 
 	// Initialize handlers
 	eventHandler := handler.NewEventHandler(eventService)
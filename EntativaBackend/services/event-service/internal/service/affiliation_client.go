@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// affiliationKind is how the user service knows an event RSVP
+const affiliationKind = "event"
+
+// AffiliationClient tells the user service who is going to which event, so
+// attendees can be suggested to each other as friends. With no user service URL
+// configured it does nothing.
+type AffiliationClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewAffiliationClient(userServiceURL, internalToken string) *AffiliationClient {
+	return &AffiliationClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Attending reports that a user is going to, or interested in, an event
+func (c *AffiliationClient) Attending(eventID, userID uuid.UUID) {
+	c.send(http.MethodPut, eventID, userID)
+}
+
+// NotAttending reports that a user is no longer going to an event
+func (c *AffiliationClient) NotAttending(eventID, userID uuid.UUID) {
+	c.send(http.MethodDelete, eventID, userID)
+}
+
+// send reports in the background; a lost report only makes suggestions less accurate
+func (c *AffiliationClient) send(method string, eventID, userID uuid.UUID) {
+	if c == nil || c.userServiceURL == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/affiliations/%s/%s", c.userServiceURL, userID, affiliationKind, eventID)
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			log.Printf("Failed to report RSVP of %s to %s: %v", userID, eventID, err)
+			return
+		}
+		req.Header.Set("X-Internal-Token", c.internalToken)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			log.Printf("Failed to report RSVP of %s to %s: %v", userID, eventID, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Failed to report RSVP of %s to %s: user service returned %d", userID, eventID, resp.StatusCode)
+		}
+	}()
+}
@@ -11,10 +11,11 @@ import (
 )
 
 type EventService struct {
-	eventRepo *repository.EventRepository
-	rsvpRepo  *repository.RSVPRepository
-	kafka     *KafkaProducer
-	redis     *RedisClient
+	eventRepo    *repository.EventRepository
+	rsvpRepo     *repository.RSVPRepository
+	kafka        *KafkaProducer
+	redis        *RedisClient
+	affiliations *AffiliationClient
 }
 
 func NewEventService(
@@ -22,12 +23,14 @@ func NewEventService(
 	rsvpRepo *repository.RSVPRepository,
 	kafka *KafkaProducer,
 	redis *RedisClient,
+	affiliations *AffiliationClient,
 ) *EventService {
 	return &EventService{
-		eventRepo: eventRepo,
-		rsvpRepo:  rsvpRepo,
-		kafka:     kafka,
-		redis:     redis,
+		eventRepo:    eventRepo,
+		rsvpRepo:     rsvpRepo,
+		kafka:        kafka,
+		redis:        redis,
+		affiliations: affiliations,
 	}
 }
 
@@ -250,12 +253,23 @@ func (s *EventService) RSVP(ctx context.Context, eventID, userID uuid.UUID, req
 	// Publish RSVP event
 	s.kafka.PublishEventRSVP(eventID, userID, string(req.Status))
 
+	// Report attendance for friend suggestions
+	if req.Status == model.RSVPNotGoing {
+		s.affiliations.NotAttending(eventID, userID)
+	} else {
+		s.affiliations.Attending(eventID, userID)
+	}
+
 	return nil
 }
 
 // RemoveRSVP removes user's RSVP
 func (s *EventService) RemoveRSVP(ctx context.Context, eventID, userID uuid.UUID) error {
-	return s.rsvpRepo.Delete(ctx, eventID, userID)
+	if err := s.rsvpRepo.Delete(ctx, eventID, userID); err != nil {
+		return err
+	}
+	s.affiliations.NotAttending(eventID, userID)
+	return nil
 }
 
 // CheckIn user to event
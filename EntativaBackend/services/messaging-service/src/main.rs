@@ -48,6 +48,25 @@ async fn main() -> std::io::Result<()> {
     
     tracing::info!("✅ Redis connected");
     
+    // Take part in account deletion: purge users the user service asks about
+    match std::env::var("KAFKA_BROKERS") {
+        Ok(brokers) if !brokers.is_empty() => {
+            let purge_service = services::user_purge_service::UserPurgeService::new(
+                pool.clone(),
+                redis_client.clone(),
+                std::env::var("USER_SERVICE_URL").unwrap_or_else(|_| "http://localhost:8001".to_string()),
+                std::env::var("USER_SERVICE_INTERNAL_TOKEN").unwrap_or_default(),
+            );
+            tokio::spawn(async move {
+                if let Err(e) = purge_service.run(&brokers, "messaging-service").await {
+                    tracing::error!("Account purge consumer stopped: {}", e);
+                }
+            });
+            tracing::info!("✅ Listening for account purges");
+        }
+        _ => tracing::warn!("KAFKA_BROKERS not set; account purges will not be confirmed"),
+    }
+    
     // Initialize WebSocket server
     let ws_server = Arc::new(websocket::ws_server::WsServer::new(redis_client.clone()));
     
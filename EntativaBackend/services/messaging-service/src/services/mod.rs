@@ -4,3 +4,4 @@ pub mod group_service;
 pub mod presence_service;
 pub mod typing_service;
 pub mod call_service;
+pub mod user_purge_service;
@@ -0,0 +1,281 @@
+use anyhow::{anyhow, Result};
+use rdkafka::config::ClientConfig;
+use rdkafka::consumer::{CommitMode, Consumer, StreamConsumer};
+use rdkafka::Message as KafkaMessage;
+use redis::AsyncCommands;
+use serde::{Serialize, Deserialize};
+use sqlx::PgPool;
+use std::time::Duration;
+use uuid::Uuid;
+
+const USER_EVENTS_TOPIC: &str = "user-events";
+const USER_DELETION_REQUESTED_EVENT: &str = "user.deletion_requested";
+
+/// How the user service knows this service in a purge
+const PURGE_SERVICE_NAME: &str = "messaging";
+
+/// User Purge Service
+/// Takes part in account deletion: when the user service asks, it removes the
+/// deleted user's devices, keys, messages, memberships and backups, then reports
+/// back. The user service repeats the request until it hears that the purge
+/// succeeded.
+pub struct UserPurgeService {
+    db: PgPool,
+    redis: redis::Client,
+    http: reqwest::Client,
+    user_service_url: String,
+    internal_token: String,
+}
+
+/// Published by the user service on "user-events" when a deleted account's
+/// grace period is over. Other user events share the topic, so every field
+/// but the type is optional here.
+#[derive(Debug, Deserialize)]
+pub struct UserDeletionRequestedEvent {
+    pub event_type: String,
+    #[serde(default)]
+    pub deletion_id: String,
+    #[serde(default)]
+    pub user_id: String,
+    #[serde(default)]
+    pub services: Vec<String>,
+}
+
+/// Counts what was removed when purging a deleted user's data
+#[derive(Debug, Default, Serialize)]
+pub struct UserPurgeResult {
+    pub messages: u64,
+    pub devices: u64,
+    pub conversations: u64,
+    pub groups: u64,
+    pub backups: u64,
+    pub cache_keys: i64,
+}
+
+impl UserPurgeService {
+    pub fn new(db: PgPool, redis: redis::Client, user_service_url: String, internal_token: String) -> Self {
+        let http = reqwest::Client::builder()
+            .timeout(Duration::from_secs(10))
+            .build()
+            .expect("Failed to build HTTP client");
+
+        Self {
+            db,
+            redis,
+            http,
+            user_service_url: user_service_url.trim_end_matches('/').to_string(),
+            internal_token,
+        }
+    }
+
+    /// Consume user events until the consumer fails to start.
+    /// Messages are committed even when handling fails: the user service
+    /// redelivers purges that were not confirmed.
+    pub async fn run(&self, brokers: &str, group_id: &str) -> Result<()> {
+        let consumer: StreamConsumer = ClientConfig::new()
+            .set("bootstrap.servers", brokers)
+            .set("group.id", group_id)
+            .set("enable.auto.commit", "false")
+            .set("auto.offset.reset", "earliest")
+            .create()?;
+
+        consumer.subscribe(&[USER_EVENTS_TOPIC])?;
+
+        loop {
+            let msg = match consumer.recv().await {
+                Ok(msg) => msg,
+                Err(e) => {
+                    tracing::warn!("Failed to fetch message from {}: {}", USER_EVENTS_TOPIC, e);
+                    continue;
+                }
+            };
+
+            if let Some(payload) = msg.payload() {
+                if let Err(e) = self.handle_user_event(payload).await {
+                    tracing::error!("Failed to handle message from {} at offset {}: {}", USER_EVENTS_TOPIC, msg.offset(), e);
+                }
+            }
+
+            if let Err(e) = consumer.commit_message(&msg, CommitMode::Async) {
+                tracing::warn!("Failed to commit message from {}: {}", USER_EVENTS_TOPIC, e);
+            }
+        }
+    }
+
+    /// Purge a user on a user.deletion_requested event naming this service.
+    /// Other user events are ignored.
+    pub async fn handle_user_event(&self, payload: &[u8]) -> Result<()> {
+        let event: UserDeletionRequestedEvent = match serde_json::from_slice(payload) {
+            Ok(event) => event,
+            Err(_) => return Ok(()),
+        };
+
+        if event.event_type != USER_DELETION_REQUESTED_EVENT
+            || !event.services.iter().any(|s| s == PURGE_SERVICE_NAME)
+        {
+            return Ok(());
+        }
+
+        let purge = match Uuid::parse_str(&event.user_id) {
+            Ok(user_id) => self.purge_user(user_id).await,
+            Err(e) => Err(anyhow!("invalid user id {}: {}", event.user_id, e)),
+        };
+
+        if let Ok(result) = &purge {
+            tracing::info!(
+                "Purged user {}: {} messages, {} devices, {} conversations, {} groups, {} backups, {} cache keys",
+                event.user_id,
+                result.messages,
+                result.devices,
+                result.conversations,
+                result.groups,
+                result.backups,
+                result.cache_keys
+            );
+        }
+
+        self.report_purge(&event.deletion_id, purge.as_ref().err())
+            .await
+            .map_err(|e| anyhow!("failed to report purge of user {}: {}", event.user_id, e))?;
+
+        purge.map(|_| ())
+    }
+
+    /// Remove everything stored for the user. Conversations and groups other
+    /// people share stay; only the user's membership in them goes. Every step
+    /// is idempotent, so a purge that failed halfway is simply run again.
+    async fn purge_user(&self, user_id: Uuid) -> Result<UserPurgeResult> {
+        let mut result = UserPurgeResult::default();
+
+        // Devices name the user's offline queues in Redis
+        let device_ids = sqlx::query_scalar!(
+            "SELECT device_id FROM devices WHERE user_id = $1",
+            user_id
+        )
+        .fetch_all(&self.db)
+        .await?;
+
+        let mut tx = self.db.begin().await?;
+
+        sqlx::query!(
+            r#"
+            DELETE FROM encrypted_media
+            WHERE message_id IN (
+                SELECT id FROM messages WHERE sender_id = $1 OR recipient_id = $1
+            )
+            "#,
+            user_id
+        )
+        .execute(&mut *tx)
+        .await?;
+
+        result.messages = sqlx::query!(
+            "DELETE FROM messages WHERE sender_id = $1 OR recipient_id = $1",
+            user_id
+        )
+        .execute(&mut *tx)
+        .await?
+        .rows_affected();
+
+        sqlx::query!("DELETE FROM deleted_messages WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        sqlx::query!("DELETE FROM read_receipts WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+
+        result.conversations = sqlx::query!(
+            "DELETE FROM conversation_participants WHERE user_id = $1",
+            user_id
+        )
+        .execute(&mut *tx)
+        .await?
+        .rows_affected();
+
+        result.groups = sqlx::query!("DELETE FROM group_members WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?
+            .rows_affected();
+        sqlx::query!("DELETE FROM mls_welcome_messages WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+
+        sqlx::query!("DELETE FROM call_ice_candidates WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        sqlx::query!("DELETE FROM calls WHERE caller_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+
+        sqlx::query!("DELETE FROM onetime_prekeys WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        sqlx::query!("DELETE FROM signed_prekeys WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        result.devices = sqlx::query!("DELETE FROM devices WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?
+            .rows_affected();
+        sqlx::query!("DELETE FROM user_presence WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+
+        // Backups; the backup key goes last since the rest reference it
+        sqlx::query!("DELETE FROM backup_restoration_tokens WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        sqlx::query!("DELETE FROM backup_activity_log WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        result.backups = sqlx::query!("DELETE FROM message_backups WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?
+            .rows_affected();
+        sqlx::query!("DELETE FROM backup_settings WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+        sqlx::query!("DELETE FROM backup_keys WHERE user_id = $1", user_id)
+            .execute(&mut *tx)
+            .await?;
+
+        tx.commit().await?;
+
+        // Presence and queued messages live in Redis too
+        let mut keys = vec![format!("presence:{}", user_id)];
+        for device_id in &device_ids {
+            keys.push(format!("offline_queue:{}:{}", user_id, device_id));
+        }
+
+        let mut conn = self.redis.get_async_connection().await?;
+        result.cache_keys = conn.del(keys).await?;
+
+        Ok(result)
+    }
+
+    /// Tell the user service whether the purge succeeded
+    async fn report_purge(&self, deletion_id: &str, purge_err: Option<&anyhow::Error>) -> Result<()> {
+        let report = match purge_err {
+            None => serde_json::json!({ "status": "completed" }),
+            Some(e) => serde_json::json!({ "status": "failed", "error": e.to_string() }),
+        };
+
+        let url = format!(
+            "{}/api/v1/internal/deletions/{}/services/{}",
+            self.user_service_url, deletion_id, PURGE_SERVICE_NAME
+        );
+
+        let response = self.http
+            .post(&url)
+            .header("X-Internal-Token", &self.internal_token)
+            .json(&report)
+            .send()
+            .await?;
+
+        if response.status() != reqwest::StatusCode::OK {
+            return Err(anyhow!("user service returned {}", response.status()));
+        }
+
+        Ok(())
+    }
+}
@@ -5,13 +5,13 @@ go 1.21
 require (
 	github.com/gin-gonic/gin v1.9.1
 	github.com/google/uuid v1.5.0
+	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/redis/go-redis/v9 v9.3.0
-	github.com/joho/godotenv v1.5.1
-	google.golang.org/grpc v1.60.0
-	google.golang.org/protobuf v1.31.0
 	github.com/segmentio/kafka-go v0.4.47
 	golang.org/x/time v0.5.0
+	google.golang.org/grpc v1.60.0
+	google.golang.org/protobuf v1.31.0
 )
 
 require (
@@ -26,6 +26,7 @@ require (
 	github.com/go-playground/validator/v10 v10.14.0 // indirect
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.4 // indirect
 	github.com/leodido/go-urn v1.2.4 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"socialink/post-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// GetExportPage returns a page of a user's posts, comments, likes or saves
+// for the user service's data export. Internal only: the route is guarded by
+// the service-to-service token, not a user session.
+// @Summary Export user data
+// @Tags internal
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param section path string true "posts, comments, likes or saves"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Page size (default 500, max 1000)"
+// @Success 200 {object} model.ExportPage
+// @Failure 400 {object} map[string]interface{}
+// @Router /internal/export/users/{user_id}/{section} [get]
+func (h *ExportHandler) GetExportPage(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user ID",
+			"message": "The provided user ID is not valid",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
+	page, err := h.exportService.GetExportPage(c.Request.Context(), userID, c.Param("section"), c.Query("cursor"), limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "unknown export section") || strings.Contains(err.Error(), "invalid cursor") {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to export data",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
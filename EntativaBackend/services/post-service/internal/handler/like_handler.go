@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"socialink/post-service/internal/model"
@@ -72,7 +74,12 @@ func (h *LikeHandler) LikePost(c *gin.Context) {
 	}
 
 	if err := h.likeService.LikePost(c.Request.Context(), postID, userUUID, reactionType); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to like post",
 			"message": err.Error(),
 		})
@@ -190,7 +197,12 @@ func (h *LikeHandler) LikeComment(c *gin.Context) {
 	}
 
 	if err := h.likeService.LikeComment(c.Request.Context(), commentID, userUUID, reactionType); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to like comment",
 			"message": err.Error(),
 		})
@@ -278,6 +290,12 @@ func (h *LikeHandler) GetPostLikers(c *gin.Context) {
 		return
 	}
 
+	// Get requesting user ID (optional)
+	var requestingUserID uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		requestingUserID, _ = uuid.Parse(userID.(string))
+	}
+
 	limit := 50
 	offset := 0
 	if l := c.Query("limit"); l != "" {
@@ -287,9 +305,14 @@ func (h *LikeHandler) GetPostLikers(c *gin.Context) {
 		fmt.Sscanf(o, "%d", &offset)
 	}
 
-	userIDs, err := h.likeService.GetPostLikers(c.Request.Context(), postID, limit, offset)
+	userIDs, err := h.likeService.GetPostLikers(c.Request.Context(), postID, requestingUserID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to get likers",
 			"message": err.Error(),
 		})
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"socialink/post-service/internal/model"
@@ -62,7 +64,12 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	post, err := h.postService.CreatePost(c.Request.Context(), userUUID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, model.ErrInvalidAudience) {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to create post",
 			"message": err.Error(),
 		})
@@ -174,6 +181,9 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		if err.Error() == "permission denied: not the post owner" {
 			statusCode = http.StatusForbidden
 		}
+		if errors.Is(err, model.ErrInvalidAudience) {
+			statusCode = http.StatusBadRequest
+		}
 
 		c.JSON(statusCode, gin.H{
 			"error":   "Failed to update post",
@@ -307,6 +317,7 @@ func (h *PostHandler) GetUserPosts(c *gin.Context) {
 // @Produce json
 // @Param cursor query string false "Cursor for pagination"
 // @Param limit query int false "Limit" default(20)
+// @Param explain query bool false "Include each post's ranking signals"
 // @Success 200 {object} model.PostListResponse
 // @Router /posts/feed [get]
 func (h *PostHandler) GetFeed(c *gin.Context) {
@@ -334,7 +345,16 @@ func (h *PostHandler) GetFeed(c *gin.Context) {
 		fmt.Sscanf(l, "%d", &limit)
 	}
 
-	posts, nextCursor, err := h.postService.GetFeed(c.Request.Context(), userUUID, cursor, limit)
+	explain := c.Query("explain") == "true"
+
+	posts, nextCursor, explanations, err := h.postService.GetFeed(c.Request.Context(), userUUID, cursor, limit, explain)
+	if errors.Is(err, model.ErrInvalidFeedCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid cursor",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get feed",
@@ -343,40 +363,138 @@ func (h *PostHandler) GetFeed(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success":     true,
 		"data":        posts,
 		"next_cursor": nextCursor,
 		"has_more":    nextCursor != nil,
-	})
+	}
+	if explain {
+		response["ranking"] = explanations
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// GetTrendingPosts retrieves trending posts
-// @Summary Get trending posts
-// @Description Get currently trending posts
+// GetExplorePosts retrieves the explore page
+// @Summary Get explore posts
+// @Description Get the most engaged-with recent posts, ranked
 // @Tags posts
 // @Produce json
 // @Param limit query int false "Limit" default(20)
+// @Param explain query bool false "Include each post's ranking signals"
 // @Success 200 {object} model.PostListResponse
-// @Router /posts/trending [get]
-func (h *PostHandler) GetTrendingPosts(c *gin.Context) {
+// @Router /posts/explore [get]
+func (h *PostHandler) GetExplorePosts(c *gin.Context) {
 	limit := 20
 	if l := c.Query("limit"); l != "" {
 		fmt.Sscanf(l, "%d", &limit)
 	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	explain := c.Query("explain") == "true"
 
-	posts, err := h.postService.GetTrendingPosts(c.Request.Context(), limit)
+	posts, explanations, err := h.postService.GetExplorePosts(c.Request.Context(), limit, explain)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get trending posts",
+			"error":   "Failed to get explore posts",
 			"message": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"data":    posts,
 		"count":   len(posts),
+	}
+	if explain {
+		response["ranking"] = explanations
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HidePost hides a post from the user's feeds
+// @Summary Hide post
+// @Description Hide a post from your feeds; posts by its author are ranked lower for you
+// @Tags posts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /posts/{post_id}/hide [post]
+func (h *PostHandler) HidePost(c *gin.Context) {
+	h.setHidden(c, true)
+}
+
+// UnhidePost shows a hidden post again
+// @Summary Unhide post
+// @Description Show a post you hid again
+// @Tags posts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /posts/{post_id}/hide [delete]
+func (h *PostHandler) UnhidePost(c *gin.Context) {
+	h.setHidden(c, false)
+}
+
+func (h *PostHandler) setHidden(c *gin.Context, hidden bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid user ID",
+			"message": "The user ID is not valid",
+		})
+		return
+	}
+
+	postID, err := uuid.Parse(c.Param("post_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid post ID",
+			"message": "The provided post ID is not valid",
+		})
+		return
+	}
+
+	if hidden {
+		err = h.postService.HidePost(c.Request.Context(), userUUID, postID)
+	} else {
+		err = h.postService.UnhidePost(c.Request.Context(), userUUID, postID)
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err.Error() {
+		case "post not found":
+			statusCode = http.StatusNotFound
+		case "cannot hide your own post":
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to update hidden posts",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "Post hidden"
+	if !hidden {
+		message = "Post unhidden"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
 	})
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -67,7 +68,12 @@ func (h *SaveHandler) SavePost(c *gin.Context) {
 	c.ShouldBindJSON(&req)
 
 	if err := h.postService.SavePost(c.Request.Context(), userUUID, postID, req.Collection); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to save post",
 			"message": err.Error(),
 		})
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -108,6 +109,12 @@ func (h *ShareHandler) GetPostShares(c *gin.Context) {
 		return
 	}
 
+	// Get requesting user ID (optional)
+	var requestingUserID uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		requestingUserID, _ = uuid.Parse(userID.(string))
+	}
+
 	limit := 20
 	offset := 0
 	if l := c.Query("limit"); l != "" {
@@ -117,9 +124,14 @@ func (h *ShareHandler) GetPostShares(c *gin.Context) {
 		fmt.Sscanf(o, "%d", &offset)
 	}
 
-	shares, err := h.shareService.GetPostShares(c.Request.Context(), postID, limit, offset)
+	shares, err := h.shareService.GetPostShares(c.Request.Context(), postID, requestingUserID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to get shares",
 			"message": err.Error(),
 		})
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Statuses of a post or Take. Only published ones are shown to anyone but the author.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+
+	// StatusFailed is a scheduled post or Take that could not be published
+	StatusFailed = "failed"
+)
+
+// How far ahead a post or Take can be scheduled
+const (
+	MinScheduleLead = 5 * time.Minute
+	MaxScheduleLead = 75 * 24 * time.Hour
+)
+
+var (
+	// ErrDraftNotFound is returned for drafts that don't exist or aren't the user's
+	ErrDraftNotFound = errors.New("draft not found")
+
+	// ErrInvalidSchedule is returned for a time too soon or too far ahead to schedule for
+	ErrInvalidSchedule = errors.New("scheduled_at must be between 5 minutes and 75 days from now")
+)
+
+// ValidateSchedule checks a time a post or Take is being scheduled for
+func ValidateSchedule(scheduledAt, now time.Time) error {
+	if scheduledAt.Before(now.Add(MinScheduleLead)) || scheduledAt.After(now.Add(MaxScheduleLead)) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// CreateDraftRequest creates a draft post, scheduled if ScheduledAt is set
+type CreateDraftRequest struct {
+	CreatePostRequest
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+}
+
+// CreateTakeDraftRequest creates a draft Take, scheduled if ScheduledAt is set
+type CreateTakeDraftRequest struct {
+	CreateTakeRequest
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+}
+
+// ScheduleRequest schedules a draft, or moves a scheduled one to another time
+type ScheduleRequest struct {
+	ScheduledAt time.Time `json:"scheduled_at" binding:"required"`
+}
+
+// UpdateTakeDraftRequest edits a draft Take
+type UpdateTakeDraftRequest struct {
+	Caption         *string   `json:"caption,omitempty" binding:"omitempty,max=2200"`
+	Hashtags        *[]string `json:"hashtags,omitempty"`
+	Location        *Location `json:"location,omitempty"`
+	TrendKeyword    *string   `json:"trend_keyword,omitempty"`
+	CommentsEnabled *bool     `json:"comments_enabled,omitempty"`
+	RemixEnabled    *bool     `json:"remix_enabled,omitempty"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// ExportPage is one page of a user's posts, comments, likes or saves,
+// returned to the user service when it builds a data export
+type ExportPage struct {
+	Section    string      `json:"section"`
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// Sections of a user's data the post service exports
+const (
+	ExportSectionPosts    = "posts"
+	ExportSectionComments = "comments"
+	ExportSectionLikes    = "likes"
+	ExportSectionSaves    = "saves"
+)
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"socialink/post-service/pkg/textdiff"
+
+	"github.com/google/uuid"
+)
+
+// ErrHistoryNotFound is returned for the edit history of a post or comment that doesn't exist
+var ErrHistoryNotFound = errors.New("post or comment not found")
+
+// Revision is one version of the text of a post or comment
+type Revision struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	// ReplacedAt is when an edit replaced this version; nil for the current text
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+	// Diff is what changed from the version before, when asked for
+	Diff []textdiff.Change `json:"diff,omitempty"`
+}
+
+// EditHistory is every version of the text of a post or comment, oldest first
+// and ending with the current one
+type EditHistory struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// DeletedAt is set for posts and comments the author deleted, which only
+	// moderation sees
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Revisions []Revision `json:"revisions"`
+}
@@ -44,6 +44,13 @@ type Take struct {
 	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
 	DeletedAt       *time.Time     `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	// Drafts and scheduling, as for posts. TrendKeyword is the trend a draft
+	// joins when it is published.
+	Status       string     `json:"status" db:"status"`
+	ScheduledAt  *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"`
+	PublishError *string    `json:"publish_error,omitempty" db:"publish_error"`
+	TrendKeyword *string    `json:"trend_keyword,omitempty" db:"trend_keyword"`
 }
 
 // BehindTheTakes represents the behind-the-scenes content for a Take
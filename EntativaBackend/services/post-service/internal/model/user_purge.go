@@ -0,0 +1,24 @@
+package model
+
+import "github.com/google/uuid"
+
+// UserDeletionRequestedEvent is published by the user service on "user-events" when
+// a deleted account's grace period is over. Services named in Services purge the
+// user's data and report back; the event is repeated until they do.
+type UserDeletionRequestedEvent struct {
+	EventType  string    `json:"event_type"`
+	DeletionID uuid.UUID `json:"deletion_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Services   []string  `json:"services"`
+}
+
+// UserPurgeResult counts what was removed when purging a deleted user's data
+type UserPurgeResult struct {
+	PostIDs  []uuid.UUID `json:"-"`
+	Posts    int64       `json:"posts"`
+	Comments int64       `json:"comments"`
+	Likes    int64       `json:"likes"`
+	Saves    int64       `json:"saves"`
+	Shares   int64       `json:"shares"`
+	Takes    int64       `json:"takes"`
+}
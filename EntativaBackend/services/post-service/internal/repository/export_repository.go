@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"socialink/post-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// ExportRepository pages through everything a user has created for data exports.
+// Pages are keyed on (created_at, id) rather than offsets so an export that is
+// resumed later neither skips nor repeats rows when the user posts in between.
+// Soft-deleted rows are included: they are still data held about the user.
+type ExportRepository interface {
+	GetPosts(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Post, string, error)
+	GetComments(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Comment, string, error)
+	GetLikes(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Like, string, error)
+	GetSaves(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Save, string, error)
+}
+
+type exportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+func (r *exportRepository) GetPosts(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Post, string, error) {
+	query := `
+		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
+			   filter_used, is_carousel, likes_count, comments_count, views_count,
+			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
+			   comments_enabled, likes_visible, audience, created_at, updated_at, deleted_at
+		FROM posts
+		WHERE user_id = $1`
+
+	rows, err := r.queryPage(ctx, query, userID, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	posts, err := (&postRepository{db: r.db}).scanPosts(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = encodeExportCursor(posts[limit-1].CreatedAt, posts[limit-1].ID)
+	}
+
+	return posts, next, nil
+}
+
+func (r *exportRepository) GetComments(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Comment, string, error) {
+	query := `
+		SELECT id, post_id, user_id, parent_id, content, media_id, likes_count,
+			   is_edited, edited_at, created_at, updated_at, deleted_at
+		FROM comments
+		WHERE user_id = $1`
+
+	rows, err := r.queryPage(ctx, query, userID, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	comments, err := (&commentRepository{db: r.db}).scanComments(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(comments) > limit {
+		comments = comments[:limit]
+		next = encodeExportCursor(comments[limit-1].CreatedAt, comments[limit-1].ID)
+	}
+
+	return comments, next, nil
+}
+
+func (r *exportRepository) GetLikes(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Like, string, error) {
+	query := `
+		SELECT id, user_id, post_id, comment_id, created_at
+		FROM likes
+		WHERE user_id = $1`
+
+	rows, err := r.queryPage(ctx, query, userID, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var likes []model.Like
+	for rows.Next() {
+		like := model.Like{}
+		if err := rows.Scan(&like.ID, &like.UserID, &like.PostID, &like.CommentID, &like.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		likes = append(likes, like)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(likes) > limit {
+		likes = likes[:limit]
+		next = encodeExportCursor(likes[limit-1].CreatedAt, likes[limit-1].ID)
+	}
+
+	return likes, next, nil
+}
+
+func (r *exportRepository) GetSaves(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Save, string, error) {
+	query := `
+		SELECT id, user_id, post_id, collection, created_at
+		FROM saves
+		WHERE user_id = $1`
+
+	rows, err := r.queryPage(ctx, query, userID, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var saves []model.Save
+	for rows.Next() {
+		save := model.Save{}
+		if err := rows.Scan(&save.ID, &save.UserID, &save.PostID, &save.Collection, &save.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		saves = append(saves, save)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(saves) > limit {
+		saves = saves[:limit]
+		next = encodeExportCursor(saves[limit-1].CreatedAt, saves[limit-1].ID)
+	}
+
+	return saves, next, nil
+}
+
+// queryPage appends the keyset condition and ordering to a query filtered on
+// user_id = $1 and fetches one row more than limit to tell if there is a next page
+func (r *exportRepository) queryPage(ctx context.Context, query string, userID uuid.UUID, cursor string, limit int) (*sql.Rows, error) {
+	args := []interface{}{userID}
+
+	if cursor != "" {
+		createdAt, id, err := decodeExportCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) > ($2, $3)`
+		args = append(args, createdAt, id)
+	}
+
+	query += fmt.Sprintf(` ORDER BY created_at, id LIMIT $%d`, len(args)+1)
+	args = append(args, limit+1)
+
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func encodeExportCursor(createdAt time.Time, id uuid.UUID) string {
+	return createdAt.UTC().Format(time.RFC3339Nano) + "_" + id.String()
+}
+
+func decodeExportCursor(cursor string) (time.Time, uuid.UUID, error) {
+	createdAtStr, idStr, ok := strings.Cut(cursor, "_")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor")
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor")
+	}
+
+	return createdAt, id, nil
+}
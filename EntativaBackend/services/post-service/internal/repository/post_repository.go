@@ -10,6 +10,7 @@ import (
 	"socialink/post-service/internal/model"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type PostRepository interface {
@@ -18,7 +19,9 @@ type PostRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Post, error)
 	Update(ctx context.Context, post *model.Post) error
 	Delete(ctx context.Context, id uuid.UUID) error
-	GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Post, *string, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Post, error)
+	GetByAuthors(ctx context.Context, q AuthorPostsQuery) ([]model.Post, error)
+	SetFannedOut(ctx context.Context, postID uuid.UUID, fannedOut bool) error
 	IncrementLikes(ctx context.Context, postID uuid.UUID) error
 	DecrementLikes(ctx context.Context, postID uuid.UUID) error
 	IncrementComments(ctx context.Context, postID uuid.UUID) error
@@ -29,7 +32,23 @@ type PostRepository interface {
 	DecrementSaves(ctx context.Context, postID uuid.UUID) error
 	GetByHashtag(ctx context.Context, hashtag string, limit, offset int) ([]model.Post, error)
 	GetReels(ctx context.Context, limit, offset int) ([]model.Post, error)
-	GetTrendingPosts(ctx context.Context, limit int, timeWindow time.Duration) ([]model.Post, error)
+	GetExploreCandidates(ctx context.Context, limit int, timeWindow time.Duration) ([]model.Post, error)
+
+	// Drafts and scheduled posts. The read methods above only return published posts.
+	GetDraftByID(ctx context.Context, id uuid.UUID) (*model.Post, error)
+	GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Post, error)
+	SetSchedule(ctx context.Context, id uuid.UUID, status string, scheduledAt *time.Time) error
+	GetDueScheduled(ctx context.Context, limit int) ([]model.Post, error)
+	MarkPublished(ctx context.Context, post *model.Post, publishedAt time.Time) (bool, error)
+	MarkPublishFailed(ctx context.Context, post *model.Post, reason string) (bool, error)
+}
+
+// AuthorPostsQuery selects a page of posts by a set of authors
+type AuthorPostsQuery struct {
+	AuthorIDs []uuid.UUID
+	Before    *model.FeedCursor // only posts after this place in the feed; nil for the start
+	FannedOut *bool             // only posts fanned out (or not) on create; nil for all
+	Limit     int
 }
 
 type postRepository struct {
@@ -46,8 +65,9 @@ func (r *postRepository) Create(ctx context.Context, post *model.Post) error {
 			id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			filter_used, is_carousel, likes_count, comments_count, views_count,
 			saves_count, shares_count, is_edited, is_sponsored, is_reels,
-			comments_enabled, likes_visible, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+			comments_enabled, likes_visible, audience, fanned_out, status, scheduled_at,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
 		RETURNING created_at, updated_at
 	`
 
@@ -61,7 +81,8 @@ func (r *postRepository) Create(ctx context.Context, post *model.Post) error {
 		post.ID, post.UserID, post.Caption, mediaIDsJSON, locationJSON, taggedUserIDsJSON,
 		hashtagsJSON, post.FilterUsed, post.IsCarousel, post.LikesCount, post.CommentsCount,
 		post.ViewsCount, post.SavesCount, post.SharesCount, post.IsEdited, post.IsSponsored,
-		post.IsReels, post.CommentsEnabled, post.LikesVisible, post.CreatedAt, post.UpdatedAt,
+		post.IsReels, post.CommentsEnabled, post.LikesVisible, post.Audience, post.FannedOut,
+		post.Status, post.ScheduledAt, post.CreatedAt, post.UpdatedAt,
 	).Scan(&post.CreatedAt, &post.UpdatedAt)
 }
 
@@ -70,9 +91,10 @@ func (r *postRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Post
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND deleted_at IS NULL AND status = 'published'
 	`
 
 	post := &model.Post{}
@@ -83,7 +105,8 @@ func (r *postRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Post
 		&taggedUserIDsJSON, &hashtagsJSON, &post.FilterUsed, &post.IsCarousel,
 		&post.LikesCount, &post.CommentsCount, &post.ViewsCount, &post.SavesCount,
 		&post.SharesCount, &post.IsEdited, &post.EditedAt, &post.IsSponsored,
-		&post.IsReels, &post.CommentsEnabled, &post.LikesVisible,
+		&post.IsReels, &post.CommentsEnabled, &post.LikesVisible, &post.Audience,
+		&post.Status, &post.ScheduledAt, &post.PublishError,
 		&post.CreatedAt, &post.UpdatedAt, &post.DeletedAt,
 	)
 
@@ -119,9 +142,10 @@ func (r *postRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limi
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE user_id = $1 AND deleted_at IS NULL
+		WHERE user_id = $1 AND deleted_at IS NULL AND status = 'published'
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -135,21 +159,43 @@ func (r *postRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limi
 	return r.scanPosts(rows)
 }
 
+// Update saves an edit to a post. If the caption of a published post changes, the
+// caption it replaces is kept in post_revisions in the same transaction. The row
+// is locked first, so concurrent edits each keep the caption they really replaced.
 func (r *postRepository) Update(ctx context.Context, post *model.Post) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revision := `
+		INSERT INTO post_revisions (post_id, caption, replaced_at)
+		SELECT id, COALESCE(caption, ''), $3
+		FROM posts
+		WHERE id = $1 AND deleted_at IS NULL AND status = 'published'
+			AND COALESCE(caption, '') <> $2
+		FOR UPDATE
+	`
+	if _, err := tx.ExecContext(ctx, revision, post.ID, post.Caption, post.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+
 	query := `
 		UPDATE posts
 		SET caption = $1, location = $2, hashtags = $3, is_edited = $4,
-			edited_at = $5, updated_at = $6, comments_enabled = $7, likes_visible = $8
-		WHERE id = $9 AND deleted_at IS NULL
+			edited_at = $5, updated_at = $6, comments_enabled = $7, likes_visible = $8,
+			audience = $9
+		WHERE id = $10 AND deleted_at IS NULL
 	`
 
 	hashtagsJSON, _ := json.Marshal(post.Hashtags)
 	locationJSON, _ := json.Marshal(post.Location)
 
-	result, err := r.db.ExecContext(
+	result, err := tx.ExecContext(
 		ctx, query,
 		post.Caption, locationJSON, hashtagsJSON, post.IsEdited, post.EditedAt,
-		post.UpdatedAt, post.CommentsEnabled, post.LikesVisible, post.ID,
+		post.UpdatedAt, post.CommentsEnabled, post.LikesVisible, post.Audience, post.ID,
 	)
 
 	if err != nil {
@@ -165,7 +211,7 @@ func (r *postRepository) Update(ctx context.Context, post *model.Post) error {
 		return fmt.Errorf("post not found")
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *postRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -188,48 +234,76 @@ func (r *postRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (r *postRepository) GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Post, *string, error) {
+// GetByIDs returns the published posts with the given IDs that are not deleted, in no
+// particular order
+func (r *postRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Post, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
 	query := `
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE deleted_at IS NULL
+		WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL AND status = 'published'
 	`
 
-	args := []interface{}{}
-	
-	if cursor != "" {
-		query += ` AND created_at < (SELECT created_at FROM posts WHERE id = $1)`
-		var cursorID uuid.UUID
-		if err := cursorID.UnmarshalText([]byte(cursor)); err == nil {
-			args = append(args, cursorID)
-		}
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(idStrings(ids)))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	query += ` ORDER BY created_at DESC LIMIT $` + fmt.Sprintf("%d", len(args)+1)
-	args = append(args, limit+1)
+	return r.scanPosts(rows)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, nil, err
+// GetByAuthors pages through published posts by a set of authors, newest first. Posts
+// created at the same moment come in descending ID order, so pages never overlap.
+func (r *postRepository) GetByAuthors(ctx context.Context, q AuthorPostsQuery) ([]model.Post, error) {
+	if len(q.AuthorIDs) == 0 {
+		return nil, nil
 	}
-	defer rows.Close()
 
-	posts, err := r.scanPosts(rows)
-	if err != nil {
-		return nil, nil, err
+	query := `
+		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
+			   filter_used, is_carousel, likes_count, comments_count, views_count,
+			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
+		FROM posts
+		WHERE user_id = ANY($1::uuid[]) AND deleted_at IS NULL AND status = 'published'
+	`
+	args := []interface{}{pq.Array(idStrings(q.AuthorIDs))}
+
+	if q.Before != nil {
+		args = append(args, q.Before.CreatedAt, q.Before.PostID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
 	}
+	if q.FannedOut != nil {
+		args = append(args, *q.FannedOut)
+		query += fmt.Sprintf(` AND fanned_out = $%d`, len(args))
+	}
+
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
 
-	var nextCursor *string
-	if len(posts) > limit {
-		posts = posts[:limit]
-		cursorStr := posts[limit-1].ID.String()
-		nextCursor = &cursorStr
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return posts, nextCursor, nil
+	return r.scanPosts(rows)
+}
+
+// SetFannedOut records whether a post is in its author's friends' home timelines
+func (r *postRepository) SetFannedOut(ctx context.Context, postID uuid.UUID, fannedOut bool) error {
+	query := `UPDATE posts SET fanned_out = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, fannedOut, postID)
+	return err
 }
 
 func (r *postRepository) IncrementLikes(ctx context.Context, postID uuid.UUID) error {
@@ -280,14 +354,16 @@ func (r *postRepository) DecrementSaves(ctx context.Context, postID uuid.UUID) e
 	return err
 }
 
+// GetByHashtag returns public posts tagged with hashtag, newest first
 func (r *postRepository) GetByHashtag(ctx context.Context, hashtag string, limit, offset int) ([]model.Post, error) {
 	query := `
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND status = 'published' AND audience = 'public'
 		AND hashtags ? $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
@@ -302,14 +378,16 @@ func (r *postRepository) GetByHashtag(ctx context.Context, hashtag string, limit
 	return r.scanPosts(rows)
 }
 
+// GetReels returns public reels, newest first
 func (r *postRepository) GetReels(ctx context.Context, limit, offset int) ([]model.Post, error) {
 	query := `
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE deleted_at IS NULL AND is_reels = TRUE
+		WHERE deleted_at IS NULL AND status = 'published' AND is_reels = TRUE AND audience = 'public'
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
@@ -323,23 +401,20 @@ func (r *postRepository) GetReels(ctx context.Context, limit, offset int) ([]mod
 	return r.scanPosts(rows)
 }
 
-func (r *postRepository) GetTrendingPosts(ctx context.Context, limit int, timeWindow time.Duration) ([]model.Post, error) {
-	// Facebook explore algorithm: weighted engagement
+// GetExploreCandidates returns the public posts from the last timeWindow with the
+// most interactions, for the explore ranking to order. Interactions are simply counted
+// here; what each kind is worth is up to the ranking.
+func (r *postRepository) GetExploreCandidates(ctx context.Context, limit int, timeWindow time.Duration) ([]model.Post, error) {
 	query := `
 		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
 			   filter_used, is_carousel, likes_count, comments_count, views_count,
 			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
-			   comments_enabled, likes_visible, created_at, updated_at, deleted_at
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
 		FROM posts
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND status = 'published' AND audience = 'public'
 		AND created_at > $1
-		ORDER BY (
-			likes_count + 
-			views_count / 10 + 
-			saves_count * 2 + 
-			shares_count * 3 + 
-			comments_count * 2
-		) DESC
+		ORDER BY likes_count + comments_count + saves_count + shares_count DESC, created_at DESC
 		LIMIT $2
 	`
 
@@ -353,6 +428,157 @@ func (r *postRepository) GetTrendingPosts(ctx context.Context, limit int, timeWi
 	return r.scanPosts(rows)
 }
 
+// GetDraftByID returns a draft, scheduled or failed post
+func (r *postRepository) GetDraftByID(ctx context.Context, id uuid.UUID) (*model.Post, error) {
+	query := `
+		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
+			   filter_used, is_carousel, likes_count, comments_count, views_count,
+			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
+		FROM posts
+		WHERE id = $1 AND deleted_at IS NULL AND status <> 'published'
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts, err := r.scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, model.ErrDraftNotFound
+	}
+	return &posts[0], nil
+}
+
+// GetDrafts returns the user's unpublished posts, soonest scheduled first and then
+// the most recently edited drafts
+func (r *postRepository) GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Post, error) {
+	query := `
+		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
+			   filter_used, is_carousel, likes_count, comments_count, views_count,
+			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
+		FROM posts
+		WHERE user_id = $1 AND deleted_at IS NULL AND status <> 'published'
+		ORDER BY scheduled_at ASC NULLS LAST, updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanPosts(rows)
+}
+
+// SetSchedule moves an unpublished post to status, due at scheduledAt (nil for a
+// draft), clearing any earlier publish error
+func (r *postRepository) SetSchedule(ctx context.Context, id uuid.UUID, status string, scheduledAt *time.Time) error {
+	query := `
+		UPDATE posts
+		SET status = $1, scheduled_at = $2, publish_error = NULL, updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL AND status <> 'published'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, status, scheduledAt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return model.ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// GetDueScheduled returns scheduled posts whose time has come, longest overdue first
+func (r *postRepository) GetDueScheduled(ctx context.Context, limit int) ([]model.Post, error) {
+	query := `
+		SELECT id, user_id, caption, media_ids, location, tagged_user_ids, hashtags,
+			   filter_used, is_carousel, likes_count, comments_count, views_count,
+			   saves_count, shares_count, is_edited, edited_at, is_sponsored, is_reels,
+			   comments_enabled, likes_visible, audience, status, scheduled_at, publish_error,
+			   created_at, updated_at, deleted_at
+		FROM posts
+		WHERE deleted_at IS NULL AND status = 'scheduled' AND scheduled_at <= NOW()
+		ORDER BY scheduled_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanPosts(rows)
+}
+
+// MarkPublished publishes post as of publishedAt, with its FannedOut as decided by
+// the caller. It only does so if the post still has the status and scheduled time
+// it was read with: the update locks the row and checks again, so of several
+// callers publishing the same post only one gets true, and a post rescheduled or
+// cancelled in the meantime is left alone.
+func (r *postRepository) MarkPublished(ctx context.Context, post *model.Post, publishedAt time.Time) (bool, error) {
+	query := `
+		UPDATE posts
+		SET status = 'published', publish_error = NULL, fanned_out = $1,
+			created_at = $2, updated_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+		AND status = $4 AND scheduled_at IS NOT DISTINCT FROM $5
+	`
+
+	result, err := r.db.ExecContext(ctx, query, post.FannedOut, publishedAt, post.ID, post.Status, post.ScheduledAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// MarkPublishFailed records why a scheduled post could not be published, on the
+// same terms as MarkPublished
+func (r *postRepository) MarkPublishFailed(ctx context.Context, post *model.Post, reason string) (bool, error) {
+	query := `
+		UPDATE posts
+		SET status = 'failed', publish_error = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+		AND status = 'scheduled' AND scheduled_at IS NOT DISTINCT FROM $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, reason, post.ID, post.ScheduledAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
 func (r *postRepository) scanPosts(rows *sql.Rows) ([]model.Post, error) {
 	var posts []model.Post
 
@@ -365,7 +591,8 @@ func (r *postRepository) scanPosts(rows *sql.Rows) ([]model.Post, error) {
 			&taggedUserIDsJSON, &hashtagsJSON, &post.FilterUsed, &post.IsCarousel,
 			&post.LikesCount, &post.CommentsCount, &post.ViewsCount, &post.SavesCount,
 			&post.SharesCount, &post.IsEdited, &post.EditedAt, &post.IsSponsored,
-			&post.IsReels, &post.CommentsEnabled, &post.LikesVisible,
+			&post.IsReels, &post.CommentsEnabled, &post.LikesVisible, &post.Audience,
+			&post.Status, &post.ScheduledAt, &post.PublishError,
 			&post.CreatedAt, &post.UpdatedAt, &post.DeletedAt,
 		)
 
@@ -395,3 +622,11 @@ func (r *postRepository) scanPosts(rows *sql.Rows) ([]model.Post, error) {
 
 	return posts, rows.Err()
 }
+
+func idStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"socialink/post-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// RevisionRepository reads the edit history of posts and comments. Revisions are
+// written by PostRepository.Update and CommentRepository.Update, with the edit.
+type RevisionRepository interface {
+	// GetPostHistory returns every version of a published post's caption, whether
+	// or not the author has deleted it
+	GetPostHistory(ctx context.Context, postID uuid.UUID) (*model.EditHistory, error)
+	// GetCommentHistory returns every version of a comment, whether or not the
+	// author has deleted it
+	GetCommentHistory(ctx context.Context, commentID uuid.UUID) (*model.EditHistory, error)
+}
+
+type revisionRepository struct {
+	db *sql.DB
+}
+
+func NewRevisionRepository(db *sql.DB) RevisionRepository {
+	return &revisionRepository{db: db}
+}
+
+func (r *revisionRepository) GetPostHistory(ctx context.Context, postID uuid.UUID) (*model.EditHistory, error) {
+	current := `
+		SELECT id, user_id, COALESCE(caption, ''), created_at, deleted_at
+		FROM posts
+		WHERE id = $1 AND status = 'published'
+	`
+	earlier := `
+		SELECT caption, replaced_at
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY replaced_at ASC, id ASC
+	`
+
+	return r.getHistory(ctx, current, earlier, postID)
+}
+
+func (r *revisionRepository) GetCommentHistory(ctx context.Context, commentID uuid.UUID) (*model.EditHistory, error) {
+	current := `
+		SELECT id, user_id, content, created_at, deleted_at
+		FROM comments
+		WHERE id = $1
+	`
+	earlier := `
+		SELECT content, replaced_at
+		FROM comment_revisions
+		WHERE comment_id = $1
+		ORDER BY replaced_at ASC, id ASC
+	`
+
+	return r.getHistory(ctx, current, earlier, commentID)
+}
+
+// getHistory reads the current text with the current query and the replaced ones
+// with the earlier query. Each version was written when the one before it was
+// replaced, and the first when the post or comment was created.
+func (r *revisionRepository) getHistory(ctx context.Context, current, earlier string, id uuid.UUID) (*model.EditHistory, error) {
+	history := &model.EditHistory{}
+	var text string
+	var createdAt time.Time
+	err := r.db.QueryRowContext(ctx, current, id).Scan(
+		&history.ID, &history.UserID, &text, &createdAt, &history.DeletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrHistoryNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, earlier, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	writtenAt := createdAt
+	for rows.Next() {
+		revision := model.Revision{CreatedAt: writtenAt}
+		var replacedAt time.Time
+		if err := rows.Scan(&revision.Text, &replacedAt); err != nil {
+			return nil, err
+		}
+		revision.ReplacedAt = &replacedAt
+		history.Revisions = append(history.Revisions, revision)
+		writtenAt = replacedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	history.Revisions = append(history.Revisions, model.Revision{Text: text, CreatedAt: writtenAt})
+	return history, nil
+}
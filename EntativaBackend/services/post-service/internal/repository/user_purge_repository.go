@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"socialink/post-service/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// UserPurgeRepository removes everything a deleted user left in the post service.
+// Purging is idempotent: running it again for the same user removes nothing more.
+type UserPurgeRepository interface {
+	PurgeUser(ctx context.Context, userID uuid.UUID) (*model.UserPurgeResult, error)
+}
+
+type userPurgeRepository struct {
+	db *sql.DB
+}
+
+func NewUserPurgeRepository(db *sql.DB) UserPurgeRepository {
+	return &userPurgeRepository{db: db}
+}
+
+// PurgeUser hard deletes the user's posts, comments, likes, saves, shares, hides and takes
+// in one transaction, then recounts the likes, comments, saves and shares of other
+// people's posts and comments the user had interacted with
+func (r *userPurgeRepository) PurgeUser(ctx context.Context, userID uuid.UUID) (*model.UserPurgeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &model.UserPurgeResult{}
+	touchedPosts := make(map[uuid.UUID]bool)
+	touchedComments := make(map[uuid.UUID]bool)
+
+	likedPosts, likedComments, err := r.deleteLikes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Likes = int64(len(likedPosts) + len(likedComments))
+	markTouched(touchedPosts, likedPosts)
+	markTouched(touchedComments, likedComments)
+
+	savedPosts, err := deleteReturningIDs(ctx, tx, `DELETE FROM saves WHERE user_id = $1 RETURNING post_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete saves: %w", err)
+	}
+	result.Saves = int64(len(savedPosts))
+	markTouched(touchedPosts, savedPosts)
+
+	sharedPosts, err := deleteReturningIDs(ctx, tx, `DELETE FROM shares WHERE user_id = $1 RETURNING original_post_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete shares: %w", err)
+	}
+	result.Shares = int64(len(sharedPosts))
+	markTouched(touchedPosts, sharedPosts)
+
+	// Hides of the user's own posts cascade with them below
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_hides WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete post hides: %w", err)
+	}
+
+	// Replies to the user's comments go with them (ON DELETE CASCADE)
+	commentedPosts, err := deleteReturningIDs(ctx, tx, `DELETE FROM comments WHERE user_id = $1 RETURNING post_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete comments: %w", err)
+	}
+	result.Comments = int64(len(commentedPosts))
+	markTouched(touchedPosts, commentedPosts)
+
+	// Comments, likes, saves and shares on the user's own posts cascade
+	result.PostIDs, err = deleteReturningIDs(ctx, tx, `DELETE FROM posts WHERE user_id = $1 RETURNING id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete posts: %w", err)
+	}
+	result.Posts = int64(len(result.PostIDs))
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM behind_the_takes WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete behind the takes: %w", err)
+	}
+	takes, err := tx.ExecContext(ctx, `DELETE FROM takes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete takes: %w", err)
+	}
+	result.Takes, _ = takes.RowsAffected()
+
+	// Recount rather than decrement so a repeated or partial purge can't drift the counts
+	_, err = tx.ExecContext(ctx, `
+		UPDATE posts p SET
+			likes_count = (SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id),
+			comments_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+			saves_count = (SELECT COUNT(*) FROM saves s WHERE s.post_id = p.id),
+			shares_count = (SELECT COUNT(*) FROM shares sh WHERE sh.original_post_id = p.id)
+		WHERE p.id = ANY($1)`,
+		pq.Array(uuidStrings(touchedPosts)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recount post interactions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE comments c SET likes_count = (SELECT COUNT(*) FROM likes l WHERE l.comment_id = c.id)
+		WHERE c.id = ANY($1)`,
+		pq.Array(uuidStrings(touchedComments)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recount comment likes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user purge: %w", err)
+	}
+
+	return result, nil
+}
+
+// deleteLikes deletes the user's reactions, returning the posts and comments they were on
+func (r *userPurgeRepository) deleteLikes(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]uuid.UUID, []uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, `DELETE FROM likes WHERE user_id = $1 RETURNING post_id, comment_id`, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to delete likes: %w", err)
+	}
+	defer rows.Close()
+
+	var posts, comments []uuid.UUID
+	for rows.Next() {
+		var postID, commentID uuid.NullUUID
+		if err := rows.Scan(&postID, &commentID); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan deleted like: %w", err)
+		}
+		if postID.Valid {
+			posts = append(posts, postID.UUID)
+		}
+		if commentID.Valid {
+			comments = append(comments, commentID.UUID)
+		}
+	}
+
+	return posts, comments, rows.Err()
+}
+
+func deleteReturningIDs(ctx context.Context, tx *sql.Tx, query string, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func markTouched(touched map[uuid.UUID]bool, ids []uuid.UUID) {
+	for _, id := range ids {
+		touched[id] = true
+	}
+}
+
+func uuidStrings(set map[uuid.UUID]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id.String())
+	}
+	return ids
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"socialink/post-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// ErrPostNotFound is returned for posts that don't exist and for posts the viewer
+// may not see, so a post's audience doesn't give away that it exists
+var ErrPostNotFound = errors.New("post not found")
+
+// AudienceClient decides who may see a post, asking the user service how the
+// viewer relates to the post's author
+type AudienceClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewAudienceClient(userServiceURL, internalToken string) *AudienceClient {
+	return &AudienceClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// CheckCanView returns ErrPostNotFound unless viewerID may see post. viewerID is
+// uuid.Nil for signed-out viewers. If the user service can't be asked, only
+// public posts and the viewer's own can be seen.
+func (c *AudienceClient) CheckCanView(ctx context.Context, viewerID uuid.UUID, post *model.Post) error {
+	if len(c.Visible(ctx, viewerID, []model.Post{*post})) == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// Visible returns those of posts viewerID may see, in the same order. Authors see
+// all their own posts; nobody sees the posts of someone they have blocked or been
+// blocked by. If the user service can't be asked, only public posts and the
+// viewer's own are returned.
+func (c *AudienceClient) Visible(ctx context.Context, viewerID uuid.UUID, posts []model.Post) []model.Post {
+	if len(posts) == 0 {
+		return posts
+	}
+	if viewerID == uuid.Nil {
+		return filterPosts(posts, func(post *model.Post) bool {
+			return post.Audience == model.AudiencePublic
+		})
+	}
+
+	seen := make(map[uuid.UUID]bool, len(posts))
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		if post.UserID != viewerID && !seen[post.UserID] {
+			seen[post.UserID] = true
+			authorIDs = append(authorIDs, post.UserID)
+		}
+	}
+	if len(authorIDs) == 0 {
+		return posts
+	}
+
+	friends, blocked, err := c.relationships(ctx, viewerID, authorIDs)
+	if err != nil {
+		log.Printf("Failed to check relationships for %s, showing public posts only: %v", viewerID, err)
+		return filterPosts(posts, func(post *model.Post) bool {
+			return post.UserID == viewerID || post.Audience == model.AudiencePublic
+		})
+	}
+
+	members, err := c.listMemberships(ctx, viewerID, posts, blocked)
+	if err != nil {
+		log.Printf("Failed to check audience lists for %s, leaving out list posts: %v", viewerID, err)
+	}
+
+	return filterPosts(posts, func(post *model.Post) bool {
+		if post.UserID == viewerID {
+			return true
+		}
+		if blocked[post.UserID] {
+			return false
+		}
+
+		switch post.Audience {
+		case model.AudiencePublic:
+			return true
+		case model.AudienceFriends:
+			return friends[post.UserID]
+		case model.AudienceOnlyMe:
+			return false
+		}
+		listID, ok := model.AudienceListID(post.Audience)
+		return ok && members[audienceList{ownerID: post.UserID, listID: listID}]
+	})
+}
+
+// relationships returns which of userIDs are friends with viewerID, and which are
+// blocked from them either way
+func (c *AudienceClient) relationships(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]bool, map[uuid.UUID]bool, error) {
+	request := map[string]interface{}{
+		"viewer_id": viewerID,
+		"user_ids":  userIDs,
+	}
+
+	var data struct {
+		FriendIDs      []uuid.UUID `json:"friend_ids"`
+		BlockedUserIDs []uuid.UUID `json:"blocked_user_ids"`
+	}
+	if err := c.post(ctx, "/api/v1/internal/relationships/check", request, &data); err != nil {
+		return nil, nil, err
+	}
+
+	friends := make(map[uuid.UUID]bool, len(data.FriendIDs))
+	for _, id := range data.FriendIDs {
+		friends[id] = true
+	}
+	blocked := make(map[uuid.UUID]bool, len(data.BlockedUserIDs))
+	for _, id := range data.BlockedUserIDs {
+		blocked[id] = true
+	}
+	return friends, blocked, nil
+}
+
+type audienceList struct {
+	ownerID uuid.UUID
+	listID  uuid.UUID
+}
+
+// listMemberships returns which of the audience lists posts are shared with
+// viewerID is in. Lists of authors blocked from the viewer aren't asked about.
+func (c *AudienceClient) listMemberships(ctx context.Context, viewerID uuid.UUID, posts []model.Post, blocked map[uuid.UUID]bool) (map[audienceList]bool, error) {
+	type check struct {
+		OwnerID  uuid.UUID `json:"owner_id"`
+		ListID   uuid.UUID `json:"list_id"`
+		ViewerID uuid.UUID `json:"viewer_id"`
+	}
+
+	members := make(map[audienceList]bool)
+	seen := make(map[audienceList]bool)
+	checks := []check{}
+	for _, post := range posts {
+		listID, ok := model.AudienceListID(post.Audience)
+		if !ok || post.UserID == viewerID || blocked[post.UserID] {
+			continue
+		}
+		list := audienceList{ownerID: post.UserID, listID: listID}
+		if !seen[list] {
+			seen[list] = true
+			checks = append(checks, check{OwnerID: post.UserID, ListID: listID, ViewerID: viewerID})
+		}
+	}
+	if len(checks) == 0 {
+		return members, nil
+	}
+
+	var data struct {
+		Results []struct {
+			check
+			IsMember bool `json:"is_member"`
+		} `json:"results"`
+	}
+	if err := c.post(ctx, "/api/v1/internal/audience-lists/check", map[string]interface{}{"checks": checks}, &data); err != nil {
+		return members, err
+	}
+
+	for _, result := range data.Results {
+		if result.IsMember {
+			members[audienceList{ownerID: result.OwnerID, listID: result.ListID}] = true
+		}
+	}
+	return members, nil
+}
+
+func (c *AudienceClient) post(ctx context.Context, path string, request, data interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.userServiceURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func filterPosts(posts []model.Post, keep func(post *model.Post) bool) []model.Post {
+	kept := make([]model.Post, 0, len(posts))
+	for i := range posts {
+		if keep(&posts[i]) {
+			kept = append(kept, posts[i])
+		}
+	}
+	return kept
+}
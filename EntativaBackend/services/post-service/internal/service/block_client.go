@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Events the user service publishes on "user-events" when a block changes
+const (
+	userBlockedEvent   = "user.blocked"
+	userUnblockedEvent = "user.unblocked"
+)
+
+// BlockClient asks the user service who a user has blocked or been blocked by, so
+// their posts can be kept out of each other's feeds
+type BlockClient struct {
+	userServiceURL string
+	internalToken  string
+	redis          *redis.Client
+	httpClient     *http.Client
+}
+
+func NewBlockClient(userServiceURL, internalToken string, redis *redis.Client) *BlockClient {
+	return &BlockClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		redis:          redis,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// BlockedUserIDs returns everyone userID has blocked or been blocked by
+func (c *BlockClient) BlockedUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/blocks/%s", c.userServiceURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			BlockedUserIDs []uuid.UUID `json:"blocked_user_ids"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode blocked users: %w", err)
+	}
+
+	return body.Data.BlockedUserIDs, nil
+}
+
+// HandleUserEvent drops both users' home timelines when a block is created or
+// lifted, so they are rebuilt from the friend lists that follow from it
+func (c *BlockClient) HandleUserEvent(ctx context.Context, key, value []byte) error {
+	var event struct {
+		EventType     string    `json:"event_type"`
+		UserID        uuid.UUID `json:"user_id"`
+		BlockedUserID uuid.UUID `json:"blocked_user_id"`
+	}
+	if err := json.Unmarshal(value, &event); err != nil {
+		return nil
+	}
+	if event.EventType != userBlockedEvent && event.EventType != userUnblockedEvent {
+		return nil
+	}
+	if c.redis == nil {
+		return nil
+	}
+
+	return c.redis.Del(ctx,
+		timelineKey(event.UserID), timelineSourcesKey(event.UserID),
+		timelineKey(event.BlockedUserID), timelineSourcesKey(event.BlockedUserID),
+	).Err()
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"socialink/post-service/internal/model"
+	"socialink/post-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultExportPageSize = 500
+	maxExportPageSize     = 1000
+)
+
+type ExportService struct {
+	exportRepo repository.ExportRepository
+}
+
+func NewExportService(exportRepo repository.ExportRepository) *ExportService {
+	return &ExportService{
+		exportRepo: exportRepo,
+	}
+}
+
+// GetExportPage returns one page of a section of the user's data for a data export
+func (s *ExportService) GetExportPage(ctx context.Context, userID uuid.UUID, section, cursor string, limit int) (*model.ExportPage, error) {
+	if limit <= 0 {
+		limit = defaultExportPageSize
+	}
+	if limit > maxExportPageSize {
+		limit = maxExportPageSize
+	}
+
+	page := &model.ExportPage{Section: section}
+
+	var err error
+	switch section {
+	case model.ExportSectionPosts:
+		var posts []model.Post
+		posts, page.NextCursor, err = s.exportRepo.GetPosts(ctx, userID, cursor, limit)
+		page.Items = nonNil(posts)
+	case model.ExportSectionComments:
+		var comments []model.Comment
+		comments, page.NextCursor, err = s.exportRepo.GetComments(ctx, userID, cursor, limit)
+		page.Items = nonNil(comments)
+	case model.ExportSectionLikes:
+		var likes []model.Like
+		likes, page.NextCursor, err = s.exportRepo.GetLikes(ctx, userID, cursor, limit)
+		page.Items = nonNil(likes)
+	case model.ExportSectionSaves:
+		var saves []model.Save
+		saves, page.NextCursor, err = s.exportRepo.GetSaves(ctx, userID, cursor, limit)
+		page.Items = nonNil(saves)
+	default:
+		return nil, fmt.Errorf("unknown export section: %s", section)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", section, err)
+	}
+
+	return page, nil
+}
+
+// nonNil makes empty sections encode as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GraphClient asks the user service who is friends with whom, so home timelines
+// can be built from friends' posts
+type GraphClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewGraphClient(userServiceURL, internalToken string) *GraphClient {
+	return &GraphClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Audience returns the users whose home timelines authorID's posts go to: their
+// friends. If there are more than max of them, ok is false and no IDs are returned.
+func (c *GraphClient) Audience(ctx context.Context, authorID uuid.UUID, max int) ([]uuid.UUID, bool, error) {
+	friends, err := c.friends(ctx, authorID, max)
+	if err != nil {
+		return nil, false, err
+	}
+	if friends.Truncated {
+		return nil, false, nil
+	}
+	return friends.FriendIDs, true, nil
+}
+
+// Sources returns the users whose posts belong in userID's home timeline: their friends
+func (c *GraphClient) Sources(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	friends, err := c.friends(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return friends.FriendIDs, nil
+}
+
+type friendList struct {
+	FriendIDs []uuid.UUID `json:"friend_ids"`
+	Truncated bool        `json:"truncated"`
+}
+
+func (c *GraphClient) friends(ctx context.Context, userID uuid.UUID, max int) (*friendList, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/friends", c.userServiceURL, userID)
+	if max > 0 {
+		endpoint += "?" + url.Values{"max": {strconv.Itoa(max)}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data friendList `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode friends: %w", err)
+	}
+
+	return &body.Data, nil
+}
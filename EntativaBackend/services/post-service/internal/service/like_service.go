@@ -17,6 +17,7 @@ type LikeService struct {
 	likeRepo    repository.LikeRepository
 	postRepo    repository.PostRepository
 	commentRepo repository.CommentRepository
+	audiences   *AudienceClient
 	redis       *redis.Client
 	kafka       *kafka.Producer
 }
@@ -25,6 +26,7 @@ func NewLikeService(
 	likeRepo repository.LikeRepository,
 	postRepo repository.PostRepository,
 	commentRepo repository.CommentRepository,
+	audiences *AudienceClient,
 	redis *redis.Client,
 	kafka *kafka.Producer,
 ) *LikeService {
@@ -32,6 +34,7 @@ func NewLikeService(
 		likeRepo:    likeRepo,
 		postRepo:    postRepo,
 		commentRepo: commentRepo,
+		audiences:   audiences,
 		redis:       redis,
 		kafka:       kafka,
 	}
@@ -39,10 +42,13 @@ func NewLikeService(
 
 // LikePost creates or updates a like/reaction on a post
 func (s *LikeService) LikePost(ctx context.Context, postID, userID uuid.UUID, reactionType model.ReactionType) error {
-	// Verify post exists
+	// Verify post exists and the user may see it
 	post, err := s.postRepo.GetByID(ctx, postID)
 	if err != nil {
-		return fmt.Errorf("post not found")
+		return ErrPostNotFound
+	}
+	if err := s.audiences.CheckCanView(ctx, userID, post); err != nil {
+		return err
 	}
 
 	// Check if already liked
@@ -117,6 +123,9 @@ func (s *LikeService) LikeComment(ctx context.Context, commentID, userID uuid.UU
 	if err != nil {
 		return fmt.Errorf("comment not found")
 	}
+	if err := s.checkCanViewPost(ctx, userID, comment.PostID); err != nil {
+		return err
+	}
 
 	// Check if already liked
 	existingLike, _ := s.likeRepo.GetCommentLike(ctx, userID, commentID)
@@ -189,8 +198,11 @@ func (s *LikeService) UnlikeComment(ctx context.Context, commentID, userID uuid.
 	return nil
 }
 
-// GetPostLikers retrieves users who liked a post
-func (s *LikeService) GetPostLikers(ctx context.Context, postID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+// GetPostLikers retrieves users who liked a post, if viewerID may see it
+func (s *LikeService) GetPostLikers(ctx context.Context, postID, viewerID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+	if err := s.checkCanViewPost(ctx, viewerID, postID); err != nil {
+		return nil, err
+	}
 	return s.likeRepo.GetPostLikers(ctx, postID, limit, offset)
 }
 
@@ -199,6 +211,14 @@ func (s *LikeService) GetUserPostReaction(ctx context.Context, userID, postID uu
 	return s.likeRepo.GetUserPostReaction(ctx, userID, postID)
 }
 
+func (s *LikeService) checkCanViewPost(ctx context.Context, viewerID, postID uuid.UUID) error {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return ErrPostNotFound
+	}
+	return s.audiences.CheckCanView(ctx, viewerID, post)
+}
+
 // Cache methods
 func (s *LikeService) invalidatePostCache(ctx context.Context, postID uuid.UUID) {
 	if s.redis == nil {
@@ -4,24 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"socialink/post-service/internal/model"
 	"socialink/post-service/internal/repository"
 	"socialink/post-service/pkg/kafka"
+	"socialink/post-service/pkg/ranking"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// explorePoolFactor is how many candidates explore ranks per post it shows
+const explorePoolFactor = 5
+
+// publishGiveUpAfter is how long past its time a scheduled post keeps being
+// retried before it is marked failed
+const publishGiveUpAfter = time.Hour
+
 type PostService struct {
 	postRepo repository.PostRepository
 	likeRepo repository.LikeRepository
 	commentRepo repository.CommentRepository
 	saveRepo repository.SaveRepository
+	audiences *AudienceClient
+	timelines *TimelineService
+	ranking   *RankingService
 	redis    *redis.Client
 	kafka    *kafka.Producer
+	notifications *NotificationClient
 }
 
 func NewPostService(
@@ -29,21 +42,54 @@ func NewPostService(
 	likeRepo repository.LikeRepository,
 	commentRepo repository.CommentRepository,
 	saveRepo repository.SaveRepository,
+	audiences *AudienceClient,
+	timelines *TimelineService,
+	ranking *RankingService,
 	redis *redis.Client,
 	kafka *kafka.Producer,
+	notifications *NotificationClient,
 ) *PostService {
 	return &PostService{
 		postRepo:    postRepo,
 		likeRepo:    likeRepo,
 		commentRepo: commentRepo,
 		saveRepo:    saveRepo,
+		audiences:   audiences,
+		timelines:   timelines,
+		ranking:     ranking,
 		redis:       redis,
 		kafka:       kafka,
+		notifications: notifications,
 	}
 }
 
 // CreatePost creates a new Facebook-style post (media required)
 func (s *PostService) CreatePost(ctx context.Context, userID uuid.UUID, req *model.CreatePostRequest) (*model.Post, error) {
+	post, err := s.newPost(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	post.Status = model.StatusPublished
+
+	// Decide whether the post is pushed into friends' timelines or pulled on read
+	audience, fanOut := s.timelines.Audience(ctx, userID)
+	post.FannedOut = fanOut
+
+	if err := s.postRepo.Create(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+
+	// Publish event to Kafka
+	s.publishPostCreatedEvent(post)
+
+	// Deliver to home timelines
+	s.timelines.Publish(post, audience)
+
+	return post, nil
+}
+
+// newPost builds a post from a create request, for publishing now or saving as a draft
+func (s *PostService) newPost(userID uuid.UUID, req *model.CreatePostRequest) (*model.Post, error) {
 	// Validate media is provided (Facebook requires media)
 	if len(req.MediaIDs) == 0 {
 		return nil, fmt.Errorf("at least one media attachment is required")
@@ -56,11 +102,18 @@ func (s *PostService) CreatePost(ctx context.Context, userID uuid.UUID, req *mod
 		hashtags = s.deduplicateStrings(hashtags)
 	}
 
+	postAudience := req.Audience
+	if postAudience == "" {
+		postAudience = model.AudiencePublic
+	}
+	if err := model.ValidateAudience(postAudience); err != nil {
+		return nil, err
+	}
+
 	// Determine if carousel (multiple images)
 	isCarousel := len(req.MediaIDs) > 1
 
-	// Create post
-	post := &model.Post{
+	return &model.Post{
 		ID:              uuid.New(),
 		UserID:          userID,
 		Caption:         req.Caption,
@@ -78,39 +131,32 @@ func (s *PostService) CreatePost(ctx context.Context, userID uuid.UUID, req *mod
 		IsEdited:        false,
 		CommentsEnabled: req.CommentsEnabled,
 		LikesVisible:    req.LikesVisible,
+		Audience:        postAudience,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
-	}
-
-	if err := s.postRepo.Create(ctx, post); err != nil {
-		return nil, fmt.Errorf("failed to create post: %w", err)
-	}
-
-	// Publish event to Kafka
-	s.publishPostCreatedEvent(post)
-
-	// Invalidate user's feed cache
-	s.invalidateFeedCache(ctx, userID)
-
-	return post, nil
+	}, nil
 }
 
-// GetPost retrieves a post by ID
-func (s *PostService) GetPost(ctx context.Context, postID uuid.UUID) (*model.Post, error) {
+// GetPost retrieves a post by ID, if viewerID may see it. viewerID is uuid.Nil
+// for signed-out viewers.
+func (s *PostService) GetPost(ctx context.Context, postID, viewerID uuid.UUID) (*model.Post, error) {
 	// Try cache first
-	if post, err := s.getPostFromCache(ctx, postID); err == nil && post != nil {
-		return post, nil
+	post, err := s.getPostFromCache(ctx, postID)
+	if err != nil || post == nil {
+		// Get from database
+		post, err = s.postRepo.GetByID(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache it
+		s.cachePost(ctx, post)
 	}
 
-	// Get from database
-	post, err := s.postRepo.GetByID(ctx, postID)
-	if err != nil {
+	if err := s.audiences.CheckCanView(ctx, viewerID, post); err != nil {
 		return nil, err
 	}
 
-	// Cache it
-	s.cachePost(ctx, post)
-
 	// Increment view count asynchronously
 	go func() {
 		s.postRepo.IncrementViews(context.Background(), postID)
@@ -132,7 +178,30 @@ func (s *PostService) UpdatePost(ctx context.Context, postID, userID uuid.UUID,
 		return nil, fmt.Errorf("permission denied: not the post owner")
 	}
 
-	// Update fields
+	if err := s.applyUpdate(post, req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	post.IsEdited = true
+	post.EditedAt = &now
+	post.UpdatedAt = now
+
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	// Invalidate cache
+	s.invalidatePostCache(ctx, postID)
+
+	// Publish event
+	s.publishPostUpdatedEvent(post)
+
+	return post, nil
+}
+
+// applyUpdate applies the fields set in req to post
+func (s *PostService) applyUpdate(post *model.Post, req *model.UpdatePostRequest) error {
 	if req.Caption != nil {
 		post.Caption = *req.Caption
 		// Re-extract hashtags
@@ -151,23 +220,13 @@ func (s *PostService) UpdatePost(ctx context.Context, postID, userID uuid.UUID,
 	if req.LikesVisible != nil {
 		post.LikesVisible = *req.LikesVisible
 	}
-
-	now := time.Now()
-	post.IsEdited = true
-	post.EditedAt = &now
-	post.UpdatedAt = now
-
-	if err := s.postRepo.Update(ctx, post); err != nil {
-		return nil, fmt.Errorf("failed to update post: %w", err)
+	if req.Audience != nil {
+		if err := model.ValidateAudience(*req.Audience); err != nil {
+			return err
+		}
+		post.Audience = *req.Audience
 	}
-
-	// Invalidate cache
-	s.invalidatePostCache(ctx, postID)
-
-	// Publish event
-	s.publishPostUpdatedEvent(post)
-
-	return post, nil
+	return nil
 }
 
 // DeletePost soft deletes a post
@@ -190,7 +249,9 @@ func (s *PostService) DeletePost(ctx context.Context, postID, userID uuid.UUID)
 
 	// Invalidate cache
 	s.invalidatePostCache(ctx, postID)
-	s.invalidateFeedCache(ctx, userID)
+
+	// Take it out of home timelines
+	s.timelines.Remove(post)
 
 	// Publish event
 	s.publishPostDeletedEvent(postID, userID)
@@ -198,65 +259,120 @@ func (s *PostService) DeletePost(ctx context.Context, postID, userID uuid.UUID)
 	return nil
 }
 
-// GetUserPosts retrieves posts by a user
-func (s *PostService) GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Post, error) {
-	return s.postRepo.GetByUserID(ctx, userID, limit, offset)
+// GetUserPosts retrieves the posts by a user that viewerID may see
+func (s *PostService) GetUserPosts(ctx context.Context, userID, viewerID uuid.UUID, limit, offset int) ([]model.Post, error) {
+	posts, err := s.postRepo.GetByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.audiences.Visible(ctx, viewerID, posts), nil
 }
 
-// GetFeed retrieves the user's personalized feed
-func (s *PostService) GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]model.Post, *string, error) {
-	// Try cache first for first page
-	if cursor == "" {
-		if cachedPosts, err := s.getFeedFromCache(ctx, userID, limit); err == nil && len(cachedPosts) > 0 {
-			var nextCursor *string
-			if len(cachedPosts) > limit {
-				cachedPosts = cachedPosts[:limit]
-				cursorStr := cachedPosts[limit-1].ID.String()
-				nextCursor = &cursorStr
-			}
-			return cachedPosts, nextCursor, nil
-		}
+// GetFeed retrieves the user's home timeline. cursor is the next_cursor of the
+// previous page, or empty for the first page. Each page holds the next posts in
+// time, ranked among themselves, so ranking never moves a post to another page.
+// With explain, the ranking of each post is returned in the same order.
+func (s *PostService) GetFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int, explain bool) ([]model.Post, *string, []ranking.Explanation, error) {
+	if limit < 1 || limit > 100 {
+		limit = 20
 	}
 
-	// Get from database
-	posts, nextCursor, err := s.postRepo.GetFeed(ctx, userID, cursor, limit)
+	after, err := model.ParseFeedCursor(cursor)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	// Cache first page
-	if cursor == "" && len(posts) > 0 {
-		s.cacheFeed(ctx, userID, posts)
+	posts, next, err := s.timelines.Read(ctx, userID, after, limit)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	return posts, nextCursor, nil
+	// Leave out posts shared with audiences the user isn't in, and anyone they have
+	// blocked or been blocked by
+	posts = s.audiences.Visible(ctx, userID, posts)
+
+	posts, explanations := s.ranking.Rank(ctx, userID, posts)
+	if !explain {
+		explanations = nil
+	}
+
+	var nextCursor *string
+	if next != nil {
+		cursorStr := next.String()
+		nextCursor = &cursorStr
+	}
+
+	return posts, nextCursor, explanations, nil
 }
 
-// GetExplorePosts retrieves explore page posts
-func (s *PostService) GetExplorePosts(ctx context.Context, limit int) ([]model.Post, error) {
+// GetExplorePosts retrieves explore page posts: the most engaged-with public posts
+// of the last two days, ranked. With explain the ranking is worked out afresh rather
+// than read from the cache, and each post's is returned in the same order.
+func (s *PostService) GetExplorePosts(ctx context.Context, limit int, explain bool) ([]model.Post, []ranking.Explanation, error) {
 	// Check cache
 	cacheKey := fmt.Sprintf("explore:posts:%d", limit)
-	if cachedPosts, err := s.getTrendingFromCache(ctx, cacheKey); err == nil && len(cachedPosts) > 0 {
-		return cachedPosts, nil
+	if !explain {
+		if cachedPosts, err := s.getTrendingFromCache(ctx, cacheKey); err == nil && len(cachedPosts) > 0 {
+			return cachedPosts, nil, nil
+		}
 	}
 
-	// Get trending posts for explore
-	posts, err := s.postRepo.GetTrendingPosts(ctx, limit, 48*time.Hour)
+	// Rank a pool several times the page size, so the ranking has room to reorder
+	candidates, err := s.postRepo.GetExploreCandidates(ctx, limit*explorePoolFactor, 48*time.Hour)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// The same for everyone, so it can be cached
+	posts, explanations := s.ranking.Rank(ctx, uuid.Nil, candidates)
+	if len(posts) > limit {
+		posts = posts[:limit]
+		explanations = explanations[:limit]
 	}
 
 	// Cache for 10 minutes
 	s.cacheTrending(ctx, cacheKey, posts, 10*time.Minute)
 
-	return posts, nil
+	if !explain {
+		explanations = nil
+	}
+	return posts, explanations, nil
+}
+
+// HidePost hides another user's post from the user's feeds, and ranks that
+// user's posts lower for them
+func (s *PostService) HidePost(ctx context.Context, userID, postID uuid.UUID) error {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("post not found")
+	}
+	if post.UserID == userID {
+		return fmt.Errorf("cannot hide your own post")
+	}
+
+	if err := s.ranking.HidePost(ctx, userID, post); err != nil {
+		return fmt.Errorf("failed to hide post: %w", err)
+	}
+	return nil
+}
+
+// UnhidePost shows a hidden post again
+func (s *PostService) UnhidePost(ctx context.Context, userID, postID uuid.UUID) error {
+	if err := s.ranking.UnhidePost(ctx, userID, postID); err != nil {
+		return fmt.Errorf("failed to unhide post: %w", err)
+	}
+	return nil
 }
 
 // SavePost saves a post to user's saved collection
 func (s *PostService) SavePost(ctx context.Context, userID, postID uuid.UUID, collection *string) error {
-	// Verify post exists
-	if _, err := s.postRepo.GetByID(ctx, postID); err != nil {
-		return fmt.Errorf("post not found")
+	// Verify post exists and the user may see it
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return ErrPostNotFound
+	}
+	if err := s.audiences.CheckCanView(ctx, userID, post); err != nil {
+		return err
 	}
 
 	save := &model.Save{
@@ -294,6 +410,218 @@ func (s *PostService) GetSavedPosts(ctx context.Context, userID uuid.UUID, colle
 	return s.saveRepo.GetByUserID(ctx, userID, collection, limit, offset)
 }
 
+// Drafts and scheduled posts
+
+// CreateDraft saves a post without publishing it, scheduling it if req has a time
+func (s *PostService) CreateDraft(ctx context.Context, userID uuid.UUID, req *model.CreateDraftRequest) (*model.Post, error) {
+	post, err := s.newPost(userID, &req.CreatePostRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Status = model.StatusDraft
+	if req.ScheduledAt != nil {
+		if err := model.ValidateSchedule(*req.ScheduledAt, time.Now()); err != nil {
+			return nil, err
+		}
+		post.Status = model.StatusScheduled
+		post.ScheduledAt = req.ScheduledAt
+	}
+
+	if err := s.postRepo.Create(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+
+	return post, nil
+}
+
+// GetDrafts retrieves the user's drafts, scheduled posts and posts that failed to publish
+func (s *PostService) GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Post, error) {
+	return s.postRepo.GetDrafts(ctx, userID, limit, offset)
+}
+
+// GetDraft retrieves one of the user's unpublished posts
+func (s *PostService) GetDraft(ctx context.Context, postID, userID uuid.UUID) (*model.Post, error) {
+	return s.getOwnDraft(ctx, postID, userID)
+}
+
+// UpdateDraft edits an unpublished post. It doesn't count as an edit once published.
+func (s *PostService) UpdateDraft(ctx context.Context, postID, userID uuid.UUID, req *model.UpdatePostRequest) (*model.Post, error) {
+	post, err := s.getOwnDraft(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyUpdate(post, req); err != nil {
+		return nil, err
+	}
+	post.UpdatedAt = time.Now()
+
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to update draft: %w", err)
+	}
+
+	return post, nil
+}
+
+// DeleteDraft deletes an unpublished post, cancelling it if it was scheduled
+func (s *PostService) DeleteDraft(ctx context.Context, postID, userID uuid.UUID) error {
+	if _, err := s.getOwnDraft(ctx, postID, userID); err != nil {
+		return err
+	}
+
+	if err := s.postRepo.Delete(ctx, postID); err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	return nil
+}
+
+// SchedulePost schedules a draft for scheduledAt, or moves a scheduled post to
+// it. A post that failed to publish is tried again then.
+func (s *PostService) SchedulePost(ctx context.Context, postID, userID uuid.UUID, scheduledAt time.Time) (*model.Post, error) {
+	if _, err := s.getOwnDraft(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	if err := model.ValidateSchedule(scheduledAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.postRepo.SetSchedule(ctx, postID, model.StatusScheduled, &scheduledAt); err != nil {
+		return nil, err
+	}
+
+	return s.postRepo.GetDraftByID(ctx, postID)
+}
+
+// CancelSchedule turns a scheduled post back into a draft
+func (s *PostService) CancelSchedule(ctx context.Context, postID, userID uuid.UUID) (*model.Post, error) {
+	if _, err := s.getOwnDraft(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.postRepo.SetSchedule(ctx, postID, model.StatusDraft, nil); err != nil {
+		return nil, err
+	}
+
+	return s.postRepo.GetDraftByID(ctx, postID)
+}
+
+// PublishDraft publishes an unpublished post now
+func (s *PostService) PublishDraft(ctx context.Context, postID, userID uuid.UUID) (*model.Post, error) {
+	post, err := s.getOwnDraft(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPublishable(post); err != nil {
+		return nil, err
+	}
+
+	published, err := s.publish(ctx, post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish post: %w", err)
+	}
+	if !published {
+		// Published by the scheduler, rescheduled or deleted since it was read
+		return nil, model.ErrDraftNotFound
+	}
+
+	return post, nil
+}
+
+// PublishDue publishes up to limit scheduled posts that are due, returning how
+// many it published. Several instances may run it at once: each post is published
+// by exactly one of them. A post that can't be published is retried on later
+// runs, and after publishGiveUpAfter marked failed and its author told.
+func (s *PostService) PublishDue(ctx context.Context, limit int) int {
+	posts, err := s.postRepo.GetDueScheduled(ctx, limit)
+	if err != nil {
+		log.Printf("Failed to get due scheduled posts: %v", err)
+		return 0
+	}
+
+	count := 0
+	for i := range posts {
+		post := &posts[i]
+
+		if err := s.checkPublishable(post); err != nil {
+			s.failScheduled(ctx, post, err.Error())
+			continue
+		}
+
+		published, err := s.publish(ctx, post)
+		if err != nil {
+			log.Printf("Failed to publish scheduled post %s: %v", post.ID, err)
+			if time.Since(*post.ScheduledAt) > publishGiveUpAfter {
+				s.failScheduled(ctx, post, "the post could not be published")
+			}
+			continue
+		}
+		if published {
+			count++
+		}
+	}
+
+	return count
+}
+
+// publish publishes a draft or scheduled post as of now, delivering it as
+// CreatePost does. It returns false, doing nothing, if the post was published
+// elsewhere or changed since it was read.
+func (s *PostService) publish(ctx context.Context, post *model.Post) (bool, error) {
+	audience, fanOut := s.timelines.Audience(ctx, post.UserID)
+	post.FannedOut = fanOut
+
+	now := time.Now()
+	published, err := s.postRepo.MarkPublished(ctx, post, now)
+	if err != nil || !published {
+		return false, err
+	}
+
+	post.Status = model.StatusPublished
+	post.PublishError = nil
+	post.CreatedAt = now
+	post.UpdatedAt = now
+
+	s.publishPostCreatedEvent(post)
+	s.timelines.Publish(post, audience)
+
+	return true, nil
+}
+
+// failScheduled marks a scheduled post failed and tells its author, unless it
+// changed since it was read
+func (s *PostService) failScheduled(ctx context.Context, post *model.Post, reason string) {
+	failed, err := s.postRepo.MarkPublishFailed(ctx, post, reason)
+	if err != nil {
+		log.Printf("Failed to mark scheduled post %s failed: %v", post.ID, err)
+		return
+	}
+	if failed {
+		s.publishPostPublishFailedEvent(post, reason)
+		s.notifications.PostPublishFailed(ctx, post.UserID, post.ID, reason)
+	}
+}
+
+// checkPublishable checks a draft is still fit to publish
+func (s *PostService) checkPublishable(post *model.Post) error {
+	if len(post.MediaIDs) == 0 {
+		return fmt.Errorf("at least one media attachment is required")
+	}
+	return model.ValidateAudience(post.Audience)
+}
+
+func (s *PostService) getOwnDraft(ctx context.Context, postID, userID uuid.UUID) (*model.Post, error) {
+	post, err := s.postRepo.GetDraftByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, model.ErrDraftNotFound
+	}
+	return post, nil
+}
+
 // Helper functions
 
 func (s *PostService) extractHashtags(caption string) []string {
@@ -368,44 +696,6 @@ func (s *PostService) invalidatePostCache(ctx context.Context, postID uuid.UUID)
 	s.redis.Del(ctx, key)
 }
 
-func (s *PostService) cacheFeed(ctx context.Context, userID uuid.UUID, posts []model.Post) {
-	if s.redis == nil {
-		return
-	}
-
-	key := fmt.Sprintf("feed:%s", userID.String())
-	data, _ := json.Marshal(posts)
-	s.redis.Set(ctx, key, data, 10*time.Minute)
-}
-
-func (s *PostService) getFeedFromCache(ctx context.Context, userID uuid.UUID, limit int) ([]model.Post, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("redis not available")
-	}
-
-	key := fmt.Sprintf("feed:%s", userID.String())
-	data, err := s.redis.Get(ctx, key).Bytes()
-	if err != nil {
-		return nil, err
-	}
-
-	var posts []model.Post
-	if err := json.Unmarshal(data, &posts); err != nil {
-		return nil, err
-	}
-
-	return posts, nil
-}
-
-func (s *PostService) invalidateFeedCache(ctx context.Context, userID uuid.UUID) {
-	if s.redis == nil {
-		return
-	}
-
-	key := fmt.Sprintf("feed:%s", userID.String())
-	s.redis.Del(ctx, key)
-}
-
 func (s *PostService) cacheTrending(ctx context.Context, key string, posts []model.Post, ttl time.Duration) {
 	if s.redis == nil {
 		return
@@ -445,6 +735,7 @@ func (s *PostService) publishPostCreatedEvent(post *model.Post) {
 		"user_id":    post.UserID.String(),
 		"is_reels":   post.IsReels,
 		"hashtags":   post.Hashtags,
+		"audience":   post.Audience,
 		"created_at": post.CreatedAt,
 	}
 
@@ -460,6 +751,7 @@ func (s *PostService) publishPostUpdatedEvent(post *model.Post) {
 		"event_type": "post.updated",
 		"post_id":    post.ID.String(),
 		"user_id":    post.UserID.String(),
+		"audience":   post.Audience,
 		"updated_at": post.UpdatedAt,
 	}
 
@@ -480,3 +772,22 @@ func (s *PostService) publishPostDeletedEvent(postID, userID uuid.UUID) {
 
 	s.kafka.PublishEvent(context.Background(), "post-events", postID.String(), event)
 }
+
+// publishPostPublishFailedEvent announces that a scheduled post could
+// not be published
+func (s *PostService) publishPostPublishFailedEvent(post *model.Post, reason string) {
+	if s.kafka == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"event_type":   "post.publish_failed",
+		"post_id":      post.ID.String(),
+		"user_id":      post.UserID.String(),
+		"scheduled_at": post.ScheduledAt,
+		"reason":       reason,
+		"failed_at":    time.Now(),
+	}
+
+	s.kafka.PublishEvent(context.Background(), "post-events", post.ID.String(), event)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"socialink/post-service/internal/model"
+	"socialink/post-service/internal/repository"
+	"socialink/post-service/pkg/ranking"
+
+	"github.com/google/uuid"
+)
+
+// feedbackWindow is how far back a viewer's likes, comments and hides count
+// towards ranking
+const feedbackWindow = 90 * 24 * time.Hour
+
+// RankingService orders posts with a ranking.Ranker, looking up what the ranker
+// needs to know about the viewer
+type RankingService struct {
+	rankingRepo repository.RankingRepository
+	ranker      *ranking.Ranker
+}
+
+func NewRankingService(rankingRepo repository.RankingRepository, ranker *ranking.Ranker) *RankingService {
+	return &RankingService{
+		rankingRepo: rankingRepo,
+		ranker:      ranker,
+	}
+}
+
+// Rank orders posts best first for viewerID, leaving out the posts they hid, and
+// returns each post's score breakdown in the same order. viewerID is uuid.Nil for
+// rankings that are the same for everyone. If the viewer's history can't be
+// read the posts are ranked without it.
+func (s *RankingService) Rank(ctx context.Context, viewerID uuid.UUID, posts []model.Post) ([]model.Post, []ranking.Explanation) {
+	rc := &ranking.Context{
+		Now:      time.Now(),
+		Affinity: map[string]float64{},
+		Hides:    map[string]int{},
+	}
+
+	if viewerID != uuid.Nil && len(posts) > 0 {
+		posts = s.withoutHidden(ctx, viewerID, posts)
+		s.loadViewer(ctx, viewerID, posts, rc)
+	}
+
+	byID := make(map[string]model.Post, len(posts))
+	candidates := make([]ranking.Candidate, len(posts))
+	for i, post := range posts {
+		byID[post.ID.String()] = post
+		candidates[i] = rankingCandidate(&post)
+	}
+
+	explanations := s.ranker.Rank(candidates, rc)
+	ranked := make([]model.Post, len(explanations))
+	for i, explanation := range explanations {
+		ranked[i] = byID[explanation.ID]
+	}
+	return ranked, explanations
+}
+
+// HidePost hides a post from the user's feeds and ranks its author lower for them
+func (s *RankingService) HidePost(ctx context.Context, userID uuid.UUID, post *model.Post) error {
+	return s.rankingRepo.HidePost(ctx, userID, post.ID, post.UserID)
+}
+
+// UnhidePost takes back a hide
+func (s *RankingService) UnhidePost(ctx context.Context, userID, postID uuid.UUID) error {
+	return s.rankingRepo.UnhidePost(ctx, userID, postID)
+}
+
+func (s *RankingService) withoutHidden(ctx context.Context, viewerID uuid.UUID, posts []model.Post) []model.Post {
+	postIDs := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	hidden, err := s.rankingRepo.HiddenPostIDs(ctx, viewerID, postIDs)
+	if err != nil {
+		log.Printf("Failed to get hidden posts for %s: %v", viewerID, err)
+		return posts
+	}
+	if len(hidden) == 0 {
+		return posts
+	}
+
+	visible := make([]model.Post, 0, len(posts))
+	for _, post := range posts {
+		if !hidden[post.ID] {
+			visible = append(visible, post)
+		}
+	}
+	return visible
+}
+
+func (s *RankingService) loadViewer(ctx context.Context, viewerID uuid.UUID, posts []model.Post, rc *ranking.Context) {
+	seen := make(map[uuid.UUID]bool, len(posts))
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		if post.UserID != viewerID && !seen[post.UserID] {
+			seen[post.UserID] = true
+			authorIDs = append(authorIDs, post.UserID)
+		}
+	}
+
+	since := rc.Now.Add(-feedbackWindow)
+
+	affinity, err := s.rankingRepo.AuthorAffinity(ctx, viewerID, authorIDs, since)
+	if err != nil {
+		log.Printf("Failed to get author affinity for %s: %v", viewerID, err)
+	}
+	for authorID, weight := range affinity {
+		rc.Affinity[authorID.String()] = weight
+	}
+
+	hides, err := s.rankingRepo.AuthorHides(ctx, viewerID, authorIDs, since)
+	if err != nil {
+		log.Printf("Failed to get hidden authors for %s: %v", viewerID, err)
+	}
+	for authorID, count := range hides {
+		rc.Hides[authorID.String()] = count
+	}
+}
+
+func rankingCandidate(post *model.Post) ranking.Candidate {
+	mediaType := ranking.MediaPhoto
+	switch {
+	case post.IsReels:
+		mediaType = ranking.MediaVideo
+	case post.IsCarousel:
+		mediaType = ranking.MediaCarousel
+	}
+
+	return ranking.Candidate{
+		ID:        post.ID.String(),
+		AuthorID:  post.UserID.String(),
+		CreatedAt: post.CreatedAt,
+		MediaType: mediaType,
+		Engagement: ranking.Engagement{
+			Likes:    post.LikesCount,
+			Comments: post.CommentsCount,
+			Shares:   post.SharesCount,
+			Saves:    post.SavesCount,
+			Views:    post.ViewsCount,
+		},
+	}
+}
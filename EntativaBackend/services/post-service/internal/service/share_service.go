@@ -15,17 +15,20 @@ import (
 type ShareService struct {
 	shareRepo repository.ShareRepository
 	postRepo  repository.PostRepository
+	audiences *AudienceClient
 	kafka     *kafka.Producer
 }
 
 func NewShareService(
 	shareRepo repository.ShareRepository,
 	postRepo repository.PostRepository,
+	audiences *AudienceClient,
 	kafka *kafka.Producer,
 ) *ShareService {
 	return &ShareService{
 		shareRepo: shareRepo,
 		postRepo:  postRepo,
+		audiences: audiences,
 		kafka:     kafka,
 	}
 }
@@ -38,8 +41,12 @@ func (s *ShareService) SharePost(ctx context.Context, originalPostID, userID uui
 		return nil, fmt.Errorf("original post not found")
 	}
 
-	// Check if post is shareable (respect privacy)
-	if originalPost.Privacy == model.PrivacyOnlyMe {
+	// The user must be able to see the post, and only public posts can be passed
+	// on to people outside their audience
+	if err := s.audiences.CheckCanView(ctx, userID, originalPost); err != nil {
+		return nil, fmt.Errorf("original post not found")
+	}
+	if originalPost.Audience != model.AudiencePublic && originalPost.UserID != userID {
 		return nil, fmt.Errorf("this post cannot be shared")
 	}
 
@@ -73,8 +80,16 @@ func (s *ShareService) GetUserShares(ctx context.Context, userID uuid.UUID, limi
 	return s.shareRepo.GetByUserID(ctx, userID, limit, offset)
 }
 
-// GetPostShares retrieves shares of a post
-func (s *ShareService) GetPostShares(ctx context.Context, postID uuid.UUID, limit, offset int) ([]model.Share, error) {
+// GetPostShares retrieves shares of a post, if viewerID may see it
+func (s *ShareService) GetPostShares(ctx context.Context, postID, viewerID uuid.UUID, limit, offset int) ([]model.Share, error) {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	if err := s.audiences.CheckCanView(ctx, viewerID, post); err != nil {
+		return nil, err
+	}
+
 	return s.shareRepo.GetByOriginalPostID(ctx, postID, limit, offset)
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ type TakesService struct {
 	trendRepo    repository.TrendRepository
 	redis        *redis.Client
 	kafka        *kafka.Producer
+	notifications *NotificationClient
 }
 
 func NewTakesService(
@@ -31,6 +33,7 @@ func NewTakesService(
 	trendRepo repository.TrendRepository,
 	redis *redis.Client,
 	kafka *kafka.Producer,
+	notifications *NotificationClient,
 ) *TakesService {
 	return &TakesService{
 		takesRepo:    takesRepo,
@@ -39,18 +42,12 @@ func NewTakesService(
 		trendRepo:    trendRepo,
 		redis:        redis,
 		kafka:        kafka,
+		notifications: notifications,
 	}
 }
 
 // CreateTake creates a new Take
 func (s *TakesService) CreateTake(ctx context.Context, userID uuid.UUID, req *model.CreateTakeRequest) (*model.Take, error) {
-	// Extract hashtags from caption
-	hashtags := s.extractHashtags(req.Caption)
-	if len(req.Hashtags) > 0 {
-		hashtags = append(hashtags, req.Hashtags...)
-		hashtags = s.deduplicateStrings(hashtags)
-	}
-
 	// Handle trend participation
 	var trendID *uuid.UUID
 	if req.TrendKeyword != nil && *req.TrendKeyword != "" {
@@ -61,20 +58,50 @@ func (s *TakesService) CreateTake(ctx context.Context, userID uuid.UUID, req *mo
 	}
 
 	// Create Take
-	take := &model.Take{
+	take := s.newTake(userID, req)
+	take.TrendID = trendID
+	take.Status = model.StatusPublished
+
+	if err := s.takesRepo.Create(ctx, take); err != nil {
+		return nil, fmt.Errorf("failed to create Take: %w", err)
+	}
+
+	// Increment template usage if used
+	if req.TemplateID != nil {
+		s.templateRepo.IncrementUsage(ctx, *req.TemplateID)
+	}
+
+	// Publish event
+	s.publishTakeCreatedEvent(take)
+
+	// Invalidate feed cache
+	s.invalidateFeedCache(ctx, userID)
+
+	return take, nil
+}
+
+// newTake builds a Take from a create request, for publishing now or saving as a draft
+func (s *TakesService) newTake(userID uuid.UUID, req *model.CreateTakeRequest) *model.Take {
+	// Extract hashtags from caption
+	hashtags := s.extractHashtags(req.Caption)
+	if len(req.Hashtags) > 0 {
+		hashtags = append(hashtags, req.Hashtags...)
+		hashtags = s.deduplicateStrings(hashtags)
+	}
+
+	return &model.Take{
 		ID:              uuid.New(),
 		UserID:          userID,
 		Caption:         req.Caption,
 		MediaID:         req.MediaID,
 		AudioTrackID:    req.AudioTrackID,
-		Duration:        0, // Will be set from media metadata
+		Duration:        0,  // Will be set from media metadata
 		ThumbnailURL:    "", // Will be set from media service
 		Hashtags:        hashtags,
 		FilterUsed:      req.FilterUsed,
 		Location:        req.Location,
 		TaggedUserIDs:   req.TaggedUserIDs,
 		TemplateID:      req.TemplateID,
-		TrendID:         trendID,
 		HasBTT:          false,
 		ViewsCount:      0,
 		LikesCount:      0,
@@ -88,23 +115,6 @@ func (s *TakesService) CreateTake(ctx context.Context, userID uuid.UUID, req *mo
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
-
-	if err := s.takesRepo.Create(ctx, take); err != nil {
-		return nil, fmt.Errorf("failed to create Take: %w", err)
-	}
-
-	// Increment template usage if used
-	if req.TemplateID != nil {
-		s.templateRepo.IncrementUsage(ctx, *req.TemplateID)
-	}
-
-	// Publish event
-	s.publishTakeCreatedEvent(take)
-
-	// Invalidate feed cache
-	s.invalidateFeedCache(ctx, userID)
-
-	return take, nil
 }
 
 // CreateBTT creates Behind-the-Takes content
@@ -346,6 +356,231 @@ func (s *TakesService) GetTemplates(ctx context.Context, category *string, limit
 	return s.templateRepo.GetTrending(ctx, limit)
 }
 
+// Drafts and scheduled Takes
+
+// CreateDraft saves a Take without publishing it, scheduling it if req has a time.
+// Its trend and template are only used once it is published.
+func (s *TakesService) CreateDraft(ctx context.Context, userID uuid.UUID, req *model.CreateTakeDraftRequest) (*model.Take, error) {
+	take := s.newTake(userID, &req.CreateTakeRequest)
+	take.TrendKeyword = req.TrendKeyword
+
+	take.Status = model.StatusDraft
+	if req.ScheduledAt != nil {
+		if err := model.ValidateSchedule(*req.ScheduledAt, time.Now()); err != nil {
+			return nil, err
+		}
+		take.Status = model.StatusScheduled
+		take.ScheduledAt = req.ScheduledAt
+	}
+
+	if err := s.takesRepo.Create(ctx, take); err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+
+	return take, nil
+}
+
+// GetDrafts retrieves the user's drafts, scheduled Takes and Takes that failed to publish
+func (s *TakesService) GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Take, error) {
+	return s.takesRepo.GetDrafts(ctx, userID, limit, offset)
+}
+
+// GetDraft retrieves one of the user's unpublished Takes
+func (s *TakesService) GetDraft(ctx context.Context, takeID, userID uuid.UUID) (*model.Take, error) {
+	return s.getOwnDraft(ctx, takeID, userID)
+}
+
+// UpdateDraft edits an unpublished Take
+func (s *TakesService) UpdateDraft(ctx context.Context, takeID, userID uuid.UUID, req *model.UpdateTakeDraftRequest) (*model.Take, error) {
+	take, err := s.getOwnDraft(ctx, takeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Caption != nil {
+		take.Caption = *req.Caption
+		take.Hashtags = s.extractHashtags(take.Caption)
+		if req.Hashtags != nil {
+			take.Hashtags = append(take.Hashtags, *req.Hashtags...)
+			take.Hashtags = s.deduplicateStrings(take.Hashtags)
+		}
+	}
+	if req.Location != nil {
+		take.Location = req.Location
+	}
+	if req.TrendKeyword != nil {
+		take.TrendKeyword = req.TrendKeyword
+	}
+	if req.CommentsEnabled != nil {
+		take.CommentsEnabled = *req.CommentsEnabled
+	}
+	if req.RemixEnabled != nil {
+		take.RemixEnabled = *req.RemixEnabled
+	}
+	take.UpdatedAt = time.Now()
+
+	if err := s.takesRepo.Update(ctx, take); err != nil {
+		return nil, fmt.Errorf("failed to update draft: %w", err)
+	}
+
+	return take, nil
+}
+
+// DeleteDraft deletes an unpublished Take, cancelling it if it was scheduled
+func (s *TakesService) DeleteDraft(ctx context.Context, takeID, userID uuid.UUID) error {
+	if _, err := s.getOwnDraft(ctx, takeID, userID); err != nil {
+		return err
+	}
+
+	if err := s.takesRepo.Delete(ctx, takeID); err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	return nil
+}
+
+// ScheduleTake schedules a draft for scheduledAt, or moves a scheduled Take to it.
+// A Take that failed to publish is tried again then.
+func (s *TakesService) ScheduleTake(ctx context.Context, takeID, userID uuid.UUID, scheduledAt time.Time) (*model.Take, error) {
+	if _, err := s.getOwnDraft(ctx, takeID, userID); err != nil {
+		return nil, err
+	}
+	if err := model.ValidateSchedule(scheduledAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.takesRepo.SetSchedule(ctx, takeID, model.StatusScheduled, &scheduledAt); err != nil {
+		return nil, err
+	}
+
+	return s.takesRepo.GetDraftByID(ctx, takeID)
+}
+
+// CancelSchedule turns a scheduled Take back into a draft
+func (s *TakesService) CancelSchedule(ctx context.Context, takeID, userID uuid.UUID) (*model.Take, error) {
+	if _, err := s.getOwnDraft(ctx, takeID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.takesRepo.SetSchedule(ctx, takeID, model.StatusDraft, nil); err != nil {
+		return nil, err
+	}
+
+	return s.takesRepo.GetDraftByID(ctx, takeID)
+}
+
+// PublishDraft publishes an unpublished Take now
+func (s *TakesService) PublishDraft(ctx context.Context, takeID, userID uuid.UUID) (*model.Take, error) {
+	take, err := s.getOwnDraft(ctx, takeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := s.publish(ctx, take)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish Take: %w", err)
+	}
+	if !published {
+		// Published by the scheduler, rescheduled or deleted since it was read
+		return nil, model.ErrDraftNotFound
+	}
+
+	return take, nil
+}
+
+// PublishDue publishes up to limit scheduled Takes that are due, returning how
+// many it published. As with PostService.PublishDue, each Take is published by
+// exactly one instance, and one that keeps failing is marked failed after
+// publishGiveUpAfter and its author told.
+func (s *TakesService) PublishDue(ctx context.Context, limit int) int {
+	takes, err := s.takesRepo.GetDueScheduled(ctx, limit)
+	if err != nil {
+		log.Printf("Failed to get due scheduled Takes: %v", err)
+		return 0
+	}
+
+	count := 0
+	for i := range takes {
+		take := &takes[i]
+
+		published, err := s.publish(ctx, take)
+		if err != nil {
+			log.Printf("Failed to publish scheduled Take %s: %v", take.ID, err)
+			if time.Since(*take.ScheduledAt) > publishGiveUpAfter {
+				s.failScheduled(ctx, take, "the Take could not be published")
+			}
+			continue
+		}
+		if published {
+			count++
+		}
+	}
+
+	return count
+}
+
+// publish publishes a draft or scheduled Take as of now, joining its trend and
+// counting its template as CreateTake does. It returns false, doing nothing, if
+// the Take was published elsewhere or changed since it was read.
+func (s *TakesService) publish(ctx context.Context, take *model.Take) (bool, error) {
+	now := time.Now()
+	published, err := s.takesRepo.MarkPublished(ctx, take, now)
+	if err != nil || !published {
+		return false, err
+	}
+
+	take.Status = model.StatusPublished
+	take.PublishError = nil
+	take.CreatedAt = now
+	take.UpdatedAt = now
+
+	// Handle trend participation, now that the Take can be seen
+	if take.TrendKeyword != nil && *take.TrendKeyword != "" {
+		trend, err := s.JoinOrCreateTrend(ctx, take.UserID, *take.TrendKeyword, take.ID)
+		if err == nil && trend != nil {
+			take.TrendID = &trend.ID
+			if err := s.takesRepo.Update(ctx, take); err != nil {
+				log.Printf("Failed to record trend of Take %s: %v", take.ID, err)
+			}
+		}
+	}
+
+	// Increment template usage if used
+	if take.TemplateID != nil {
+		s.templateRepo.IncrementUsage(ctx, *take.TemplateID)
+	}
+
+	s.publishTakeCreatedEvent(take)
+	s.invalidateFeedCache(ctx, take.UserID)
+
+	return true, nil
+}
+
+// failScheduled marks a scheduled Take failed and tells its author, unless it
+// changed since it was read
+func (s *TakesService) failScheduled(ctx context.Context, take *model.Take, reason string) {
+	failed, err := s.takesRepo.MarkPublishFailed(ctx, take, reason)
+	if err != nil {
+		log.Printf("Failed to mark scheduled Take %s failed: %v", take.ID, err)
+		return
+	}
+	if failed {
+		s.publishTakePublishFailedEvent(take, reason)
+		s.notifications.TakePublishFailed(ctx, take.UserID, take.ID, reason)
+	}
+}
+
+func (s *TakesService) getOwnDraft(ctx context.Context, takeID, userID uuid.UUID) (*model.Take, error) {
+	take, err := s.takesRepo.GetDraftByID(ctx, takeID)
+	if err != nil {
+		return nil, err
+	}
+	if take.UserID != userID {
+		return nil, model.ErrDraftNotFound
+	}
+	return take, nil
+}
+
 // Helper methods
 
 func (s *TakesService) extractHashtags(caption string) []string {
@@ -514,3 +749,22 @@ func (s *TakesService) publishTrendCreatedEvent(trend *model.TakeTrend) {
 
 	s.kafka.PublishEvent(context.Background(), "takes-events", trend.ID.String(), event)
 }
+
+// publishTakePublishFailedEvent announces that a scheduled Take could
+// not be published
+func (s *TakesService) publishTakePublishFailedEvent(take *model.Take, reason string) {
+	if s.kafka == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"event_type":   "take.publish_failed",
+		"take_id":      take.ID.String(),
+		"user_id":      take.UserID.String(),
+		"scheduled_at": take.ScheduledAt,
+		"reason":       reason,
+		"failed_at":    time.Now(),
+	}
+
+	s.kafka.PublishEvent(context.Background(), "takes-events", take.ID.String(), event)
+}
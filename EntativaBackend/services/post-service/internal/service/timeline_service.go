@@ -0,0 +1,465 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"socialink/post-service/internal/model"
+	"socialink/post-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// fanOutBatchSize is how many timelines one Redis round trip writes to
+	fanOutBatchSize = 500
+
+	// fanOutTimeout bounds delivering one post to every timeline
+	fanOutTimeout = 30 * time.Second
+)
+
+// TimelineConfig tunes home timelines
+type TimelineConfig struct {
+	// CelebrityThreshold is the most friends an author may have for their posts
+	// to be pushed into timelines; posts by bigger accounts are pulled in on read
+	CelebrityThreshold int
+	// MaxLength is how many posts each timeline keeps; older pages are read from the database
+	MaxLength int
+	// TTL is how long an unread timeline is kept before it is rebuilt on the next read
+	TTL time.Duration
+}
+
+// TimelineService keeps a home timeline per user in Redis, fan-out-on-write: a new
+// post is pushed into the timelines of the author's friends as it is created.
+// Authors with more than CelebrityThreshold friends are fanned out on read instead.
+// Their posts are marked as not fanned out and merged into every timeline read
+// from the database.
+//
+// A timeline is a sorted set of post IDs scored by creation time, at
+// "timeline:{user_id}". Next to it, "timeline:{user_id}:sources" holds the authors
+// it was built from. Only timelines with a sources set are written to; others are
+// built on their next read. When the user gains a friend, that friend's recent
+// posts are added on the next read. Posts by people who are no longer friends, and
+// deleted posts, are skipped as the timeline is read.
+//
+// Without Redis every timeline is read straight from the database.
+type TimelineService struct {
+	postRepo repository.PostRepository
+	graph    *GraphClient
+	redis    *redis.Client
+	cfg      TimelineConfig
+}
+
+func NewTimelineService(postRepo repository.PostRepository, graph *GraphClient, redis *redis.Client, cfg TimelineConfig) *TimelineService {
+	return &TimelineService{
+		postRepo: postRepo,
+		graph:    graph,
+		redis:    redis,
+		cfg:      cfg,
+	}
+}
+
+// Audience decides how a post authorID is about to create reaches timelines. If
+// fanOut is true it should be pushed to the returned users with Publish; otherwise
+// it is left to be pulled in on read.
+func (s *TimelineService) Audience(ctx context.Context, authorID uuid.UUID) ([]uuid.UUID, bool) {
+	if s.redis == nil {
+		// Timelines are read from the database, which has every post
+		return nil, true
+	}
+
+	audience, ok, err := s.graph.Audience(ctx, authorID, s.cfg.CelebrityThreshold)
+	if err != nil {
+		log.Printf("Failed to get audience of %s, leaving their post to fan-out-on-read: %v", authorID, err)
+		return nil, false
+	}
+	return audience, ok
+}
+
+// Publish pushes a post just created into its author's timeline and those of
+// audience, in the background. If that fails the post is switched to
+// fan-out-on-read, so no timeline misses it.
+func (s *TimelineService) Publish(post *model.Post, audience []uuid.UUID) {
+	if s.redis == nil || !post.FannedOut {
+		return
+	}
+
+	recipients := make([]uuid.UUID, 0, len(audience)+1)
+	recipients = append(recipients, post.UserID)
+	recipients = append(recipients, audience...)
+
+	go func(post model.Post) {
+		ctx, cancel := context.WithTimeout(context.Background(), fanOutTimeout)
+		defer cancel()
+
+		if err := s.fanOut(ctx, &post, recipients); err != nil {
+			log.Printf("Failed to fan out post %s, switching it to fan-out-on-read: %v", post.ID, err)
+			if err := s.postRepo.SetFannedOut(ctx, post.ID, false); err != nil {
+				log.Printf("Failed to switch post %s to fan-out-on-read: %v", post.ID, err)
+			}
+		}
+	}(*post)
+}
+
+// Remove takes a deleted post out of the timelines it was pushed to, in the
+// background. Timelines skip deleted posts anyway, so failures are only logged.
+func (s *TimelineService) Remove(post *model.Post) {
+	if s.redis == nil {
+		return
+	}
+
+	go func(post model.Post) {
+		ctx, cancel := context.WithTimeout(context.Background(), fanOutTimeout)
+		defer cancel()
+
+		recipients := []uuid.UUID{post.UserID}
+		audience, ok, err := s.graph.Audience(ctx, post.UserID, s.cfg.CelebrityThreshold)
+		if err != nil {
+			log.Printf("Failed to get audience of %s to remove post %s: %v", post.UserID, post.ID, err)
+		}
+		if ok {
+			recipients = append(recipients, audience...)
+		}
+
+		for start := 0; start < len(recipients); start += fanOutBatchSize {
+			batch := recipients[start:min(start+fanOutBatchSize, len(recipients))]
+			_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, userID := range batch {
+					pipe.ZRem(ctx, timelineKey(userID), post.ID.String())
+				}
+				return nil
+			})
+			if err != nil {
+				log.Printf("Failed to remove post %s from timelines: %v", post.ID, err)
+				return
+			}
+		}
+	}(*post)
+}
+
+// Read returns a page of userID's home timeline after cursor (nil for the first
+// page), newest first, and the cursor for the next page if there is one
+func (s *TimelineService) Read(ctx context.Context, userID uuid.UUID, cursor *model.FeedCursor, limit int) ([]model.Post, *model.FeedCursor, error) {
+	sources, err := s.graph.Sources(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get timeline sources: %w", err)
+	}
+	authors := append([]uuid.UUID{userID}, sources...)
+
+	// One extra post tells whether there is another page
+	want := limit + 1
+
+	var posts []model.Post
+	if s.redis != nil {
+		posts, err = s.readTimeline(ctx, userID, authors, cursor, want)
+		if err != nil {
+			log.Printf("Failed to read timeline of %s, reading from the database: %v", userID, err)
+		}
+	}
+	if s.redis == nil || err != nil {
+		posts, err = s.postRepo.GetByAuthors(ctx, repository.AuthorPostsQuery{
+			AuthorIDs: authors,
+			Before:    cursor,
+			Limit:     want,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var next *model.FeedCursor
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = model.NewFeedCursor(&posts[limit-1])
+	}
+	return posts, next, nil
+}
+
+// readTimeline merges the fanned-out posts in userID's timeline with the posts
+// pulled in on read
+func (s *TimelineService) readTimeline(ctx context.Context, userID uuid.UUID, authors []uuid.UUID, cursor *model.FeedCursor, want int) ([]model.Post, error) {
+	if err := s.syncTimeline(ctx, userID, authors); err != nil {
+		return nil, err
+	}
+
+	pushed, err := s.scanTimeline(ctx, userID, authors, cursor, want)
+	if err != nil {
+		return nil, err
+	}
+
+	notFannedOut := false
+	pulled, err := s.postRepo.GetByAuthors(ctx, repository.AuthorPostsQuery{
+		AuthorIDs: authors,
+		Before:    cursor,
+		FannedOut: &notFannedOut,
+		Limit:     want,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergePosts(pushed, pulled), nil
+}
+
+// syncTimeline brings the authors userID's timeline is built from up to date,
+// adding the recent posts of any new ones. A timeline that doesn't exist yet has
+// no authors, so it is built from all of them. The new authors are recorded
+// before their posts are read, so a post created meanwhile is either read or
+// fanned out to this timeline.
+func (s *TimelineService) syncTimeline(ctx context.Context, userID uuid.UUID, authors []uuid.UUID) error {
+	key, sourcesKey := timelineKey(userID), timelineSourcesKey(userID)
+
+	stored, err := s.redis.SMembers(ctx, sourcesKey).Result()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(stored))
+	for _, id := range stored {
+		have[id] = true
+	}
+
+	current := make(map[string]bool, len(authors))
+	var added []uuid.UUID
+	var addedMembers []interface{}
+	for _, id := range authors {
+		current[id.String()] = true
+		if !have[id.String()] {
+			added = append(added, id)
+			addedMembers = append(addedMembers, id.String())
+		}
+	}
+	var removedMembers []interface{}
+	for _, id := range stored {
+		if !current[id] {
+			removedMembers = append(removedMembers, id)
+		}
+	}
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(addedMembers) > 0 {
+			pipe.SAdd(ctx, sourcesKey, addedMembers...)
+		}
+		if len(removedMembers) > 0 {
+			pipe.SRem(ctx, sourcesKey, removedMembers...)
+		}
+		pipe.Expire(ctx, sourcesKey, s.cfg.TTL)
+		pipe.Expire(ctx, key, s.cfg.TTL)
+		return nil
+	})
+	if err != nil || len(added) == 0 {
+		return err
+	}
+
+	fannedOut := true
+	posts, err := s.postRepo.GetByAuthors(ctx, repository.AuthorPostsQuery{
+		AuthorIDs: added,
+		FannedOut: &fannedOut,
+		Limit:     s.cfg.MaxLength,
+	})
+	if err == nil && len(posts) > 0 {
+		members := make([]redis.Z, 0, len(posts))
+		for _, post := range posts {
+			members = append(members, timelineMember(&post))
+		}
+		_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZAdd(ctx, key, members...)
+			pipe.ZRemRangeByRank(ctx, key, 0, int64(-s.cfg.MaxLength-1))
+			pipe.Expire(ctx, key, s.cfg.TTL)
+			return nil
+		})
+	}
+	if err != nil {
+		// Forget the new authors so the next read tries again
+		s.redis.SRem(ctx, sourcesKey, addedMembers...)
+		return err
+	}
+	return nil
+}
+
+// scanTimeline reads up to want posts from userID's timeline after cursor,
+// skipping deleted posts and posts by anyone not in authors. A timeline that has
+// been trimmed to MaxLength and runs out goes on in the database.
+func (s *TimelineService) scanTimeline(ctx context.Context, userID uuid.UUID, authors []uuid.UUID, cursor *model.FeedCursor, want int) ([]model.Post, error) {
+	key := timelineKey(userID)
+
+	allowed := make(map[uuid.UUID]bool, len(authors))
+	for _, id := range authors {
+		allowed[id] = true
+	}
+
+	var posts []model.Post
+	position := cursor
+	for len(posts) < want {
+		entries, err := s.timelinePage(ctx, key, position, want)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]uuid.UUID, 0, len(entries))
+		for _, entry := range entries {
+			ids = append(ids, entry.PostID)
+		}
+		found, err := s.postRepo.GetByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uuid.UUID]model.Post, len(found))
+		for _, post := range found {
+			byID[post.ID] = post
+		}
+
+		for _, entry := range entries {
+			if post, ok := byID[entry.PostID]; ok && allowed[post.UserID] {
+				posts = append(posts, post)
+			}
+		}
+		if len(entries) > 0 {
+			position = entries[len(entries)-1]
+		}
+
+		if len(entries) < want {
+			break
+		}
+	}
+	if len(posts) >= want {
+		return posts[:want], nil
+	}
+
+	length, err := s.redis.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if length < int64(s.cfg.MaxLength) {
+		return posts, nil
+	}
+
+	fannedOut := true
+	older, err := s.postRepo.GetByAuthors(ctx, repository.AuthorPostsQuery{
+		AuthorIDs: authors,
+		Before:    position,
+		FannedOut: &fannedOut,
+		Limit:     want - len(posts),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(posts, older...), nil
+}
+
+// timelinePage reads up to count entries from the timeline at key after cursor.
+// Posts created at the same moment share a score; Redis orders those by member,
+// which is the post ID, the same way feeds break ties.
+func (s *TimelineService) timelinePage(ctx context.Context, key string, cursor *model.FeedCursor, count int) ([]*model.FeedCursor, error) {
+	max := "+inf"
+	var skip int64
+	if cursor != nil {
+		max = strconv.FormatInt(cursor.CreatedAt.UnixMicro(), 10)
+
+		// Skip the posts sharing the cursor's score that come before it
+		ties, err := s.redis.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: max, Max: max}).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range ties {
+			if member >= cursor.PostID.String() {
+				skip++
+			}
+		}
+	}
+
+	members, err := s.redis.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:    "-inf",
+		Max:    max,
+		Offset: skip,
+		Count:  int64(count),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*model.FeedCursor, 0, len(members))
+	for _, member := range members {
+		postID, err := uuid.Parse(fmt.Sprint(member.Member))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &model.FeedCursor{
+			CreatedAt: time.UnixMicro(int64(member.Score)),
+			PostID:    postID,
+		})
+	}
+	return entries, nil
+}
+
+// fanOut adds post to the recipients' timelines that have been built
+func (s *TimelineService) fanOut(ctx context.Context, post *model.Post, recipients []uuid.UUID) error {
+	member := timelineMember(post)
+
+	for start := 0; start < len(recipients); start += fanOutBatchSize {
+		batch := recipients[start:min(start+fanOutBatchSize, len(recipients))]
+
+		exists := make([]*redis.IntCmd, len(batch))
+		_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, userID := range batch {
+				exists[i] = pipe.Exists(ctx, timelineSourcesKey(userID))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, userID := range batch {
+				if exists[i].Val() == 0 {
+					continue
+				}
+				key := timelineKey(userID)
+				pipe.ZAdd(ctx, key, member)
+				pipe.ZRemRangeByRank(ctx, key, 0, int64(-s.cfg.MaxLength-1))
+				pipe.Expire(ctx, key, s.cfg.TTL)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePosts combines two pages of posts into one in feed order, without duplicates
+func mergePosts(a, b []model.Post) []model.Post {
+	merged := make([]model.Post, 0, len(a)+len(b))
+	seen := make(map[uuid.UUID]bool, len(a)+len(b))
+	for _, posts := range [][]model.Post{a, b} {
+		for _, post := range posts {
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				merged = append(merged, post)
+			}
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return model.NewFeedCursor(&merged[i]).Precedes(&merged[j])
+	})
+	return merged
+}
+
+func timelineMember(post *model.Post) redis.Z {
+	return redis.Z{Score: float64(post.CreatedAt.UnixMicro()), Member: post.ID.String()}
+}
+
+func timelineKey(userID uuid.UUID) string {
+	return fmt.Sprintf("timeline:%s", userID.String())
+}
+
+func timelineSourcesKey(userID uuid.UUID) string {
+	return fmt.Sprintf("timeline:%s:sources", userID.String())
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"socialink/post-service/internal/model"
+	"socialink/post-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userDeletionRequestedEvent = "user.deletion_requested"
+
+	// purgeServiceName is how the user service knows this service in a purge
+	purgeServiceName = "post"
+)
+
+// UserPurgeService takes part in account deletion: when the user service asks,
+// it removes everything the deleted user left here and reports back. The user
+// service repeats the request until it hears that the purge succeeded.
+type UserPurgeService struct {
+	purgeRepo      repository.UserPurgeRepository
+	redis          *redis.Client
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewUserPurgeService(purgeRepo repository.UserPurgeRepository, redis *redis.Client, userServiceURL, internalToken string) *UserPurgeService {
+	return &UserPurgeService{
+		purgeRepo:      purgeRepo,
+		redis:          redis,
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HandleUserEvent purges a user's data on a user.deletion_requested event naming this
+// service. Other user events are ignored.
+func (s *UserPurgeService) HandleUserEvent(ctx context.Context, key, value []byte) error {
+	var event model.UserDeletionRequestedEvent
+	if err := json.Unmarshal(value, &event); err != nil || event.EventType != userDeletionRequestedEvent {
+		return nil
+	}
+	if !containsString(event.Services, purgeServiceName) {
+		return nil
+	}
+
+	result, purgeErr := s.purgeRepo.PurgeUser(ctx, event.UserID)
+	if purgeErr == nil {
+		s.invalidateCaches(ctx, event.UserID, result.PostIDs)
+		log.Printf("Purged user %s: %d posts, %d comments, %d likes, %d saves, %d shares, %d takes",
+			event.UserID, result.Posts, result.Comments, result.Likes, result.Saves, result.Shares, result.Takes)
+	}
+
+	if err := s.reportPurge(ctx, event.DeletionID, purgeErr); err != nil {
+		return fmt.Errorf("failed to report purge of user %s: %w", event.UserID, err)
+	}
+	return purgeErr
+}
+
+// reportPurge tells the user service whether the purge succeeded
+func (s *UserPurgeService) reportPurge(ctx context.Context, deletionID uuid.UUID, purgeErr error) error {
+	report := map[string]string{"status": "completed"}
+	if purgeErr != nil {
+		report = map[string]string{"status": "failed", "error": purgeErr.Error()}
+	}
+	body, _ := json.Marshal(report)
+
+	endpoint := fmt.Sprintf("%s/api/v1/internal/deletions/%s/services/%s", s.userServiceURL, deletionID, purgeServiceName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", s.internalToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// invalidateCaches drops cached copies of the purged posts and the user's timeline
+func (s *UserPurgeService) invalidateCaches(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) {
+	if s.redis == nil {
+		return
+	}
+
+	keys := []string{timelineKey(userID), timelineSourcesKey(userID)}
+	for _, postID := range postIDs {
+		keys = append(keys, fmt.Sprintf("post:%s", postID.String()))
+	}
+	s.redis.Del(ctx, keys...)
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+-- Posts users chose to hide from their feeds. Hiding a post drops it from the
+-- viewer's feeds and ranks the author's other posts lower for them.
+CREATE TABLE IF NOT EXISTS post_hides (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- Ranking counts a viewer's recent hides per author
+CREATE INDEX IF NOT EXISTS idx_post_hides_user_author ON post_hides(user_id, author_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_post_hides_author ON post_hides(author_id);
+
+COMMENT ON TABLE post_hides IS 'Posts hidden from feeds by users; negative feedback for ranking';
@@ -0,0 +1,11 @@
+-- Who may see each post: public, friends, only_me or one of the author's
+-- audience lists as list:<list id>
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS audience TEXT NOT NULL DEFAULT 'public';
+
+ALTER TABLE posts ADD CONSTRAINT posts_audience_check CHECK (
+    audience IN ('public', 'friends', 'only_me') OR audience LIKE 'list:%'
+);
+
+-- Explore, hashtag and reels listings only read public posts
+CREATE INDEX IF NOT EXISTS idx_posts_public_created_at ON posts(created_at DESC)
+    WHERE audience = 'public' AND deleted_at IS NULL;
@@ -0,0 +1,38 @@
+-- Drafts and scheduled posts and Takes. Rows that already exist are published.
+-- Only published rows are shown to anyone but the author.
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS publish_error TEXT;
+
+ALTER TABLE posts ADD CONSTRAINT posts_status_check CHECK (
+    status IN ('draft', 'scheduled', 'published', 'failed')
+);
+ALTER TABLE posts ADD CONSTRAINT posts_scheduled_at_check CHECK (
+    status <> 'scheduled' OR scheduled_at IS NOT NULL
+);
+
+ALTER TABLE takes
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS publish_error TEXT,
+    ADD COLUMN IF NOT EXISTS trend_keyword TEXT;
+
+ALTER TABLE takes ADD CONSTRAINT takes_status_check CHECK (
+    status IN ('draft', 'scheduled', 'published', 'failed')
+);
+ALTER TABLE takes ADD CONSTRAINT takes_scheduled_at_check CHECK (
+    status <> 'scheduled' OR scheduled_at IS NOT NULL
+);
+
+-- The scheduler looks for scheduled rows that are due
+CREATE INDEX IF NOT EXISTS idx_posts_due ON posts(scheduled_at)
+    WHERE status = 'scheduled' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_takes_due ON takes(scheduled_at)
+    WHERE status = 'scheduled' AND deleted_at IS NULL;
+
+-- Authors list their drafts
+CREATE INDEX IF NOT EXISTS idx_posts_user_drafts ON posts(user_id, updated_at DESC)
+    WHERE status <> 'published' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_takes_user_drafts ON takes(user_id, updated_at DESC)
+    WHERE status <> 'published' AND deleted_at IS NULL;
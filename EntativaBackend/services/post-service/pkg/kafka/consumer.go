@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Handler processes one message. Returning an error logs it; the message is
+// still committed, so handlers must rely on the producer to redeliver work
+// that has to be retried.
+type Handler func(ctx context.Context, key, value []byte) error
+
+// Chain hands every message to each handler in turn, returning the first error
+func Chain(handlers ...Handler) Handler {
+	return func(ctx context.Context, key, value []byte) error {
+		var firstErr error
+		for _, handler := range handlers {
+			if err := handler(ctx, key, value); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+type Consumer struct {
+	reader *kafka.Reader
+}
+
+func NewConsumer(brokers []string, groupID, topic string) *Consumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  brokers,
+		GroupID:  groupID,
+		Topic:    topic,
+		MinBytes: 1,
+		MaxBytes: 10e6,
+	})
+
+	return &Consumer{
+		reader: reader,
+	}
+}
+
+// Run hands messages to handler until ctx is cancelled
+func (c *Consumer) Run(ctx context.Context, handler Handler) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to fetch message from %s: %v", c.reader.Config().Topic, err)
+			continue
+		}
+
+		if err := handler(ctx, msg.Key, msg.Value); err != nil {
+			log.Printf("Failed to handle message from %s at offset %d: %v", msg.Topic, msg.Offset, err)
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to commit message from %s: %v", msg.Topic, err)
+		}
+	}
+}
+
+func (c *Consumer) Close() error {
+	if c.reader != nil {
+		return c.reader.Close()
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package ranking orders posts for feeds by adding up weighted signals. Scoring
+// depends only on its inputs, the current time included, so the same candidates
+// always come out in the same order.
+package ranking
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaType is the kind of media a post leads with
+type MediaType string
+
+const (
+	MediaPhoto    MediaType = "photo"
+	MediaCarousel MediaType = "carousel"
+	MediaVideo    MediaType = "video"
+)
+
+// Engagement counts the interactions a post has had
+type Engagement struct {
+	Likes    int64
+	Comments int64
+	Shares   int64
+	Saves    int64
+	Views    int64
+}
+
+// Candidate is a post to be ranked
+type Candidate struct {
+	ID         string
+	AuthorID   string
+	CreatedAt  time.Time
+	MediaType  MediaType
+	Engagement Engagement
+}
+
+// Context is what candidates are ranked against: the time, and what is known
+// about the viewer. The viewer maps are empty for rankings that are the same for
+// everyone.
+type Context struct {
+	Now time.Time
+
+	// Affinity is how much the viewer has interacted with each author lately, by author ID
+	Affinity map[string]float64
+
+	// Hides is how many of each author's posts the viewer has hidden lately, by author ID
+	Hides map[string]int
+}
+
+// Signal scores one aspect of a candidate. Scores should stay within [-1, 1] so
+// weights mean the same thing across signals.
+type Signal interface {
+	// Name identifies the signal in explanations and weight settings
+	Name() string
+	Score(c *Candidate, rc *Context) float64
+}
+
+// Weighted is a signal and what its score is multiplied by
+type Weighted struct {
+	Signal Signal
+	Weight float64
+}
+
+// Contribution is one signal's part in a candidate's score
+type Contribution struct {
+	Signal string  `json:"signal"`
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+}
+
+// Explanation breaks a candidate's score down by signal
+type Explanation struct {
+	ID            string         `json:"post_id"`
+	Score         float64        `json:"score"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+// Ranker scores candidates as the weighted sum of its signals
+type Ranker struct {
+	signals []Weighted
+}
+
+// New creates a ranker from signals, which must have distinct names
+func New(signals ...Weighted) *Ranker {
+	return &Ranker{signals: append([]Weighted(nil), signals...)}
+}
+
+// WithWeights returns a copy of the ranker with the weights of the named signals
+// replaced. A name no signal has is an error, so a typo in a setting isn't
+// silently ignored.
+func (r *Ranker) WithWeights(weights map[string]float64) (*Ranker, error) {
+	signals := append([]Weighted(nil), r.signals...)
+
+	for name, weight := range weights {
+		found := false
+		for i := range signals {
+			if signals[i].Signal.Name() == name {
+				signals[i].Weight = weight
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown ranking signal %q", name)
+		}
+	}
+
+	return &Ranker{signals: signals}, nil
+}
+
+// Score returns a candidate's score
+func (r *Ranker) Score(c *Candidate, rc *Context) float64 {
+	return r.Explain(c, rc).Score
+}
+
+// Explain scores a candidate and says how much each signal added
+func (r *Ranker) Explain(c *Candidate, rc *Context) Explanation {
+	explanation := Explanation{
+		ID:            c.ID,
+		Contributions: make([]Contribution, 0, len(r.signals)),
+	}
+
+	for _, s := range r.signals {
+		value := s.Signal.Score(c, rc)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			value = 0
+		}
+
+		score := value * s.Weight
+		explanation.Contributions = append(explanation.Contributions, Contribution{
+			Signal: s.Signal.Name(),
+			Value:  value,
+			Weight: s.Weight,
+			Score:  score,
+		})
+		explanation.Score += score
+	}
+
+	return explanation
+}
+
+// Rank returns the explanations of candidates, best first. Equal scores go newest
+// first, then by descending ID, so the order never depends on the input order.
+func (r *Ranker) Rank(candidates []Candidate, rc *Context) []Explanation {
+	type scored struct {
+		candidate   *Candidate
+		explanation Explanation
+	}
+
+	ranked := make([]scored, len(candidates))
+	for i := range candidates {
+		ranked[i] = scored{
+			candidate:   &candidates[i],
+			explanation: r.Explain(&candidates[i], rc),
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.explanation.Score != b.explanation.Score {
+			return a.explanation.Score > b.explanation.Score
+		}
+		if !a.candidate.CreatedAt.Equal(b.candidate.CreatedAt) {
+			return a.candidate.CreatedAt.After(b.candidate.CreatedAt)
+		}
+		return a.candidate.ID > b.candidate.ID
+	})
+
+	explanations := make([]Explanation, len(ranked))
+	for i, s := range ranked {
+		explanations[i] = s.explanation
+	}
+	return explanations
+}
+
+// ParseWeights reads signal weights written as "recency=1,affinity=2.5"
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := map[string]float64{}
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid ranking weight %q: want name=weight", pair)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid ranking weight %q: not a number", pair)
+		}
+		weights[strings.TrimSpace(name)] = weight
+	}
+
+	return weights, nil
+}
@@ -0,0 +1,305 @@
+package ranking
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func candidate(id, authorID string, age time.Duration) Candidate {
+	return Candidate{
+		ID:        id,
+		AuthorID:  authorID,
+		CreatedAt: now.Add(-age),
+		MediaType: MediaPhoto,
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecencyHalvesEveryHalfLife(t *testing.T) {
+	s := Recency{HalfLife: 24 * time.Hour}
+	rc := &Context{Now: now}
+
+	tests := []struct {
+		name string
+		age  time.Duration
+		want float64
+	}{
+		{"Just posted", 0, 1},
+		{"One half-life", 24 * time.Hour, 0.5},
+		{"Two half-lives", 48 * time.Hour, 0.25},
+		{"From the future", -time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := candidate("p", "a", tt.age)
+			if got := s.Score(&c, rc); !near(got, tt.want) {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffinitySaturates(t *testing.T) {
+	s := Affinity{Saturation: 5}
+	rc := &Context{Now: now, Affinity: map[string]float64{"close": 5, "closer": 50}}
+
+	stranger := candidate("p1", "stranger", 0)
+	close := candidate("p2", "close", 0)
+	closer := candidate("p3", "closer", 0)
+
+	if got := s.Score(&stranger, rc); got != 0 {
+		t.Errorf("Stranger: got %v, want 0", got)
+	}
+	if got := s.Score(&close, rc); !near(got, 0.5) {
+		t.Errorf("At saturation: got %v, want 0.5", got)
+	}
+	if got := s.Score(&closer, rc); got <= 0.5 || got >= 1 {
+		t.Errorf("Past saturation: got %v, want between 0.5 and 1", got)
+	}
+}
+
+func TestVelocityFavoursFasterEngagement(t *testing.T) {
+	s := Velocity{Weights: DefaultEngagementWeights, Scale: 50}
+	rc := &Context{Now: now}
+
+	quiet := candidate("p1", "a", 2*time.Hour)
+	fast := candidate("p2", "a", 2*time.Hour)
+	fast.Engagement = Engagement{Likes: 100, Comments: 10}
+	slow := candidate("p3", "a", 20*time.Hour)
+	slow.Engagement = Engagement{Likes: 100, Comments: 10}
+
+	if got := s.Score(&quiet, rc); got != 0 {
+		t.Errorf("No engagement: got %v, want 0", got)
+	}
+	if s.Score(&fast, rc) <= s.Score(&slow, rc) {
+		t.Errorf("Same engagement in less time should score higher")
+	}
+
+	// 120 weighted interactions in under an hour count as 120 an hour, not more
+	young := candidate("p4", "a", time.Minute)
+	young.Engagement = Engagement{Likes: 100, Comments: 10}
+	if got, want := s.Score(&young, rc), 120.0/170.0; !near(got, want) {
+		t.Errorf("Under an hour old: got %v, want %v", got, want)
+	}
+}
+
+func TestEngagementWeightsTotal(t *testing.T) {
+	e := Engagement{Likes: 10, Comments: 2, Shares: 1, Saves: 3, Views: 100}
+	if got := DefaultEngagementWeights.Total(e); !near(got, 10+4+3+6+10) {
+		t.Errorf("Got %v, want 33", got)
+	}
+}
+
+func TestNegativeFeedbackPushesDown(t *testing.T) {
+	s := NegativeFeedback{Saturation: 1}
+	rc := &Context{Now: now, Hides: map[string]int{"hidden": 1}}
+
+	hidden := candidate("p1", "hidden", 0)
+	other := candidate("p2", "other", 0)
+
+	if got := s.Score(&hidden, rc); !near(got, -0.5) {
+		t.Errorf("Hidden author: got %v, want -0.5", got)
+	}
+	if got := s.Score(&other, rc); got != 0 {
+		t.Errorf("Other author: got %v, want 0", got)
+	}
+}
+
+func TestExplainAddsUpToScore(t *testing.T) {
+	r := New(DefaultSignals()...)
+	rc := &Context{
+		Now:      now,
+		Affinity: map[string]float64{"a": 3},
+		Hides:    map[string]int{"a": 1},
+	}
+	c := candidate("p", "a", 6*time.Hour)
+	c.MediaType = MediaVideo
+	c.Engagement = Engagement{Likes: 40, Views: 900}
+
+	explanation := r.Explain(&c, rc)
+	if explanation.ID != "p" {
+		t.Errorf("Got ID %q, want p", explanation.ID)
+	}
+	if len(explanation.Contributions) != len(DefaultSignals()) {
+		t.Fatalf("Got %d contributions, want %d", len(explanation.Contributions), len(DefaultSignals()))
+	}
+
+	var sum float64
+	for _, contribution := range explanation.Contributions {
+		if !near(contribution.Score, contribution.Value*contribution.Weight) {
+			t.Errorf("%s: score %v is not value %v times weight %v",
+				contribution.Signal, contribution.Score, contribution.Value, contribution.Weight)
+		}
+		sum += contribution.Score
+	}
+	if !near(sum, explanation.Score) {
+		t.Errorf("Contributions add up to %v, score is %v", sum, explanation.Score)
+	}
+	if got := r.Score(&c, rc); got != explanation.Score {
+		t.Errorf("Score %v differs from explained score %v", got, explanation.Score)
+	}
+}
+
+func TestRankOrder(t *testing.T) {
+	r := New(
+		Weighted{Signal: Recency{HalfLife: 24 * time.Hour}, Weight: 1},
+		Weighted{Signal: Affinity{Saturation: 5}, Weight: 1},
+	)
+	rc := &Context{Now: now, Affinity: map[string]float64{"friend": 20}}
+
+	candidates := []Candidate{
+		candidate("old-stranger", "stranger", 48*time.Hour),
+		candidate("new-stranger", "stranger", time.Hour),
+		candidate("old-friend", "friend", 12*time.Hour),
+	}
+
+	got := ids(r.Rank(candidates, rc))
+	want := []string{"old-friend", "new-stranger", "old-stranger"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestRankIsDeterministic(t *testing.T) {
+	r := New(Weighted{Signal: MediaBoost{Values: map[MediaType]float64{MediaVideo: 1}}, Weight: 1})
+	rc := &Context{Now: now}
+
+	// Equal scores: newest first, then by descending ID
+	a := candidate("a", "x", time.Hour)
+	b := candidate("b", "x", time.Hour)
+	c := candidate("c", "x", 2*time.Hour)
+	video := candidate("v", "x", 3*time.Hour)
+	video.MediaType = MediaVideo
+
+	want := "v,b,a,c"
+	orders := [][]Candidate{
+		{a, b, c, video},
+		{video, c, b, a},
+		{c, a, video, b},
+	}
+	for _, candidates := range orders {
+		if got := strings.Join(ids(r.Rank(candidates, rc)), ","); got != want {
+			t.Errorf("Got %v, want %v", got, want)
+		}
+	}
+}
+
+// pinned is a custom signal, to check rankers take signals from outside the package
+type pinned struct {
+	id string
+}
+
+func (pinned) Name() string { return "pinned" }
+
+func (s pinned) Score(c *Candidate, rc *Context) float64 {
+	if c.ID == s.id {
+		return 1
+	}
+	return 0
+}
+
+func TestCustomSignal(t *testing.T) {
+	signals := append(DefaultSignals(), Weighted{Signal: pinned{id: "old"}, Weight: 10})
+	r := New(signals...)
+	rc := &Context{Now: now}
+
+	candidates := []Candidate{
+		candidate("new", "a", 0),
+		candidate("old", "a", 30*24*time.Hour),
+	}
+
+	ranked := r.Rank(candidates, rc)
+	if ranked[0].ID != "old" {
+		t.Errorf("Got %v first, want the pinned post", ranked[0].ID)
+	}
+	last := ranked[0].Contributions[len(ranked[0].Contributions)-1]
+	if last.Signal != "pinned" || last.Score != 10 {
+		t.Errorf("Got contribution %+v, want pinned scoring 10", last)
+	}
+}
+
+func TestWithWeights(t *testing.T) {
+	r := New(DefaultSignals()...)
+
+	reweighted, err := r.WithWeights(map[string]float64{"recency": 3, "media": 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c := candidate("p", "a", 0)
+	c.MediaType = MediaVideo
+	for _, contribution := range reweighted.Explain(&c, &Context{Now: now}).Contributions {
+		switch contribution.Signal {
+		case "recency":
+			if contribution.Weight != 3 || !near(contribution.Score, 3) {
+				t.Errorf("Recency: got %+v, want weight 3 scoring 3", contribution)
+			}
+		case "media":
+			if contribution.Score != 0 {
+				t.Errorf("Media: got %+v, want it switched off", contribution)
+			}
+		}
+	}
+
+	// The original ranker keeps its weights
+	if got := r.Explain(&c, &Context{Now: now}).Contributions[0].Weight; got != 1 {
+		t.Errorf("Original recency weight changed to %v", got)
+	}
+
+	if _, err := r.WithWeights(map[string]float64{"recencey": 1}); err == nil {
+		t.Error("Expected an error for an unknown signal")
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"Empty", "", map[string]float64{}, false},
+		{"One", "recency=1.5", map[string]float64{"recency": 1.5}, false},
+		{"Several with spaces", " recency = 2, affinity=0.5 ,media=-1", map[string]float64{"recency": 2, "affinity": 0.5, "media": -1}, false},
+		{"Missing weight", "recency", nil, true},
+		{"Missing name", "=1", nil, true},
+		{"Not a number", "recency=fast", nil, true},
+		{"Infinite", "recency=Inf", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWeights(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Got %v, want %v", got, tt.want)
+			}
+			for name, weight := range tt.want {
+				if got[name] != weight {
+					t.Errorf("%s: got %v, want %v", name, got[name], weight)
+				}
+			}
+		})
+	}
+}
+
+func ids(explanations []Explanation) []string {
+	out := make([]string, len(explanations))
+	for i, e := range explanations {
+		out[i] = e.ID
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// EngagementWeights says how much each kind of interaction is worth
+type EngagementWeights struct {
+	Like    float64
+	Comment float64
+	Share   float64
+	Save    float64
+	View    float64
+}
+
+// DefaultEngagementWeights count a comment or save as two likes, a share as three
+// and ten views as one
+var DefaultEngagementWeights = EngagementWeights{
+	Like:    1,
+	Comment: 2,
+	Share:   3,
+	Save:    2,
+	View:    0.1,
+}
+
+// Total weighs up a post's engagement
+func (w EngagementWeights) Total(e Engagement) float64 {
+	return float64(e.Likes)*w.Like +
+		float64(e.Comments)*w.Comment +
+		float64(e.Shares)*w.Share +
+		float64(e.Saves)*w.Save +
+		float64(e.Views)*w.View
+}
+
+// Recency is 1 for a post made just now and halves every HalfLife
+type Recency struct {
+	HalfLife time.Duration
+}
+
+func (Recency) Name() string { return "recency" }
+
+func (s Recency) Score(c *Candidate, rc *Context) float64 {
+	age := rc.Now.Sub(c.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+	return math.Exp2(-float64(age) / float64(s.HalfLife))
+}
+
+// Affinity is how close the viewer is to the author, from the viewer's recent
+// likes and comments on the author's posts. It is 0.5 at Saturation interactions
+// and approaches 1 beyond.
+type Affinity struct {
+	Saturation float64
+}
+
+func (Affinity) Name() string { return "affinity" }
+
+func (s Affinity) Score(c *Candidate, rc *Context) float64 {
+	interactions := rc.Affinity[c.AuthorID]
+	if interactions <= 0 {
+		return 0
+	}
+	return interactions / (interactions + s.Saturation)
+}
+
+// Velocity is how fast a post is gathering engagement, in weighted interactions
+// per hour. It is 0.5 at Scale per hour and approaches 1 beyond, so a viral post
+// can't drown out every other signal. Posts under an hour old count as an hour
+// old, so a couple of early likes don't look like a landslide.
+type Velocity struct {
+	Weights EngagementWeights
+	Scale   float64
+}
+
+func (Velocity) Name() string { return "velocity" }
+
+func (s Velocity) Score(c *Candidate, rc *Context) float64 {
+	total := s.Weights.Total(c.Engagement)
+	if total <= 0 {
+		return 0
+	}
+
+	hours := math.Max(rc.Now.Sub(c.CreatedAt).Hours(), 1)
+	rate := total / hours
+	return rate / (rate + s.Scale)
+}
+
+// MediaBoost gives each media type a fixed score
+type MediaBoost struct {
+	Values map[MediaType]float64
+}
+
+func (MediaBoost) Name() string { return "media" }
+
+func (s MediaBoost) Score(c *Candidate, rc *Context) float64 {
+	return s.Values[c.MediaType]
+}
+
+// NegativeFeedback pushes down authors the viewer has been hiding posts from. It
+// is -0.5 at Saturation hides and approaches -1 beyond.
+type NegativeFeedback struct {
+	Saturation float64
+}
+
+func (NegativeFeedback) Name() string { return "negative_feedback" }
+
+func (s NegativeFeedback) Score(c *Candidate, rc *Context) float64 {
+	hides := float64(rc.Hides[c.AuthorID])
+	if hides <= 0 {
+		return 0
+	}
+	return -hides / (hides + s.Saturation)
+}
+
+// DefaultSignals is the ranking feeds use unless weights are set otherwise
+func DefaultSignals() []Weighted {
+	return []Weighted{
+		{Signal: Recency{HalfLife: 24 * time.Hour}, Weight: 1},
+		{Signal: Affinity{Saturation: 5}, Weight: 1},
+		{Signal: Velocity{Weights: DefaultEngagementWeights, Scale: 50}, Weight: 0.8},
+		{Signal: MediaBoost{Values: map[MediaType]float64{
+			MediaPhoto:    0,
+			MediaCarousel: 0.1,
+			MediaVideo:    0.2,
+		}}, Weight: 0.5},
+		{Signal: NegativeFeedback{Saturation: 1}, Weight: 2},
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"socialink/search-service/internal/elasticsearch"
 	"socialink/search-service/internal/handler"
 	"socialink/search-service/internal/service"
+	"socialink/search-service/pkg/kafka"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -51,12 +53,34 @@ func main() {
 	}
 	log.Println("✅ Connected to Redis")
 
+	// Block list lookups go to the user service's internal API
+	blockClient := service.NewBlockClient(
+		getEnv("USER_SERVICE_URL", "http://localhost:8001"),
+		getEnv("USER_SERVICE_INTERNAL_TOKEN", ""),
+	)
+
 	// Initialize services
-	searchService := service.NewSearchService(esClient, redisClient)
+	searchService := service.NewSearchService(esClient, redisClient, blockClient)
 	autocompleteService := service.NewAutocompleteService(esClient, redisClient)
 	indexingService := service.NewIndexingService(esClient, redisClient)
 	hashtagService := service.NewHashtagService(esClient, redisClient)
 
+	// Take part in account deletion: purge users the user service asks about
+	purgeService := service.NewUserPurgeService(
+		esClient,
+		redisClient,
+		getEnv("USER_SERVICE_URL", "http://localhost:8001"),
+		getEnv("USER_SERVICE_INTERNAL_TOKEN", ""),
+	)
+	if brokers := getEnv("KAFKA_BROKERS", ""); brokers != "" {
+		userEvents := kafka.NewConsumer(strings.Split(brokers, ","), "search-service", "user-events")
+		defer userEvents.Close()
+		go userEvents.Run(context.Background(), purgeService.HandleUserEvent)
+		log.Println("✅ Listening for account purges")
+	} else {
+		log.Println("KAFKA_BROKERS not set; account purges will not be confirmed")
+	}
+
 	// Initialize handlers
 	searchHandler := handler.NewSearchHandler(searchService)
 	autocompleteHandler := handler.NewAutocompleteHandler(autocompleteService)
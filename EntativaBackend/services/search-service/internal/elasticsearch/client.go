@@ -286,6 +286,39 @@ func (c *Client) Search(ctx context.Context, indices []string, query map[string]
 	return res, nil
 }
 
+// DeleteByQuery deletes every document in indices matching query and returns how many were deleted
+func (c *Client) DeleteByQuery(ctx context.Context, indices []string, query map[string]interface{}) (int64, error) {
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return 0, fmt.Errorf("error marshaling query: %w", err)
+	}
+
+	res, err := c.es.DeleteByQuery(
+		indices,
+		bytes.NewReader(queryJSON),
+		c.es.DeleteByQuery.WithContext(ctx),
+		c.es.DeleteByQuery.WithConflicts("proceed"),
+		c.es.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting by query: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("error response deleting by query: %s", res.String())
+	}
+
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("error parsing delete by query response: %w", err)
+	}
+
+	return result.Deleted, nil
+}
+
 // MultiSearch performs multiple searches in one request
 func (c *Client) MultiSearch(ctx context.Context, queries []map[string]interface{}) (*esapi.Response, error) {
 	var buf bytes.Buffer
@@ -176,6 +176,9 @@ func getPostsIndexMapping() map[string]interface{} {
 				"hashtags": map[string]interface{}{
 					"type": "keyword",
 				},
+				"audience": map[string]interface{}{
+					"type": "keyword",
+				},
 				"location": map[string]interface{}{
 					"type": "text",
 				},
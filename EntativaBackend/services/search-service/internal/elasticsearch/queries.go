@@ -19,13 +19,21 @@ func BuildSearchQuery(req *model.SearchRequest) map[string]interface{} {
 		},
 	}
 
-	// Add filters
-	if len(getFilters(req)) > 0 {
+	// Add filters and exclusions
+	filters := getFilters(req)
+	exclusions := getExclusions(req)
+	if len(filters) > 0 || len(exclusions) > 0 {
+		boolQuery := map[string]interface{}{
+			"must": buildMultiMatchQuery(req.Query),
+		}
+		if len(filters) > 0 {
+			boolQuery["filter"] = filters
+		}
+		if len(exclusions) > 0 {
+			boolQuery["must_not"] = exclusions
+		}
 		query["query"] = map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must":   buildMultiMatchQuery(req.Query),
-				"filter": getFilters(req),
-			},
+			"bool": boolQuery,
 		}
 	}
 
@@ -68,6 +76,19 @@ func buildMultiMatchQuery(query string) map[string]interface{} {
 	}
 }
 
+// getExclusions hides users the searcher has blocked or been blocked by, along with
+// their posts and takes
+func getExclusions(req *model.SearchRequest) []map[string]interface{} {
+	if len(req.ExcludeUserIDs) == 0 {
+		return nil
+	}
+
+	return []map[string]interface{}{
+		{"terms": map[string]interface{}{"id": req.ExcludeUserIDs}},
+		{"terms": map[string]interface{}{"user_id": req.ExcludeUserIDs}},
+	}
+}
+
 // getFilters builds filters based on search filters
 func getFilters(req *model.SearchRequest) []map[string]interface{} {
 	var filters []map[string]interface{}
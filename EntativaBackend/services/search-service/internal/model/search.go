@@ -22,6 +22,9 @@ type SearchRequest struct {
 	Offset     int          `json:"offset" binding:"omitempty,min=0"`
 	Filters    SearchFilter `json:"filters,omitempty"`
 	UserID     string       `json:"-"` // From auth header
+
+	// ExcludeUserIDs are users hidden from the searcher because of a block
+	ExcludeUserIDs []string `json:"-"`
 }
 
 // SearchFilter represents advanced search filters
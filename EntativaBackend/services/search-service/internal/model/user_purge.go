@@ -0,0 +1,19 @@
+package model
+
+// UserDeletionRequestedEvent is published by the user service on "user-events" when
+// a deleted account's grace period is over. Services named in Services purge the
+// user's data and report back; the event is repeated until they do.
+type UserDeletionRequestedEvent struct {
+	EventType  string   `json:"event_type"`
+	DeletionID string   `json:"deletion_id"`
+	UserID     string   `json:"user_id"`
+	Services   []string `json:"services"`
+}
+
+// UserPurgeResult counts what was removed when purging a deleted user's data
+type UserPurgeResult struct {
+	Users         int64 `json:"users"`
+	Posts         int64 `json:"posts"`
+	Takes         int64 `json:"takes"`
+	SearchHistory int64 `json:"search_history"`
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// BlockClient asks the user service who a user has blocked or been blocked by, so
+// they can be left out of each other's search results
+type BlockClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewBlockClient(userServiceURL, internalToken string) *BlockClient {
+	return &BlockClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// BlockedUserIDs returns everyone userID has blocked or been blocked by
+func (c *BlockClient) BlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/blocks/%s", c.userServiceURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			BlockedUserIDs []string `json:"blocked_user_ids"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode blocked users: %w", err)
+	}
+
+	return body.Data.BlockedUserIDs, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -28,7 +29,7 @@ func (s *IndexingService) IndexDocument(ctx context.Context, req *model.Indexing
 	// Get index name
 	indexName := s.getIndexName(req.DocumentType)
 	
-	switch req.Action {
+	switch publicOnlyAction(req) {
 	case "index":
 		return s.indexDoc(ctx, indexName, req.DocumentID, req.Data)
 	case "update":
@@ -62,7 +63,7 @@ func (s *IndexingService) BulkIndex(ctx context.Context, req *model.BulkIndexing
 			"_id":    doc.DocumentID,
 		}
 
-		switch doc.Action {
+		switch publicOnlyAction(&doc) {
 		case "index":
 			operations = append(operations, map[string]interface{}{
 				"index": actionMeta,
@@ -163,6 +164,29 @@ func (s *IndexingService) deleteDoc(ctx context.Context, indexName, documentID s
 	}, nil
 }
 
+// publicOnlyAction returns the action to take for an indexing request. Search
+// only holds public posts, so indexing or updating a post shared with a smaller
+// audience deletes it instead, taking it out of search if it was public before.
+// A post indexed without an audience counts as public.
+func publicOnlyAction(req *model.IndexingRequest) string {
+	if req.DocumentType != model.SearchTypePost || (req.Action != "index" && req.Action != "update") {
+		return req.Action
+	}
+
+	fields, ok := req.Data.(map[string]interface{})
+	if !ok {
+		raw, err := json.Marshal(req.Data)
+		if err != nil || json.Unmarshal(raw, &fields) != nil {
+			return req.Action
+		}
+	}
+
+	if audience, ok := fields["audience"].(string); ok && audience != model.AudiencePublic {
+		return "delete"
+	}
+	return req.Action
+}
+
 // getIndexName returns the index name for a document type
 func (s *IndexingService) getIndexName(docType model.SearchType) string {
 	switch docType {
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"socialink/search-service/internal/elasticsearch"
+	"socialink/search-service/internal/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	userDeletionRequestedEvent = "user.deletion_requested"
+
+	// purgeServiceName is how the user service knows this service in a purge
+	purgeServiceName = "search"
+)
+
+// UserPurgeService takes part in account deletion: when the user service asks,
+// it removes the deleted user's profile, posts and Takes from the indices, drops
+// their search history and reports back. The user service repeats the request
+// until it hears that the purge succeeded.
+type UserPurgeService struct {
+	es             *elasticsearch.Client
+	redis          *redis.Client
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewUserPurgeService(es *elasticsearch.Client, redis *redis.Client, userServiceURL, internalToken string) *UserPurgeService {
+	return &UserPurgeService{
+		es:             es,
+		redis:          redis,
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HandleUserEvent purges a user on a user.deletion_requested event naming this
+// service. Other user events are ignored.
+func (s *UserPurgeService) HandleUserEvent(ctx context.Context, key, value []byte) error {
+	var event model.UserDeletionRequestedEvent
+	if err := json.Unmarshal(value, &event); err != nil || event.EventType != userDeletionRequestedEvent {
+		return nil
+	}
+	if !containsString(event.Services, purgeServiceName) {
+		return nil
+	}
+
+	result, purgeErr := s.purgeUser(ctx, event.UserID)
+	if purgeErr == nil {
+		log.Printf("Purged user %s: %d user documents, %d posts, %d Takes, %d search history lists",
+			event.UserID, result.Users, result.Posts, result.Takes, result.SearchHistory)
+	}
+
+	if err := s.reportPurge(ctx, event.DeletionID, purgeErr); err != nil {
+		return fmt.Errorf("failed to report purge of user %s: %w", event.UserID, err)
+	}
+	return purgeErr
+}
+
+// purgeUser removes everything indexed for the user. Each step is idempotent,
+// so a purge that failed halfway is simply run again.
+func (s *UserPurgeService) purgeUser(ctx context.Context, userID string) (*model.UserPurgeResult, error) {
+	result := &model.UserPurgeResult{}
+	var err error
+
+	byID := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"id": userID},
+		},
+	}
+	if result.Users, err = s.es.DeleteByQuery(ctx, []string{elasticsearch.IndexUsers}, byID); err != nil {
+		return nil, fmt.Errorf("failed to delete user document: %w", err)
+	}
+
+	byAuthor := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"user_id": userID},
+		},
+	}
+	if result.Posts, err = s.es.DeleteByQuery(ctx, []string{elasticsearch.IndexPosts}, byAuthor); err != nil {
+		return nil, fmt.Errorf("failed to delete posts: %w", err)
+	}
+	if result.Takes, err = s.es.DeleteByQuery(ctx, []string{elasticsearch.IndexTakes}, byAuthor); err != nil {
+		return nil, fmt.Errorf("failed to delete takes: %w", err)
+	}
+
+	if result.SearchHistory, err = s.redis.Del(ctx, fmt.Sprintf("search:history:%s", userID)).Result(); err != nil {
+		return nil, fmt.Errorf("failed to delete search history: %w", err)
+	}
+
+	return result, nil
+}
+
+// reportPurge tells the user service whether the purge succeeded
+func (s *UserPurgeService) reportPurge(ctx context.Context, deletionID string, purgeErr error) error {
+	report := map[string]string{"status": "completed"}
+	if purgeErr != nil {
+		report = map[string]string{"status": "failed", "error": purgeErr.Error()}
+	}
+	body, _ := json.Marshal(report)
+
+	endpoint := fmt.Sprintf("%s/api/v1/internal/deletions/%s/services/%s", s.userServiceURL, deletionID, purgeServiceName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", s.internalToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/entativa/socialink/settings-service/internal/crypto"
 	"github.com/entativa/socialink/settings-service/internal/handler"
 	"github.com/entativa/socialink/settings-service/internal/repository"
 	"github.com/entativa/socialink/settings-service/internal/service"
+	"github.com/entativa/socialink/settings-service/pkg/kafka"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -40,11 +43,23 @@ func main() {
 	// Initialize repositories
 	settingsRepo := repository.NewSettingsRepository(db)
 	keyBackupRepo := repository.NewKeyBackupRepository(db)
+	purgeRepo := repository.NewUserPurgeRepository(db)
 
 	// Initialize services
 	encryptionSvc := crypto.NewEncryptionService()
 	settingsService := service.NewSettingsService(settingsRepo, keyBackupRepo, encryptionSvc)
 
+	// Take part in account deletion: purge users the user service asks about
+	purgeService := service.NewUserPurgeService(purgeRepo, os.Getenv("USER_SERVICE_URL"), os.Getenv("USER_SERVICE_INTERNAL_TOKEN"))
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		userEvents := kafka.NewConsumer(strings.Split(brokers, ","), "settings-service", "user-events")
+		defer userEvents.Close()
+		go userEvents.Run(context.Background(), purgeService.HandleUserEvent)
+		log.Println("✅ Listening for account purges")
+	} else {
+		log.Println("KAFKA_BROKERS not set; account purges will not be confirmed")
+	}
+
 	// Initialize handlers
 	settingsHandler := handler.NewSettingsHandler(settingsService)
 
@@ -0,0 +1,21 @@
+package model
+
+import "github.com/google/uuid"
+
+// UserDeletionRequestedEvent is published by the user service on "user-events" when
+// a deleted account's grace period is over. Services named in Services purge the
+// user's data and report back; the event is repeated until they do.
+type UserDeletionRequestedEvent struct {
+	EventType  string    `json:"event_type"`
+	DeletionID uuid.UUID `json:"deletion_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Services   []string  `json:"services"`
+}
+
+// UserPurgeResult counts what was removed when purging a deleted user's data
+type UserPurgeResult struct {
+	Settings   int64 `json:"settings"`
+	KeyBackups int64 `json:"key_backups"`
+	History    int64 `json:"history"`
+	AccessLog  int64 `json:"access_log"`
+}
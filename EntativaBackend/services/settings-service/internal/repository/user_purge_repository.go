@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/entativa/socialink/settings-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// UserPurgeRepository removes a deleted user's settings and key backups. Purging
+// is idempotent: running it again for the same user removes nothing more.
+type UserPurgeRepository struct {
+	db *sql.DB
+}
+
+func NewUserPurgeRepository(db *sql.DB) *UserPurgeRepository {
+	return &UserPurgeRepository{db: db}
+}
+
+// PurgeUser deletes the user's settings, settings history, key backups and key
+// backup access log in one transaction
+func (r *UserPurgeRepository) PurgeUser(ctx context.Context, userID uuid.UUID) (*model.UserPurgeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &model.UserPurgeResult{}
+
+	// Backups go before the settings they reference, so they can be counted
+	deletes := []struct {
+		query string
+		count *int64
+		what  string
+	}{
+		{`DELETE FROM key_backup_access_log WHERE user_id = $1`, &result.AccessLog, "key backup access log"},
+		{`DELETE FROM encrypted_key_backups WHERE user_id = $1`, &result.KeyBackups, "key backups"},
+		{`DELETE FROM user_settings WHERE user_id = $1`, &result.Settings, "settings"},
+		{`DELETE FROM settings_history WHERE user_id = $1`, &result.History, "settings history"},
+	}
+	for _, d := range deletes {
+		res, err := tx.ExecContext(ctx, d.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", d.what, err)
+		}
+		*d.count, _ = res.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return result, nil
+}
@@ -12,12 +12,15 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 	"user-service/internal/config"
 	"user-service/internal/handler"
 	"user-service/internal/logger"
 	"user-service/internal/middleware"
 	"user-service/internal/repository"
 	"user-service/internal/service"
+	"user-service/internal/util"
+	"user-service/pkg/cache"
 )
 
 func main() {
@@ -40,35 +43,132 @@ func main() {
 	
 	appLogger.Info("Connected to database: %s", cfg.Database.Name)
 	
+	// Connect to Redis (optional; without it nothing is cached)
+	redisClient := connectRedis(cfg, appLogger)
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+	
+	// Load access token signing keys
+	keyring, err := loadSigningKeys(cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to load signing keys", err)
+	}
+	util.SetKeyring(keyring)
+	appLogger.Info("Signing access tokens with key %s", keyring.Active().KID)
+	
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	tokenRepo := repository.NewTokenRepository(db)
+	emailTokenRepo := repository.NewEmailTokenRepository(db)
+	oauthRepo := repository.NewOAuthRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	exportRepo := repository.NewDataExportRepository(db)
+	deletionRepo := repository.NewAccountDeletionRepository(db)
+	suggestionRepo := repository.NewFriendSuggestionRepository(db)
+	audienceListRepo := repository.NewAudienceListRepository(db)
+	usernameRepo := repository.NewUsernameRepository(db)
+	friendGraphRepo := repository.NewFriendGraphRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	challengeRepo := repository.NewLoginChallengeRepository(db)
+	webAuthnRepo := repository.NewWebAuthnRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	profileRepo := repository.NewProfileRepository(db, cache.NewClientCache(redisClient))
 	
 	// Initialize services
 	emailService := service.NewEmailService()
+	kafkaProducer := service.NewKafkaProducer(cfg.Kafka.Brokers)
+	defer kafkaProducer.Close()
 	auditLog := service.NewAuditLog(db)
+	ipLocator := service.NewIPLocator(cfg)
+	sessionService := service.NewSessionService(sessionRepo, userRepo, ipLocator, auditLog, cfg)
+	emailVerificationService := service.NewEmailVerificationService(userRepo, emailTokenRepo, sessionRepo, emailService, auditLog, cfg)
+	oidcService := service.NewOIDCService(oauthRepo, userRepo, auditLog, cfg)
+	identityService := service.NewIdentityService(identityRepo, userRepo, auditLog)
+	settingsRepo := repository.NewSettingsRepository(db)
+	blockService := service.NewBlockService(settingsRepo, redisClient, kafkaProducer, cfg.Blocks.CacheTTL)
+	deletionService := service.NewAccountDeletionService(deletionRepo, sessionRepo, emailService, kafkaProducer, auditLog, cfg)
+	suggestionService := service.NewFriendSuggestionService(suggestionRepo, userRepo, cfg)
+	audienceListService := service.NewAudienceListService(audienceListRepo)
+	usernameService := service.NewUsernameService(usernameRepo, blockService, kafkaProducer, auditLog, cfg)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, auditLog)
+	webAuthnService := service.NewWebAuthnService(webAuthnRepo, auditLog, cfg)
+	loginProtection := service.NewLoginProtectionService(loginAttemptRepo, emailService, auditLog, cfg)
+	authService := service.NewAuthService(userRepo, challengeRepo, sessionService, twoFactorService, webAuthnService, loginProtection, deletionService, auditLog)
+	profileService := service.NewProfileService(profileRepo, userRepo, blockService, friendGraphRepo, audienceListService)
+	
+	// Initialize data exports
+	exportStorage, err := service.NewLocalExportStorage(cfg.DataExport.StorageDir)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize data export storage", err)
+	}
+	postExportClient := service.NewPostExportClient(cfg)
+	dataExportService := service.NewDataExportService(exportRepo, userRepo, postExportClient, exportStorage, emailService, auditLog, cfg)
+	
+	// Initialize profile picture and cover photo storage
+	photoStore, err := service.NewS3PhotoStore(cfg.Media)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize photo storage", err)
+	}
+	mediaService := service.NewMediaService(photoStore, userRepo)
 	
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(
 		userRepo,
 		sessionRepo,
 		tokenRepo,
+		sessionService,
+		authService,
+		twoFactorService,
+		webAuthnService,
 		emailService,
+		emailVerificationService,
+		identityService,
+		deletionService,
 		auditLog,
 		appLogger,
 		cfg,
 	)
 	
-	// Initialize settings repository and handler
-	settingsRepo := repository.NewSettingsRepository(db)
-	settingsHandler := handler.NewSettingsHandler(settingsRepo, appLogger)
+	// Initialize settings handler
+	settingsHandler := handler.NewSettingsHandler(settingsRepo, blockService, deletionService, usernameService, appLogger)
+	
+	// Initialize OpenID Connect provider handler
+	oauthHandler := handler.NewOAuthHandler(oidcService, appLogger, cfg)
+	
+	// Initialize data export handler
+	exportHandler := handler.NewDataExportHandler(dataExportService, appLogger)
+	
+	// Initialize account deletion handler
+	deletionHandler := handler.NewAccountDeletionHandler(deletionService, appLogger)
+	
+	// Initialize block graph handler
+	blockHandler := handler.NewBlockHandler(blockService, appLogger)
+	
+	// Initialize friend suggestion handler
+	suggestionHandler := handler.NewFriendSuggestionHandler(suggestionService, appLogger)
+	
+	// Initialize audience list handler
+	audienceListHandler := handler.NewAudienceListHandler(audienceListService, appLogger)
+	
+	// Initialize photo upload handler
+	photoHandler := handler.NewPhotoHandler(mediaService, appLogger)
+	
+	// Initialize username handler
+	usernameHandler := handler.NewUsernameHandler(usernameService, appLogger)
+	
+	// Initialize friend graph handler
+	friendGraphHandler := handler.NewFriendGraphHandler(friendGraphRepo, blockService, appLogger)
+	
+	// Initialize profile handler
+	profileHandler := handler.NewProfileHandler(profileService)
 	
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(userRepo, appLogger)
+	authMiddleware := middleware.NewAuthMiddleware(userRepo, sessionRepo, appLogger)
 	
 	// Setup routes
-	router := SetupRoutes(authHandler, settingsHandler, authMiddleware)
+	router := SetupRoutes(authHandler, settingsHandler, oauthHandler, exportHandler, deletionHandler, blockHandler, suggestionHandler, audienceListHandler, photoHandler, usernameHandler, friendGraphHandler, setupProfileRoutes(profileHandler), authMiddleware, cfg.Security.InternalAPIToken)
 	
 	// Create HTTP server
 	server := &http.Server{
@@ -80,7 +180,13 @@ func main() {
 	}
 	
 	// Start cleanup goroutine for expired sessions and tokens
-	go cleanupExpiredData(sessionRepo, tokenRepo, appLogger)
+	go cleanupExpiredData(sessionRepo, tokenRepo, emailTokenRepo, oauthRepo, challengeRepo, webAuthnRepo, loginAttemptRepo, dataExportService, appLogger)
+	
+	// Start the data export, account purge and friend suggestion workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	go dataExportService.RunWorker(workerCtx)
+	go deletionService.RunWorker(workerCtx)
+	go suggestionService.RunWorker(workerCtx)
 	
 	// Start server in a goroutine
 	go func() {
@@ -97,6 +203,9 @@ func main() {
 	
 	appLogger.Info("Shutting down server...")
 	
+	// Stop background work; an export in progress is handed back for another instance
+	stopWorkers()
+	
 	// Graceful shutdown with 30 second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -133,10 +242,51 @@ func connectDatabase(cfg *config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// connectRedis connects to Redis, returning nil when it is not configured or unreachable
+func connectRedis(cfg *config.Config, logger *logger.Logger) *redis.Client {
+	if cfg.Redis.Host == "" {
+		logger.Warn("REDIS_HOST not set, running without a cache", nil)
+		return nil
+	}
+	
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Warn("Redis unreachable, running without a cache", err)
+		client.Close()
+		return nil
+	}
+	
+	return client
+}
+
+// loadSigningKeys loads the JWT keyring, falling back to a throwaway key outside production
+func loadSigningKeys(cfg *config.Config, logger *logger.Logger) (*util.Keyring, error) {
+	if cfg.JWT.SigningKeysDir != "" {
+		return util.LoadKeyring(cfg.JWT.SigningKeysDir, cfg.JWT.ActiveKeyID)
+	}
+	
+	logger.Warn("JWT_SIGNING_KEYS_DIR not set, using an ephemeral signing key", nil)
+	return util.NewEphemeralKeyring()
+}
+
 // cleanupExpiredData periodically cleans up expired sessions and tokens
 func cleanupExpiredData(
 	sessionRepo *repository.SessionRepository,
 	tokenRepo *repository.TokenRepository,
+	emailTokenRepo *repository.EmailTokenRepository,
+	oauthRepo *repository.OAuthRepository,
+	challengeRepo *repository.LoginChallengeRepository,
+	webAuthnRepo *repository.WebAuthnRepository,
+	loginAttemptRepo *repository.LoginAttemptRepository,
+	dataExportService *service.DataExportService,
 	logger *logger.Logger,
 ) {
 	ticker := time.NewTicker(1 * time.Hour)
@@ -154,5 +304,35 @@ func cleanupExpiredData(
 		if err := tokenRepo.DeleteExpiredTokens(ctx); err != nil {
 			logger.Error("Failed to delete expired tokens", err)
 		}
+		
+		// Clean up expired email verification and change tokens
+		if err := emailTokenRepo.DeleteExpiredEmailTokens(ctx); err != nil {
+			logger.Error("Failed to delete expired email tokens", err)
+		}
+		
+		// Clean up expired OAuth authorization codes
+		if err := oauthRepo.DeleteExpiredAuthorizationCodes(ctx); err != nil {
+			logger.Error("Failed to delete expired authorization codes", err)
+		}
+		
+		// Clean up abandoned 2FA logins once they no longer count towards the attempt limit
+		if err := challengeRepo.DeleteExpiredChallenges(ctx, time.Now().Add(-time.Hour)); err != nil {
+			logger.Error("Failed to delete expired login challenges", err)
+		}
+		
+		// Clean up passkey challenges that were never answered
+		if err := webAuthnRepo.DeleteExpiredCeremonies(ctx); err != nil {
+			logger.Error("Failed to delete expired webauthn ceremonies", err)
+		}
+		
+		// Failures only matter inside the lockout window; successes are kept longer to recognise known devices
+		if err := loginAttemptRepo.DeleteExpiredAttempts(ctx, time.Now().Add(-24*time.Hour), time.Now().Add(-180*24*time.Hour)); err != nil {
+			logger.Error("Failed to delete expired login attempts", err)
+		}
+		
+		// Delete data export archives whose download window has passed
+		if err := dataExportService.ExpireExports(ctx); err != nil {
+			logger.Error("Failed to expire data exports", err)
+		}
 	}
 }
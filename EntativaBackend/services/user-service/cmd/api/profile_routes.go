@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"user-service/internal/handler"
+)
+
+// setupProfileRoutes serves the profile endpoints, whose handlers are written for gin.
+// SetupRoutes forwards their paths here after RequireAuth, so routes keep their full
+// path and the signed-in user is already on the request context.
+func setupProfileRoutes(profileHandler *handler.ProfileHandler) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery(), requestUserID)
+
+	profile := r.Group("/api/v1/profile")
+	profile.GET("/me", profileHandler.GetMyProfile)
+	profile.GET("/me/view-as/:viewer_id", profileHandler.ViewProfileAs)
+	profile.GET("/:user_id", profileHandler.GetProfile)
+	profile.PUT("/info", profileHandler.UpdateProfileInfo)
+	profile.POST("/work", profileHandler.AddWorkExperience)
+	profile.DELETE("/work/:work_id", profileHandler.RemoveWorkExperience)
+	profile.POST("/education", profileHandler.AddEducation)
+	profile.DELETE("/education/:education_id", profileHandler.RemoveEducation)
+	profile.PUT("/contact", profileHandler.UpdateContactInfo)
+	profile.PUT("/social-links", profileHandler.UpdateSocialLinks)
+	profile.PUT("/visibility", profileHandler.UpdateVisibility)
+
+	return r
+}
+
+// requestUserID hands the user RequireAuth put on the request context to the gin handlers
+func requestUserID(c *gin.Context) {
+	if userID, ok := c.Request.Context().Value("user_id").(string); ok {
+		c.Set("user_id", userID)
+	}
+	c.Next()
+}
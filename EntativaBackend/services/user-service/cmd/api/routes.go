@@ -1,18 +1,29 @@
 package main
 
 import (
+	"crypto/subtle"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"user-service/internal/handler"
 	"user-service/internal/middleware"
+	"user-service/internal/util"
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(authHandler *handler.AuthHandler, settingsHandler *handler.SettingsHandler, authMiddleware *middleware.AuthMiddleware) *mux.Router {
+func SetupRoutes(authHandler *handler.AuthHandler, settingsHandler *handler.SettingsHandler, oauthHandler *handler.OAuthHandler, exportHandler *handler.DataExportHandler, deletionHandler *handler.AccountDeletionHandler, blockHandler *handler.BlockHandler, suggestionHandler *handler.FriendSuggestionHandler, audienceListHandler *handler.AudienceListHandler, photoHandler *handler.PhotoHandler, usernameHandler *handler.UsernameHandler, friendGraphHandler *handler.FriendGraphHandler, profileRouter http.Handler, authMiddleware *middleware.AuthMiddleware, internalToken string) *mux.Router {
 	r := mux.NewRouter()
 	
+	// Public verification keys for access tokens
+	r.HandleFunc("/.well-known/jwks.json", authHandler.HandleJWKS).Methods("GET")
+	
+	// OpenID Connect provider endpoints used by client apps
+	r.HandleFunc("/.well-known/openid-configuration", oauthHandler.HandleDiscovery).Methods("GET")
+	r.HandleFunc("/oauth/authorize", oauthHandler.HandleAuthorize).Methods("GET")
+	r.HandleFunc("/oauth/token", oauthHandler.HandleToken).Methods("POST")
+	r.HandleFunc("/oauth/userinfo", oauthHandler.HandleUserInfo).Methods("GET", "POST")
+	
 	// API version prefix
 	api := r.PathPrefix("/api/v1").Subrouter()
 	
@@ -23,9 +34,18 @@ func SetupRoutes(authHandler *handler.AuthHandler, settingsHandler *handler.Sett
 	auth := api.PathPrefix("/auth").Subrouter()
 	auth.HandleFunc("/signup", authHandler.HandleSignUp).Methods("POST")
 	auth.HandleFunc("/login", authHandler.HandleLogin).Methods("POST")
+	auth.HandleFunc("/2fa/login", authHandler.HandleTwoFactorLogin).Methods("POST")
+	auth.HandleFunc("/2fa/webauthn/begin", authHandler.HandleBeginTwoFactorPasskey).Methods("POST")
+	auth.HandleFunc("/2fa/webauthn/finish", authHandler.HandleFinishTwoFactorPasskey).Methods("POST")
+	auth.HandleFunc("/webauthn/login/begin", authHandler.HandleBeginPasskeyLogin).Methods("POST")
+	auth.HandleFunc("/webauthn/login/finish", authHandler.HandleFinishPasskeyLogin).Methods("POST")
 	auth.HandleFunc("/forgot-password", authHandler.HandleForgotPassword).Methods("POST")
 	auth.HandleFunc("/reset-password", authHandler.HandleResetPassword).Methods("POST")
 	auth.HandleFunc("/verify-reset-token/{token}", authHandler.HandleVerifyResetToken).Methods("GET")
+	auth.HandleFunc("/refresh", authHandler.HandleRefreshToken).Methods("POST")
+	auth.HandleFunc("/email/verify", authHandler.HandleVerifyEmail).Methods("POST")
+	auth.HandleFunc("/email/change/confirm", authHandler.HandleConfirmEmailChange).Methods("POST")
+	auth.HandleFunc("/email/change/undo", authHandler.HandleUndoEmailChange).Methods("POST")
 	
 	// Cross-platform authentication
 	crossPlatform := auth.PathPrefix("/cross-platform").Subrouter()
@@ -37,20 +57,69 @@ func SetupRoutes(authHandler *handler.AuthHandler, settingsHandler *handler.Sett
 	authProtected.Use(authMiddleware.RequireAuth)
 	authProtected.HandleFunc("/me", authHandler.HandleGetCurrentUser).Methods("GET")
 	authProtected.HandleFunc("/logout", authHandler.HandleLogout).Methods("POST")
-	authProtected.HandleFunc("/refresh", authHandler.HandleRefreshToken).Methods("POST")
+	authProtected.HandleFunc("/sessions", authHandler.HandleListSessions).Methods("GET")
+	authProtected.HandleFunc("/sessions", authHandler.HandleRevokeOtherSessions).Methods("DELETE")
+	authProtected.HandleFunc("/sessions/{id}", authHandler.HandleRevokeSession).Methods("DELETE")
+	authProtected.HandleFunc("/2fa/setup", authHandler.HandleSetupTwoFactor).Methods("POST")
+	authProtected.HandleFunc("/2fa/enable", authHandler.HandleEnableTwoFactor).Methods("POST")
+	authProtected.HandleFunc("/2fa/disable", authHandler.HandleDisableTwoFactor).Methods("POST")
+	authProtected.HandleFunc("/webauthn/register/begin", authHandler.HandleBeginWebAuthnRegistration).Methods("POST")
+	authProtected.HandleFunc("/webauthn/register/finish", authHandler.HandleFinishWebAuthnRegistration).Methods("POST")
+	authProtected.HandleFunc("/webauthn/credentials", authHandler.HandleListWebAuthnCredentials).Methods("GET")
+	authProtected.HandleFunc("/webauthn/credentials/{id}", authHandler.HandleRenameWebAuthnCredential).Methods("PATCH")
+	authProtected.HandleFunc("/webauthn/credentials/{id}", authHandler.HandleRevokeWebAuthnCredential).Methods("DELETE")
+	authProtected.HandleFunc("/email/verify/resend", authHandler.HandleResendVerification).Methods("POST")
+	authProtected.HandleFunc("/email/change", authHandler.HandleRequestEmailChange).Methods("POST")
+	authProtected.HandleFunc("/identities", authHandler.HandleListIdentities).Methods("GET")
+	authProtected.HandleFunc("/identities", authHandler.HandleLinkIdentity).Methods("POST")
+	authProtected.HandleFunc("/identities/merge", authHandler.HandleMergeAccounts).Methods("POST")
+	authProtected.HandleFunc("/identities/merges", authHandler.HandleListMerges).Methods("GET")
+	authProtected.HandleFunc("/identities/{platform}", authHandler.HandleUnlinkIdentity).Methods("DELETE")
 	
 	// User management routes (protected)
 	users := api.PathPrefix("/users").Subrouter()
 	users.Use(authMiddleware.RequireAuth)
+	users.HandleFunc("/me/profile-picture", photoHandler.HandleUploadProfilePicture).Methods("POST")
+	users.HandleFunc("/me/cover-photo", photoHandler.HandleUploadCoverPhoto).Methods("POST")
+	users.HandleFunc("/by-username/{username}", usernameHandler.HandleLookupUsername).Methods("GET")
 	users.HandleFunc("/{id}", authHandler.HandleGetUser).Methods("GET")
 	users.HandleFunc("/{id}", authHandler.HandleUpdateUser).Methods("PUT")
 	users.HandleFunc("/{id}", authHandler.HandleDeleteUser).Methods("DELETE")
 	
+	// OAuth consent and developer routes (protected)
+	oauth := api.PathPrefix("/oauth").Subrouter()
+	oauth.Use(authMiddleware.RequireAuth)
+	oauth.HandleFunc("/authorize", oauthHandler.HandleGetAuthorization).Methods("GET")
+	oauth.HandleFunc("/authorize", oauthHandler.HandleApproveAuthorization).Methods("POST")
+	oauth.HandleFunc("/clients", oauthHandler.HandleRegisterClient).Methods("POST")
+	oauth.HandleFunc("/clients", oauthHandler.HandleListClients).Methods("GET")
+	oauth.HandleFunc("/clients/{id}", oauthHandler.HandleDeleteClient).Methods("DELETE")
+	oauth.HandleFunc("/consents", oauthHandler.HandleListConsents).Methods("GET")
+	oauth.HandleFunc("/consents/{client_id}", oauthHandler.HandleRevokeConsent).Methods("DELETE")
+	
+	// Data export download (signed link from the email or the export status)
+	api.HandleFunc("/exports/download", exportHandler.HandleDownloadExport).Methods("GET")
+	
+	// Data export routes (protected)
+	exports := api.PathPrefix("/exports").Subrouter()
+	exports.Use(authMiddleware.RequireAuth)
+	exports.HandleFunc("", exportHandler.HandleRequestExport).Methods("POST")
+	exports.HandleFunc("", exportHandler.HandleListExports).Methods("GET")
+	exports.HandleFunc("/{id}", exportHandler.HandleGetExport).Methods("GET")
+	
+	// Profile routes (protected), served by the gin profile handlers
+	profile := api.PathPrefix("/profile").Subrouter()
+	profile.Use(authMiddleware.RequireAuth)
+	profile.PathPrefix("").Handler(profileRouter)
+	
 	// Settings routes (protected)
 	settings := api.PathPrefix("/settings").Subrouter()
 	settings.Use(authMiddleware.RequireAuth)
 	settings.HandleFunc("", settingsHandler.GetUserSettings).Methods("GET")
 	settings.HandleFunc("/account", settingsHandler.UpdateAccountSettings).Methods("PUT")
+	settings.HandleFunc("/username", usernameHandler.HandleGetUsername).Methods("GET")
+	settings.HandleFunc("/username", usernameHandler.HandleChangeUsername).Methods("PUT")
+	settings.HandleFunc("/username/availability", usernameHandler.HandleCheckAvailability).Methods("GET")
 	settings.HandleFunc("/privacy", settingsHandler.UpdatePrivacySettings).Methods("PUT")
 	settings.HandleFunc("/notifications", settingsHandler.UpdateNotificationSettings).Methods("PUT")
 	settings.HandleFunc("/data", settingsHandler.UpdateDataSettings).Methods("PUT")
@@ -62,6 +131,44 @@ func SetupRoutes(authHandler *handler.AuthHandler, settingsHandler *handler.Sett
 	settings.HandleFunc("/login-activity", settingsHandler.GetLoginActivity).Methods("GET")
 	settings.HandleFunc("/delete-account", settingsHandler.DeleteAccount).Methods("POST")
 	
+	// Friend suggestion routes (protected)
+	friends := api.PathPrefix("/friends").Subrouter()
+	friends.Use(authMiddleware.RequireAuth)
+	friends.HandleFunc("/suggestions", suggestionHandler.HandleListSuggestions).Methods("GET")
+	friends.HandleFunc("/suggestions/{userID}", suggestionHandler.HandleDismissSuggestion).Methods("DELETE")
+	
+	// Close friends and custom audience list routes (protected)
+	lists := api.PathPrefix("/lists").Subrouter()
+	lists.Use(authMiddleware.RequireAuth)
+	lists.HandleFunc("", audienceListHandler.HandleListLists).Methods("GET")
+	lists.HandleFunc("", audienceListHandler.HandleCreateList).Methods("POST")
+	lists.HandleFunc("/{listID}", audienceListHandler.HandleGetList).Methods("GET")
+	lists.HandleFunc("/{listID}", audienceListHandler.HandleRenameList).Methods("PUT")
+	lists.HandleFunc("/{listID}", audienceListHandler.HandleDeleteList).Methods("DELETE")
+	lists.HandleFunc("/{listID}/members", audienceListHandler.HandleListMembers).Methods("GET")
+	lists.HandleFunc("/{listID}/members", audienceListHandler.HandleAddMembers).Methods("POST")
+	lists.HandleFunc("/{listID}/members", audienceListHandler.HandleRemoveMembers).Methods("DELETE")
+	
+	// Admin routes (founder only)
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authMiddleware.RequireAuth, authMiddleware.RequireFounder)
+	admin.HandleFunc("/users/{userID}/username-history", usernameHandler.HandleGetUsernameHistory).Methods("GET")
+	
+	// Service-to-service routes
+	internal := api.PathPrefix("/internal").Subrouter()
+	internal.Use(internalMiddleware(internalToken))
+	internal.HandleFunc("/deletions", deletionHandler.HandleListDeletions).Methods("GET")
+	internal.HandleFunc("/deletions/{id}", deletionHandler.HandleGetDeletion).Methods("GET")
+	internal.HandleFunc("/deletions/{id}/retry", deletionHandler.HandleRetryPurge).Methods("POST")
+	internal.HandleFunc("/deletions/{id}/services/{service}", deletionHandler.HandleReportPurge).Methods("POST")
+	internal.HandleFunc("/blocks/check", blockHandler.HandleCheckBlocks).Methods("POST")
+	internal.HandleFunc("/blocks/{userID}", blockHandler.HandleGetBlockedUsers).Methods("GET")
+	internal.HandleFunc("/users/{userID}/friends", friendGraphHandler.HandleGetFriendIDs).Methods("GET")
+	internal.HandleFunc("/relationships/check", friendGraphHandler.HandleCheckRelationships).Methods("POST")
+	internal.HandleFunc("/audience-lists/check", audienceListHandler.HandleCheckMemberships).Methods("POST")
+	internal.HandleFunc("/users/{userID}/affiliations/{kind}/{affiliationID}", suggestionHandler.HandleAddAffiliation).Methods("PUT")
+	internal.HandleFunc("/users/{userID}/affiliations/{kind}/{affiliationID}", suggestionHandler.HandleRemoveAffiliation).Methods("DELETE")
+	
 	// CORS middleware
 	r.Use(corsMiddleware)
 	
@@ -92,6 +199,22 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// internalMiddleware only admits other services presenting the shared internal token.
+// With no token configured the internal routes are closed.
+func internalMiddleware(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get("X-Internal-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				util.RespondWithForbidden(w, "Internal endpoint")
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%s %s %s", r.Method, r.RequestURI, r.RemoteAddr)
@@ -4,17 +4,30 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Email    EmailConfig
-	Security SecurityConfig
-	Platform PlatformConfig
+	Server          ServerConfig
+	Database        DatabaseConfig
+	JWT             JWTConfig
+	Email           EmailConfig
+	Security        SecurityConfig
+	Platform        PlatformConfig
+	WebAuthn        WebAuthnConfig
+	GeoIP           GeoIPConfig
+	LoginProtection LoginProtectionConfig
+	OIDC            OIDCConfig
+	DataExport      DataExportConfig
+	AccountDeletion AccountDeletionConfig
+	Kafka           KafkaConfig
+	Redis           RedisConfig
+	Blocks          BlocksConfig
+	Suggestions     SuggestionsConfig
+	Media           MediaConfig
+	Usernames       UsernamesConfig
 }
 
 // ServerConfig holds server configuration
@@ -22,6 +35,7 @@ type ServerConfig struct {
 	Port        string
 	Environment string
 	ServiceName string
+	AppURL      string // web app base URL, used for links in emails
 }
 
 // DatabaseConfig holds database configuration
@@ -38,7 +52,8 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret              string
+	SigningKeysDir      string // directory of PKCS#8 *.pem private keys, one per kid
+	ActiveKeyID         string // kid used to sign new tokens; defaults to the newest
 	AccessTokenExpiry   time.Duration
 	RefreshTokenExpiry  time.Duration
 }
@@ -58,7 +73,15 @@ type SecurityConfig struct {
 	BcryptCost                int
 	PasswordMinLength         int
 	PasswordResetTokenExpiry  time.Duration
+	PasswordResetMaxPerEmail  int           // reset requests allowed per email per window
+	PasswordResetMaxPerIP     int           // reset requests allowed per IP per window
+	PasswordResetWindow       time.Duration
 	SessionExpiry             time.Duration
+	EmailVerificationExpiry   time.Duration
+	EmailChangeExpiry         time.Duration
+	EmailChangeUndoExpiry     time.Duration // how long the old address can revert a change
+	RequireVerifiedEmailForPasswordReset bool
+	InternalAPIToken          string        // X-Internal-Token other services present on /internal routes
 }
 
 // PlatformConfig holds cross-platform integration settings
@@ -66,6 +89,111 @@ type PlatformConfig struct {
 	VignetteAPIURL          string
 	EntativaAPIURL          string
 	EnableCrossPlatformSSO  bool
+	RequireVerifiedEmail    bool // only link accounts when both sides have verified the email
+}
+
+// GeoIPConfig configures the lookup used to show an approximate location for sessions
+type GeoIPConfig struct {
+	LookupURL string // e.g. http://geoip:8080/json/{ip}; empty disables lookups
+	CacheTTL  time.Duration
+}
+
+// LoginProtectionConfig holds the thresholds for login lockout and credential-stuffing defences.
+// Failures only count within FailureWindow; a successful login resets the account's count.
+type LoginProtectionConfig struct {
+	FailureWindow           time.Duration
+	AccountFailureThreshold int           // failures before an account is locked out
+	IPFailureThreshold      int           // failures before an IP is locked out
+	BackoffBase             time.Duration // first lockout; doubles with each further failure
+	MaxLockDuration         time.Duration
+	ChallengeAfterFailures  int           // account failures before a proof-of-work is required
+	IPAccountThreshold      int           // distinct accounts failing from one IP before a proof-of-work is required
+	ProofOfWorkDifficulty   int           // leading zero bits; 0 disables the challenge
+	ProofOfWorkTTL          time.Duration
+	NotifyNewDevice         bool          // email users when their password is used from a new device
+}
+
+// OIDCConfig configures the OAuth 2.0 / OpenID Connect provider
+type OIDCConfig struct {
+	Issuer               string        // public base URL of this service; must match what clients discover
+	ConsentURL           string        // web app page that asks the user to approve a client
+	AuthorizationCodeTTL time.Duration
+	AccessTokenTTL       time.Duration
+	IDTokenTTL           time.Duration
+}
+
+// DataExportConfig configures "Download Your Information" exports
+type DataExportConfig struct {
+	StorageDir        string        // directory finished archives are written to
+	ArchiveTTL        time.Duration // how long an archive can be downloaded before it is deleted
+	LinkTTL           time.Duration // lifetime of a download link handed out by the API
+	RequestCooldown   time.Duration // minimum time between a user's export requests
+	WorkerInterval    time.Duration // how often idle workers look for queued exports
+	LeaseDuration     time.Duration // how long a worker may go silent before another takes over
+	MaxAttempts       int           // attempts before an export is failed and the user told
+	PostServiceURL    string        // post service base URL, for posts, comments, likes and saves
+	PostServiceToken  string        // X-Internal-Token sent to the post service
+}
+
+// AccountDeletionConfig configures the grace period before an account is deleted
+// and the purge that follows it across services
+type AccountDeletionConfig struct {
+	GracePeriod     time.Duration // logging in before it ends cancels the deletion
+	Services        []string      // services that must confirm their purge before the account is removed
+	WorkerInterval  time.Duration // how often to start due purges and chase unconfirmed services
+	RetryBackoff    time.Duration // wait before asking a service again; doubles with each attempt
+	MaxRetryBackoff time.Duration
+}
+
+// KafkaConfig holds Kafka connection settings; with no brokers events are not published
+type KafkaConfig struct {
+	Brokers []string
+}
+
+// RedisConfig holds Redis connection settings; with no host nothing is cached
+type RedisConfig struct {
+	Host     string
+	Port     string
+	Password string
+	DB       int
+}
+
+// BlocksConfig configures the block list cache
+type BlocksConfig struct {
+	CacheTTL time.Duration // how long a user's cached block list lives; bounds how stale it can get
+}
+
+// SuggestionsConfig configures how "People You May Know" is precomputed
+type SuggestionsConfig struct {
+	WorkerInterval time.Duration // how often to recompute stale and expired suggestions
+	MaxAge         time.Duration // suggestions older than this are recomputed even if nothing changed
+	BatchSize      int           // users recomputed per pass by each instance
+	LeaseDuration  time.Duration // how long a claimed user is left to one instance
+}
+
+// MediaConfig locates the S3-compatible bucket profile pictures and cover photos
+// are published to; with no access key, photo uploads are turned off
+type MediaConfig struct {
+	Endpoint        string // empty for AWS, or a MinIO URL
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string // CDN or bucket URL photos are served from
+	UsePathStyle    bool   // true for MinIO
+}
+
+// UsernamesConfig limits how often usernames change and how long an old one is held
+type UsernamesConfig struct {
+	ChangeCooldown    time.Duration // minimum time between two changes by the same user
+	ReservationPeriod time.Duration // how long an old username is held for its previous owner and redirected
+}
+
+// WebAuthnConfig identifies this service as a WebAuthn relying party
+type WebAuthnConfig struct {
+	RPID    string   // effective domain passkeys are scoped to
+	RPName  string
+	Origins []string // origins allowed to run ceremonies
 }
 
 // Load loads configuration from environment variables
@@ -75,6 +203,7 @@ func Load() (*Config, error) {
 			Port:        getEnv("PORT", "8001"),
 			Environment: getEnv("ENV", "development"),
 			ServiceName: getEnv("SERVICE_NAME", "entativa-user-service"),
+			AppURL:      getEnv("APP_URL", "https://app.entativa.com"),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -87,8 +216,9 @@ func Load() (*Config, error) {
 			MaxIdleConnections: getEnvAsInt("DB_MAX_IDLE_CONNECTIONS", 5),
 		},
 		JWT: JWTConfig{
-			Secret:              getEnv("JWT_SECRET", "your-super-secret-key-change-this-in-production"),
-			AccessTokenExpiry:   getEnvAsDuration("JWT_ACCESS_TOKEN_EXPIRY", 24*time.Hour),
+			SigningKeysDir:      getEnv("JWT_SIGNING_KEYS_DIR", ""),
+			ActiveKeyID:         getEnv("JWT_ACTIVE_KEY_ID", ""),
+			AccessTokenExpiry:   getEnvAsDuration("JWT_ACCESS_TOKEN_EXPIRY", 15*time.Minute),
 			RefreshTokenExpiry:  getEnvAsDuration("JWT_REFRESH_TOKEN_EXPIRY", 720*time.Hour),
 		},
 		Email: EmailConfig{
@@ -103,12 +233,99 @@ func Load() (*Config, error) {
 			BcryptCost:               getEnvAsInt("BCRYPT_COST", 12),
 			PasswordMinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
 			PasswordResetTokenExpiry: getEnvAsDuration("PASSWORD_RESET_TOKEN_EXPIRY", 1*time.Hour),
+			PasswordResetMaxPerEmail: getEnvAsInt("PASSWORD_RESET_MAX_PER_EMAIL", 3),
+			PasswordResetMaxPerIP:    getEnvAsInt("PASSWORD_RESET_MAX_PER_IP", 10),
+			PasswordResetWindow:      getEnvAsDuration("PASSWORD_RESET_WINDOW", 1*time.Hour),
 			SessionExpiry:            getEnvAsDuration("SESSION_EXPIRY", 24*time.Hour),
+			EmailVerificationExpiry:  getEnvAsDuration("EMAIL_VERIFICATION_EXPIRY", 48*time.Hour),
+			EmailChangeExpiry:        getEnvAsDuration("EMAIL_CHANGE_EXPIRY", 1*time.Hour),
+			EmailChangeUndoExpiry:    getEnvAsDuration("EMAIL_CHANGE_UNDO_EXPIRY", 7*24*time.Hour),
+			RequireVerifiedEmailForPasswordReset: getEnvAsBool("REQUIRE_VERIFIED_EMAIL_FOR_PASSWORD_RESET", false),
+			InternalAPIToken:         getEnv("INTERNAL_API_TOKEN", ""),
 		},
 		Platform: PlatformConfig{
 			VignetteAPIURL:         getEnv("VIGNETTE_API_URL", "http://localhost:8002/api/v1"),
 			EntativaAPIURL:         getEnv("ENTATIVA_API_URL", "http://localhost:8001/api/v1"),
 			EnableCrossPlatformSSO: getEnvAsBool("ENABLE_CROSS_PLATFORM_SSO", true),
+			RequireVerifiedEmail:   getEnvAsBool("CROSS_PLATFORM_REQUIRE_VERIFIED_EMAIL", true),
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+			RPName:  getEnv("WEBAUTHN_RP_NAME", "Entativa"),
+			Origins: getEnvAsSlice("WEBAUTHN_ORIGINS", []string{"http://localhost:3000"}),
+		},
+		GeoIP: GeoIPConfig{
+			LookupURL: getEnv("GEOIP_LOOKUP_URL", ""),
+			CacheTTL:  getEnvAsDuration("GEOIP_CACHE_TTL", 24*time.Hour),
+		},
+		LoginProtection: LoginProtectionConfig{
+			FailureWindow:           getEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			AccountFailureThreshold: getEnvAsInt("LOGIN_ACCOUNT_FAILURE_THRESHOLD", 5),
+			IPFailureThreshold:      getEnvAsInt("LOGIN_IP_FAILURE_THRESHOLD", 20),
+			BackoffBase:             getEnvAsDuration("LOGIN_BACKOFF_BASE", 30*time.Second),
+			MaxLockDuration:         getEnvAsDuration("LOGIN_MAX_LOCK_DURATION", 15*time.Minute),
+			ChallengeAfterFailures:  getEnvAsInt("LOGIN_CHALLENGE_AFTER_FAILURES", 3),
+			IPAccountThreshold:      getEnvAsInt("LOGIN_IP_ACCOUNT_THRESHOLD", 5),
+			ProofOfWorkDifficulty:   getEnvAsInt("LOGIN_POW_DIFFICULTY", 20),
+			ProofOfWorkTTL:          getEnvAsDuration("LOGIN_POW_TTL", 2*time.Minute),
+			NotifyNewDevice:         getEnvAsBool("LOGIN_NOTIFY_NEW_DEVICE", true),
+		},
+		OIDC: OIDCConfig{
+			Issuer:               strings.TrimRight(getEnv("OIDC_ISSUER", "http://localhost:8001"), "/"),
+			ConsentURL:           getEnv("OIDC_CONSENT_URL", getEnv("APP_URL", "https://app.entativa.com")+"/oauth/consent"),
+			AuthorizationCodeTTL: getEnvAsDuration("OIDC_AUTHORIZATION_CODE_TTL", 1*time.Minute),
+			AccessTokenTTL:       getEnvAsDuration("OIDC_ACCESS_TOKEN_TTL", 1*time.Hour),
+			IDTokenTTL:           getEnvAsDuration("OIDC_ID_TOKEN_TTL", 1*time.Hour),
+		},
+		DataExport: DataExportConfig{
+			StorageDir:       getEnv("DATA_EXPORT_STORAGE_DIR", "/var/lib/entativa/exports"),
+			ArchiveTTL:       getEnvAsDuration("DATA_EXPORT_ARCHIVE_TTL", 4*24*time.Hour),
+			LinkTTL:          getEnvAsDuration("DATA_EXPORT_LINK_TTL", 1*time.Hour),
+			RequestCooldown:  getEnvAsDuration("DATA_EXPORT_REQUEST_COOLDOWN", 24*time.Hour),
+			WorkerInterval:   getEnvAsDuration("DATA_EXPORT_WORKER_INTERVAL", 30*time.Second),
+			LeaseDuration:    getEnvAsDuration("DATA_EXPORT_LEASE_DURATION", 5*time.Minute),
+			MaxAttempts:      getEnvAsInt("DATA_EXPORT_MAX_ATTEMPTS", 3),
+			PostServiceURL:   strings.TrimRight(getEnv("POST_SERVICE_URL", "http://localhost:8084"), "/"),
+			PostServiceToken: getEnv("POST_SERVICE_INTERNAL_TOKEN", ""),
+		},
+		AccountDeletion: AccountDeletionConfig{
+			GracePeriod:     getEnvAsDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			// Only services that consume user.deletion_requested belong here; one that never reports back blocks every purge
+			Services:        getEnvAsSlice("ACCOUNT_DELETION_SERVICES", []string{"post", "community", "search", "settings", "messaging"}),
+			WorkerInterval:  getEnvAsDuration("ACCOUNT_DELETION_WORKER_INTERVAL", 1*time.Minute),
+			RetryBackoff:    getEnvAsDuration("ACCOUNT_DELETION_RETRY_BACKOFF", 15*time.Minute),
+			MaxRetryBackoff: getEnvAsDuration("ACCOUNT_DELETION_MAX_RETRY_BACKOFF", 24*time.Hour),
+		},
+		Kafka: KafkaConfig{
+			Brokers: getEnvAsSlice("KAFKA_BROKERS", nil),
+		},
+		Redis: RedisConfig{
+			Host:     getEnv("REDIS_HOST", ""),
+			Port:     getEnv("REDIS_PORT", "6379"),
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getEnvAsInt("REDIS_DB", 0),
+		},
+		Blocks: BlocksConfig{
+			CacheTTL: getEnvAsDuration("BLOCK_CACHE_TTL", 10*time.Minute),
+		},
+		Suggestions: SuggestionsConfig{
+			WorkerInterval: getEnvAsDuration("FRIEND_SUGGESTIONS_WORKER_INTERVAL", 1*time.Minute),
+			MaxAge:         getEnvAsDuration("FRIEND_SUGGESTIONS_MAX_AGE", 24*time.Hour),
+			BatchSize:      getEnvAsInt("FRIEND_SUGGESTIONS_BATCH_SIZE", 50),
+			LeaseDuration:  getEnvAsDuration("FRIEND_SUGGESTIONS_LEASE_DURATION", 10*time.Minute),
+		},
+		Media: MediaConfig{
+			Endpoint:        getEnv("MEDIA_S3_ENDPOINT", ""),
+			Region:          getEnv("MEDIA_S3_REGION", "us-east-1"),
+			Bucket:          getEnv("MEDIA_S3_BUCKET", "entativa-media"),
+			AccessKeyID:     getEnv("MEDIA_S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("MEDIA_S3_SECRET_ACCESS_KEY", ""),
+			PublicURL:       strings.TrimRight(getEnv("MEDIA_PUBLIC_URL", ""), "/"),
+			UsePathStyle:    getEnvAsBool("MEDIA_S3_USE_PATH_STYLE", false),
+		},
+		Usernames: UsernamesConfig{
+			ChangeCooldown:    getEnvAsDuration("USERNAME_CHANGE_COOLDOWN", 14*24*time.Hour),
+			ReservationPeriod: getEnvAsDuration("USERNAME_RESERVATION_PERIOD", 30*24*time.Hour),
 		},
 	}
 	
@@ -128,10 +345,8 @@ func (c *Config) Validate() error {
 	if c.Database.Name == "" {
 		return fmt.Errorf("DB_NAME is required")
 	}
-	if c.JWT.Secret == "" || c.JWT.Secret == "your-super-secret-key-change-this-in-production" {
-		if c.Server.Environment == "production" {
-			return fmt.Errorf("JWT_SECRET must be set in production")
-		}
+	if c.JWT.SigningKeysDir == "" && c.Server.Environment == "production" {
+		return fmt.Errorf("JWT_SIGNING_KEYS_DIR must be set in production")
 	}
 	return nil
 }
@@ -186,6 +401,22 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return value
 }
 
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	
+	return values
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
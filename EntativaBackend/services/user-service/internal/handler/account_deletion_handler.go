@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// AccountDeletionHandler serves the internal side of account deletion: services
+// reporting on their purge of a deleted user, and the status of each purge
+type AccountDeletionHandler struct {
+	deletionService *service.AccountDeletionService
+	logger          *logger.Logger
+}
+
+// NewAccountDeletionHandler creates a new account deletion handler
+func NewAccountDeletionHandler(deletionService *service.AccountDeletionService, logger *logger.Logger) *AccountDeletionHandler {
+	return &AccountDeletionHandler{
+		deletionService: deletionService,
+		logger:          logger,
+	}
+}
+
+// PurgeReportRequest is a service's report on purging a deleted user's data
+type PurgeReportRequest struct {
+	Status string `json:"status"` // completed or failed
+	Error  string `json:"error,omitempty"`
+}
+
+// HandleListDeletions lists the deletions requested for the user given by ?user_id=
+func (h *AccountDeletionHandler) HandleListDeletions(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		util.RespondWithValidationError(w, "user_id", "user_id is required")
+		return
+	}
+
+	deletions, err := h.deletionService.ListDeletions(r.Context(), userID)
+	if err != nil {
+		h.respondWithDeletionError(w, err, "Failed to list account deletions")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", deletions)
+}
+
+// HandleGetDeletion shows a deletion and which services have finished purging
+func (h *AccountDeletionHandler) HandleGetDeletion(w http.ResponseWriter, r *http.Request) {
+	deletion, err := h.deletionService.GetDeletion(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithDeletionError(w, err, "Failed to get account deletion")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", deletion)
+}
+
+// HandleReportPurge records a service confirming, or failing, its purge
+func (h *AccountDeletionHandler) HandleReportPurge(w http.ResponseWriter, r *http.Request) {
+	var req PurgeReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Status != repository.DeletionStepCompleted && req.Status != repository.DeletionStepFailed {
+		util.RespondWithValidationError(w, "status", "status must be completed or failed")
+		return
+	}
+
+	vars := mux.Vars(r)
+	err := h.deletionService.ReportPurge(r.Context(), vars["id"], vars["service"], req.Status == repository.DeletionStepCompleted, req.Error)
+	if err != nil {
+		h.respondWithDeletionError(w, err, "Failed to record purge")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Purge recorded", nil)
+}
+
+// HandleRetryPurge asks services that have not confirmed a purge again straight away
+func (h *AccountDeletionHandler) HandleRetryPurge(w http.ResponseWriter, r *http.Request) {
+	deletion, err := h.deletionService.RetryPurge(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithDeletionError(w, err, "Failed to retry purge")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Unconfirmed services will be asked again shortly", deletion)
+}
+
+// respondWithDeletionError maps account deletion service errors to responses
+func (h *AccountDeletionHandler) respondWithDeletionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAccountDeletionNotFound):
+		util.RespondWithNotFound(w, err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+const (
+	// maxListMemberPageSize caps how many members one page of a list may hold
+	maxListMemberPageSize = 100
+
+	// maxListMembersPerRequest caps how many people one request may add or remove
+	maxListMembersPerRequest = 500
+
+	// maxAudienceCheckBatch caps how many memberships one check may ask about
+	maxAudienceCheckBatch = 1000
+)
+
+// AudienceListHandler lets users manage Close Friends and their own audience
+// lists, and answers membership checks from the post and story services
+type AudienceListHandler struct {
+	listService *service.AudienceListService
+	logger      *logger.Logger
+}
+
+// NewAudienceListHandler creates a new audience list handler
+func NewAudienceListHandler(listService *service.AudienceListService, logger *logger.Logger) *AudienceListHandler {
+	return &AudienceListHandler{
+		listService: listService,
+		logger:      logger,
+	}
+}
+
+// AudienceListRequest names a list
+type AudienceListRequest struct {
+	Name string `json:"name"`
+}
+
+// AudienceListMembersRequest lists people to add to or remove from a list
+type AudienceListMembersRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// AudienceMembershipCheck asks whether ViewerID is in OwnerID's list ListID
+type AudienceMembershipCheck struct {
+	OwnerID  string `json:"owner_id"`
+	ListID   string `json:"list_id"`
+	ViewerID string `json:"viewer_id"`
+}
+
+// AudienceCheckRequest is a batch of membership checks
+type AudienceCheckRequest struct {
+	Checks []AudienceMembershipCheck `json:"checks"`
+}
+
+// AudienceCheckResult answers one membership check
+type AudienceCheckResult struct {
+	AudienceMembershipCheck
+	IsMember bool `json:"is_member"`
+}
+
+// HandleListLists returns all of the current user's lists
+func (h *AudienceListHandler) HandleListLists(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	lists, err := h.listService.ListLists(r.Context(), user.ID)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to get lists")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"lists": lists,
+	})
+}
+
+// HandleCreateList makes a new list for the current user
+func (h *AudienceListHandler) HandleCreateList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req AudienceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, err := h.listService.CreateList(r.Context(), user.ID, req.Name)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to create list")
+		return
+	}
+
+	util.RespondWithSuccess(w, "List created", list)
+}
+
+// HandleGetList returns one of the current user's lists. The list ID may be
+// "close-friends".
+func (h *AudienceListHandler) HandleGetList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	list, err := h.listService.GetList(r.Context(), user.ID, mux.Vars(r)["listID"])
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to get list")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", list)
+}
+
+// HandleRenameList renames one of the current user's lists
+func (h *AudienceListHandler) HandleRenameList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req AudienceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, err := h.listService.RenameList(r.Context(), user.ID, mux.Vars(r)["listID"], req.Name)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to rename list")
+		return
+	}
+
+	util.RespondWithSuccess(w, "List renamed", list)
+}
+
+// HandleDeleteList deletes one of the current user's lists
+func (h *AudienceListHandler) HandleDeleteList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	if err := h.listService.DeleteList(r.Context(), user.ID, mux.Vars(r)["listID"]); err != nil {
+		h.respondWithListError(w, err, "Failed to delete list")
+		return
+	}
+
+	util.RespondWithSuccess(w, "List deleted", nil)
+}
+
+// HandleListMembers returns a page of a list's members
+func (h *AudienceListHandler) HandleListMembers(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	page := 1
+	limit := 50
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= maxListMemberPageSize {
+			limit = l
+		}
+	}
+
+	members, err := h.listService.ListMembers(r.Context(), user.ID, mux.Vars(r)["listID"], limit, (page-1)*limit)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to get list members")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"members":  members,
+		"page":     page,
+		"limit":    limit,
+		"has_more": len(members) == limit,
+	})
+}
+
+// HandleAddMembers adds people to one of the current user's lists
+func (h *AudienceListHandler) HandleAddMembers(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	req, ok := decodeListMembersRequest(w, r)
+	if !ok {
+		return
+	}
+
+	added, err := h.listService.AddMembers(r.Context(), user.ID, mux.Vars(r)["listID"], req.UserIDs)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to add list members")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"added": added,
+	})
+}
+
+// HandleRemoveMembers takes people out of one of the current user's lists
+func (h *AudienceListHandler) HandleRemoveMembers(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	req, ok := decodeListMembersRequest(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := h.listService.RemoveMembers(r.Context(), user.ID, mux.Vars(r)["listID"], req.UserIDs)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to remove list members")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"removed": removed,
+	})
+}
+
+// HandleCheckMemberships answers a batch of "is the viewer in list X of owner Y"
+// checks, in the order asked. The post and story services use it to decide who
+// may see content shared with a list.
+func (h *AudienceListHandler) HandleCheckMemberships(w http.ResponseWriter, r *http.Request) {
+	var req AudienceCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Checks) > maxAudienceCheckBatch {
+		util.RespondWithValidationError(w, "checks", "at most 1000 checks can be made at once")
+		return
+	}
+
+	checks := make([]repository.AudienceMembershipCheck, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		if !util.IsValidUUID(check.OwnerID) || !util.IsValidUUID(check.ListID) || !util.IsValidUUID(check.ViewerID) {
+			util.RespondWithValidationError(w, "checks", "owner_id, list_id and viewer_id must be valid IDs")
+			return
+		}
+		checks = append(checks, repository.AudienceMembershipCheck{
+			OwnerID:  check.OwnerID,
+			ListID:   check.ListID,
+			ViewerID: check.ViewerID,
+		})
+	}
+
+	memberships, err := h.listService.CheckMemberships(r.Context(), checks)
+	if err != nil {
+		h.respondWithListError(w, err, "Failed to check list memberships")
+		return
+	}
+
+	results := make([]AudienceCheckResult, 0, len(req.Checks))
+	for i, check := range req.Checks {
+		results = append(results, AudienceCheckResult{
+			AudienceMembershipCheck: check,
+			IsMember:                memberships[i],
+		})
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"results": results,
+	})
+}
+
+func decodeListMembersRequest(w http.ResponseWriter, r *http.Request) (*AudienceListMembersRequest, bool) {
+	var req AudienceListMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if len(req.UserIDs) == 0 {
+		util.RespondWithValidationError(w, "user_ids", "user_ids is required")
+		return nil, false
+	}
+	if len(req.UserIDs) > maxListMembersPerRequest {
+		util.RespondWithValidationError(w, "user_ids", "at most 500 user_ids can be changed at once")
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// respondWithListError maps audience list service errors to responses
+func (h *AudienceListHandler) respondWithListError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAudienceListNotFound):
+		util.RespondWithNotFound(w, err.Error())
+	case errors.Is(err, service.ErrInvalidAudienceListName):
+		util.RespondWithValidationError(w, "name", err.Error())
+	case errors.Is(err, service.ErrInvalidListMember):
+		util.RespondWithValidationError(w, "user_ids", err.Error())
+	case errors.Is(err, service.ErrAudienceListNameTaken):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrTooManyAudienceLists),
+		errors.Is(err, service.ErrAudienceListFull),
+		errors.Is(err, service.ErrCloseFriendsListFixed),
+		errors.Is(err, service.ErrCannotAddSelfToList):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
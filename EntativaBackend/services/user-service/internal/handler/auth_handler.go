@@ -3,8 +3,11 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"user-service/internal/config"
@@ -16,13 +19,20 @@ import (
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	userRepo     *repository.UserRepository
-	sessionRepo  *repository.SessionRepository
-	tokenRepo    *repository.TokenRepository
-	emailService *service.EmailService
-	auditLog     *service.AuditLog
-	logger       *logger.Logger
-	config       *config.Config
+	userRepo       *repository.UserRepository
+	sessionRepo    *repository.SessionRepository
+	tokenRepo      *repository.TokenRepository
+	sessionService *service.SessionService
+	authService    *service.AuthService
+	twoFactor      *service.TwoFactorService
+	webAuthn       *service.WebAuthnService
+	emailService   *service.EmailService
+	emailVerifier  *service.EmailVerificationService
+	identities     *service.IdentityService
+	deletions      *service.AccountDeletionService
+	auditLog       *service.AuditLog
+	logger         *logger.Logger
+	config         *config.Config
 }
 
 // NewAuthHandler creates a new auth handler
@@ -30,19 +40,33 @@ func NewAuthHandler(
 	userRepo *repository.UserRepository,
 	sessionRepo *repository.SessionRepository,
 	tokenRepo *repository.TokenRepository,
+	sessionService *service.SessionService,
+	authService *service.AuthService,
+	twoFactor *service.TwoFactorService,
+	webAuthn *service.WebAuthnService,
 	emailService *service.EmailService,
+	emailVerifier *service.EmailVerificationService,
+	identities *service.IdentityService,
+	deletions *service.AccountDeletionService,
 	auditLog *service.AuditLog,
 	logger *logger.Logger,
 	cfg *config.Config,
 ) *AuthHandler {
 	return &AuthHandler{
-		userRepo:     userRepo,
-		sessionRepo:  sessionRepo,
-		tokenRepo:    tokenRepo,
-		emailService: emailService,
-		auditLog:     auditLog,
-		logger:       logger,
-		config:       cfg,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		tokenRepo:      tokenRepo,
+		sessionService: sessionService,
+		authService:    authService,
+		twoFactor:      twoFactor,
+		webAuthn:       webAuthn,
+		emailService:   emailService,
+		emailVerifier:  emailVerifier,
+		identities:     identities,
+		deletions:      deletions,
+		auditLog:       auditLog,
+		logger:         logger,
+		config:         cfg,
 	}
 }
 
@@ -60,6 +84,8 @@ type SignUpRequest struct {
 type LoginRequest struct {
 	EmailOrUsername string `json:"email_or_username"`
 	Password        string `json:"password"`
+	PowChallenge    string `json:"pow_challenge,omitempty"` // set when retrying after a proof-of-work challenge
+	PowSolution     string `json:"pow_solution,omitempty"`
 }
 
 // UserResponse represents user data in responses
@@ -73,16 +99,39 @@ type UserResponse struct {
 	Gender            *string `json:"gender,omitempty"`
 	ProfilePictureURL *string `json:"profile_picture_url,omitempty"`
 	CoverPhotoURL     *string `json:"cover_photo_url,omitempty"`
+	EmailVerified     bool    `json:"email_verified"`
 	IsActive          bool    `json:"is_active"`
 	CreatedAt         string  `json:"created_at"`
 }
 
 // AuthResponse represents authentication response with token
 type AuthResponse struct {
-	User        *UserResponse `json:"user"`
-	AccessToken string        `json:"access_token"`
-	TokenType   string        `json:"token_type"`
-	ExpiresIn   int           `json:"expires_in"`
+	User         *UserResponse `json:"user"`
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	TokenType    string        `json:"token_type"`
+	ExpiresIn    int           `json:"expires_in"`
+}
+
+// TwoFactorChallengeResponse is returned by login when a second factor is required
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool     `json:"two_factor_required"`
+	TwoFactorMethods  []string `json:"two_factor_methods"`
+	ChallengeToken    string   `json:"challenge_token"`
+	ExpiresIn         int      `json:"expires_in"`
+}
+
+// RefreshTokenRequest represents refresh token request payload
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshTokenResponse represents a rotated token pair
+type RefreshTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 // HandleSignUp handles user sign up
@@ -158,6 +207,7 @@ func (h *AuthHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 		Email:        req.Email,
 		Username:     username,
 		PasswordHash: hashedPassword,
+		HasPassword:  true,
 		Birthday:     birthday,
 		Gender:       &req.Gender,
 		IsActive:     true,
@@ -173,37 +223,9 @@ func (h *AuthHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Generate access token
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Username, user.Email)
-	if err != nil {
-		h.logger.Error("Failed to generate access token", err)
-		util.RespondWithInternalError(w, "Failed to create session")
-		return
-	}
-	
-	// Generate refresh token
-	refreshToken, err := util.GenerateRefreshToken(user.ID)
+	// Create session with access and refresh tokens
+	tokens, err := h.sessionService.IssueSession(r.Context(), user, getIPAddress(r), r.UserAgent())
 	if err != nil {
-		h.logger.Error("Failed to generate refresh token", err)
-		util.RespondWithInternalError(w, "Failed to create session")
-		return
-	}
-	
-	// Create session
-	session := &repository.Session{
-		ID:           util.GenerateUUID(),
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		DeviceInfo:   r.UserAgent(),
-		IPAddress:    getIPAddress(r),
-		UserAgent:    r.UserAgent(),
-		ExpiresAt:    time.Now().Add(h.config.Security.SessionExpiry),
-		CreatedAt:    time.Now(),
-		LastActiveAt: time.Now(),
-	}
-	
-	if err := h.sessionRepo.CreateSession(r.Context(), session); err != nil {
 		h.logger.Error("Failed to create session", err)
 		util.RespondWithInternalError(w, "Failed to create session")
 		return
@@ -212,19 +234,26 @@ func (h *AuthHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 	// Send welcome email (async)
 	go h.emailService.SendWelcomeEmail(user.Email, user.FirstName)
 	
+	// Ask the user to confirm their address
+	if err := h.emailVerifier.SendVerification(r.Context(), user); err != nil {
+		h.logger.Error("Failed to send verification email", err)
+	}
+	
 	// Log sign up
 	h.auditLog.LogSignUp(user.ID, getIPAddress(r), r.UserAgent())
 	
 	// Return response
 	util.RespondWithCreated(w, "Account created successfully! Welcome to Entativa!", AuthResponse{
-		User:        mapUserToResponse(user),
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   86400, // 24 hours
+		User:         mapUserToResponse(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
 	})
 }
 
-// HandleLogin handles user login
+// HandleLogin handles user login. With 2FA enabled the response carries a
+// challenge instead of tokens; see HandleTwoFactorLogin.
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	
@@ -233,79 +262,81 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Find user by email or username
-	user, err := h.userRepo.FindByEmailOrUsername(r.Context(), req.EmailOrUsername)
-	if err != nil {
-		h.auditLog.LogFailedLogin(req.EmailOrUsername, getIPAddress(r), "user_not_found")
-		util.RespondWithError(w, http.StatusUnauthorized, "Invalid credentials")
-		return
-	}
-	
-	// Check if user is active
-	if !user.IsActive {
-		util.RespondWithError(w, http.StatusForbidden, "Account is deactivated")
-		return
+	login := &service.PasswordLogin{
+		EmailOrUsername: req.EmailOrUsername,
+		Password:        req.Password,
+		PowChallenge:    req.PowChallenge,
+		PowSolution:     req.PowSolution,
 	}
 	
-	// Verify password
-	if !util.ComparePassword(user.PasswordHash, req.Password) {
-		h.auditLog.LogFailedLogin(req.EmailOrUsername, getIPAddress(r), "invalid_password")
-		util.RespondWithError(w, http.StatusUnauthorized, "Invalid credentials")
-		return
-	}
-	
-	// Generate tokens
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Username, user.Email)
+	result, err := h.authService.Login(r.Context(), login, getIPAddress(r), r.UserAgent())
 	if err != nil {
-		h.logger.Error("Failed to generate access token", err)
-		util.RespondWithInternalError(w, "Failed to create session")
+		h.respondWithLoginError(w, err)
 		return
 	}
 	
-	refreshToken, err := util.GenerateRefreshToken(user.ID)
-	if err != nil {
-		h.logger.Error("Failed to generate refresh token", err)
-		util.RespondWithInternalError(w, "Failed to create session")
-		return
-	}
-	
-	// Create session
-	session := &repository.Session{
-		ID:           util.GenerateUUID(),
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		DeviceInfo:   r.UserAgent(),
-		IPAddress:    getIPAddress(r),
-		UserAgent:    r.UserAgent(),
-		ExpiresAt:    time.Now().Add(h.config.Security.SessionExpiry),
-		CreatedAt:    time.Now(),
-		LastActiveAt: time.Now(),
-	}
-	
-	if err := h.sessionRepo.CreateSession(r.Context(), session); err != nil {
-		h.logger.Error("Failed to create session", err)
-		util.RespondWithInternalError(w, "Failed to create session")
+	h.respondWithLogin(w, result)
+}
+
+// respondWithLogin writes the session, or the second-factor challenge, from a login step
+func (h *AuthHandler) respondWithLogin(w http.ResponseWriter, result *service.LoginResult) {
+	if result.Challenge != nil {
+		util.RespondWithSuccess(w, "Confirm it's you with your second factor to finish signing in", TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			TwoFactorMethods:  result.Challenge.Methods,
+			ChallengeToken:    result.Challenge.Token,
+			ExpiresIn:         result.Challenge.ExpiresIn,
+		})
 		return
 	}
 	
-	// Update last login
-	if err := h.userRepo.UpdateLastLogin(r.Context(), user.ID); err != nil {
-		h.logger.Warn("Failed to update last login", err)
+	message := "Login successful! Welcome back!"
+	if result.Restored {
+		message = "Welcome back! Your account is no longer scheduled for deletion."
 	}
 	
-	// Log login
-	h.auditLog.LogLogin(user.ID, getIPAddress(r), r.UserAgent())
-	
-	// Return response
-	util.RespondWithSuccess(w, "Login successful! Welcome back!", AuthResponse{
-		User:        mapUserToResponse(user),
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   86400,
+	util.RespondWithSuccess(w, message, AuthResponse{
+		User:         mapUserToResponse(result.User),
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    result.Tokens.ExpiresIn,
 	})
 }
 
+// respondWithLoginError maps login failures to responses that don't reveal which check failed
+func (h *AuthHandler) respondWithLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LoginLockedError
+	var powRequired *service.ProofOfWorkRequiredError
+	
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		util.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.As(err, &powRequired):
+		// The client solves the puzzle and repeats the login with pow_challenge and pow_solution
+		util.RespondWithJSON(w, http.StatusPreconditionRequired, util.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+			Details: powRequired.Challenge,
+		})
+	case errors.Is(err, service.ErrInvalidCredentials):
+		util.RespondWithError(w, http.StatusUnauthorized, "Invalid credentials")
+	case errors.Is(err, service.ErrUserNotActive):
+		util.RespondWithError(w, http.StatusForbidden, "Account is deactivated")
+	case errors.Is(err, service.ErrInvalidChallenge),
+		errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrWebAuthnCeremonyInvalid),
+		errors.Is(err, service.ErrWebAuthnCredential):
+		util.RespondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTooManyTwoFactorTries):
+		util.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		h.logger.Error("Failed to log in", err)
+		util.RespondWithInternalError(w, "Failed to log in")
+	}
+}
+
 // HandleGetCurrentUser returns the current authenticated user
 func (h *AuthHandler) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
@@ -320,11 +351,10 @@ func (h *AuthHandler) HandleGetCurrentUser(w http.ResponseWriter, r *http.Reques
 
 // HandleLogout handles user logout
 func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
-	// Get token from header
-	authHeader := r.Header.Get("Authorization")
-	token, err := util.ExtractTokenFromHeader(authHeader)
-	if err != nil {
-		util.RespondWithUnauthorized(w, "Invalid authorization header")
+	// Only the session this request was made with is ended; see /auth/sessions for other devices
+	sessionID, ok := r.Context().Value("session_id").(string)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
 		return
 	}
 	
@@ -335,50 +365,43 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Delete session
-	if err := h.sessionRepo.DeleteSession(r.Context(), token); err != nil {
+	if err := h.sessionRepo.RevokeSessionFamily(r.Context(), sessionID); err != nil {
 		h.logger.Warn("Failed to delete session", err)
 	}
 	
 	util.RespondWithSuccess(w, "Logged out successfully", nil)
 }
 
-// HandleRefreshToken refreshes an access token
+// HandleRefreshToken exchanges a refresh token for a new token pair.
+// The presented refresh token is single-use; replaying it revokes the session.
 func (h *AuthHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		RefreshToken string `json:"refresh_token"`
-	}
+	var req RefreshTokenRequest
 	
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	
-	// Validate refresh token
-	claims, err := util.ParseAccessToken(req.RefreshToken)
+	tokens, err := h.sessionService.RefreshSession(r.Context(), req.RefreshToken, getIPAddress(r))
 	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
-		return
-	}
-	
-	// Get user
-	user, err := h.userRepo.FindByID(r.Context(), claims.UserID)
-	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, "User not found")
-		return
-	}
-	
-	// Generate new access token
-	accessToken, err := util.GenerateAccessToken(user.ID, user.Username, user.Email)
-	if err != nil {
-		h.logger.Error("Failed to generate access token", err)
-		util.RespondWithInternalError(w, "Failed to refresh token")
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			h.logger.Warn("Refresh token reuse detected", err)
+			util.RespondWithUnauthorized(w, "Session has been revoked. Please log in again")
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			util.RespondWithUnauthorized(w, "Invalid or expired refresh token")
+		default:
+			h.logger.Error("Failed to refresh token", err)
+			util.RespondWithInternalError(w, "Failed to refresh token")
+		}
 		return
 	}
 	
-	util.RespondWithSuccess(w, "Token refreshed successfully", map[string]interface{}{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
-		"expires_in":   86400,
+	util.RespondWithSuccess(w, "Token refreshed successfully", RefreshTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
 	})
 }
 
@@ -402,19 +425,20 @@ func (h *AuthHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
-func (h *AuthHandler) generateAccessToken(userID string) (string, error) {
-	user, err := h.userRepo.FindByID(context.Background(), userID)
-	if err != nil {
-		return "", err
-	}
-	return util.GenerateAccessToken(user.ID, user.Username, user.Email)
-}
-
 func (h *AuthHandler) parseAccessToken(token string) (*util.TokenClaims, error) {
 	return util.ParseAccessToken(token)
 }
 
 func (h *AuthHandler) ensureUniqueUsername(ctx context.Context, baseUsername string) (string, error) {
+	// A name containing a reserved word stays reserved whatever number follows it
+	reserved, err := h.userRepo.IsUsernameReserved(ctx, baseUsername)
+	if err != nil {
+		return "", err
+	}
+	if reserved {
+		baseUsername = "user"
+	}
+	
 	username := baseUsername
 	counter := 1
 	
@@ -458,6 +482,7 @@ func mapUserToResponse(user *repository.User) *UserResponse {
 		Gender:            user.Gender,
 		ProfilePictureURL: user.ProfilePictureURL,
 		CoverPhotoURL:     user.CoverPhotoURL,
+		EmailVerified:     user.HasVerifiedEmail(),
 		IsActive:          user.IsActive,
 		CreatedAt:         user.CreatedAt.Format(time.RFC3339),
 	}
@@ -509,8 +534,3 @@ func getIPAddress(r *http.Request) string {
 	// Fallback to RemoteAddr
 	return r.RemoteAddr
 }
-
-func generateRefreshToken() string {
-	token, _ := util.GenerateSecureToken(32)
-	return token
-}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// maxBlockCheckBatch caps how many users one block check may ask about
+const maxBlockCheckBatch = 1000
+
+// BlockHandler serves the block graph to other services, so they can hide blocked
+// users from each other in feeds, search and messaging
+type BlockHandler struct {
+	blockService *service.BlockService
+	logger       *logger.Logger
+}
+
+// NewBlockHandler creates a new block handler
+func NewBlockHandler(blockService *service.BlockService, logger *logger.Logger) *BlockHandler {
+	return &BlockHandler{
+		blockService: blockService,
+		logger:       logger,
+	}
+}
+
+// BlockCheckRequest asks which of UserIDs are blocked from UserID, either way
+type BlockCheckRequest struct {
+	UserID  string   `json:"user_id"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// HandleGetBlockedUsers lists everyone the user has blocked or been blocked by
+func (h *BlockHandler) HandleGetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+
+	userIDs, err := h.blockService.BlockedUserIDs(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get blocked users", err)
+		util.RespondWithInternalError(w, "Failed to get blocked users")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"user_id":          userID,
+		"blocked_user_ids": userIDs,
+	})
+}
+
+// HandleCheckBlocks returns which of a batch of users are blocked from a user, either way
+func (h *BlockHandler) HandleCheckBlocks(w http.ResponseWriter, r *http.Request) {
+	var req BlockCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		util.RespondWithValidationError(w, "user_id", "user_id is required")
+		return
+	}
+	if len(req.UserIDs) > maxBlockCheckBatch {
+		util.RespondWithValidationError(w, "user_ids", "at most 1000 user_ids can be checked at once")
+		return
+	}
+
+	blocked, err := h.blockService.FilterBlocked(r.Context(), req.UserID, req.UserIDs)
+	if err != nil {
+		h.logger.Error("Failed to check blocks", err)
+		util.RespondWithInternalError(w, "Failed to check blocks")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"user_id":          req.UserID,
+		"blocked_user_ids": blocked,
+	})
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"user-service/internal/repository"
@@ -17,34 +16,21 @@ import (
 
 // CrossPlatformSignInRequest represents cross-platform sign-in request
 type CrossPlatformSignInRequest struct {
-	Platform    string `json:"platform" validate:"required,oneof=vignette entativa"`
-	AccessToken string `json:"access_token" validate:"required"`
-}
-
-// CrossPlatformSignInResponse represents cross-platform sign-in response
-type CrossPlatformSignInResponse struct {
-	Success bool                     `json:"success"`
-	Message string                   `json:"message"`
-	Data    *CrossPlatformSignInData `json:"data,omitempty"`
-}
-
-// CrossPlatformSignInData contains user and token data
-type CrossPlatformSignInData struct {
-	User         *UserResponse `json:"user"`
-	AccessToken  string        `json:"access_token"`
-	TokenType    string        `json:"token_type"`
-	ExpiresIn    int           `json:"expires_in"`
-	IsNewAccount bool          `json:"is_new_account"`
+	Platform     string `json:"platform" validate:"required,oneof=vignette entativa"`
+	AccessToken  string `json:"access_token" validate:"required"`
+	PowChallenge string `json:"pow_challenge,omitempty"` // set when retrying after a 428
+	PowSolution  string `json:"pow_solution,omitempty"`
 }
 
 // VignetteUserInfo represents user info from Vignette API
 type VignetteUserInfo struct {
-	ID                string `json:"id"`
-	Username          string `json:"username"`
-	Email             string `json:"email"`
-	FullName          string `json:"full_name"`
-	ProfilePictureURL string `json:"profile_picture_url"`
-	IsVerified        bool   `json:"is_verified"`
+	ID                string  `json:"id"`
+	Username          string  `json:"username"`
+	Email             string  `json:"email"`
+	FullName          string  `json:"full_name"`
+	ProfilePictureURL *string `json:"profile_picture_url"`
+	IsVerified        bool    `json:"is_verified"`
+	EmailVerified     bool    `json:"email_verified"`
 }
 
 // HandleCrossPlatformSignIn handles signing in with another platform's credentials
@@ -53,13 +39,13 @@ func (h *AuthHandler) HandleCrossPlatformSignIn(w http.ResponseWriter, r *http.R
 	
 	// Decode request
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	
 	// Validate platform
 	if req.Platform != "vignette" && req.Platform != "entativa" {
-		respondWithError(w, http.StatusBadRequest, "Invalid platform. Must be 'vignette' or 'entativa'")
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid platform. Must be 'vignette' or 'entativa'")
 		return
 	}
 	
@@ -75,25 +61,36 @@ func (h *AuthHandler) HandleCrossPlatformSignIn(w http.ResponseWriter, r *http.R
 	}
 	
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token from "+req.Platform)
+		util.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired token from "+req.Platform)
+		return
+	}
+	
+	// An identity the user linked (or merged) signs in to that account, whatever its email
+	if linkedUser, err := h.identities.FindLinkedUser(r.Context(), req.Platform, userInfo.ID); err == nil {
+		h.completeCrossPlatformSignIn(w, r, linkedUser, &req)
 		return
 	}
 	
 	// Check if user already exists in our system
 	existingUser, err := h.userRepo.FindByEmail(r.Context(), userInfo.Email)
 	
-	isNewAccount := false
-	var user *User
+	var user *repository.User
 	
 	if err != nil || existingUser == nil {
 		// Create new user from platform data
-		isNewAccount = true
 		user, err = h.createUserFromCrossPlatform(r.Context(), userInfo, req.Platform)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create user account")
+			util.RespondWithError(w, http.StatusInternalServerError, "Failed to create user account")
 			return
 		}
 	} else {
+		// Matching on email only proves ownership if both platforms confirmed it;
+		// otherwise anyone could register the address on one side and take over the other
+		if h.config.Platform.RequireVerifiedEmail && (!userInfo.EmailVerified || !existingUser.HasVerifiedEmail()) {
+			util.RespondWithError(w, http.StatusForbidden, "Verify your email address on both platforms before linking accounts")
+			return
+		}
+		
 		user = existingUser
 		// Update cross-platform link
 		if err := h.userRepo.LinkCrossPlatformAccount(r.Context(), user.ID, req.Platform, userInfo.ID); err != nil {
@@ -102,45 +99,19 @@ func (h *AuthHandler) HandleCrossPlatformSignIn(w http.ResponseWriter, r *http.R
 		}
 	}
 	
-	// Generate new access token for our platform
-	accessToken, err := h.generateAccessToken(user.ID)
+	h.completeCrossPlatformSignIn(w, r, user, &req)
+}
+
+// completeCrossPlatformSignIn signs in a user the other platform vouched for, the
+// same way a password login does: a second factor is still asked for if one is set up
+func (h *AuthHandler) completeCrossPlatformSignIn(w http.ResponseWriter, r *http.Request, user *repository.User, req *CrossPlatformSignInRequest) {
+	result, err := h.authService.LoginWithPlatform(r.Context(), user, req.Platform, req.PowChallenge, req.PowSolution, getIPAddress(r), r.UserAgent())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate access token")
-		return
-	}
-	
-	// Create session
-	session := &Session{
-		ID:           generateUUID(),
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: generateRefreshToken(),
-		DeviceInfo:   r.UserAgent(),
-		IPAddress:    r.RemoteAddr,
-		ExpiresAt:    time.Now().Add(24 * time.Hour),
-		CreatedAt:    time.Now(),
-	}
-	
-	if err := h.sessionRepo.CreateSession(r.Context(), session); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		h.respondWithLoginError(w, err)
 		return
 	}
 	
-	// Log the cross-platform sign-in
-	h.auditLog.LogCrossPlatformSignIn(user.ID, req.Platform, r.RemoteAddr)
-	
-	// Return response
-	respondWithJSON(w, http.StatusOK, CrossPlatformSignInResponse{
-		Success: true,
-		Message: fmt.Sprintf("Successfully signed in with %s", req.Platform),
-		Data: &CrossPlatformSignInData{
-			User:         mapUserToResponse(user),
-			AccessToken:  accessToken,
-			TokenType:    "Bearer",
-			ExpiresIn:    86400, // 24 hours
-			IsNewAccount: isNewAccount,
-		},
-	})
+	h.respondWithLogin(w, result)
 }
 
 // HandleCheckCrossPlatformAccount checks if user exists on platform
@@ -148,7 +119,7 @@ func (h *AuthHandler) HandleCheckCrossPlatformAccount(w http.ResponseWriter, r *
 	email := r.URL.Query().Get("email")
 	
 	if email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email parameter is required")
+		util.RespondWithError(w, http.StatusBadRequest, "Email parameter is required")
 		return
 	}
 	
@@ -156,7 +127,7 @@ func (h *AuthHandler) HandleCheckCrossPlatformAccount(w http.ResponseWriter, r *
 	user, err := h.userRepo.FindByEmail(r.Context(), email)
 	exists := err == nil && user != nil
 	
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+	util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"exists": exists,
 		"email":  email,
 	})
@@ -223,6 +194,7 @@ func (h *AuthHandler) verifyEntativaToken(ctx context.Context, token string) (*V
 		FullName:          fmt.Sprintf("%s %s", user.FirstName, user.LastName),
 		ProfilePictureURL: user.ProfilePictureURL,
 		IsVerified:        user.IsVerified,
+		EmailVerified:     user.HasVerifiedEmail(),
 	}, nil
 }
 
@@ -231,27 +203,47 @@ func (h *AuthHandler) createUserFromCrossPlatform(
 	ctx context.Context,
 	userInfo *VignetteUserInfo,
 	sourcePlatform string,
-) (*User, error) {
+) (*repository.User, error) {
 	// Split full name into first and last name
 	firstName, lastName := splitFullName(userInfo.FullName)
 	
 	// Generate a random password (user won't use it, they'll use cross-platform sign-in)
 	randomPassword := generateRandomPassword(32)
-	hashedPassword, err := hashPassword(randomPassword)
+	hashedPassword, err := util.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Keep the other platform's username where it is free here; otherwise it gets
+	// the same reserved-name and uniqueness checks as a sign-up
+	baseUsername := userInfo.Username
+	if !util.IsValidUsername(baseUsername) {
+		baseUsername = generateUsernameFromEmail(userInfo.Email)
+	}
+	username, err := h.ensureUniqueUsername(ctx, baseUsername)
 	if err != nil {
 		return nil, err
 	}
 	
-	user := &User{
-		ID:                generateUUID(),
+	// The other platform has already confirmed the address
+	var emailVerifiedAt *time.Time
+	if userInfo.EmailVerified {
+		now := time.Now()
+		emailVerifiedAt = &now
+	}
+	
+	user := &repository.User{
+		ID:                util.GenerateUUID(),
 		FirstName:         firstName,
 		LastName:          lastName,
 		Email:             userInfo.Email,
-		Username:          userInfo.Username,
+		Username:          username,
 		PasswordHash:      hashedPassword,
+		HasPassword:       false,
 		ProfilePictureURL: userInfo.ProfilePictureURL,
 		IsActive:          true,
 		IsVerified:        userInfo.IsVerified,
+		EmailVerifiedAt:   emailVerifiedAt,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
 	}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// DataExportHandler serves "Download Your Information": requesting a copy of
+// your data, following its progress and downloading the finished archive
+type DataExportHandler struct {
+	exportService *service.DataExportService
+	logger        *logger.Logger
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(exportService *service.DataExportService, logger *logger.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		exportService: exportService,
+		logger:        logger,
+	}
+}
+
+// HandleRequestExport queues an export of the current user's data
+func (h *DataExportHandler) HandleRequestExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	export, err := h.exportService.RequestExport(r.Context(), user, getIPAddress(r))
+	if err != nil {
+		h.respondWithExportError(w, err, "Failed to request data export")
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusAccepted, util.APIResponse{
+		Success: true,
+		Message: "We're preparing your information and will email you when it's ready",
+		Data:    export,
+	})
+}
+
+// HandleListExports lists the current user's exports
+func (h *DataExportHandler) HandleListExports(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	exports, err := h.exportService.ListExports(r.Context(), user.ID)
+	if err != nil {
+		h.respondWithExportError(w, err, "Failed to list data exports")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", exports)
+}
+
+// HandleGetExport returns an export's progress, and a download link once it is ready
+func (h *DataExportHandler) HandleGetExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), user.ID, mux.Vars(r)["id"])
+	if err != nil {
+		h.respondWithExportError(w, err, "Failed to get data export")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", export)
+}
+
+// HandleDownloadExport streams an archive to whoever holds a valid signed link.
+// It is public so the link in the email works in a browser without an access token.
+func (h *DataExportHandler) HandleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	download, err := h.exportService.OpenDownload(r.Context(), r.URL.Query().Get("token"), getIPAddress(r))
+	if err != nil {
+		h.respondWithExportError(w, err, "Failed to download data export")
+		return
+	}
+	defer download.Body.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, download.Filename))
+	w.Header().Set("Cache-Control", "no-store")
+	if download.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(download.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// Archives can take longer to send than the server's write timeout allows
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	if _, err := io.Copy(w, download.Body); err != nil {
+		h.logger.Error("Failed to stream data export", err)
+	}
+}
+
+// respondWithExportError maps data export service errors to responses
+func (h *DataExportHandler) respondWithExportError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrDataExportNotFound):
+		util.RespondWithNotFound(w, err.Error())
+	case errors.Is(err, service.ErrDataExportTooSoon):
+		util.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, service.ErrDataExportLinkInvalid):
+		util.RespondWithError(w, http.StatusGone, err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
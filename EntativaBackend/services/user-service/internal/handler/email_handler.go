@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// EmailTokenRequest carries a link token from a verification or email change email
+type EmailTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangeEmailRequest represents a request to move the account to a new address
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+// HandleVerifyEmail confirms the address a verification link was sent to
+func (h *AuthHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req EmailTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.emailVerifier.VerifyEmail(r.Context(), req.Token, getIPAddress(r)); err != nil {
+		h.respondWithEmailError(w, err, "Failed to verify email")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Your email address has been verified", nil)
+}
+
+// HandleResendVerification sends a fresh verification link to the current user's address
+func (h *AuthHandler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	if err := h.emailVerifier.SendVerification(r.Context(), user); err != nil {
+		h.respondWithEmailError(w, err, "Failed to send verification email")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Verification email sent", nil)
+}
+
+// HandleRequestEmailChange sends a confirmation link to the new address
+func (h *AuthHandler) HandleRequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := util.ValidateEmail(req.NewEmail); err != nil {
+		util.RespondWithValidationError(w, "new_email", err.Error())
+		return
+	}
+
+	if err := h.emailVerifier.RequestEmailChange(r.Context(), user, req.NewEmail, req.Password, getIPAddress(r)); err != nil {
+		h.respondWithEmailError(w, err, "Failed to request email change")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Check your new email address for a confirmation link", nil)
+}
+
+// HandleConfirmEmailChange switches the account to the new address from the confirmation link
+func (h *AuthHandler) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req EmailTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.emailVerifier.ConfirmEmailChange(r.Context(), req.Token, getIPAddress(r)); err != nil {
+		h.respondWithEmailError(w, err, "Failed to change email")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Your email address has been changed", nil)
+}
+
+// HandleUndoEmailChange restores the previous address from the link sent to it
+func (h *AuthHandler) HandleUndoEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req EmailTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.emailVerifier.UndoEmailChange(r.Context(), req.Token, getIPAddress(r)); err != nil {
+		h.respondWithEmailError(w, err, "Failed to undo email change")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Your previous email address has been restored and all sessions were signed out. Please reset your password.", nil)
+}
+
+func (h *AuthHandler) respondWithEmailError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrEmailTokenInvalid),
+		errors.Is(err, service.ErrEmailAlreadyVerified),
+		errors.Is(err, service.ErrEmailUnchanged):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrIncorrectPassword):
+		util.RespondWithUnauthorized(w, err.Error())
+	case errors.Is(err, service.ErrEmailTaken):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrTooManyEmailRequests):
+		util.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
@@ -1,15 +1,13 @@
 package handler
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"user-service/internal/repository"
 	"user-service/internal/util"
 )
 
@@ -30,73 +28,91 @@ type ForgotPasswordResponse struct {
 	Message string `json:"message"`
 }
 
-// PasswordResetToken represents a password reset token in the database
-type PasswordResetToken struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	Used      bool      `json:"used"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
 // HandleForgotPassword handles the forgot password request
 func (h *AuthHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
 	var req ForgotPasswordRequest
 	
 	// Decode request body
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	
 	// Validate email format
-	if !isValidEmail(req.Email) {
-		respondWithError(w, http.StatusBadRequest, "Invalid email format")
+	if !util.IsValidEmail(req.Email) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid email format")
 		return
 	}
 	
+	// Throttle per email and per IP before looking the account up, so the limit
+	// applies the same way to addresses that have no account
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	ipAddress := getIPAddress(r)
+	
+	since := time.Now().Add(-h.config.Security.PasswordResetWindow)
+	byEmail, byIP, err := h.tokenRepo.CountResetAttempts(r.Context(), email, ipAddress, since)
+	if err != nil {
+		h.logger.Error("Failed to count reset attempts", err)
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
+		return
+	}
+	if byEmail >= h.config.Security.PasswordResetMaxPerEmail || byIP >= h.config.Security.PasswordResetMaxPerIP {
+		util.RespondWithError(w, http.StatusTooManyRequests, "Too many password reset requests. Please try again later.")
+		return
+	}
+	
+	if err := h.tokenRepo.RecordResetAttempt(r.Context(), email, ipAddress); err != nil {
+		h.logger.Error("Failed to record reset attempt", err)
+	}
+	
 	// Find user by email
-	user, err := h.userRepo.FindByEmail(r.Context(), req.Email)
+	user, err := h.userRepo.FindByEmail(r.Context(), email)
+
+	// Optionally only send reset links to addresses the owner has confirmed
+	if err == nil && h.config.Security.RequireVerifiedEmailForPasswordReset && !user.HasVerifiedEmail() {
+		err = &repository.NotFoundError{Message: "User not found"}
+	}
+
 	if err != nil {
 		// Don't reveal if user exists or not for security
-		respondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
+		util.RespondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
 			Success: true,
 			Message: "If an account exists with this email, you will receive a password reset link shortly.",
 		})
 		return
 	}
 	
-	// Generate secure reset token
-	token, err := generateSecureToken(32)
+	// Generate selector/verifier reset token; only the verifier hash is stored
+	token, err := util.GenerateSplitToken()
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate reset token")
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to generate reset token")
 		return
 	}
 	
-	// Store reset token in database (expires in 1 hour)
-	resetToken := PasswordResetToken{
-		ID:        generateUUID(),
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(1 * time.Hour),
-		Used:      false,
-		CreatedAt: time.Now(),
+	// Store reset token in database
+	resetToken := repository.PasswordResetToken{
+		ID:           util.GenerateUUID(),
+		UserID:       user.ID,
+		Selector:     token.Selector,
+		VerifierHash: token.VerifierHash,
+		IPAddress:    ipAddress,
+		ExpiresAt:    time.Now().Add(h.config.Security.PasswordResetTokenExpiry),
+		CreatedAt:    time.Now(),
 	}
 	
 	if err := h.tokenRepo.CreateResetToken(r.Context(), &resetToken); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
 		return
 	}
 	
 	// Send password reset email
-	resetLink := buildResetLink(token)
+	resetLink := buildResetLink(token.Token)
 	go h.emailService.SendPasswordResetEmail(user.Email, user.FirstName, resetLink)
 	
 	// Log the reset attempt
-	h.auditLog.LogPasswordResetRequest(user.ID, r.RemoteAddr)
+	h.auditLog.LogPasswordResetRequest(user.ID, ipAddress)
 	
-	respondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
+	util.RespondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
 		Success: true,
 		Message: "If an account exists with this email, you will receive a password reset link shortly.",
 	})
@@ -108,52 +124,58 @@ func (h *AuthHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request
 	
 	// Decode request body
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	
 	// Validate password strength
 	if err := validatePasswordStrength(req.NewPassword); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	
 	// Find and validate reset token
 	resetToken, err := h.tokenRepo.FindResetToken(r.Context(), req.Token)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
 		return
 	}
 	
 	// Check if token is expired
 	if time.Now().After(resetToken.ExpiresAt) {
-		respondWithError(w, http.StatusBadRequest, "Reset token has expired")
+		util.RespondWithError(w, http.StatusBadRequest, "Reset token has expired")
 		return
 	}
 	
 	// Check if token was already used
 	if resetToken.Used {
-		respondWithError(w, http.StatusBadRequest, "Reset token has already been used")
+		util.RespondWithError(w, http.StatusBadRequest, "Reset token has already been used")
+		return
+	}
+	
+	// Claim the token before changing anything, so concurrent requests cannot both use it
+	if err := h.tokenRepo.MarkTokenAsUsed(r.Context(), resetToken.ID); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Reset token has already been used")
 		return
 	}
 	
 	// Hash the new password
-	hashedPassword, err := hashPassword(req.NewPassword)
+	hashedPassword, err := util.HashPassword(req.NewPassword)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
 		return
 	}
 	
 	// Update user password
 	if err := h.userRepo.UpdatePassword(r.Context(), resetToken.UserID, hashedPassword); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to update password")
 		return
 	}
 	
-	// Mark token as used
-	if err := h.tokenRepo.MarkTokenAsUsed(r.Context(), resetToken.ID); err != nil {
+	// Links from earlier requests must not work after the password has changed
+	if err := h.tokenRepo.InvalidateUserResetTokens(r.Context(), resetToken.UserID); err != nil {
 		// Log error but don't fail the request
-		h.logger.Error("Failed to mark token as used", err)
+		h.logger.Error("Failed to invalidate reset tokens", err)
 	}
 	
 	// Invalidate all existing sessions for this user (force re-login)
@@ -163,9 +185,9 @@ func (h *AuthHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request
 	}
 	
 	// Log the password reset
-	h.auditLog.LogPasswordReset(resetToken.UserID, r.RemoteAddr)
+	h.auditLog.LogPasswordReset(resetToken.UserID, getIPAddress(r))
 	
-	respondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
+	util.RespondWithJSON(w, http.StatusOK, ForgotPasswordResponse{
 		Success: true,
 		Message: "Your password has been successfully reset. Please log in with your new password.",
 	})
@@ -177,14 +199,14 @@ func (h *AuthHandler) HandleVerifyResetToken(w http.ResponseWriter, r *http.Requ
 	token := vars["token"]
 	
 	if token == "" {
-		respondWithError(w, http.StatusBadRequest, "Token is required")
+		util.RespondWithError(w, http.StatusBadRequest, "Token is required")
 		return
 	}
 	
 	// Find reset token
 	resetToken, err := h.tokenRepo.FindResetToken(r.Context(), token)
 	if err != nil {
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"valid": false,
 			"message": "Invalid reset token",
 		})
@@ -193,7 +215,7 @@ func (h *AuthHandler) HandleVerifyResetToken(w http.ResponseWriter, r *http.Requ
 	
 	// Check if expired
 	if time.Now().After(resetToken.ExpiresAt) {
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"valid": false,
 			"message": "Reset token has expired",
 		})
@@ -202,14 +224,14 @@ func (h *AuthHandler) HandleVerifyResetToken(w http.ResponseWriter, r *http.Requ
 	
 	// Check if already used
 	if resetToken.Used {
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"valid": false,
 			"message": "Reset token has already been used",
 		})
 		return
 	}
 	
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+	util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"valid": true,
 		"message": "Token is valid",
 		"expires_at": resetToken.ExpiresAt,
@@ -218,14 +240,6 @@ func (h *AuthHandler) HandleVerifyResetToken(w http.ResponseWriter, r *http.Requ
 
 // Helper functions
 
-func generateSecureToken(length int) (string, error) {
-	bytes := make([]byte, length)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(bytes), nil
-}
-
 func buildResetLink(token string) string {
 	// In production, use your actual domain
 	baseURL := "https://app.entativa.com"
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// maxRelationshipCheckBatch caps how many users one relationship check may ask about
+const maxRelationshipCheckBatch = 1000
+
+// FriendGraphHandler serves the friend graph to other services. The post service
+// uses it to build home timelines from friends' posts, and to decide who may see
+// a post.
+type FriendGraphHandler struct {
+	graphRepo    *repository.FriendGraphRepository
+	blockService *service.BlockService
+	logger       *logger.Logger
+}
+
+// NewFriendGraphHandler creates a new friend graph handler
+func NewFriendGraphHandler(graphRepo *repository.FriendGraphRepository, blockService *service.BlockService, logger *logger.Logger) *FriendGraphHandler {
+	return &FriendGraphHandler{
+		graphRepo:    graphRepo,
+		blockService: blockService,
+		logger:       logger,
+	}
+}
+
+// RelationshipCheckRequest asks how ViewerID relates to each of UserIDs
+type RelationshipCheckRequest struct {
+	ViewerID string   `json:"viewer_id"`
+	UserIDs  []string `json:"user_ids"`
+}
+
+// HandleGetFriendIDs lists the user's friends. With ?max=N, a user with more than N
+// friends gets only the count back, so callers that treat big accounts differently
+// don't have to page through all of them.
+func (h *FriendGraphHandler) HandleGetFriendIDs(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	if !util.IsValidUUID(userID) {
+		util.RespondWithValidationError(w, "user_id", "Invalid user ID")
+		return
+	}
+
+	max := 0
+	if raw := r.URL.Query().Get("max"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			util.RespondWithValidationError(w, "max", "max must be a positive number")
+			return
+		}
+		max = n
+	}
+
+	count, err := h.graphRepo.CountFriends(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to count friends", err)
+		util.RespondWithInternalError(w, "Failed to get friends")
+		return
+	}
+
+	friendIDs := []string{}
+	truncated := max > 0 && count > max
+	if !truncated {
+		friendIDs, err = h.graphRepo.ListFriendIDs(r.Context(), userID)
+		if err != nil {
+			h.logger.Error("Failed to list friends", err)
+			util.RespondWithInternalError(w, "Failed to get friends")
+			return
+		}
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"user_id":      userID,
+		"friend_count": count,
+		"friend_ids":   friendIDs,
+		"truncated":    truncated,
+	})
+}
+
+// HandleCheckRelationships returns which of a batch of users are friends with the
+// viewer, and which are blocked from the viewer either way. The post service uses
+// it to decide which posts the viewer may see.
+func (h *FriendGraphHandler) HandleCheckRelationships(w http.ResponseWriter, r *http.Request) {
+	var req RelationshipCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !util.IsValidUUID(req.ViewerID) {
+		util.RespondWithValidationError(w, "viewer_id", "Invalid viewer ID")
+		return
+	}
+	if len(req.UserIDs) > maxRelationshipCheckBatch {
+		util.RespondWithValidationError(w, "user_ids", "at most 1000 user_ids can be checked at once")
+		return
+	}
+	for _, userID := range req.UserIDs {
+		if !util.IsValidUUID(userID) {
+			util.RespondWithValidationError(w, "user_ids", "user_ids must be valid IDs")
+			return
+		}
+	}
+
+	friendIDs, err := h.graphRepo.FilterFriends(r.Context(), req.ViewerID, req.UserIDs)
+	if err != nil {
+		h.logger.Error("Failed to check friendships", err)
+		util.RespondWithInternalError(w, "Failed to check relationships")
+		return
+	}
+
+	blocked, err := h.blockService.FilterBlocked(r.Context(), req.ViewerID, req.UserIDs)
+	if err != nil {
+		h.logger.Error("Failed to check blocks", err)
+		util.RespondWithInternalError(w, "Failed to check relationships")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"viewer_id":        req.ViewerID,
+		"friend_ids":       friendIDs,
+		"blocked_user_ids": blocked,
+	})
+}
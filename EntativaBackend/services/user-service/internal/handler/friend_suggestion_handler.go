@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// maxSuggestionPageSize caps how many suggestions one page may hold
+const maxSuggestionPageSize = 50
+
+// FriendSuggestionHandler serves "People You May Know", and takes community and
+// event memberships from other services to inform it
+type FriendSuggestionHandler struct {
+	suggestionService *service.FriendSuggestionService
+	logger            *logger.Logger
+}
+
+// NewFriendSuggestionHandler creates a new friend suggestion handler
+func NewFriendSuggestionHandler(suggestionService *service.FriendSuggestionService, logger *logger.Logger) *FriendSuggestionHandler {
+	return &FriendSuggestionHandler{
+		suggestionService: suggestionService,
+		logger:            logger,
+	}
+}
+
+// HandleListSuggestions returns a page of people the current user may know
+func (h *FriendSuggestionHandler) HandleListSuggestions(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	page := 1
+	limit := 20
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= maxSuggestionPageSize {
+			limit = l
+		}
+	}
+
+	suggestions, err := h.suggestionService.ListSuggestions(r.Context(), user.ID, limit, (page-1)*limit)
+	if err != nil {
+		h.respondWithSuggestionError(w, err, "Failed to get friend suggestions")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"suggestions": suggestions,
+		"page":        page,
+		"limit":       limit,
+		"has_more":    len(suggestions) == limit,
+	})
+}
+
+// HandleDismissSuggestion stops a person being suggested to the current user
+func (h *FriendSuggestionHandler) HandleDismissSuggestion(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	dismissedUserID := mux.Vars(r)["userID"]
+	if !util.IsValidUUID(dismissedUserID) {
+		util.RespondWithValidationError(w, "userID", "Invalid user ID")
+		return
+	}
+
+	if err := h.suggestionService.DismissSuggestion(r.Context(), user.ID, dismissedUserID); err != nil {
+		h.respondWithSuggestionError(w, err, "Failed to dismiss suggestion")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Suggestion dismissed", nil)
+}
+
+// HandleAddAffiliation records that a user joined a community or is going to an event
+func (h *FriendSuggestionHandler) HandleAddAffiliation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.validAffiliationVars(w, vars) {
+		return
+	}
+
+	if err := h.suggestionService.AddAffiliation(r.Context(), vars["userID"], vars["kind"], vars["affiliationID"]); err != nil {
+		h.respondWithSuggestionError(w, err, "Failed to add affiliation")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", nil)
+}
+
+// HandleRemoveAffiliation records that a user left a community or is no longer going to an event
+func (h *FriendSuggestionHandler) HandleRemoveAffiliation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.validAffiliationVars(w, vars) {
+		return
+	}
+
+	if err := h.suggestionService.RemoveAffiliation(r.Context(), vars["userID"], vars["kind"], vars["affiliationID"]); err != nil {
+		h.respondWithSuggestionError(w, err, "Failed to remove affiliation")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", nil)
+}
+
+func (h *FriendSuggestionHandler) validAffiliationVars(w http.ResponseWriter, vars map[string]string) bool {
+	if !util.IsValidUUID(vars["userID"]) {
+		util.RespondWithValidationError(w, "userID", "Invalid user ID")
+		return false
+	}
+	if !util.IsValidUUID(vars["affiliationID"]) {
+		util.RespondWithValidationError(w, "affiliationID", "Invalid affiliation ID")
+		return false
+	}
+	return true
+}
+
+// respondWithSuggestionError maps friend suggestion service errors to responses
+func (h *FriendSuggestionHandler) respondWithSuggestionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrSuggestionUserNotFound):
+		util.RespondWithNotFound(w, err.Error())
+	case errors.Is(err, service.ErrCannotDismissSelf):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInvalidAffiliationKind):
+		util.RespondWithValidationError(w, "kind", err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// ReauthenticationRequest confirms a sensitive change with the account password,
+// or for accounts without one, a fresh access token from an already linked platform
+type ReauthenticationRequest struct {
+	Password          string `json:"password"`
+	ReauthPlatform    string `json:"reauth_platform"`
+	ReauthAccessToken string `json:"reauth_access_token"`
+}
+
+// LinkIdentityRequest links the account an access token from the other platform belongs to
+type LinkIdentityRequest struct {
+	Platform    string `json:"platform"`
+	AccessToken string `json:"access_token"`
+	ReauthenticationRequest
+}
+
+// MergeAccountsRequest identifies the account to merge into the current one,
+// by its credentials or by an access token from a platform identity linked to it
+type MergeAccountsRequest struct {
+	Source struct {
+		EmailOrUsername string `json:"email_or_username"`
+		Password        string `json:"password"`
+		Platform        string `json:"platform"`
+		AccessToken     string `json:"access_token"`
+	} `json:"source"`
+	ReauthenticationRequest
+}
+
+// HandleListIdentities lists the current user's linked accounts
+func (h *AuthHandler) HandleListIdentities(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	identities, err := h.identities.ListIdentities(r.Context(), user)
+	if err != nil {
+		h.logger.Error("Failed to list linked identities", err)
+		util.RespondWithInternalError(w, "Failed to list linked accounts")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", identities)
+}
+
+// HandleLinkIdentity links an account on the other platform to the current user
+func (h *AuthHandler) HandleLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req LinkIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccessToken == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	identity, err := h.verifyPlatformToken(r.Context(), req.Platform, req.AccessToken)
+	if err != nil {
+		h.respondWithIdentityError(w, err, "")
+		return
+	}
+
+	reauth, err := h.reauthentication(r.Context(), &req.ReauthenticationRequest)
+	if err != nil {
+		h.respondWithIdentityError(w, err, "")
+		return
+	}
+
+	if err := h.identities.LinkIdentity(r.Context(), user, identity, reauth, getIPAddress(r)); err != nil {
+		h.respondWithIdentityError(w, err, "Failed to link account")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Account linked", nil)
+}
+
+// HandleUnlinkIdentity removes the current user's link to a platform
+func (h *AuthHandler) HandleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req ReauthenticationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	reauth, err := h.reauthentication(r.Context(), &req)
+	if err != nil {
+		h.respondWithIdentityError(w, err, "")
+		return
+	}
+
+	err = h.identities.UnlinkIdentity(r.Context(), user, mux.Vars(r)["platform"], reauth, getIPAddress(r))
+	if err != nil {
+		h.respondWithIdentityError(w, err, "Failed to unlink account")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Account unlinked", nil)
+}
+
+// HandleMergeAccounts merges another account the user owns into the current one
+func (h *AuthHandler) HandleMergeAccounts(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req MergeAccountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	source := &service.MergeSource{
+		EmailOrUsername: req.Source.EmailOrUsername,
+		Password:        req.Source.Password,
+	}
+	if req.Source.AccessToken != "" {
+		identity, err := h.verifyPlatformToken(r.Context(), req.Source.Platform, req.Source.AccessToken)
+		if err != nil {
+			h.respondWithIdentityError(w, err, "")
+			return
+		}
+		source.Identity = identity
+	}
+
+	reauth, err := h.reauthentication(r.Context(), &req.ReauthenticationRequest)
+	if err != nil {
+		h.respondWithIdentityError(w, err, "")
+		return
+	}
+
+	result, err := h.identities.MergeAccounts(r.Context(), user, source, reauth, getIPAddress(r))
+	if err != nil {
+		h.respondWithIdentityError(w, err, "Failed to merge accounts")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Accounts merged", result)
+}
+
+// HandleListMerges lists the accounts merged into the current user
+func (h *AuthHandler) HandleListMerges(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	merges, err := h.identities.ListMerges(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to list account merges", err)
+		util.RespondWithInternalError(w, "Failed to list merged accounts")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", merges)
+}
+
+// errPlatformToken is returned when the other platform does not accept an access token
+var errPlatformToken = errors.New("invalid or expired token from the other platform")
+
+// verifyPlatformToken asks the other platform who an access token belongs to
+func (h *AuthHandler) verifyPlatformToken(ctx context.Context, platform, token string) (*service.PlatformIdentity, error) {
+	if !service.ValidPlatform(platform) {
+		return nil, service.ErrUnsupportedPlatform
+	}
+
+	var userInfo *VignetteUserInfo
+	var err error
+	if platform == "vignette" {
+		userInfo, err = h.verifyVignetteToken(ctx, token)
+	} else {
+		userInfo, err = h.verifyEntativaToken(ctx, token)
+	}
+	if err != nil {
+		return nil, errPlatformToken
+	}
+
+	return &service.PlatformIdentity{
+		Platform:      platform,
+		UserID:        userInfo.ID,
+		Username:      userInfo.Username,
+		Email:         userInfo.Email,
+		EmailVerified: userInfo.EmailVerified,
+	}, nil
+}
+
+// reauthentication builds the re-authentication proof from a request
+func (h *AuthHandler) reauthentication(ctx context.Context, req *ReauthenticationRequest) (*service.Reauthentication, error) {
+	reauth := &service.Reauthentication{Password: req.Password}
+	if req.Password == "" && req.ReauthAccessToken != "" {
+		identity, err := h.verifyPlatformToken(ctx, req.ReauthPlatform, req.ReauthAccessToken)
+		if err != nil {
+			return nil, err
+		}
+		reauth.Identity = identity
+	}
+	return reauth, nil
+}
+
+// respondWithIdentityError maps identity service errors to responses
+func (h *AuthHandler) respondWithIdentityError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, errPlatformToken), errors.Is(err, service.ErrIncorrectPassword),
+		errors.Is(err, service.ErrReauthenticationRequired), errors.Is(err, service.ErrMergeSourceCredentials):
+		util.RespondWithUnauthorized(w, err.Error())
+	case errors.Is(err, service.ErrUnsupportedPlatform), errors.Is(err, service.ErrMergeSameAccount):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrIdentityNotLinked), errors.Is(err, service.ErrMergeSourceNotFound):
+		util.RespondWithNotFound(w, err.Error())
+	case errors.Is(err, service.ErrIdentityAlreadyLinked), errors.Is(err, service.ErrIdentityLinkedElsewhere),
+		errors.Is(err, service.ErrPlatformAlreadyLinked), errors.Is(err, service.ErrLastSignInMethod):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
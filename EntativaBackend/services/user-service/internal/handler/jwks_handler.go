@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-service/internal/util"
+)
+
+// HandleJWKS publishes the public keys that verify access tokens.
+// Other services (api-gateway, messaging, admin) fetch this set and select a key by the token's kid.
+func (h *AuthHandler) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	keyring := util.CurrentKeyring()
+	if keyring == nil {
+		util.RespondWithInternalError(w, "Signing keys not configured")
+		return
+	}
+
+	// Verifiers re-fetch on an unknown kid, so a short cache is enough to pick up rotations
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	util.RespondWithJSON(w, http.StatusOK, keyring.JWKS())
+}
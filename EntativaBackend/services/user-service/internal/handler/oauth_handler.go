@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/config"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// OAuthHandler serves the OpenID Connect provider: the protocol endpoints used
+// by client apps, and the API behind the consent screen and developer settings
+type OAuthHandler struct {
+	oidcService *service.OIDCService
+	logger      *logger.Logger
+	config      *config.Config
+}
+
+// NewOAuthHandler creates a new OAuth handler
+func NewOAuthHandler(oidcService *service.OIDCService, logger *logger.Logger, cfg *config.Config) *OAuthHandler {
+	return &OAuthHandler{
+		oidcService: oidcService,
+		logger:      logger,
+		config:      cfg,
+	}
+}
+
+// HandleDiscovery serves the OpenID Provider metadata
+func (h *OAuthHandler) HandleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	util.RespondWithJSON(w, http.StatusOK, h.oidcService.Discovery())
+}
+
+// HandleAuthorize is the authorization endpoint. Valid requests are passed on to
+// the web app's consent page, which signs the user in if needed and calls
+// HandleApproveAuthorization.
+func (h *OAuthHandler) HandleAuthorize(w http.ResponseWriter, r *http.Request) {
+	req := service.AuthorizationRequestFromQuery(r.URL.Query())
+
+	_, _, err := h.oidcService.ValidateAuthorizationRequest(r.Context(), req)
+	if err != nil {
+		h.redirectWithError(w, r, req, err)
+		return
+	}
+
+	http.Redirect(w, r, h.config.OIDC.ConsentURL+"?"+r.URL.RawQuery, http.StatusFound)
+}
+
+// HandleGetAuthorization returns what the consent page should show for an authorization request
+func (h *OAuthHandler) HandleGetAuthorization(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	req := service.AuthorizationRequestFromQuery(r.URL.Query())
+
+	prompt, err := h.oidcService.DescribeAuthorizationRequest(r.Context(), user.ID, req)
+	if err != nil {
+		h.respondWithOAuthError(w, err)
+		return
+	}
+
+	util.RespondWithSuccess(w, "", prompt)
+}
+
+// ApproveAuthorizationRequest is the user's answer on the consent page
+type ApproveAuthorizationRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// HandleApproveAuthorization records the user's decision on an authorization
+// request (passed in the query string) and returns where to send the browser
+func (h *OAuthHandler) HandleApproveAuthorization(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var body ApproveAuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req := service.AuthorizationRequestFromQuery(r.URL.Query())
+
+	redirectTo, err := h.oidcService.Authorize(r.Context(), user, req, body.Approve, getIPAddress(r))
+	if err != nil {
+		h.respondWithOAuthError(w, err)
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"redirect_to": redirectTo,
+	})
+}
+
+// HandleToken is the token endpoint. Clients authenticate with HTTP Basic or
+// client_id/client_secret in the form; public clients send only client_id.
+func (h *OAuthHandler) HandleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed form body"})
+		return
+	}
+
+	req := &service.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	tokens, err := h.oidcService.ExchangeCode(r.Context(), req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if !errors.As(err, &oauthErr) {
+			h.logger.Error("Failed to exchange authorization code", err)
+			writeOAuthError(w, http.StatusInternalServerError, &service.OAuthError{Code: "server_error"})
+			return
+		}
+
+		status := http.StatusBadRequest
+		if oauthErr.Code == "invalid_client" {
+			status = http.StatusUnauthorized
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, status, oauthErr)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// HandleUserInfo returns claims about the user an access token was issued for
+func (h *OAuthHandler) HandleUserInfo(w http.ResponseWriter, r *http.Request) {
+	token, err := util.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeOAuthError(w, http.StatusUnauthorized, &service.OAuthError{Code: "invalid_token", Description: err.Error()})
+		return
+	}
+
+	claims, err := h.oidcService.UserInfo(r.Context(), token)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if !errors.As(err, &oauthErr) {
+			h.logger.Error("Failed to load userinfo", err)
+			writeOAuthError(w, http.StatusInternalServerError, &service.OAuthError{Code: "server_error"})
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+oauthErr.Code+`"`)
+		writeOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	util.RespondWithJSON(w, http.StatusOK, claims)
+}
+
+// HandleRegisterClient registers an application owned by the current user
+func (h *OAuthHandler) HandleRegisterClient(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req service.RegisterClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	client, err := h.oidcService.RegisterClient(r.Context(), user.ID, &req, getIPAddress(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidClientName):
+			util.RespondWithValidationError(w, "name", err.Error())
+		case errors.Is(err, service.ErrInvalidRedirectURI):
+			util.RespondWithValidationError(w, "redirect_uris", err.Error())
+		default:
+			h.logger.Error("Failed to register OAuth client", err)
+			util.RespondWithInternalError(w, "Failed to register client")
+		}
+		return
+	}
+
+	util.RespondWithCreated(w, "Client registered. Store the client secret now; it will not be shown again.", client)
+}
+
+// HandleListClients lists the applications the current user has registered
+func (h *OAuthHandler) HandleListClients(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	clients, err := h.oidcService.ListClients(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to list OAuth clients", err)
+		util.RespondWithInternalError(w, "Failed to list clients")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", clients)
+}
+
+// HandleDeleteClient deletes an application the current user registered
+func (h *OAuthHandler) HandleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	err := h.oidcService.DeleteClient(r.Context(), user.ID, mux.Vars(r)["id"], getIPAddress(r))
+	if err != nil {
+		if errors.Is(err, service.ErrOAuthClientNotFound) {
+			util.RespondWithNotFound(w, "Client not found")
+			return
+		}
+		h.logger.Error("Failed to delete OAuth client", err)
+		util.RespondWithInternalError(w, "Failed to delete client")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Client deleted", nil)
+}
+
+// HandleListConsents lists the applications the current user has signed in to
+func (h *OAuthHandler) HandleListConsents(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	apps, err := h.oidcService.ListAuthorizedApps(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to list OAuth consents", err)
+		util.RespondWithInternalError(w, "Failed to list connected apps")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", apps)
+}
+
+// HandleRevokeConsent removes an application's access to the current user's account
+func (h *OAuthHandler) HandleRevokeConsent(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	err := h.oidcService.RevokeConsent(r.Context(), user.ID, mux.Vars(r)["client_id"], getIPAddress(r))
+	if err != nil {
+		if errors.Is(err, service.ErrOAuthConsentNotFound) {
+			util.RespondWithNotFound(w, "App not connected")
+			return
+		}
+		h.logger.Error("Failed to revoke OAuth consent", err)
+		util.RespondWithInternalError(w, "Failed to disconnect app")
+		return
+	}
+
+	util.RespondWithSuccess(w, "App disconnected", nil)
+}
+
+// redirectWithError reports an invalid authorization request. Problems with the
+// client or redirect_uri are shown here rather than sent to an unverified URL.
+func (h *OAuthHandler) redirectWithError(w http.ResponseWriter, r *http.Request, req *service.AuthorizationRequest, err error) {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		redirectTo, redirectErr := h.oidcService.ErrorRedirect(req, oauthErr)
+		if redirectErr == nil {
+			http.Redirect(w, r, redirectTo, http.StatusFound)
+			return
+		}
+		err = redirectErr
+	}
+
+	switch {
+	case errors.Is(err, service.ErrUnknownClient), errors.Is(err, service.ErrRedirectURIMismatch):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error("Failed to validate authorization request", err)
+		util.RespondWithInternalError(w, "Failed to process authorization request")
+	}
+}
+
+// respondWithOAuthError maps authorization request errors for the consent page API
+func (h *OAuthHandler) respondWithOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *service.OAuthError
+	switch {
+	case errors.As(err, &oauthErr):
+		util.RespondWithJSON(w, http.StatusBadRequest, oauthErr)
+	case errors.Is(err, service.ErrUnknownClient), errors.Is(err, service.ErrRedirectURIMismatch):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error("Failed to process authorization request", err)
+		util.RespondWithInternalError(w, "Failed to process authorization request")
+	}
+}
+
+// writeOAuthError writes an RFC 6749 error body, which clients expect instead of the API envelope
+func writeOAuthError(w http.ResponseWriter, statusCode int, oauthErr *service.OAuthError) {
+	util.RespondWithJSON(w, statusCode, oauthErr)
+}
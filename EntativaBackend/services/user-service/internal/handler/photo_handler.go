@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// multipartOverhead allows for the form around an upload when capping request size
+const multipartOverhead = 1024 * 1024
+
+// PhotoHandler takes profile picture and cover photo uploads
+type PhotoHandler struct {
+	mediaService *service.MediaService
+	logger       *logger.Logger
+}
+
+// NewPhotoHandler creates a new photo handler
+func NewPhotoHandler(mediaService *service.MediaService, logger *logger.Logger) *PhotoHandler {
+	return &PhotoHandler{
+		mediaService: mediaService,
+		logger:       logger,
+	}
+}
+
+// HandleUploadProfilePicture replaces the current user's profile picture with the
+// image in the "file" form field
+func (h *PhotoHandler) HandleUploadProfilePicture(w http.ResponseWriter, r *http.Request) {
+	h.handleUpload(w, r, service.MaxProfilePictureBytes, h.mediaService.UploadProfilePicture, "Failed to upload profile picture")
+}
+
+// HandleUploadCoverPhoto replaces the current user's cover photo with the image in
+// the "file" form field
+func (h *PhotoHandler) HandleUploadCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	h.handleUpload(w, r, service.MaxCoverPhotoBytes, h.mediaService.UploadCoverPhoto, "Failed to upload cover photo")
+}
+
+func (h *PhotoHandler) handleUpload(
+	w http.ResponseWriter,
+	r *http.Request,
+	maxBytes int64,
+	upload func(ctx context.Context, userID string, file io.Reader) (*service.PhotoView, error),
+	fallback string,
+) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			util.RespondWithError(w, http.StatusRequestEntityTooLarge, service.ErrPhotoTooLarge.Error())
+			return
+		}
+		util.RespondWithValidationError(w, "file", "Please provide a photo to upload")
+		return
+	}
+	defer file.Close()
+
+	photo, err := upload(r.Context(), user.ID, file)
+	if err != nil {
+		h.respondWithPhotoError(w, err, fallback)
+		return
+	}
+
+	util.RespondWithSuccess(w, "Photo updated", photo)
+}
+
+// respondWithPhotoError maps media service errors to responses
+func (h *PhotoHandler) respondWithPhotoError(w http.ResponseWriter, err error, fallback string) {
+	var notFound *repository.NotFoundError
+	switch {
+	case errors.Is(err, service.ErrMediaNotConfigured):
+		util.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		util.RespondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrPhotoUnsupported):
+		util.RespondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, service.ErrPhotoUnreadable), errors.Is(err, service.ErrPhotoTooSmall):
+		util.RespondWithValidationError(w, "file", err.Error())
+	case errors.As(err, &notFound):
+		util.RespondWithNotFound(w, "User not found")
+	default:
+		h.logger.Error(fallback, err)
+		util.RespondWithInternalError(w, fallback)
+	}
+}
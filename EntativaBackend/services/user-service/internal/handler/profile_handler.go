@@ -1,10 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
-	"socialink/user-service/internal/model"
-	"socialink/user-service/internal/service"
+	"user-service/internal/model"
+	"user-service/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -42,8 +43,12 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userID)
+	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userID, viewerID(c))
 	if err != nil {
+		if errors.Is(err, service.ErrProfileUnavailable) {
+			respondProfileUnavailable(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get profile",
 			"message": err.Error(),
@@ -85,7 +90,7 @@ func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userUUID)
+	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userUUID, userUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get profile",
@@ -100,6 +105,64 @@ func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
 	})
 }
 
+// ViewProfileAs previews the authenticated user's profile as another person sees it
+// @Summary View my profile as someone else
+// @Description Get the authenticated user's profile with only the fields the given viewer may see
+// @Tags profile
+// @Security BearerAuth
+// @Produce json
+// @Param viewer_id path string true "Viewer user ID"
+// @Success 200 {object} model.ProfileResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /profile/me/view-as/{viewer_id} [get]
+func (h *ProfileHandler) ViewProfileAs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	viewerUUID, err := uuid.Parse(c.Param("viewer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid viewer ID",
+			"message": "The provided viewer ID is not valid",
+		})
+		return
+	}
+
+	userUUID, _ := uuid.Parse(userID.(string))
+	profile, err := h.profileService.ViewProfileAs(c.Request.Context(), userUUID, viewerUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrViewerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Viewer not found",
+				"message": "The person you want to view your profile as does not exist",
+			})
+		case errors.Is(err, service.ErrProfileUnavailable):
+			// The preview is exactly what the viewer gets, so it can't reveal that they blocked the owner
+			respondProfileUnavailable(c)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to get profile",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profile,
+	})
+}
+
 // UpdateProfileInfo updates basic profile information
 // @Summary Update profile information
 // @Description Update basic profile details
@@ -449,6 +512,13 @@ func (h *ProfileHandler) UpdateVisibility(c *gin.Context) {
 
 	userUUID, _ := uuid.Parse(userID.(string))
 	profile, err := h.profileService.UpdateVisibility(c.Request.Context(), userUUID, &req)
+	if errors.Is(err, service.ErrInvalidAudience) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update visibility",
@@ -463,3 +533,22 @@ func (h *ProfileHandler) UpdateVisibility(c *gin.Context) {
 		"data":    profile.ToProfileResponse(),
 	})
 }
+
+// respondProfileUnavailable answers for a profile hidden by a block, exactly as for one that doesn't exist
+func respondProfileUnavailable(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "Profile not found",
+		"message": "This profile isn't available",
+	})
+}
+
+// viewerID returns the signed-in user looking at a profile, or uuid.Nil when signed out
+func viewerID(c *gin.Context) uuid.UUID {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil
+	}
+
+	id, _ := uuid.Parse(userID.(string))
+	return id
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// HandleListSessions lists the devices the user is signed in on
+func (h *AuthHandler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+	currentSessionID, _ := r.Context().Value("session_id").(string)
+
+	sessions, err := h.sessionService.ListSessions(r.Context(), user.ID, currentSessionID)
+	if err != nil {
+		h.logger.Error("Failed to list sessions", err)
+		util.RespondWithInternalError(w, "Failed to list sessions")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Sessions retrieved successfully", sessions)
+}
+
+// HandleRevokeSession signs out one of the user's other devices
+func (h *AuthHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+	currentSessionID, _ := r.Context().Value("session_id").(string)
+
+	sessionID := mux.Vars(r)["id"]
+	if !util.IsValidUUID(sessionID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	err := h.sessionService.RevokeSession(r.Context(), user.ID, sessionID, currentSessionID, getIPAddress(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionNotFound):
+			util.RespondWithNotFound(w, "Session not found")
+		case errors.Is(err, service.ErrRevokeCurrentSession):
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.logger.Error("Failed to revoke session", err)
+			util.RespondWithInternalError(w, "Failed to revoke session")
+		}
+		return
+	}
+
+	util.RespondWithSuccess(w, "Session revoked", nil)
+}
+
+// HandleRevokeOtherSessions signs out every device except the one making the request
+func (h *AuthHandler) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+	currentSessionID, _ := r.Context().Value("session_id").(string)
+
+	revoked, err := h.sessionService.RevokeOtherSessions(r.Context(), user.ID, currentSessionID, getIPAddress(r))
+	if err != nil {
+		h.logger.Error("Failed to revoke sessions", err)
+		util.RespondWithInternalError(w, "Failed to revoke sessions")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Signed out of all other sessions", map[string]interface{}{
+		"revoked": revoked,
+	})
+}
@@ -5,22 +5,30 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"user-service/internal/logger"
 	"user-service/internal/repository"
+	"user-service/internal/service"
 	"user-service/internal/util"
 )
 
 type SettingsHandler struct {
-	settingsRepo *repository.SettingsRepository
-	logger       *logger.Logger
+	settingsRepo    *repository.SettingsRepository
+	blockService    *service.BlockService
+	deletionService *service.AccountDeletionService
+	usernameService *service.UsernameService
+	logger          *logger.Logger
 }
 
-func NewSettingsHandler(settingsRepo *repository.SettingsRepository, logger *logger.Logger) *SettingsHandler {
+func NewSettingsHandler(settingsRepo *repository.SettingsRepository, blockService *service.BlockService, deletionService *service.AccountDeletionService, usernameService *service.UsernameService, logger *logger.Logger) *SettingsHandler {
 	return &SettingsHandler{
-		settingsRepo: settingsRepo,
-		logger:       logger,
+		settingsRepo:    settingsRepo,
+		blockService:    blockService,
+		deletionService: deletionService,
+		usernameService: usernameService,
+		logger:          logger,
 	}
 }
 
@@ -64,21 +72,16 @@ func (h *SettingsHandler) UpdateAccountSettings(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Validate email if provided
-	if req.Email != "" && !util.IsValidEmail(req.Email) {
-		util.RespondWithError(w, http.StatusBadRequest, "Invalid email format")
+	// Email changes need confirmation from the new address
+	if req.Email != "" && !strings.EqualFold(req.Email, user.Email) {
+		util.RespondWithError(w, http.StatusBadRequest, "Use POST /api/v1/auth/email/change to change your email address")
 		return
 	}
 
-	// Check if username is taken (if changing)
-	if req.Username != "" && req.Username != user.Username {
-		exists, err := h.settingsRepo.UsernameExists(r.Context(), req.Username, user.ID)
-		if err != nil {
-			util.RespondWithError(w, http.StatusInternalServerError, "Failed to check username")
-			return
-		}
-		if exists {
-			util.RespondWithError(w, http.StatusConflict, "Username already taken")
+	// Username changes have their own rules: cooldown, reservations and the blocklist
+	if req.Username != "" && !strings.EqualFold(req.Username, user.Username) {
+		if _, err := h.usernameService.ChangeUsername(r.Context(), user, req.Username, getIPAddress(r)); err != nil {
+			respondWithUsernameError(w, h.logger, err)
 			return
 		}
 	}
@@ -247,7 +250,7 @@ func (h *SettingsHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// BlockUser blocks a user
+// BlockUser blocks a user, unfriending and unfollowing them
 func (h *SettingsHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
 	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
@@ -263,7 +266,7 @@ func (h *SettingsHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.settingsRepo.BlockUser(r.Context(), user.ID, targetUserID); err != nil {
+	if err := h.blockService.Block(r.Context(), user.ID, targetUserID); err != nil {
 		h.logger.Error("Failed to block user", err)
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to block user")
 		return
@@ -283,7 +286,7 @@ func (h *SettingsHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	targetUserID := vars["userID"]
 
-	if err := h.settingsRepo.UnblockUser(r.Context(), user.ID, targetUserID); err != nil {
+	if err := h.blockService.Unblock(r.Context(), user.ID, targetUserID); err != nil {
 		h.logger.Error("Failed to unblock user", err)
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to unblock user")
 		return
@@ -339,7 +342,8 @@ func (h *SettingsHandler) ChangePassword(w http.ResponseWriter, r *http.Request)
 	util.RespondWithSuccess(w, "Password changed successfully", nil)
 }
 
-// DeleteAccount permanently deletes a user account
+// DeleteAccount schedules the user's account for deletion after a grace period.
+// The user is signed out; logging back in before the grace period ends cancels it.
 func (h *SettingsHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
@@ -364,14 +368,15 @@ func (h *SettingsHandler) DeleteAccount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Delete account (soft delete with 30-day grace period)
-	if err := h.settingsRepo.DeleteAccount(r.Context(), user.ID, req.Reason); err != nil {
+	// Start the grace period; the purge across services follows once it ends
+	deletion, err := h.deletionService.ScheduleDeletion(r.Context(), user, req.Reason, getIPAddress(r))
+	if err != nil {
 		h.logger.Error("Failed to delete account", err)
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to delete account")
 		return
 	}
 
-	util.RespondWithSuccess(w, "Account scheduled for deletion. You have 30 days to recover it.", nil)
+	util.RespondWithSuccess(w, "Account scheduled for deletion. Log in before "+deletion.PurgeAfter.UTC().Format("January 2, 2006")+" to keep it.", deletion)
 }
 
 // ClearCache clears user's cached data
@@ -1,175 +1,136 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
-	"socialink/user-service/internal/middleware"
-	"socialink/user-service/internal/model"
-	"socialink/user-service/internal/service"
-
-	"github.com/gin-gonic/gin"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
 )
 
-type TwoFactorHandler struct {
-	twoFactorService *service.TwoFactorService
+// TwoFactorCodeRequest carries a TOTP code, or a backup code where one is accepted
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
 }
 
-func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
-	return &TwoFactorHandler{twoFactorService: twoFactorService}
+// TwoFactorLoginRequest completes a login that returned a 2FA challenge
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // TOTP or backup code
 }
 
-// Setup2FA initializes 2FA for the user
-// @Summary Setup Two-Factor Authentication
-// @Description Generate QR code and backup codes for 2FA
-// @Tags 2fa
-// @Security BearerAuth
-// @Produce json
-// @Success 200 {object} model.TwoFactorSetupResponse
-// @Failure 401 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
-// @Router /auth/2fa/setup [post]
-func (h *TwoFactorHandler) Setup2FA(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+// HandleSetupTwoFactor generates a secret and backup codes for the authenticated user
+func (h *AuthHandler) HandleSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Unauthorized",
-			"message": "User not authenticated",
-		})
+		util.RespondWithUnauthorized(w, "")
 		return
 	}
 
-	response, err := h.twoFactorService.SetupTwoFactor(userID)
+	setup, err := h.twoFactor.Setup(r.Context(), user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to setup 2FA",
-			"message": err.Error(),
-		})
+		if errors.Is(err, repository.ErrTwoFactorEnabled) {
+			util.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+			return
+		}
+		h.logger.Error("Failed to set up 2FA", err)
+		util.RespondWithInternalError(w, "Failed to set up two-factor authentication")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "2FA setup initiated. Scan the QR code with your authenticator app",
-		"data":    response,
-	})
+	util.RespondWithSuccess(w, "Scan the QR code with your authenticator app, then confirm a code to finish", setup)
 }
 
-// Enable2FA enables 2FA after verification
-// @Summary Enable Two-Factor Authentication
-// @Description Enable 2FA by verifying TOTP code
-// @Tags 2fa
-// @Security BearerAuth
-// @Accept json
-// @Produce json
-// @Param request body model.Enable2FARequest true "Verification code"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Router /auth/2fa/enable [post]
-func (h *TwoFactorHandler) Enable2FA(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+// HandleEnableTwoFactor turns 2FA on once the user confirms a code from their authenticator
+func (h *AuthHandler) HandleEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		util.RespondWithUnauthorized(w, "")
 		return
 	}
 
-	var req model.Enable2FARequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"message": err.Error(),
-		})
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		util.RespondWithValidationError(w, "code", "Verification code is required")
 		return
 	}
 
-	err := h.twoFactorService.EnableTwoFactor(userID, req.Code)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Failed to enable 2FA",
-			"message": err.Error(),
-		})
+	if err := h.twoFactor.Enable(r.Context(), user.ID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, service.ErrTwoFactorNotSetUp),
+			errors.Is(err, service.ErrInvalidTwoFactorCode):
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrTwoFactorEnabled):
+			util.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		default:
+			h.logger.Error("Failed to enable 2FA", err)
+			util.RespondWithInternalError(w, "Failed to enable two-factor authentication")
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Two-factor authentication enabled successfully",
-	})
+	util.RespondWithSuccess(w, "Two-factor authentication enabled", nil)
 }
 
-// Verify2FA verifies a 2FA code
-// @Summary Verify 2FA Code
-// @Description Verify TOTP code or backup code
-// @Tags 2fa
-// @Accept json
-// @Produce json
-// @Param request body model.Verify2FARequest true "2FA code"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Router /auth/2fa/verify [post]
-func (h *TwoFactorHandler) Verify2FA(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+// HandleDisableTwoFactor turns 2FA off. A current code is required so a stolen
+// session alone cannot strip the second factor.
+func (h *AuthHandler) HandleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		util.RespondWithUnauthorized(w, "")
 		return
 	}
 
-	var req model.Verify2FARequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request",
-		})
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		util.RespondWithValidationError(w, "code", "A code from your authenticator or a backup code is required")
 		return
 	}
 
-	valid, err := h.twoFactorService.VerifyTwoFactorCode(userID, req.Code)
-	if err != nil || !valid {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid 2FA code",
-			"message": "The code you entered is incorrect",
-		})
+	valid, err := h.twoFactor.VerifyCode(r.Context(), user.ID, req.Code)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, service.ErrTwoFactorNotSetUp) {
+			util.RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+			return
+		}
+		h.logger.Error("Failed to verify 2FA code", err)
+		util.RespondWithInternalError(w, "Failed to disable two-factor authentication")
+		return
+	}
+	if !valid {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid 2FA code")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "2FA verification successful",
-	})
+	if err := h.twoFactor.Disable(r.Context(), user.ID); err != nil {
+		h.logger.Error("Failed to disable 2FA", err)
+		util.RespondWithInternalError(w, "Failed to disable two-factor authentication")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Two-factor authentication disabled", nil)
 }
 
-// Disable2FA disables 2FA for the user
-// @Summary Disable Two-Factor Authentication
-// @Description Disable 2FA for the authenticated user
-// @Tags 2fa
-// @Security BearerAuth
-// @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Router /auth/2fa/disable [post]
-func (h *TwoFactorHandler) Disable2FA(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+// HandleTwoFactorLogin exchanges the challenge token from login plus a TOTP or backup code for a session
+func (h *AuthHandler) HandleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Challenge token and code are required")
 		return
 	}
 
-	err := h.twoFactorService.DisableTwoFactor(userID)
+	result, err := h.authService.CompleteTwoFactorLogin(r.Context(), req.ChallengeToken, req.Code, getIPAddress(r), r.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to disable 2FA",
-			"message": err.Error(),
-		})
+		h.respondWithLoginError(w, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Two-factor authentication disabled",
-	})
+	h.respondWithLogin(w, result)
 }
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/logger"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+)
+
+// UsernameHandler handles username changes, lookups and the admin view of
+// username history
+type UsernameHandler struct {
+	usernameService *service.UsernameService
+	logger          *logger.Logger
+}
+
+// NewUsernameHandler creates a new username handler
+func NewUsernameHandler(usernameService *service.UsernameService, logger *logger.Logger) *UsernameHandler {
+	return &UsernameHandler{
+		usernameService: usernameService,
+		logger:          logger,
+	}
+}
+
+// HandleGetUsername returns the current user's username and when they may next change it
+func (h *UsernameHandler) HandleGetUsername(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	status, err := h.usernameService.Status(r.Context(), user)
+	if err != nil {
+		h.logger.Error("Failed to get username status", err)
+		util.RespondWithInternalError(w, "Failed to get username")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", status)
+}
+
+// HandleCheckAvailability reports whether the current user may take ?username=
+func (h *UsernameHandler) HandleCheckAvailability(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		util.RespondWithValidationError(w, "username", "username is required")
+		return
+	}
+
+	availability, err := h.usernameService.CheckAvailability(r.Context(), user, username)
+	if err != nil {
+		h.logger.Error("Failed to check username availability", err)
+		util.RespondWithInternalError(w, "Failed to check username")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", availability)
+}
+
+// HandleChangeUsername changes the current user's username
+func (h *UsernameHandler) HandleChangeUsername(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	change, err := h.usernameService.ChangeUsername(r.Context(), user, req.Username, getIPAddress(r))
+	if err != nil {
+		respondWithUsernameError(w, h.logger, err)
+		return
+	}
+
+	util.RespondWithSuccess(w, "Username changed", change)
+}
+
+// HandleLookupUsername finds the account with a username. A username its owner
+// gave up is answered with a redirect to their current one; the body carries the
+// account too, for clients that don't follow redirects. The redirect is temporary
+// because the old username may be taken by someone else once its reservation ends.
+func (h *UsernameHandler) HandleLookupUsername(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	lookup, err := h.usernameService.Lookup(r.Context(), viewer.ID, mux.Vars(r)["username"])
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			util.RespondWithNotFound(w, "User not found")
+			return
+		}
+		h.logger.Error("Failed to look up username", err)
+		util.RespondWithInternalError(w, "Failed to look up username")
+		return
+	}
+
+	if lookup.Redirected {
+		w.Header().Set("Location", "/api/v1/users/by-username/"+url.PathEscape(lookup.Username))
+		util.RespondWithJSON(w, http.StatusTemporaryRedirect, util.APIResponse{
+			Success: true,
+			Message: "This username has changed",
+			Data:    lookup,
+		})
+		return
+	}
+
+	util.RespondWithSuccess(w, "", lookup)
+}
+
+// HandleGetUsernameHistory lists every username a user has had (admin only)
+func (h *UsernameHandler) HandleGetUsernameHistory(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	if !util.IsValidUUID(userID) {
+		util.RespondWithValidationError(w, "user_id", "Invalid user ID")
+		return
+	}
+
+	history, err := h.usernameService.History(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get username history", err)
+		util.RespondWithInternalError(w, "Failed to get username history")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", map[string]interface{}{
+		"user_id": userID,
+		"history": history,
+	})
+}
+
+// respondWithUsernameError maps username service errors to responses
+func respondWithUsernameError(w http.ResponseWriter, logger *logger.Logger, err error) {
+	var invalid *service.InvalidUsernameError
+	var cooldown *service.UsernameCooldownError
+	var notFound *repository.NotFoundError
+	switch {
+	case errors.As(err, &invalid), errors.Is(err, service.ErrUsernameUnchanged):
+		util.RespondWithValidationError(w, "username", err.Error())
+	case errors.Is(err, service.ErrUsernameTaken), errors.Is(err, service.ErrUsernameReserved):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.As(err, &cooldown):
+		util.RespondWithJSON(w, http.StatusTooManyRequests, util.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+			Details: map[string]interface{}{"next_change_at": cooldown.NextChangeAt},
+		})
+	case errors.As(err, &notFound):
+		util.RespondWithNotFound(w, "User not found")
+	default:
+		logger.Error("Failed to change username", err)
+		util.RespondWithInternalError(w, "Failed to change username")
+	}
+}
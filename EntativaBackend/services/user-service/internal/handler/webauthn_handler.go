@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+	"user-service/internal/util"
+	"user-service/pkg/webauthn"
+)
+
+// FinishWebAuthnRegistrationRequest completes a registration ceremony
+type FinishWebAuthnRegistrationRequest struct {
+	CeremonyID string                       `json:"ceremony_id"`
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+// PasskeyLoginRequest completes a passwordless login
+type PasskeyLoginRequest struct {
+	CeremonyID string                     `json:"ceremony_id"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+// BeginTwoFactorPasskeyRequest starts a passkey second factor for a pending 2FA login
+type BeginTwoFactorPasskeyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TwoFactorPasskeyLoginRequest completes a 2FA login with a passkey instead of a code
+type TwoFactorPasskeyLoginRequest struct {
+	ChallengeToken string                     `json:"challenge_token"`
+	CeremonyID     string                     `json:"ceremony_id"`
+	Credential     webauthn.AssertionResponse `json:"credential"`
+}
+
+// RenameWebAuthnCredentialRequest renames a credential
+type RenameWebAuthnCredentialRequest struct {
+	Name string `json:"name"`
+}
+
+// HandleBeginWebAuthnRegistration returns options for navigator.credentials.create()
+func (h *AuthHandler) HandleBeginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	options, err := h.webAuthn.BeginRegistration(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyPasskeys) {
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to start passkey registration", err)
+		util.RespondWithInternalError(w, "Failed to start passkey registration")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", options)
+}
+
+// HandleFinishWebAuthnRegistration verifies the create() response and saves the passkey
+func (h *AuthHandler) HandleFinishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	var req FinishWebAuthnRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !util.IsValidUUID(req.CeremonyID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Name) > 100 {
+		util.RespondWithValidationError(w, "name", "Name must be at most 100 characters")
+		return
+	}
+
+	credential, err := h.webAuthn.FinishRegistration(r.Context(), user.ID, req.CeremonyID, req.Name, &req.Credential, getIPAddress(r))
+	if err != nil {
+		if errors.Is(err, service.ErrWebAuthnCeremonyInvalid) || errors.Is(err, service.ErrWebAuthnCredential) {
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to register passkey", err)
+		util.RespondWithInternalError(w, "Failed to register passkey")
+		return
+	}
+
+	util.RespondWithCreated(w, "Passkey registered", credential)
+}
+
+// HandleListWebAuthnCredentials lists the user's passkeys
+func (h *AuthHandler) HandleListWebAuthnCredentials(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	credentials, err := h.webAuthn.ListCredentials(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to list passkeys", err)
+		util.RespondWithInternalError(w, "Failed to list passkeys")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Passkeys retrieved successfully", credentials)
+}
+
+// HandleRenameWebAuthnCredential renames a passkey
+func (h *AuthHandler) HandleRenameWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	credentialID := mux.Vars(r)["id"]
+	if !util.IsValidUUID(credentialID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid credential ID")
+		return
+	}
+
+	var req RenameWebAuthnCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if name := strings.TrimSpace(req.Name); name == "" || len(name) > 100 {
+		util.RespondWithValidationError(w, "name", "Name must be between 1 and 100 characters")
+		return
+	}
+
+	if err := h.webAuthn.RenameCredential(r.Context(), user.ID, credentialID, req.Name); err != nil {
+		h.respondWithCredentialError(w, err, "Failed to rename passkey")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Passkey renamed", nil)
+}
+
+// HandleRevokeWebAuthnCredential deletes a passkey
+func (h *AuthHandler) HandleRevokeWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*repository.User)
+	if !ok {
+		util.RespondWithUnauthorized(w, "")
+		return
+	}
+
+	credentialID := mux.Vars(r)["id"]
+	if !util.IsValidUUID(credentialID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid credential ID")
+		return
+	}
+
+	if err := h.webAuthn.RevokeCredential(r.Context(), user.ID, credentialID, getIPAddress(r)); err != nil {
+		h.respondWithCredentialError(w, err, "Failed to revoke passkey")
+		return
+	}
+
+	util.RespondWithSuccess(w, "Passkey removed", nil)
+}
+
+// HandleBeginPasskeyLogin returns options for navigator.credentials.get() with a discoverable passkey
+func (h *AuthHandler) HandleBeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	options, err := h.webAuthn.BeginLogin(r.Context(), nil)
+	if err != nil {
+		h.logger.Error("Failed to start passkey login", err)
+		util.RespondWithInternalError(w, "Failed to start passkey login")
+		return
+	}
+
+	util.RespondWithSuccess(w, "", options)
+}
+
+// HandleFinishPasskeyLogin verifies the get() response and signs the user in
+func (h *AuthHandler) HandleFinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var req PasskeyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !util.IsValidUUID(req.CeremonyID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.authService.LoginWithPasskey(r.Context(), req.CeremonyID, &req.Credential, getIPAddress(r), r.UserAgent())
+	if err != nil {
+		h.respondWithLoginError(w, err)
+		return
+	}
+
+	h.respondWithLogin(w, result)
+}
+
+// HandleBeginTwoFactorPasskey starts a passkey assertion as the second factor of a login
+func (h *AuthHandler) HandleBeginTwoFactorPasskey(w http.ResponseWriter, r *http.Request) {
+	var req BeginTwoFactorPasskeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Challenge token is required")
+		return
+	}
+
+	options, err := h.authService.BeginTwoFactorPasskey(r.Context(), req.ChallengeToken, getIPAddress(r))
+	if err != nil {
+		h.respondWithLoginError(w, err)
+		return
+	}
+
+	util.RespondWithSuccess(w, "", options)
+}
+
+// HandleFinishTwoFactorPasskey completes a login with a passkey as the second factor
+func (h *AuthHandler) HandleFinishTwoFactorPasskey(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorPasskeyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || !util.IsValidUUID(req.CeremonyID) {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.authService.CompleteTwoFactorPasskeyLogin(r.Context(), req.ChallengeToken, req.CeremonyID, &req.Credential, getIPAddress(r), r.UserAgent())
+	if err != nil {
+		h.respondWithLoginError(w, err)
+		return
+	}
+
+	h.respondWithLogin(w, result)
+}
+
+func (h *AuthHandler) respondWithCredentialError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrCredentialNotFound) {
+		util.RespondWithNotFound(w, "Passkey not found")
+		return
+	}
+	h.logger.Error(message, err)
+	util.RespondWithInternalError(w, message)
+}
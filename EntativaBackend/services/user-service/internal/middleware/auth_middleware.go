@@ -3,23 +3,29 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"user-service/internal/logger"
 	"user-service/internal/repository"
 	"user-service/internal/util"
 )
 
+// lastActiveUpdateInterval limits how often a request refreshes its session's last_active_at
+const lastActiveUpdateInterval = 5 * time.Minute
+
 // AuthMiddleware handles JWT authentication
 type AuthMiddleware struct {
-	userRepo *repository.UserRepository
-	logger   *logger.Logger
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	logger      *logger.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(userRepo *repository.UserRepository, logger *logger.Logger) *AuthMiddleware {
+func NewAuthMiddleware(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, logger *logger.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		userRepo: userRepo,
-		logger:   logger,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		logger:      logger,
 	}
 }
 
@@ -47,6 +53,13 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 		
+		// The session must still exist; revoking it revokes every token issued for it
+		session, err := m.activeSession(r.Context(), claims)
+		if err != nil {
+			util.RespondWithUnauthorized(w, "Session has been revoked")
+			return
+		}
+		
 		// Get user from database
 		user, err := m.userRepo.FindByID(r.Context(), claims.UserID)
 		if err != nil {
@@ -63,6 +76,7 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		// Add user to request context
 		ctx := context.WithValue(r.Context(), "user", user)
 		ctx = context.WithValue(ctx, "user_id", user.ID)
+		ctx = context.WithValue(ctx, "session_id", session.ID)
 		
 		// Continue to next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -79,11 +93,15 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			if err == nil {
 				claims, err := util.ParseAccessToken(token)
 				if err == nil {
-					user, err := m.userRepo.FindByID(r.Context(), claims.UserID)
-					if err == nil && user.IsActive {
-						ctx := context.WithValue(r.Context(), "user", user)
-						ctx = context.WithValue(ctx, "user_id", user.ID)
-						r = r.WithContext(ctx)
+					session, err := m.activeSession(r.Context(), claims)
+					if err == nil {
+						user, err := m.userRepo.FindByID(r.Context(), claims.UserID)
+						if err == nil && user.IsActive {
+							ctx := context.WithValue(r.Context(), "user", user)
+							ctx = context.WithValue(ctx, "user_id", user.ID)
+							ctx = context.WithValue(ctx, "session_id", session.ID)
+							r = r.WithContext(ctx)
+						}
 					}
 				}
 			}
@@ -112,3 +130,26 @@ func (m *AuthMiddleware) RequireFounder(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// activeSession loads the session an access token was issued for and records activity on it
+func (m *AuthMiddleware) activeSession(ctx context.Context, claims *util.TokenClaims) (*repository.Session, error) {
+	if claims.SessionID == "" {
+		return nil, &repository.NotFoundError{Message: "Session not found"}
+	}
+	
+	session, err := m.sessionRepo.FindSessionByID(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, &repository.NotFoundError{Message: "Session not found"}
+	}
+	
+	if time.Since(session.LastActiveAt) > lastActiveUpdateInterval {
+		if err := m.sessionRepo.UpdateLastActive(ctx, session.ID); err != nil {
+			m.logger.Warn("Failed to update session activity", err)
+		}
+	}
+	
+	return session, nil
+}
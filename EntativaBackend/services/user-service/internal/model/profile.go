@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -77,7 +78,8 @@ type SocialLinks struct {
 	Website   *string `json:"website,omitempty"`
 }
 
-// ProfileVisibility controls who can see profile information
+// ProfileVisibility controls who can see profile information. Any field may
+// also be shown to one of the owner's audience lists, as "list:<list id>".
 type ProfileVisibility struct {
 	Bio              string `json:"bio"`               // public, friends, only_me
 	Work             string `json:"work"`              // public, friends, only_me
@@ -88,6 +90,56 @@ type ProfileVisibility struct {
 	Birthday         string `json:"birthday"`          // public, friends, only_me
 }
 
+// Audiences a profile field can be shown to
+const (
+	AudiencePublic  = "public"
+	AudienceFriends = "friends"
+	AudienceOnlyMe  = "only_me"
+
+	// AudienceListPrefix marks an audience that is one of the owner's lists
+	AudienceListPrefix = "list:"
+)
+
+// ListAudience is the audience for the members of an audience list
+func ListAudience(listID string) string {
+	return AudienceListPrefix + listID
+}
+
+// AudienceListID returns the list an audience refers to, if it is a list audience
+func AudienceListID(audience string) (string, bool) {
+	if !strings.HasPrefix(audience, AudienceListPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(audience, AudienceListPrefix), true
+}
+
+// ListIDs returns the audience lists the visibility settings refer to, once each
+func (p *ProfileVisibility) ListIDs() []string {
+	seen := map[string]bool{}
+	listIDs := []string{}
+	for _, audience := range []string{p.Bio, p.Work, p.Education, p.ContactInfo, p.RelationshipInfo, p.Hometown, p.Birthday} {
+		if listID, ok := AudienceListID(audience); ok && !seen[listID] {
+			seen[listID] = true
+			listIDs = append(listIDs, listID)
+		}
+	}
+	return listIDs
+}
+
+// DefaultProfileVisibility is what a new profile starts with, and what applies to
+// any field a stored ProfileVisibility leaves empty
+func DefaultProfileVisibility() *ProfileVisibility {
+	return &ProfileVisibility{
+		Bio:              AudiencePublic,
+		Work:             AudiencePublic,
+		Education:        AudiencePublic,
+		ContactInfo:      AudienceFriends,
+		RelationshipInfo: AudienceFriends,
+		Hometown:         AudiencePublic,
+		Birthday:         AudienceFriends,
+	}
+}
+
 // StringArray custom type for PostgreSQL array handling
 type StringArray []string
 
@@ -241,15 +293,16 @@ type UpdateSocialLinksRequest struct {
 	Website   *string `json:"website,omitempty" binding:"omitempty,url"`
 }
 
-// UpdateVisibilityRequest for privacy settings
+// UpdateVisibilityRequest for privacy settings. Each field takes public, friends,
+// only_me or a list audience; contact info may not be public.
 type UpdateVisibilityRequest struct {
-	Bio              *string `json:"bio,omitempty" binding:"omitempty,oneof=public friends only_me"`
-	Work             *string `json:"work,omitempty" binding:"omitempty,oneof=public friends only_me"`
-	Education        *string `json:"education,omitempty" binding:"omitempty,oneof=public friends only_me"`
-	ContactInfo      *string `json:"contact_info,omitempty" binding:"omitempty,oneof=friends only_me"`
-	RelationshipInfo *string `json:"relationship_info,omitempty" binding:"omitempty,oneof=public friends only_me"`
-	Hometown         *string `json:"hometown,omitempty" binding:"omitempty,oneof=public friends only_me"`
-	Birthday         *string `json:"birthday,omitempty" binding:"omitempty,oneof=public friends only_me"`
+	Bio              *string `json:"bio,omitempty"`
+	Work             *string `json:"work,omitempty"`
+	Education        *string `json:"education,omitempty"`
+	ContactInfo      *string `json:"contact_info,omitempty"`
+	RelationshipInfo *string `json:"relationship_info,omitempty"`
+	Hometown         *string `json:"hometown,omitempty"`
+	Birthday         *string `json:"birthday,omitempty"`
 }
 
 // ProfileResponse for API responses
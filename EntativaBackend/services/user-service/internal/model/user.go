@@ -22,6 +22,7 @@ type User struct {
 	CoverPhotoURL     *string    `json:"cover_photo_url,omitempty" db:"cover_photo_url"`
 	IsActive          bool       `json:"is_active" db:"is_active"`
 	IsDeleted         bool       `json:"is_deleted" db:"is_deleted"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
 	LastLoginAt       *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
 	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
@@ -47,14 +48,23 @@ type SignupResponse struct {
 type LoginRequest struct {
 	EmailOrUsername string `json:"email_or_username" binding:"required"`
 	Password        string `json:"password" binding:"required"`
+	PowChallenge    string `json:"pow_challenge,omitempty"` // set when retrying after a proof-of-work challenge
+	PowSolution     string `json:"pow_solution,omitempty"`
 }
 
-// AuthResponse represents the response after successful authentication
+// AuthResponse represents the response after successful authentication.
+// When the account has 2FA enabled, login only returns TwoFactorRequired and a
+// ChallengeToken; the tokens come from /auth/2fa/login (or /auth/2fa/webauthn/finish)
+// once the second factor is verified.
 type AuthResponse struct {
-	User        *UserResponse `json:"user"`
-	AccessToken string        `json:"access_token"`
-	TokenType   string        `json:"token_type"`
-	ExpiresIn   int64         `json:"expires_in"` // seconds
+	User              *UserResponse `json:"user,omitempty"`
+	AccessToken       string        `json:"access_token,omitempty"`
+	RefreshToken      string        `json:"refresh_token,omitempty"` // Opaque, single-use; exchange at /auth/refresh
+	TokenType         string        `json:"token_type,omitempty"`
+	ExpiresIn         int64         `json:"expires_in,omitempty"` // seconds
+	TwoFactorRequired bool          `json:"two_factor_required,omitempty"`
+	TwoFactorMethods  []string      `json:"two_factor_methods,omitempty"` // "totp", "webauthn"
+	ChallengeToken    string        `json:"challenge_token,omitempty"`
 }
 
 // UserResponse represents user data returned to clients (sanitized)
@@ -62,9 +72,9 @@ type UserResponse struct {
 	ID                uuid.UUID  `json:"id"`
 	FirstName         string     `json:"first_name"`
 	LastName          string     `json:"last_name"`
-	Email             string     `json:"email"`
+	Email             string     `json:"email,omitempty"`
 	Username          string     `json:"username"`
-	Birthday          time.Time  `json:"birthday"`
+	Birthday          *time.Time `json:"birthday,omitempty"`
 	Gender            string     `json:"gender"`
 	PhoneNumber       *string    `json:"phone_number,omitempty"`
 	Bio               *string    `json:"bio,omitempty"`
@@ -83,7 +93,7 @@ func (u *User) ToUserResponse() *UserResponse {
 		LastName:          u.LastName,
 		Email:             u.Email,
 		Username:          u.Username,
-		Birthday:          u.Birthday,
+		Birthday:          &u.Birthday,
 		Gender:            u.Gender,
 		PhoneNumber:       u.PhoneNumber,
 		Bio:               u.Bio,
@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Account deletion statuses
+const (
+	AccountDeletionScheduled = "scheduled"
+	AccountDeletionCancelled = "cancelled"
+	AccountDeletionPurging   = "purging"
+	AccountDeletionCompleted = "completed"
+)
+
+// Purge step statuses
+const (
+	DeletionStepPending   = "pending"
+	DeletionStepRequested = "requested"
+	DeletionStepFailed    = "failed"
+	DeletionStepCompleted = "completed"
+)
+
+// AccountDeletion is a request to delete an account, from the grace period through the purge
+type AccountDeletion struct {
+	ID             string
+	UserID         string
+	Status         string
+	Reason         string
+	RequestedIP    string
+	RequestedAt    time.Time
+	PurgeAfter     time.Time
+	CancelledAt    *time.Time
+	PurgeStartedAt *time.Time
+	CompletedAt    *time.Time
+	Steps          []*AccountDeletionStep
+}
+
+// AccountDeletionStep is one service's part in purging an account
+type AccountDeletionStep struct {
+	DeletionID    string
+	UserID        string
+	Service       string
+	Status        string
+	Attempts      int
+	LastError     string
+	RequestedAt   *time.Time
+	NextAttemptAt time.Time
+	CompletedAt   *time.Time
+}
+
+// AccountDeletionRepository handles database operations for account deletions
+type AccountDeletionRepository struct {
+	db *sql.DB
+}
+
+// NewAccountDeletionRepository creates a new account deletion repository
+func NewAccountDeletionRepository(db *sql.DB) *AccountDeletionRepository {
+	return &AccountDeletionRepository{db: db}
+}
+
+const accountDeletionColumns = `
+	id, user_id, status, COALESCE(reason, ''), COALESCE(requested_ip, ''), requested_at,
+	purge_after, cancelled_at, purge_started_at, completed_at
+`
+
+// ScheduleDeletion starts the grace period for deleting an account and marks the user
+// as pending deletion. If a deletion is already scheduled or purging, that one is
+// returned instead and created is false.
+func (r *AccountDeletionRepository) ScheduleDeletion(ctx context.Context, deletion *AccountDeletion) (*AccountDeletion, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO account_deletions (id, user_id, status, reason, requested_ip, requested_at, purge_after, updated_at)
+		 VALUES ($1, $2, 'scheduled', NULLIF($3, ''), NULLIF($4, ''), $5, $6, $5)
+		 ON CONFLICT (user_id) WHERE status IN ('scheduled', 'purging') DO NOTHING`,
+		deletion.ID, deletion.UserID, deletion.Reason, deletion.RequestedIP, deletion.RequestedAt, deletion.PurgeAfter,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		existing, err := scanAccountDeletion(tx.QueryRowContext(
+			ctx,
+			`SELECT `+accountDeletionColumns+` FROM account_deletions WHERE user_id = $1 AND status IN ('scheduled', 'purging')`,
+			deletion.UserID,
+		))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to find scheduled account deletion: %w", err)
+		}
+		return existing, false, nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE users SET deleted_at = $2, deletion_reason = NULLIF($3, ''), updated_at = NOW() WHERE id = $1`,
+		deletion.UserID, deletion.RequestedAt, deletion.Reason,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to mark user for deletion: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+
+	deletion.Status = AccountDeletionScheduled
+	return deletion, true, nil
+}
+
+// CancelScheduledDeletion cancels the user's deletion if it is still in its grace period.
+// Returns nil if there was nothing to cancel.
+func (r *AccountDeletionRepository) CancelScheduledDeletion(ctx context.Context, userID string) (*AccountDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := scanAccountDeletion(tx.QueryRowContext(
+		ctx,
+		`UPDATE account_deletions SET status = 'cancelled', cancelled_at = NOW(), updated_at = NOW()
+		 WHERE user_id = $1 AND status = 'scheduled'
+		 RETURNING `+accountDeletionColumns,
+		userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE users SET deleted_at = NULL, deletion_reason = NULL, updated_at = NOW() WHERE id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account deletion cancellation: %w", err)
+	}
+
+	return deletion, nil
+}
+
+// GetDeletion finds a deletion with the progress of each purge step.
+// Returns NotFoundError if it does not exist.
+func (r *AccountDeletionRepository) GetDeletion(ctx context.Context, id string) (*AccountDeletion, error) {
+	deletion, err := scanAccountDeletion(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+accountDeletionColumns+` FROM account_deletions WHERE id = $1`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Account deletion not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account deletion: %w", err)
+	}
+
+	if deletion.Steps, err = r.listSteps(ctx, deletion.ID); err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+// ListDeletions lists the deletions requested for a user, newest first, with their steps
+func (r *AccountDeletionRepository) ListDeletions(ctx context.Context, userID string) ([]*AccountDeletion, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+accountDeletionColumns+` FROM account_deletions WHERE user_id = $1 ORDER BY requested_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var deletions []*AccountDeletion
+	for rows.Next() {
+		deletion, err := scanAccountDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account deletion: %w", err)
+		}
+		deletions = append(deletions, deletion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, deletion := range deletions {
+		if deletion.Steps, err = r.listSteps(ctx, deletion.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return deletions, nil
+}
+
+// StartDuePurges moves deletions whose grace period is over into the purge, creating
+// a pending step for each service. The user can no longer log in from this point.
+func (r *AccountDeletionRepository) StartDuePurges(ctx context.Context, services []string, limit int) ([]*AccountDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`UPDATE account_deletions SET status = 'purging', purge_started_at = NOW(), updated_at = NOW()
+		 WHERE id IN (
+			SELECT id FROM account_deletions
+			WHERE status = 'scheduled' AND purge_after <= NOW()
+			ORDER BY purge_after
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+accountDeletionColumns,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start account purges: %w", err)
+	}
+
+	var deletions []*AccountDeletion
+	for rows.Next() {
+		deletion, err := scanAccountDeletion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan account deletion: %w", err)
+		}
+		deletions = append(deletions, deletion)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, deletion := range deletions {
+		for _, service := range services {
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO account_deletion_steps (deletion_id, service, status, next_attempt_at, updated_at)
+				 VALUES ($1, $2, 'pending', NOW(), NOW())
+				 ON CONFLICT (deletion_id, service) DO NOTHING`,
+				deletion.ID, service,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create purge step: %w", err)
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE users SET is_deleted = true, is_active = false, updated_at = NOW() WHERE id = $1`,
+			deletion.UserID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deactivate user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account purges: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// ClaimDueSteps marks up to limit unfinished steps whose next attempt is due as
+// requested and schedules the attempt after, backing off exponentially from
+// backoff up to maxBackoff. Callers then ask the services to purge.
+func (r *AccountDeletionRepository) ClaimDueSteps(ctx context.Context, limit int, backoff, maxBackoff time.Duration) ([]*AccountDeletionStep, error) {
+	query := `
+		UPDATE account_deletion_steps s SET
+			status = 'requested',
+			attempts = s.attempts + 1,
+			requested_at = NOW(),
+			next_attempt_at = NOW() + LEAST($2 * POWER(2, LEAST(s.attempts, 20)), $3) * INTERVAL '1 second',
+			updated_at = NOW()
+		FROM account_deletions d
+		WHERE d.id = s.deletion_id
+		  AND (s.deletion_id, s.service) IN (
+			SELECT st.deletion_id, st.service
+			FROM account_deletion_steps st
+			JOIN account_deletions ad ON ad.id = st.deletion_id
+			WHERE ad.status = 'purging' AND st.status <> 'completed' AND st.next_attempt_at <= NOW()
+			ORDER BY st.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF st SKIP LOCKED
+		  )
+		RETURNING s.deletion_id, d.user_id, s.service, s.status, s.attempts, COALESCE(s.last_error, ''),
+		          s.requested_at, s.next_attempt_at, s.completed_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, int64(backoff.Seconds()), int64(maxBackoff.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim purge steps: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []*AccountDeletionStep
+	for rows.Next() {
+		step := &AccountDeletionStep{}
+		err := rows.Scan(
+			&step.DeletionID, &step.UserID, &step.Service, &step.Status, &step.Attempts, &step.LastError,
+			&step.RequestedAt, &step.NextAttemptAt, &step.CompletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan purge step: %w", err)
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, rows.Err()
+}
+
+// ReportStep records a service's report on its purge step. Reports are idempotent:
+// a step stays completed once confirmed, and a failure is retried at the step's
+// next attempt. Returns NotFoundError for an unknown deletion or service.
+func (r *AccountDeletionRepository) ReportStep(ctx context.Context, deletionID, service string, succeeded bool, lastError string) error {
+	query := `
+		UPDATE account_deletion_steps SET
+			status = CASE WHEN status = 'completed' OR $3::boolean THEN 'completed' ELSE 'failed' END,
+			last_error = CASE WHEN status = 'completed' OR $3::boolean THEN NULL ELSE NULLIF($4, '') END,
+			completed_at = CASE WHEN $3::boolean THEN COALESCE(completed_at, NOW()) ELSE completed_at END,
+			updated_at = NOW()
+		WHERE deletion_id = $1 AND service = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, deletionID, service, succeeded, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to record purge step: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"Purge step not found"}
+	}
+
+	return nil
+}
+
+// RetryNow makes every unfinished step of a purge due immediately
+func (r *AccountDeletionRepository) RetryNow(ctx context.Context, deletionID string) error {
+	query := `
+		UPDATE account_deletion_steps SET next_attempt_at = NOW(), updated_at = NOW()
+		WHERE deletion_id = $1 AND status <> 'completed'
+	`
+
+	_, err := r.db.ExecContext(ctx, query, deletionID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule purge steps: %w", err)
+	}
+
+	return nil
+}
+
+// FinishPurges completes purges every service has confirmed by deleting the user's
+// row, which cascades to everything this service holds about them
+func (r *AccountDeletionRepository) FinishPurges(ctx context.Context, limit int) ([]*AccountDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`UPDATE account_deletions SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		 WHERE id IN (
+			SELECT d.id FROM account_deletions d
+			WHERE d.status = 'purging'
+			  AND NOT EXISTS (
+				SELECT 1 FROM account_deletion_steps s WHERE s.deletion_id = d.id AND s.status <> 'completed'
+			  )
+			ORDER BY d.purge_started_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+accountDeletionColumns,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish account purges: %w", err)
+	}
+
+	var deletions []*AccountDeletion
+	for rows.Next() {
+		deletion, err := scanAccountDeletion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan account deletion: %w", err)
+		}
+		deletions = append(deletions, deletion)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, deletion := range deletions {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, deletion.UserID); err != nil {
+			return nil, fmt.Errorf("failed to delete user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account purges: %w", err)
+	}
+
+	return deletions, nil
+}
+
+func (r *AccountDeletionRepository) listSteps(ctx context.Context, deletionID string) ([]*AccountDeletionStep, error) {
+	query := `
+		SELECT s.deletion_id, d.user_id, s.service, s.status, s.attempts, COALESCE(s.last_error, ''),
+		       s.requested_at, s.next_attempt_at, s.completed_at
+		FROM account_deletion_steps s
+		JOIN account_deletions d ON d.id = s.deletion_id
+		WHERE s.deletion_id = $1
+		ORDER BY s.service
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, deletionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge steps: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []*AccountDeletionStep
+	for rows.Next() {
+		step := &AccountDeletionStep{}
+		err := rows.Scan(
+			&step.DeletionID, &step.UserID, &step.Service, &step.Status, &step.Attempts, &step.LastError,
+			&step.RequestedAt, &step.NextAttemptAt, &step.CompletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan purge step: %w", err)
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, rows.Err()
+}
+
+func scanAccountDeletion(row rowScanner) (*AccountDeletion, error) {
+	deletion := &AccountDeletion{}
+	err := row.Scan(
+		&deletion.ID, &deletion.UserID, &deletion.Status, &deletion.Reason, &deletion.RequestedIP, &deletion.RequestedAt,
+		&deletion.PurgeAfter, &deletion.CancelledAt, &deletion.PurgeStartedAt, &deletion.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of audience list
+const (
+	AudienceListCloseFriends = "close_friends"
+	AudienceListCustom       = "custom"
+)
+
+// AudienceList is a named group of people a user can share with
+type AudienceList struct {
+	ID          string
+	OwnerID     string
+	Name        string
+	Kind        string
+	MemberCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// AudienceListMember is someone in an audience list
+type AudienceListMember struct {
+	UserID            string
+	Username          string
+	FirstName         string
+	LastName          string
+	ProfilePictureURL *string
+	AddedAt           time.Time
+}
+
+// AudienceMembershipCheck asks whether ViewerID is in OwnerID's list ListID
+type AudienceMembershipCheck struct {
+	OwnerID  string
+	ListID   string
+	ViewerID string
+}
+
+// AudienceListRepository handles database operations for audience lists
+type AudienceListRepository struct {
+	db *sql.DB
+}
+
+// NewAudienceListRepository creates a new audience list repository
+func NewAudienceListRepository(db *sql.DB) *AudienceListRepository {
+	return &AudienceListRepository{db: db}
+}
+
+const audienceListColumns = `
+	l.id, l.owner_id, l.name, l.kind,
+	(SELECT COUNT(*) FROM audience_list_members m WHERE m.list_id = l.id),
+	l.created_at, l.updated_at`
+
+func scanAudienceList(row interface{ Scan(...interface{}) error }) (*AudienceList, error) {
+	list := &AudienceList{}
+	err := row.Scan(&list.ID, &list.OwnerID, &list.Name, &list.Kind, &list.MemberCount, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CreateList stores a new audience list
+func (r *AudienceListRepository) CreateList(ctx context.Context, list *AudienceList) error {
+	query := `
+		INSERT INTO audience_lists (id, owner_id, name, kind, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, list.ID, list.OwnerID, list.Name, list.Kind, list.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audience list: %w", err)
+	}
+
+	return nil
+}
+
+// GetList finds one of a user's audience lists.
+// Returns NotFoundError if it does not exist or belongs to someone else.
+func (r *AudienceListRepository) GetList(ctx context.Context, id, ownerID string) (*AudienceList, error) {
+	query := `SELECT ` + audienceListColumns + ` FROM audience_lists l WHERE l.id = $1 AND l.owner_id = $2`
+
+	list, err := scanAudienceList(r.db.QueryRowContext(ctx, query, id, ownerID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Audience list not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audience list: %w", err)
+	}
+
+	return list, nil
+}
+
+// GetCloseFriendsList finds the user's Close Friends list.
+// Returns NotFoundError if they have never made one.
+func (r *AudienceListRepository) GetCloseFriendsList(ctx context.Context, ownerID string) (*AudienceList, error) {
+	query := `SELECT ` + audienceListColumns + ` FROM audience_lists l WHERE l.owner_id = $1 AND l.kind = $2`
+
+	list, err := scanAudienceList(r.db.QueryRowContext(ctx, query, ownerID, AudienceListCloseFriends))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Audience list not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find close friends list: %w", err)
+	}
+
+	return list, nil
+}
+
+// ListLists returns all of a user's audience lists, Close Friends first
+func (r *AudienceListRepository) ListLists(ctx context.Context, ownerID string) ([]*AudienceList, error) {
+	query := `
+		SELECT ` + audienceListColumns + `
+		FROM audience_lists l
+		WHERE l.owner_id = $1
+		ORDER BY l.kind = 'close_friends' DESC, LOWER(l.name)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audience lists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := []*AudienceList{}
+	for rows.Next() {
+		list, err := scanAudienceList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// CountLists counts a user's audience lists
+func (r *AudienceListRepository) CountLists(ctx context.Context, ownerID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audience_lists WHERE owner_id = $1`, ownerID).Scan(&count)
+	return count, err
+}
+
+// NameExists reports whether the user has another list with this name, ignoring case
+func (r *AudienceListRepository) NameExists(ctx context.Context, ownerID, name, excludeID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM audience_lists
+			WHERE owner_id = $1 AND LOWER(name) = LOWER($2) AND id::text != $3
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, ownerID, name, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// RenameList renames one of a user's audience lists
+func (r *AudienceListRepository) RenameList(ctx context.Context, id, ownerID, name string) error {
+	query := `UPDATE audience_lists SET name = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND owner_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, ownerID, name)
+	if err != nil {
+		return fmt.Errorf("failed to rename audience list: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return &NotFoundError{"Audience list not found"}
+	}
+
+	return nil
+}
+
+// DeleteList deletes one of a user's audience lists along with its members
+func (r *AudienceListRepository) DeleteList(ctx context.Context, id, ownerID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM audience_lists WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete audience list: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return &NotFoundError{"Audience list not found"}
+	}
+
+	return nil
+}
+
+// ListMembers returns a page of a list's members, most recently added first
+func (r *AudienceListRepository) ListMembers(ctx context.Context, listID string, limit, offset int) ([]*AudienceListMember, error) {
+	query := `
+		SELECT u.id, u.username, u.first_name, u.last_name, u.profile_picture_url, m.added_at
+		FROM audience_list_members m
+		JOIN users u ON u.id = m.member_id AND u.is_active = true AND u.is_deleted = false
+		WHERE m.list_id = $1
+		ORDER BY m.added_at DESC, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, listID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audience list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*AudienceListMember{}
+	for rows.Next() {
+		member := &AudienceListMember{}
+		if err := rows.Scan(
+			&member.UserID, &member.Username, &member.FirstName, &member.LastName,
+			&member.ProfilePictureURL, &member.AddedAt,
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMembers adds users to a list, skipping any already in it or no longer
+// active. Returns how many were added.
+func (r *AudienceListRepository) AddMembers(ctx context.Context, listID string, memberIDs []string) (int, error) {
+	query := `
+		INSERT INTO audience_list_members (list_id, member_id, added_at)
+		SELECT $1, u.id, CURRENT_TIMESTAMP
+		FROM users u
+		WHERE u.id = ANY($2::uuid[]) AND u.is_active = true AND u.is_deleted = false
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, listID, pq.Array(memberIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add audience list members: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE audience_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, listID); err != nil {
+		return int(rows), fmt.Errorf("failed to touch audience list: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// RemoveMembers takes users out of a list. Returns how many were removed.
+func (r *AudienceListRepository) RemoveMembers(ctx context.Context, listID string, memberIDs []string) (int, error) {
+	query := `DELETE FROM audience_list_members WHERE list_id = $1 AND member_id = ANY($2::uuid[])`
+
+	result, err := r.db.ExecContext(ctx, query, listID, pq.Array(memberIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove audience list members: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE audience_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, listID); err != nil {
+		return int(rows), fmt.Errorf("failed to touch audience list: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// CountMembers counts the members of a list
+func (r *AudienceListRepository) CountMembers(ctx context.Context, listID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audience_list_members WHERE list_id = $1`, listID).Scan(&count)
+	return count, err
+}
+
+// OwnedListIDs returns those of listIDs that belong to ownerID
+func (r *AudienceListRepository) OwnedListIDs(ctx context.Context, ownerID string, listIDs []string) ([]string, error) {
+	query := `SELECT id FROM audience_lists WHERE owner_id = $1 AND id = ANY($2::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID, pq.Array(listIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audience lists: %w", err)
+	}
+	defer rows.Close()
+
+	var owned []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owned = append(owned, id)
+	}
+
+	return owned, rows.Err()
+}
+
+// CheckMemberships answers a batch of "is the viewer in this owner's list"
+// questions in one query. A list that doesn't exist, or isn't the owner's, has no
+// members. Results are in the order of checks.
+func (r *AudienceListRepository) CheckMemberships(ctx context.Context, checks []AudienceMembershipCheck) ([]bool, error) {
+	if len(checks) == 0 {
+		return []bool{}, nil
+	}
+
+	owners := make([]string, len(checks))
+	lists := make([]string, len(checks))
+	viewers := make([]string, len(checks))
+	for i, check := range checks {
+		owners[i] = check.OwnerID
+		lists[i] = check.ListID
+		viewers[i] = check.ViewerID
+	}
+
+	query := `
+		SELECT c.idx, EXISTS(
+			SELECT 1
+			FROM audience_lists l
+			JOIN audience_list_members m ON m.list_id = l.id
+			WHERE l.id = c.list_id AND l.owner_id = c.owner_id AND m.member_id = c.viewer_id
+		)
+		FROM unnest($1::uuid[], $2::uuid[], $3::uuid[]) WITH ORDINALITY AS c(owner_id, list_id, viewer_id, idx)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(owners), pq.Array(lists), pq.Array(viewers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audience list memberships: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]bool, len(checks))
+	for rows.Next() {
+		var idx int
+		var member bool
+		if err := rows.Scan(&idx, &member); err != nil {
+			return nil, err
+		}
+		results[idx-1] = member
+	}
+
+	return results, rows.Err()
+}
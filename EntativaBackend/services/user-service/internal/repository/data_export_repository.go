@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Data export statuses
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+// ErrExportLeaseLost is returned when a worker writes to an export another worker has taken over
+var ErrExportLeaseLost = errors.New("export lease lost")
+
+// DataExport is a user's request for a copy of their data
+type DataExport struct {
+	ID                string
+	UserID            string
+	Status            string
+	CompletedSections []string
+	Attempts          int
+	LeaseOwner        string
+	LeaseExpiresAt    *time.Time
+	ArchiveKey        string
+	ArchiveSize       int64
+	DownloadCount     int
+	LastError         string
+	RequestedIP       string
+	CreatedAt         time.Time
+	StartedAt         *time.Time
+	CompletedAt       *time.Time
+	ExpiresAt         *time.Time
+}
+
+// DataExportPart is one page of collected data for a section of an export
+type DataExportPart struct {
+	Section    string
+	Page       int
+	Content    json.RawMessage
+	NextCursor string
+}
+
+// DataExportRepository handles database operations for data exports
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+const dataExportColumns = `
+	id, user_id, status, completed_sections, attempts, COALESCE(lease_owner, ''),
+	lease_expires_at, COALESCE(archive_key, ''), COALESCE(archive_size, 0), download_count,
+	COALESCE(last_error, ''), COALESCE(requested_ip, ''), created_at, started_at, completed_at, expires_at
+`
+
+// CreateExport queues a new export
+func (r *DataExportRepository) CreateExport(ctx context.Context, export *DataExport) error {
+	query := `
+		INSERT INTO data_exports (id, user_id, status, requested_ip, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $5)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, export.ID, export.UserID, export.Status, export.RequestedIP, export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return nil
+}
+
+// GetExport finds one of a user's exports.
+// Returns NotFoundError if it does not exist or belongs to someone else.
+func (r *DataExportRepository) GetExport(ctx context.Context, id, userID string) (*DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Data export not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// ListExports lists a user's exports, newest first
+func (r *DataExportRepository) ListExports(ctx context.Context, userID string, limit int) ([]*DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []*DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+// FindLatestExport returns the user's most recent export, or NotFoundError if they never requested one
+func (r *DataExportRepository) FindLatestExport(ctx context.Context, userID string) (*DataExport, error) {
+	exports, err := r.ListExports(ctx, userID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(exports) == 0 {
+		return nil, &NotFoundError{"Data export not found"}
+	}
+	return exports[0], nil
+}
+
+// ClaimNextExport leases the oldest export that is waiting, or whose worker's
+// lease has run out, to workerID. Returns nil when there is nothing to do.
+func (r *DataExportRepository) ClaimNextExport(ctx context.Context, workerID string, lease time.Duration) (*DataExport, error) {
+	query := `
+		UPDATE data_exports SET
+			status = 'running',
+			lease_owner = $1,
+			lease_expires_at = NOW() + $2 * INTERVAL '1 second',
+			attempts = attempts + 1,
+			started_at = COALESCE(started_at, NOW()),
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' OR (status = 'running' AND lease_expires_at < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + dataExportColumns
+
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, query, workerID, int64(lease.Seconds())))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// RenewLease extends a worker's lease on an export.
+// Returns ErrExportLeaseLost if another worker has claimed it since.
+func (r *DataExportRepository) RenewLease(ctx context.Context, id, workerID string, lease time.Duration) error {
+	query := `
+		UPDATE data_exports SET lease_expires_at = NOW() + $3 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = $1 AND lease_owner = $2 AND status = 'running'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, workerID, int64(lease.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to renew export lease: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrExportLeaseLost
+	}
+
+	return nil
+}
+
+// ListParts returns the collected pages of an export in section and page order
+func (r *DataExportRepository) ListParts(ctx context.Context, exportID string) ([]*DataExportPart, error) {
+	query := `
+		SELECT section, page, content, COALESCE(next_cursor, '')
+		FROM data_export_parts
+		WHERE export_id = $1
+		ORDER BY section, page
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, exportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export parts: %w", err)
+	}
+	defer rows.Close()
+
+	var parts []*DataExportPart
+	for rows.Next() {
+		part := &DataExportPart{}
+		if err := rows.Scan(&part.Section, &part.Page, &part.Content, &part.NextCursor); err != nil {
+			return nil, fmt.Errorf("failed to scan export part: %w", err)
+		}
+		parts = append(parts, part)
+	}
+
+	return parts, rows.Err()
+}
+
+// SavePart stores a page of collected data, and when sectionDone marks the section
+// complete. The write only happens while workerID still holds the lease, so a
+// worker that stalled past its lease cannot interleave pages with its successor.
+func (r *DataExportRepository) SavePart(ctx context.Context, exportID, workerID string, part *DataExportPart, sectionDone bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owner string
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(lease_owner, '') FROM data_exports WHERE id = $1 AND status = 'running' FOR UPDATE`,
+		exportID,
+	).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != workerID) {
+		return ErrExportLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock data export: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO data_export_parts (export_id, section, page, content, next_cursor, created_at)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+		 ON CONFLICT (export_id, section, page) DO UPDATE SET content = EXCLUDED.content, next_cursor = EXCLUDED.next_cursor`,
+		exportID, part.Section, part.Page, []byte(part.Content), part.NextCursor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save export part: %w", err)
+	}
+
+	if sectionDone {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE data_exports SET
+				completed_sections = CASE WHEN completed_sections ? $2 THEN completed_sections
+				                          ELSE completed_sections || to_jsonb($2::text) END,
+				updated_at = NOW()
+			 WHERE id = $1`,
+			exportID, part.Section,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to mark export section complete: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit export part: %w", err)
+	}
+
+	return nil
+}
+
+// MarkReady records the finished archive and drops the collected parts it was built from
+func (r *DataExportRepository) MarkReady(ctx context.Context, exportID, workerID, archiveKey string, archiveSize int64, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE data_exports SET
+			status = 'ready', archive_key = $3, archive_size = $4, expires_at = $5,
+			completed_at = NOW(), lease_owner = NULL, lease_expires_at = NULL, last_error = NULL, updated_at = NOW()
+		 WHERE id = $1 AND lease_owner = $2 AND status = 'running'`,
+		exportID, workerID, archiveKey, archiveSize, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export ready: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrExportLeaseLost
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM data_export_parts WHERE export_id = $1`, exportID); err != nil {
+		return fmt.Errorf("failed to delete export parts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit data export: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseExport records a failed attempt and gives the export up so it is retried
+// on the next claim, or when final marks it failed and drops its parts
+func (r *DataExportRepository) ReleaseExport(ctx context.Context, exportID, workerID, lastError string, final bool) error {
+	status := DataExportPending
+	if final {
+		status = DataExportFailed
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE data_exports SET
+			status = $3, last_error = $4, lease_owner = NULL, lease_expires_at = NULL,
+			completed_at = CASE WHEN $3 = 'failed' THEN NOW() END, updated_at = NOW()
+		 WHERE id = $1 AND lease_owner = $2 AND status = 'running'`,
+		exportID, workerID, status, lastError,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release data export: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrExportLeaseLost
+	}
+
+	if final {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM data_export_parts WHERE export_id = $1`, exportID); err != nil {
+			return fmt.Errorf("failed to delete export parts: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordDownload counts a download of a ready export
+func (r *DataExportRepository) RecordDownload(ctx context.Context, exportID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE data_exports SET download_count = download_count + 1, updated_at = NOW() WHERE id = $1`, exportID)
+	if err != nil {
+		return fmt.Errorf("failed to record export download: %w", err)
+	}
+	return nil
+}
+
+// ExpireExports marks ready exports past their expiry as expired and returns
+// them so their archives can be deleted
+func (r *DataExportRepository) ExpireExports(ctx context.Context) ([]*DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = 'expired', updated_at = NOW()
+		WHERE status = 'ready' AND expires_at < NOW()
+		RETURNING ` + dataExportColumns
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []*DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+// exportSectionQueries select each section of the user's data held by this
+// service as a single JSON document. Secrets (password hashes, session tokens,
+// 2FA seeds) are never selected.
+var exportSectionQueries = map[string]string{
+	"account": `
+		SELECT to_jsonb(u) - 'password_hash'
+		FROM users u WHERE u.id = $1`,
+	"profile": `
+		SELECT COALESCE((SELECT to_jsonb(p) FROM profiles p WHERE p.user_id = $1), 'null'::jsonb)`,
+	"connections": `
+		SELECT jsonb_build_object(
+			'friends', (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+					'user_id', CASE WHEN f.user_id_1 = $1 THEN f.user_id_2 ELSE f.user_id_1 END,
+					'relationship_type', f.relationship_type,
+					'since', f.created_at) ORDER BY f.created_at), '[]'::jsonb)
+				FROM friendships f WHERE f.user_id_1 = $1 OR f.user_id_2 = $1),
+			'friend_requests_sent', (SELECT COALESCE(jsonb_agg(to_jsonb(r) ORDER BY r.created_at), '[]'::jsonb)
+				FROM friend_requests r WHERE r.sender_id = $1),
+			'friend_requests_received', (SELECT COALESCE(jsonb_agg(to_jsonb(r) ORDER BY r.created_at), '[]'::jsonb)
+				FROM friend_requests r WHERE r.receiver_id = $1),
+			'following', (SELECT COALESCE(jsonb_agg(to_jsonb(f) ORDER BY f.created_at), '[]'::jsonb)
+				FROM follows f WHERE f.follower_id = $1),
+			'followers', (SELECT COALESCE(jsonb_agg(to_jsonb(f) ORDER BY f.created_at), '[]'::jsonb)
+				FROM follows f WHERE f.following_id = $1),
+			'close_friends', (SELECT COALESCE(jsonb_agg(to_jsonb(c) ORDER BY c.created_at), '[]'::jsonb)
+				FROM close_friends c WHERE c.user_id = $1),
+			'audience_lists', (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+					'id', l.id, 'name', l.name, 'kind', l.kind, 'created_at', l.created_at,
+					'member_ids', (SELECT COALESCE(jsonb_agg(m.member_id ORDER BY m.added_at), '[]'::jsonb)
+						FROM audience_list_members m WHERE m.list_id = l.id)) ORDER BY l.created_at), '[]'::jsonb)
+				FROM audience_lists l WHERE l.owner_id = $1)
+		)`,
+	"login_activity": `
+		SELECT jsonb_build_object(
+			'sessions', (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+					'id', s.id, 'device_info', s.device_info, 'device_name', s.device_name,
+					'location', s.location, 'ip_address', s.ip_address, 'user_agent', s.user_agent,
+					'created_at', s.created_at, 'last_active_at', s.last_active_at,
+					'expires_at', s.expires_at) ORDER BY s.created_at), '[]'::jsonb)
+				FROM sessions s WHERE s.user_id = $1),
+			'login_attempts', (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+					'ip_address', a.ip_address, 'success', a.success,
+					'reason', a.reason, 'created_at', a.created_at) ORDER BY a.created_at), '[]'::jsonb)
+				FROM login_attempts a WHERE a.user_id = $1)
+		)`,
+	"settings": `
+		SELECT jsonb_build_object(
+			'settings', (SELECT to_jsonb(s) - 'user_id' FROM user_settings s WHERE s.user_id = $1),
+			'blocked_users', (SELECT COALESCE(jsonb_agg(to_jsonb(b) - 'user_id' ORDER BY b.blocked_at), '[]'::jsonb)
+				FROM blocked_users b WHERE b.user_id = $1),
+			'muted_users', (SELECT COALESCE(jsonb_agg(to_jsonb(m) - 'user_id' ORDER BY m.muted_at), '[]'::jsonb)
+				FROM muted_users m WHERE m.user_id = $1),
+			'restricted_users', (SELECT COALESCE(jsonb_agg(to_jsonb(r) - 'user_id' ORDER BY r.restricted_at), '[]'::jsonb)
+				FROM restricted_users r WHERE r.user_id = $1)
+		)`,
+	"linked_accounts": `
+		SELECT jsonb_build_object(
+			'linked_accounts', (SELECT COALESCE(jsonb_agg(to_jsonb(l) - 'user_id' ORDER BY l.created_at), '[]'::jsonb)
+				FROM cross_platform_links l WHERE l.user_id = $1),
+			'merged_accounts', (SELECT COALESCE(jsonb_agg(to_jsonb(m) ORDER BY m.created_at), '[]'::jsonb)
+				FROM account_merges m WHERE m.target_user_id = $1)
+		)`,
+	"takes": `
+		SELECT jsonb_build_object(
+			'takes', (SELECT COALESCE(jsonb_agg(to_jsonb(t) ORDER BY t.created_at), '[]'::jsonb)
+				FROM takes t WHERE t.user_id = $1),
+			'comments', (SELECT COALESCE(jsonb_agg(to_jsonb(c) ORDER BY c.created_at), '[]'::jsonb)
+				FROM take_comments c WHERE c.user_id = $1),
+			'likes', (SELECT COALESCE(jsonb_agg(to_jsonb(l) ORDER BY l.created_at), '[]'::jsonb)
+				FROM take_likes l WHERE l.user_id = $1),
+			'saves', (SELECT COALESCE(jsonb_agg(to_jsonb(s) ORDER BY s.created_at), '[]'::jsonb)
+				FROM take_saves s WHERE s.user_id = $1)
+		)`,
+}
+
+// HasExportSection reports whether section is held by this service
+func HasExportSection(section string) bool {
+	_, ok := exportSectionQueries[section]
+	return ok
+}
+
+// ExportSection returns one section of the data this service holds about a user as JSON
+func (r *DataExportRepository) ExportSection(ctx context.Context, section, userID string) (json.RawMessage, error) {
+	query, ok := exportSectionQueries[section]
+	if !ok {
+		return nil, fmt.Errorf("unknown export section: %s", section)
+	}
+
+	var content []byte
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&content); err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", section, err)
+	}
+
+	return content, nil
+}
+
+func scanDataExport(row rowScanner) (*DataExport, error) {
+	export := &DataExport{}
+	var sectionsJSON []byte
+
+	err := row.Scan(
+		&export.ID, &export.UserID, &export.Status, &sectionsJSON, &export.Attempts, &export.LeaseOwner,
+		&export.LeaseExpiresAt, &export.ArchiveKey, &export.ArchiveSize, &export.DownloadCount,
+		&export.LastError, &export.RequestedIP, &export.CreatedAt, &export.StartedAt, &export.CompletedAt, &export.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(sectionsJSON, &export.CompletedSections)
+	return export, nil
+}
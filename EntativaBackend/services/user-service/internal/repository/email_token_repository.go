@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"user-service/internal/util"
+)
+
+// Email token purposes
+const (
+	EmailTokenVerify     = "verify_email"
+	EmailTokenChange     = "change_email"
+	EmailTokenUndoChange = "undo_email_change"
+)
+
+// EmailToken is a single-use link sent by email.
+// Only the selector and a hash of the verifier are stored; see util.GenerateSplitToken.
+type EmailToken struct {
+	ID            string
+	UserID        string
+	Purpose       string
+	Selector      string
+	VerifierHash  string
+	Email         string // address the token confirms, or restores for an undo
+	PreviousEmail string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+// EmailTokenRepository handles database operations for email tokens
+type EmailTokenRepository struct {
+	db *sql.DB
+}
+
+// NewEmailTokenRepository creates a new email token repository
+func NewEmailTokenRepository(db *sql.DB) *EmailTokenRepository {
+	return &EmailTokenRepository{db: db}
+}
+
+// CreateEmailToken creates a new email token
+func (r *EmailTokenRepository) CreateEmailToken(ctx context.Context, token *EmailToken) error {
+	query := `
+		INSERT INTO email_tokens (
+			id, user_id, purpose, selector, verifier_hash,
+			email, previous_email, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		token.ID, token.UserID, token.Purpose, token.Selector, token.VerifierHash,
+		token.Email, token.PreviousEmail, token.ExpiresAt, token.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create email token: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeEmailToken finds an unused, unexpired token for purpose by the
+// selector.verifier string the user was sent and marks it used.
+// Returns NotFoundError if the token is unknown, expired, used or for another purpose.
+func (r *EmailTokenRepository) ConsumeEmailToken(ctx context.Context, purpose, token string) (*EmailToken, error) {
+	selector, verifier, err := util.ParseSplitToken(token)
+	if err != nil {
+		return nil, &NotFoundError{"Email token not found"}
+	}
+
+	query := `
+		SELECT id, user_id, purpose, selector, verifier_hash,
+		       email, COALESCE(previous_email, ''), expires_at, used_at, created_at
+		FROM email_tokens
+		WHERE selector = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	emailToken := &EmailToken{}
+	err = r.db.QueryRowContext(ctx, query, selector, purpose).Scan(
+		&emailToken.ID, &emailToken.UserID, &emailToken.Purpose, &emailToken.Selector, &emailToken.VerifierHash,
+		&emailToken.Email, &emailToken.PreviousEmail, &emailToken.ExpiresAt, &emailToken.UsedAt, &emailToken.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Email token not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email token: %w", err)
+	}
+
+	if !util.VerifierMatches(verifier, emailToken.VerifierHash) {
+		return nil, &NotFoundError{"Email token not found"}
+	}
+
+	// Claim the token; a concurrent request with the same link loses here
+	result, err := r.db.ExecContext(ctx, `UPDATE email_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, emailToken.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume email token: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, &NotFoundError{"Email token not found"}
+	}
+
+	return emailToken, nil
+}
+
+// InvalidateUserEmailTokens marks a user's outstanding tokens for purpose as used
+func (r *EmailTokenRepository) InvalidateUserEmailTokens(ctx context.Context, userID, purpose string) error {
+	query := `UPDATE email_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate email tokens: %w", err)
+	}
+
+	return nil
+}
+
+// CountRecentEmailTokens counts tokens for purpose issued to a user since a point in time
+func (r *EmailTokenRepository) CountRecentEmailTokens(ctx context.Context, userID, purpose string, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM email_tokens WHERE user_id = $1 AND purpose = $2 AND created_at > $3`
+
+	if err := r.db.QueryRowContext(ctx, query, userID, purpose, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count email tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteExpiredEmailTokens deletes expired email tokens (cleanup job)
+func (r *EmailTokenRepository) DeleteExpiredEmailTokens(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM email_tokens WHERE expires_at < NOW()`)
+	return err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// FriendGraphRepository reads who is friends with whom, for other services that
+// build on the friend graph
+type FriendGraphRepository struct {
+	db *sql.DB
+}
+
+// NewFriendGraphRepository creates a new friend graph repository
+func NewFriendGraphRepository(db *sql.DB) *FriendGraphRepository {
+	return &FriendGraphRepository{db: db}
+}
+
+// CountFriends counts the user's friends
+func (r *FriendGraphRepository) CountFriends(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COUNT(*) FROM friendships WHERE user_id_1 = $1 OR user_id_2 = $1`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count friends: %w", err)
+	}
+	return count, nil
+}
+
+// ListFriendIDs returns the IDs of all the user's friends whose accounts are not deleted
+func (r *FriendGraphRepository) ListFriendIDs(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT f.friend_id
+		FROM (
+			SELECT CASE WHEN user_id_1 = $1 THEN user_id_2 ELSE user_id_1 END AS friend_id
+			FROM friendships
+			WHERE user_id_1 = $1 OR user_id_2 = $1
+		) f
+		JOIN users u ON u.id = f.friend_id AND u.is_deleted = false
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friends: %w", err)
+	}
+	defer rows.Close()
+
+	friendIDs := []string{}
+	for rows.Next() {
+		var friendID string
+		if err := rows.Scan(&friendID); err != nil {
+			return nil, fmt.Errorf("failed to scan friend: %w", err)
+		}
+		friendIDs = append(friendIDs, friendID)
+	}
+	return friendIDs, rows.Err()
+}
+
+// FilterFriends returns those of otherIDs who are friends with the user
+func (r *FriendGraphRepository) FilterFriends(ctx context.Context, userID string, otherIDs []string) ([]string, error) {
+	friendIDs := []string{}
+	if len(otherIDs) == 0 {
+		return friendIDs, nil
+	}
+
+	query := `
+		SELECT CASE WHEN user_id_1 = $1 THEN user_id_2 ELSE user_id_1 END
+		FROM friendships
+		WHERE (user_id_1 = $1 AND user_id_2 = ANY($2::uuid[]))
+		   OR (user_id_2 = $1 AND user_id_1 = ANY($2::uuid[]))
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(otherIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter friends: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var friendID string
+		if err := rows.Scan(&friendID); err != nil {
+			return nil, fmt.Errorf("failed to scan friend: %w", err)
+		}
+		friendIDs = append(friendIDs, friendID)
+	}
+	return friendIDs, rows.Err()
+}
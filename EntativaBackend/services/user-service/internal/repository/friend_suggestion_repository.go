@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Kinds of affiliation other services report for friend suggestions
+const (
+	AffiliationCommunity = "community"
+	AffiliationEvent     = "event"
+)
+
+// FriendSuggestion is someone a user may know, with what they have in common
+type FriendSuggestion struct {
+	SuggestedUserID   string
+	Score             int
+	MutualFriends     int
+	SharedWorkplaces  int
+	SharedSchools     int
+	SharedCommunities int
+	SharedEvents      int
+
+	// Filled in when suggestions are listed
+	Username          string
+	FirstName         string
+	LastName          string
+	ProfilePictureURL *string
+}
+
+// FriendSuggestionRepository handles database operations for friend suggestions
+type FriendSuggestionRepository struct {
+	db *sql.DB
+}
+
+// NewFriendSuggestionRepository creates a new friend suggestion repository
+func NewFriendSuggestionRepository(db *sql.DB) *FriendSuggestionRepository {
+	return &FriendSuggestionRepository{db: db}
+}
+
+// FindCandidates collects everyone who shares friends, a workplace, a school, a
+// community or an event with userID, counting each. Each signal contributes at
+// most perSignal candidates, strongest first. Friends, pending requests either
+// way, blocks either way and dismissed suggestions are left out.
+func (r *FriendSuggestionRepository) FindCandidates(ctx context.Context, userID string, perSignal int) ([]*FriendSuggestion, error) {
+	query := `
+		WITH friends AS (
+			SELECT CASE WHEN user_id_1 = $1 THEN user_id_2 ELSE user_id_1 END AS friend_id
+			FROM friendships
+			WHERE user_id_1 = $1 OR user_id_2 = $1
+		),
+		excluded AS (
+			SELECT $1::uuid AS user_id
+			UNION SELECT friend_id FROM friends
+			UNION SELECT blocked_user_id FROM blocked_users WHERE user_id = $1
+			UNION SELECT user_id FROM blocked_users WHERE blocked_user_id = $1
+			UNION SELECT receiver_id FROM friend_requests WHERE sender_id = $1 AND status = 'pending'
+			UNION SELECT sender_id FROM friend_requests WHERE receiver_id = $1 AND status = 'pending'
+			UNION SELECT dismissed_user_id FROM friend_suggestion_dismissals WHERE user_id = $1
+		),
+		mutual AS (
+			SELECT candidate_id, COUNT(*) AS n
+			FROM (
+				SELECT CASE WHEN f.user_id_1 = fr.friend_id THEN f.user_id_2 ELSE f.user_id_1 END AS candidate_id
+				FROM friendships f
+				JOIN friends fr ON fr.friend_id IN (f.user_id_1, f.user_id_2)
+			) friends_of_friends
+			WHERE candidate_id NOT IN (SELECT user_id FROM excluded)
+			GROUP BY candidate_id
+			ORDER BY n DESC
+			LIMIT $2
+		),
+		workplaces AS (
+			SELECT p.user_id AS candidate_id, COUNT(*) AS n
+			FROM (
+				SELECT DISTINCT w->>'company' AS company
+				FROM profiles, jsonb_array_elements(work) w
+				WHERE user_id = $1 AND COALESCE(w->>'company', '') <> ''
+			) mine
+			JOIN profiles p ON p.work @> jsonb_build_array(jsonb_build_object('company', mine.company))
+			WHERE p.user_id NOT IN (SELECT user_id FROM excluded)
+			GROUP BY p.user_id
+			ORDER BY n DESC
+			LIMIT $2
+		),
+		schools AS (
+			SELECT p.user_id AS candidate_id, COUNT(*) AS n
+			FROM (
+				SELECT DISTINCT e->>'school' AS school
+				FROM profiles, jsonb_array_elements(education) e
+				WHERE user_id = $1 AND COALESCE(e->>'school', '') <> ''
+			) mine
+			JOIN profiles p ON p.education @> jsonb_build_array(jsonb_build_object('school', mine.school))
+			WHERE p.user_id NOT IN (SELECT user_id FROM excluded)
+			GROUP BY p.user_id
+			ORDER BY n DESC
+			LIMIT $2
+		),
+		communities AS (
+			SELECT theirs.user_id AS candidate_id, COUNT(*) AS n
+			FROM user_affiliations mine
+			JOIN user_affiliations theirs ON theirs.kind = mine.kind AND theirs.affiliation_id = mine.affiliation_id
+			WHERE mine.user_id = $1 AND mine.kind = $3
+			  AND theirs.user_id NOT IN (SELECT user_id FROM excluded)
+			GROUP BY theirs.user_id
+			ORDER BY n DESC
+			LIMIT $2
+		),
+		events AS (
+			SELECT theirs.user_id AS candidate_id, COUNT(*) AS n
+			FROM user_affiliations mine
+			JOIN user_affiliations theirs ON theirs.kind = mine.kind AND theirs.affiliation_id = mine.affiliation_id
+			WHERE mine.user_id = $1 AND mine.kind = $4
+			  AND theirs.user_id NOT IN (SELECT user_id FROM excluded)
+			GROUP BY theirs.user_id
+			ORDER BY n DESC
+			LIMIT $2
+		),
+		candidates AS (
+			SELECT candidate_id FROM mutual
+			UNION SELECT candidate_id FROM workplaces
+			UNION SELECT candidate_id FROM schools
+			UNION SELECT candidate_id FROM communities
+			UNION SELECT candidate_id FROM events
+		)
+		SELECT c.candidate_id,
+		       COALESCE(m.n, 0), COALESCE(w.n, 0), COALESCE(s.n, 0), COALESCE(cm.n, 0), COALESCE(e.n, 0)
+		FROM candidates c
+		JOIN users u ON u.id = c.candidate_id AND u.is_active = true AND u.is_deleted = false
+		LEFT JOIN mutual m USING (candidate_id)
+		LEFT JOIN workplaces w USING (candidate_id)
+		LEFT JOIN schools s USING (candidate_id)
+		LEFT JOIN communities cm USING (candidate_id)
+		LEFT JOIN events e USING (candidate_id)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, perSignal, AffiliationCommunity, AffiliationEvent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suggestion candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*FriendSuggestion
+	for rows.Next() {
+		candidate := &FriendSuggestion{}
+		if err := rows.Scan(
+			&candidate.SuggestedUserID, &candidate.MutualFriends, &candidate.SharedWorkplaces,
+			&candidate.SharedSchools, &candidate.SharedCommunities, &candidate.SharedEvents,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion candidate: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+// ReplaceSuggestions swaps a user's stored suggestions for a freshly computed set and
+// records when they were computed
+func (r *FriendSuggestionRepository) ReplaceSuggestions(ctx context.Context, userID string, suggestions []*FriendSuggestion) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM friend_suggestions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear friend suggestions: %w", err)
+	}
+
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO friend_suggestions (
+			user_id, suggested_user_id, score, mutual_friends, shared_workplaces,
+			shared_schools, shared_communities, shared_events
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare friend suggestion insert: %w", err)
+	}
+	defer insert.Close()
+
+	for _, s := range suggestions {
+		if _, err := insert.ExecContext(ctx,
+			userID, s.SuggestedUserID, s.Score, s.MutualFriends, s.SharedWorkplaces,
+			s.SharedSchools, s.SharedCommunities, s.SharedEvents,
+		); err != nil {
+			return fmt.Errorf("failed to store friend suggestion: %w", err)
+		}
+	}
+
+	// A run marked stale while this one was computing stays stale, so it is redone
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO friend_suggestion_runs (user_id, stale, computed_at)
+		VALUES ($1, false, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET computed_at = NOW(), claimed_until = NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record friend suggestion run: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// HasComputed reports whether a user's suggestions have ever been computed
+func (r *FriendSuggestionRepository) HasComputed(ctx context.Context, userID string) (bool, error) {
+	var computed bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM friend_suggestion_runs WHERE user_id = $1 AND computed_at IS NOT NULL)
+	`, userID).Scan(&computed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check friend suggestion run: %w", err)
+	}
+	return computed, nil
+}
+
+// ListSuggestions returns a page of a user's suggestions, best first. People who
+// have become friends, sent or received a request, or blocked either way since the
+// suggestions were computed are skipped.
+func (r *FriendSuggestionRepository) ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*FriendSuggestion, error) {
+	query := `
+		SELECT s.suggested_user_id, s.score, s.mutual_friends, s.shared_workplaces,
+		       s.shared_schools, s.shared_communities, s.shared_events,
+		       u.username, u.first_name, u.last_name, u.profile_picture_url
+		FROM friend_suggestions s
+		JOIN users u ON u.id = s.suggested_user_id AND u.is_active = true AND u.is_deleted = false
+		WHERE s.user_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM friendships f
+			WHERE f.user_id_1 = LEAST(s.user_id, s.suggested_user_id)
+			  AND f.user_id_2 = GREATEST(s.user_id, s.suggested_user_id)
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM friend_requests fr
+			WHERE fr.status = 'pending'
+			  AND ((fr.sender_id = s.user_id AND fr.receiver_id = s.suggested_user_id)
+			    OR (fr.sender_id = s.suggested_user_id AND fr.receiver_id = s.user_id))
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM blocked_users b
+			WHERE (b.user_id = s.user_id AND b.blocked_user_id = s.suggested_user_id)
+			   OR (b.user_id = s.suggested_user_id AND b.blocked_user_id = s.user_id)
+		  )
+		ORDER BY s.score DESC, s.suggested_user_id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friend suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []*FriendSuggestion{}
+	for rows.Next() {
+		s := &FriendSuggestion{}
+		if err := rows.Scan(
+			&s.SuggestedUserID, &s.Score, &s.MutualFriends, &s.SharedWorkplaces,
+			&s.SharedSchools, &s.SharedCommunities, &s.SharedEvents,
+			&s.Username, &s.FirstName, &s.LastName, &s.ProfilePictureURL,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan friend suggestion: %w", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// DismissSuggestion stops dismissedUserID from being suggested to userID again
+func (r *FriendSuggestionRepository) DismissSuggestion(ctx context.Context, userID, dismissedUserID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO friend_suggestion_dismissals (user_id, dismissed_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, dismissedUserID)
+	if err != nil {
+		return fmt.Errorf("failed to dismiss friend suggestion: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM friend_suggestions WHERE user_id = $1 AND suggested_user_id = $2
+	`, userID, dismissedUserID)
+	if err != nil {
+		return fmt.Errorf("failed to remove friend suggestion: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// MarkStale queues a user's suggestions to be recomputed
+func (r *FriendSuggestionRepository) MarkStale(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO friend_suggestion_runs (user_id, stale) VALUES ($1, true)
+		ON CONFLICT (user_id) DO UPDATE SET stale = true
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark friend suggestions stale: %w", err)
+	}
+	return nil
+}
+
+// MarkFriendshipStale queues recomputing the suggestions of two users whose
+// friendship has started or ended, and of their friends, whose mutual friend
+// counts it changes
+func (r *FriendSuggestionRepository) MarkFriendshipStale(ctx context.Context, userID, otherUserID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO friend_suggestion_runs (user_id, stale)
+		SELECT user_id, true FROM (
+			SELECT $1::uuid AS user_id
+			UNION SELECT $2::uuid
+			UNION SELECT CASE WHEN user_id_1 IN ($1::uuid, $2::uuid) THEN user_id_2 ELSE user_id_1 END
+			FROM friendships
+			WHERE user_id_1 IN ($1::uuid, $2::uuid) OR user_id_2 IN ($1::uuid, $2::uuid)
+		) affected
+		ON CONFLICT (user_id) DO UPDATE SET stale = true
+	`, userID, otherUserID)
+	if err != nil {
+		return fmt.Errorf("failed to mark friend suggestions stale: %w", err)
+	}
+	return nil
+}
+
+// EnqueueNewUsers starts tracking up to limit active users whose suggestions have
+// never been computed, so the worker gets to everyone in time
+func (r *FriendSuggestionRepository) EnqueueNewUsers(ctx context.Context, limit int) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO friend_suggestion_runs (user_id, stale)
+		SELECT u.id, true FROM users u
+		WHERE u.is_active = true AND u.is_deleted = false
+		  AND NOT EXISTS (SELECT 1 FROM friend_suggestion_runs r WHERE r.user_id = u.id)
+		LIMIT $1
+		ON CONFLICT (user_id) DO NOTHING
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue users for friend suggestions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClaimDueUsers leases up to limit users whose suggestions are stale or older than
+// maxAge, stale ones first, and clears their stale mark. A change after the claim
+// marks them stale again.
+func (r *FriendSuggestionRepository) ClaimDueUsers(ctx context.Context, limit int, maxAge, lease time.Duration) ([]string, error) {
+	query := `
+		UPDATE friend_suggestion_runs SET
+			stale = false,
+			claimed_until = NOW() + $3 * INTERVAL '1 second'
+		WHERE user_id IN (
+			SELECT user_id FROM friend_suggestion_runs
+			WHERE (stale OR computed_at IS NULL OR computed_at < NOW() - $2 * INTERVAL '1 second')
+			  AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY stale DESC, computed_at NULLS FIRST
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, int64(maxAge.Seconds()), int64(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim friend suggestion runs: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan friend suggestion run: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// AddAffiliation records that a user belongs to a community or is going to an event
+func (r *FriendSuggestionRepository) AddAffiliation(ctx context.Context, userID, kind, affiliationID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_affiliations (user_id, kind, affiliation_id) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, userID, kind, affiliationID)
+	if err != nil {
+		return fmt.Errorf("failed to add affiliation: %w", err)
+	}
+	return nil
+}
+
+// RemoveAffiliation records that a user has left a community or is no longer going to an event
+func (r *FriendSuggestionRepository) RemoveAffiliation(ctx context.Context, userID, kind, affiliationID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM user_affiliations WHERE user_id = $1 AND kind = $2 AND affiliation_id = $3
+	`, userID, kind, affiliationID)
+	if err != nil {
+		return fmt.Errorf("failed to remove affiliation: %w", err)
+	}
+	return nil
+}
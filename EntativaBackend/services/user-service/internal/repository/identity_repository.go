@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// How a cross-platform identity came to be linked
+const (
+	LinkedViaSignIn = "sign_in"
+	LinkedViaManual = "manual"
+	LinkedViaMerge  = "merge"
+)
+
+// LinkedIdentity is an account on the other platform linked to a user
+type LinkedIdentity struct {
+	UserID           string
+	Platform         string
+	PlatformUserID   string
+	PlatformUsername string
+	PlatformEmail    string
+	LinkedVia        string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// AccountMerge records one account being merged into another
+type AccountMerge struct {
+	ID           string
+	SourceUserID string
+	TargetUserID string
+	Moved        map[string]int64
+	IPAddress    string
+	CreatedAt    time.Time
+}
+
+// IdentityRepository handles database operations for linked identities and account merges
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+const linkedIdentityColumns = `
+	user_id, platform, platform_user_id, COALESCE(platform_username, ''),
+	COALESCE(platform_email, ''), linked_via, created_at, updated_at
+`
+
+// ListIdentities lists the identities linked to a user
+func (r *IdentityRepository) ListIdentities(ctx context.Context, userID string) ([]*LinkedIdentity, error) {
+	query := `SELECT ` + linkedIdentityColumns + ` FROM cross_platform_links WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list linked identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*LinkedIdentity
+	for rows.Next() {
+		identity, err := scanLinkedIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan linked identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// FindIdentity finds the link for an identity on the other platform, whichever user it belongs to
+func (r *IdentityRepository) FindIdentity(ctx context.Context, platform, platformUserID string) (*LinkedIdentity, error) {
+	query := `SELECT ` + linkedIdentityColumns + ` FROM cross_platform_links WHERE platform = $1 AND platform_user_id = $2`
+
+	identity, err := scanLinkedIdentity(r.db.QueryRowContext(ctx, query, platform, platformUserID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Linked identity not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find linked identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// LinkIdentity links an identity to a user. The caller checks for conflicts first;
+// the unique indexes reject a platform linked twice or an identity linked to two users.
+func (r *IdentityRepository) LinkIdentity(ctx context.Context, identity *LinkedIdentity) error {
+	query := `
+		INSERT INTO cross_platform_links (
+			user_id, platform, platform_user_id, platform_username,
+			platform_email, linked_via, created_at, updated_at
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $7)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		identity.UserID, identity.Platform, identity.PlatformUserID, identity.PlatformUsername,
+		identity.PlatformEmail, identity.LinkedVia, identity.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return nil
+}
+
+// UnlinkIdentity removes a user's link to a platform.
+// Returns NotFoundError if the user has no identity linked on it.
+func (r *IdentityRepository) UnlinkIdentity(ctx context.Context, userID, platform string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM cross_platform_links WHERE user_id = $1 AND platform = $2`, userID, platform)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"Linked identity not found"}
+	}
+
+	return nil
+}
+
+// MergeAccounts moves the source account's data to the target, deletes the source
+// and records the merge, all in one transaction. Data the target already has wins:
+// profile fields are only filled where the target's are empty, and links, follows
+// and friendships the target already has are dropped from the source.
+func (r *IdentityRepository) MergeAccounts(ctx context.Context, merge *AccountMerge) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	source, target := merge.SourceUserID, merge.TargetUserID
+	merge.Moved = make(map[string]int64)
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		// Fill empty profile fields on the target
+		{"profile_fields", `
+			UPDATE users t SET
+				bio = COALESCE(t.bio, s.bio),
+				profile_picture_url = COALESCE(t.profile_picture_url, s.profile_picture_url),
+				cover_photo_url = COALESCE(t.cover_photo_url, s.cover_photo_url),
+				phone_number = COALESCE(t.phone_number, s.phone_number),
+				birthday = COALESCE(t.birthday, s.birthday),
+				gender = COALESCE(t.gender, s.gender),
+				is_verified = t.is_verified OR s.is_verified,
+				updated_at = NOW()
+			FROM users s
+			WHERE t.id = $2 AND s.id = $1`},
+		{"profile", `
+			UPDATE profiles SET user_id = $2, updated_at = NOW()
+			WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM profiles WHERE user_id = $2)`},
+		{"links", `
+			UPDATE cross_platform_links SET user_id = $2, linked_via = 'merge'
+			WHERE user_id = $1 AND platform NOT IN (SELECT platform FROM cross_platform_links WHERE user_id = $2)`},
+		{"followers", `
+			UPDATE follows SET following_id = $2
+			WHERE following_id = $1 AND follower_id <> $2
+			  AND follower_id NOT IN (SELECT follower_id FROM follows WHERE following_id = $2)`},
+		{"following", `
+			UPDATE follows SET follower_id = $2
+			WHERE follower_id = $1 AND following_id <> $2
+			  AND following_id NOT IN (SELECT following_id FROM follows WHERE follower_id = $2)`},
+		// friendships keep user_id_1 < user_id_2, so they are re-inserted rather than updated
+		{"friendships", `
+			INSERT INTO friendships (user_id_1, user_id_2, relationship_type, show_in_friends_list, created_at, updated_at)
+			SELECT LEAST(other_id, $2::uuid), GREATEST(other_id, $2::uuid), relationship_type, show_in_friends_list, created_at, NOW()
+			FROM (
+				SELECT CASE WHEN user_id_1 = $1 THEN user_id_2 ELSE user_id_1 END AS other_id,
+				       relationship_type, show_in_friends_list, created_at
+				FROM friendships WHERE user_id_1 = $1 OR user_id_2 = $1
+			) f
+			WHERE other_id <> $2
+			ON CONFLICT (user_id_1, user_id_2) DO NOTHING`},
+		{"takes", `UPDATE takes SET user_id = $2, updated_at = NOW() WHERE user_id = $1`},
+	}
+
+	for _, step := range steps {
+		result, err := tx.ExecContext(ctx, step.query, source, target)
+		if err != nil {
+			return fmt.Errorf("failed to merge %s: %w", step.name, err)
+		}
+		moved, _ := result.RowsAffected()
+		merge.Moved[step.name] = moved
+	}
+
+	// Whatever was not moved is a duplicate of something the target has
+	cleanup := []string{
+		`DELETE FROM cross_platform_links WHERE user_id = $1`,
+		`DELETE FROM follows WHERE follower_id = $1 OR following_id = $1`,
+		`DELETE FROM friendships WHERE user_id_1 = $1 OR user_id_2 = $1`,
+		`UPDATE users SET is_deleted = true, is_active = false, updated_at = NOW() WHERE id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+	}
+	for _, query := range cleanup {
+		if _, err := tx.ExecContext(ctx, query, source); err != nil {
+			return fmt.Errorf("failed to clean up merged account: %w", err)
+		}
+	}
+
+	movedJSON, _ := json.Marshal(merge.Moved)
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO account_merges (id, source_user_id, target_user_id, moved, ip_address, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		merge.ID, source, target, movedJSON, merge.IPAddress, merge.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record account merge: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account merge: %w", err)
+	}
+
+	return nil
+}
+
+// ListMerges lists the accounts merged into a user, newest first
+func (r *IdentityRepository) ListMerges(ctx context.Context, targetUserID string) ([]*AccountMerge, error) {
+	query := `
+		SELECT id, source_user_id, target_user_id, moved, COALESCE(ip_address, ''), created_at
+		FROM account_merges
+		WHERE target_user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []*AccountMerge
+	for rows.Next() {
+		merge := &AccountMerge{}
+		var movedJSON []byte
+		if err := rows.Scan(&merge.ID, &merge.SourceUserID, &merge.TargetUserID, &movedJSON, &merge.IPAddress, &merge.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account merge: %w", err)
+		}
+		json.Unmarshal(movedJSON, &merge.Moved)
+		merges = append(merges, merge)
+	}
+
+	return merges, rows.Err()
+}
+
+func scanLinkedIdentity(row rowScanner) (*LinkedIdentity, error) {
+	identity := &LinkedIdentity{}
+
+	err := row.Scan(
+		&identity.UserID, &identity.Platform, &identity.PlatformUserID, &identity.PlatformUsername,
+		&identity.PlatformEmail, &identity.LinkedVia, &identity.CreatedAt, &identity.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LoginAttempt is one password login attempt, kept for lockout and anomaly checks
+type LoginAttempt struct {
+	ID         string
+	AccountKey string  // user ID, or "unknown:<identifier>" when no account matched
+	UserID     *string
+	IPAddress  string
+	DeviceKey  string
+	Success    bool
+	Reason     string
+	CreatedAt  time.Time
+}
+
+// LoginFailureStats summarises recent failed attempts for an account or IP
+type LoginFailureStats struct {
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// ProofOfWorkChallenge is a puzzle a client must solve before a risky login attempt is accepted.
+// A solution is any string for which SHA-256(challenge + ":" + solution) starts with
+// Difficulty zero bits.
+type ProofOfWorkChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	IPAddress  string    `json:"-"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"-"`
+}
+
+// LoginAttemptRepository handles database operations for login attempts
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// CreateAttempt records a login attempt
+func (r *LoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (
+			id, account_key, user_id, ip_address, device_key, success, reason, created_at
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		attempt.ID, attempt.AccountKey, attempt.UserID, attempt.IPAddress,
+		attempt.DeviceKey, attempt.Success, attempt.Reason, attempt.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// AccountFailureStats counts an account's failures since a point in time,
+// ignoring failures from before its last successful login
+func (r *LoginAttemptRepository) AccountFailureStats(ctx context.Context, accountKey string, since time.Time) (*LoginFailureStats, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(MAX(created_at), $2)
+		FROM login_attempts
+		WHERE account_key = $1 AND NOT success AND created_at > GREATEST($2, COALESCE((
+			SELECT MAX(created_at) FROM login_attempts WHERE account_key = $1 AND success
+		), $2))
+	`
+
+	return r.failureStats(ctx, query, accountKey, since)
+}
+
+// IPFailureStats counts failures from an IP since a point in time, across all accounts
+func (r *LoginAttemptRepository) IPFailureStats(ctx context.Context, ipAddress string, since time.Time) (*LoginFailureStats, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(MAX(created_at), $2)
+		FROM login_attempts
+		WHERE ip_address = $1 AND NOT success AND created_at > $2
+	`
+
+	return r.failureStats(ctx, query, ipAddress, since)
+}
+
+func (r *LoginAttemptRepository) failureStats(ctx context.Context, query, key string, since time.Time) (*LoginFailureStats, error) {
+	stats := &LoginFailureStats{}
+	if err := r.db.QueryRowContext(ctx, query, key, since).Scan(&stats.Failures, &stats.LastFailureAt); err != nil {
+		return nil, fmt.Errorf("failed to count login failures: %w", err)
+	}
+	return stats, nil
+}
+
+// CountAccountsFailedFromIP counts the distinct accounts that failed to log in from an IP since a point in time
+func (r *LoginAttemptRepository) CountAccountsFailedFromIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT account_key)
+		FROM login_attempts
+		WHERE ip_address = $1 AND NOT success AND created_at > $2
+	`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, ipAddress, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count accounts: %w", err)
+	}
+
+	return count, nil
+}
+
+// HasSuccessfulLogin reports whether a user has logged in successfully before
+func (r *LoginAttemptRepository) HasSuccessfulLogin(ctx context.Context, userID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM login_attempts WHERE user_id = $1 AND success)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check login history: %w", err)
+	}
+
+	return exists, nil
+}
+
+// HasSuccessfulLoginFromDevice reports whether a user has logged in successfully from a device before
+func (r *LoginAttemptRepository) HasSuccessfulLoginFromDevice(ctx context.Context, userID, deviceKey string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM login_attempts WHERE user_id = $1 AND device_key = $2 AND success)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, userID, deviceKey).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check login history: %w", err)
+	}
+
+	return exists, nil
+}
+
+// CreatePowChallenge stores a proof-of-work challenge handed to a client
+func (r *LoginAttemptRepository) CreatePowChallenge(ctx context.Context, challenge *ProofOfWorkChallenge) error {
+	query := `
+		INSERT INTO login_pow_challenges (challenge, ip_address, difficulty, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		challenge.Challenge, challenge.IPAddress, challenge.Difficulty, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create proof-of-work challenge: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumePowChallenge deletes an unexpired challenge issued to an IP and returns it,
+// so each challenge can be used for one login attempt only.
+// Returns NotFoundError if the challenge is unknown, expired or was issued elsewhere.
+func (r *LoginAttemptRepository) ConsumePowChallenge(ctx context.Context, challenge, ipAddress string) (*ProofOfWorkChallenge, error) {
+	query := `
+		DELETE FROM login_pow_challenges
+		WHERE challenge = $1 AND ip_address = $2 AND expires_at > NOW()
+		RETURNING challenge, ip_address, difficulty, expires_at, created_at
+	`
+
+	pow := &ProofOfWorkChallenge{}
+	err := r.db.QueryRowContext(ctx, query, challenge, ipAddress).Scan(
+		&pow.Challenge, &pow.IPAddress, &pow.Difficulty, &pow.ExpiresAt, &pow.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Proof-of-work challenge not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume proof-of-work challenge: %w", err)
+	}
+
+	return pow, nil
+}
+
+// DeleteExpiredAttempts deletes failed attempts and successful logins older than the given
+// times, and expired challenges. Successful logins are kept longer as device history.
+func (r *LoginAttemptRepository) DeleteExpiredAttempts(ctx context.Context, failuresBefore, successesBefore time.Time) error {
+	query := `DELETE FROM login_attempts WHERE (NOT success AND created_at < $1) OR (success AND created_at < $2)`
+	if _, err := r.db.ExecContext(ctx, query, failuresBefore, successesBefore); err != nil {
+		return fmt.Errorf("failed to delete expired login attempts: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM login_pow_challenges WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired proof-of-work challenges: %w", err)
+	}
+
+	return nil
+}
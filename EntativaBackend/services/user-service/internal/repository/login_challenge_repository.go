@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LoginChallenge is a pending 2FA login: the password was correct, a second factor is still required
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	TokenHash string // SHA-256 of the challenge token
+	IPAddress string
+	UserAgent string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// LoginChallengeRepository handles database operations for pending 2FA logins
+type LoginChallengeRepository struct {
+	db *sql.DB
+}
+
+// NewLoginChallengeRepository creates a new login challenge repository
+func NewLoginChallengeRepository(db *sql.DB) *LoginChallengeRepository {
+	return &LoginChallengeRepository{db: db}
+}
+
+// CreateChallenge creates a new login challenge
+func (r *LoginChallengeRepository) CreateChallenge(ctx context.Context, challenge *LoginChallenge) error {
+	query := `
+		INSERT INTO login_challenges (
+			id, user_id, token_hash, ip_address, user_agent, attempts, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		challenge.ID, challenge.UserID, challenge.TokenHash, challenge.IPAddress, challenge.UserAgent,
+		challenge.Attempts, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+
+	return nil
+}
+
+// FindByTokenHash finds an unexpired challenge by the hash of its token
+func (r *LoginChallengeRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*LoginChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+		       attempts, expires_at, created_at
+		FROM login_challenges
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	challenge := &LoginChallenge{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.IPAddress, &challenge.UserAgent,
+		&challenge.Attempts, &challenge.ExpiresAt, &challenge.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Login challenge not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// IncrementAttempts records a failed code attempt and returns the new count
+func (r *LoginChallengeRepository) IncrementAttempts(ctx context.Context, id string) (int, error) {
+	query := `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to record challenge attempt: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// CountRecentFailedAttempts sums failed code attempts across a user's challenges since a point in time
+func (r *LoginChallengeRepository) CountRecentFailedAttempts(ctx context.Context, userID string, since time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(attempts), 0)
+		FROM login_challenges
+		WHERE user_id = $1 AND created_at > $2
+	`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, userID, since).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to count challenge attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// Consume deletes a challenge; false means another request already used it
+func (r *LoginChallengeRepository) Consume(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM login_challenges WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume login challenge: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume login challenge: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// DeleteExpiredChallenges deletes challenges that are past their expiry and outside the rate limit window
+func (r *LoginChallengeRepository) DeleteExpiredChallenges(ctx context.Context, olderThan time.Time) error {
+	query := `DELETE FROM login_challenges WHERE expires_at < NOW() AND created_at < $1`
+
+	if _, err := r.db.ExecContext(ctx, query, olderThan); err != nil {
+		return fmt.Errorf("failed to delete expired login challenges: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OAuthClient is an application registered to sign users in through OpenID Connect
+type OAuthClient struct {
+	ID            string
+	SecretHash    string // empty for public clients
+	Name          string
+	RedirectURIs  []string
+	AllowedScopes []string
+	OwnerUserID   string // empty for clients created by operators
+	IsFirstParty  bool
+	CreatedAt     time.Time
+}
+
+// IsConfidential reports whether the client authenticates with a secret at the token endpoint
+func (c *OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// OAuthConsent records the scopes a user approved for a client
+type OAuthConsent struct {
+	UserID     string
+	ClientID   string
+	ClientName string
+	Scopes     []string
+	GrantedAt  time.Time
+}
+
+// OAuthAuthorizationCode is a single-use code issued by the authorization endpoint.
+// Only the SHA-256 of the code is stored.
+type OAuthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
+
+// OAuthRepository handles database operations for OAuth clients, consents and codes
+type OAuthRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthRepository creates a new OAuth repository
+func NewOAuthRepository(db *sql.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+// CreateClient registers a new client
+func (r *OAuthRepository) CreateClient(ctx context.Context, client *OAuthClient) error {
+	query := `
+		INSERT INTO oauth_clients (
+			id, secret_hash, name, redirect_uris, allowed_scopes,
+			owner_user_id, is_first_party, created_at
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, '')::uuid, $7, $8)
+	`
+
+	redirectURIsJSON, _ := json.Marshal(client.RedirectURIs)
+	scopesJSON, _ := json.Marshal(client.AllowedScopes)
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		client.ID, client.SecretHash, client.Name, redirectURIsJSON, scopesJSON,
+		client.OwnerUserID, client.IsFirstParty, client.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+
+	return nil
+}
+
+const oauthClientColumns = `
+	id, COALESCE(secret_hash, ''), name, redirect_uris, allowed_scopes,
+	COALESCE(owner_user_id::text, ''), is_first_party, created_at
+`
+
+// FindClientByID finds a client by its client_id
+func (r *OAuthRepository) FindClientByID(ctx context.Context, clientID string) (*OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+
+	client, err := scanOAuthClient(r.db.QueryRowContext(ctx, query, clientID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"OAuth client not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find oauth client: %w", err)
+	}
+
+	return client, nil
+}
+
+// ListClientsByOwner lists the clients a user has registered
+func (r *OAuthRepository) ListClientsByOwner(ctx context.Context, ownerUserID string) ([]*OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE owner_user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+// DeleteClient deletes a client owned by a user, with its consents and codes.
+// Returns NotFoundError if the user owns no such client.
+func (r *OAuthRepository) DeleteClient(ctx context.Context, ownerUserID, clientID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE id = $1 AND owner_user_id = $2`, clientID, ownerUserID)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"OAuth client not found"}
+	}
+
+	return nil
+}
+
+// FindConsent finds the scopes a user has approved for a client
+func (r *OAuthRepository) FindConsent(ctx context.Context, userID, clientID string) (*OAuthConsent, error) {
+	query := `
+		SELECT c.user_id, c.client_id, oc.name, c.scopes, c.granted_at
+		FROM oauth_consents c
+		JOIN oauth_clients oc ON oc.id = c.client_id
+		WHERE c.user_id = $1 AND c.client_id = $2
+	`
+
+	consent, err := scanOAuthConsent(r.db.QueryRowContext(ctx, query, userID, clientID))
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"OAuth consent not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find oauth consent: %w", err)
+	}
+
+	return consent, nil
+}
+
+// SaveConsent records the scopes a user approved for a client, replacing any earlier consent
+func (r *OAuthRepository) SaveConsent(ctx context.Context, consent *OAuthConsent) error {
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes, granted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = EXCLUDED.granted_at
+	`
+
+	scopesJSON, _ := json.Marshal(consent.Scopes)
+
+	if _, err := r.db.ExecContext(ctx, query, consent.UserID, consent.ClientID, scopesJSON, consent.GrantedAt); err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+
+	return nil
+}
+
+// ListConsents lists the clients a user has approved
+func (r *OAuthRepository) ListConsents(ctx context.Context, userID string) ([]*OAuthConsent, error) {
+	query := `
+		SELECT c.user_id, c.client_id, oc.name, c.scopes, c.granted_at
+		FROM oauth_consents c
+		JOIN oauth_clients oc ON oc.id = c.client_id
+		WHERE c.user_id = $1
+		ORDER BY c.granted_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []*OAuthConsent
+	for rows.Next() {
+		consent, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+
+	return consents, rows.Err()
+}
+
+// RevokeConsent deletes a user's consent for a client and any codes not yet exchanged.
+// Returns NotFoundError if there was no consent.
+func (r *OAuthRepository) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_consents WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke oauth consent: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"OAuth consent not found"}
+	}
+
+	_, err = r.db.ExecContext(ctx, `DELETE FROM oauth_authorization_codes WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	return err
+}
+
+// CreateAuthorizationCode stores a newly issued authorization code
+func (r *OAuthRepository) CreateAuthorizationCode(ctx context.Context, code *OAuthAuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (
+			code_hash, client_id, user_id, redirect_uri, scopes,
+			nonce, code_challenge, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+	`
+
+	scopesJSON, _ := json.Marshal(code.Scopes)
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, scopesJSON,
+		code.Nonce, code.CodeChallenge, code.ExpiresAt, code.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode marks an unused, unexpired code as used and returns it.
+// Returns NotFoundError if the code is unknown, expired or already used.
+func (r *OAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*OAuthAuthorizationCode, error) {
+	query := `
+		UPDATE oauth_authorization_codes
+		SET used_at = NOW()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING code_hash, client_id, user_id, redirect_uri, scopes,
+		          COALESCE(nonce, ''), code_challenge, expires_at, created_at
+	`
+
+	code := &OAuthAuthorizationCode{}
+	var scopesJSON []byte
+	err := r.db.QueryRowContext(ctx, query, codeHash).Scan(
+		&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &scopesJSON,
+		&code.Nonce, &code.CodeChallenge, &code.ExpiresAt, &code.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Authorization code not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume authorization code: %w", err)
+	}
+
+	json.Unmarshal(scopesJSON, &code.Scopes)
+
+	return code, nil
+}
+
+// DeleteExpiredAuthorizationCodes deletes expired codes (cleanup job)
+func (r *OAuthRepository) DeleteExpiredAuthorizationCodes(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM oauth_authorization_codes WHERE expires_at < NOW()`)
+	return err
+}
+
+func scanOAuthClient(row rowScanner) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	var redirectURIsJSON, scopesJSON []byte
+
+	err := row.Scan(
+		&client.ID, &client.SecretHash, &client.Name, &redirectURIsJSON, &scopesJSON,
+		&client.OwnerUserID, &client.IsFirstParty, &client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(redirectURIsJSON, &client.RedirectURIs)
+	json.Unmarshal(scopesJSON, &client.AllowedScopes)
+
+	return client, nil
+}
+
+func scanOAuthConsent(row rowScanner) (*OAuthConsent, error) {
+	consent := &OAuthConsent{}
+	var scopesJSON []byte
+
+	err := row.Scan(&consent.UserID, &consent.ClientID, &consent.ClientName, &scopesJSON, &consent.GrantedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(scopesJSON, &consent.Scopes)
+
+	return consent, nil
+}
@@ -7,8 +7,8 @@ import (
 	"fmt"
 	"time"
 
-	"socialink/user-service/internal/model"
-	"socialink/user-service/pkg/cache"
+	"user-service/internal/model"
+	"user-service/pkg/cache"
 
 	"github.com/google/uuid"
 )
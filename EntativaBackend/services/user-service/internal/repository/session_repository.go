@@ -82,6 +82,33 @@ func (r *SessionRepository) FindSessionByToken(ctx context.Context, token string
 	return session, nil
 }
 
+// FindSessionByID finds an unexpired session by its ID
+func (r *SessionRepository) FindSessionByID(ctx context.Context, sessionID string) (*Session, error) {
+	query := `
+		SELECT id, user_id, access_token, refresh_token,
+		       device_info, ip_address, user_agent,
+		       expires_at, created_at, last_active_at
+		FROM sessions
+		WHERE id = $1 AND expires_at > NOW()
+	`
+	
+	session := &Session{}
+	err := r.db.QueryRowContext(ctx, query, sessionID).Scan(
+		&session.ID, &session.UserID, &session.AccessToken, &session.RefreshToken,
+		&session.DeviceInfo, &session.IPAddress, &session.UserAgent,
+		&session.ExpiresAt, &session.CreatedAt, &session.LastActiveAt,
+	)
+	
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Session not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	
+	return session, nil
+}
+
 // UpdateLastActive updates the last active timestamp
 func (r *SessionRepository) UpdateLastActive(ctx context.Context, sessionID string) error {
 	query := `
@@ -136,6 +163,11 @@ func (r *SessionRepository) DeleteExpiredSessions(ctx context.Context) error {
 		fmt.Printf("Deleted %d expired sessions\n", rowsAffected)
 	}
 	
+	// Rotated refresh tokens are only needed while they could still be replayed
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM rotated_refresh_tokens WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired rotated refresh tokens: %w", err)
+	}
+	
 	return nil
 }
 
@@ -172,3 +204,188 @@ func (r *SessionRepository) GetUserSessions(ctx context.Context, userID string)
 	
 	return sessions, rows.Err()
 }
+
+// FindSessionByRefreshToken finds an active session by the hash of its current refresh token
+func (r *SessionRepository) FindSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (*Session, error) {
+	query := `
+		SELECT id, user_id, access_token, refresh_token,
+		       device_info, ip_address, user_agent,
+		       expires_at, created_at, last_active_at
+		FROM sessions
+		WHERE refresh_token = $1 AND expires_at > NOW()
+	`
+	
+	session := &Session{}
+	err := r.db.QueryRowContext(ctx, query, refreshTokenHash).Scan(
+		&session.ID, &session.UserID, &session.AccessToken, &session.RefreshToken,
+		&session.DeviceInfo, &session.IPAddress, &session.UserAgent,
+		&session.ExpiresAt, &session.CreatedAt, &session.LastActiveAt,
+	)
+	
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Session not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	
+	return session, nil
+}
+
+// RotatedRefreshToken represents a refresh token that has already been exchanged
+type RotatedRefreshToken struct {
+	TokenHash string
+	SessionID string
+	UserID    string
+	RotatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// RotateRefreshToken replaces a session's refresh token and access token.
+// The old token hash is remembered so that a replay of it can be detected.
+// Returns NotFoundError if the session no longer holds oldHash (it was rotated concurrently).
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, session *Session, oldHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	result, err := tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET refresh_token = $1, access_token = $2, ip_address = $3,
+		    expires_at = $4, last_active_at = $5
+		WHERE id = $6 AND refresh_token = $7
+	`,
+		session.RefreshToken, session.AccessToken, session.IPAddress,
+		session.ExpiresAt, session.LastActiveAt,
+		session.ID, oldHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"Session not found"}
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rotated_refresh_tokens (token_hash, session_id, user_id, rotated_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (token_hash) DO NOTHING
+	`, oldHash, session.ID, session.UserID, time.Now(), session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record rotated refresh token: %w", err)
+	}
+	
+	return tx.Commit()
+}
+
+// FindRotatedRefreshToken finds a previously rotated refresh token by its hash
+func (r *SessionRepository) FindRotatedRefreshToken(ctx context.Context, refreshTokenHash string) (*RotatedRefreshToken, error) {
+	query := `
+		SELECT token_hash, session_id, user_id, rotated_at, expires_at
+		FROM rotated_refresh_tokens
+		WHERE token_hash = $1
+	`
+	
+	token := &RotatedRefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, refreshTokenHash).Scan(
+		&token.TokenHash, &token.SessionID, &token.UserID, &token.RotatedAt, &token.ExpiresAt,
+	)
+	
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Refresh token not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rotated refresh token: %w", err)
+	}
+	
+	return token, nil
+}
+
+// RevokeSessionFamily deletes a session together with every refresh token it has ever rotated through
+func (r *SessionRepository) RevokeSessionFamily(ctx context.Context, sessionID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rotated_refresh_tokens WHERE session_id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke rotated refresh tokens: %w", err)
+	}
+	
+	return tx.Commit()
+}
+
+// RevokeUserSession deletes one of a user's sessions and its rotated refresh tokens.
+// Returns NotFoundError if the session does not exist or belongs to someone else.
+func (r *SessionRepository) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	result, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"Session not found"}
+	}
+	
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rotated_refresh_tokens WHERE session_id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke rotated refresh tokens: %w", err)
+	}
+	
+	return tx.Commit()
+}
+
+// RevokeOtherUserSessions deletes every session of a user except keepSessionID
+// and returns the IDs of the sessions it deleted
+func (r *SessionRepository) RevokeOtherUserSessions(ctx context.Context, userID, keepSessionID string) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	rows, err := tx.QueryContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND id <> $2 RETURNING id`, userID, keepSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	
+	var revoked []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		revoked = append(revoked, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	
+	_, err = tx.ExecContext(ctx, `DELETE FROM rotated_refresh_tokens WHERE user_id = $1 AND session_id <> $2`, userID, keepSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke rotated refresh tokens: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	
+	return revoked, nil
+}
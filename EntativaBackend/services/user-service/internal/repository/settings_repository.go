@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -163,14 +164,6 @@ func (r *SettingsRepository) GetUserSettings(ctx context.Context, userID string)
 	return &settings, nil
 }
 
-// UsernameExists checks if username is taken by another user
-func (r *SettingsRepository) UsernameExists(ctx context.Context, username, excludeUserID string) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 AND id != $2)`
-	err := r.db.QueryRowContext(ctx, query, username, excludeUserID).Scan(&exists)
-	return exists, err
-}
-
 // UpdateAccountSettings updates account information
 func (r *SettingsRepository) UpdateAccountSettings(ctx context.Context, userID string, req interface{}) error {
 	// Type assertion to get the request data
@@ -186,17 +179,17 @@ func (r *SettingsRepository) UpdateAccountSettings(ctx context.Context, userID s
 	query := `
 		UPDATE users 
 		SET 
-			email = COALESCE(NULLIF($2, ''), email),
-			phone = COALESCE(NULLIF($3, ''), phone),
-			bio = COALESCE(NULLIF($4, ''), bio),
-			website = COALESCE(NULLIF($5, ''), website),
-			name = COALESCE(NULLIF($6, ''), name),
-			username = COALESCE(NULLIF($7, ''), username),
+			phone = COALESCE(NULLIF($2, ''), phone),
+			bio = COALESCE(NULLIF($3, ''), bio),
+			website = COALESCE(NULLIF($4, ''), website),
+			name = COALESCE(NULLIF($5, ''), name),
 			updated_at = NOW()
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, userID, reqMap.Email, reqMap.Phone, reqMap.Bio, reqMap.Website, reqMap.Name, reqMap.Username)
+	// Email is changed through the confirmation flow and username through
+	// UsernameRepository.ChangeUsername, never here
+	_, err := r.db.ExecContext(ctx, query, userID, reqMap.Phone, reqMap.Bio, reqMap.Website, reqMap.Name)
 	return err
 }
 
@@ -402,11 +395,37 @@ func (r *SettingsRepository) GetBlockedUsers(ctx context.Context, userID string)
 	return blockedUsers, nil
 }
 
-// BlockUser blocks a user
+// BlockUser blocks a user, ending any friendship, pending friend request or follow
+// between the two in the same transaction
 func (r *SettingsRepository) BlockUser(ctx context.Context, userID, targetUserID string) error {
-	query := `INSERT INTO blocked_users (user_id, blocked_user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-	_, err := r.db.ExecContext(ctx, query, userID, targetUserID)
-	return err
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"block", `INSERT INTO blocked_users (user_id, blocked_user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`},
+		{"friendship", `DELETE FROM friendships WHERE user_id_1 = LEAST($1::uuid, $2::uuid) AND user_id_2 = GREATEST($1::uuid, $2::uuid)`},
+		{"friend requests", `
+			DELETE FROM friend_requests
+			WHERE status = 'pending'
+			  AND ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))`},
+		{"follows", `
+			DELETE FROM follows
+			WHERE (follower_id = $1 AND following_id = $2) OR (follower_id = $2 AND following_id = $1)`},
+	}
+
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, userID, targetUserID); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", step.name, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // UnblockUser unblocks a user
@@ -416,6 +435,47 @@ func (r *SettingsRepository) UnblockUser(ctx context.Context, userID, targetUser
 	return err
 }
 
+// IsBlockedEitherWay reports whether either user has blocked the other
+func (r *SettingsRepository) IsBlockedEitherWay(ctx context.Context, userID, otherUserID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM blocked_users
+			WHERE (user_id = $1 AND blocked_user_id = $2)
+			   OR (user_id = $2 AND blocked_user_id = $1)
+		)
+	`
+
+	var blocked bool
+	err := r.db.QueryRowContext(ctx, query, userID, otherUserID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlockRelations returns the IDs of everyone the user has blocked or been blocked by
+func (r *SettingsRepository) GetBlockRelations(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT blocked_user_id FROM blocked_users WHERE user_id = $1
+		UNION
+		SELECT user_id FROM blocked_users WHERE blocked_user_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
 // VerifyPassword verifies the user's current password
 func (r *SettingsRepository) VerifyPassword(ctx context.Context, userID, password string) (bool, error) {
 	var hashedPassword string
@@ -441,21 +501,6 @@ func (r *SettingsRepository) ChangePassword(ctx context.Context, userID, newPass
 	return err
 }
 
-// DeleteAccount soft deletes a user account
-func (r *SettingsRepository) DeleteAccount(ctx context.Context, userID, reason string) error {
-	query := `
-		UPDATE users 
-		SET 
-			deleted_at = NOW(),
-			deletion_reason = $2,
-			updated_at = NOW()
-		WHERE id = $1
-	`
-
-	_, err := r.db.ExecContext(ctx, query, userID, reason)
-	return err
-}
-
 // GetLoginActivity retrieves recent login activity
 func (r *SettingsRepository) GetLoginActivity(ctx context.Context, userID string, limit int) ([]LoginActivity, error) {
 	query := `
@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
+
+	"user-service/internal/util"
 )
 
 // TokenRepository handles database operations for password reset tokens
@@ -16,22 +19,25 @@ func NewTokenRepository(db *sql.DB) *TokenRepository {
 	return &TokenRepository{db: db}
 }
 
-// PasswordResetToken represents a password reset token
+// PasswordResetToken represents a password reset token.
+// Only the selector and a hash of the verifier are stored; see util.GenerateSplitToken.
 type PasswordResetToken struct {
-	ID        string
-	UserID    string
-	Token     string
-	ExpiresAt time.Time
-	Used      bool
-	CreatedAt time.Time
+	ID           string
+	UserID       string
+	Selector     string
+	VerifierHash string
+	IPAddress    string
+	ExpiresAt    time.Time
+	Used         bool
+	CreatedAt    time.Time
 }
 
 // CreateResetToken creates a new password reset token
 func (r *TokenRepository) CreateResetToken(ctx context.Context, token *PasswordResetToken) error {
 	query := `
 		INSERT INTO password_reset_tokens 
-		(id, user_id, token, expires_at, used, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		(id, user_id, selector, verifier_hash, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 	
 	_, err := r.db.ExecContext(
@@ -39,29 +45,38 @@ func (r *TokenRepository) CreateResetToken(ctx context.Context, token *PasswordR
 		query,
 		token.ID,
 		token.UserID,
-		token.Token,
+		token.Selector,
+		token.VerifierHash,
+		token.IPAddress,
 		token.ExpiresAt,
-		token.Used,
 		token.CreatedAt,
 	)
 	
 	return err
 }
 
-// FindResetToken finds a reset token by token string
+// FindResetToken finds a reset token by the selector.verifier string the user was sent.
+// The row is found by selector and the verifier is compared in constant time.
 func (r *TokenRepository) FindResetToken(ctx context.Context, token string) (*PasswordResetToken, error) {
+	selector, verifier, err := util.ParseSplitToken(token)
+	if err != nil {
+		return nil, &NotFoundError{"Reset token not found"}
+	}
+	
 	query := `
-		SELECT id, user_id, token, expires_at, used, created_at
+		SELECT id, user_id, selector, verifier_hash, COALESCE(ip_address, ''),
+		       expires_at, used_at IS NOT NULL, created_at
 		FROM password_reset_tokens
-		WHERE token = $1
-		LIMIT 1
+		WHERE selector = $1
 	`
 	
 	var resetToken PasswordResetToken
-	err := r.db.QueryRowContext(ctx, query, token).Scan(
+	err = r.db.QueryRowContext(ctx, query, selector).Scan(
 		&resetToken.ID,
 		&resetToken.UserID,
-		&resetToken.Token,
+		&resetToken.Selector,
+		&resetToken.VerifierHash,
+		&resetToken.IPAddress,
 		&resetToken.ExpiresAt,
 		&resetToken.Used,
 		&resetToken.CreatedAt,
@@ -74,28 +89,98 @@ func (r *TokenRepository) FindResetToken(ctx context.Context, token string) (*Pa
 		return nil, err
 	}
 	
+	if !util.VerifierMatches(verifier, resetToken.VerifierHash) {
+		return nil, &NotFoundError{"Reset token not found"}
+	}
+	
 	return &resetToken, nil
 }
 
-// MarkTokenAsUsed marks a reset token as used
+// MarkTokenAsUsed marks a reset token as used.
+// Returns NotFoundError if it was already used, so a token cannot be redeemed twice.
 func (r *TokenRepository) MarkTokenAsUsed(ctx context.Context, tokenID string) error {
 	query := `
 		UPDATE password_reset_tokens
-		SET used = true
-		WHERE id = $1
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
 	`
 	
-	_, err := r.db.ExecContext(ctx, query, tokenID)
-	return err
+	result, err := r.db.ExecContext(ctx, query, tokenID)
+	if err != nil {
+		return err
+	}
+	
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"Reset token not found"}
+	}
+	
+	return nil
 }
 
-// DeleteExpiredTokens deletes all expired reset tokens (cleanup job)
+// InvalidateUserResetTokens marks every outstanding reset token of a user as used
+func (r *TokenRepository) InvalidateUserResetTokens(ctx context.Context, userID string) error {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+	
+	return nil
+}
+
+// RecordResetAttempt records a password reset request for throttling
+func (r *TokenRepository) RecordResetAttempt(ctx context.Context, email, ipAddress string) error {
+	query := `
+		INSERT INTO password_reset_attempts (id, email, ip_address, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	
+	if _, err := r.db.ExecContext(ctx, query, util.GenerateUUID(), email, ipAddress, time.Now()); err != nil {
+		return fmt.Errorf("failed to record reset attempt: %w", err)
+	}
+	
+	return nil
+}
+
+// CountResetAttempts counts password reset requests for an email and from an IP address since a point in time
+func (r *TokenRepository) CountResetAttempts(ctx context.Context, email, ipAddress string, since time.Time) (byEmail, byIP int, err error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE email = $1),
+			COUNT(*) FILTER (WHERE ip_address = $2)
+		FROM password_reset_attempts
+		WHERE (email = $1 OR ip_address = $2) AND created_at > $3
+	`
+	
+	err = r.db.QueryRowContext(ctx, query, email, ipAddress, since).Scan(&byEmail, &byIP)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count reset attempts: %w", err)
+	}
+	
+	return byEmail, byIP, nil
+}
+
+// DeleteExpiredTokens deletes all expired reset tokens and old reset attempts (cleanup job)
 func (r *TokenRepository) DeleteExpiredTokens(ctx context.Context) error {
 	query := `
 		DELETE FROM password_reset_tokens
 		WHERE expires_at < NOW()
 	`
 	
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	
+	query = `
+		DELETE FROM password_reset_attempts
+		WHERE created_at < NOW() - INTERVAL '1 day'
+	`
+	
 	_, err := r.db.ExecContext(ctx, query)
 	return err
 }
@@ -103,7 +188,8 @@ func (r *TokenRepository) DeleteExpiredTokens(ctx context.Context) error {
 // GetUserResetTokens gets all reset tokens for a user
 func (r *TokenRepository) GetUserResetTokens(ctx context.Context, userID string) ([]*PasswordResetToken, error) {
 	query := `
-		SELECT id, user_id, token, expires_at, used, created_at
+		SELECT id, user_id, selector, verifier_hash, COALESCE(ip_address, ''),
+		       expires_at, used_at IS NOT NULL, created_at
 		FROM password_reset_tokens
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -121,7 +207,9 @@ func (r *TokenRepository) GetUserResetTokens(ctx context.Context, userID string)
 		if err := rows.Scan(
 			&token.ID,
 			&token.UserID,
-			&token.Token,
+			&token.Selector,
+			&token.VerifierHash,
+			&token.IPAddress,
 			&token.ExpiresAt,
 			&token.Used,
 			&token.CreatedAt,
@@ -1,118 +1,169 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
-
-	"socialink/user-service/internal/model"
-
-	"github.com/google/uuid"
 )
 
+// ErrTwoFactorEnabled is returned when changing a 2FA configuration that is already in use
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+// TwoFactorAuth is a user's TOTP configuration
+type TwoFactorAuth struct {
+	ID               string
+	UserID           string
+	Secret           string   // TOTP secret, never exposed after setup
+	IsEnabled        bool
+	BackupCodeHashes []string // SHA-256 of unused backup codes
+	CreatedAt        time.Time
+	EnabledAt        *time.Time
+	LastUsedAt       *time.Time
+}
+
+// TwoFactorRepository handles database operations for 2FA configuration
 type TwoFactorRepository struct {
 	db *sql.DB
 }
 
+// NewTwoFactorRepository creates a new two-factor repository
 func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
 	return &TwoFactorRepository{db: db}
 }
 
-// Create creates a new 2FA configuration
-func (r *TwoFactorRepository) Create(twoFactor *model.TwoFactorAuth) error {
-	backupCodesJSON, _ := json.Marshal(twoFactor.BackupCodes)
-	
+// UpsertPending stores a new secret and backup codes for a user.
+// Re-running setup replaces a configuration that was never enabled, but never
+// one that is in use; disable 2FA first to start over.
+func (r *TwoFactorRepository) UpsertPending(ctx context.Context, twoFactor *TwoFactorAuth) error {
+	backupCodesJSON, err := json.Marshal(twoFactor.BackupCodeHashes)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup codes: %w", err)
+	}
+
 	query := `
 		INSERT INTO two_factor_auth (
-			id, user_id, secret, is_enabled, backup_codes, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
+			id, user_id, secret, is_enabled, backup_code_hashes, created_at
+		) VALUES ($1, $2, $3, FALSE, $4, $5)
 		ON CONFLICT (user_id) DO UPDATE SET
 			secret = EXCLUDED.secret,
-			backup_codes = EXCLUDED.backup_codes,
+			backup_code_hashes = EXCLUDED.backup_code_hashes,
 			created_at = EXCLUDED.created_at
+		WHERE two_factor_auth.is_enabled = FALSE
 	`
 
-	_, err := r.db.Exec(
-		query,
-		twoFactor.ID,
-		twoFactor.UserID,
-		twoFactor.Secret,
-		twoFactor.IsEnabled,
-		backupCodesJSON,
-		twoFactor.CreatedAt,
+	result, err := r.db.ExecContext(
+		ctx, query,
+		twoFactor.ID, twoFactor.UserID, twoFactor.Secret, backupCodesJSON, twoFactor.CreatedAt,
 	)
-
 	if err != nil {
-		return fmt.Errorf("failed to create 2FA config: %w", err)
+		return fmt.Errorf("failed to store 2FA config: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrTwoFactorEnabled
 	}
 
 	return nil
 }
 
-// FindByUserID finds 2FA configuration by user ID
-func (r *TwoFactorRepository) FindByUserID(userID uuid.UUID) (*model.TwoFactorAuth, error) {
+// FindByUserID finds a user's 2FA configuration
+func (r *TwoFactorRepository) FindByUserID(ctx context.Context, userID string) (*TwoFactorAuth, error) {
 	query := `
-		SELECT id, user_id, secret, is_enabled, backup_codes, 
+		SELECT id, user_id, secret, is_enabled, backup_code_hashes,
 		       created_at, enabled_at, last_used_at
 		FROM two_factor_auth
 		WHERE user_id = $1
 	`
 
-	twoFactor := &model.TwoFactorAuth{}
+	twoFactor := &TwoFactorAuth{}
 	var backupCodesJSON []byte
 
-	err := r.db.QueryRow(query, userID).Scan(
-		&twoFactor.ID,
-		&twoFactor.UserID,
-		&twoFactor.Secret,
-		&twoFactor.IsEnabled,
-		&backupCodesJSON,
-		&twoFactor.CreatedAt,
-		&twoFactor.EnabledAt,
-		&twoFactor.LastUsedAt,
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&twoFactor.ID, &twoFactor.UserID, &twoFactor.Secret, &twoFactor.IsEnabled, &backupCodesJSON,
+		&twoFactor.CreatedAt, &twoFactor.EnabledAt, &twoFactor.LastUsedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("2FA not configured")
+		return nil, &NotFoundError{"Two-factor authentication is not set up"}
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find 2FA config: %w", err)
 	}
 
-	// Unmarshal backup codes
 	if len(backupCodesJSON) > 0 {
-		json.Unmarshal(backupCodesJSON, &twoFactor.BackupCodes)
+		if err := json.Unmarshal(backupCodesJSON, &twoFactor.BackupCodeHashes); err != nil {
+			return nil, fmt.Errorf("failed to decode backup codes: %w", err)
+		}
 	}
 
 	return twoFactor, nil
 }
 
-// Update updates 2FA configuration
-func (r *TwoFactorRepository) Update(twoFactor *model.TwoFactorAuth) error {
-	backupCodesJSON, _ := json.Marshal(twoFactor.BackupCodes)
-	
+// IsEnabled reports whether a user has 2FA turned on
+func (r *TwoFactorRepository) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM two_factor_auth WHERE user_id = $1 AND is_enabled = TRUE)`
+
+	var enabled bool
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, fmt.Errorf("failed to check 2FA: %w", err)
+	}
+
+	return enabled, nil
+}
+
+// Enable turns on a pending 2FA configuration
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID string) error {
+	query := `UPDATE two_factor_auth SET is_enabled = TRUE, enabled_at = NOW() WHERE user_id = $1 AND is_enabled = FALSE`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable 2FA: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// TouchLastUsed records a successful TOTP code
+func (r *TwoFactorRepository) TouchLastUsed(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE two_factor_auth SET last_used_at = NOW() WHERE user_id = $1`, userID)
+	return err
+}
+
+// ConsumeBackupCode removes a backup code hash if present; false means it was unknown or already used.
+// The check and removal are a single statement so a code cannot be redeemed twice concurrently.
+func (r *TwoFactorRepository) ConsumeBackupCode(ctx context.Context, userID, codeHash string) (bool, error) {
 	query := `
 		UPDATE two_factor_auth
-		SET is_enabled = $1, backup_codes = $2, enabled_at = $3, last_used_at = $4
-		WHERE user_id = $5
+		SET backup_code_hashes = backup_code_hashes - $2::text, last_used_at = NOW()
+		WHERE user_id = $1 AND is_enabled = TRUE AND backup_code_hashes ? $2
 	`
 
-	_, err := r.db.Exec(
-		query,
-		twoFactor.IsEnabled,
-		backupCodesJSON,
-		twoFactor.EnabledAt,
-		twoFactor.LastUsedAt,
-		twoFactor.UserID,
-	)
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume backup code: %w", err)
+	}
 
-	return err
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume backup code: %w", err)
+	}
+
+	return rowsAffected == 1, nil
 }
 
-// DeleteByUserID deletes 2FA configuration
-func (r *TwoFactorRepository) DeleteByUserID(userID uuid.UUID) error {
-	query := `DELETE FROM two_factor_auth WHERE user_id = $1`
-	_, err := r.db.Exec(query, userID)
-	return err
+// DeleteByUserID removes a user's 2FA configuration
+func (r *TwoFactorRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM two_factor_auth WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete 2FA config: %w", err)
+	}
+	return nil
 }
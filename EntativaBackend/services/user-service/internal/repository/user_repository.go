@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -24,11 +25,18 @@ type User struct {
 	IsActive          bool
 	IsDeleted         bool
 	IsVerified        bool
+	EmailVerifiedAt   *time.Time // nil until the current email address is confirmed
+	HasPassword       bool       // false for accounts created by cross-platform sign-in until a password is set
 	LastLoginAt       *time.Time
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
 
+// HasVerifiedEmail reports whether the user has confirmed their current email address
+func (u *User) HasVerifiedEmail() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 // UserRepository handles database operations for users
 type UserRepository struct {
 	db *sql.DB
@@ -46,9 +54,9 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *User) error {
 			id, first_name, last_name, email, username, password_hash,
 			birthday, gender, phone_number, bio, profile_picture_url,
 			cover_photo_url, is_active, is_deleted, is_verified,
-			created_at, updated_at
+			email_verified_at, password_set, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		)
 	`
 	
@@ -58,7 +66,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *User) error {
 		user.PasswordHash, user.Birthday, user.Gender, user.PhoneNumber,
 		user.Bio, user.ProfilePictureURL, user.CoverPhotoURL,
 		user.IsActive, user.IsDeleted, user.IsVerified,
-		user.CreatedAt, user.UpdatedAt,
+		user.EmailVerifiedAt, user.HasPassword, user.CreatedAt, user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -74,7 +82,7 @@ func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error)
 		SELECT id, first_name, last_name, email, username, password_hash,
 		       birthday, gender, phone_number, bio, profile_picture_url,
 		       cover_photo_url, is_active, is_deleted, is_verified,
-		       last_login_at, created_at, updated_at
+		       email_verified_at, password_set, last_login_at, created_at, updated_at
 		FROM users
 		WHERE id = $1 AND is_deleted = false
 	`
@@ -85,7 +93,7 @@ func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error)
 		&user.Username, &user.PasswordHash, &user.Birthday, &user.Gender,
 		&user.PhoneNumber, &user.Bio, &user.ProfilePictureURL,
 		&user.CoverPhotoURL, &user.IsActive, &user.IsDeleted, &user.IsVerified,
-		&user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
+		&user.EmailVerifiedAt, &user.HasPassword, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -104,7 +112,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 		SELECT id, first_name, last_name, email, username, password_hash,
 		       birthday, gender, phone_number, bio, profile_picture_url,
 		       cover_photo_url, is_active, is_deleted, is_verified,
-		       last_login_at, created_at, updated_at
+		       email_verified_at, password_set, last_login_at, created_at, updated_at
 		FROM users
 		WHERE LOWER(email) = LOWER($1) AND is_deleted = false
 	`
@@ -115,7 +123,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 		&user.Username, &user.PasswordHash, &user.Birthday, &user.Gender,
 		&user.PhoneNumber, &user.Bio, &user.ProfilePictureURL,
 		&user.CoverPhotoURL, &user.IsActive, &user.IsDeleted, &user.IsVerified,
-		&user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
+		&user.EmailVerifiedAt, &user.HasPassword, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -134,7 +142,7 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 		SELECT id, first_name, last_name, email, username, password_hash,
 		       birthday, gender, phone_number, bio, profile_picture_url,
 		       cover_photo_url, is_active, is_deleted, is_verified,
-		       last_login_at, created_at, updated_at
+		       email_verified_at, password_set, last_login_at, created_at, updated_at
 		FROM users
 		WHERE LOWER(username) = LOWER($1) AND is_deleted = false
 	`
@@ -145,7 +153,7 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 		&user.Username, &user.PasswordHash, &user.Birthday, &user.Gender,
 		&user.PhoneNumber, &user.Bio, &user.ProfilePictureURL,
 		&user.CoverPhotoURL, &user.IsActive, &user.IsDeleted, &user.IsVerified,
-		&user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
+		&user.EmailVerifiedAt, &user.HasPassword, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -164,7 +172,7 @@ func (r *UserRepository) FindByEmailOrUsername(ctx context.Context, emailOrUsern
 		SELECT id, first_name, last_name, email, username, password_hash,
 		       birthday, gender, phone_number, bio, profile_picture_url,
 		       cover_photo_url, is_active, is_deleted, is_verified,
-		       last_login_at, created_at, updated_at
+		       email_verified_at, password_set, last_login_at, created_at, updated_at
 		FROM users
 		WHERE (LOWER(email) = LOWER($1) OR LOWER(username) = LOWER($1))
 		  AND is_deleted = false
@@ -176,7 +184,7 @@ func (r *UserRepository) FindByEmailOrUsername(ctx context.Context, emailOrUsern
 		&user.Username, &user.PasswordHash, &user.Birthday, &user.Gender,
 		&user.PhoneNumber, &user.Bio, &user.ProfilePictureURL,
 		&user.CoverPhotoURL, &user.IsActive, &user.IsDeleted, &user.IsVerified,
-		&user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
+		&user.EmailVerifiedAt, &user.HasPassword, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -193,7 +201,7 @@ func (r *UserRepository) FindByEmailOrUsername(ctx context.Context, emailOrUsern
 func (r *UserRepository) UpdatePassword(ctx context.Context, userID, hashedPassword string) error {
 	query := `
 		UPDATE users
-		SET password_hash = $1, updated_at = $2
+		SET password_hash = $1, password_set = true, updated_at = $2
 		WHERE id = $3
 	`
 	
@@ -214,6 +222,50 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID, hashedPassw
 	return nil
 }
 
+// MarkEmailVerified records that the user confirmed email. It only applies while
+// email is still the account's address, so a stale link cannot verify a newer one.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID, email string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = $1, updated_at = $1
+		WHERE id = $2 AND LOWER(email) = LOWER($3)
+	`
+	
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"User not found"}
+	}
+	
+	return nil
+}
+
+// ChangeEmail replaces the account's address with an already confirmed one.
+// It only applies while fromEmail is still the current address.
+func (r *UserRepository) ChangeEmail(ctx context.Context, userID, fromEmail, toEmail string) error {
+	query := `
+		UPDATE users
+		SET email = $1, email_verified_at = $2, updated_at = $2
+		WHERE id = $3 AND LOWER(email) = LOWER($4)
+	`
+	
+	result, err := r.db.ExecContext(ctx, query, toEmail, time.Now(), userID, fromEmail)
+	if err != nil {
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return &NotFoundError{"User not found"}
+	}
+	
+	return nil
+}
+
 // UpdateLastLogin updates the last login timestamp
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID string) error {
 	query := `
@@ -291,17 +343,29 @@ func (r *UserRepository) LinkCrossPlatformAccount(ctx context.Context, userID, p
 	return nil
 }
 
-// CheckUsernameExists checks if a username is already taken
+// CheckUsernameExists checks if a username is already taken, reserved for someone
+// who recently gave it up, or a reserved brand, staff or system name. It applies
+// the same rules as UsernameRepository, so sign-up can't take a name a change couldn't.
 func (r *UserRepository) CheckUsernameExists(ctx context.Context, username string) (bool, error) {
-	query := `SELECT COUNT(*) FROM users WHERE LOWER(username) = LOWER($1) AND is_deleted = false`
+	reserved, err := r.IsUsernameReserved(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	if reserved {
+		return true, nil
+	}
 	
-	var count int
-	err := r.db.QueryRowContext(ctx, query, username).Scan(&count)
+	available, err := usernameAvailable(ctx, r.db, username, "", time.Now())
 	if err != nil {
-		return false, fmt.Errorf("failed to check username: %w", err)
+		return false, err
 	}
 	
-	return count > 0, nil
+	return !available, nil
+}
+
+// IsUsernameReserved reports whether username is a reserved brand, staff or system name
+func (r *UserRepository) IsUsernameReserved(ctx context.Context, username string) (bool, error) {
+	return usernameBlocklisted(ctx, r.db, username)
 }
 
 // CheckEmailExists checks if an email is already taken
@@ -316,3 +380,78 @@ func (r *UserRepository) CheckEmailExists(ctx context.Context, email string) (bo
 	
 	return count > 0, nil
 }
+
+// Photos a user can have on their profile
+const (
+	PhotoKindProfilePicture = "profile_picture"
+	PhotoKindCoverPhoto     = "cover_photo"
+)
+
+// UserPhoto is a profile picture or cover photo published in several sizes
+type UserPhoto struct {
+	URL      string // the size most screens use
+	Blurhash string
+	Variants []UserPhotoVariant
+}
+
+// UserPhotoVariant is one published size of a photo, stored as JSON
+type UserPhotoVariant struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ReplacePhoto sets the user's profile picture or cover photo and returns the one
+// it replaced, or nil when they had none. Photos set before sizes were published
+// come back without variants.
+func (r *UserRepository) ReplacePhoto(ctx context.Context, userID, kind string, photo *UserPhoto) (*UserPhoto, error) {
+	if kind != PhotoKindProfilePicture && kind != PhotoKindCoverPhoto {
+		return nil, fmt.Errorf("unknown photo kind %q", kind)
+	}
+	
+	variants, err := json.Marshal(photo.Variants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode photo variants: %w", err)
+	}
+	
+	// kind is one of the two constants, so it is safe to build column names from
+	query := fmt.Sprintf(`
+		WITH previous AS (
+			SELECT id, %[1]s_url AS url, %[1]s_blurhash AS blurhash, %[1]s_variants AS variants
+			FROM users
+			WHERE id = $1 AND is_deleted = false
+			FOR UPDATE
+		)
+		UPDATE users u
+		SET %[1]s_url = $2, %[1]s_blurhash = $3, %[1]s_variants = $4, updated_at = CURRENT_TIMESTAMP
+		FROM previous
+		WHERE u.id = previous.id
+		RETURNING previous.url, previous.blurhash, previous.variants
+	`, kind)
+	
+	var previousURL, previousBlurhash sql.NullString
+	var previousVariants []byte
+	err = r.db.QueryRowContext(ctx, query, userID, photo.URL, photo.Blurhash, variants).
+		Scan(&previousURL, &previousBlurhash, &previousVariants)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"User not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", kind, err)
+	}
+	
+	if !previousURL.Valid || previousURL.String == "" {
+		return nil, nil
+	}
+	
+	previous := &UserPhoto{URL: previousURL.String, Blurhash: previousBlurhash.String}
+	if len(previousVariants) > 0 {
+		if err := json.Unmarshal(previousVariants, &previous.Variants); err != nil {
+			return nil, fmt.Errorf("failed to decode photo variants: %w", err)
+		}
+	}
+	
+	return previous, nil
+}
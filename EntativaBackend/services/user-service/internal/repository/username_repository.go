@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrUsernameUnavailable is returned when a username is held or reserved by someone else
+	ErrUsernameUnavailable = errors.New("username is not available")
+	// ErrUsernameChangedRecently is returned when the user changed their username within the cooldown
+	ErrUsernameChangedRecently = errors.New("username was changed recently")
+)
+
+// UsernameChange is one entry in a user's username history
+type UsernameChange struct {
+	ID            string
+	UserID        string
+	OldUsername   string
+	NewUsername   string
+	ChangedAt     time.Time
+	ReservedUntil time.Time // the old username is held for the user until then
+}
+
+// UsernameLookup is the account a username leads to. Redirected is set when the
+// username was given up by that account and OldUsername is what was asked for.
+type UsernameLookup struct {
+	UserID            string
+	Username          string
+	FirstName         string
+	LastName          string
+	ProfilePictureURL *string
+	IsVerified        bool
+	Redirected        bool
+	OldUsername       string
+	ChangedAt         *time.Time
+}
+
+// UsernameRepository handles username history, reservations and the blocklist
+type UsernameRepository struct {
+	db *sql.DB
+}
+
+// NewUsernameRepository creates a new username repository
+func NewUsernameRepository(db *sql.DB) *UsernameRepository {
+	return &UsernameRepository{db: db}
+}
+
+// IsBlocklisted reports whether username is a reserved brand, staff or system name
+func (r *UsernameRepository) IsBlocklisted(ctx context.Context, username string) (bool, error) {
+	return usernameBlocklisted(ctx, r.db, username)
+}
+
+// IsAvailable reports whether userID may take username: nobody else holds it and
+// it is not reserved for someone who recently gave it up. Pass an empty userID for
+// someone who does not have an account yet.
+func (r *UsernameRepository) IsAvailable(ctx context.Context, username, userID string) (bool, error) {
+	return usernameAvailable(ctx, r.db, username, userID, time.Now())
+}
+
+// LastChange returns the user's most recent username change, or nil if they never changed it
+func (r *UsernameRepository) LastChange(ctx context.Context, userID string) (*UsernameChange, error) {
+	query := `
+		SELECT id, user_id, old_username, new_username, changed_at, reserved_until
+		FROM username_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC
+		LIMIT 1
+	`
+
+	change, err := scanUsernameChange(r.db.QueryRowContext(ctx, query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last username change: %w", err)
+	}
+	return change, nil
+}
+
+// ChangeUsername gives the user change.NewUsername and records the change, filling in
+// change.OldUsername. Changes to the same name are serialized, so two users can't
+// take a name at once. Returns ErrUsernameUnavailable if someone else holds or has
+// reserved the name, and ErrUsernameChangedRecently if the user's last change was
+// less than cooldown ago.
+func (r *UsernameRepository) ChangeUsername(ctx context.Context, change *UsernameChange, cooldown time.Duration) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user's row serializes their own changes for the cooldown check
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT username FROM users WHERE id = $1 AND is_deleted = false FOR UPDATE`,
+		change.UserID,
+	).Scan(&change.OldUsername)
+	if err == sql.ErrNoRows {
+		return &NotFoundError{"User not found"}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var changedRecently bool
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM username_history WHERE user_id = $1 AND changed_at > $2)`,
+		change.UserID, change.ChangedAt.Add(-cooldown),
+	).Scan(&changedRecently)
+	if err != nil {
+		return fmt.Errorf("failed to check last username change: %w", err)
+	}
+	if changedRecently {
+		return ErrUsernameChangedRecently
+	}
+
+	// Two users taking the same free name would both see it as available; the lock
+	// makes the second wait and then see it taken
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext(LOWER($1)))`, change.NewUsername); err != nil {
+		return fmt.Errorf("failed to lock username: %w", err)
+	}
+
+	available, err := usernameAvailable(ctx, tx, change.NewUsername, change.UserID, change.ChangedAt)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return ErrUsernameUnavailable
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE users SET username = $2, updated_at = $3 WHERE id = $1`,
+		change.UserID, change.NewUsername, change.ChangedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update username: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO username_history (id, user_id, old_username, new_username, changed_at, reserved_until)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		change.ID, change.UserID, change.OldUsername, change.NewUsername, change.ChangedAt, change.ReservedUntil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record username change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit username change: %w", err)
+	}
+	return nil
+}
+
+// LookupUsername finds the account holding username or, if nobody holds it, the
+// account that most recently gave it up
+func (r *UsernameRepository) LookupUsername(ctx context.Context, username string) (*UsernameLookup, error) {
+	lookup := &UsernameLookup{}
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT id, username, first_name, last_name, profile_picture_url, is_verified
+		 FROM users
+		 WHERE LOWER(username) = LOWER($1) AND is_deleted = false`,
+		username,
+	).Scan(&lookup.UserID, &lookup.Username, &lookup.FirstName, &lookup.LastName, &lookup.ProfilePictureURL, &lookup.IsVerified)
+	if err == nil {
+		return lookup, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up username: %w", err)
+	}
+
+	var changedAt time.Time
+	err = r.db.QueryRowContext(
+		ctx,
+		`SELECT u.id, u.username, u.first_name, u.last_name, u.profile_picture_url, u.is_verified,
+		        h.old_username, h.changed_at
+		 FROM username_history h
+		 JOIN users u ON u.id = h.user_id AND u.is_deleted = false
+		 WHERE LOWER(h.old_username) = LOWER($1)
+		 ORDER BY h.changed_at DESC
+		 LIMIT 1`,
+		username,
+	).Scan(
+		&lookup.UserID, &lookup.Username, &lookup.FirstName, &lookup.LastName, &lookup.ProfilePictureURL, &lookup.IsVerified,
+		&lookup.OldUsername, &changedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{"Username not found"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve old username: %w", err)
+	}
+
+	lookup.Redirected = true
+	lookup.ChangedAt = &changedAt
+	return lookup, nil
+}
+
+// ListHistory returns every username change the user made, newest first
+func (r *UsernameRepository) ListHistory(ctx context.Context, userID string) ([]*UsernameChange, error) {
+	query := `
+		SELECT id, user_id, old_username, new_username, changed_at, reserved_until
+		FROM username_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list username history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*UsernameChange
+	for rows.Next() {
+		change, err := scanUsernameChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan username change: %w", err)
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// usernameBlocklisted matches username against reserved_usernames: exact names, and
+// patterns that may not appear anywhere in a username. Sign-up checks it too.
+func usernameBlocklisted(ctx context.Context, db queryRower, username string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM reserved_usernames
+			WHERE (match_kind = 'exact' AND pattern = LOWER($1))
+			   OR (match_kind = 'contains' AND POSITION(pattern IN LOWER($1)) > 0)
+		)
+	`
+
+	var blocklisted bool
+	if err := db.QueryRowContext(ctx, query, username).Scan(&blocklisted); err != nil {
+		return false, fmt.Errorf("failed to check reserved usernames: %w", err)
+	}
+	return blocklisted, nil
+}
+
+func usernameAvailable(ctx context.Context, db queryRower, username, userID string, now time.Time) (bool, error) {
+	query := `
+		SELECT NOT EXISTS(
+			SELECT 1 FROM users
+			WHERE LOWER(username) = LOWER($1) AND id::text != $2 AND is_deleted = false
+		) AND NOT EXISTS(
+			SELECT 1 FROM username_history
+			WHERE LOWER(old_username) = LOWER($1) AND user_id::text != $2 AND reserved_until > $3
+		)
+	`
+
+	var available bool
+	if err := db.QueryRowContext(ctx, query, username, userID, now).Scan(&available); err != nil {
+		return false, fmt.Errorf("failed to check username: %w", err)
+	}
+	return available, nil
+}
+
+func scanUsernameChange(row rowScanner) (*UsernameChange, error) {
+	change := &UsernameChange{}
+	err := row.Scan(
+		&change.ID, &change.UserID, &change.OldUsername, &change.NewUsername,
+		&change.ChangedAt, &change.ReservedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
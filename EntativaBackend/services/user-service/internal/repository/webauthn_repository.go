@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WebAuthn ceremony types
+const (
+	WebAuthnCeremonyRegistration   = "registration"
+	WebAuthnCeremonyAuthentication = "authentication"
+)
+
+var (
+	ErrCredentialNotFound = errors.New("webauthn credential not found")
+	ErrCeremonyNotFound   = errors.New("webauthn ceremony not found")
+)
+
+// WebAuthnCredential is a registered passkey or security key
+type WebAuthnCredential struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"-"`
+	CredentialID   []byte     `json:"-"`
+	PublicKey      []byte     `json:"-"` // COSE_Key
+	Algorithm      int        `json:"-"`
+	SignCount      uint32     `json:"-"`
+	AAGUID         []byte     `json:"-"`
+	Transports     []string   `json:"transports"`
+	Name           string     `json:"name"`
+	BackupEligible bool       `json:"backup_eligible"` // synced passkey
+	BackupState    bool       `json:"backup_state"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebAuthnCeremony is a pending registration or authentication and its challenge
+type WebAuthnCeremony struct {
+	ID        string
+	UserID    *string // nil for discoverable login
+	Ceremony  string
+	Challenge []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// WebAuthnRepository handles database operations for passkeys
+type WebAuthnRepository struct {
+	db *sql.DB
+}
+
+// NewWebAuthnRepository creates a new WebAuthn repository
+func NewWebAuthnRepository(db *sql.DB) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+const webAuthnCredentialColumns = `
+	id, user_id, credential_id, public_key, algorithm, sign_count, aaguid,
+	transports, name, backup_eligible, backup_state, created_at, last_used_at
+`
+
+// CreateCredential stores a newly registered credential
+func (r *WebAuthnRepository) CreateCredential(ctx context.Context, credential *WebAuthnCredential) error {
+	transportsJSON, err := json.Marshal(credential.Transports)
+	if err != nil {
+		return fmt.Errorf("failed to encode transports: %w", err)
+	}
+
+	query := `
+		INSERT INTO webauthn_credentials (` + webAuthnCredentialColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err = r.db.ExecContext(
+		ctx, query,
+		credential.ID, credential.UserID, credential.CredentialID, credential.PublicKey, credential.Algorithm,
+		int64(credential.SignCount), credential.AAGUID, transportsJSON, credential.Name,
+		credential.BackupEligible, credential.BackupState, credential.CreatedAt, credential.LastUsedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+
+	return nil
+}
+
+// FindCredentialByCredentialID finds a credential by the authenticator's credential ID
+func (r *WebAuthnRepository) FindCredentialByCredentialID(ctx context.Context, credentialID []byte) (*WebAuthnCredential, error) {
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+	return scanWebAuthnCredential(r.db.QueryRowContext(ctx, query, credentialID))
+}
+
+// ListCredentials lists a user's credentials, oldest first
+func (r *WebAuthnRepository) ListCredentials(ctx context.Context, userID string) ([]*WebAuthnCredential, error) {
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webauthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []*WebAuthnCredential{}
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+// CountCredentials counts a user's credentials
+func (r *WebAuthnRepository) CountCredentials(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count webauthn credentials: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateSignCount records a successful assertion.
+// The counter only moves forward, so a concurrent replay of the same assertion loses.
+func (r *WebAuthnRepository) UpdateSignCount(ctx context.Context, id string, previous, signCount uint32, backupState bool) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $1, backup_state = $2, last_used_at = NOW()
+		WHERE id = $3 AND sign_count = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, int64(signCount), backupState, id, int64(previous))
+	if err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+// RenameCredential renames a user's credential
+func (r *WebAuthnRepository) RenameCredential(ctx context.Context, userID, id, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE webauthn_credentials SET name = $1 WHERE id = $2 AND user_id = $3`, name, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rename webauthn credential: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+// DeleteCredential revokes a user's credential
+func (r *WebAuthnRepository) DeleteCredential(ctx context.Context, userID, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+// CreateCeremony stores the challenge of a pending ceremony
+func (r *WebAuthnRepository) CreateCeremony(ctx context.Context, ceremony *WebAuthnCeremony) error {
+	query := `
+		INSERT INTO webauthn_ceremonies (id, user_id, ceremony, challenge, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		ceremony.ID, ceremony.UserID, ceremony.Ceremony, ceremony.Challenge, ceremony.ExpiresAt, ceremony.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webauthn ceremony: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeCeremony deletes and returns an unexpired ceremony; each challenge can be answered once
+func (r *WebAuthnRepository) ConsumeCeremony(ctx context.Context, id, ceremonyType string) (*WebAuthnCeremony, error) {
+	query := `
+		DELETE FROM webauthn_ceremonies
+		WHERE id = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING id, user_id, ceremony, challenge, expires_at, created_at
+	`
+
+	ceremony := &WebAuthnCeremony{}
+	err := r.db.QueryRowContext(ctx, query, id, ceremonyType).Scan(
+		&ceremony.ID, &ceremony.UserID, &ceremony.Ceremony, &ceremony.Challenge, &ceremony.ExpiresAt, &ceremony.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCeremonyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume webauthn ceremony: %w", err)
+	}
+
+	return ceremony, nil
+}
+
+// DeleteExpiredCeremonies deletes ceremonies that were never completed
+func (r *WebAuthnRepository) DeleteExpiredCeremonies(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_ceremonies WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired webauthn ceremonies: %w", err)
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebAuthnCredential(row rowScanner) (*WebAuthnCredential, error) {
+	credential := &WebAuthnCredential{}
+	var signCount int64
+	var transportsJSON []byte
+
+	err := row.Scan(
+		&credential.ID, &credential.UserID, &credential.CredentialID, &credential.PublicKey, &credential.Algorithm,
+		&signCount, &credential.AAGUID, &transportsJSON, &credential.Name,
+		&credential.BackupEligible, &credential.BackupState, &credential.CreatedAt, &credential.LastUsedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webauthn credential: %w", err)
+	}
+
+	credential.SignCount = uint32(signCount)
+	if len(transportsJSON) > 0 {
+		if err := json.Unmarshal(transportsJSON, &credential.Transports); err != nil {
+			return nil, fmt.Errorf("failed to decode transports: %w", err)
+		}
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+// UserDeletionRequestedEvent is the event_type services listen for on "user-events"
+// to purge a deleted user's data. They report back through the internal API.
+const UserDeletionRequestedEvent = "user.deletion_requested"
+
+// Batch sizes for one pass of the purge worker
+const (
+	purgeStartBatch  = 100
+	purgeStepBatch   = 500
+	purgeFinishBatch = 100
+)
+
+var ErrAccountDeletionNotFound = errors.New("account deletion not found")
+
+// AccountDeletionView is an account deletion and how far each service has got with its purge
+type AccountDeletionView struct {
+	ID              string           `json:"id"`
+	UserID          string           `json:"user_id"`
+	Status          string           `json:"status"`
+	RequestedAt     time.Time        `json:"requested_at"`
+	PurgeAfter      time.Time        `json:"purge_after"`
+	CancelledAt     *time.Time       `json:"cancelled_at,omitempty"`
+	PurgeStartedAt  *time.Time       `json:"purge_started_at,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	Services        []*PurgeStepView `json:"services,omitempty"`
+	PendingServices []string         `json:"pending_services,omitempty"`
+}
+
+// PurgeStepView is one service's progress purging a deleted account
+type PurgeStepView struct {
+	Service       string     `json:"service"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	RequestedAt   *time.Time `json:"requested_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// AccountDeletionService deletes accounts.
+//
+// A deletion request deactivates the account for a grace period; logging back in
+// during it cancels the deletion. Once it ends the purge starts: the account can
+// no longer be used, and every service holding the user's data is sent a
+// user.deletion_requested event and asked to confirm its cleanup. Services that
+// fail or stay silent are asked again with exponential backoff, so their purges
+// must be idempotent. When all have confirmed, the user's row is deleted here,
+// taking the rest of this service's data with it.
+type AccountDeletionService struct {
+	deletionRepo *repository.AccountDeletionRepository
+	sessionRepo  *repository.SessionRepository
+	emailService *EmailService
+	kafka        *KafkaProducer
+	auditLog     *AuditLog
+	config       *config.Config
+}
+
+// NewAccountDeletionService creates a new account deletion service
+func NewAccountDeletionService(
+	deletionRepo *repository.AccountDeletionRepository,
+	sessionRepo *repository.SessionRepository,
+	emailService *EmailService,
+	kafka *KafkaProducer,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *AccountDeletionService {
+	return &AccountDeletionService{
+		deletionRepo: deletionRepo,
+		sessionRepo:  sessionRepo,
+		emailService: emailService,
+		kafka:        kafka,
+		auditLog:     auditLog,
+		config:       cfg,
+	}
+}
+
+// ScheduleDeletion starts the grace period before the user's account is deleted and
+// signs them out everywhere. Asking again returns the deletion already under way.
+func (s *AccountDeletionService) ScheduleDeletion(ctx context.Context, user *repository.User, reason, ipAddress string) (*AccountDeletionView, error) {
+	now := time.Now()
+	deletion, created, err := s.deletionRepo.ScheduleDeletion(ctx, &repository.AccountDeletion{
+		ID:          util.GenerateUUID(),
+		UserID:      user.ID,
+		Reason:      reason,
+		RequestedIP: ipAddress,
+		RequestedAt: now,
+		PurgeAfter:  now.Add(s.config.AccountDeletion.GracePeriod),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		if err := s.sessionRepo.InvalidateAllUserSessions(ctx, user.ID); err != nil {
+			log.Printf("Failed to sign out user %s after deletion request: %v", user.ID, err)
+		}
+		if err := s.emailService.SendAccountDeletionScheduledEmail(user.Email, user.FirstName, deletion.PurgeAfter); err != nil {
+			log.Printf("Failed to send account deletion email: %v", err)
+		}
+		s.auditLog.LogAccountDeletionScheduled(user.ID, deletion.ID, ipAddress)
+	}
+
+	return accountDeletionView(deletion), nil
+}
+
+// CancelOnLogin cancels the user's deletion if it is still in its grace period,
+// reporting whether there was one. Call it whenever the user signs in.
+func (s *AccountDeletionService) CancelOnLogin(ctx context.Context, user *repository.User, ipAddress string) (bool, error) {
+	deletion, err := s.deletionRepo.CancelScheduledDeletion(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	if deletion == nil {
+		return false, nil
+	}
+
+	if err := s.emailService.SendAccountDeletionCancelledEmail(user.Email, user.FirstName); err != nil {
+		log.Printf("Failed to send account deletion cancelled email: %v", err)
+	}
+	s.auditLog.LogAccountDeletionCancelled(user.ID, deletion.ID, ipAddress)
+	return true, nil
+}
+
+// GetDeletion returns a deletion with the progress of each service's purge
+func (s *AccountDeletionService) GetDeletion(ctx context.Context, id string) (*AccountDeletionView, error) {
+	deletion, err := s.deletionRepo.GetDeletion(ctx, id)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, ErrAccountDeletionNotFound
+		}
+		return nil, err
+	}
+	return accountDeletionView(deletion), nil
+}
+
+// ListDeletions returns every deletion requested for a user, newest first
+func (s *AccountDeletionService) ListDeletions(ctx context.Context, userID string) ([]*AccountDeletionView, error) {
+	deletions, err := s.deletionRepo.ListDeletions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*AccountDeletionView, 0, len(deletions))
+	for _, deletion := range deletions {
+		views = append(views, accountDeletionView(deletion))
+	}
+	return views, nil
+}
+
+// ReportPurge records a service confirming, or failing, its purge of a deleted user.
+// Confirming twice is harmless; a failure is retried at the step's next attempt.
+func (s *AccountDeletionService) ReportPurge(ctx context.Context, deletionID, serviceName string, succeeded bool, lastError string) error {
+	err := s.deletionRepo.ReportStep(ctx, deletionID, serviceName, succeeded, lastError)
+	var notFound *repository.NotFoundError
+	if errors.As(err, &notFound) {
+		return ErrAccountDeletionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !succeeded {
+		log.Printf("Service %s failed to purge data for deletion %s: %s", serviceName, deletionID, lastError)
+	}
+	return nil
+}
+
+// RetryPurge asks every service that has not confirmed a purge again on the worker's next pass
+func (s *AccountDeletionService) RetryPurge(ctx context.Context, deletionID string) (*AccountDeletionView, error) {
+	if _, err := s.GetDeletion(ctx, deletionID); err != nil {
+		return nil, err
+	}
+	if err := s.deletionRepo.RetryNow(ctx, deletionID); err != nil {
+		return nil, err
+	}
+	return s.GetDeletion(ctx, deletionID)
+}
+
+// RunWorker drives purges until ctx is cancelled. Every instance may run one:
+// each pass claims its rows with SKIP LOCKED, so they share the work.
+func (s *AccountDeletionService) RunWorker(ctx context.Context) {
+	if len(s.config.Kafka.Brokers) == 0 && len(s.config.AccountDeletion.Services) > 0 {
+		log.Printf("KAFKA_BROKERS not set; services will not hear about account purges")
+	}
+
+	ticker := time.NewTicker(s.config.AccountDeletion.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processPurges(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processPurges starts purges whose grace period is over, (re)requests cleanup from
+// services that are due, and finishes purges every service has confirmed
+func (s *AccountDeletionService) processPurges(ctx context.Context) {
+	started, err := s.deletionRepo.StartDuePurges(ctx, s.config.AccountDeletion.Services, purgeStartBatch)
+	if err != nil {
+		log.Printf("Failed to start account purges: %v", err)
+	}
+	for _, deletion := range started {
+		log.Printf("Purging account %s (deletion %s)", deletion.UserID, deletion.ID)
+	}
+
+	steps, err := s.deletionRepo.ClaimDueSteps(
+		ctx,
+		purgeStepBatch,
+		s.config.AccountDeletion.RetryBackoff,
+		s.config.AccountDeletion.MaxRetryBackoff,
+	)
+	if err != nil {
+		log.Printf("Failed to claim purge steps: %v", err)
+	}
+	s.requestPurges(steps)
+
+	finished, err := s.deletionRepo.FinishPurges(ctx, purgeFinishBatch)
+	if err != nil {
+		log.Printf("Failed to finish account purges: %v", err)
+	}
+	for _, deletion := range finished {
+		s.auditLog.LogAccountPurged(deletion.UserID, deletion.ID)
+	}
+}
+
+// requestPurges publishes one user.deletion_requested event per deletion, naming the
+// services asked to act. A lost event is harmless: the steps are already scheduled
+// to be requested again if no confirmation arrives.
+func (s *AccountDeletionService) requestPurges(steps []*repository.AccountDeletionStep) {
+	var order []string
+	services := make(map[string][]string)
+	users := make(map[string]string)
+	for _, step := range steps {
+		if _, ok := services[step.DeletionID]; !ok {
+			order = append(order, step.DeletionID)
+		}
+		services[step.DeletionID] = append(services[step.DeletionID], step.Service)
+		users[step.DeletionID] = step.UserID
+	}
+
+	for _, deletionID := range order {
+		event := map[string]interface{}{
+			"event_type":  UserDeletionRequestedEvent,
+			"deletion_id": deletionID,
+			"user_id":     users[deletionID],
+			"services":    services[deletionID],
+			"timestamp":   time.Now(),
+		}
+
+		if err := s.kafka.PublishEvent("user-events", event); err != nil {
+			log.Printf("Failed to publish %s for deletion %s: %v", UserDeletionRequestedEvent, deletionID, err)
+		}
+	}
+}
+
+func accountDeletionView(deletion *repository.AccountDeletion) *AccountDeletionView {
+	view := &AccountDeletionView{
+		ID:             deletion.ID,
+		UserID:         deletion.UserID,
+		Status:         deletion.Status,
+		RequestedAt:    deletion.RequestedAt,
+		PurgeAfter:     deletion.PurgeAfter,
+		CancelledAt:    deletion.CancelledAt,
+		PurgeStartedAt: deletion.PurgeStartedAt,
+		CompletedAt:    deletion.CompletedAt,
+	}
+
+	for _, step := range deletion.Steps {
+		stepView := &PurgeStepView{
+			Service:     step.Service,
+			Status:      step.Status,
+			Attempts:    step.Attempts,
+			LastError:   step.LastError,
+			RequestedAt: step.RequestedAt,
+			CompletedAt: step.CompletedAt,
+		}
+		if step.Status != repository.DeletionStepCompleted {
+			nextAttemptAt := step.NextAttemptAt
+			stepView.NextAttemptAt = &nextAttemptAt
+			view.PendingServices = append(view.PendingServices, step.Service)
+		}
+		view.Services = append(view.Services, stepView)
+	}
+
+	return view
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+const (
+	maxAudienceListsPerUser   = 50
+	maxAudienceListMembers    = 5000
+	maxAudienceListNameLength = 50
+
+	// CloseFriendsListAlias may be used in place of the Close Friends list's ID;
+	// the list is created the first time it is used
+	CloseFriendsListAlias = "close-friends"
+	closeFriendsListName  = "Close Friends"
+)
+
+var (
+	ErrAudienceListNotFound    = errors.New("audience list not found")
+	ErrAudienceListNameTaken   = errors.New("you already have a list with this name")
+	ErrInvalidAudienceListName = errors.New("list name must be between 1 and 50 characters")
+	ErrTooManyAudienceLists    = errors.New("you can have at most 50 lists")
+	ErrAudienceListFull        = errors.New("a list can have at most 5000 members")
+	ErrCloseFriendsListFixed   = errors.New("the Close Friends list can't be renamed or deleted")
+	ErrCannotAddSelfToList     = errors.New("you can't add yourself to a list")
+	ErrInvalidListMember       = errors.New("member IDs must be valid user IDs")
+)
+
+// AudienceListView is one of the user's audience lists
+type AudienceListView struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AudienceListMemberView is someone in an audience list
+type AudienceListMemberView struct {
+	UserID            string    `json:"user_id"`
+	Username          string    `json:"username"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	ProfilePictureURL *string   `json:"profile_picture_url,omitempty"`
+	AddedAt           time.Time `json:"added_at"`
+}
+
+// AudienceListService manages user-defined audiences such as "Close Friends" and
+// "Family". A list's ID can be used as the audience of a post, a story or a
+// profile field; other services ask CheckMemberships whether a viewer is in it.
+// Members are never told which lists they are in.
+type AudienceListService struct {
+	listRepo *repository.AudienceListRepository
+}
+
+// NewAudienceListService creates a new audience list service
+func NewAudienceListService(listRepo *repository.AudienceListRepository) *AudienceListService {
+	return &AudienceListService{listRepo: listRepo}
+}
+
+// ListLists returns all of the user's lists, Close Friends first
+func (s *AudienceListService) ListLists(ctx context.Context, ownerID string) ([]*AudienceListView, error) {
+	lists, err := s.listRepo.ListLists(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*AudienceListView, 0, len(lists))
+	for _, list := range lists {
+		views = append(views, audienceListView(list))
+	}
+	return views, nil
+}
+
+// GetList returns one of the user's lists
+func (s *AudienceListService) GetList(ctx context.Context, ownerID, listID string) (*AudienceListView, error) {
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return nil, err
+	}
+	return audienceListView(list), nil
+}
+
+// CreateList makes a new, empty list
+func (s *AudienceListService) CreateList(ctx context.Context, ownerID, name string) (*AudienceListView, error) {
+	name, err := s.validateName(ctx, ownerID, name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.listRepo.CountLists(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAudienceListsPerUser {
+		return nil, ErrTooManyAudienceLists
+	}
+
+	list := &repository.AudienceList{
+		ID:        util.GenerateUUID(),
+		OwnerID:   ownerID,
+		Name:      name,
+		Kind:      repository.AudienceListCustom,
+		CreatedAt: time.Now(),
+	}
+	list.UpdatedAt = list.CreatedAt
+
+	if err := s.listRepo.CreateList(ctx, list); err != nil {
+		return nil, err
+	}
+
+	return audienceListView(list), nil
+}
+
+// RenameList renames one of the user's lists. Close Friends keeps its name.
+func (s *AudienceListService) RenameList(ctx context.Context, ownerID, listID, name string) (*AudienceListView, error) {
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if list.Kind == repository.AudienceListCloseFriends {
+		return nil, ErrCloseFriendsListFixed
+	}
+
+	name, err = s.validateName(ctx, ownerID, name, list.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listRepo.RenameList(ctx, list.ID, ownerID, name); err != nil {
+		return nil, mapAudienceListError(err)
+	}
+
+	list.Name = name
+	list.UpdatedAt = time.Now()
+	return audienceListView(list), nil
+}
+
+// DeleteList deletes one of the user's lists. Anything shared with it is then
+// seen by no one but the owner.
+func (s *AudienceListService) DeleteList(ctx context.Context, ownerID, listID string) error {
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return err
+	}
+	if list.Kind == repository.AudienceListCloseFriends {
+		return ErrCloseFriendsListFixed
+	}
+
+	return mapAudienceListError(s.listRepo.DeleteList(ctx, list.ID, ownerID))
+}
+
+// ListMembers returns a page of a list's members
+func (s *AudienceListService) ListMembers(ctx context.Context, ownerID, listID string, limit, offset int) ([]*AudienceListMemberView, error) {
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.listRepo.ListMembers(ctx, list.ID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*AudienceListMemberView, 0, len(members))
+	for _, member := range members {
+		views = append(views, &AudienceListMemberView{
+			UserID:            member.UserID,
+			Username:          member.Username,
+			FirstName:         member.FirstName,
+			LastName:          member.LastName,
+			ProfilePictureURL: member.ProfilePictureURL,
+			AddedAt:           member.AddedAt,
+		})
+	}
+	return views, nil
+}
+
+// AddMembers adds people to a list. People already in it, and accounts that no
+// longer exist, are skipped. Returns how many were added.
+func (s *AudienceListService) AddMembers(ctx context.Context, ownerID, listID string, memberIDs []string) (int, error) {
+	if err := validateListMembers(ownerID, memberIDs); err != nil {
+		return 0, err
+	}
+
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return 0, err
+	}
+	if list.MemberCount+len(memberIDs) > maxAudienceListMembers {
+		return 0, ErrAudienceListFull
+	}
+
+	return s.listRepo.AddMembers(ctx, list.ID, memberIDs)
+}
+
+// RemoveMembers takes people out of a list. Returns how many were removed.
+func (s *AudienceListService) RemoveMembers(ctx context.Context, ownerID, listID string, memberIDs []string) (int, error) {
+	if err := validateListMembers("", memberIDs); err != nil {
+		return 0, err
+	}
+
+	list, err := s.resolveList(ctx, ownerID, listID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.listRepo.RemoveMembers(ctx, list.ID, memberIDs)
+}
+
+// CheckMemberships answers a batch of "is the viewer in list X of owner Y"
+// questions for other services, in the order asked. An owner is treated as being
+// in their own lists.
+func (s *AudienceListService) CheckMemberships(ctx context.Context, checks []repository.AudienceMembershipCheck) ([]bool, error) {
+	results, err := s.listRepo.CheckMemberships(ctx, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, check := range checks {
+		if check.ViewerID == check.OwnerID {
+			results[i] = true
+		}
+	}
+	return results, nil
+}
+
+// MemberOfLists returns which of the owner's lists viewerID is in
+func (s *AudienceListService) MemberOfLists(ctx context.Context, ownerID, viewerID string, listIDs []string) (map[string]bool, error) {
+	checks := make([]repository.AudienceMembershipCheck, 0, len(listIDs))
+	for _, listID := range listIDs {
+		checks = append(checks, repository.AudienceMembershipCheck{OwnerID: ownerID, ListID: listID, ViewerID: viewerID})
+	}
+
+	results, err := s.CheckMemberships(ctx, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	member := make(map[string]bool, len(listIDs))
+	for i, listID := range listIDs {
+		member[listID] = results[i]
+	}
+	return member, nil
+}
+
+// CheckOwnership returns ErrAudienceListNotFound unless every one of listIDs is
+// the owner's, so nobody can share with someone else's list
+func (s *AudienceListService) CheckOwnership(ctx context.Context, ownerID string, listIDs []string) error {
+	if len(listIDs) == 0 {
+		return nil
+	}
+	for _, listID := range listIDs {
+		if !util.IsValidUUID(listID) {
+			return ErrAudienceListNotFound
+		}
+	}
+
+	owned, err := s.listRepo.OwnedListIDs(ctx, ownerID, listIDs)
+	if err != nil {
+		return err
+	}
+
+	ownedSet := make(map[string]bool, len(owned))
+	for _, id := range owned {
+		ownedSet[id] = true
+	}
+	for _, listID := range listIDs {
+		if !ownedSet[listID] {
+			return ErrAudienceListNotFound
+		}
+	}
+	return nil
+}
+
+// resolveList finds one of the owner's lists by ID, or their Close Friends list by
+// CloseFriendsListAlias, creating it if they have never used it
+func (s *AudienceListService) resolveList(ctx context.Context, ownerID, listID string) (*repository.AudienceList, error) {
+	if listID != CloseFriendsListAlias {
+		if !util.IsValidUUID(listID) {
+			return nil, ErrAudienceListNotFound
+		}
+		list, err := s.listRepo.GetList(ctx, listID, ownerID)
+		return list, mapAudienceListError(err)
+	}
+
+	list, err := s.listRepo.GetCloseFriendsList(ctx, ownerID)
+	var notFound *repository.NotFoundError
+	if !errors.As(err, &notFound) {
+		return list, err
+	}
+
+	list = &repository.AudienceList{
+		ID:        util.GenerateUUID(),
+		OwnerID:   ownerID,
+		Name:      closeFriendsListName,
+		Kind:      repository.AudienceListCloseFriends,
+		CreatedAt: time.Now(),
+	}
+	list.UpdatedAt = list.CreatedAt
+	if err := s.listRepo.CreateList(ctx, list); err != nil {
+		// Lost a race with another request creating it
+		if existing, getErr := s.listRepo.GetCloseFriendsList(ctx, ownerID); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *AudienceListService) validateName(ctx context.Context, ownerID, name, excludeID string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxAudienceListNameLength {
+		return "", ErrInvalidAudienceListName
+	}
+	if strings.EqualFold(name, closeFriendsListName) {
+		return "", ErrAudienceListNameTaken
+	}
+
+	taken, err := s.listRepo.NameExists(ctx, ownerID, name, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrAudienceListNameTaken
+	}
+	return name, nil
+}
+
+func validateListMembers(ownerID string, memberIDs []string) error {
+	for _, memberID := range memberIDs {
+		if !util.IsValidUUID(memberID) {
+			return ErrInvalidListMember
+		}
+		if memberID == ownerID {
+			return ErrCannotAddSelfToList
+		}
+	}
+	return nil
+}
+
+func mapAudienceListError(err error) error {
+	var notFound *repository.NotFoundError
+	if errors.As(err, &notFound) {
+		return ErrAudienceListNotFound
+	}
+	return err
+}
+
+func audienceListView(list *repository.AudienceList) *AudienceListView {
+	return &AudienceListView{
+		ID:          list.ID,
+		Name:        list.Name,
+		Kind:        list.Kind,
+		MemberCount: list.MemberCount,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+	}
+}
@@ -71,6 +71,239 @@ func (a *AuditLog) LogFailedLogin(emailOrUsername, ipAddress, reason string) {
 	go a.logEvent("failed_login", "", ipAddress, "", details)
 }
 
+// LogLoginBlocked logs a login attempt refused by lockout or a proof-of-work challenge
+func (a *AuditLog) LogLoginBlocked(emailOrUsername, ipAddress, reason string) {
+	details := map[string]interface{}{
+		"email_or_username": emailOrUsername,
+		"reason":            reason,
+	}
+	go a.logEvent("login_blocked", "", ipAddress, "", details)
+}
+
+// LogNewDeviceLogin logs a successful password login from a device the user has not used before
+func (a *AuditLog) LogNewDeviceLogin(userID, ipAddress, userAgent string) {
+	go a.logEvent("new_device_login", userID, ipAddress, userAgent, nil)
+}
+
+// LogTwoFactorChallenge logs a password login that is waiting for a second factor
+func (a *AuditLog) LogTwoFactorChallenge(userID, ipAddress, userAgent string) {
+	go a.logEvent("two_factor_challenge", userID, ipAddress, userAgent, nil)
+}
+
+// LogTwoFactorSuccess logs a login completed with a TOTP or backup code
+func (a *AuditLog) LogTwoFactorSuccess(userID, ipAddress, userAgent string) {
+	go a.logEvent("two_factor_success", userID, ipAddress, userAgent, nil)
+}
+
+// LogTwoFactorFailure logs a rejected 2FA code or a rate-limited attempt
+func (a *AuditLog) LogTwoFactorFailure(userID, ipAddress, reason string) {
+	details := map[string]interface{}{
+		"reason": reason,
+	}
+	go a.logEvent("two_factor_failure", userID, ipAddress, "", details)
+}
+
+// LogWebAuthnRegistration logs a newly registered passkey
+func (a *AuditLog) LogWebAuthnRegistration(userID, credentialID, ipAddress string) {
+	details := map[string]interface{}{
+		"credential_id": credentialID,
+	}
+	go a.logEvent("webauthn_registration", userID, ipAddress, "", details)
+}
+
+// LogWebAuthnAuthentication logs a successful passkey assertion
+func (a *AuditLog) LogWebAuthnAuthentication(userID, credentialID, ipAddress string) {
+	details := map[string]interface{}{
+		"credential_id": credentialID,
+	}
+	go a.logEvent("webauthn_authentication", userID, ipAddress, "", details)
+}
+
+// LogWebAuthnFailure logs a rejected registration or authentication ceremony
+func (a *AuditLog) LogWebAuthnFailure(userID, ceremony, ipAddress, reason string) {
+	details := map[string]interface{}{
+		"ceremony": ceremony,
+		"reason":   reason,
+	}
+	go a.logEvent("webauthn_failure", userID, ipAddress, "", details)
+}
+
+// LogWebAuthnRevocation logs a passkey removed by its owner
+func (a *AuditLog) LogWebAuthnRevocation(userID, credentialID, ipAddress string) {
+	details := map[string]interface{}{
+		"credential_id": credentialID,
+	}
+	go a.logEvent("webauthn_revocation", userID, ipAddress, "", details)
+}
+
+// LogRefreshTokenReuse logs a replayed refresh token and the revocation of its session
+func (a *AuditLog) LogRefreshTokenReuse(userID, sessionID, ipAddress string) {
+	details := map[string]interface{}{
+		"session_id": sessionID,
+	}
+	go a.logEvent("refresh_token_reuse", userID, ipAddress, "", details)
+}
+
+// LogSessionRevoked logs a user signing out one of their other sessions
+func (a *AuditLog) LogSessionRevoked(userID, sessionID, ipAddress string) {
+	details := map[string]interface{}{
+		"session_id": sessionID,
+	}
+	go a.logEvent("session_revoked", userID, ipAddress, "", details)
+}
+
+// LogOtherSessionsRevoked logs a user signing out everywhere except the current session
+func (a *AuditLog) LogOtherSessionsRevoked(userID, currentSessionID, ipAddress string, revoked int) {
+	details := map[string]interface{}{
+		"current_session_id": currentSessionID,
+		"revoked":            revoked,
+	}
+	go a.logEvent("other_sessions_revoked", userID, ipAddress, "", details)
+}
+
+// LogEmailVerified logs a user confirming their email address
+func (a *AuditLog) LogEmailVerified(userID, email, ipAddress string) {
+	details := map[string]interface{}{
+		"email": email,
+	}
+	go a.logEvent("email_verified", userID, ipAddress, "", details)
+}
+
+// LogEmailChangeRequested logs a confirmation link being sent to a new address
+func (a *AuditLog) LogEmailChangeRequested(userID, newEmail, ipAddress string) {
+	details := map[string]interface{}{
+		"new_email": newEmail,
+	}
+	go a.logEvent("email_change_requested", userID, ipAddress, "", details)
+}
+
+// LogEmailChanged logs a confirmed email change
+func (a *AuditLog) LogEmailChanged(userID, oldEmail, newEmail, ipAddress string) {
+	details := map[string]interface{}{
+		"old_email": oldEmail,
+		"new_email": newEmail,
+	}
+	go a.logEvent("email_changed", userID, ipAddress, "", details)
+}
+
+// LogEmailChangeUndone logs an email change reverted from the link sent to the old address
+func (a *AuditLog) LogEmailChangeUndone(userID, restoredEmail, replacedEmail, ipAddress string) {
+	details := map[string]interface{}{
+		"restored_email": restoredEmail,
+		"replaced_email": replacedEmail,
+	}
+	go a.logEvent("email_change_undone", userID, ipAddress, "", details)
+}
+
+// LogOAuthConsentGranted logs a user approving an OpenID Connect client
+func (a *AuditLog) LogOAuthConsentGranted(userID, clientID string, scopes []string, ipAddress string) {
+	details := map[string]interface{}{
+		"client_id": clientID,
+		"scopes":    scopes,
+	}
+	go a.logEvent("oauth_consent_granted", userID, ipAddress, "", details)
+}
+
+// LogOAuthConsentRevoked logs a user removing a client's access to their account
+func (a *AuditLog) LogOAuthConsentRevoked(userID, clientID, ipAddress string) {
+	details := map[string]interface{}{
+		"client_id": clientID,
+	}
+	go a.logEvent("oauth_consent_revoked", userID, ipAddress, "", details)
+}
+
+// LogOAuthClientRegistered logs a user registering an OpenID Connect client
+func (a *AuditLog) LogOAuthClientRegistered(userID, clientID, ipAddress string) {
+	details := map[string]interface{}{
+		"client_id": clientID,
+	}
+	go a.logEvent("oauth_client_registered", userID, ipAddress, "", details)
+}
+
+// LogOAuthClientDeleted logs a user deleting a client they registered
+func (a *AuditLog) LogOAuthClientDeleted(userID, clientID, ipAddress string) {
+	details := map[string]interface{}{
+		"client_id": clientID,
+	}
+	go a.logEvent("oauth_client_deleted", userID, ipAddress, "", details)
+}
+
+// LogIdentityLinked logs a user linking an account on the other platform
+func (a *AuditLog) LogIdentityLinked(userID, platform, platformUserID, ipAddress string) {
+	details := map[string]interface{}{
+		"platform":         platform,
+		"platform_user_id": platformUserID,
+	}
+	go a.logEvent("identity_linked", userID, ipAddress, "", details)
+}
+
+// LogIdentityUnlinked logs a user removing a linked account
+func (a *AuditLog) LogIdentityUnlinked(userID, platform, platformUserID, ipAddress string) {
+	details := map[string]interface{}{
+		"platform":         platform,
+		"platform_user_id": platformUserID,
+	}
+	go a.logEvent("identity_unlinked", userID, ipAddress, "", details)
+}
+
+// LogAccountMerged logs an account being merged into the user's and what moved
+func (a *AuditLog) LogAccountMerged(userID, sourceUserID string, moved map[string]int64, ipAddress string) {
+	details := map[string]interface{}{
+		"source_user_id": sourceUserID,
+		"moved":          moved,
+	}
+	go a.logEvent("account_merged", userID, ipAddress, "", details)
+}
+
+// LogDataExportRequested logs a user asking for a copy of their data
+func (a *AuditLog) LogDataExportRequested(userID, exportID, ipAddress string) {
+	details := map[string]interface{}{
+		"export_id": exportID,
+	}
+	go a.logEvent("data_export_requested", userID, ipAddress, "", details)
+}
+
+// LogDataExportDownloaded logs a data export archive being downloaded
+func (a *AuditLog) LogDataExportDownloaded(userID, exportID, ipAddress string) {
+	details := map[string]interface{}{
+		"export_id": exportID,
+	}
+	go a.logEvent("data_export_downloaded", userID, ipAddress, "", details)
+}
+
+// LogAccountDeletionScheduled logs a user asking for their account to be deleted
+func (a *AuditLog) LogAccountDeletionScheduled(userID, deletionID, ipAddress string) {
+	details := map[string]interface{}{
+		"deletion_id": deletionID,
+	}
+	go a.logEvent("account_deletion_scheduled", userID, ipAddress, "", details)
+}
+
+// LogAccountDeletionCancelled logs a scheduled deletion being cancelled by the user logging in
+func (a *AuditLog) LogAccountDeletionCancelled(userID, deletionID, ipAddress string) {
+	details := map[string]interface{}{
+		"deletion_id": deletionID,
+	}
+	go a.logEvent("account_deletion_cancelled", userID, ipAddress, "", details)
+}
+
+// LogAccountPurged logs every service having confirmed that a deleted user's data is gone
+func (a *AuditLog) LogAccountPurged(userID, deletionID string) {
+	details := map[string]interface{}{
+		"deletion_id": deletionID,
+	}
+	go a.logEvent("account_purged", userID, "", "", details)
+}
+
+// LogUsernameChanged logs a user changing their username
+func (a *AuditLog) LogUsernameChanged(userID, oldUsername, newUsername, ipAddress string) {
+	details := map[string]interface{}{
+		"old_username": oldUsername,
+		"new_username": newUsername,
+	}
+	go a.logEvent("username_changed", userID, ipAddress, "", details)
+}
+
 // logEvent logs an event to the database
 func (a *AuditLog) logEvent(action, userID, ipAddress, userAgent string, details map[string]interface{}) {
 	// For now, just log to console
@@ -1,270 +1,376 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
-	"socialink/user-service/internal/config"
-	"socialink/user-service/internal/model"
-	"socialink/user-service/internal/repository"
-	"socialink/user-service/internal/util"
-
-	"github.com/google/uuid"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+	"user-service/pkg/webauthn"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email/username or password")
-	ErrUserNotActive      = errors.New("user account is not active")
-	ErrInvalidBirthday    = errors.New("invalid birthday - must be at least 13 years old")
+	ErrInvalidCredentials    = errors.New("invalid email/username or password")
+	ErrUserNotActive         = errors.New("user account is not active")
+	ErrInvalidChallenge      = errors.New("invalid or expired login challenge")
+	ErrTooManyTwoFactorTries = errors.New("too many 2FA attempts, please log in again later")
+)
+
+const (
+	// loginChallengeTTL is how long the user has to enter a code after the password step
+	loginChallengeTTL = 5 * time.Minute
+	// maxChallengeAttempts is the number of wrong codes allowed per challenge
+	maxChallengeAttempts = 5
+	// maxTwoFactorFailures caps wrong codes per account across challenges within twoFactorFailureWindow
+	maxTwoFactorFailures   = 10
+	twoFactorFailureWindow = 15 * time.Minute
 )
 
+// LoginResult is the outcome of a login step. Exactly one of Tokens and
+// Challenge is set: with a second factor set up, the password only earns a
+// challenge, and the session comes from completing it.
+type LoginResult struct {
+	User      *repository.User
+	Tokens    *SessionTokens
+	Restored  bool // the login cancelled a pending account deletion
+	Challenge *PendingLogin
+}
+
+// PendingLogin is returned to the client when a second factor is required
+type PendingLogin struct {
+	Token     string
+	Methods   []string // "totp", "webauthn"
+	ExpiresIn int
+}
+
+// AuthService authenticates users and issues their sessions
 type AuthService struct {
-	userRepo    *repository.UserRepository
-	sessionRepo *repository.SessionRepository
-	config      *config.Config
+	userRepo         *repository.UserRepository
+	challengeRepo    *repository.LoginChallengeRepository
+	sessionService   *SessionService
+	twoFactorService *TwoFactorService
+	webAuthnService  *WebAuthnService
+	loginProtection  *LoginProtectionService
+	deletions        *AccountDeletionService
+	auditLog         *AuditLog
 }
 
-func NewAuthService(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, cfg *config.Config) *AuthService {
+// NewAuthService creates a new auth service
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	challengeRepo *repository.LoginChallengeRepository,
+	sessionService *SessionService,
+	twoFactorService *TwoFactorService,
+	webAuthnService *WebAuthnService,
+	loginProtection *LoginProtectionService,
+	deletions *AccountDeletionService,
+	auditLog *AuditLog,
+) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		config:      cfg,
+		userRepo:         userRepo,
+		challengeRepo:    challengeRepo,
+		sessionService:   sessionService,
+		twoFactorService: twoFactorService,
+		webAuthnService:  webAuthnService,
+		loginProtection:  loginProtection,
+		deletions:        deletions,
+		auditLog:         auditLog,
 	}
 }
 
-// Signup registers a new user (Meta-level: instant access, no verification)
-// Relaxed name policy: We don't require legal names but recommend them
-func (s *AuthService) Signup(req *model.SignupRequest, ipAddress, userAgent string) (*model.AuthResponse, error) {
-	var recommendations []string
-	
-	// Validate email
-	if !util.IsValidEmail(req.Email) {
-		return nil, fmt.Errorf("invalid email format")
+// Login checks an email/username and password.
+// Attempts may be refused with a *LoginLockedError or a *ProofOfWorkRequiredError
+// before the password is checked; see LoginProtectionService.
+func (s *AuthService) Login(ctx context.Context, login *PasswordLogin, ipAddress, userAgent string) (*LoginResult, error) {
+	// Lookup errors are treated as an unknown account so they don't bypass the lockout
+	user, err := s.userRepo.FindByEmailOrUsername(ctx, login.EmailOrUsername)
+	if err != nil {
+		user = nil
 	}
 
-	// Check if email exists
-	emailExists, err := s.userRepo.EmailExists(req.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check email: %w", err)
+	accountKey := loginAccountKey(user, login.EmailOrUsername)
+	if err := s.loginProtection.CheckAttempt(ctx, accountKey, login, ipAddress); err != nil {
+		return nil, err
 	}
-	if emailExists {
-		return nil, repository.ErrEmailExists
+
+	if user == nil {
+		if err := s.loginProtection.RecordFailure(ctx, accountKey, nil, login, ipAddress, userAgent, "user_not_found"); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	// Validate display names (relaxed policy)
-	if valid, msg := util.ValidateDisplayName(req.FirstName); !valid {
-		return nil, fmt.Errorf("first name: %s", msg)
+	if !user.IsActive {
+		return nil, ErrUserNotActive
 	}
-	if valid, msg := util.ValidateDisplayName(req.LastName); !valid {
-		return nil, fmt.Errorf("last name: %s", msg)
+
+	if !util.ComparePassword(user.PasswordHash, login.Password) {
+		if err := s.loginProtection.RecordFailure(ctx, accountKey, user, login, ipAddress, userAgent, "invalid_password"); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	// Check if names look real (recommendation only, not enforced)
-	if isReal, suggestion := util.IsLikelyRealName(req.FirstName, req.LastName); !isReal {
-		recommendations = append(recommendations, suggestion)
+	// A correct password resets the failure count, even if a second factor is still due
+	if err := s.loginProtection.RecordSuccess(ctx, user, ipAddress, userAgent); err != nil {
+		return nil, err
 	}
 
-	// Parse birthday
-	birthday, err := time.Parse("2006-01-02", req.Birthday)
+	methods, err := s.secondFactorMethods(ctx, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid birthday format, use YYYY-MM-DD")
+		return nil, err
 	}
+	if len(methods) > 0 {
+		return s.createLoginChallenge(ctx, user, methods, ipAddress, userAgent)
+	}
+
+	return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
 
-	// Validate birthday (must be at least 13 years old)
-	if !util.IsValidBirthday(birthday) {
-		return nil, ErrInvalidBirthday
+// LoginWithPlatform signs in a user whose token the other platform has vouched for.
+// The token stands in for the password only: the lockout and proof-of-work checks
+// still apply, and a second factor is still required when one is set up.
+func (s *AuthService) LoginWithPlatform(ctx context.Context, user *repository.User, platform, powChallenge, powSolution, ipAddress, userAgent string) (*LoginResult, error) {
+	login := &PasswordLogin{
+		EmailOrUsername: user.Email,
+		PowChallenge:    powChallenge,
+		PowSolution:     powSolution,
+	}
+	if err := s.loginProtection.CheckAttempt(ctx, loginAccountKey(user, ""), login, ipAddress); err != nil {
+		return nil, err
 	}
 
-	// Validate password
-	if !util.ValidatePassword(req.Password) {
-		return nil, fmt.Errorf("password must be at least 8 characters")
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+
+	if err := s.loginProtection.RecordSuccess(ctx, user, ipAddress, userAgent); err != nil {
+		return nil, err
 	}
 
-	// Hash password
-	hashedPassword, err := util.HashPassword(req.Password)
+	s.auditLog.LogCrossPlatformSignIn(user.ID, platform, ipAddress)
+
+	methods, err := s.secondFactorMethods(ctx, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, err
+	}
+	if len(methods) > 0 {
+		return s.createLoginChallenge(ctx, user, methods, ipAddress, userAgent)
 	}
 
-	// Generate unique username (firstname.lastname format for clean URLs)
-	username := util.GenerateUsername(req.FirstName, req.LastName)
-	
-	// Ensure username is unique, add suffix if needed
-	exists, err := s.userRepo.UsernameExists(username)
+	return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or backup code for a session
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code, ipAddress, userAgent string) (*LoginResult, error) {
+	challenge, err := s.pendingChallenge(ctx, challengeToken, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check username: %w", err)
-	}
-	if exists {
-		// Username taken, try with suffix
-		maxAttempts := 100
-		for i := 1; i <= maxAttempts; i++ {
-			username = util.GenerateUniqueUsername(req.FirstName, req.LastName)
-			exists, err := s.userRepo.UsernameExists(username)
-			if err != nil {
-				return nil, fmt.Errorf("failed to check username: %w", err)
-			}
-			if !exists {
-				break
-			}
-			if i == maxAttempts {
-				return nil, fmt.Errorf("unable to generate unique username, please try different names")
-			}
+		return nil, err
+	}
+
+	valid, _ := s.twoFactorService.VerifyCode(ctx, challenge.UserID, code)
+	if !valid {
+		if err := s.recordFailedAttempt(ctx, challenge, ipAddress, "invalid_code"); err != nil {
+			return nil, err
 		}
+		return nil, ErrInvalidTwoFactorCode
 	}
 
-	// Create user
-	user := &model.User{
-		ID:        uuid.New(),
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-		Username:  username, // Clean format: firstname.lastname or firstname.lastname123
-		Password:  hashedPassword,
-		Birthday:  birthday,
-		Gender:    req.Gender,
-		IsActive:  true, // Instantly active - Meta-level approach
-		IsDeleted: false,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	return s.completeChallenge(ctx, challenge, ipAddress, userAgent)
+}
+
+// LoginWithPasskey completes a passwordless login with a user-verified passkey
+func (s *AuthService) LoginWithPasskey(ctx context.Context, ceremonyID string, resp *webauthn.AssertionResponse, ipAddress, userAgent string) (*LoginResult, error) {
+	credential, err := s.webAuthnService.FinishLogin(ctx, ceremonyID, resp, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, credential.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+
+	return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
 
-	err = s.userRepo.Create(user)
+// BeginTwoFactorPasskey starts a passkey assertion as the second factor of a pending login
+func (s *AuthService) BeginTwoFactorPasskey(ctx context.Context, challengeToken, ipAddress string) (*WebAuthnLoginOptions, error) {
+	challenge, err := s.pendingChallenge(ctx, challengeToken, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
-	}
-
-	// Generate JWT token
-	accessToken, err := util.GenerateToken(
-		user.ID,
-		user.Email,
-		user.Username,
-		user.FirstName,
-		user.LastName,
-		s.config.JWT.SecretKey,
-		s.config.JWT.AccessTokenTTL,
-	)
+		return nil, err
+	}
+
+	return s.webAuthnService.BeginLogin(ctx, &challenge.UserID)
+}
+
+// CompleteTwoFactorPasskeyLogin exchanges a login challenge and a passkey assertion for a session
+func (s *AuthService) CompleteTwoFactorPasskeyLogin(ctx context.Context, challengeToken, ceremonyID string, resp *webauthn.AssertionResponse, ipAddress, userAgent string) (*LoginResult, error) {
+	challenge, err := s.pendingChallenge(ctx, challengeToken, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	// Create session
-	session := &model.Session{
-		ID:           uuid.New(),
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: uuid.New().String(), // Simple refresh token for now
-		DeviceInfo:   "Web",
-		IPAddress:    ipAddress,
-		UserAgent:    userAgent,
-		ExpiresAt:    time.Now().Add(s.config.JWT.AccessTokenTTL),
-		CreatedAt:    time.Now(),
-		LastActiveAt: time.Now(),
+	credential, verifyErr := s.webAuthnService.FinishLogin(ctx, ceremonyID, resp, ipAddress)
+	if verifyErr == nil && credential.UserID != challenge.UserID {
+		// A valid passkey, but for another account
+		verifyErr = ErrWebAuthnCredential
+	}
+	if verifyErr != nil {
+		if err := s.recordFailedAttempt(ctx, challenge, ipAddress, "invalid_passkey"); err != nil {
+			return nil, err
+		}
+		return nil, verifyErr
 	}
 
-	err = s.sessionRepo.Create(session)
+	return s.completeChallenge(ctx, challenge, ipAddress, userAgent)
+}
+
+// secondFactorMethods lists the second factors a user has set up
+func (s *AuthService) secondFactorMethods(ctx context.Context, userID string) ([]string, error) {
+	var methods []string
+
+	totpEnabled, err := s.twoFactorService.IsEnabled(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		return nil, err
+	}
+	if totpEnabled {
+		methods = append(methods, "totp")
 	}
 
-	// Update last login
-	_ = s.userRepo.UpdateLastLogin(user.ID)
+	hasPasskeys, err := s.webAuthnService.HasCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hasPasskeys {
+		methods = append(methods, "webauthn")
+	}
 
-	response := &model.AuthResponse{
-		User:        user.ToUserResponse(),
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(s.config.JWT.AccessTokenTTL.Seconds()),
+	return methods, nil
+}
+
+// pendingChallenge loads a login challenge and enforces the 2FA attempt limits
+func (s *AuthService) pendingChallenge(ctx context.Context, challengeToken, ipAddress string) (*repository.LoginChallenge, error) {
+	challenge, err := s.challengeRepo.FindByTokenHash(ctx, util.HashRefreshToken(challengeToken))
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
 	}
 
-	// Add recommendations if any (these are shown to user but don't block signup)
-	if len(recommendations) > 0 {
-		// Store recommendations in response for client to display
-		// Client can show these as suggestions without blocking
+	// Rate limit per challenge and per account, before the code is even looked at
+	if challenge.Attempts >= maxChallengeAttempts {
+		s.auditLog.LogTwoFactorFailure(challenge.UserID, ipAddress, "challenge_attempts_exceeded")
+		return nil, ErrTooManyTwoFactorTries
+	}
+	failures, err := s.challengeRepo.CountRecentFailedAttempts(ctx, challenge.UserID, time.Now().Add(-twoFactorFailureWindow))
+	if err != nil {
+		return nil, err
+	}
+	if failures >= maxTwoFactorFailures {
+		s.auditLog.LogTwoFactorFailure(challenge.UserID, ipAddress, "account_attempts_exceeded")
+		return nil, ErrTooManyTwoFactorTries
 	}
 
-	return response, nil
+	return challenge, nil
 }
 
-// Login authenticates a user with email/username and password
-func (s *AuthService) Login(req *model.LoginRequest, ipAddress, userAgent string) (*model.AuthResponse, error) {
-	// Find user by email or username
-	var user *model.User
-	var err error
+// recordFailedAttempt counts a wrong second factor against the challenge
+func (s *AuthService) recordFailedAttempt(ctx context.Context, challenge *repository.LoginChallenge, ipAddress, reason string) error {
+	if _, err := s.challengeRepo.IncrementAttempts(ctx, challenge.ID); err != nil {
+		return err
+	}
+	s.auditLog.LogTwoFactorFailure(challenge.UserID, ipAddress, reason)
+	return nil
+}
 
-	if util.IsValidEmail(req.EmailOrUsername) {
-		user, err = s.userRepo.FindByEmail(req.EmailOrUsername)
-	} else {
-		user, err = s.userRepo.FindByUsername(req.EmailOrUsername)
+// completeChallenge consumes a challenge whose second factor was verified and issues the session
+func (s *AuthService) completeChallenge(ctx context.Context, challenge *repository.LoginChallenge, ipAddress, userAgent string) (*LoginResult, error) {
+	// A challenge is single use, even if two requests carry a valid code at once
+	consumed, err := s.challengeRepo.Consume(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidChallenge
 	}
 
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
 	if err != nil {
-		if errors.Is(err, repository.ErrUserNotFound) {
-			return nil, ErrInvalidCredentials
-		}
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
-
-	// Check if user is active
 	if !user.IsActive {
 		return nil, ErrUserNotActive
 	}
 
-	// Verify password
-	if !util.CheckPassword(req.Password, user.Password) {
-		return nil, ErrInvalidCredentials
-	}
+	s.auditLog.LogTwoFactorSuccess(user.ID, ipAddress, userAgent)
+
+	return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
 
-	// Generate JWT token
-	accessToken, err := util.GenerateToken(
-		user.ID,
-		user.Email,
-		user.Username,
-		user.FirstName,
-		user.LastName,
-		s.config.JWT.SecretKey,
-		s.config.JWT.AccessTokenTTL,
-	)
+// createLoginChallenge records a pending 2FA login and returns its token to the client
+func (s *AuthService) createLoginChallenge(ctx context.Context, user *repository.User, methods []string, ipAddress, userAgent string) (*LoginResult, error) {
+	// Same shape and entropy as a refresh token, stored hashed the same way
+	token, err := util.GenerateRefreshToken()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	// Create session
-	session := &model.Session{
-		ID:           uuid.New(),
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: uuid.New().String(),
-		DeviceInfo:   "Web",
-		IPAddress:    ipAddress,
-		UserAgent:    userAgent,
-		ExpiresAt:    time.Now().Add(s.config.JWT.AccessTokenTTL),
-		CreatedAt:    time.Now(),
-		LastActiveAt: time.Now(),
+	now := time.Now()
+	challenge := &repository.LoginChallenge{
+		ID:        util.GenerateUUID(),
+		UserID:    user.ID,
+		TokenHash: util.HashRefreshToken(token),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(loginChallengeTTL),
+		CreatedAt: now,
 	}
 
-	err = s.sessionRepo.Create(session)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+	if err := s.challengeRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
 	}
 
-	// Update last login
-	_ = s.userRepo.UpdateLastLogin(user.ID)
+	s.auditLog.LogTwoFactorChallenge(user.ID, ipAddress, userAgent)
 
-	return &model.AuthResponse{
-		User:        user.ToUserResponse(),
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(s.config.JWT.AccessTokenTTL.Seconds()),
+	return &LoginResult{
+		Challenge: &PendingLogin{
+			Token:     token,
+			Methods:   methods,
+			ExpiresIn: int(loginChallengeTTL.Seconds()),
+		},
 	}, nil
 }
 
-// Logout logs out a user by deleting their sessions
-func (s *AuthService) Logout(userID uuid.UUID) error {
-	return s.sessionRepo.DeleteByUserID(userID)
-}
+// completeLogin runs once the user is fully authenticated: it keeps an account
+// that was scheduled for deletion and issues the session
+func (s *AuthService) completeLogin(ctx context.Context, user *repository.User, ipAddress, userAgent string) (*LoginResult, error) {
+	// Logging in during the deletion grace period keeps the account
+	restored, err := s.deletions.CancelOnLogin(ctx, user, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	tokens, err := s.sessionService.IssueSession(ctx, user, ipAddress, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Last login is informational; a failure here shouldn't fail the login
+	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
 
-// ValidateToken validates a JWT token and returns user claims
-func (s *AuthService) ValidateToken(token string) (*util.JWTClaims, error) {
-	return util.ValidateToken(token, s.config.JWT.SecretKey)
+	s.auditLog.LogLogin(user.ID, ipAddress, userAgent)
+
+	return &LoginResult{
+		User:     user,
+		Tokens:   tokens,
+		Restored: restored,
+	}, nil
 }
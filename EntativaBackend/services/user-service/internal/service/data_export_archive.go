@@ -0,0 +1,281 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"user-service/internal/repository"
+)
+
+// exportSection is one part of a data export, in the order it is collected and shown
+type exportSection struct {
+	Name  string
+	Title string
+}
+
+// exportSections lists everything a data export contains. Sections held by this
+// service are read in one query; the rest are paged from the post service.
+var exportSections = []exportSection{
+	{"account", "Account"},
+	{"profile", "Profile"},
+	{"connections", "Friends and Followers"},
+	{"login_activity", "Login Activity"},
+	{"settings", "Settings"},
+	{"linked_accounts", "Linked Accounts"},
+	{"takes", "Takes"},
+	{"posts", "Posts"},
+	{"comments", "Comments"},
+	{"likes", "Likes"},
+	{"saves", "Saves"},
+}
+
+// MediaReference points at a photo, video or audio file mentioned in exported data.
+// Archives list media in a manifest per section instead of embedding the files,
+// which keeps them small enough to build and download in one go.
+type MediaReference struct {
+	ItemID  string `json:"item_id,omitempty"`
+	Field   string `json:"field"`
+	URL     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+}
+
+// Fields that hold media URLs or media service IDs
+var (
+	mediaURLFields = map[string]bool{
+		"profile_picture_url": true,
+		"cover_photo_url":     true,
+		"avatar_url":          true,
+		"video_url":           true,
+		"thumbnail_url":       true,
+		"audio_url":           true,
+		"featured_photos":     true,
+	}
+	mediaIDFields = map[string]bool{
+		"media_id":  true,
+		"media_ids": true,
+	}
+)
+
+type archiveSection struct {
+	exportSection
+	Data       interface{}
+	ItemCount  int // -1 when the section is not a list
+	MediaCount int
+}
+
+// writeExportArchive packages the collected parts of an export as a zip holding,
+// for each section, the data as JSON, a browsable HTML page and a media
+// manifest, plus an index.html linking them together
+func writeExportArchive(w io.Writer, user *repository.User, parts []*repository.DataExportPart, generatedAt time.Time) error {
+	pages := make(map[string][]*repository.DataExportPart)
+	for _, part := range parts {
+		pages[part.Section] = append(pages[part.Section], part)
+	}
+
+	archive := zip.NewWriter(w)
+	var sections []*archiveSection
+
+	for _, section := range exportSections {
+		content, err := joinExportPages(pages[section.Name])
+		if err != nil {
+			return fmt.Errorf("failed to assemble %s: %w", section.Name, err)
+		}
+
+		var data interface{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", section.Name, err)
+		}
+
+		entry := &archiveSection{exportSection: section, Data: data, ItemCount: -1}
+		if items, ok := data.([]interface{}); ok {
+			entry.ItemCount = len(items)
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, content, "", "  "); err != nil {
+			return fmt.Errorf("failed to format %s: %w", section.Name, err)
+		}
+		if err := writeArchiveFile(archive, section.Name+"/"+section.Name+".json", indented.Bytes()); err != nil {
+			return err
+		}
+
+		media := collectMedia(data, "", "", nil)
+		entry.MediaCount = len(media)
+		if len(media) > 0 {
+			manifest, _ := json.MarshalIndent(media, "", "  ")
+			if err := writeArchiveFile(archive, section.Name+"/media_manifest.json", manifest); err != nil {
+				return err
+			}
+		}
+
+		var page bytes.Buffer
+		if err := exportSectionTemplate.Execute(&page, entry); err != nil {
+			return fmt.Errorf("failed to render %s: %w", section.Name, err)
+		}
+		if err := writeArchiveFile(archive, section.Name+"/"+section.Name+".html", page.Bytes()); err != nil {
+			return err
+		}
+
+		sections = append(sections, entry)
+	}
+
+	var index bytes.Buffer
+	err := exportIndexTemplate.Execute(&index, struct {
+		Name        string
+		Username    string
+		GeneratedAt string
+		Sections    []*archiveSection
+	}{
+		Name:        user.FirstName + " " + user.LastName,
+		Username:    user.Username,
+		GeneratedAt: generatedAt.UTC().Format("January 2, 2006 at 15:04 MST"),
+		Sections:    sections,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render index: %w", err)
+	}
+	if err := writeArchiveFile(archive, "index.html", index.Bytes()); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// joinExportPages turns the stored pages of a section back into one JSON document.
+// Sections collected in one query have a single page; paged sections are arrays
+// that are concatenated in page order.
+func joinExportPages(pages []*repository.DataExportPart) (json.RawMessage, error) {
+	if len(pages) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	if len(pages) == 1 {
+		return pages[0].Content, nil
+	}
+
+	var items []json.RawMessage
+	for _, page := range pages {
+		var pageItems []json.RawMessage
+		if err := json.Unmarshal(page.Content, &pageItems); err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+	}
+	return json.Marshal(items)
+}
+
+// collectMedia walks exported data for media URLs and IDs, attributing each to
+// the id of the nearest enclosing record
+func collectMedia(value interface{}, itemID, field string, refs []MediaReference) []MediaReference {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if id, ok := v["id"].(string); ok {
+			itemID = id
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			refs = collectMedia(v[key], itemID, key, refs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			refs = collectMedia(child, itemID, field, refs)
+		}
+	case string:
+		if v == "" {
+			break
+		}
+		if mediaURLFields[field] {
+			refs = append(refs, MediaReference{ItemID: itemID, Field: field, URL: v})
+		} else if mediaIDFields[field] {
+			refs = append(refs, MediaReference{ItemID: itemID, Field: field, MediaID: v})
+		}
+	}
+	return refs
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+var exportTemplateFuncs = template.FuncMap{
+	"isMap": func(v interface{}) bool {
+		_, ok := v.(map[string]interface{})
+		return ok
+	},
+	"isList": func(v interface{}) bool {
+		_, ok := v.([]interface{})
+		return ok
+	},
+	"isNil": func(v interface{}) bool {
+		return v == nil
+	},
+}
+
+const exportPageStyle = `
+<style>
+    body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; color: #1c1e21; max-width: 960px; margin: 0 auto; padding: 20px; }
+    h1 { font-style: italic; color: #007CFC; }
+    table { border-collapse: collapse; width: 100%; margin: 4px 0; }
+    th, td { border: 1px solid #e4e6eb; padding: 6px 8px; text-align: left; vertical-align: top; }
+    th { background: #f0f2f5; width: 200px; font-weight: 600; }
+    ol { padding-left: 20px; margin: 0; }
+    li { margin-bottom: 8px; }
+    .empty { color: #65676b; }
+    .meta { color: #65676b; font-size: 14px; }
+</style>
+`
+
+var exportSectionTemplate = template.Must(template.New("section").Funcs(exportTemplateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>{{.Title}} - Your Entativa Information</title>` + exportPageStyle + `</head>
+<body>
+<p><a href="../index.html">&larr; Your Entativa Information</a></p>
+<h2>{{.Title}}</h2>
+<p class="meta"><a href="{{.Name}}.json">{{.Name}}.json</a>{{if .MediaCount}} &middot; <a href="media_manifest.json">media_manifest.json</a> ({{.MediaCount}} files){{end}}</p>
+{{template "value" .Data}}
+</body>
+</html>
+{{define "value"}}{{if isMap .}}<table>{{range $key, $value := .}}<tr><th>{{$key}}</th><td>{{template "value" $value}}</td></tr>{{end}}</table>{{else if isList .}}{{if .}}<ol>{{range .}}<li>{{template "value" .}}</li>{{end}}</ol>{{else}}<span class="empty">None</span>{{end}}{{else if isNil .}}<span class="empty">&mdash;</span>{{else}}{{.}}{{end}}{{end}}`))
+
+var exportIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Your Entativa Information</title>` + exportPageStyle + `</head>
+<body>
+<h1>entativa</h1>
+<h2>Your Information</h2>
+<p>{{.Name}} (@{{.Username}})</p>
+<p class="meta">Generated {{.GeneratedAt}}. Each section is included as a page you can read here and as JSON.
+Photos, videos and audio are listed in each section's media manifest rather than included in this file.</p>
+<table>
+<tr><th>Section</th><th>Items</th><th>Files</th></tr>
+{{range .Sections}}<tr>
+<td><a href="{{.Name}}/{{.Name}}.html">{{.Title}}</a></td>
+<td>{{if ge .ItemCount 0}}{{.ItemCount}}{{else}}&mdash;{{end}}</td>
+<td><a href="{{.Name}}/{{.Name}}.json">JSON</a>{{if .MediaCount}} &middot; <a href="{{.Name}}/media_manifest.json">{{.MediaCount}} media</a>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
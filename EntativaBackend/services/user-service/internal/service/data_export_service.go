@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+// dataExportTokenType is the "typ" header of signed download links, so they can
+// never be mistaken for access tokens (which also carry no user_id or sid)
+const dataExportTokenType = "export+jwt"
+
+var (
+	ErrDataExportNotFound    = errors.New("data export not found")
+	ErrDataExportTooSoon     = errors.New("you recently requested a copy of your information; try again later")
+	ErrDataExportLinkInvalid = errors.New("this download link is invalid or has expired")
+)
+
+// DataExportView is a data export as shown to its owner
+type DataExportView struct {
+	ID                   string     `json:"id"`
+	Status               string     `json:"status"`
+	CompletedSections    []string   `json:"completed_sections"`
+	TotalSections        int        `json:"total_sections"`
+	ArchiveSize          int64      `json:"archive_size,omitempty"`
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+	RequestedAt          time.Time  `json:"requested_at"`
+	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+}
+
+// DataExportDownload is an archive being streamed to its owner
+type DataExportDownload struct {
+	Filename string
+	Size     int64
+	Body     io.ReadCloser
+}
+
+type dataExportClaims struct {
+	ExportID string `json:"export_id"`
+	jwt.RegisteredClaims
+}
+
+// DataExportService builds "Download Your Information" archives.
+//
+// Requests are queued and built by background workers. A worker leases the job
+// it claims and saves each section (or page of a section) as it goes, so if it
+// crashes another worker takes over when the lease runs out and carries on from
+// the last saved page instead of starting again. Finished archives are kept for
+// a few days; the owner is emailed a signed link that stops working when the
+// archive expires.
+type DataExportService struct {
+	exportRepo   *repository.DataExportRepository
+	userRepo     *repository.UserRepository
+	postExports  *PostExportClient
+	storage      ExportStorage
+	emailService *EmailService
+	auditLog     *AuditLog
+	config       *config.Config
+	workerID     string
+}
+
+// NewDataExportService creates a new data export service
+func NewDataExportService(
+	exportRepo *repository.DataExportRepository,
+	userRepo *repository.UserRepository,
+	postExports *PostExportClient,
+	storage ExportStorage,
+	emailService *EmailService,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *DataExportService {
+	hostname, _ := os.Hostname()
+	return &DataExportService{
+		exportRepo:   exportRepo,
+		userRepo:     userRepo,
+		postExports:  postExports,
+		storage:      storage,
+		emailService: emailService,
+		auditLog:     auditLog,
+		config:       cfg,
+		workerID:     fmt.Sprintf("%s-%s", hostname, util.GenerateUUID()[:8]),
+	}
+}
+
+// RequestExport queues an export of the user's data. If one is already queued or
+// being built it is returned instead of starting another.
+func (s *DataExportService) RequestExport(ctx context.Context, user *repository.User, ipAddress string) (*DataExportView, error) {
+	latest, err := s.exportRepo.FindLatestExport(ctx, user.ID)
+	var notFound *repository.NotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, err
+	}
+	if latest != nil {
+		if latest.Status == repository.DataExportPending || latest.Status == repository.DataExportRunning {
+			return s.view(latest)
+		}
+		if latest.Status != repository.DataExportFailed && time.Since(latest.CreatedAt) < s.config.DataExport.RequestCooldown {
+			return nil, ErrDataExportTooSoon
+		}
+	}
+
+	export := &repository.DataExport{
+		ID:          util.GenerateUUID(),
+		UserID:      user.ID,
+		Status:      repository.DataExportPending,
+		RequestedIP: ipAddress,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.exportRepo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogDataExportRequested(user.ID, export.ID, ipAddress)
+	return s.view(export)
+}
+
+// GetExport returns one of the user's exports, with a fresh download link if it is ready
+func (s *DataExportService) GetExport(ctx context.Context, userID, exportID string) (*DataExportView, error) {
+	export, err := s.exportRepo.GetExport(ctx, exportID, userID)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	return s.view(export)
+}
+
+// ListExports lists the user's recent exports
+func (s *DataExportService) ListExports(ctx context.Context, userID string) ([]*DataExportView, error) {
+	exports, err := s.exportRepo.ListExports(ctx, userID, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*DataExportView, 0, len(exports))
+	for _, export := range exports {
+		view, err := s.view(export)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// OpenDownload checks a signed download link and opens the archive it points to
+func (s *DataExportService) OpenDownload(ctx context.Context, token, ipAddress string) (*DataExportDownload, error) {
+	claims := &dataExportClaims{}
+	tokenType, err := util.ParseSignedToken(token, claims)
+	if err != nil || tokenType != dataExportTokenType || claims.ExportID == "" || claims.Subject == "" {
+		return nil, ErrDataExportLinkInvalid
+	}
+
+	export, err := s.exportRepo.GetExport(ctx, claims.ExportID, claims.Subject)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, ErrDataExportLinkInvalid
+		}
+		return nil, err
+	}
+	if export.Status != repository.DataExportReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrDataExportLinkInvalid
+	}
+
+	body, err := s.storage.Open(export.ArchiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export archive: %w", err)
+	}
+
+	if err := s.exportRepo.RecordDownload(ctx, export.ID); err != nil {
+		log.Printf("Failed to record download of export %s: %v", export.ID, err)
+	}
+	s.auditLog.LogDataExportDownloaded(export.UserID, export.ID, ipAddress)
+
+	return &DataExportDownload{
+		Filename: fmt.Sprintf("entativa-information-%s.zip", export.CreatedAt.UTC().Format("2006-01-02")),
+		Size:     export.ArchiveSize,
+		Body:     body,
+	}, nil
+}
+
+// RunWorker builds queued exports until ctx is cancelled
+func (s *DataExportService) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.config.DataExport.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && s.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireExports deletes the archives of exports past their expiry
+func (s *DataExportService) ExpireExports(ctx context.Context) error {
+	expired, err := s.exportRepo.ExpireExports(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range expired {
+		if err := s.storage.Delete(export.ArchiveKey); err != nil {
+			log.Printf("Failed to delete archive of expired export %s: %v", export.ID, err)
+		}
+	}
+	return nil
+}
+
+// processNext claims one export and builds it, reporting whether to look for another straight away
+func (s *DataExportService) processNext(ctx context.Context) bool {
+	export, err := s.exportRepo.ClaimNextExport(ctx, s.workerID, s.config.DataExport.LeaseDuration)
+	if err != nil {
+		log.Printf("Failed to claim data export: %v", err)
+		return false
+	}
+	if export == nil {
+		return false
+	}
+
+	err = s.buildExport(ctx, export)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, repository.ErrExportLeaseLost) {
+		log.Printf("Data export %s was taken over by another worker", export.ID)
+		return true
+	}
+
+	// Shutting down is not the export's fault; hand it back for another worker
+	final := ctx.Err() == nil && export.Attempts >= s.config.DataExport.MaxAttempts
+	log.Printf("Data export %s attempt %d failed: %v", export.ID, export.Attempts, err)
+
+	if err := s.exportRepo.ReleaseExport(context.Background(), export.ID, s.workerID, err.Error(), final); err != nil {
+		log.Printf("Failed to release data export %s: %v", export.ID, err)
+		return false
+	}
+
+	if final {
+		if user, err := s.userRepo.FindByID(context.Background(), export.UserID); err == nil {
+			if err := s.emailService.SendDataExportFailedEmail(user.Email, user.FirstName); err != nil {
+				log.Printf("Failed to send data export failure email: %v", err)
+			}
+		}
+	}
+
+	// Wait for the next tick before retrying, rather than spinning through the attempts
+	return false
+}
+
+// buildExport collects whatever sections are still missing, then packages the
+// archive and tells the user it is ready
+func (s *DataExportService) buildExport(ctx context.Context, export *repository.DataExport) error {
+	parts, err := s.exportRepo.ListParts(ctx, export.ID)
+	if err != nil {
+		return err
+	}
+
+	lastPage := make(map[string]*repository.DataExportPart)
+	for _, part := range parts {
+		lastPage[part.Section] = part
+	}
+	completed := make(map[string]bool)
+	for _, section := range export.CompletedSections {
+		completed[section] = true
+	}
+
+	for _, section := range exportSections {
+		if completed[section.Name] {
+			continue
+		}
+		if err := s.collectSection(ctx, export, section.Name, lastPage[section.Name]); err != nil {
+			return err
+		}
+		if err := s.exportRepo.RenewLease(ctx, export.ID, s.workerID, s.config.DataExport.LeaseDuration); err != nil {
+			return err
+		}
+	}
+
+	user, err := s.userRepo.FindByID(ctx, export.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	parts, err = s.exportRepo.ListParts(ctx, export.ID)
+	if err != nil {
+		return err
+	}
+
+	archiveKey := export.ID + ".zip"
+	file, err := s.storage.Create(archiveKey)
+	if err != nil {
+		return err
+	}
+	counter := &countingWriter{w: file}
+	if err := writeExportArchive(counter, user, parts, time.Now()); err != nil {
+		file.Close()
+		s.storage.Delete(archiveKey)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.config.DataExport.ArchiveTTL)
+	if err := s.exportRepo.MarkReady(ctx, export.ID, s.workerID, archiveKey, counter.n, expiresAt); err != nil {
+		// The worker that took over writes the same key; only remove ours if nobody did
+		if !errors.Is(err, repository.ErrExportLeaseLost) {
+			s.storage.Delete(archiveKey)
+		}
+		return err
+	}
+
+	link, err := s.downloadLink(export, expiresAt)
+	if err != nil {
+		log.Printf("Failed to sign download link for export %s: %v", export.ID, err)
+		return nil
+	}
+	if err := s.emailService.SendDataExportReadyEmail(user.Email, user.FirstName, link, expiresAt); err != nil {
+		log.Printf("Failed to send data export email: %v", err)
+	}
+
+	return nil
+}
+
+// collectSection saves one section of the export. Local sections are one query;
+// post service sections are fetched page by page, resuming after lastPage.
+func (s *DataExportService) collectSection(ctx context.Context, export *repository.DataExport, section string, lastPage *repository.DataExportPart) error {
+	if repository.HasExportSection(section) {
+		content, err := s.exportRepo.ExportSection(ctx, section, export.UserID)
+		if err != nil {
+			return err
+		}
+		part := &repository.DataExportPart{Section: section, Page: 0, Content: content}
+		return s.exportRepo.SavePart(ctx, export.ID, s.workerID, part, true)
+	}
+
+	page, cursor := 0, ""
+	if lastPage != nil {
+		page, cursor = lastPage.Page+1, lastPage.NextCursor
+	}
+
+	for {
+		result, err := s.postExports.FetchPage(ctx, export.UserID, section, cursor)
+		if err != nil {
+			return err
+		}
+
+		part := &repository.DataExportPart{Section: section, Page: page, Content: result.Items, NextCursor: result.NextCursor}
+		if err := s.exportRepo.SavePart(ctx, export.ID, s.workerID, part, result.NextCursor == ""); err != nil {
+			return err
+		}
+		if result.NextCursor == "" {
+			return nil
+		}
+
+		if err := s.exportRepo.RenewLease(ctx, export.ID, s.workerID, s.config.DataExport.LeaseDuration); err != nil {
+			return err
+		}
+		page, cursor = page+1, result.NextCursor
+	}
+}
+
+// view shows an export to its owner, signing a short-lived download link when it is ready
+func (s *DataExportService) view(export *repository.DataExport) (*DataExportView, error) {
+	view := &DataExportView{
+		ID:                export.ID,
+		Status:            export.Status,
+		CompletedSections: export.CompletedSections,
+		TotalSections:     len(exportSections),
+		RequestedAt:       export.CreatedAt,
+		CompletedAt:       export.CompletedAt,
+		ExpiresAt:         export.ExpiresAt,
+	}
+	if view.CompletedSections == nil {
+		view.CompletedSections = []string{}
+	}
+
+	if export.Status == repository.DataExportReady && export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+		linkExpiresAt := time.Now().Add(s.config.DataExport.LinkTTL)
+		if linkExpiresAt.After(*export.ExpiresAt) {
+			linkExpiresAt = *export.ExpiresAt
+		}
+
+		link, err := s.downloadLink(export, linkExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		view.ArchiveSize = export.ArchiveSize
+		view.DownloadURL = link
+		view.DownloadURLExpiresAt = &linkExpiresAt
+	}
+
+	return view, nil
+}
+
+// downloadLink signs a link to an export's archive that works until expiresAt
+func (s *DataExportService) downloadLink(export *repository.DataExport, expiresAt time.Time) (string, error) {
+	token, err := util.SignToken(&dataExportClaims{
+		ExportID: export.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.config.OIDC.Issuer,
+			Subject:   export.UserID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}, dataExportTokenType)
+	if err != nil {
+		return "", err
+	}
+
+	return s.config.OIDC.Issuer + "/api/v1/exports/download?token=" + url.QueryEscape(token), nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/smtp"
 	"os"
+	"time"
 )
 
 // EmailService handles sending emails
@@ -171,6 +172,540 @@ func (s *EmailService) SendWelcomeEmail(toEmail, firstName string) error {
 	return s.sendEmail(toEmail, subject, body.String())
 }
 
+// SendVerificationEmail asks a user to confirm they own their email address
+func (s *EmailService) SendVerificationEmail(toEmail, firstName, verifyLink string) error {
+	subject := "Confirm your email for Entativa"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>Please confirm that this is your email address so we can keep your account secure:</p>
+            <p style="text-align: center;">
+                <a href="{{.VerifyLink}}" class="button">Confirm Email</a>
+            </p>
+            <p>If you didn't create an Entativa account, you can ignore this email.</p>
+            <hr style="border: none; border-top: 1px solid #e4e6eb; margin: 30px 0;">
+            <p style="color: #65676b; font-size: 14px;">
+                If the button doesn't work, copy and paste this link into your browser:<br>
+                <a href="{{.VerifyLink}}" style="color: #007CFC; word-break: break-all;">{{.VerifyLink}}</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("verifyEmail").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName  string
+		VerifyLink string
+	}{
+		FirstName:  firstName,
+		VerifyLink: verifyLink,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendEmailChangeConfirmation asks a user to confirm a new email address before it replaces the old one
+func (s *EmailService) SendEmailChangeConfirmation(toEmail, firstName, confirmLink string) error {
+	subject := "Confirm your new Entativa email"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>You asked to use this address for your Entativa account. Confirm the change to finish:</p>
+            <p style="text-align: center;">
+                <a href="{{.ConfirmLink}}" class="button">Confirm New Email</a>
+            </p>
+            <div class="warning">
+                <strong>⚠️ Important:</strong> This link will expire in 1 hour and can only be used once.
+            </div>
+            <p>If you didn't ask for this, ignore this email and your account will keep its current address.</p>
+            <hr style="border: none; border-top: 1px solid #e4e6eb; margin: 30px 0;">
+            <p style="color: #65676b; font-size: 14px;">
+                If the button doesn't work, copy and paste this link into your browser:<br>
+                <a href="{{.ConfirmLink}}" style="color: #007CFC; word-break: break-all;">{{.ConfirmLink}}</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("confirmEmailChange").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName   string
+		ConfirmLink string
+	}{
+		FirstName:   firstName,
+		ConfirmLink: confirmLink,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendEmailChangedNotice tells the previous address that the account's email changed, with a link to undo it
+func (s *EmailService) SendEmailChangedNotice(toEmail, firstName, newEmail, undoLink string) error {
+	subject := "Your Entativa email was changed"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>The email address on your Entativa account was changed to <strong>{{.NewEmail}}</strong>.</p>
+            <p>If you made this change, there's nothing else to do.</p>
+            <div class="warning">
+                <strong>⚠️ Didn't change it?</strong> Undo the change to restore this address and sign out every device:
+            </div>
+            <p style="text-align: center;">
+                <a href="{{.UndoLink}}" class="button">This Wasn't Me</a>
+            </p>
+            <hr style="border: none; border-top: 1px solid #e4e6eb; margin: 30px 0;">
+            <p style="color: #65676b; font-size: 14px;">
+                If the button doesn't work, copy and paste this link into your browser:<br>
+                <a href="{{.UndoLink}}" style="color: #007CFC; word-break: break-all;">{{.UndoLink}}</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("emailChanged").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName string
+		NewEmail  string
+		UndoLink  string
+	}{
+		FirstName: firstName,
+		NewEmail:  newEmail,
+		UndoLink:  undoLink,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendDataExportReadyEmail tells the user their copy of their data can be downloaded
+func (s *EmailService) SendDataExportReadyEmail(toEmail, firstName, downloadLink string, expiresAt time.Time) error {
+	subject := "Your Entativa information is ready to download"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>The copy of your Entativa information you asked for is ready. It's a zip file with your data as JSON and as pages you can open in a browser.</p>
+            <p style="text-align: center;">
+                <a href="{{.DownloadLink}}" class="button">Download Your Information</a>
+            </p>
+            <div class="warning">
+                <strong>⚠️ Keep it safe:</strong> The file contains your private information. The link stops working on {{.ExpiresAt}}.
+            </div>
+            <p>If you didn't ask for a copy of your information, change your password and sign out of other sessions.</p>
+            <hr style="border: none; border-top: 1px solid #e4e6eb; margin: 30px 0;">
+            <p style="color: #65676b; font-size: 14px;">
+                If the button doesn't work, copy and paste this link into your browser:<br>
+                <a href="{{.DownloadLink}}" style="color: #007CFC; word-break: break-all;">{{.DownloadLink}}</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("dataExportReady").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName    string
+		DownloadLink string
+		ExpiresAt    string
+	}{
+		FirstName:    firstName,
+		DownloadLink: downloadLink,
+		ExpiresAt:    expiresAt.UTC().Format("January 2, 2006 at 15:04 MST"),
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendDataExportFailedEmail tells the user their data export could not be completed
+func (s *EmailService) SendDataExportFailedEmail(toEmail, firstName string) error {
+	subject := "We couldn't prepare your Entativa information"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>Something went wrong while we were preparing the copy of your Entativa information, and we weren't able to finish it.</p>
+            <p>Please request a new copy from <strong>Settings → Your Information</strong>. Nothing on your account was changed.</p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("dataExportFailed").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName string
+	}{
+		FirstName: firstName,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendAccountDeletionScheduledEmail tells the user their account will be deleted and how to keep it
+func (s *EmailService) SendAccountDeletionScheduledEmail(toEmail, firstName string, purgeAfter time.Time) error {
+	subject := "Your Entativa account is scheduled for deletion"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>We received your request to delete your Entativa account. Your account has been deactivated and will be permanently deleted on <strong>{{.PurgeAfter}}</strong>.</p>
+            <p>Changed your mind? Just log in before then and your account will be restored as it was.</p>
+            <div class="warning">
+                <strong>⚠️ Didn't ask for this?</strong> Log in now to cancel the deletion, then change your password.
+            </div>
+            <p>After that date your profile, posts, comments, messages and everything else on your account will be removed and can't be recovered.</p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("accountDeletionScheduled").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName  string
+		PurgeAfter string
+	}{
+		FirstName:  firstName,
+		PurgeAfter: purgeAfter.UTC().Format("January 2, 2006"),
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendAccountDeletionCancelledEmail confirms that logging in kept the user's account
+func (s *EmailService) SendAccountDeletionCancelledEmail(toEmail, firstName string) error {
+	subject := "Welcome back! Your Entativa account won't be deleted"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Welcome back, {{.FirstName}}!</h2>
+            <p>You logged in to your Entativa account, so we've cancelled its deletion. Everything is just as you left it.</p>
+            <p>If you still want to leave, you can ask to delete your account again from <strong>Settings → Your Account</strong>.</p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("accountDeletionCancelled").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName string
+	}{
+		FirstName: firstName,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
+// SendNewDeviceLoginEmail tells the user their password was used on a device they haven't signed in from before
+func (s *EmailService) SendNewDeviceLoginEmail(toEmail, firstName, device, ipAddress string, at time.Time, securityLink string) error {
+	subject := "New sign-in to your Entativa account"
+	
+	htmlTemplate := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: linear-gradient(135deg, #007CFC 0%, #6F3EFB 50%, #FC30E1 100%); color: white; padding: 30px; text-align: center; border-radius: 8px 8px 0 0; }
+        .content { background: white; padding: 30px; border: 1px solid #e4e6eb; border-top: none; border-radius: 0 0 8px 8px; }
+        .button { display: inline-block; background: #007CFC; color: white; padding: 14px 32px; text-decoration: none; border-radius: 8px; margin: 20px 0; font-weight: 600; }
+        .footer { text-align: center; color: #65676b; font-size: 12px; margin-top: 20px; }
+        .warning { background: #fff3cd; border: 1px solid #ffc107; padding: 15px; border-radius: 6px; margin: 20px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1 style="margin: 0; font-size: 36px; font-style: italic;">entativa</h1>
+        </div>
+        <div class="content">
+            <h2>Hi {{.FirstName}},</h2>
+            <p>Your Entativa password was just used to sign in from a new device:</p>
+            <p>
+                <strong>Device:</strong> {{.Device}}<br>
+                <strong>IP address:</strong> {{.IPAddress}}<br>
+                <strong>Time:</strong> {{.Time}}
+            </p>
+            <p>If this was you, there's nothing else to do.</p>
+            <div class="warning">
+                <strong>⚠️ Don't recognise it?</strong> Change your password and sign out the devices you don't know:
+            </div>
+            <p style="text-align: center;">
+                <a href="{{.SecurityLink}}" class="button">Review Sign-ins</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>© 2025 Entativa. All rights reserved.</p>
+            <p>This is an automated email. Please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+	
+	tmpl, err := template.New("newDeviceLogin").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+	
+	var body bytes.Buffer
+	data := struct {
+		FirstName    string
+		Device       string
+		IPAddress    string
+		Time         string
+		SecurityLink string
+	}{
+		FirstName:    firstName,
+		Device:       device,
+		IPAddress:    ipAddress,
+		Time:         at.UTC().Format("January 2, 2006 at 15:04 UTC"),
+		SecurityLink: securityLink,
+	}
+	
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+	
+	return s.sendEmail(toEmail, subject, body.String())
+}
+
 // sendEmail sends an email using SMTP
 func (s *EmailService) sendEmail(to, subject, htmlBody string) error {
 	// In development, just log the email
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+var (
+	ErrEmailTokenInvalid    = errors.New("invalid or expired link")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrEmailTaken           = errors.New("email is already in use")
+	ErrEmailUnchanged       = errors.New("new email is the same as the current one")
+	ErrIncorrectPassword    = errors.New("password is incorrect")
+	ErrTooManyEmailRequests = errors.New("too many emails requested, please try again later")
+)
+
+// maxEmailTokensPerHour caps verification and change emails per user
+const maxEmailTokensPerHour = 5
+
+// EmailVerificationService confirms that users own their email addresses.
+//
+// A new account gets a verification link. Changing the address sends a link to
+// the new address and only switches once it is confirmed; the old address is
+// then told about the change and gets a link that reverts it and signs out
+// every session, in case the account was taken over.
+type EmailVerificationService struct {
+	userRepo       *repository.UserRepository
+	emailTokenRepo *repository.EmailTokenRepository
+	sessionRepo    *repository.SessionRepository
+	emailService   *EmailService
+	auditLog       *AuditLog
+	config         *config.Config
+}
+
+// NewEmailVerificationService creates a new email verification service
+func NewEmailVerificationService(
+	userRepo *repository.UserRepository,
+	emailTokenRepo *repository.EmailTokenRepository,
+	sessionRepo *repository.SessionRepository,
+	emailService *EmailService,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:       userRepo,
+		emailTokenRepo: emailTokenRepo,
+		sessionRepo:    sessionRepo,
+		emailService:   emailService,
+		auditLog:       auditLog,
+		config:         cfg,
+	}
+}
+
+// SendVerification emails a verification link for the user's current address.
+// Earlier verification links stop working.
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *repository.User) error {
+	if user.HasVerifiedEmail() {
+		return ErrEmailAlreadyVerified
+	}
+	if err := s.checkThrottle(ctx, user.ID, repository.EmailTokenVerify); err != nil {
+		return err
+	}
+
+	if err := s.emailTokenRepo.InvalidateUserEmailTokens(ctx, user.ID, repository.EmailTokenVerify); err != nil {
+		return err
+	}
+
+	token, err := s.issueToken(ctx, user.ID, repository.EmailTokenVerify, user.Email, "", s.config.Security.EmailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	go s.emailService.SendVerificationEmail(user.Email, user.FirstName, s.link("/verify-email", token))
+
+	return nil
+}
+
+// VerifyEmail marks the address a verification link was sent to as verified
+func (s *EmailVerificationService) VerifyEmail(ctx context.Context, token, ipAddress string) error {
+	emailToken, err := s.emailTokenRepo.ConsumeEmailToken(ctx, repository.EmailTokenVerify, token)
+	if err != nil {
+		return ErrEmailTokenInvalid
+	}
+
+	// Fails if the account's address changed after the link was sent
+	if err := s.userRepo.MarkEmailVerified(ctx, emailToken.UserID, emailToken.Email); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrEmailTokenInvalid
+		}
+		return err
+	}
+
+	s.auditLog.LogEmailVerified(emailToken.UserID, emailToken.Email, ipAddress)
+
+	return nil
+}
+
+// RequestEmailChange sends a confirmation link to a new address. The account
+// keeps its current address until the link is used.
+func (s *EmailVerificationService) RequestEmailChange(ctx context.Context, user *repository.User, newEmail, password, ipAddress string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if err := util.ValidateEmail(newEmail); err != nil {
+		return err
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrEmailUnchanged
+	}
+
+	// Re-authenticate: a stolen session alone must not be able to take the account
+	if !util.ComparePassword(user.PasswordHash, password) {
+		return ErrIncorrectPassword
+	}
+
+	exists, err := s.userRepo.CheckEmailExists(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailTaken
+	}
+
+	if err := s.checkThrottle(ctx, user.ID, repository.EmailTokenChange); err != nil {
+		return err
+	}
+
+	// Only the most recent request can be confirmed
+	if err := s.emailTokenRepo.InvalidateUserEmailTokens(ctx, user.ID, repository.EmailTokenChange); err != nil {
+		return err
+	}
+
+	token, err := s.issueToken(ctx, user.ID, repository.EmailTokenChange, newEmail, user.Email, s.config.Security.EmailChangeExpiry)
+	if err != nil {
+		return err
+	}
+
+	go s.emailService.SendEmailChangeConfirmation(newEmail, user.FirstName, s.link("/confirm-email-change", token))
+
+	s.auditLog.LogEmailChangeRequested(user.ID, newEmail, ipAddress)
+
+	return nil
+}
+
+// ConfirmEmailChange switches the account to the confirmed new address and
+// sends the previous address a notice with an undo link
+func (s *EmailVerificationService) ConfirmEmailChange(ctx context.Context, token, ipAddress string) error {
+	emailToken, err := s.emailTokenRepo.ConsumeEmailToken(ctx, repository.EmailTokenChange, token)
+	if err != nil {
+		return ErrEmailTokenInvalid
+	}
+
+	exists, err := s.userRepo.CheckEmailExists(ctx, emailToken.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailTaken
+	}
+
+	// Fails if the address changed again since the request
+	if err := s.userRepo.ChangeEmail(ctx, emailToken.UserID, emailToken.PreviousEmail, emailToken.Email); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrEmailTokenInvalid
+		}
+		return err
+	}
+
+	// Verification links for the old address are meaningless now
+	if err := s.emailTokenRepo.InvalidateUserEmailTokens(ctx, emailToken.UserID, repository.EmailTokenVerify); err != nil {
+		return err
+	}
+
+	s.auditLog.LogEmailChanged(emailToken.UserID, emailToken.PreviousEmail, emailToken.Email, ipAddress)
+
+	user, err := s.userRepo.FindByID(ctx, emailToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	// The undo token restores Email and only applies while the account still has PreviousEmail
+	undoToken, err := s.issueToken(ctx, user.ID, repository.EmailTokenUndoChange, emailToken.PreviousEmail, emailToken.Email, s.config.Security.EmailChangeUndoExpiry)
+	if err != nil {
+		return err
+	}
+
+	go s.emailService.SendEmailChangedNotice(emailToken.PreviousEmail, user.FirstName, emailToken.Email, s.link("/undo-email-change", undoToken))
+
+	return nil
+}
+
+// UndoEmailChange restores the previous address from the link sent to it, and
+// signs out every session since the change may not have been the owner's
+func (s *EmailVerificationService) UndoEmailChange(ctx context.Context, token, ipAddress string) error {
+	emailToken, err := s.emailTokenRepo.ConsumeEmailToken(ctx, repository.EmailTokenUndoChange, token)
+	if err != nil {
+		return ErrEmailTokenInvalid
+	}
+
+	if err := s.userRepo.ChangeEmail(ctx, emailToken.UserID, emailToken.PreviousEmail, emailToken.Email); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrEmailTokenInvalid
+		}
+		return err
+	}
+
+	if err := s.emailTokenRepo.InvalidateUserEmailTokens(ctx, emailToken.UserID, repository.EmailTokenChange); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.InvalidateAllUserSessions(ctx, emailToken.UserID); err != nil {
+		return fmt.Errorf("failed to sign out sessions: %w", err)
+	}
+
+	s.auditLog.LogEmailChangeUndone(emailToken.UserID, emailToken.PreviousEmail, emailToken.Email, ipAddress)
+
+	return nil
+}
+
+func (s *EmailVerificationService) issueToken(ctx context.Context, userID, purpose, email, previousEmail string, ttl time.Duration) (string, error) {
+	token, err := util.GenerateSplitToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	now := time.Now()
+	emailToken := &repository.EmailToken{
+		ID:            util.GenerateUUID(),
+		UserID:        userID,
+		Purpose:       purpose,
+		Selector:      token.Selector,
+		VerifierHash:  token.VerifierHash,
+		Email:         email,
+		PreviousEmail: previousEmail,
+		ExpiresAt:     now.Add(ttl),
+		CreatedAt:     now,
+	}
+
+	if err := s.emailTokenRepo.CreateEmailToken(ctx, emailToken); err != nil {
+		return "", err
+	}
+
+	return token.Token, nil
+}
+
+func (s *EmailVerificationService) checkThrottle(ctx context.Context, userID, purpose string) error {
+	count, err := s.emailTokenRepo.CountRecentEmailTokens(ctx, userID, purpose, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= maxEmailTokensPerHour {
+		return ErrTooManyEmailRequests
+	}
+	return nil
+}
+
+func (s *EmailVerificationService) link(path, token string) string {
+	return strings.TrimRight(s.config.Server.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportStorage holds finished data export archives until they expire
+type ExportStorage interface {
+	// Create opens a new archive for writing; it only becomes visible to Open once closed
+	Create(key string) (io.WriteCloser, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// LocalExportStorage keeps archives in a directory, typically a volume shared by
+// every replica so any of them can serve a download
+type LocalExportStorage struct {
+	dir string
+}
+
+// NewLocalExportStorage creates the storage directory if needed
+func NewLocalExportStorage(dir string) (*LocalExportStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export storage directory: %w", err)
+	}
+	return &LocalExportStorage{dir: dir}, nil
+}
+
+// Create writes to a temporary file that is renamed into place on Close,
+// so a worker that dies mid-write never leaves a truncated archive behind
+func (s *LocalExportStorage) Create(key string) (io.WriteCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(s.dir, ".export-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export archive: %w", err)
+	}
+
+	return &pendingArchive{File: file, path: path}, nil
+}
+
+// Open opens a stored archive
+func (s *LocalExportStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes a stored archive; deleting one that is already gone is not an error
+func (s *LocalExportStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete export archive: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalExportStorage) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid export archive key: %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+type pendingArchive struct {
+	*os.File
+	path string
+}
+
+func (a *pendingArchive) Close() error {
+	if err := a.File.Close(); err != nil {
+		os.Remove(a.File.Name())
+		return err
+	}
+	if err := os.Rename(a.File.Name(), a.path); err != nil {
+		os.Remove(a.File.Name())
+		return fmt.Errorf("failed to store export archive: %w", err)
+	}
+	return nil
+}
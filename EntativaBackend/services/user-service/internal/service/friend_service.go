@@ -11,20 +11,26 @@ import (
 )
 
 type FriendService struct {
-	friendRepo *repository.FriendRepository
-	userRepo   *repository.UserRepository
-	kafka      *KafkaProducer
+	friendRepo   *repository.FriendRepository
+	userRepo     *repository.UserRepository
+	blockService *BlockService
+	suggestions  *FriendSuggestionService
+	kafka        *KafkaProducer
 }
 
 func NewFriendService(
 	friendRepo *repository.FriendRepository,
 	userRepo *repository.UserRepository,
+	blockService *BlockService,
+	suggestions *FriendSuggestionService,
 	kafka *KafkaProducer,
 ) *FriendService {
 	return &FriendService{
-		friendRepo: friendRepo,
-		userRepo:   userRepo,
-		kafka:      kafka,
+		friendRepo:   friendRepo,
+		userRepo:     userRepo,
+		blockService: blockService,
+		suggestions:  suggestions,
+		kafka:        kafka,
 	}
 }
 
@@ -45,6 +51,15 @@ func (s *FriendService) SendFriendRequest(ctx context.Context, senderID, receive
 		return fmt.Errorf("user not found")
 	}
 
+	// Blocked users can't find each other, so they look the same as a missing user
+	blocked, err := s.blockService.IsBlocked(ctx, senderID.String(), receiverID.String())
+	if err != nil {
+		return fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return fmt.Errorf("user not found")
+	}
+
 	// Check if already friends
 	isFriend, err := s.friendRepo.AreFriends(ctx, senderID, receiverID)
 	if err != nil {
@@ -183,6 +198,9 @@ func (s *FriendService) AcceptFriendRequest(ctx context.Context, requestID, rece
 		return err
 	}
 
+	// New mutual friends change both users' suggestions, and their friends'
+	s.suggestions.HandleFriendshipChange(ctx, request.SenderID.String(), request.ReceiverID.String())
+
 	// Publish Kafka event
 	s.kafka.PublishFriendRequestEvent(request.SenderID, request.ReceiverID, "accepted")
 
@@ -267,6 +285,8 @@ func (s *FriendService) Unfriend(ctx context.Context, userID, friendID uuid.UUID
 		return err
 	}
 
+	s.suggestions.HandleFriendshipChange(ctx, userID.String(), friendID.String())
+
 	// Publish Kafka event
 	s.kafka.PublishFriendRequestEvent(userID, friendID, "unfriended")
 
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+)
+
+// Points each thing a user has in common with a candidate adds to the candidate's score
+const (
+	mutualFriendWeight    = 10
+	sharedWorkplaceWeight = 8
+	sharedSchoolWeight    = 6
+	sharedCommunityWeight = 3
+	sharedEventWeight     = 2
+)
+
+const (
+	// maxSuggestionsPerUser is how many ranked suggestions are kept for each user
+	maxSuggestionsPerUser = 100
+
+	// candidatesPerSignal caps how many candidates each signal contributes, so a
+	// huge community doesn't turn one refresh into a scan of all its members
+	candidatesPerSignal = 500
+)
+
+var (
+	ErrSuggestionUserNotFound = errors.New("user not found")
+	ErrCannotDismissSelf      = errors.New("you can't dismiss yourself")
+	ErrInvalidAffiliationKind = errors.New("affiliation kind must be community or event")
+)
+
+// FriendSuggestionView is someone the user may know and why they were suggested
+type FriendSuggestionView struct {
+	UserID            string  `json:"user_id"`
+	Username          string  `json:"username"`
+	FirstName         string  `json:"first_name"`
+	LastName          string  `json:"last_name"`
+	ProfilePictureURL *string `json:"profile_picture_url,omitempty"`
+	MutualFriends     int     `json:"mutual_friends"`
+	SharedWorkplaces  int     `json:"shared_workplaces,omitempty"`
+	SharedSchools     int     `json:"shared_schools,omitempty"`
+	SharedCommunities int     `json:"shared_communities,omitempty"`
+	SharedEvents      int     `json:"shared_events,omitempty"`
+}
+
+// FriendSuggestionService ranks people a user may know ("People You May Know").
+//
+// Candidates are friends of friends and people who share a workplace, a school, a
+// community or an event with the user, scored by how much they have in common.
+// Suggestions are precomputed: a worker recomputes users whose friendships or
+// affiliations have changed, and refreshes everyone else once they pass
+// SuggestionsConfig.MaxAge. A user whose suggestions were never computed gets them
+// computed on first request. Friends, pending requests, blocks and dismissed
+// suggestions are never suggested.
+type FriendSuggestionService struct {
+	suggestionRepo *repository.FriendSuggestionRepository
+	userRepo       *repository.UserRepository
+	config         *config.Config
+}
+
+// NewFriendSuggestionService creates a new friend suggestion service
+func NewFriendSuggestionService(suggestionRepo *repository.FriendSuggestionRepository, userRepo *repository.UserRepository, cfg *config.Config) *FriendSuggestionService {
+	return &FriendSuggestionService{
+		suggestionRepo: suggestionRepo,
+		userRepo:       userRepo,
+		config:         cfg,
+	}
+}
+
+// ListSuggestions returns a page of the user's suggestions, best first
+func (s *FriendSuggestionService) ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*FriendSuggestionView, error) {
+	computed, err := s.suggestionRepo.HasComputed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !computed {
+		if err := s.Refresh(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	suggestions, err := s.suggestionRepo.ListSuggestions(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*FriendSuggestionView, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		views = append(views, &FriendSuggestionView{
+			UserID:            suggestion.SuggestedUserID,
+			Username:          suggestion.Username,
+			FirstName:         suggestion.FirstName,
+			LastName:          suggestion.LastName,
+			ProfilePictureURL: suggestion.ProfilePictureURL,
+			MutualFriends:     suggestion.MutualFriends,
+			SharedWorkplaces:  suggestion.SharedWorkplaces,
+			SharedSchools:     suggestion.SharedSchools,
+			SharedCommunities: suggestion.SharedCommunities,
+			SharedEvents:      suggestion.SharedEvents,
+		})
+	}
+
+	return views, nil
+}
+
+// DismissSuggestion stops suggesting dismissedUserID to the user
+func (s *FriendSuggestionService) DismissSuggestion(ctx context.Context, userID, dismissedUserID string) error {
+	if userID == dismissedUserID {
+		return ErrCannotDismissSelf
+	}
+	if _, err := s.userRepo.FindByID(ctx, dismissedUserID); err != nil {
+		return ErrSuggestionUserNotFound
+	}
+
+	return s.suggestionRepo.DismissSuggestion(ctx, userID, dismissedUserID)
+}
+
+// Refresh recomputes and stores a user's suggestions
+func (s *FriendSuggestionService) Refresh(ctx context.Context, userID string) error {
+	candidates, err := s.suggestionRepo.FindCandidates(ctx, userID, candidatesPerSignal)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		candidate.Score = suggestionScore(candidate)
+	}
+
+	// Ties go to the lower user ID so a refresh with no changes keeps the same order
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].SuggestedUserID < candidates[j].SuggestedUserID
+	})
+	if len(candidates) > maxSuggestionsPerUser {
+		candidates = candidates[:maxSuggestionsPerUser]
+	}
+
+	return s.suggestionRepo.ReplaceSuggestions(ctx, userID, candidates)
+}
+
+// HandleFriendshipChange queues recomputing suggestions for two users who have
+// become, or stopped being, friends, and for their friends
+func (s *FriendSuggestionService) HandleFriendshipChange(ctx context.Context, userID, otherUserID string) {
+	if err := s.suggestionRepo.MarkFriendshipStale(ctx, userID, otherUserID); err != nil {
+		log.Printf("Failed to queue friend suggestions for %s and %s: %v", userID, otherUserID, err)
+	}
+}
+
+// AddAffiliation records a community membership or event RSVP reported by another
+// service. The user's suggestions are recomputed; other members pick the change up
+// at their next refresh.
+func (s *FriendSuggestionService) AddAffiliation(ctx context.Context, userID, kind, affiliationID string) error {
+	if !validAffiliationKind(kind) {
+		return ErrInvalidAffiliationKind
+	}
+	if err := s.suggestionRepo.AddAffiliation(ctx, userID, kind, affiliationID); err != nil {
+		return err
+	}
+	return s.suggestionRepo.MarkStale(ctx, userID)
+}
+
+// RemoveAffiliation records that a user left a community or is no longer going to an event
+func (s *FriendSuggestionService) RemoveAffiliation(ctx context.Context, userID, kind, affiliationID string) error {
+	if !validAffiliationKind(kind) {
+		return ErrInvalidAffiliationKind
+	}
+	if err := s.suggestionRepo.RemoveAffiliation(ctx, userID, kind, affiliationID); err != nil {
+		return err
+	}
+	return s.suggestionRepo.MarkStale(ctx, userID)
+}
+
+// RunWorker keeps suggestions fresh until ctx is cancelled. Every instance may run
+// one: users are claimed with SKIP LOCKED, so they share the work.
+func (s *FriendSuggestionService) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Suggestions.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDue starts tracking users never computed, then recomputes one batch of
+// stale and expired users
+func (s *FriendSuggestionService) refreshDue(ctx context.Context) {
+	cfg := s.config.Suggestions
+
+	if _, err := s.suggestionRepo.EnqueueNewUsers(ctx, cfg.BatchSize); err != nil {
+		log.Printf("Failed to enqueue users for friend suggestions: %v", err)
+	}
+
+	userIDs, err := s.suggestionRepo.ClaimDueUsers(ctx, cfg.BatchSize, cfg.MaxAge, cfg.LeaseDuration)
+	if err != nil {
+		log.Printf("Failed to claim friend suggestion refreshes: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.Refresh(ctx, userID); err != nil {
+			log.Printf("Failed to refresh friend suggestions for %s: %v", userID, err)
+			// Try again once the lease runs out
+			if err := s.suggestionRepo.MarkStale(ctx, userID); err != nil {
+				log.Printf("Failed to requeue friend suggestions for %s: %v", userID, err)
+			}
+		}
+	}
+}
+
+func suggestionScore(candidate *repository.FriendSuggestion) int {
+	return candidate.MutualFriends*mutualFriendWeight +
+		candidate.SharedWorkplaces*sharedWorkplaceWeight +
+		candidate.SharedSchools*sharedSchoolWeight +
+		candidate.SharedCommunities*sharedCommunityWeight +
+		candidate.SharedEvents*sharedEventWeight
+}
+
+func validAffiliationKind(kind string) bool {
+	return kind == repository.AffiliationCommunity || kind == repository.AffiliationEvent
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+var (
+	ErrUnsupportedPlatform      = errors.New("platform must be 'vignette' or 'entativa'")
+	ErrReauthenticationRequired = errors.New("confirm your password or sign in with an account already linked")
+	ErrIdentityAlreadyLinked    = errors.New("this account is already linked")
+	ErrIdentityLinkedElsewhere  = errors.New("this account is linked to a different profile; merge the profiles instead")
+	ErrPlatformAlreadyLinked    = errors.New("another account on this platform is already linked; unlink it first")
+	ErrIdentityNotLinked        = errors.New("no account linked on this platform")
+	ErrLastSignInMethod         = errors.New("set a password before unlinking your only sign-in method")
+	ErrMergeSameAccount         = errors.New("cannot merge an account into itself")
+	ErrMergeSourceNotFound      = errors.New("the account to merge was not found")
+	ErrMergeSourceCredentials   = errors.New("could not verify the account to merge")
+)
+
+// PlatformIdentity is an account on the other platform, as vouched for by that
+// platform when it accepted an access token
+type PlatformIdentity struct {
+	Platform      string
+	UserID        string
+	Username      string
+	Email         string
+	EmailVerified bool
+}
+
+// Reauthentication is how a signed-in user confirms a sensitive change: with their
+// password, or with a fresh token from an identity already linked to the account
+// (the only option for accounts created by cross-platform sign-in)
+type Reauthentication struct {
+	Password string
+	Identity *PlatformIdentity
+}
+
+// MergeSource proves ownership of the account being merged away, either by its
+// password or by a token from an identity linked to it
+type MergeSource struct {
+	EmailOrUsername string
+	Password        string
+	Identity        *PlatformIdentity
+}
+
+// LinkedIdentityView is a linked identity as shown in account settings
+type LinkedIdentityView struct {
+	Platform         string    `json:"platform"`
+	PlatformUserID   string    `json:"platform_user_id"`
+	PlatformUsername string    `json:"platform_username,omitempty"`
+	PlatformEmail    string    `json:"platform_email,omitempty"`
+	LinkedVia        string    `json:"linked_via"`
+	LinkedAt         time.Time `json:"linked_at"`
+}
+
+// LinkedIdentities lists a user's sign-in methods
+type LinkedIdentities struct {
+	HasPassword bool                  `json:"has_password"`
+	Identities  []*LinkedIdentityView `json:"identities"`
+}
+
+// MergeResult summarises a completed merge
+type MergeResult struct {
+	MergeID      string           `json:"merge_id"`
+	SourceUserID string           `json:"source_user_id"`
+	Moved        map[string]int64 `json:"moved"`
+	MergedAt     time.Time        `json:"merged_at"`
+}
+
+// IdentityService manages the accounts on other platforms linked to a user, and
+// merges duplicate accounts created before the user linked them.
+//
+// Linking, unlinking and merging all require the user to re-authenticate, so a
+// stolen session cannot attach an attacker's account or detach the owner's.
+type IdentityService struct {
+	identityRepo *repository.IdentityRepository
+	userRepo     *repository.UserRepository
+	auditLog     *AuditLog
+}
+
+// NewIdentityService creates a new identity service
+func NewIdentityService(
+	identityRepo *repository.IdentityRepository,
+	userRepo *repository.UserRepository,
+	auditLog *AuditLog,
+) *IdentityService {
+	return &IdentityService{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		auditLog:     auditLog,
+	}
+}
+
+// ValidPlatform reports whether platform is one accounts can be linked from
+func ValidPlatform(platform string) bool {
+	return platform == "vignette" || platform == "entativa"
+}
+
+// ListIdentities lists the identities linked to the user
+func (s *IdentityService) ListIdentities(ctx context.Context, user *repository.User) (*LinkedIdentities, error) {
+	identities, err := s.identityRepo.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LinkedIdentities{
+		HasPassword: user.HasPassword,
+		Identities:  make([]*LinkedIdentityView, 0, len(identities)),
+	}
+	for _, identity := range identities {
+		result.Identities = append(result.Identities, &LinkedIdentityView{
+			Platform:         identity.Platform,
+			PlatformUserID:   identity.PlatformUserID,
+			PlatformUsername: identity.PlatformUsername,
+			PlatformEmail:    identity.PlatformEmail,
+			LinkedVia:        identity.LinkedVia,
+			LinkedAt:         identity.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// FindLinkedUser returns the user an identity is linked to
+func (s *IdentityService) FindLinkedUser(ctx context.Context, platform, platformUserID string) (*repository.User, error) {
+	identity, err := s.identityRepo.FindIdentity(ctx, platform, platformUserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.FindByID(ctx, identity.UserID)
+}
+
+// LinkIdentity links an identity on the other platform to the user. If the identity
+// already belongs to another account it returns ErrIdentityLinkedElsewhere, and the
+// user can merge that account instead.
+func (s *IdentityService) LinkIdentity(ctx context.Context, user *repository.User, identity *PlatformIdentity, reauth *Reauthentication, ipAddress string) error {
+	if !ValidPlatform(identity.Platform) {
+		return ErrUnsupportedPlatform
+	}
+	if err := s.reauthenticate(ctx, user, reauth); err != nil {
+		return err
+	}
+
+	existing, err := s.identityRepo.FindIdentity(ctx, identity.Platform, identity.UserID)
+	if err == nil {
+		if existing.UserID == user.ID {
+			return ErrIdentityAlreadyLinked
+		}
+		return ErrIdentityLinkedElsewhere
+	}
+	var notFound *repository.NotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+
+	linked, err := s.identityRepo.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, link := range linked {
+		if link.Platform == identity.Platform {
+			return ErrPlatformAlreadyLinked
+		}
+	}
+
+	err = s.identityRepo.LinkIdentity(ctx, &repository.LinkedIdentity{
+		UserID:           user.ID,
+		Platform:         identity.Platform,
+		PlatformUserID:   identity.UserID,
+		PlatformUsername: identity.Username,
+		PlatformEmail:    identity.Email,
+		LinkedVia:        repository.LinkedViaManual,
+		CreatedAt:        time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditLog.LogIdentityLinked(user.ID, identity.Platform, identity.UserID, ipAddress)
+	return nil
+}
+
+// UnlinkIdentity removes the user's link to a platform. An account without a
+// password of its own cannot remove its last linked identity.
+func (s *IdentityService) UnlinkIdentity(ctx context.Context, user *repository.User, platform string, reauth *Reauthentication, ipAddress string) error {
+	if !ValidPlatform(platform) {
+		return ErrUnsupportedPlatform
+	}
+	if err := s.reauthenticate(ctx, user, reauth); err != nil {
+		return err
+	}
+
+	linked, err := s.identityRepo.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var target *repository.LinkedIdentity
+	for _, link := range linked {
+		if link.Platform == platform {
+			target = link
+		}
+	}
+	if target == nil {
+		return ErrIdentityNotLinked
+	}
+	if !user.HasPassword && len(linked) == 1 {
+		return ErrLastSignInMethod
+	}
+
+	if err := s.identityRepo.UnlinkIdentity(ctx, user.ID, platform); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrIdentityNotLinked
+		}
+		return err
+	}
+
+	s.auditLog.LogIdentityUnlinked(user.ID, platform, target.PlatformUserID, ipAddress)
+	return nil
+}
+
+// MergeAccounts merges the account identified by source into the signed-in user.
+// The source account's profile data, links, connections and takes move to the
+// user; the source account is then deleted and signed out everywhere.
+func (s *IdentityService) MergeAccounts(ctx context.Context, target *repository.User, source *MergeSource, reauth *Reauthentication, ipAddress string) (*MergeResult, error) {
+	if err := s.reauthenticate(ctx, target, reauth); err != nil {
+		return nil, err
+	}
+
+	sourceUser, err := s.resolveMergeSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if sourceUser.ID == target.ID {
+		return nil, ErrMergeSameAccount
+	}
+
+	merge := &repository.AccountMerge{
+		ID:           util.GenerateUUID(),
+		SourceUserID: sourceUser.ID,
+		TargetUserID: target.ID,
+		IPAddress:    ipAddress,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.identityRepo.MergeAccounts(ctx, merge); err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogAccountMerged(target.ID, sourceUser.ID, merge.Moved, ipAddress)
+
+	return &MergeResult{
+		MergeID:      merge.ID,
+		SourceUserID: merge.SourceUserID,
+		Moved:        merge.Moved,
+		MergedAt:     merge.CreatedAt,
+	}, nil
+}
+
+// ListMerges lists the accounts that were merged into the user
+func (s *IdentityService) ListMerges(ctx context.Context, userID string) ([]*MergeResult, error) {
+	merges, err := s.identityRepo.ListMerges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*MergeResult, 0, len(merges))
+	for _, merge := range merges {
+		results = append(results, &MergeResult{
+			MergeID:      merge.ID,
+			SourceUserID: merge.SourceUserID,
+			Moved:        merge.Moved,
+			MergedAt:     merge.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+// reauthenticate checks that the request came from the account owner, not just someone holding a session
+func (s *IdentityService) reauthenticate(ctx context.Context, user *repository.User, reauth *Reauthentication) error {
+	if reauth == nil {
+		return ErrReauthenticationRequired
+	}
+
+	if reauth.Password != "" {
+		if !user.HasPassword || !util.ComparePassword(user.PasswordHash, reauth.Password) {
+			return ErrIncorrectPassword
+		}
+		return nil
+	}
+
+	if reauth.Identity != nil {
+		identity, err := s.identityRepo.FindIdentity(ctx, reauth.Identity.Platform, reauth.Identity.UserID)
+		if err != nil {
+			var notFound *repository.NotFoundError
+			if errors.As(err, &notFound) {
+				return ErrReauthenticationRequired
+			}
+			return err
+		}
+		if identity.UserID != user.ID {
+			return ErrReauthenticationRequired
+		}
+		return nil
+	}
+
+	return ErrReauthenticationRequired
+}
+
+// resolveMergeSource finds the account to merge and checks the caller controls it
+func (s *IdentityService) resolveMergeSource(ctx context.Context, source *MergeSource) (*repository.User, error) {
+	if source == nil {
+		return nil, ErrMergeSourceCredentials
+	}
+
+	if source.Identity != nil {
+		user, err := s.FindLinkedUser(ctx, source.Identity.Platform, source.Identity.UserID)
+		if err != nil {
+			return nil, ErrMergeSourceNotFound
+		}
+		return user, nil
+	}
+
+	if source.EmailOrUsername == "" || source.Password == "" {
+		return nil, ErrMergeSourceCredentials
+	}
+
+	user, err := s.userRepo.FindByEmailOrUsername(ctx, source.EmailOrUsername)
+	if err != nil {
+		return nil, ErrMergeSourceCredentials
+	}
+	if !user.HasPassword || !util.ComparePassword(user.PasswordHash, source.Password) {
+		return nil, ErrMergeSourceCredentials
+	}
+
+	return user, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"user-service/internal/config"
+)
+
+// IPLocator resolves an IP address to an approximate, human-readable location
+// such as "Lagos, Nigeria". It returns "" when the location is unknown.
+type IPLocator interface {
+	Locate(ctx context.Context, ip string) string
+}
+
+// NewIPLocator returns a locator backed by the configured GeoIP lookup service,
+// or one that only recognises private networks when none is configured
+func NewIPLocator(cfg *config.Config) IPLocator {
+	if cfg.GeoIP.LookupURL == "" {
+		return privateNetworkLocator{}
+	}
+	return &httpIPLocator{
+		lookupURL: cfg.GeoIP.LookupURL,
+		cacheTTL:  cfg.GeoIP.CacheTTL,
+		client:    &http.Client{Timeout: 2 * time.Second},
+		cache:     make(map[string]cachedLocation),
+	}
+}
+
+// privateNetworkLocator knows nothing beyond whether an address is local
+type privateNetworkLocator struct{}
+
+func (privateNetworkLocator) Locate(_ context.Context, ip string) string {
+	if addr := parseClientIP(ip); addr != nil && isLocalAddress(addr) {
+		return "Local network"
+	}
+	return ""
+}
+
+type cachedLocation struct {
+	location  string
+	expiresAt time.Time
+}
+
+// httpIPLocator queries a GeoIP service that answers with JSON in the shape
+// used by ip-api and most self-hosted GeoLite2 front ends
+type httpIPLocator struct {
+	lookupURL string
+	cacheTTL  time.Duration
+	client    *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedLocation
+}
+
+type geoIPResponse struct {
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	RegionName string `json:"regionName"`
+	Country    string `json:"country"`
+	CountryAlt string `json:"country_name"`
+}
+
+func (l *httpIPLocator) Locate(ctx context.Context, ip string) string {
+	addr := parseClientIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if isLocalAddress(addr) {
+		return "Local network"
+	}
+	key := addr.String()
+
+	l.mu.Lock()
+	cached, ok := l.cache[key]
+	l.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.location
+	}
+
+	location, err := l.lookup(ctx, key)
+	if err != nil {
+		// Leave it uncached so the next listing tries again
+		return ""
+	}
+
+	l.mu.Lock()
+	l.cache[key] = cachedLocation{location: location, expiresAt: time.Now().Add(l.cacheTTL)}
+	l.mu.Unlock()
+
+	return location
+}
+
+func (l *httpIPLocator) lookup(ctx context.Context, ip string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(l.lookupURL, "{ip}", url.PathEscape(ip)), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("geoip lookup returned %s", resp.Status)
+	}
+
+	var body geoIPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	region := body.RegionName
+	if region == "" {
+		region = body.Region
+	}
+	country := body.Country
+	if country == "" {
+		country = body.CountryAlt
+	}
+
+	var parts []string
+	for _, part := range []string{body.City, region, country} {
+		if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, ", "), nil
+}
+
+// parseClientIP accepts a bare IP, a host:port pair or an X-Forwarded-For list
+// (the first entry is the client)
+func parseClientIP(ip string) net.IP {
+	ip = strings.TrimSpace(strings.Split(ip, ",")[0])
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return net.ParseIP(ip)
+}
+
+func isLocalAddress(addr net.IP) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast()
+}
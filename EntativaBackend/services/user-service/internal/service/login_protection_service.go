@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+var (
+	ErrLoginLocked         = errors.New("too many failed login attempts, please try again later")
+	ErrProofOfWorkRequired = errors.New("proof of work required")
+)
+
+// LoginLockedError is returned while an account or IP is in a failure backoff
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrLoginLocked
+}
+
+// ProofOfWorkRequiredError carries the challenge a client must solve and send
+// back with its next login attempt
+type ProofOfWorkRequiredError struct {
+	Challenge *repository.ProofOfWorkChallenge
+}
+
+func (e *ProofOfWorkRequiredError) Error() string {
+	return ErrProofOfWorkRequired.Error()
+}
+
+func (e *ProofOfWorkRequiredError) Is(target error) bool {
+	return target == ErrProofOfWorkRequired
+}
+
+// PasswordLogin is a password login attempt as the client sent it
+type PasswordLogin struct {
+	EmailOrUsername string
+	Password        string
+	PowChallenge    string // set when retrying after a proof-of-work challenge
+	PowSolution     string
+}
+
+// LoginProtectionService decides whether a password login attempt may go ahead.
+//
+// Failures are counted per account and per IP in the database, so the limits
+// hold across instances and when Redis is down. Past a threshold each further
+// failure doubles a lockout, up to a maximum. Before that, attempts that look
+// like credential stuffing (several failures on the account, or many accounts
+// failing from one IP) must carry a solved proof-of-work challenge.
+type LoginProtectionService struct {
+	attemptRepo  *repository.LoginAttemptRepository
+	emailService *EmailService
+	auditLog     *AuditLog
+	config       *config.Config
+}
+
+// NewLoginProtectionService creates a new login protection service
+func NewLoginProtectionService(
+	attemptRepo *repository.LoginAttemptRepository,
+	emailService *EmailService,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *LoginProtectionService {
+	return &LoginProtectionService{
+		attemptRepo:  attemptRepo,
+		emailService: emailService,
+		auditLog:     auditLog,
+		config:       cfg,
+	}
+}
+
+// loginAccountKey identifies the account an attempt targets. Identifiers that match no
+// account are tracked under their own key, so they lock out the same way.
+func loginAccountKey(user *repository.User, emailOrUsername string) string {
+	if user != nil {
+		return user.ID
+	}
+	return "unknown:" + strings.ToLower(strings.TrimSpace(emailOrUsername))
+}
+
+// CheckAttempt runs before the password is checked. It returns a *LoginLockedError
+// during a lockout, or a *ProofOfWorkRequiredError when the attempt is risky and
+// does not carry a solution to a challenge issued to this IP.
+func (s *LoginProtectionService) CheckAttempt(ctx context.Context, accountKey string, login *PasswordLogin, ipAddress string) error {
+	cfg := s.config.LoginProtection
+	since := time.Now().Add(-cfg.FailureWindow)
+
+	accountStats, err := s.attemptRepo.AccountFailureStats(ctx, accountKey, since)
+	if err != nil {
+		return err
+	}
+	if wait := s.lockRemaining(accountStats, cfg.AccountFailureThreshold); wait > 0 {
+		s.auditLog.LogLoginBlocked(login.EmailOrUsername, ipAddress, "account_locked")
+		return &LoginLockedError{RetryAfter: wait}
+	}
+
+	ipStats, err := s.attemptRepo.IPFailureStats(ctx, ipAddress, since)
+	if err != nil {
+		return err
+	}
+	if wait := s.lockRemaining(ipStats, cfg.IPFailureThreshold); wait > 0 {
+		s.auditLog.LogLoginBlocked(login.EmailOrUsername, ipAddress, "ip_locked")
+		return &LoginLockedError{RetryAfter: wait}
+	}
+
+	if cfg.ProofOfWorkDifficulty <= 0 {
+		return nil
+	}
+
+	risky := cfg.ChallengeAfterFailures > 0 && accountStats.Failures >= cfg.ChallengeAfterFailures
+	if !risky && cfg.IPAccountThreshold > 0 {
+		accounts, err := s.attemptRepo.CountAccountsFailedFromIP(ctx, ipAddress, since)
+		if err != nil {
+			return err
+		}
+		risky = accounts >= cfg.IPAccountThreshold
+	}
+	if !risky {
+		return nil
+	}
+
+	if login.PowChallenge != "" {
+		pow, err := s.attemptRepo.ConsumePowChallenge(ctx, login.PowChallenge, ipAddress)
+		var notFound *repository.NotFoundError
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+		if err == nil && util.VerifyProofOfWork(pow.Challenge, login.PowSolution, pow.Difficulty) {
+			return nil
+		}
+	}
+
+	s.auditLog.LogLoginBlocked(login.EmailOrUsername, ipAddress, "proof_of_work_required")
+	return s.issueChallenge(ctx, ipAddress)
+}
+
+// RecordFailure counts a failed attempt against the account and the IP
+func (s *LoginProtectionService) RecordFailure(ctx context.Context, accountKey string, user *repository.User, login *PasswordLogin, ipAddress, userAgent, reason string) error {
+	attempt := &repository.LoginAttempt{
+		ID:         util.GenerateUUID(),
+		AccountKey: accountKey,
+		IPAddress:  ipAddress,
+		DeviceKey:  util.DeviceKey(userAgent),
+		Success:    false,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	s.auditLog.LogFailedLogin(login.EmailOrUsername, ipAddress, reason)
+
+	return s.attemptRepo.CreateAttempt(ctx, attempt)
+}
+
+// RecordSuccess records a correct password, which resets the account's failure
+// count, and emails the user when it was entered on a device they have not used before
+func (s *LoginProtectionService) RecordSuccess(ctx context.Context, user *repository.User, ipAddress, userAgent string) error {
+	deviceKey := util.DeviceKey(userAgent)
+
+	// Users with no login history yet have no known devices to compare against
+	hasHistory, err := s.attemptRepo.HasSuccessfulLogin(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	knownDevice := true
+	if hasHistory {
+		knownDevice, err = s.attemptRepo.HasSuccessfulLoginFromDevice(ctx, user.ID, deviceKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	attempt := &repository.LoginAttempt{
+		ID:         util.GenerateUUID(),
+		AccountKey: loginAccountKey(user, ""),
+		UserID:     &user.ID,
+		IPAddress:  ipAddress,
+		DeviceKey:  deviceKey,
+		Success:    true,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.attemptRepo.CreateAttempt(ctx, attempt); err != nil {
+		return err
+	}
+
+	if !knownDevice {
+		s.auditLog.LogNewDeviceLogin(user.ID, ipAddress, userAgent)
+		if s.config.LoginProtection.NotifyNewDevice {
+			go s.notifyNewDevice(user, ipAddress, userAgent)
+		}
+	}
+
+	return nil
+}
+
+// lockRemaining returns how much of a lockout is left: none below threshold, then
+// BackoffBase after the threshold-th failure, doubling per further failure up to MaxLockDuration
+func (s *LoginProtectionService) lockRemaining(stats *repository.LoginFailureStats, threshold int) time.Duration {
+	cfg := s.config.LoginProtection
+	if threshold <= 0 || stats.Failures < threshold {
+		return 0
+	}
+
+	lock := cfg.BackoffBase
+	for i := threshold; i < stats.Failures && lock < cfg.MaxLockDuration; i++ {
+		lock *= 2
+	}
+	if lock > cfg.MaxLockDuration {
+		lock = cfg.MaxLockDuration
+	}
+
+	remaining := time.Until(stats.LastFailureAt.Add(lock))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func (s *LoginProtectionService) issueChallenge(ctx context.Context, ipAddress string) error {
+	challenge, err := util.GenerateProofOfWorkChallenge()
+	if err != nil {
+		return fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	now := time.Now()
+	pow := &repository.ProofOfWorkChallenge{
+		Challenge:  challenge,
+		Difficulty: s.config.LoginProtection.ProofOfWorkDifficulty,
+		IPAddress:  ipAddress,
+		ExpiresAt:  now.Add(s.config.LoginProtection.ProofOfWorkTTL),
+		CreatedAt:  now,
+	}
+	if err := s.attemptRepo.CreatePowChallenge(ctx, pow); err != nil {
+		return err
+	}
+
+	return &ProofOfWorkRequiredError{Challenge: pow}
+}
+
+// notifyNewDevice emails the user about a sign-in from a device they haven't used before
+func (s *LoginProtectionService) notifyNewDevice(user *repository.User, ipAddress, userAgent string) {
+	device := util.ParseUserAgent(userAgent)
+	securityLink := strings.TrimRight(s.config.Server.AppURL, "/") + "/settings/security"
+
+	err := s.emailService.SendNewDeviceLoginEmail(
+		user.Email,
+		user.FirstName,
+		device.Browser+" on "+device.OS,
+		ipAddress,
+		time.Now(),
+		securityLink,
+	)
+	if err != nil {
+		log.Printf("Failed to send new device login email: %v", err)
+	}
+}
@@ -1,215 +1,184 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"path/filepath"
-	"strings"
-	"time"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/google/uuid"
-)
-
-type MediaService struct {
-	s3Client   *s3.S3
-	bucketName string
-	cdnURL     string
-}
 
-type S3Config struct {
-	Endpoint        string
-	AccessKeyID     string
-	SecretAccessKey string
-	BucketName      string
-	Region          string
-	CDN             URL string
-	UsePathStyle    bool // true for MinIO
-}
-
-// NewMediaService creates a new media upload service (S3/MinIO)
-func NewMediaService(cfg *S3Config) (*MediaService, error) {
-	if cfg == nil || cfg.AccessKeyID == "" {
-		return nil, nil // Media service not configured
-	}
+	"user-service/internal/repository"
+	"user-service/internal/util"
+	"user-service/pkg/imaging"
+)
 
-	sess, err := session.NewSession(&aws.Config{
-		Credentials:      credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
-		Endpoint:         aws.String(cfg.Endpoint),
-		Region:           aws.String(cfg.Region),
-		S3ForcePathStyle: aws.Bool(cfg.UsePathStyle),
-	})
+// Largest uploads accepted, before processing
+const (
+	MaxProfilePictureBytes = 10 * 1024 * 1024
+	MaxCoverPhotoBytes     = 20 * 1024 * 1024
+)
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 session: %w", err)
-	}
+var (
+	ErrMediaNotConfigured = errors.New("photo uploads are not available")
+	ErrPhotoTooLarge      = errors.New("photo is too large")
+	ErrPhotoUnsupported   = errors.New("photo must be a JPEG, PNG or GIF image")
+	ErrPhotoUnreadable    = errors.New("photo could not be read")
+	ErrPhotoTooSmall      = errors.New("photo is too small")
+)
 
-	return &MediaService{
-		s3Client:   s3.New(sess),
-		bucketName: cfg.BucketName,
-		cdnURL:     cfg.CDNURL,
-	}, nil
+// photoKind is how one kind of profile photo is processed and stored
+type photoKind struct {
+	repoKind string
+	spec     imaging.Spec
+	maxBytes int64
+	folder   string
+	primary  string // the variant stored as the user's photo URL
 }
 
-// UploadProfilePicture uploads a profile picture and returns the URL
-func (s *MediaService) UploadProfilePicture(userID uuid.UUID, file *multipart.FileHeader) (string, error) {
-	if s == nil {
-		return "", fmt.Errorf("media service not configured")
+var (
+	profilePictureKind = photoKind{
+		repoKind: repository.PhotoKindProfilePicture,
+		spec:     imaging.ProfilePictureSpec,
+		maxBytes: MaxProfilePictureBytes,
+		folder:   "profile-pictures",
+		primary:  "medium",
+	}
+	coverPhotoKind = photoKind{
+		repoKind: repository.PhotoKindCoverPhoto,
+		spec:     imaging.CoverPhotoSpec,
+		maxBytes: MaxCoverPhotoBytes,
+		folder:   "cover-photos",
+		primary:  "large",
 	}
+)
 
-	// Validate file type
-	if !isValidImageType(file.Filename) {
-		return "", fmt.Errorf("invalid file type. Only jpg, jpeg, png, gif allowed")
-	}
-
-	// Validate file size (max 5MB)
-	if file.Size > 5*1024*1024 {
-		return "", fmt.Errorf("file too large. Maximum size is 5MB")
-	}
-
-	// Open file
-	src, err := file.Open()
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
-	}
-	defer src.Close()
-
-	// Read file content
-	fileBytes, err := io.ReadAll(src)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
+// PhotoView is a published profile picture or cover photo
+type PhotoView struct {
+	URL      string             `json:"url"`
+	Blurhash string             `json:"blurhash"`
+	Variants []PhotoVariantView `json:"variants"`
+}
 
-	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("profile-pictures/%s/%s%s", userID.String(), uuid.New().String(), ext)
+// PhotoVariantView is one size of a published photo
+type PhotoVariantView struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
 
-	// Upload to S3/MinIO
-	_, err = s.s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.bucketName),
-		Key:           aws.String(filename),
-		Body:          bytes.NewReader(fileBytes),
-		ContentType:   aws.String(file.Header.Get("Content-Type")),
-		ContentLength: aws.Int64(file.Size),
-		ACL:           aws.String("public-read"),
-	})
+// MediaService publishes profile pictures and cover photos. Uploads are
+// identified by content, stripped of EXIF and GPS metadata, turned upright,
+// cropped and published in several sizes with a blurhash placeholder. The
+// replaced photo's files are deleted.
+type MediaService struct {
+	store    imaging.ObjectStore
+	userRepo *repository.UserRepository
+}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %w", err)
+// NewMediaService creates a new media service. With a nil store, uploads fail
+// with ErrMediaNotConfigured.
+func NewMediaService(store imaging.ObjectStore, userRepo *repository.UserRepository) *MediaService {
+	return &MediaService{
+		store:    store,
+		userRepo: userRepo,
 	}
+}
 
-	// Generate URL
-	if s.cdnURL != "" {
-		return fmt.Sprintf("%s/%s", s.cdnURL, filename), nil
-	}
+// UploadProfilePicture publishes a new profile picture for the user
+func (s *MediaService) UploadProfilePicture(ctx context.Context, userID string, upload io.Reader) (*PhotoView, error) {
+	return s.upload(ctx, userID, profilePictureKind, upload)
+}
 
-	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.bucketName, filename), nil
+// UploadCoverPhoto publishes a new cover photo for the user
+func (s *MediaService) UploadCoverPhoto(ctx context.Context, userID string, upload io.Reader) (*PhotoView, error) {
+	return s.upload(ctx, userID, coverPhotoKind, upload)
 }
 
-// UploadCoverPhoto uploads a cover photo and returns the URL
-func (s *MediaService) UploadCoverPhoto(userID uuid.UUID, file *multipart.FileHeader) (string, error) {
-	if s == nil {
-		return "", fmt.Errorf("media service not configured")
+func (s *MediaService) upload(ctx context.Context, userID string, kind photoKind, upload io.Reader) (*PhotoView, error) {
+	if s.store == nil {
+		return nil, ErrMediaNotConfigured
 	}
 
-	// Validate file type
-	if !isValidImageType(file.Filename) {
-		return "", fmt.Errorf("invalid file type. Only jpg, jpeg, png, gif allowed")
+	data, err := io.ReadAll(io.LimitReader(upload, kind.maxBytes+1))
+	if err != nil {
+		return nil, ErrPhotoUnreadable
 	}
-
-	// Validate file size (max 10MB for cover photos)
-	if file.Size > 10*1024*1024 {
-		return "", fmt.Errorf("file too large. Maximum size is 10MB")
+	if int64(len(data)) > kind.maxBytes {
+		return nil, ErrPhotoTooLarge
 	}
 
-	// Open file
-	src, err := file.Open()
+	result, err := imaging.Process(data, kind.spec)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, mapImagingError(err)
 	}
-	defer src.Close()
 
-	// Read file content
-	fileBytes, err := io.ReadAll(src)
+	prefix := fmt.Sprintf("%s/%s/%s", kind.folder, userID, util.GenerateUUID())
+	stored, err := imaging.Store(ctx, s.store, prefix, result)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return nil, err
+	}
+
+	photo := &repository.UserPhoto{Blurhash: result.Blurhash}
+	for _, image := range stored {
+		photo.Variants = append(photo.Variants, repository.UserPhotoVariant{
+			Name:   image.Variant,
+			Key:    image.Key,
+			URL:    image.URL,
+			Width:  image.Width,
+			Height: image.Height,
+		})
+		if image.Variant == kind.primary {
+			photo.URL = image.URL
+		}
 	}
 
-	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("cover-photos/%s/%s%s", userID.String(), uuid.New().String(), ext)
-
-	// Upload to S3/MinIO
-	_, err = s.s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.bucketName),
-		Key:           aws.String(filename),
-		Body:          bytes.NewReader(fileBytes),
-		ContentType:   aws.String(file.Header.Get("Content-Type")),
-		ContentLength: aws.Int64(file.Size),
-		ACL:           aws.String("public-read"),
-	})
-
+	previous, err := s.userRepo.ReplacePhoto(ctx, userID, kind.repoKind, photo)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %w", err)
+		s.deleteVariants(photo)
+		return nil, err
 	}
-
-	// Generate URL
-	if s.cdnURL != "" {
-		return fmt.Sprintf("%s/%s", s.cdnURL, filename), nil
+	if previous != nil {
+		s.deleteVariants(previous)
 	}
 
-	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.bucketName, filename), nil
+	return photoView(photo), nil
 }
 
-// DeleteFile deletes a file from S3/MinIO
-func (s *MediaService) DeleteFile(fileURL string) error {
-	if s == nil {
-		return nil
-	}
-
-	// Extract key from URL
-	key := extractKeyFromURL(fileURL, s.bucketName, s.cdnURL)
-	if key == "" {
-		return fmt.Errorf("invalid file URL")
+// deleteVariants removes a photo's files. Failures only leave unreferenced
+// files behind, so they are ignored.
+func (s *MediaService) deleteVariants(photo *repository.UserPhoto) {
+	for _, variant := range photo.Variants {
+		s.store.Delete(context.Background(), variant.Key)
 	}
-
-	_, err := s.s3Client.DeleteObject(&s3.DeleteObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    aws.String(key),
-	})
-
-	return err
 }
 
-// isValidImageType checks if the file has a valid image extension
-func isValidImageType(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	validExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
-	for _, validExt := range validExts {
-		if ext == validExt {
-			return true
-		}
+func mapImagingError(err error) error {
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return ErrPhotoUnsupported
+	case errors.Is(err, imaging.ErrCorruptImage):
+		return ErrPhotoUnreadable
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		return ErrPhotoTooLarge
+	case errors.Is(err, imaging.ErrImageTooSmall):
+		return ErrPhotoTooSmall
 	}
-	return false
+	return err
 }
 
-// extractKeyFromURL extracts the S3 key from a URL
-func extractKeyFromURL(url, bucket, cdnURL string) string {
-	// Handle CDN URL
-	if cdnURL != "" && strings.HasPrefix(url, cdnURL) {
-		return strings.TrimPrefix(url, cdnURL+"/")
-	}
-
-	// Handle S3 URL
-	s3Prefix := fmt.Sprintf("https://%s.s3.amazonaws.com/", bucket)
-	if strings.HasPrefix(url, s3Prefix) {
-		return strings.TrimPrefix(url, s3Prefix)
-	}
-
-	return ""
+func photoView(photo *repository.UserPhoto) *PhotoView {
+	view := &PhotoView{
+		URL:      photo.URL,
+		Blurhash: photo.Blurhash,
+		Variants: make([]PhotoVariantView, 0, len(photo.Variants)),
+	}
+	for _, variant := range photo.Variants {
+		view.Variants = append(view.Variants, PhotoVariantView{
+			Name:   variant.Name,
+			URL:    variant.URL,
+			Width:  variant.Width,
+			Height: variant.Height,
+		})
+	}
+	return view
 }
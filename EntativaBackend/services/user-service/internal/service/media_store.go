@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"user-service/internal/config"
+	"user-service/pkg/imaging"
+)
+
+// photoCacheControl lets CDNs and apps keep photos forever; a new photo always
+// gets new keys
+const photoCacheControl = "public, max-age=31536000, immutable"
+
+// S3PhotoStore publishes processed photos to S3 or MinIO
+type S3PhotoStore struct {
+	client    *s3.S3
+	bucket    string
+	publicURL string
+}
+
+// NewS3PhotoStore connects to the configured bucket. It returns nil, turning
+// photo uploads off, when no access key is configured.
+func NewS3PhotoStore(cfg config.MediaConfig) (imaging.ObjectStore, error) {
+	if cfg.AccessKeyID == "" {
+		return nil, nil
+	}
+
+	awsConfig := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.UsePathStyle),
+	}
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 session: %w", err)
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("https://%s.s3.amazonaws.com", cfg.Bucket)
+	}
+
+	return &S3PhotoStore{
+		client:    s3.New(sess),
+		bucket:    cfg.Bucket,
+		publicURL: publicURL,
+	}, nil
+}
+
+// Put uploads a photo, readable by anyone
+func (s *S3PhotoStore) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(data))),
+		CacheControl:  aws.String(photoCacheControl),
+		ACL:           aws.String("public-read"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+
+	return fmt.Sprintf("%s/%s", s.publicURL, key), nil
+}
+
+// Delete removes a photo
+func (s *S3PhotoStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
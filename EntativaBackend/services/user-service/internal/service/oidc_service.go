@@ -0,0 +1,733 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+// Errors that must not be reported by redirecting, because the redirect_uri
+// cannot be trusted (RFC 6749 section 4.1.2.1)
+var (
+	ErrUnknownClient       = errors.New("unknown client_id")
+	ErrRedirectURIMismatch = errors.New("redirect_uri is not registered for this client")
+)
+
+var (
+	ErrInvalidRedirectURI   = errors.New("redirect URIs must be absolute https URLs without a fragment; http is only allowed for localhost")
+	ErrInvalidClientName    = errors.New("client name must be between 1 and 100 characters")
+	ErrOAuthClientNotFound  = errors.New("client not found")
+	ErrOAuthConsentNotFound = errors.New("consent not found")
+)
+
+// OAuthError is a protocol error returned to the client, either in a redirect
+// or in a token/userinfo response, with an RFC 6749 error code
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// Scopes understood by the provider
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+var scopeDescriptions = map[string]string{
+	ScopeOpenID:  "Sign you in with your Entativa account",
+	ScopeProfile: "See your name, username and profile picture",
+	ScopeEmail:   "See your email address",
+}
+
+const (
+	maxRedirectURIs       = 10
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// AuthorizationRequest holds the query parameters of an authorization request
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizationRequestFromQuery reads an authorization request from a query string
+func AuthorizationRequestFromQuery(query url.Values) *AuthorizationRequest {
+	return &AuthorizationRequest{
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		ResponseType:        query.Get("response_type"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		Nonce:               query.Get("nonce"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+}
+
+// ScopeDescription is a scope as shown on the consent screen
+type ScopeDescription struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// AuthorizationPrompt is what the consent screen shows for an authorization request
+type AuthorizationPrompt struct {
+	ClientID        string             `json:"client_id"`
+	ClientName      string             `json:"client_name"`
+	Scopes          []ScopeDescription `json:"scopes"`
+	ConsentRequired bool               `json:"consent_required"`
+}
+
+// TokenRequest holds the form parameters of a token request
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	ClientID     string
+	ClientSecret string
+}
+
+// TokenResponse is returned by the token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// RegisterClientRequest is a developer registering an application
+type RegisterClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Confidential bool     `json:"confidential"` // server-side apps that can keep a secret
+}
+
+// RegisteredClient is a client as shown to its owner. ClientSecret is only set
+// in the response to registration; it cannot be retrieved later.
+type RegisteredClient struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AuthorizedApp is a client the user has approved
+type AuthorizedApp struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+// oauthAccessTokenClaims are the claims of an access token issued to a client (RFC 9068)
+type oauthAccessTokenClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// OIDCService is an OpenID Connect provider, so other apps (including Vignette)
+// can offer "Sign in with Entativa".
+//
+// Only the authorization code flow is supported, and every client must use
+// PKCE with S256; confidential clients also authenticate with their secret.
+// Tokens are signed by the same keyring as session tokens and verified through
+// the same JWKS, but access tokens carry the "at+jwt" type and no session, so
+// they are never accepted as a first-party login.
+type OIDCService struct {
+	oauthRepo *repository.OAuthRepository
+	userRepo  *repository.UserRepository
+	auditLog  *AuditLog
+	config    *config.Config
+}
+
+// NewOIDCService creates a new OpenID Connect provider service
+func NewOIDCService(
+	oauthRepo *repository.OAuthRepository,
+	userRepo *repository.UserRepository,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *OIDCService {
+	return &OIDCService{
+		oauthRepo: oauthRepo,
+		userRepo:  userRepo,
+		auditLog:  auditLog,
+		config:    cfg,
+	}
+}
+
+// Discovery returns the OpenID Provider metadata
+func (s *OIDCService) Discovery() map[string]interface{} {
+	issuer := s.config.OIDC.Issuer
+
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": util.SigningAlgorithms(),
+		"scopes_supported":                      supportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce",
+			"name", "given_name", "family_name", "preferred_username", "picture", "updated_at",
+			"email", "email_verified",
+		},
+		"authorization_response_iss_parameter_supported": true,
+	}
+}
+
+// ValidateAuthorizationRequest checks an authorization request and returns the
+// client and the requested scopes. ErrUnknownClient and ErrRedirectURIMismatch
+// must be shown to the user; an *OAuthError can be sent back to the redirect_uri.
+func (s *OIDCService) ValidateAuthorizationRequest(ctx context.Context, req *AuthorizationRequest) (*repository.OAuthClient, []string, error) {
+	client, err := s.oauthRepo.FindClientByID(ctx, req.ClientID)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil, ErrUnknownClient
+		}
+		return nil, nil, err
+	}
+
+	if !containsString(client.RedirectURIs, req.RedirectURI) {
+		return nil, nil, ErrRedirectURIMismatch
+	}
+
+	if req.ResponseType != "code" {
+		return nil, nil, oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, nil, oauthError("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if !containsString(scopes, ScopeOpenID) {
+		return nil, nil, oauthError("invalid_scope", "the openid scope is required")
+	}
+	for _, scope := range scopes {
+		if !containsString(supportedScopes, scope) || !containsString(client.AllowedScopes, scope) {
+			return nil, nil, oauthError("invalid_scope", fmt.Sprintf("scope %q is not allowed", scope))
+		}
+	}
+
+	return client, uniqueStrings(scopes), nil
+}
+
+// DescribeAuthorizationRequest returns what the consent screen should show.
+// Consent is not required for first-party clients or when the user has already
+// approved every requested scope.
+func (s *OIDCService) DescribeAuthorizationRequest(ctx context.Context, userID string, req *AuthorizationRequest) (*AuthorizationPrompt, error) {
+	client, scopes, err := s.ValidateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	consentRequired, err := s.consentRequired(ctx, userID, client, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := &AuthorizationPrompt{
+		ClientID:        client.ID,
+		ClientName:      client.Name,
+		ConsentRequired: consentRequired,
+	}
+	for _, scope := range scopes {
+		prompt.Scopes = append(prompt.Scopes, ScopeDescription{Scope: scope, Description: scopeDescriptions[scope]})
+	}
+
+	return prompt, nil
+}
+
+// Authorize completes an authorization request for a signed-in user and returns
+// the URL to send the browser back to: with a code if the user approved, or
+// access_denied if they declined.
+func (s *OIDCService) Authorize(ctx context.Context, user *repository.User, req *AuthorizationRequest, approve bool, ipAddress string) (string, error) {
+	client, scopes, err := s.ValidateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !approve {
+		return s.ErrorRedirect(req, oauthError("access_denied", "the user declined the request"))
+	}
+
+	consentRequired, err := s.consentRequired(ctx, user.ID, client, scopes)
+	if err != nil {
+		return "", err
+	}
+	if consentRequired {
+		s.auditLog.LogOAuthConsentGranted(user.ID, client.ID, scopes, ipAddress)
+	}
+
+	// Save consent for first-party clients too, so the user can see and revoke their access
+	err = s.oauthRepo.SaveConsent(ctx, &repository.OAuthConsent{
+		UserID:    user.ID,
+		ClientID:  client.ID,
+		Scopes:    scopes,
+		GrantedAt: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	code, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.oauthRepo.CreateAuthorizationCode(ctx, &repository.OAuthAuthorizationCode{
+		CodeHash:      util.HashVerifier(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(s.config.OIDC.AuthorizationCodeTTL),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("iss", s.config.OIDC.Issuer)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	return appendQuery(req.RedirectURI, params)
+}
+
+// ErrorRedirect returns the redirect_uri of a request with an error response appended.
+// Only call it once the client and redirect_uri have been validated.
+func (s *OIDCService) ErrorRedirect(req *AuthorizationRequest, oauthErr *OAuthError) (string, error) {
+	params := url.Values{}
+	params.Set("error", oauthErr.Code)
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	params.Set("iss", s.config.OIDC.Issuer)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	return appendQuery(req.RedirectURI, params)
+}
+
+// ExchangeCode redeems an authorization code for an access token and ID token.
+// Errors are *OAuthError unless something unexpected failed.
+func (s *OIDCService) ExchangeCode(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, oauthError("unsupported_grant_type", "only authorization_code is supported")
+	}
+	if req.Code == "" || req.CodeVerifier == "" || req.RedirectURI == "" {
+		return nil, oauthError("invalid_request", "code, code_verifier and redirect_uri are required")
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.oauthRepo.ConsumeAuthorizationCode(ctx, util.HashVerifier(req.Code))
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, oauthError("invalid_grant", "authorization code is invalid, expired or already used")
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return nil, oauthError("invalid_grant", "authorization code was not issued to this client and redirect_uri")
+	}
+	if !pkceMatches(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, code.UserID)
+	if err != nil || !user.IsActive || user.IsDeleted {
+		return nil, oauthError("invalid_grant", "the user is no longer available")
+	}
+
+	now := time.Now()
+	scope := strings.Join(code.Scopes, " ")
+
+	accessToken, err := util.SignToken(&oauthAccessTokenClaims{
+		ClientID: client.ID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.config.OIDC.Issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{s.config.OIDC.Issuer},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.OIDC.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        util.GenerateUUID(),
+		},
+	}, util.OAuthAccessTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	idClaims := jwt.MapClaims(s.userClaims(user, code.Scopes))
+	idClaims["iss"] = s.config.OIDC.Issuer
+	idClaims["aud"] = client.ID
+	idClaims["exp"] = now.Add(s.config.OIDC.IDTokenTTL).Unix()
+	idClaims["iat"] = now.Unix()
+	if code.Nonce != "" {
+		idClaims["nonce"] = code.Nonce
+	}
+
+	idToken, err := util.SignToken(idClaims, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.OIDC.AccessTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       scope,
+	}, nil
+}
+
+// UserInfo returns the claims an access token's scopes allow. The token stops
+// working as soon as the user revokes the client's consent.
+func (s *OIDCService) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	invalidToken := oauthError("invalid_token", "the access token is invalid or expired")
+
+	claims := &oauthAccessTokenClaims{}
+	tokenType, err := util.ParseSignedToken(accessToken, claims)
+	if err != nil || tokenType != util.OAuthAccessTokenType {
+		return nil, invalidToken
+	}
+	if claims.Issuer != s.config.OIDC.Issuer || !containsString(claims.Audience, s.config.OIDC.Issuer) {
+		return nil, invalidToken
+	}
+
+	if _, err := s.oauthRepo.FindConsent(ctx, claims.Subject, claims.ClientID); err != nil {
+		return nil, invalidToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, claims.Subject)
+	if err != nil || !user.IsActive || user.IsDeleted {
+		return nil, invalidToken
+	}
+
+	return s.userClaims(user, strings.Fields(claims.Scope)), nil
+}
+
+// RegisterClient registers an application owned by a user. Confidential clients
+// get a secret, which is returned once and only stored hashed.
+func (s *OIDCService) RegisterClient(ctx context.Context, ownerUserID string, req *RegisterClientRequest, ipAddress string) (*RegisteredClient, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidClientName
+	}
+	if len(req.RedirectURIs) == 0 || len(req.RedirectURIs) > maxRedirectURIs {
+		return nil, fmt.Errorf("%w (between 1 and %d allowed)", ErrInvalidRedirectURI, maxRedirectURIs)
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+
+	clientID, err := util.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &repository.OAuthClient{
+		ID:            clientID,
+		Name:          name,
+		RedirectURIs:  uniqueStrings(req.RedirectURIs),
+		AllowedScopes: supportedScopes,
+		OwnerUserID:   ownerUserID,
+		CreatedAt:     time.Now(),
+	}
+
+	var secret string
+	if req.Confidential {
+		secret, err = util.GenerateSecureToken(32)
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash = util.HashVerifier(secret)
+	}
+
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogOAuthClientRegistered(ownerUserID, client.ID, ipAddress)
+
+	registered := toRegisteredClient(client)
+	registered.ClientSecret = secret
+	return registered, nil
+}
+
+// ListClients lists the applications a user has registered
+func (s *OIDCService) ListClients(ctx context.Context, ownerUserID string) ([]*RegisteredClient, error) {
+	clients, err := s.oauthRepo.ListClientsByOwner(ctx, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make([]*RegisteredClient, 0, len(clients))
+	for _, client := range clients {
+		registered = append(registered, toRegisteredClient(client))
+	}
+	return registered, nil
+}
+
+// DeleteClient deletes an application owned by the user. Every user's consent
+// and pending codes go with it.
+func (s *OIDCService) DeleteClient(ctx context.Context, ownerUserID, clientID, ipAddress string) error {
+	if err := s.oauthRepo.DeleteClient(ctx, ownerUserID, clientID); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrOAuthClientNotFound
+		}
+		return err
+	}
+
+	s.auditLog.LogOAuthClientDeleted(ownerUserID, clientID, ipAddress)
+	return nil
+}
+
+// ListAuthorizedApps lists the clients the user has approved
+func (s *OIDCService) ListAuthorizedApps(ctx context.Context, userID string) ([]*AuthorizedApp, error) {
+	consents, err := s.oauthRepo.ListConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*AuthorizedApp, 0, len(consents))
+	for _, consent := range consents {
+		apps = append(apps, &AuthorizedApp{
+			ClientID:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     consent.Scopes,
+			GrantedAt:  consent.GrantedAt,
+		})
+	}
+	return apps, nil
+}
+
+// RevokeConsent removes a client's access to the user's account. Its access
+// tokens stop working at the userinfo endpoint immediately.
+func (s *OIDCService) RevokeConsent(ctx context.Context, userID, clientID, ipAddress string) error {
+	if err := s.oauthRepo.RevokeConsent(ctx, userID, clientID); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrOAuthConsentNotFound
+		}
+		return err
+	}
+
+	s.auditLog.LogOAuthConsentRevoked(userID, clientID, ipAddress)
+	return nil
+}
+
+// consentRequired reports whether the user must approve the request on the consent screen
+func (s *OIDCService) consentRequired(ctx context.Context, userID string, client *repository.OAuthClient, scopes []string) (bool, error) {
+	if client.IsFirstParty {
+		return false, nil
+	}
+
+	consent, err := s.oauthRepo.FindConsent(ctx, userID, client.ID)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	for _, scope := range scopes {
+		if !containsString(consent.Scopes, scope) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// authenticateClient identifies the client at the token endpoint. Confidential
+// clients must present their secret; public clients must not have one.
+func (s *OIDCService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*repository.OAuthClient, error) {
+	invalidClient := oauthError("invalid_client", "client authentication failed")
+
+	if clientID == "" {
+		return nil, invalidClient
+	}
+
+	client, err := s.oauthRepo.FindClientByID(ctx, clientID)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, invalidClient
+		}
+		return nil, err
+	}
+
+	if client.IsConfidential() {
+		if clientSecret == "" || !util.VerifierMatches(clientSecret, client.SecretHash) {
+			return nil, invalidClient
+		}
+	} else if clientSecret != "" {
+		return nil, invalidClient
+	}
+
+	return client, nil
+}
+
+// userClaims returns the standard claims about a user that the scopes allow
+func (s *OIDCService) userClaims(user *repository.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": user.ID,
+	}
+
+	if containsString(scopes, ScopeProfile) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["preferred_username"] = user.Username
+		claims["updated_at"] = user.UpdatedAt.Unix()
+		if user.ProfilePictureURL != nil {
+			claims["picture"] = *user.ProfilePictureURL
+		}
+	}
+
+	if containsString(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.HasVerifiedEmail()
+	}
+
+	return claims
+}
+
+func toRegisteredClient(client *repository.OAuthClient) *RegisteredClient {
+	return &RegisteredClient{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.AllowedScopes,
+		Confidential: client.IsConfidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// pkceMatches checks a code_verifier against an S256 code_challenge (RFC 7636)
+func pkceMatches(verifier, challenge string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// validRedirectURI accepts absolute https URLs, and http only on loopback for native apps and development
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Fragment != "" || strings.Contains(raw, "#") {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return false
+	}
+}
+
+// appendQuery adds params to a URL that may already have a query string
+func appendQuery(rawURL string, params url.Values) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
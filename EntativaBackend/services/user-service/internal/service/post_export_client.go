@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"user-service/internal/config"
+)
+
+// PostExportPage is one page of a user's posts, comments, likes or saves from the post service
+type PostExportPage struct {
+	Items      json.RawMessage `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// PostExportClient pages through the data the post service holds about a user
+type PostExportClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewPostExportClient creates a client for the post service's internal export API
+func NewPostExportClient(cfg *config.Config) *PostExportClient {
+	return &PostExportClient{
+		baseURL: cfg.DataExport.PostServiceURL,
+		token:   cfg.DataExport.PostServiceToken,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FetchPage fetches the page of section that starts at cursor ("" for the first page)
+func (c *PostExportClient) FetchPage(ctx context.Context, userID, section, cursor string) (*PostExportPage, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/export/users/%s/%s", c.baseURL, url.PathEscape(userID), url.PathEscape(section))
+	if cursor != "" {
+		endpoint += "?cursor=" + url.QueryEscape(cursor)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post service unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("post service returned %d for %s: %s", resp.StatusCode, section, body)
+	}
+
+	var page PostExportPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("invalid post service response: %w", err)
+	}
+	if len(page.Items) == 0 || string(page.Items) == "null" {
+		page.Items = json.RawMessage("[]")
+	}
+
+	return &page, nil
+}
@@ -6,21 +6,33 @@ import (
 	"fmt"
 	"time"
 
-	"socialink/user-service/internal/model"
-	"socialink/user-service/internal/repository"
+	"user-service/internal/model"
+	"user-service/internal/repository"
 
 	"github.com/google/uuid"
 )
 
 type ProfileService struct {
-	profileRepo *repository.ProfileRepository
-	userRepo    *repository.UserRepository
+	profileRepo   *repository.ProfileRepository
+	userRepo      *repository.UserRepository
+	blockService  *BlockService
+	friendGraph   *repository.FriendGraphRepository
+	audienceLists *AudienceListService
 }
 
-func NewProfileService(profileRepo *repository.ProfileRepository, userRepo *repository.UserRepository) *ProfileService {
+func NewProfileService(
+	profileRepo *repository.ProfileRepository,
+	userRepo *repository.UserRepository,
+	blockService *BlockService,
+	friendGraph *repository.FriendGraphRepository,
+	audienceLists *AudienceListService,
+) *ProfileService {
 	return &ProfileService{
-		profileRepo: profileRepo,
-		userRepo:    userRepo,
+		profileRepo:   profileRepo,
+		userRepo:      userRepo,
+		blockService:  blockService,
+		friendGraph:   friendGraph,
+		audienceLists: audienceLists,
 	}
 }
 
@@ -44,22 +56,14 @@ func (s *ProfileService) GetOrCreateProfile(ctx context.Context, userID uuid.UUI
 func (s *ProfileService) createDefaultProfile(ctx context.Context, userID uuid.UUID) (*model.Profile, error) {
 	now := time.Now()
 	profile := &model.Profile{
-		ID:        uuid.New(),
-		UserID:    userID,
-		Languages: []string{},
-		Work:      []model.WorkExperience{},
-		Education: []model.EducationEntry{},
-		Visibility: &model.ProfileVisibility{
-			Bio:              "public",
-			Work:             "public",
-			Education:        "public",
-			ContactInfo:      "friends",
-			RelationshipInfo: "friends",
-			Hometown:         "public",
-			Birthday:         "friends",
-		},
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:         uuid.New(),
+		UserID:     userID,
+		Languages:  []string{},
+		Work:       []model.WorkExperience{},
+		Education:  []model.EducationEntry{},
+		Visibility: model.DefaultProfileVisibility(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
 	if err := s.profileRepo.CreateProfile(ctx, profile); err != nil {
@@ -311,6 +315,10 @@ func (s *ProfileService) UpdateSocialLinks(ctx context.Context, userID uuid.UUID
 
 // UpdateVisibility updates profile visibility settings
 func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID, req *model.UpdateVisibilityRequest) (*model.Profile, error) {
+	if err := s.validateAudiences(ctx, userID, req); err != nil {
+		return nil, err
+	}
+
 	profile, err := s.GetOrCreateProfile(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -318,15 +326,7 @@ func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID,
 
 	// Initialize if nil
 	if profile.Visibility == nil {
-		profile.Visibility = &model.ProfileVisibility{
-			Bio:              "public",
-			Work:             "public",
-			Education:        "public",
-			ContactInfo:      "friends",
-			RelationshipInfo: "friends",
-			Hometown:         "public",
-			Birthday:         "friends",
-		}
+		profile.Visibility = model.DefaultProfileVisibility()
 	}
 
 	// Update fields
@@ -359,21 +359,69 @@ func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID,
 	return profile, nil
 }
 
-// GetProfileWithUser gets profile along with user information
-func (s *ProfileService) GetProfileWithUser(ctx context.Context, userID uuid.UUID) (*model.ProfileResponse, error) {
+// GetProfileWithUser gets profile along with user information, as viewerID may see it.
+// Fields are stripped according to the owner's visibility settings; users who have
+// blocked one another get ErrProfileUnavailable. A zero viewerID is a signed-out viewer.
+func (s *ProfileService) GetProfileWithUser(ctx context.Context, userID, viewerID uuid.UUID) (*model.ProfileResponse, error) {
+	relationship, err := s.resolveViewer(ctx, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
 	profile, err := s.GetOrCreateProfile(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get user info
-	user, err := s.userRepo.GetByID(ctx, userID)
+	user, err := s.userRepo.FindByID(ctx, userID.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	memberOf, err := s.listMemberships(ctx, userID, viewerID, profile.Visibility, relationship)
+	if err != nil {
+		return nil, err
+	}
+
 	response := profile.ToProfileResponse()
-	response.User = user.ToUserResponse()
+	response.User = toUserResponse(user)
+	applyProfileVisibility(response, profile.Visibility, relationship, memberOf)
 
 	return response, nil
 }
+
+// ViewProfileAs shows the owner their profile as viewerID would see it
+func (s *ProfileService) ViewProfileAs(ctx context.Context, ownerID, viewerID uuid.UUID) (*model.ProfileResponse, error) {
+	if _, err := s.userRepo.FindByID(ctx, viewerID.String()); err != nil {
+		return nil, ErrViewerNotFound
+	}
+
+	return s.GetProfileWithUser(ctx, ownerID, viewerID)
+}
+
+// toUserResponse maps an account to the user summary shown with a profile
+func toUserResponse(user *repository.User) *model.UserResponse {
+	id, _ := uuid.Parse(user.ID)
+	gender := ""
+	if user.Gender != nil {
+		gender = *user.Gender
+	}
+
+	return &model.UserResponse{
+		ID:                id,
+		FirstName:         user.FirstName,
+		LastName:          user.LastName,
+		Email:             user.Email,
+		Username:          user.Username,
+		Birthday:          user.Birthday,
+		Gender:            gender,
+		PhoneNumber:       user.PhoneNumber,
+		Bio:               user.Bio,
+		ProfilePictureURL: user.ProfilePictureURL,
+		CoverPhotoURL:     user.CoverPhotoURL,
+		IsActive:          user.IsActive,
+		CreatedAt:         user.CreatedAt,
+		LastLoginAt:       user.LastLoginAt,
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"user-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrProfileUnavailable means the viewer and the owner have blocked one another.
+	// Handlers report it exactly like a profile that does not exist.
+	ErrProfileUnavailable = errors.New("profile not available")
+	ErrViewerNotFound     = errors.New("viewer not found")
+	ErrInvalidAudience    = errors.New("audience must be public, friends, only_me or one of your lists")
+)
+
+// viewerRelationship is how the person looking at a profile relates to its owner
+type viewerRelationship int
+
+const (
+	viewerStranger viewerRelationship = iota
+	viewerFriend
+	viewerOwner
+)
+
+// resolveViewer works out what viewerID is to the profile's owner
+func (s *ProfileService) resolveViewer(ctx context.Context, ownerID, viewerID uuid.UUID) (viewerRelationship, error) {
+	if viewerID == ownerID {
+		return viewerOwner, nil
+	}
+	if viewerID == uuid.Nil {
+		return viewerStranger, nil
+	}
+
+	blocked, err := s.blockService.IsBlocked(ctx, ownerID.String(), viewerID.String())
+	if err != nil {
+		return viewerStranger, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return viewerStranger, ErrProfileUnavailable
+	}
+
+	friends, err := s.friendGraph.FilterFriends(ctx, ownerID.String(), []string{viewerID.String()})
+	if err != nil {
+		return viewerStranger, fmt.Errorf("failed to check friendship: %w", err)
+	}
+	if len(friends) > 0 {
+		return viewerFriend, nil
+	}
+
+	return viewerStranger, nil
+}
+
+// listMemberships works out which of the audience lists used by visibility the
+// viewer is in. Only needed for viewers who are signed in and not the owner.
+func (s *ProfileService) listMemberships(ctx context.Context, ownerID, viewerID uuid.UUID, visibility *model.ProfileVisibility, relationship viewerRelationship) (map[string]bool, error) {
+	if visibility == nil || relationship == viewerOwner || viewerID == uuid.Nil {
+		return nil, nil
+	}
+
+	listIDs := visibility.ListIDs()
+	if len(listIDs) == 0 {
+		return nil, nil
+	}
+
+	memberOf, err := s.audienceLists.MemberOfLists(ctx, ownerID.String(), viewerID.String(), listIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audience lists: %w", err)
+	}
+	return memberOf, nil
+}
+
+// validateAudiences checks each audience in req is one a profile field can be
+// shown to, and that any lists belong to the owner
+func (s *ProfileService) validateAudiences(ctx context.Context, ownerID uuid.UUID, req *model.UpdateVisibilityRequest) error {
+	fields := []*string{req.Bio, req.Work, req.Education, req.ContactInfo, req.RelationshipInfo, req.Hometown, req.Birthday}
+
+	var listIDs []string
+	for _, audience := range fields {
+		if audience == nil {
+			continue
+		}
+		switch *audience {
+		case model.AudiencePublic, model.AudienceFriends, model.AudienceOnlyMe:
+			continue
+		}
+		listID, ok := model.AudienceListID(*audience)
+		if !ok {
+			return ErrInvalidAudience
+		}
+		listIDs = append(listIDs, listID)
+	}
+	if req.ContactInfo != nil && *req.ContactInfo == model.AudiencePublic {
+		return ErrInvalidAudience
+	}
+
+	if err := s.audienceLists.CheckOwnership(ctx, ownerID.String(), listIDs); err != nil {
+		if errors.Is(err, ErrAudienceListNotFound) {
+			return ErrInvalidAudience
+		}
+		return err
+	}
+	return nil
+}
+
+// canSee reports whether a viewer may see a field shown to audience. A list
+// audience is seen by the list's members; unknown audiences are treated as
+// only_me.
+func (r viewerRelationship) canSee(audience string, memberOf map[string]bool) bool {
+	switch audience {
+	case model.AudiencePublic:
+		return true
+	case model.AudienceFriends:
+		return r >= viewerFriend
+	}
+
+	if listID, ok := model.AudienceListID(audience); ok && memberOf[listID] {
+		return true
+	}
+	return r == viewerOwner
+}
+
+// applyProfileVisibility strips the fields of response the viewer may not see.
+// memberOf holds the owner's lists the viewer is in. The owner sees everything,
+// including their visibility settings.
+func applyProfileVisibility(response *model.ProfileResponse, visibility *model.ProfileVisibility, relationship viewerRelationship, memberOf map[string]bool) {
+	if relationship == viewerOwner {
+		return
+	}
+
+	audiences := model.DefaultProfileVisibility()
+	if visibility != nil {
+		audiences.Bio = audienceOrDefault(visibility.Bio, audiences.Bio)
+		audiences.Work = audienceOrDefault(visibility.Work, audiences.Work)
+		audiences.Education = audienceOrDefault(visibility.Education, audiences.Education)
+		audiences.ContactInfo = audienceOrDefault(visibility.ContactInfo, audiences.ContactInfo)
+		audiences.RelationshipInfo = audienceOrDefault(visibility.RelationshipInfo, audiences.RelationshipInfo)
+		audiences.Hometown = audienceOrDefault(visibility.Hometown, audiences.Hometown)
+		audiences.Birthday = audienceOrDefault(visibility.Birthday, audiences.Birthday)
+	}
+
+	response.Visibility = nil
+	if response.User != nil {
+		response.User.LastLoginAt = nil
+	}
+
+	if !relationship.canSee(audiences.Bio, memberOf) {
+		response.About = nil
+		response.FavoriteQuotes = nil
+		if response.User != nil {
+			response.User.Bio = nil
+		}
+	}
+	if !relationship.canSee(audiences.Work, memberOf) {
+		response.Work = nil
+	}
+	if !relationship.canSee(audiences.Education, memberOf) {
+		response.Education = nil
+	}
+	if !relationship.canSee(audiences.ContactInfo, memberOf) {
+		response.ContactInfo = nil
+		if response.User != nil {
+			response.User.Email = ""
+			response.User.PhoneNumber = nil
+		}
+	}
+	if !relationship.canSee(audiences.RelationshipInfo, memberOf) {
+		response.RelationshipStatus = nil
+		response.InterestedIn = nil
+	}
+	if !relationship.canSee(audiences.Hometown, memberOf) {
+		response.Hometown = nil
+		response.CurrentCity = nil
+	}
+	if !relationship.canSee(audiences.Birthday, memberOf) && response.User != nil {
+		response.User.Birthday = nil
+	}
+}
+
+func audienceOrDefault(audience, fallback string) string {
+	if audience == "" {
+		return fallback
+	}
+	return audience
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+var (
+	ErrInvalidRefreshToken  = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrRevokeCurrentSession = errors.New("use logout to end the current session")
+)
+
+// SessionTokens is the token pair handed to a client for a session
+type SessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // access token lifetime in seconds
+}
+
+// SessionService issues sessions and rotates their refresh tokens.
+//
+// Every session is one refresh token family: a login creates the session with
+// a fresh opaque refresh token, and each /auth/refresh exchanges the current
+// token for a new one. Presenting a token that was already exchanged means it
+// leaked (or the client is replaying it), so the whole session is revoked.
+type SessionService struct {
+	sessionRepo *repository.SessionRepository
+	userRepo    *repository.UserRepository
+	ipLocator   IPLocator
+	auditLog    *AuditLog
+	config      *config.Config
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(
+	sessionRepo *repository.SessionRepository,
+	userRepo *repository.UserRepository,
+	ipLocator IPLocator,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		ipLocator:   ipLocator,
+		auditLog:    auditLog,
+		config:      cfg,
+	}
+}
+
+// IssueSession creates a new session for a user who has just authenticated
+func (s *SessionService) IssueSession(ctx context.Context, user *repository.User, ipAddress, userAgent string) (*SessionTokens, error) {
+	sessionID := util.GenerateUUID()
+
+	accessToken, err := util.GenerateAccessToken(user.ID, user.Username, user.Email, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &repository.Session{
+		ID:           sessionID,
+		UserID:       user.ID,
+		AccessToken:  accessToken,
+		RefreshToken: util.HashRefreshToken(refreshToken),
+		DeviceInfo:   userAgent,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		ExpiresAt:    now.Add(s.config.JWT.RefreshTokenExpiry),
+		CreatedAt:    now,
+		LastActiveAt: now,
+	}
+
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:    session.ID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.config.JWT.AccessTokenExpiry.Seconds()),
+	}, nil
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new refresh token
+func (s *SessionService) RefreshSession(ctx context.Context, refreshToken, ipAddress string) (*SessionTokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	tokenHash := util.HashRefreshToken(refreshToken)
+
+	session, err := s.sessionRepo.FindSessionByRefreshToken(ctx, tokenHash)
+	if err != nil {
+		var notFound *repository.NotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
+		return nil, s.handleUnknownRefreshToken(ctx, tokenHash, ipAddress)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil || !user.IsActive {
+		_ = s.sessionRepo.RevokeSessionFamily(ctx, session.ID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	accessToken, err := util.GenerateAccessToken(user.ID, user.Username, user.Email, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	newRefreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.AccessToken = accessToken
+	session.RefreshToken = util.HashRefreshToken(newRefreshToken)
+	session.IPAddress = ipAddress
+	session.ExpiresAt = now.Add(s.config.JWT.RefreshTokenExpiry)
+	session.LastActiveAt = now
+
+	if err := s.sessionRepo.RotateRefreshToken(ctx, session, tokenHash); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			// Lost a race with a concurrent refresh of the same token
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:    session.ID,
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int(s.config.JWT.AccessTokenExpiry.Seconds()),
+	}, nil
+}
+
+// ActiveSession describes a signed-in device in the session list
+type ActiveSession struct {
+	ID           string          `json:"id"`
+	Device       util.DeviceInfo `json:"device"`
+	IPAddress    string          `json:"ip_address"`
+	Location     string          `json:"location,omitempty"`
+	Current      bool            `json:"current"`
+	CreatedAt    time.Time       `json:"created_at"`
+	LastActiveAt time.Time       `json:"last_active_at"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+}
+
+// ListSessions lists a user's active sessions, most recently used first.
+// currentSessionID marks the session the request was made with.
+func (s *SessionService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*ActiveSession, error) {
+	sessions, err := s.sessionRepo.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*ActiveSession, 0, len(sessions))
+	for _, session := range sessions {
+		active = append(active, &ActiveSession{
+			ID:           session.ID,
+			Device:       util.ParseUserAgent(session.UserAgent),
+			IPAddress:    session.IPAddress,
+			Location:     s.ipLocator.Locate(ctx, session.IPAddress),
+			Current:      session.ID == currentSessionID,
+			CreatedAt:    session.CreatedAt,
+			LastActiveAt: session.LastActiveAt,
+			ExpiresAt:    session.ExpiresAt,
+		})
+	}
+
+	return active, nil
+}
+
+// RevokeSession signs out one of the user's other sessions. Its refresh token
+// stops working at once and its access token at the next request.
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID, currentSessionID, ipAddress string) error {
+	if sessionID == currentSessionID {
+		return ErrRevokeCurrentSession
+	}
+
+	if err := s.sessionRepo.RevokeUserSession(ctx, userID, sessionID); err != nil {
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	s.auditLog.LogSessionRevoked(userID, sessionID, ipAddress)
+
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user except the current one
+// and returns how many were revoked
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID, ipAddress string) (int, error) {
+	revoked, err := s.sessionRepo.RevokeOtherUserSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.auditLog.LogOtherSessionsRevoked(userID, currentSessionID, ipAddress, len(revoked))
+
+	return len(revoked), nil
+}
+
+// handleUnknownRefreshToken decides whether a token that matches no live session is a replay
+func (s *SessionService) handleUnknownRefreshToken(ctx context.Context, tokenHash, ipAddress string) error {
+	rotated, err := s.sessionRepo.FindRotatedRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	if err := s.sessionRepo.RevokeSessionFamily(ctx, rotated.SessionID); err != nil {
+		return fmt.Errorf("failed to revoke session after refresh token reuse: %w", err)
+	}
+
+	s.auditLog.LogRefreshTokenReuse(rotated.UserID, rotated.SessionID, ipAddress)
+
+	return ErrRefreshTokenReused
+}
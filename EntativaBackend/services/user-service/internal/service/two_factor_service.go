@@ -1,42 +1,60 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base32"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"socialink/user-service/internal/model"
-	"socialink/user-service/internal/repository"
+	"user-service/internal/repository"
+	"user-service/internal/util"
 
-	"github.com/google/uuid"
-	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 )
 
+var (
+	ErrTwoFactorNotSetUp    = errors.New("two-factor authentication is not set up")
+	ErrInvalidTwoFactorCode = errors.New("invalid 2FA code")
+)
+
+const (
+	// totpIssuer is the account label authenticator apps show next to the code
+	totpIssuer = "Entativa"
+	// backupCodeCount is the number of single-use backup codes issued at setup
+	backupCodeCount = 10
+)
+
+// TwoFactorSetup is shown to the user once when they set up 2FA
+type TwoFactorSetup struct {
+	Secret      string   `json:"secret"`
+	QRCodeURL   string   `json:"qr_code_url"`
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// TwoFactorService manages TOTP secrets and backup codes
 type TwoFactorService struct {
 	twoFactorRepo *repository.TwoFactorRepository
-	userRepo      *repository.UserRepository
+	auditLog      *AuditLog
 }
 
-func NewTwoFactorService(twoFactorRepo *repository.TwoFactorRepository, userRepo *repository.UserRepository) *TwoFactorService {
+// NewTwoFactorService creates a new two-factor service
+func NewTwoFactorService(twoFactorRepo *repository.TwoFactorRepository, auditLog *AuditLog) *TwoFactorService {
 	return &TwoFactorService{
 		twoFactorRepo: twoFactorRepo,
-		userRepo:      userRepo,
+		auditLog:      auditLog,
 	}
 }
 
-// SetupTwoFactor generates a new 2FA secret and backup codes
-func (s *TwoFactorService) SetupTwoFactor(userID uuid.UUID) (*model.TwoFactorSetupResponse, error) {
-	// Get user
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %w", err)
-	}
-
-	// Generate TOTP secret
+// Setup generates a new secret and backup codes. 2FA stays off until Enable
+// confirms the user's authenticator produces valid codes.
+func (s *TwoFactorService) Setup(ctx context.Context, user *repository.User) (*TwoFactorSetup, error) {
 	key, err := totp.Generate(totp.GenerateOpts{
-		Issuer:      "Socialink",
+		Issuer:      totpIssuer,
 		AccountName: user.Email,
 		SecretSize:  32,
 	})
@@ -44,118 +62,98 @@ func (s *TwoFactorService) SetupTwoFactor(userID uuid.UUID) (*model.TwoFactorSet
 		return nil, fmt.Errorf("failed to generate TOTP key: %w", err)
 	}
 
-	// Generate backup codes
-	backupCodes := generateBackupCodes(10)
+	// Only the hashes are stored; the user sees the codes once
+	backupCodes := make([]string, backupCodeCount)
+	backupCodeHashes := make([]string, backupCodeCount)
+	for i := range backupCodes {
+		code, err := generateBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		backupCodes[i] = code
+		backupCodeHashes[i] = hashBackupCode(code)
+	}
 
-	// Store 2FA configuration (not enabled yet)
-	twoFactor := &model.TwoFactorAuth{
-		ID:          uuid.New(),
-		UserID:      userID,
-		Secret:      key.Secret(),
-		IsEnabled:   false,
-		BackupCodes: backupCodes,
-		CreatedAt:   time.Now(),
+	twoFactor := &repository.TwoFactorAuth{
+		ID:               util.GenerateUUID(),
+		UserID:           user.ID,
+		Secret:           key.Secret(),
+		BackupCodeHashes: backupCodeHashes,
+		CreatedAt:        time.Now(),
 	}
 
-	err = s.twoFactorRepo.Create(twoFactor)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create 2FA config: %w", err)
+	if err := s.twoFactorRepo.UpsertPending(ctx, twoFactor); err != nil {
+		return nil, err
 	}
 
-	return &model.TwoFactorSetupResponse{
+	return &TwoFactorSetup{
 		Secret:      key.Secret(),
 		QRCodeURL:   key.URL(),
 		BackupCodes: backupCodes,
 	}, nil
 }
 
-// EnableTwoFactor enables 2FA after code verification
-func (s *TwoFactorService) EnableTwoFactor(userID uuid.UUID, code string) error {
-	// Get 2FA config
-	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+// Enable turns 2FA on once the user proves their authenticator is set up
+func (s *TwoFactorService) Enable(ctx context.Context, userID, code string) error {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("2FA not set up")
+		var notFound *repository.NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrTwoFactorNotSetUp
+		}
+		return err
+	}
+	if twoFactor.IsEnabled {
+		return repository.ErrTwoFactorEnabled
 	}
 
-	// Verify code
-	valid := totp.Validate(code, twoFactor.Secret)
-	if !valid {
-		return fmt.Errorf("invalid verification code")
+	if !totp.Validate(code, twoFactor.Secret) {
+		return ErrInvalidTwoFactorCode
 	}
 
-	// Enable 2FA
-	now := time.Now()
-	twoFactor.IsEnabled = true
-	twoFactor.EnabledAt = &now
+	return s.twoFactorRepo.Enable(ctx, userID)
+}
 
-	err = s.twoFactorRepo.Update(twoFactor)
-	if err != nil {
-		return fmt.Errorf("failed to enable 2FA: %w", err)
-	}
+// Disable turns 2FA off. The caller must have re-checked the user's password or a current code.
+func (s *TwoFactorService) Disable(ctx context.Context, userID string) error {
+	return s.twoFactorRepo.DeleteByUserID(ctx, userID)
+}
 
-	return nil
+// IsEnabled reports whether the user must present a second factor at login
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	return s.twoFactorRepo.IsEnabled(ctx, userID)
 }
 
-// VerifyTwoFactorCode verifies a 2FA code or backup code
-func (s *TwoFactorService) VerifyTwoFactorCode(userID uuid.UUID, code string) (bool, error) {
-	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
+// VerifyCode checks a TOTP code, falling back to a single-use backup code
+func (s *TwoFactorService) VerifyCode(ctx context.Context, userID, code string) (bool, error) {
+	twoFactor, err := s.twoFactorRepo.FindByUserID(ctx, userID)
 	if err != nil {
 		return false, err
 	}
-
 	if !twoFactor.IsEnabled {
-		return false, fmt.Errorf("2FA not enabled")
+		return false, ErrTwoFactorNotSetUp
 	}
 
-	// Try TOTP code first
-	valid := totp.Validate(code, twoFactor.Secret)
-	if valid {
-		// Update last used
-		now := time.Now()
-		twoFactor.LastUsedAt = &now
-		_ = s.twoFactorRepo.Update(twoFactor)
+	if totp.Validate(code, twoFactor.Secret) {
+		_ = s.twoFactorRepo.TouchLastUsed(ctx, userID)
 		return true, nil
 	}
 
-	// Try backup codes
-	for i, backupCode := range twoFactor.BackupCodes {
-		if backupCode == code {
-			// Remove used backup code
-			twoFactor.BackupCodes = append(twoFactor.BackupCodes[:i], twoFactor.BackupCodes[i+1:]...)
-			_ = s.twoFactorRepo.Update(twoFactor)
-			return true, nil
-		}
-	}
-
-	return false, fmt.Errorf("invalid 2FA code")
+	return s.twoFactorRepo.ConsumeBackupCode(ctx, userID, hashBackupCode(code))
 }
 
-// DisableTwoFactor disables 2FA for a user
-func (s *TwoFactorService) DisableTwoFactor(userID uuid.UUID) error {
-	return s.twoFactorRepo.DeleteByUserID(userID)
-}
-
-// IsTwoFactorEnabled checks if 2FA is enabled for a user
-func (s *TwoFactorService) IsTwoFactorEnabled(userID uuid.UUID) (bool, error) {
-	twoFactor, err := s.twoFactorRepo.FindByUserID(userID)
-	if err != nil {
-		return false, nil // 2FA not set up
-	}
-	return twoFactor.IsEnabled, nil
-}
-
-// generateBackupCodes generates random backup codes
-func generateBackupCodes(count int) []string {
-	codes := make([]string, count)
-	for i := 0; i < count; i++ {
-		codes[i] = generateBackupCode()
-	}
-	return codes
+// hashBackupCode normalizes a backup code as typed by the user and hashes it for storage
+func hashBackupCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
 }
 
 // generateBackupCode generates a single random backup code
-func generateBackupCode() string {
+func generateBackupCode() (string, error) {
 	b := make([]byte, 10)
-	rand.Read(b)
-	return base32.StdEncoding.EncodeToString(b)[:16]
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate backup code: %w", err)
+	}
+	return base32.StdEncoding.EncodeToString(b)[:16], nil
 }
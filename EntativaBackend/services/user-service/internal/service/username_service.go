@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+)
+
+// UsernameChangedEvent is published on "user-events" when a user changes their
+// username, so other services can update mentions, search and cached profiles
+const UsernameChangedEvent = "user.username_changed"
+
+var (
+	ErrUsernameUnchanged = errors.New("that is already your username")
+	ErrUsernameTaken     = errors.New("username is taken")
+	ErrUsernameReserved  = errors.New("username is reserved")
+)
+
+// InvalidUsernameError explains why a username breaks the naming rules
+type InvalidUsernameError struct {
+	Reason string
+}
+
+func (e *InvalidUsernameError) Error() string {
+	return e.Reason
+}
+
+// UsernameCooldownError is returned when the user changed their username too recently
+type UsernameCooldownError struct {
+	NextChangeAt time.Time
+}
+
+func (e *UsernameCooldownError) Error() string {
+	return fmt.Sprintf("you can change your username again after %s", e.NextChangeAt.UTC().Format(time.RFC1123))
+}
+
+// UsernameStatusView is the user's username and when they may next change it
+type UsernameStatusView struct {
+	Username     string     `json:"username"`
+	CanChange    bool       `json:"can_change"`
+	NextChangeAt *time.Time `json:"next_change_at,omitempty"`
+}
+
+// UsernameAvailabilityView answers whether the user may take a username. Reason is
+// set when they may not: invalid, current, reserved or taken.
+type UsernameAvailabilityView struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// UsernameChangeView is one change in a user's username history
+type UsernameChangeView struct {
+	OldUsername   string    `json:"old_username"`
+	NewUsername   string    `json:"new_username"`
+	ChangedAt     time.Time `json:"changed_at"`
+	ReservedUntil time.Time `json:"reserved_until"`
+}
+
+// UsernameLookupView is the account a username leads to. If the username was given
+// up, Redirected is set and Username is the account's current one.
+type UsernameLookupView struct {
+	UserID            string     `json:"user_id"`
+	Username          string     `json:"username"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	ProfilePictureURL *string    `json:"profile_picture_url,omitempty"`
+	IsVerified        bool       `json:"is_verified"`
+	Redirected        bool       `json:"redirected"`
+	RequestedUsername string     `json:"requested_username,omitempty"`
+	ChangedAt         *time.Time `json:"changed_at,omitempty"`
+}
+
+// UsernameService changes usernames. A user may change theirs once per cooldown;
+// the name they give up stays reserved for them for the reservation period, so
+// nobody else can take it and they can change back. Lookups by a given-up name
+// lead to the account's current one. Brand, staff and system names in the
+// reserved_usernames table can't be chosen.
+type UsernameService struct {
+	usernameRepo *repository.UsernameRepository
+	blockService *BlockService
+	kafka        *KafkaProducer
+	auditLog     *AuditLog
+	cooldown     time.Duration
+	reservation  time.Duration
+}
+
+// NewUsernameService creates a new username service
+func NewUsernameService(
+	usernameRepo *repository.UsernameRepository,
+	blockService *BlockService,
+	kafka *KafkaProducer,
+	auditLog *AuditLog,
+	cfg *config.Config,
+) *UsernameService {
+	return &UsernameService{
+		usernameRepo: usernameRepo,
+		blockService: blockService,
+		kafka:        kafka,
+		auditLog:     auditLog,
+		cooldown:     cfg.Usernames.ChangeCooldown,
+		reservation:  cfg.Usernames.ReservationPeriod,
+	}
+}
+
+// Status returns the user's username and when they may next change it
+func (s *UsernameService) Status(ctx context.Context, user *repository.User) (*UsernameStatusView, error) {
+	next, err := s.nextChangeAt(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UsernameStatusView{
+		Username:     user.Username,
+		CanChange:    next == nil,
+		NextChangeAt: next,
+	}, nil
+}
+
+// CheckAvailability reports whether the user may change their username to username
+func (s *UsernameService) CheckAvailability(ctx context.Context, user *repository.User, username string) (*UsernameAvailabilityView, error) {
+	username = normalizeUsername(username)
+	view := &UsernameAvailabilityView{Username: username}
+
+	err := s.checkUsername(ctx, user, username)
+	switch {
+	case err == nil:
+		view.Available = true
+		return view, nil
+	case errors.Is(err, ErrUsernameUnchanged):
+		view.Reason = "current"
+	case errors.Is(err, ErrUsernameReserved):
+		view.Reason = "reserved"
+	case errors.Is(err, ErrUsernameTaken):
+		view.Reason = "taken"
+	default:
+		var invalid *InvalidUsernameError
+		if !errors.As(err, &invalid) {
+			return nil, err
+		}
+		view.Reason = "invalid"
+	}
+
+	view.Message = err.Error()
+	return view, nil
+}
+
+// ChangeUsername gives the user a new username and reserves their old one for them
+func (s *UsernameService) ChangeUsername(ctx context.Context, user *repository.User, username, ipAddress string) (*UsernameChangeView, error) {
+	username = normalizeUsername(username)
+	if err := s.checkUsername(ctx, user, username); err != nil {
+		return nil, err
+	}
+
+	next, err := s.nextChangeAt(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if next != nil {
+		return nil, &UsernameCooldownError{NextChangeAt: *next}
+	}
+
+	now := time.Now()
+	change := &repository.UsernameChange{
+		ID:            util.GenerateUUID(),
+		UserID:        user.ID,
+		NewUsername:   username,
+		ChangedAt:     now,
+		ReservedUntil: now.Add(s.reservation),
+	}
+
+	if err := s.usernameRepo.ChangeUsername(ctx, change, s.cooldown); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUsernameUnavailable):
+			return nil, ErrUsernameTaken
+		case errors.Is(err, repository.ErrUsernameChangedRecently):
+			// Another change by the same user got in since the check above
+			return nil, &UsernameCooldownError{NextChangeAt: now.Add(s.cooldown)}
+		}
+		return nil, err
+	}
+
+	s.auditLog.LogUsernameChanged(user.ID, change.OldUsername, change.NewUsername, ipAddress)
+	s.publish(change)
+
+	return usernameChangeView(change), nil
+}
+
+// Lookup finds the account a username leads to, following given-up usernames to
+// their owner's current one. Users who have blocked each other are not found.
+func (s *UsernameService) Lookup(ctx context.Context, viewerID, username string) (*UsernameLookupView, error) {
+	lookup, err := s.usernameRepo.LookupUsername(ctx, normalizeUsername(username))
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.blockService.IsBlocked(ctx, viewerID, lookup.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, &repository.NotFoundError{Message: "Username not found"}
+	}
+
+	return &UsernameLookupView{
+		UserID:            lookup.UserID,
+		Username:          lookup.Username,
+		FirstName:         lookup.FirstName,
+		LastName:          lookup.LastName,
+		ProfilePictureURL: lookup.ProfilePictureURL,
+		IsVerified:        lookup.IsVerified,
+		Redirected:        lookup.Redirected,
+		RequestedUsername: lookup.OldUsername,
+		ChangedAt:         lookup.ChangedAt,
+	}, nil
+}
+
+// History returns every username change a user made, newest first. It is for admins.
+func (s *UsernameService) History(ctx context.Context, userID string) ([]*UsernameChangeView, error) {
+	history, err := s.usernameRepo.ListHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*UsernameChangeView, 0, len(history))
+	for _, change := range history {
+		views = append(views, usernameChangeView(change))
+	}
+	return views, nil
+}
+
+// checkUsername returns why the user can't take username, if they can't
+func (s *UsernameService) checkUsername(ctx context.Context, user *repository.User, username string) error {
+	if err := util.ValidateUsername(username); err != nil {
+		return &InvalidUsernameError{Reason: err.Error()}
+	}
+	if strings.EqualFold(username, user.Username) {
+		return ErrUsernameUnchanged
+	}
+
+	blocklisted, err := s.usernameRepo.IsBlocklisted(ctx, username)
+	if err != nil {
+		return err
+	}
+	if blocklisted {
+		return ErrUsernameReserved
+	}
+
+	available, err := s.usernameRepo.IsAvailable(ctx, username, user.ID)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// nextChangeAt returns when the user may next change their username, or nil if they may now
+func (s *UsernameService) nextChangeAt(ctx context.Context, userID string) (*time.Time, error) {
+	last, err := s.usernameRepo.LastChange(ctx, userID)
+	if err != nil || last == nil {
+		return nil, err
+	}
+
+	next := last.ChangedAt.Add(s.cooldown)
+	if !time.Now().Before(next) {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+func (s *UsernameService) publish(change *repository.UsernameChange) {
+	event := map[string]interface{}{
+		"event_type":   UsernameChangedEvent,
+		"user_id":      change.UserID,
+		"old_username": change.OldUsername,
+		"new_username": change.NewUsername,
+		"timestamp":    change.ChangedAt,
+	}
+
+	if err := s.kafka.PublishEvent("user-events", event); err != nil {
+		log.Printf("Failed to publish %s for %s: %v", UsernameChangedEvent, change.UserID, err)
+	}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+func usernameChangeView(change *repository.UsernameChange) *UsernameChangeView {
+	return &UsernameChangeView{
+		OldUsername:   change.OldUsername,
+		NewUsername:   change.NewUsername,
+		ChangedAt:     change.ChangedAt,
+		ReservedUntil: change.ReservedUntil,
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-service/internal/config"
+	"user-service/internal/repository"
+	"user-service/internal/util"
+	"user-service/pkg/webauthn"
+)
+
+var (
+	ErrWebAuthnCeremonyInvalid = errors.New("invalid or expired passkey request, please try again")
+	ErrWebAuthnCredential      = errors.New("passkey was not accepted")
+	ErrTooManyPasskeys         = fmt.Errorf("a maximum of %d passkeys can be registered", maxWebAuthnCredentials)
+)
+
+const (
+	// webAuthnCeremonyTTL bounds how long a challenge can be answered
+	webAuthnCeremonyTTL = 5 * time.Minute
+	// maxWebAuthnCredentials caps credentials per user
+	maxWebAuthnCredentials = 20
+)
+
+// WebAuthnRegistrationOptions starts a registration ceremony
+type WebAuthnRegistrationOptions struct {
+	CeremonyID string                    `json:"ceremony_id"`
+	PublicKey  *webauthn.CreationOptions `json:"publicKey"`
+}
+
+// WebAuthnLoginOptions starts an authentication ceremony
+type WebAuthnLoginOptions struct {
+	CeremonyID string                   `json:"ceremony_id"`
+	PublicKey  *webauthn.RequestOptions `json:"publicKey"`
+}
+
+// WebAuthnService registers passkeys and verifies them, for passwordless login
+// or as a second factor next to TOTP.
+type WebAuthnService struct {
+	webAuthnRepo *repository.WebAuthnRepository
+	auditLog     *AuditLog
+	rp           *webauthn.Config
+}
+
+// NewWebAuthnService creates a new WebAuthn service
+func NewWebAuthnService(webAuthnRepo *repository.WebAuthnRepository, auditLog *AuditLog, cfg *config.Config) *WebAuthnService {
+	return &WebAuthnService{
+		webAuthnRepo: webAuthnRepo,
+		auditLog:     auditLog,
+		rp: &webauthn.Config{
+			RPID:    cfg.WebAuthn.RPID,
+			RPName:  cfg.WebAuthn.RPName,
+			Origins: cfg.WebAuthn.Origins,
+		},
+	}
+}
+
+// BeginRegistration creates registration options for a signed-in user
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, user *repository.User) (*WebAuthnRegistrationOptions, error) {
+	existing, err := s.webAuthnRepo.ListCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebAuthnCredentials {
+		return nil, ErrTooManyPasskeys
+	}
+
+	ceremony, err := s.createCeremony(ctx, &user.ID, repository.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user handle is the account ID: stable, and not personally identifying
+	options := s.rp.NewCreationOptions(
+		ceremony.Challenge,
+		[]byte(user.ID),
+		user.Username,
+		strings.TrimSpace(user.FirstName+" "+user.LastName),
+		credentialDescriptors(existing),
+		int(webAuthnCeremonyTTL.Milliseconds()),
+	)
+
+	return &WebAuthnRegistrationOptions{
+		CeremonyID: ceremony.ID,
+		PublicKey:  options,
+	}, nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the credential
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, userID, ceremonyID, name string, resp *webauthn.AttestationResponse, ipAddress string) (*repository.WebAuthnCredential, error) {
+	ceremony, err := s.webAuthnRepo.ConsumeCeremony(ctx, ceremonyID, repository.WebAuthnCeremonyRegistration)
+	if err != nil || ceremony.UserID == nil || *ceremony.UserID != userID {
+		s.auditLog.LogWebAuthnFailure(userID, repository.WebAuthnCeremonyRegistration, ipAddress, "invalid_ceremony")
+		return nil, ErrWebAuthnCeremonyInvalid
+	}
+
+	verified, err := s.rp.VerifyRegistration(ceremony.Challenge, resp, false)
+	if err != nil {
+		s.auditLog.LogWebAuthnFailure(userID, repository.WebAuthnCeremonyRegistration, ipAddress, err.Error())
+		return nil, ErrWebAuthnCredential
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	credential := &repository.WebAuthnCredential{
+		ID:             util.GenerateUUID(),
+		UserID:         userID,
+		CredentialID:   verified.ID,
+		PublicKey:      verified.PublicKey,
+		Algorithm:      verified.Algorithm,
+		SignCount:      verified.SignCount,
+		AAGUID:         verified.AAGUID,
+		Transports:     verified.Transports,
+		Name:           name,
+		BackupEligible: verified.BackupEligible,
+		BackupState:    verified.BackupState,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.webAuthnRepo.CreateCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	s.auditLog.LogWebAuthnRegistration(userID, credential.ID, ipAddress)
+
+	return credential, nil
+}
+
+// BeginLogin creates authentication options. With a user the allow list names
+// their credentials (second factor); without one any discoverable passkey may answer.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, userID *string) (*WebAuthnLoginOptions, error) {
+	var allow []webauthn.CredentialDescriptor
+	userVerification := "required"
+
+	if userID != nil {
+		credentials, err := s.webAuthnRepo.ListCredentials(ctx, *userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(credentials) == 0 {
+			return nil, ErrWebAuthnCredential
+		}
+		allow = credentialDescriptors(credentials)
+		// The password was already checked, so presence is enough for a second factor
+		userVerification = "preferred"
+	}
+
+	ceremony, err := s.createCeremony(ctx, userID, repository.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnLoginOptions{
+		CeremonyID: ceremony.ID,
+		PublicKey:  s.rp.NewRequestOptions(ceremony.Challenge, allow, userVerification, int(webAuthnCeremonyTTL.Milliseconds())),
+	}, nil
+}
+
+// FinishLogin verifies an assertion and returns the credential that made it.
+// A passwordless login needs user verification (PIN or biometric); the key then
+// counts as both factors. As a second factor, presence is enough.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, ceremonyID string, resp *webauthn.AssertionResponse, ipAddress string) (*repository.WebAuthnCredential, error) {
+	ceremony, err := s.webAuthnRepo.ConsumeCeremony(ctx, ceremonyID, repository.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		s.auditLog.LogWebAuthnFailure("", repository.WebAuthnCeremonyAuthentication, ipAddress, "invalid_ceremony")
+		return nil, ErrWebAuthnCeremonyInvalid
+	}
+
+	credentialID := []byte(resp.RawID)
+	if len(credentialID) == 0 {
+		credentialID, _ = base64.RawURLEncoding.DecodeString(resp.ID)
+	}
+
+	credential, err := s.webAuthnRepo.FindCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		s.auditLog.LogWebAuthnFailure("", repository.WebAuthnCeremonyAuthentication, ipAddress, "unknown_credential")
+		return nil, ErrWebAuthnCredential
+	}
+
+	// A second factor ceremony only accepts the user who passed the password step
+	if ceremony.UserID != nil && *ceremony.UserID != credential.UserID {
+		s.auditLog.LogWebAuthnFailure(*ceremony.UserID, repository.WebAuthnCeremonyAuthentication, ipAddress, "credential_user_mismatch")
+		return nil, ErrWebAuthnCredential
+	}
+	// For discoverable credentials the authenticator also reports the user handle
+	if len(resp.Response.UserHandle) > 0 && string(resp.Response.UserHandle) != credential.UserID {
+		s.auditLog.LogWebAuthnFailure(credential.UserID, repository.WebAuthnCeremonyAuthentication, ipAddress, "user_handle_mismatch")
+		return nil, ErrWebAuthnCredential
+	}
+
+	requireUserVerification := ceremony.UserID == nil
+	result, err := s.rp.VerifyAssertion(ceremony.Challenge, resp, credential.PublicKey, credential.SignCount, requireUserVerification)
+	if err != nil {
+		s.auditLog.LogWebAuthnFailure(credential.UserID, repository.WebAuthnCeremonyAuthentication, ipAddress, err.Error())
+		return nil, ErrWebAuthnCredential
+	}
+
+	if err := s.webAuthnRepo.UpdateSignCount(ctx, credential.ID, credential.SignCount, result.SignCount, result.BackupState); err != nil {
+		if errors.Is(err, repository.ErrCredentialNotFound) {
+			return nil, ErrWebAuthnCredential
+		}
+		return nil, err
+	}
+
+	s.auditLog.LogWebAuthnAuthentication(credential.UserID, credential.ID, ipAddress)
+
+	return credential, nil
+}
+
+// HasCredentials reports whether a user has registered any passkey
+func (s *WebAuthnService) HasCredentials(ctx context.Context, userID string) (bool, error) {
+	count, err := s.webAuthnRepo.CountCredentials(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListCredentials lists a user's passkeys
+func (s *WebAuthnService) ListCredentials(ctx context.Context, userID string) ([]*repository.WebAuthnCredential, error) {
+	return s.webAuthnRepo.ListCredentials(ctx, userID)
+}
+
+// RenameCredential renames one of a user's passkeys
+func (s *WebAuthnService) RenameCredential(ctx context.Context, userID, credentialID, name string) error {
+	return s.webAuthnRepo.RenameCredential(ctx, userID, credentialID, strings.TrimSpace(name))
+}
+
+// RevokeCredential deletes one of a user's passkeys
+func (s *WebAuthnService) RevokeCredential(ctx context.Context, userID, credentialID, ipAddress string) error {
+	if err := s.webAuthnRepo.DeleteCredential(ctx, userID, credentialID); err != nil {
+		return err
+	}
+
+	s.auditLog.LogWebAuthnRevocation(userID, credentialID, ipAddress)
+
+	return nil
+}
+
+func (s *WebAuthnService) createCeremony(ctx context.Context, userID *string, ceremonyType string) (*repository.WebAuthnCeremony, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ceremony := &repository.WebAuthnCeremony{
+		ID:        util.GenerateUUID(),
+		UserID:    userID,
+		Ceremony:  ceremonyType,
+		Challenge: challenge,
+		ExpiresAt: now.Add(webAuthnCeremonyTTL),
+		CreatedAt: now,
+	}
+
+	if err := s.webAuthnRepo.CreateCeremony(ctx, ceremony); err != nil {
+		return nil, err
+	}
+
+	return ceremony, nil
+}
+
+func credentialDescriptors(credentials []*repository.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		descriptors[i] = webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.Transports,
+		}
+	}
+	return descriptors
+}
@@ -1,9 +1,12 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,24 +14,55 @@ import (
 
 // TokenClaims represents JWT token claims
 type TokenClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	// SessionID ties the token to a row in sessions, so revoking the session revokes the token
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 var (
-	jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-super-secret-key-change-this-in-production"))
+	// signingKeys is installed at startup with SetKeyring
+	signingKeys   *Keyring
+	signingKeysMu sync.RWMutex
+	
+	// accessTokenTTL is kept short; clients renew through the refresh token
+	accessTokenTTL = getDurationOrDefault("JWT_ACCESS_TOKEN_EXPIRY", 15*time.Minute)
 )
 
-// GenerateAccessToken generates a new JWT access token
-func GenerateAccessToken(userID, username, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // 24 hours
+// refreshTokenBytes is the amount of randomness in an opaque refresh token
+const refreshTokenBytes = 32
+
+// SetKeyring installs the keyring used to sign and verify access tokens
+func SetKeyring(keyring *Keyring) {
+	signingKeysMu.Lock()
+	defer signingKeysMu.Unlock()
+	signingKeys = keyring
+}
+
+// CurrentKeyring returns the installed keyring (nil before SetKeyring)
+func CurrentKeyring() *Keyring {
+	signingKeysMu.RLock()
+	defer signingKeysMu.RUnlock()
+	return signingKeys
+}
+
+// GenerateAccessToken generates a new JWT access token for a session, signed with the active key
+func GenerateAccessToken(userID, username, email, sessionID string) (string, error) {
+	keyring := CurrentKeyring()
+	if keyring == nil {
+		return "", errors.New("signing keys not configured")
+	}
+	signingKey := keyring.Active()
+	
+	expirationTime := time.Now().Add(accessTokenTTL)
 	
 	claims := &TokenClaims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -38,9 +72,10 @@ func GenerateAccessToken(userID, username, email string) (string, error) {
 		},
 	}
 	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(signingKey.Method(), claims)
+	token.Header["kid"] = signingKey.KID
 	
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := token.SignedString(signingKey.PrivateKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -48,45 +83,44 @@ func GenerateAccessToken(userID, username, email string) (string, error) {
 	return tokenString, nil
 }
 
-// GenerateRefreshToken generates a refresh token (longer lived)
-func GenerateRefreshToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(30 * 24 * time.Hour) // 30 days
-	
-	claims := &TokenClaims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "entativa-auth-service",
-			Subject:   userID,
-		},
-	}
-	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	tokenString, err := token.SignedString(jwtSecret)
+// GenerateRefreshToken generates an opaque refresh token.
+// Refresh tokens are not JWTs: they carry no claims and are only meaningful
+// to the session store, which keeps nothing but their hash (see HashRefreshToken).
+func GenerateRefreshToken() (string, error) {
+	token, err := GenerateSecureToken(refreshTokenBytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign refresh token: %w", err)
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 	
-	return tokenString, nil
+	return token, nil
+}
+
+// HashRefreshToken returns the SHA-256 hex digest stored in place of a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // ParseAccessToken parses and validates an access token
 func ParseAccessToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	keyring := CurrentKeyring()
+	if keyring == nil {
+		return nil, errors.New("signing keys not configured")
+	}
+	
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, keyringKeyfunc(keyring), jwt.WithValidMethods(signedTokenMethods))
 	
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 	
-	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
+	// Tokens issued to OAuth clients are signed by the same keys but are not session tokens
+	if typ, _ := token.Header["typ"].(string); typ == OAuthAccessTokenType {
+		return nil, errors.New("invalid token")
+	}
+	
+	// ID tokens have no user_id or session, so they are rejected here as well
+	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid && claims.UserID != "" && claims.SessionID != "" {
 		return claims, nil
 	}
 	
@@ -123,20 +157,11 @@ func ExtractTokenFromHeader(authHeader string) (string, error) {
 	return token, nil
 }
 
-// RefreshAccessToken generates a new access token from a valid refresh token
-func RefreshAccessToken(refreshTokenString string) (string, error) {
-	claims, err := ParseAccessToken(refreshTokenString)
-	if err != nil {
-		return "", fmt.Errorf("invalid refresh token: %w", err)
-	}
-	
-	// Generate new access token
-	return GenerateAccessToken(claims.UserID, claims.Username, claims.Email)
-}
-
-func getEnvOrDefault(key, defaultValue string) string {
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
-		return value
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
 	}
 	return defaultValue
 }
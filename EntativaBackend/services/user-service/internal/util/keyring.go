@@ -0,0 +1,208 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a private key used to sign access tokens, identified by its kid
+type SigningKey struct {
+	KID        string
+	PrivateKey crypto.Signer
+}
+
+// Method returns the JWT signing method matching the key type
+func (k *SigningKey) Method() jwt.SigningMethod {
+	if _, ok := k.PrivateKey.(*rsa.PrivateKey); ok {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// PublicKey returns the public half of the key
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// Keyring holds every key whose tokens are still accepted, plus the one used to sign new tokens.
+//
+// Rotation: add the new key next to the old one, wait until verifiers have
+// refreshed their JWKS, then make it active. Keep the old key in the ring
+// until the longest-lived token it signed has expired, then remove it.
+type Keyring struct {
+	active string
+	keys   map[string]*SigningKey
+}
+
+// NewKeyring creates a keyring from a set of keys; activeKID selects the signing key
+func NewKeyring(keys []*SigningKey, activeKID string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("keyring needs at least one signing key")
+	}
+
+	ring := &Keyring{keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, exists := ring.keys[key.KID]; exists {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.KID)
+		}
+		ring.keys[key.KID] = key
+	}
+
+	if activeKID == "" {
+		// Default to the newest key when kids sort by date (e.g. 2025-10-01.pem)
+		activeKID = ring.KIDs()[len(ring.keys)-1]
+	}
+	if _, ok := ring.keys[activeKID]; !ok {
+		return nil, fmt.Errorf("active signing key %q not found in keyring", activeKID)
+	}
+	ring.active = activeKID
+
+	return ring, nil
+}
+
+// LoadKeyring loads every *.pem private key (PKCS#8) in dir; the file name without extension is the kid
+func LoadKeyring(dir, activeKID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	var keys []*SigningKey
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys, activeKID)
+}
+
+// NewEphemeralKeyring creates a keyring with a single freshly generated Ed25519 key.
+// Tokens signed with it stop validating when the process restarts; development only.
+func NewEphemeralKeyring() (*Keyring, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	kid := "ephemeral-" + time.Now().UTC().Format("20060102T150405")
+	return NewKeyring([]*SigningKey{{KID: kid, PrivateKey: privateKey}}, kid)
+}
+
+// Active returns the key used to sign new tokens
+func (k *Keyring) Active() *SigningKey {
+	return k.keys[k.active]
+}
+
+// Lookup finds a key by kid
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// KIDs returns all key ids in sorted order
+func (k *Keyring) KIDs() []string {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+// JWK is a JSON Web Key (RFC 7517) holding a public verification key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring for publication at /.well-known/jwks.json
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+
+	for _, kid := range k.KIDs() {
+		key := k.keys[kid]
+		jwk := JWK{
+			Kid: kid,
+			Use: "sig",
+			Alg: key.Method().Alg(),
+		}
+
+		switch pub := key.PublicKey().(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func loadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+
+	var signer crypto.Signer
+	switch key := parsed.(type) {
+	case ed25519.PrivateKey:
+		signer = key
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA signing key %s must be at least 2048 bits", path)
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("signing key %s must be Ed25519 or RSA", path)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &SigningKey{KID: kid, PrivateKey: signer}, nil
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+)
+
+// A proof-of-work solution is any string for which SHA-256(challenge + ":" + solution)
+// starts with the required number of zero bits. Each extra bit doubles the expected
+// work for the client while checking a solution stays a single hash for the server.
+const (
+	powChallengeBytes    = 16
+	maxPowSolutionLength = 64
+)
+
+// GenerateProofOfWorkChallenge creates a random challenge string
+func GenerateProofOfWorkChallenge() (string, error) {
+	b := make([]byte, powChallengeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// VerifyProofOfWork reports whether solution solves challenge at the given difficulty
+func VerifyProofOfWork(challenge, solution string, difficulty int) bool {
+	if solution == "" || len(solution) > maxPowSolutionLength {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+	return leadingZeroBits(sum[:]) >= difficulty
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, v := range b {
+		if v != 0 {
+			return n + bits.LeadingZeros8(v)
+		}
+		n += 8
+	}
+	return n
+}
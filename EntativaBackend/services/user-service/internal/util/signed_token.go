@@ -0,0 +1,90 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuthAccessTokenType is the "typ" header of access tokens issued to OAuth clients
+// (RFC 9068). Session access tokens have no typ, so the two can never be confused.
+const OAuthAccessTokenType = "at+jwt"
+
+var signedTokenMethods = []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+
+// SignToken signs claims with the active key. tokenType, when set, becomes the "typ" header.
+func SignToken(claims jwt.Claims, tokenType string) (string, error) {
+	keyring := CurrentKeyring()
+	if keyring == nil {
+		return "", errors.New("signing keys not configured")
+	}
+	signingKey := keyring.Active()
+
+	token := jwt.NewWithClaims(signingKey.Method(), claims)
+	token.Header["kid"] = signingKey.KID
+	if tokenType != "" {
+		token.Header["typ"] = tokenType
+	}
+
+	tokenString, err := token.SignedString(signingKey.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// ParseSignedToken verifies a token signed by the keyring, decodes it into claims
+// and returns its "typ" header
+func ParseSignedToken(tokenString string, claims jwt.Claims) (string, error) {
+	keyring := CurrentKeyring()
+	if keyring == nil {
+		return "", errors.New("signing keys not configured")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyringKeyfunc(keyring), jwt.WithValidMethods(signedTokenMethods))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token: %w", err)
+	}
+	if !token.Valid {
+		return "", errors.New("invalid token")
+	}
+
+	tokenType, _ := token.Header["typ"].(string)
+	return tokenType, nil
+}
+
+// keyringKeyfunc selects the verification key by kid and makes sure the algorithm matches it
+func keyringKeyfunc(keyring *Keyring) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		signingKey, ok := keyring.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		if token.Method.Alg() != signingKey.Method().Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return signingKey.PublicKey(), nil
+	}
+}
+
+// SigningAlgorithms lists the JWS algorithms of the keys in the keyring
+func SigningAlgorithms() []string {
+	keyring := CurrentKeyring()
+	if keyring == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var algs []string
+	for _, kid := range keyring.KIDs() {
+		signingKey, _ := keyring.Lookup(kid)
+		if alg := signingKey.Method().Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
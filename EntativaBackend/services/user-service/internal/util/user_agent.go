@@ -0,0 +1,135 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Device types reported by ParseUserAgent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// DeviceInfo is what a session list shows about the client that created a session
+type DeviceInfo struct {
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+}
+
+// Order matters: Edge and Opera also claim to be Chrome, and Chrome claims to be Safari
+var browserPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"Entativa App", regexp.MustCompile(`Entativa/(\d+(?:\.\d+)?)`)},
+	{"Vignette App", regexp.MustCompile(`Vignette/(\d+(?:\.\d+)?)`)},
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/(\d+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+(?:\.\d+)?).*Safari/`)},
+}
+
+var osPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"iPadOS", regexp.MustCompile(`iPad.*OS (\d+)`)},
+	{"iOS", regexp.MustCompile(`(?:iPhone|iPod).*OS (\d+)`)},
+	{"Android", regexp.MustCompile(`Android (\d+(?:\.\d+)?)`)},
+	{"Windows", regexp.MustCompile(`Windows NT (\d+\.\d+)`)},
+	{"ChromeOS", regexp.MustCompile(`CrOS`)},
+	{"macOS", regexp.MustCompile(`Mac OS X (\d+[._]\d+)`)},
+	{"Linux", regexp.MustCompile(`Linux`)},
+}
+
+var botPattern = regexp.MustCompile(`(?i)bot|crawler|spider|curl|wget|python-requests|go-http-client`)
+
+// ParseUserAgent extracts browser, OS and device type from a User-Agent header.
+// It only needs to be good enough for a person to recognise their own devices.
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{
+		Browser:    "Unknown browser",
+		OS:         "Unknown OS",
+		DeviceType: DeviceUnknown,
+	}
+	if userAgent == "" {
+		return info
+	}
+
+	if botPattern.MatchString(userAgent) {
+		info.Browser = strings.SplitN(userAgent, " ", 2)[0]
+		info.DeviceType = DeviceBot
+		return info
+	}
+
+	for _, b := range browserPatterns {
+		if m := b.pattern.FindStringSubmatch(userAgent); m != nil {
+			info.Browser = b.name + " " + m[1]
+			break
+		}
+	}
+
+	for _, o := range osPatterns {
+		if m := o.pattern.FindStringSubmatch(userAgent); m != nil {
+			info.OS = o.name
+			if len(m) > 1 {
+				info.OS += " " + strings.ReplaceAll(m[1], "_", ".")
+			}
+			if o.name == "Windows" {
+				info.OS = windowsVersion(m[1])
+			}
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") ||
+		(strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		info.DeviceType = DeviceTablet
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone"):
+		info.DeviceType = DeviceMobile
+	default:
+		info.DeviceType = DeviceDesktop
+	}
+
+	return info
+}
+
+// DeviceKey identifies the kind of client behind a User-Agent without version
+// numbers, so a browser or OS update does not look like a new device
+func DeviceKey(userAgent string) string {
+	info := ParseUserAgent(userAgent)
+	return withoutVersion(info.Browser) + "|" + withoutVersion(info.OS) + "|" + info.DeviceType
+}
+
+// withoutVersion drops trailing words that contain digits ("Chrome 120" -> "Chrome")
+func withoutVersion(name string) string {
+	words := strings.Fields(name)
+	for len(words) > 1 && strings.ContainsAny(words[len(words)-1], "0123456789") {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+func windowsVersion(nt string) string {
+	switch nt {
+	case "10.0":
+		// Windows 11 still reports NT 10.0
+		return "Windows 10/11"
+	case "6.3":
+		return "Windows 8.1"
+	case "6.2":
+		return "Windows 8"
+	case "6.1":
+		return "Windows 7"
+	default:
+		return "Windows"
+	}
+}
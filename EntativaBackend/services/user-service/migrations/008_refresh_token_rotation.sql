@@ -0,0 +1,20 @@
+-- Refresh tokens are opaque random strings; only their SHA-256 hash is stored.
+-- sessions.refresh_token holds the hash of the current token for the session.
+CREATE INDEX IF NOT EXISTS idx_sessions_refresh_token ON sessions(refresh_token);
+
+-- Create rotated_refresh_tokens table so a replayed (already rotated) token can be detected
+CREATE TABLE IF NOT EXISTS rotated_refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    session_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rotated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rotated_refresh_tokens_session ON rotated_refresh_tokens(session_id);
+CREATE INDEX IF NOT EXISTS idx_rotated_refresh_tokens_expires ON rotated_refresh_tokens(expires_at);
+
+-- Comments
+COMMENT ON TABLE rotated_refresh_tokens IS 'Refresh tokens that have already been exchanged; presenting one again revokes the session';
+COMMENT ON COLUMN rotated_refresh_tokens.token_hash IS 'SHA-256 hash of the rotated refresh token';
+COMMENT ON COLUMN rotated_refresh_tokens.session_id IS 'Session (token family) the token belonged to';
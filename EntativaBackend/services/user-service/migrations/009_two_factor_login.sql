@@ -0,0 +1,52 @@
+-- Create two_factor_auth table (TOTP configuration, one row per user)
+CREATE TABLE IF NOT EXISTS two_factor_auth (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(255) NOT NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_codes JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    enabled_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+-- Backup codes are stored as SHA-256 hashes; convert any plaintext codes and drop the old column
+ALTER TABLE two_factor_auth ADD COLUMN IF NOT EXISTS backup_code_hashes JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'two_factor_auth' AND column_name = 'backup_codes'
+    ) THEN
+        UPDATE two_factor_auth
+        SET backup_code_hashes = COALESCE((
+            SELECT jsonb_agg(encode(sha256(convert_to(upper(code), 'UTF8')), 'hex'))
+            FROM jsonb_array_elements_text(backup_codes) AS code
+        ), '[]'::jsonb)
+        WHERE backup_codes IS NOT NULL;
+
+        ALTER TABLE two_factor_auth DROP COLUMN backup_codes;
+    END IF;
+END $$;
+
+-- Create login_challenges table for the second step of a 2FA login
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_created ON login_challenges(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_challenges_expires ON login_challenges(expires_at);
+
+-- Comments
+COMMENT ON COLUMN two_factor_auth.backup_code_hashes IS 'SHA-256 hashes of unused backup codes; a code is removed when used';
+COMMENT ON TABLE login_challenges IS 'Pending 2FA logins: password verified, waiting for a TOTP or backup code';
+COMMENT ON COLUMN login_challenges.token_hash IS 'SHA-256 hash of the challenge token returned by login';
+COMMENT ON COLUMN login_challenges.attempts IS 'Failed code attempts against this challenge';
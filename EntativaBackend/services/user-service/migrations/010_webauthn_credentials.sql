@@ -0,0 +1,37 @@
+-- Create webauthn_credentials table (passkeys and security keys)
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    algorithm INTEGER NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    aaguid BYTEA,
+    transports JSONB NOT NULL DEFAULT '[]'::jsonb,
+    name VARCHAR(100) NOT NULL,
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);
+
+-- Create webauthn_ceremonies table holding the challenge of each pending ceremony
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ceremony VARCHAR(20) NOT NULL,
+    challenge BYTEA NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_ceremonies_expires ON webauthn_ceremonies(expires_at);
+
+-- Comments
+COMMENT ON TABLE webauthn_credentials IS 'WebAuthn credentials; usable for passwordless login and as a second factor';
+COMMENT ON COLUMN webauthn_credentials.public_key IS 'COSE_Key encoded credential public key';
+COMMENT ON COLUMN webauthn_credentials.sign_count IS 'Last signature counter seen; a counter that goes backwards suggests a cloned authenticator';
+COMMENT ON TABLE webauthn_ceremonies IS 'Single-use challenges for pending registration and authentication ceremonies';
+COMMENT ON COLUMN webauthn_ceremonies.user_id IS 'Expected user; NULL for discoverable (usernameless) login';
@@ -0,0 +1,34 @@
+-- Password reset tokens become selector.verifier pairs: the selector is looked up
+-- through a unique index, and only a SHA-256 hash of the verifier is stored.
+-- Outstanding tokens in the old format cannot be converted (they live for an hour at most).
+DELETE FROM password_reset_tokens;
+
+ALTER TABLE password_reset_tokens DROP COLUMN IF EXISTS token;
+ALTER TABLE password_reset_tokens DROP COLUMN IF EXISTS used;
+ALTER TABLE password_reset_tokens ADD COLUMN IF NOT EXISTS selector VARCHAR(32) NOT NULL;
+ALTER TABLE password_reset_tokens ADD COLUMN IF NOT EXISTS verifier_hash VARCHAR(64) NOT NULL;
+ALTER TABLE password_reset_tokens ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+ALTER TABLE password_reset_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_selector ON password_reset_tokens(selector);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id) WHERE used_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires ON password_reset_tokens(expires_at);
+
+-- Every reset request is recorded, whether or not the email belongs to an account,
+-- so requests can be throttled per email and per IP without revealing which emails exist
+CREATE TABLE IF NOT EXISTS password_reset_attempts (
+    id UUID PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_attempts_email ON password_reset_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_password_reset_attempts_ip ON password_reset_attempts(ip_address, created_at);
+
+-- Comments
+COMMENT ON COLUMN password_reset_tokens.selector IS 'Public half of the reset token, used to find the row';
+COMMENT ON COLUMN password_reset_tokens.verifier_hash IS 'SHA-256 hash of the secret half of the reset token';
+COMMENT ON COLUMN password_reset_tokens.ip_address IS 'IP address the reset was requested from';
+COMMENT ON COLUMN password_reset_tokens.used_at IS 'When the token was used or invalidated by another reset';
+COMMENT ON TABLE password_reset_attempts IS 'Password reset requests, kept for per-email and per-IP throttling';
@@ -0,0 +1,27 @@
+-- Track when the current email address was confirmed (NULL until the user clicks the link)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Create email_tokens table for verification, change-email confirmation and undo links.
+-- Tokens are selector.verifier pairs, like password reset tokens.
+CREATE TABLE IF NOT EXISTS email_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL,
+    selector VARCHAR(32) NOT NULL UNIQUE,
+    verifier_hash VARCHAR(64) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    previous_email VARCHAR(255),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_tokens_user_purpose ON email_tokens(user_id, purpose) WHERE used_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_email_tokens_expires ON email_tokens(expires_at);
+
+-- Comments
+COMMENT ON COLUMN users.email_verified_at IS 'When the current email address was confirmed; NULL if unverified';
+COMMENT ON TABLE email_tokens IS 'Single-use links for email verification, email change confirmation and undo';
+COMMENT ON COLUMN email_tokens.purpose IS 'verify_email, change_email or undo_email_change';
+COMMENT ON COLUMN email_tokens.email IS 'Address the token confirms (for undo: the address to restore)';
+COMMENT ON COLUMN email_tokens.previous_email IS 'Address the account had when the token was issued';
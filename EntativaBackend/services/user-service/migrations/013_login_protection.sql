@@ -0,0 +1,33 @@
+-- Create login_attempts table: every password login attempt, for lockout and anomaly checks
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY,
+    account_key VARCHAR(300) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45) NOT NULL,
+    device_key VARCHAR(255),
+    success BOOLEAN NOT NULL,
+    reason VARCHAR(50),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_account_created ON login_attempts(account_key, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created ON login_attempts(ip_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_device ON login_attempts(user_id, device_key) WHERE success;
+
+-- Create login_pow_challenges table for proof-of-work puzzles handed out when login risk is high
+CREATE TABLE IF NOT EXISTS login_pow_challenges (
+    challenge VARCHAR(64) PRIMARY KEY,
+    ip_address VARCHAR(45) NOT NULL,
+    difficulty INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_pow_challenges_expires ON login_pow_challenges(expires_at);
+
+-- Comments
+COMMENT ON TABLE login_attempts IS 'Password login attempts, successful and failed';
+COMMENT ON COLUMN login_attempts.account_key IS 'User ID, or unknown:<identifier> when no account matched';
+COMMENT ON COLUMN login_attempts.device_key IS 'Browser, OS and device type without versions, used to spot new devices';
+COMMENT ON TABLE login_pow_challenges IS 'Outstanding proof-of-work challenges; deleted when used';
+COMMENT ON COLUMN login_pow_challenges.difficulty IS 'Leading zero bits required in SHA-256(challenge:solution)';
@@ -0,0 +1,46 @@
+-- Create oauth_clients table: applications that can sign users in through OpenID Connect
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    secret_hash VARCHAR(64),
+    name VARCHAR(100) NOT NULL,
+    redirect_uris JSONB NOT NULL DEFAULT '[]'::jsonb,
+    allowed_scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    owner_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    is_first_party BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_owner ON oauth_clients(owner_user_id);
+
+-- Create oauth_consents table: scopes a user has approved for a client
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    granted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- Create oauth_authorization_codes table: single-use codes exchanged at the token endpoint
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    nonce VARCHAR(255),
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_expires ON oauth_authorization_codes(expires_at);
+
+-- Comments
+COMMENT ON TABLE oauth_clients IS 'OpenID Connect relying parties';
+COMMENT ON COLUMN oauth_clients.secret_hash IS 'SHA-256 of the client secret; NULL for public clients, which rely on PKCE alone';
+COMMENT ON COLUMN oauth_clients.is_first_party IS 'Entativa-operated apps (e.g. Vignette) that skip the consent screen; set by operators only';
+COMMENT ON TABLE oauth_consents IS 'Scopes each user has approved per client; deleting a row revokes consent';
+COMMENT ON COLUMN oauth_authorization_codes.code_hash IS 'SHA-256 of the authorization code';
+COMMENT ON COLUMN oauth_authorization_codes.code_challenge IS 'PKCE S256 challenge the code_verifier must match';
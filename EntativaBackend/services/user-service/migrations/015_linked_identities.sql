@@ -0,0 +1,29 @@
+-- Accounts created by cross-platform sign-in get a random password the user never sees
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_set BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Describe linked identities so users can recognise them, and record how each was linked
+ALTER TABLE cross_platform_links ADD COLUMN IF NOT EXISTS platform_username VARCHAR(100);
+ALTER TABLE cross_platform_links ADD COLUMN IF NOT EXISTS platform_email VARCHAR(255);
+ALTER TABLE cross_platform_links ADD COLUMN IF NOT EXISTS linked_via VARCHAR(20) NOT NULL DEFAULT 'sign_in';
+
+-- An identity on the other platform belongs to at most one account
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cross_platform_links_identity ON cross_platform_links(platform, platform_user_id);
+
+-- Create account_merges table: one row per merge, kept after the source account is deleted
+CREATE TABLE IF NOT EXISTS account_merges (
+    id UUID PRIMARY KEY,
+    source_user_id UUID NOT NULL,
+    target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moved JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_merges_target ON account_merges(target_user_id);
+CREATE INDEX IF NOT EXISTS idx_account_merges_source ON account_merges(source_user_id);
+
+-- Comments
+COMMENT ON COLUMN users.password_set IS 'FALSE while the account only has the random password set by cross-platform sign-in';
+COMMENT ON COLUMN cross_platform_links.linked_via IS 'sign_in (cross-platform sign-in), manual (linked from settings) or merge';
+COMMENT ON TABLE account_merges IS 'Audit trail of accounts merged into another; source_user_id has no FK so rows outlive the source';
+COMMENT ON COLUMN account_merges.moved IS 'Row counts moved per kind of data (links, follows, friendships, takes, profile)';
@@ -0,0 +1,50 @@
+-- Create data_exports table: one row per "Download Your Information" request.
+-- Workers claim jobs with a lease; a job whose worker dies is picked up again
+-- once its lease runs out and carries on from the parts already collected.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    completed_sections JSONB NOT NULL DEFAULT '[]'::jsonb,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    lease_owner VARCHAR(100),
+    lease_expires_at TIMESTAMP,
+    archive_key VARCHAR(255),
+    archive_size BIGINT,
+    download_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    requested_ip VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_created ON data_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_claimable ON data_exports(created_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires ON data_exports(expires_at) WHERE status = 'ready';
+
+-- Create data_export_parts table: collected data waiting to be packaged.
+-- Sections paged from other services store one row per page with the cursor
+-- for the next, so a resumed job continues mid-section.
+CREATE TABLE IF NOT EXISTS data_export_parts (
+    export_id UUID NOT NULL REFERENCES data_exports(id) ON DELETE CASCADE,
+    section VARCHAR(50) NOT NULL,
+    page INTEGER NOT NULL,
+    content JSONB NOT NULL,
+    next_cursor TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (export_id, section, page)
+);
+
+-- Comments
+COMMENT ON TABLE data_exports IS 'Asynchronous exports of everything held about a user';
+COMMENT ON COLUMN data_exports.status IS 'pending, running, ready, failed or expired';
+COMMENT ON COLUMN data_exports.completed_sections IS 'Sections fully collected so far, in order';
+COMMENT ON COLUMN data_exports.lease_owner IS 'Worker currently building the export';
+COMMENT ON COLUMN data_exports.lease_expires_at IS 'When another worker may take over a running export';
+COMMENT ON COLUMN data_exports.archive_key IS 'Storage key of the finished zip; removed when the export expires';
+COMMENT ON COLUMN data_exports.expires_at IS 'When the archive is deleted and download links stop working';
+COMMENT ON TABLE data_export_parts IS 'Collected export data, deleted once packaged into the archive';
+COMMENT ON COLUMN data_export_parts.next_cursor IS 'Cursor for the next page; NULL on the last page of a section';
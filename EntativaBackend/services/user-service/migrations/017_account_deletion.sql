@@ -0,0 +1,48 @@
+-- Create account_deletions table: one row per request to delete an account.
+-- A deletion waits out a grace period (cancelled if the user logs back in),
+-- then every service that holds the user's data is asked to purge it. The row
+-- outlives the user so the purge can be audited after the account is gone,
+-- which is why user_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    reason TEXT,
+    requested_ip VARCHAR(45),
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    purge_after TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP,
+    purge_started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one deletion in flight per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_active ON account_deletions(user_id) WHERE status IN ('scheduled', 'purging');
+CREATE INDEX IF NOT EXISTS idx_account_deletions_user ON account_deletions(user_id, requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_due ON account_deletions(purge_after) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_account_deletions_purging ON account_deletions(purge_started_at) WHERE status = 'purging';
+
+-- Create account_deletion_steps table: one row per service taking part in a purge.
+-- A step is re-requested with backoff until the service confirms it is done.
+CREATE TABLE IF NOT EXISTS account_deletion_steps (
+    deletion_id UUID NOT NULL REFERENCES account_deletions(id) ON DELETE CASCADE,
+    service VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    requested_at TIMESTAMP,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (deletion_id, service)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletion_steps_due ON account_deletion_steps(next_attempt_at) WHERE status <> 'completed';
+
+COMMENT ON TABLE account_deletions IS 'Account deletion requests: a grace period followed by a cross-service purge';
+COMMENT ON COLUMN account_deletions.status IS 'scheduled, cancelled, purging or completed';
+COMMENT ON COLUMN account_deletions.purge_after IS 'End of the grace period; logging in before then cancels the deletion';
+COMMENT ON TABLE account_deletion_steps IS 'Per-service progress of an account purge';
+COMMENT ON COLUMN account_deletion_steps.status IS 'pending, requested, failed or completed';
+COMMENT ON COLUMN account_deletion_steps.next_attempt_at IS 'When the user.deletion_requested event is sent again if the service has not confirmed';
@@ -0,0 +1,61 @@
+-- Create user_affiliations table: communities a user belongs to and events they
+-- are going to, reported by the community and event services. People who share
+-- them are more likely to know each other.
+CREATE TABLE IF NOT EXISTS user_affiliations (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    affiliation_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, kind, affiliation_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_affiliations_affiliation ON user_affiliations(kind, affiliation_id);
+
+-- Create friend_suggestions table: each user's precomputed "People You May Know",
+-- with the signals that produced each score
+CREATE TABLE IF NOT EXISTS friend_suggestions (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    suggested_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    score INTEGER NOT NULL,
+    mutual_friends INTEGER NOT NULL DEFAULT 0,
+    shared_workplaces INTEGER NOT NULL DEFAULT 0,
+    shared_schools INTEGER NOT NULL DEFAULT 0,
+    shared_communities INTEGER NOT NULL DEFAULT 0,
+    shared_events INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, suggested_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_friend_suggestions_rank ON friend_suggestions(user_id, score DESC, suggested_user_id);
+
+-- Create friend_suggestion_dismissals table: people a user has said they don't
+-- want suggested again
+CREATE TABLE IF NOT EXISTS friend_suggestion_dismissals (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dismissed_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dismissed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, dismissed_user_id)
+);
+
+-- Create friend_suggestion_runs table: when each user's suggestions were last
+-- computed. A friendship or affiliation change marks the users it affects stale
+-- so the worker recomputes them ahead of the periodic refresh.
+CREATE TABLE IF NOT EXISTS friend_suggestion_runs (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    stale BOOLEAN NOT NULL DEFAULT TRUE,
+    computed_at TIMESTAMP,
+    claimed_until TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_friend_suggestion_runs_due ON friend_suggestion_runs(stale DESC, computed_at NULLS FIRST);
+
+-- Shared workplaces and schools are found by containment on the profile's JSON arrays
+CREATE INDEX IF NOT EXISTS idx_profiles_work ON profiles USING GIN (work jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_profiles_education ON profiles USING GIN (education jsonb_path_ops);
+
+COMMENT ON TABLE user_affiliations IS 'Community memberships and event RSVPs reported by other services, for friend suggestions';
+COMMENT ON COLUMN user_affiliations.kind IS 'community or event';
+COMMENT ON TABLE friend_suggestions IS 'Precomputed People You May Know, ranked by score';
+COMMENT ON TABLE friend_suggestion_dismissals IS 'Suggestions a user has dismissed; never suggested again';
+COMMENT ON TABLE friend_suggestion_runs IS 'Per-user suggestion refresh state';
+COMMENT ON COLUMN friend_suggestion_runs.claimed_until IS 'Lease held by the worker computing this user, so instances do not duplicate work';
@@ -0,0 +1,44 @@
+-- Create audience_lists table: named groups of people a user can share with,
+-- such as "Close Friends" or "Family". A list's ID can be used as an audience
+-- wherever public, friends or only_me is accepted.
+CREATE TABLE IF NOT EXISTS audience_lists (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'custom',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind IN ('close_friends', 'custom'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audience_lists_owner_name ON audience_lists(owner_id, LOWER(name));
+
+-- Everyone has at most one Close Friends list
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audience_lists_close_friends ON audience_lists(owner_id) WHERE kind = 'close_friends';
+
+-- Create audience_list_members table
+CREATE TABLE IF NOT EXISTS audience_list_members (
+    list_id UUID NOT NULL REFERENCES audience_lists(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, member_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_list_members_member ON audience_list_members(member_id);
+
+-- Carry the existing close_friends table over to each user's Close Friends list
+INSERT INTO audience_lists (id, owner_id, name, kind)
+SELECT uuid_generate_v4(), user_id, 'Close Friends', 'close_friends'
+FROM (SELECT DISTINCT user_id FROM close_friends) owners
+ON CONFLICT DO NOTHING;
+
+INSERT INTO audience_list_members (list_id, member_id, added_at)
+SELECT l.id, c.close_friend_id, c.created_at
+FROM close_friends c
+JOIN audience_lists l ON l.owner_id = c.user_id AND l.kind = 'close_friends'
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE audience_lists IS 'User-defined audiences (Close Friends, Family, ...) for posts, stories and profile fields';
+COMMENT ON COLUMN audience_lists.kind IS 'close_friends for the built-in Close Friends list, otherwise custom';
+COMMENT ON TABLE audience_list_members IS 'Who is in each audience list; members are not told';
+COMMENT ON TABLE close_friends IS 'Superseded by audience_lists (kind close_friends); kept for existing readers';
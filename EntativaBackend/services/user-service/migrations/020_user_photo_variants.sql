@@ -0,0 +1,11 @@
+-- Profile pictures and cover photos are published in several sizes. The _url
+-- columns keep the size most screens use; the _variants columns list every size
+-- with its storage key, and the _blurhash columns hold a placeholder to show
+-- while a photo loads.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_picture_variants JSONB;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_picture_blurhash VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cover_photo_variants JSONB;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cover_photo_blurhash VARCHAR(64);
+
+COMMENT ON COLUMN users.profile_picture_variants IS 'Every published size: [{name, key, url, width, height}]';
+COMMENT ON COLUMN users.cover_photo_variants IS 'Every published size: [{name, key, url, width, height}]';
@@ -0,0 +1,68 @@
+-- Every username change is recorded. The old name stays reserved for its previous
+-- owner until reserved_until, and lookups by it are redirected to the new name.
+CREATE TABLE IF NOT EXISTS username_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username VARCHAR(30) NOT NULL,
+    new_username VARCHAR(30) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reserved_until TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_old_username ON username_history(LOWER(old_username), changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, changed_at DESC);
+
+-- Names nobody can choose: brand names anywhere in a username, and staff and
+-- system names as the whole username. Existing holders keep theirs.
+CREATE TABLE IF NOT EXISTS reserved_usernames (
+    pattern VARCHAR(30) PRIMARY KEY,
+    match_kind VARCHAR(10) NOT NULL DEFAULT 'exact',
+    reason VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (pattern = LOWER(pattern)),
+    CHECK (match_kind IN ('exact', 'contains')),
+    CHECK (reason IN ('brand', 'staff', 'system'))
+);
+
+INSERT INTO reserved_usernames (pattern, match_kind, reason) VALUES
+    ('entativa', 'contains', 'brand'),
+    ('vignette', 'contains', 'brand'),
+    ('socialink', 'contains', 'brand'),
+    ('neoqiss', 'exact', 'staff'),
+    ('admin', 'exact', 'staff'),
+    ('administrator', 'exact', 'staff'),
+    ('moderator', 'exact', 'staff'),
+    ('staff', 'exact', 'staff'),
+    ('team', 'exact', 'staff'),
+    ('official', 'exact', 'staff'),
+    ('support', 'exact', 'staff'),
+    ('help', 'exact', 'staff'),
+    ('helpdesk', 'exact', 'staff'),
+    ('security', 'exact', 'staff'),
+    ('safety', 'exact', 'staff'),
+    ('trust', 'exact', 'staff'),
+    ('legal', 'exact', 'staff'),
+    ('press', 'exact', 'staff'),
+    ('billing', 'exact', 'staff'),
+    ('root', 'exact', 'system'),
+    ('system', 'exact', 'system'),
+    ('api', 'exact', 'system'),
+    ('www', 'exact', 'system'),
+    ('mail', 'exact', 'system'),
+    ('email', 'exact', 'system'),
+    ('noreply', 'exact', 'system'),
+    ('no_reply', 'exact', 'system'),
+    ('settings', 'exact', 'system'),
+    ('login', 'exact', 'system'),
+    ('logout', 'exact', 'system'),
+    ('signup', 'exact', 'system'),
+    ('register', 'exact', 'system'),
+    ('account', 'exact', 'system'),
+    ('explore', 'exact', 'system'),
+    ('search', 'exact', 'system'),
+    ('about', 'exact', 'system'),
+    ('terms', 'exact', 'system'),
+    ('privacy', 'exact', 'system'),
+    ('null', 'exact', 'system'),
+    ('undefined', 'exact', 'system')
+ON CONFLICT (pattern) DO NOTHING;
@@ -0,0 +1,16 @@
+-- password_set was added with DEFAULT TRUE, which also marked accounts created by
+-- cross-platform sign-in before 015 as having a password. Those accounts were created
+-- and linked in the same request, so the link is only moments younger than the user.
+-- Accounts that later linked an existing password account are left alone, as are
+-- accounts that have since logged in with a password.
+UPDATE users u
+SET password_set = FALSE
+FROM cross_platform_links l
+WHERE l.user_id = u.id
+  AND l.linked_via = 'sign_in'
+  AND l.created_at BETWEEN u.created_at AND u.created_at + INTERVAL '1 minute'
+  AND u.password_set = TRUE
+  AND NOT EXISTS (
+      SELECT 1 FROM login_attempts a
+      WHERE a.user_id = u.id AND a.success
+  );
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache stores JSON values for repositories that cache reads
+type Cache interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string, dest interface{}) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ClientCache is a Cache on a shared Redis client, using each caller's context
+type ClientCache struct {
+	client *redis.Client
+}
+
+// NewClientCache creates a Cache on an existing Redis client
+func NewClientCache(client *redis.Client) *ClientCache {
+	return &ClientCache{client: client}
+}
+
+// Set stores a value in Redis with expiration
+func (c *ClientCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return c.client.Set(ctx, key, data, expiration).Err()
+}
+
+// Get retrieves a value from Redis
+func (c *ClientCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return fmt.Errorf("key not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get value: %w", err)
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// Delete removes a key from Redis
+func (c *ClientCache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
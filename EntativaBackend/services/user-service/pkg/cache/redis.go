@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	"socialink/user-service/internal/config"
+	"user-service/internal/config"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"math"
+	"strings"
+)
+
+// ErrInvalidComponents means a blurhash was asked for with other than 1 to 9
+// components on an axis
+var ErrInvalidComponents = errors.New("imaging: blurhash components must be between 1 and 9")
+
+const base83Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// blurhashSampleSize is the size images are reduced to before hashing; a
+// blurhash keeps nothing finer anyway
+const blurhashSampleSize = 32
+
+// Blurhash encodes img as a BlurHash (https://blurha.sh), a short string the
+// apps draw as a blurred placeholder while the photo loads
+func Blurhash(img image.Image, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", ErrInvalidComponents
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = flatten(img)
+	}
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if w > blurhashSampleSize || h > blurhashSampleSize {
+		if w >= h {
+			w, h = blurhashSampleSize, clampInt(h*blurhashSampleSize/w, 1, blurhashSampleSize)
+		} else {
+			w, h = clampInt(w*blurhashSampleSize/h, 1, blurhashSampleSize), blurhashSampleSize
+		}
+		rgba = resize(rgba, w, h)
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			factors = append(factors, blurhashFactor(rgba, w, h, i, j))
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+
+	maximum := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			for _, c := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(c))
+			}
+		}
+		quantised := clampInt(int(math.Floor(actualMaximum*166-0.5)), 0, 82)
+		maximum = float64(quantised+1) / 166
+		hash.WriteString(encodeBase83(quantised, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(encodeDC(dc), 4))
+	for _, factor := range ac {
+		hash.WriteString(encodeBase83(encodeAC(factor, maximum), 2))
+	}
+
+	return hash.String(), nil
+}
+
+// blurhashFactor is the (i, j) cosine component of img's colour in linear RGB
+func blurhashFactor(img *image.RGBA, w, h, i, j int) [3]float64 {
+	normalisation := 2.0
+	if i == 0 && j == 0 {
+		normalisation = 1
+	}
+
+	bounds := img.Bounds()
+	var r, g, b float64
+	for y := 0; y < h; y++ {
+		cosY := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+		for x := 0; x < w; x++ {
+			basis := normalisation * math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * cosY
+			p := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			r += basis * sRGBToLinear(img.Pix[p])
+			g += basis * sRGBToLinear(img.Pix[p+1])
+			b += basis * sRGBToLinear(img.Pix[p+2])
+		}
+	}
+
+	scale := 1 / float64(w*h)
+	return [3]float64{r * scale, g * scale, b * scale}
+}
+
+func encodeDC(c [3]float64) int {
+	return linearToSRGB(c[0])<<16 | linearToSRGB(c[1])<<8 | linearToSRGB(c[2])
+}
+
+func encodeAC(c [3]float64, maximum float64) int {
+	quantise := func(v float64) int {
+		return clampInt(int(math.Floor(signPow(v/maximum, 0.5)*9+9.5)), 0, 18)
+	}
+	return quantise(c[0])*19*19 + quantise(c[1])*19 + quantise(c[2])
+}
+
+func encodeBase83(value, length int) string {
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base83Alphabet[value%83]
+		value /= 83
+	}
+	return string(encoded)
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+func clampInt(value, low, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
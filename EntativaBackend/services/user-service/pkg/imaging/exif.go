@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF orientations, naming how the stored pixels must be turned to display
+// upright
+const (
+	orientationNormal     = 1
+	orientationFlipH      = 2
+	orientationRotate180  = 3
+	orientationFlipV      = 4
+	orientationTranspose  = 5
+	orientationRotate90   = 6
+	orientationTransverse = 7
+	orientationRotate270  = 8
+)
+
+const exifOrientationTag = 0x0112
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegOrientation reads the EXIF orientation of a JPEG. Anything missing or
+// malformed is treated as normal.
+func jpegOrientation(data []byte) int {
+	i := 2 // past the SOI marker
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return orientationNormal
+		}
+
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker
+			i++
+			continue
+		case marker == 0x01, marker >= 0xD0 && marker <= 0xD8:
+			// Markers without a length
+			i += 2
+			continue
+		case marker == 0xDA, marker == 0xD9:
+			// Image data or the end: metadata always comes before
+			return orientationNormal
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return orientationNormal
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			if orientation := tiffOrientation(segment[len(exifHeader):]); orientation != 0 {
+				return orientation
+			}
+		}
+
+		i += 2 + length
+	}
+	return orientationNormal
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of an EXIF TIFF
+// block, returning 0 when there is none
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+
+		// A SHORT, stored in the first bytes of the value field
+		if order.Uint16(tiff[entry+2:]) != 3 {
+			return 0
+		}
+		orientation := int(order.Uint16(tiff[entry+8:]))
+		if orientation < orientationNormal || orientation > orientationRotate270 {
+			return 0
+		}
+		return orientation
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+// Package imaging turns uploaded photos into the sizes the apps display.
+//
+// Uploads are identified by their content, never by their name or declared
+// type. Every output is re-encoded from decoded pixels, so EXIF, GPS and any
+// other metadata in an upload never reach storage. The EXIF orientation is
+// applied first, so the pixels face the way the camera intended.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+	ErrCorruptImage      = errors.New("imaging: image could not be decoded")
+	ErrImageTooLarge     = errors.New("imaging: image dimensions too large")
+	ErrImageTooSmall     = errors.New("imaging: image too small")
+)
+
+// Limits on decoded images, so a small file can't expand into gigabytes of pixels
+const (
+	MaxSide   = 12000
+	MaxPixels = 40_000_000
+)
+
+// Format is an image encoding recognised from its magic bytes
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+)
+
+var (
+	jpegMagic  = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic   = []byte("\x89PNG\r\n\x1a\n")
+	gif87Magic = []byte("GIF87a")
+	gif89Magic = []byte("GIF89a")
+)
+
+// DetectFormat identifies an image from its leading bytes
+func DetectFormat(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, jpegMagic):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(data, pngMagic):
+		return FormatPNG, nil
+	case bytes.HasPrefix(data, gif87Magic), bytes.HasPrefix(data, gif89Magic):
+		return FormatGIF, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// decode checks an image's dimensions before decoding it. Only the first frame
+// of an animated GIF is kept.
+func decode(data []byte, format Format) (image.Image, error) {
+	var decodeConfig func(*bytes.Reader) (image.Config, error)
+	var decodeImage func(*bytes.Reader) (image.Image, error)
+
+	switch format {
+	case FormatJPEG:
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return jpeg.DecodeConfig(r) }
+		decodeImage = func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) }
+	case FormatPNG:
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return png.DecodeConfig(r) }
+		decodeImage = func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) }
+	case FormatGIF:
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return gif.DecodeConfig(r) }
+		decodeImage = func(r *bytes.Reader) (image.Image, error) { return gif.Decode(r) }
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorruptImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrCorruptImage
+	}
+	if config.Width > MaxSide || config.Height > MaxSide || config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, err := decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorruptImage
+	}
+	return img, nil
+}
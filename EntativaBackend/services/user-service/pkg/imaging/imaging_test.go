@@ -0,0 +1,463 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// quadrants draws an image whose four quarters are red, green, blue and white,
+// clockwise from the top left, so turning and flipping it is easy to see
+func quadrants(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var c color.RGBA
+			switch {
+			case x < w/2 && y < h/2:
+				c = color.RGBA{255, 0, 0, 255}
+			case x >= w/2 && y < h/2:
+				c = color.RGBA{0, 255, 0, 255}
+			case x >= w/2 && y >= h/2:
+				c = color.RGBA{0, 0, 255, 255}
+			default:
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withEXIF inserts an EXIF segment right after a JPEG's SOI marker, holding an
+// orientation, a camera make and a GPS position, the way phones write them
+func withEXIF(data []byte, orientation uint16) []byte {
+	order := binary.LittleEndian
+
+	tiff := []byte("II")
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+
+	// IFD0: Make, Orientation, GPS IFD pointer
+	const entries = 3
+	makeOffset := uint32(8 + 2 + entries*12 + 4)
+	makeValue := []byte("PhoneCam\x00")
+	gpsOffset := makeOffset + uint32(len(makeValue))
+
+	tiff = order.AppendUint16(tiff, entries)
+	tiff = appendIFDEntry(order, tiff, 0x010F, 2, uint32(len(makeValue)), makeOffset)
+	tiff = appendIFDEntry(order, tiff, exifOrientationTag, 3, 1, uint32(orientation))
+	tiff = appendIFDEntry(order, tiff, 0x8825, 4, 1, gpsOffset)
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, makeValue...)
+
+	// GPS IFD: latitude reference only, which is enough to spot
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = appendIFDEntry(order, tiff, 0x0001, 2, 2, uint32('N'))
+	tiff = order.AppendUint32(tiff, 0)
+
+	payload := append(append([]byte(nil), exifHeader...), tiff...)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func appendIFDEntry(order binary.AppendByteOrder, b []byte, tag, kind uint16, count, value uint32) []byte {
+	b = order.AppendUint16(b, tag)
+	b = order.AppendUint16(b, kind)
+	b = order.AppendUint32(b, count)
+	return order.AppendUint32(b, value)
+}
+
+// colorNear reports whether the pixel at x, y is roughly c, allowing for JPEG loss
+func colorNear(img image.Image, x, y int, c color.RGBA) bool {
+	r, g, b, _ := img.At(x, y).RGBA()
+	near := func(v uint32, want uint8) bool {
+		d := int(v>>8) - int(want)
+		return d > -40 && d < 40
+	}
+	return near(r, c.R) && near(g, c.G) && near(b, c.B)
+}
+
+func decodeOutput(t *testing.T, output Output) image.Image {
+	t.Helper()
+
+	img, err := jpeg.Decode(bytes.NewReader(output.Data))
+	if err != nil {
+		t.Fatalf("output %s is not a jpeg: %v", output.Variant, err)
+	}
+	return img
+}
+
+func outputNamed(t *testing.T, result *Result, name string) Output {
+	t.Helper()
+
+	for _, output := range result.Outputs {
+		if output.Variant == name {
+			return output
+		}
+	}
+	t.Fatalf("no %s output", name)
+	return Output{}
+}
+
+func TestDetectFormat(t *testing.T) {
+	img := quadrants(4, 4)
+
+	var gifBuf bytes.Buffer
+	if err := gif.Encode(&gifBuf, img, nil); err != nil {
+		t.Fatalf("failed to encode gif: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+		err  error
+	}{
+		{"jpeg", encodeJPEG(t, img), FormatJPEG, nil},
+		{"png", encodePNG(t, img), FormatPNG, nil},
+		{"gif", gifBuf.Bytes(), FormatGIF, nil},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "", ErrUnsupportedFormat},
+		{"html", []byte("<html><script>alert(1)</script>"), "", ErrUnsupportedFormat},
+		{"empty", nil, "", ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DetectFormat() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessProfilePicture(t *testing.T) {
+	result, err := Process(encodePNG(t, quadrants(600, 400)), ProfilePictureSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if result.SourceFormat != FormatPNG {
+		t.Errorf("SourceFormat = %q, want png", result.SourceFormat)
+	}
+	if result.Width != 400 || result.Height != 400 {
+		t.Errorf("crop = %dx%d, want 400x400", result.Width, result.Height)
+	}
+
+	want := map[string]int{"thumbnail": 80, "small": 160, "medium": 320, "large": 400}
+	if len(result.Outputs) != len(want) {
+		t.Fatalf("got %d outputs, want %d", len(result.Outputs), len(want))
+	}
+	for _, output := range result.Outputs {
+		size, ok := want[output.Variant]
+		if !ok {
+			t.Fatalf("unexpected output %s", output.Variant)
+		}
+		if output.Width != size || output.Height != size {
+			t.Errorf("%s = %dx%d, want %dx%d", output.Variant, output.Width, output.Height, size, size)
+		}
+
+		img := decodeOutput(t, output)
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("%s decodes as %dx%d, want %dx%d", output.Variant, b.Dx(), b.Dy(), size, size)
+		}
+	}
+
+	// The square is cut from the middle: red top left, blue bottom right
+	medium := decodeOutput(t, outputNamed(t, result, "medium"))
+	if !colorNear(medium, 40, 40, color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("top left of crop is %v, want red", medium.At(40, 40))
+	}
+	if !colorNear(medium, 280, 280, color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("bottom right of crop is %v, want blue", medium.At(280, 280))
+	}
+}
+
+func TestProcessCoverPhoto(t *testing.T) {
+	result, err := Process(encodeJPEG(t, quadrants(2400, 1200)), CoverPhotoSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if result.Width != 2400 || result.Height != 900 {
+		t.Errorf("crop = %dx%d, want 2400x900", result.Width, result.Height)
+	}
+
+	want := map[string][2]int{"small": {480, 180}, "medium": {960, 360}, "large": {1920, 720}}
+	for _, output := range result.Outputs {
+		size := want[output.Variant]
+		img := decodeOutput(t, output)
+		if b := img.Bounds(); b.Dx() != size[0] || b.Dy() != size[1] {
+			t.Errorf("%s = %dx%d, want %dx%d", output.Variant, b.Dx(), b.Dy(), size[0], size[1])
+		}
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	data := withEXIF(encodeJPEG(t, quadrants(400, 400)), orientationNormal)
+	if !bytes.Contains(data, []byte("PhoneCam")) {
+		t.Fatal("test image has no EXIF")
+	}
+
+	result, err := Process(data, ProfilePictureSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for _, output := range result.Outputs {
+		if bytes.Contains(output.Data, exifHeader) || bytes.Contains(output.Data, []byte("PhoneCam")) {
+			t.Errorf("%s still carries EXIF", output.Variant)
+		}
+		if jpegOrientation(output.Data) != orientationNormal {
+			t.Errorf("%s still carries an orientation", output.Variant)
+		}
+	}
+}
+
+func TestProcessAutoOrients(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	// Stored pixels are red, green / white, blue. Each orientation says how to
+	// turn them; after processing, these are the colours at each corner.
+	tests := []struct {
+		orientation             uint16
+		topLeft, topRight       color.RGBA
+		bottomLeft, bottomRight color.RGBA
+	}{
+		{orientationNormal, red, green, white, blue},
+		{orientationFlipH, green, red, blue, white},
+		{orientationRotate180, blue, white, green, red},
+		{orientationFlipV, white, blue, red, green},
+		{orientationTranspose, red, white, green, blue},
+		{orientationRotate90, white, red, blue, green},
+		{orientationTransverse, blue, green, white, red},
+		{orientationRotate270, green, blue, red, white},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(rune('0'+tt.orientation)), func(t *testing.T) {
+			data := withEXIF(encodeJPEG(t, quadrants(400, 400)), tt.orientation)
+
+			result, err := Process(data, ProfilePictureSpec)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			img := decodeOutput(t, outputNamed(t, result, "medium"))
+			corners := []struct {
+				x, y int
+				want color.RGBA
+			}{
+				{40, 40, tt.topLeft},
+				{280, 40, tt.topRight},
+				{40, 280, tt.bottomLeft},
+				{280, 280, tt.bottomRight},
+			}
+			for _, corner := range corners {
+				if !colorNear(img, corner.x, corner.y, corner.want) {
+					t.Errorf("pixel (%d, %d) = %v, want %v", corner.x, corner.y, img.At(corner.x, corner.y), corner.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessOrientsBeforeCropping(t *testing.T) {
+	// A landscape sensor image of a portrait scene: too narrow for a cover once turned
+	data := withEXIF(encodeJPEG(t, quadrants(1000, 400)), orientationRotate90)
+
+	if _, err := Process(data, CoverPhotoSpec); !errors.Is(err, ErrImageTooSmall) {
+		t.Fatalf("Process() error = %v, want ErrImageTooSmall", err)
+	}
+}
+
+func TestProcessFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+
+	result, err := Process(encodePNG(t, img), ProfilePictureSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	out := decodeOutput(t, outputNamed(t, result, "small"))
+	if !colorNear(out, 80, 80, color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent pixel became %v, want white", out.At(80, 80))
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, MaxSide+1, 1)))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not an image", []byte("just some text"), ErrUnsupportedFormat},
+		{"truncated", encodeJPEG(t, quadrants(400, 400))[:200], ErrCorruptImage},
+		{"too small", encodePNG(t, quadrants(100, 100)), ErrImageTooSmall},
+		{"too large", huge, ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data, ProfilePictureSpec); !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlurhash(t *testing.T) {
+	img := flatten(quadrants(64, 64))
+
+	hash, err := Blurhash(img, 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash() error = %v", err)
+	}
+
+	// 1 size + 1 maximum + 4 DC + 2 for each of 11 AC components
+	if len(hash) != 28 {
+		t.Errorf("len(hash) = %d, want 28", len(hash))
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune(base83Alphabet, c) {
+			t.Fatalf("hash %q has %q outside base83", hash, c)
+		}
+	}
+
+	again, _ := Blurhash(img, 4, 3)
+	if again != hash {
+		t.Errorf("Blurhash() not deterministic: %q then %q", hash, again)
+	}
+
+	if _, err := Blurhash(img, 0, 3); !errors.Is(err, ErrInvalidComponents) {
+		t.Errorf("Blurhash(0, 3) error = %v, want ErrInvalidComponents", err)
+	}
+}
+
+func TestBlurhashSolidColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	hash, err := Blurhash(img, 1, 1)
+	if err != nil {
+		t.Fatalf("Blurhash() error = %v", err)
+	}
+
+	// Size 0, maximum 0, then white: 0xFFFFFF in four base83 digits
+	if want := "00" + encodeBase83(0xFFFFFF, 4); hash != want {
+		t.Errorf("Blurhash() = %q, want %q", hash, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewMemoryStore("https://cdn.example.com/")
+
+	result, err := Process(encodePNG(t, quadrants(800, 800)), ProfilePictureSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	stored, err := Store(context.Background(), store, "profile-pictures/u1/p1", result)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if len(stored) != len(ProfilePictureSpec.Variants) {
+		t.Fatalf("stored %d images, want %d", len(stored), len(ProfilePictureSpec.Variants))
+	}
+	for _, image := range stored {
+		wantKey := "profile-pictures/u1/p1/" + image.Variant + ".jpg"
+		if image.Key != wantKey {
+			t.Errorf("key = %q, want %q", image.Key, wantKey)
+		}
+		if image.URL != "https://cdn.example.com/"+wantKey {
+			t.Errorf("url = %q", image.URL)
+		}
+
+		object, ok := store.Get(image.Key)
+		if !ok {
+			t.Fatalf("%s not in store", image.Key)
+		}
+		if object.ContentType != ContentType {
+			t.Errorf("%s content type = %q, want %q", image.Key, object.ContentType, ContentType)
+		}
+		if format, _ := DetectFormat(object.Data); format != FormatJPEG {
+			t.Errorf("%s is %q, want jpeg", image.Key, format)
+		}
+	}
+}
+
+// failingStore accepts a number of puts, then fails
+type failingStore struct {
+	*MemoryStore
+	remaining int
+}
+
+func (s *failingStore) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	if s.remaining == 0 {
+		return "", errors.New("bucket unavailable")
+	}
+	s.remaining--
+	return s.MemoryStore.Put(ctx, key, data, contentType)
+}
+
+func TestStoreCleansUpAfterFailure(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore("https://cdn.example.com"), remaining: 2}
+
+	result, err := Process(encodePNG(t, quadrants(800, 800)), ProfilePictureSpec)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if _, err := Store(context.Background(), store, "profile-pictures/u1/p1", result); err == nil {
+		t.Fatal("Store() succeeded with a failing store")
+	}
+	if keys := store.Keys(); len(keys) != 0 {
+		t.Errorf("store left %v behind", keys)
+	}
+}
@@ -0,0 +1,183 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// Variant is one size an image is published in
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Spec describes how one kind of photo is cropped and sized
+type Spec struct {
+	AspectWidth  int // every variant is cropped to this aspect ratio
+	AspectHeight int
+	MinWidth     int // smallest upload accepted, after orientation
+	MinHeight    int
+	Variants     []Variant
+	BlurhashX    int // blurhash components across and down
+	BlurhashY    int
+	Quality      int // JPEG quality of every variant
+}
+
+// ProfilePictureSpec publishes profile pictures as square crops, from list
+// avatars up to the full-screen view
+var ProfilePictureSpec = Spec{
+	AspectWidth:  1,
+	AspectHeight: 1,
+	MinWidth:     160,
+	MinHeight:    160,
+	Variants: []Variant{
+		{Name: "thumbnail", Width: 80, Height: 80},
+		{Name: "small", Width: 160, Height: 160},
+		{Name: "medium", Width: 320, Height: 320},
+		{Name: "large", Width: 720, Height: 720},
+	},
+	BlurhashX: 4,
+	BlurhashY: 4,
+	Quality:   85,
+}
+
+// CoverPhotoSpec publishes cover photos as wide crops for phones up to desktop
+var CoverPhotoSpec = Spec{
+	AspectWidth:  8,
+	AspectHeight: 3,
+	MinWidth:     480,
+	MinHeight:    180,
+	Variants: []Variant{
+		{Name: "small", Width: 480, Height: 180},
+		{Name: "medium", Width: 960, Height: 360},
+		{Name: "large", Width: 1920, Height: 720},
+	},
+	BlurhashX: 5,
+	BlurhashY: 3,
+	Quality:   85,
+}
+
+// ContentType of every processed variant
+const ContentType = "image/jpeg"
+
+// Output is one encoded variant of a processed image
+type Output struct {
+	Variant string
+	Width   int
+	Height  int
+	Data    []byte
+}
+
+// Result is a processed upload, ready to store
+type Result struct {
+	SourceFormat Format
+	Width        int // size of the crop every output was made from
+	Height       int
+	Blurhash     string
+	Outputs      []Output
+}
+
+// StoredImage is an output saved to an ObjectStore
+type StoredImage struct {
+	Variant string
+	Key     string
+	URL     string
+	Width   int
+	Height  int
+}
+
+// Process identifies, decodes, orients, crops and resizes an upload per spec.
+// A variant larger than the upload is made at the upload's size instead of
+// being enlarged.
+func Process(data []byte, spec Spec) (*Result, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	img := flatten(decoded)
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() < spec.MinWidth || bounds.Dy() < spec.MinHeight {
+		return nil, ErrImageTooSmall
+	}
+
+	crop := cropToAspect(img, spec.AspectWidth, spec.AspectHeight)
+	cw, ch := crop.Bounds().Dx(), crop.Bounds().Dy()
+
+	blurhash, err := Blurhash(crop, spec.BlurhashX, spec.BlurhashY)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		SourceFormat: format,
+		Width:        cw,
+		Height:       ch,
+		Blurhash:     blurhash,
+		Outputs:      make([]Output, 0, len(spec.Variants)),
+	}
+
+	for _, variant := range spec.Variants {
+		w, h := fit(cw, ch, variant)
+
+		var sized image.Image = crop
+		if w != cw || h != ch {
+			sized = resize(crop, w, h)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, sized, &jpeg.Options{Quality: spec.Quality}); err != nil {
+			return nil, fmt.Errorf("imaging: failed to encode %s: %w", variant.Name, err)
+		}
+
+		result.Outputs = append(result.Outputs, Output{
+			Variant: variant.Name,
+			Width:   w,
+			Height:  h,
+			Data:    buf.Bytes(),
+		})
+	}
+
+	return result, nil
+}
+
+// Store saves every output of result as keyPrefix/<variant>.jpg. If any fails
+// to save, those already saved are deleted again.
+func Store(ctx context.Context, store ObjectStore, keyPrefix string, result *Result) ([]StoredImage, error) {
+	stored := make([]StoredImage, 0, len(result.Outputs))
+
+	for _, output := range result.Outputs {
+		key := fmt.Sprintf("%s/%s.jpg", keyPrefix, output.Variant)
+
+		url, err := store.Put(ctx, key, output.Data, ContentType)
+		if err != nil {
+			for _, saved := range stored {
+				store.Delete(context.Background(), saved.Key)
+			}
+			return nil, fmt.Errorf("imaging: failed to store %s: %w", output.Variant, err)
+		}
+
+		stored = append(stored, StoredImage{
+			Variant: output.Variant,
+			Key:     key,
+			URL:     url,
+			Width:   output.Width,
+			Height:  output.Height,
+		})
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,90 @@
+package imaging
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ObjectStore is where processed images are published, such as S3 or MinIO
+type ObjectStore interface {
+	// Put saves data under key, readable by anyone, and returns its public URL
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+
+	// Delete removes key. Deleting a key that doesn't exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryObject is an object held by a MemoryStore
+type MemoryObject struct {
+	Data        []byte
+	ContentType string
+}
+
+// MemoryStore is an ObjectStore held in memory, for tests and local development
+type MemoryStore struct {
+	baseURL string
+
+	mu      sync.Mutex
+	objects map[string]MemoryObject
+}
+
+// NewMemoryStore creates an empty store whose objects are addressed under baseURL
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		objects: map[string]MemoryObject{},
+	}
+}
+
+// Put saves a copy of data under key
+func (s *MemoryStore) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = MemoryObject{
+		Data:        append([]byte(nil), data...),
+		ContentType: contentType,
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete removes key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+	return nil
+}
+
+// Get returns the object saved under key
+func (s *MemoryStore) Get(key string) (MemoryObject, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[key]
+	return object, ok
+}
+
+// Keys lists every key in the store, in order
+func (s *MemoryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// flatten copies img onto an opaque white canvas, the background transparent
+// areas get in a JPEG
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Over)
+	return canvas
+}
+
+// orient turns img so it displays upright for its EXIF orientation
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= orientationNormal || orientation > orientationRotate270 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dw, dh := w, h
+	if orientation >= orientationTranspose {
+		dw, dh = h, w
+	}
+
+	// source maps a pixel of the result back to where it is in img
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case orientationFlipH:
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case orientationRotate180:
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case orientationFlipV:
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case orientationTranspose:
+		source = func(x, y int) (int, int) { return y, x }
+	case orientationRotate90:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case orientationTransverse:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case orientationRotate270:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			si := img.PixOffset(bounds.Min.X+sx, bounds.Min.Y+sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// cropToAspect cuts the largest centred region of img with the given aspect ratio
+func cropToAspect(img *image.RGBA, aspectWidth, aspectHeight int) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	cw, ch := w, w*aspectHeight/aspectWidth
+	if ch > h {
+		cw, ch = h*aspectWidth/aspectHeight, h
+	}
+	if cw < 1 {
+		cw = 1
+	}
+	if ch < 1 {
+		ch = 1
+	}
+
+	x0 := bounds.Min.X + (w-cw)/2
+	y0 := bounds.Min.Y + (h-ch)/2
+	return img.SubImage(image.Rect(x0, y0, x0+cw, y0+ch)).(*image.RGBA)
+}
+
+// resize scales img down to w by h, averaging the source pixels behind each
+// result pixel. It is not meant for enlarging.
+func resize(img *image.RGBA, w, h int) *image.RGBA {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*sh/h
+		y1 := bounds.Min.Y + (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*sw/w
+			x1 := bounds.Min.X + (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := img.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(img.Pix[i])
+					g += uint32(img.Pix[i+1])
+					b += uint32(img.Pix[i+2])
+					a += uint32(img.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			di := dst.PixOffset(x, y)
+			dst.Pix[di] = uint8(r / n)
+			dst.Pix[di+1] = uint8(g / n)
+			dst.Pix[di+2] = uint8(b / n)
+			dst.Pix[di+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// fit returns the size of a variant for an image of w by h: the variant's own
+// size, or smaller at the same aspect ratio when the image is smaller than it
+func fit(w, h int, variant Variant) (int, int) {
+	if w >= variant.Width && h >= variant.Height {
+		return variant.Width, variant.Height
+	}
+
+	scale := float64(w) / float64(variant.Width)
+	if s := float64(h) / float64(variant.Height); s < scale {
+		scale = s
+	}
+
+	fw := int(float64(variant.Width)*scale + 0.5)
+	fh := int(float64(variant.Height)*scale + 0.5)
+	if fw < 1 {
+		fw = 1
+	}
+	if fh < 1 {
+		fh = 1
+	}
+	return fw, fh
+}
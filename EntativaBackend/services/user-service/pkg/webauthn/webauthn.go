@@ -0,0 +1,411 @@
+// Package webauthn implements the relying party side of WebAuthn (passkey)
+// registration and authentication ceremonies.
+//
+// Attestation statements are not verified: registrations request "none"
+// conveyance, so the credential key is trusted on first use like a password.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidClientData     = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch     = errors.New("webauthn: challenge mismatch")
+	ErrOriginNotAllowed      = errors.New("webauthn: origin not allowed")
+	ErrRPIDMismatch          = errors.New("webauthn: relying party id mismatch")
+	ErrUserNotPresent        = errors.New("webauthn: user presence not asserted")
+	ErrUserNotVerified       = errors.New("webauthn: user verification required")
+	ErrInvalidAuthData       = errors.New("webauthn: invalid authenticator data")
+	ErrInvalidSignature      = errors.New("webauthn: invalid signature")
+	ErrSignCountRegression   = errors.New("webauthn: signature counter went backwards, authenticator may be cloned")
+	ErrUnsupportedAlgorithm  = errors.New("webauthn: unsupported credential algorithm")
+	ErrMissingAttestedCredID = errors.New("webauthn: attestation has no credential data")
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackupState    = 0x10
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+)
+
+// ChallengeSize is the number of random bytes in a ceremony challenge
+const ChallengeSize = 32
+
+// Config identifies the relying party
+type Config struct {
+	RPID    string   // effective domain, e.g. "entativa.com"
+	RPName  string   // shown by the authenticator
+	Origins []string // allowed origins, e.g. "https://entativa.com"
+}
+
+// URLEncodedBytes is binary data carried as base64url in JSON, as browsers serialize it
+type URLEncodedBytes []byte
+
+// MarshalJSON encodes as unpadded base64url
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON accepts base64url with or without padding
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("invalid base64url value: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// NewChallenge returns a fresh random challenge
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// RelyingParty is the rp member of creation options
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity is the user member of creation options
+type UserEntity struct {
+	ID          URLEncodedBytes `json:"id"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+}
+
+// CredentialParameter is one entry of pubKeyCredParams
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CredentialDescriptor references an existing credential
+type CredentialDescriptor struct {
+	Type       string          `json:"type"`
+	ID         URLEncodedBytes `json:"id"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection expresses authenticator requirements
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create({publicKey})
+type CreationOptions struct {
+	Challenge              URLEncodedBytes        `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get({publicKey})
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// NewCreationOptions builds registration options for a user; existing credentials are excluded
+func (c *Config) NewCreationOptions(challenge, userHandle []byte, userName, displayName string, exclude []CredentialDescriptor, timeoutMillis int) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: "public-key", Alg: alg}
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:               UserEntity{ID: userHandle, Name: userName, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            timeoutMillis,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+}
+
+// NewRequestOptions builds authentication options; an empty allow list asks for a discoverable credential
+func (c *Config) NewRequestOptions(challenge []byte, allow []CredentialDescriptor, userVerification string, timeoutMillis int) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             c.RPID,
+		Timeout:          timeoutMillis,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// AttestationResponse is the JSON form of the PublicKeyCredential returned by create()
+type AttestationResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"`
+		Transports        []string        `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned by get()
+type AssertionResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+		Signature         URLEncodedBytes `json:"signature"`
+		UserHandle        URLEncodedBytes `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// Credential is a verified new credential, ready to be stored
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key
+	Algorithm      int
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	UserVerified   bool
+	BackupEligible bool
+	BackupState    bool
+}
+
+// AssertionResult is the outcome of a verified authentication ceremony
+type AssertionResult struct {
+	SignCount    uint32
+	UserVerified bool
+	BackupState  bool
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// VerifyRegistration checks a create() response against the challenge issued for it
+func (c *Config) VerifyRegistration(challenge []byte, resp *AttestationResponse, requireUserVerification bool) (*Credential, error) {
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: invalid attestation object: %w", err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("webauthn: invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidAuthData
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthenticatorFlags(authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, ErrMissingAttestedCredID
+	}
+
+	_, alg, err := ParsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, err)
+	}
+
+	return &Credential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.publicKey,
+		Algorithm:      alg,
+		SignCount:      authData.signCount,
+		AAGUID:         authData.aaguid,
+		Transports:     resp.Response.Transports,
+		UserVerified:   authData.flags&flagUserVerified != 0,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackupState:    authData.flags&flagBackupState != 0,
+	}, nil
+}
+
+// VerifyAssertion checks a get() response made with a stored credential
+func (c *Config) VerifyAssertion(challenge []byte, resp *AssertionResponse, publicKey []byte, storedSignCount uint32, requireUserVerification bool) (*AssertionResult, error) {
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthenticatorFlags(authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	pub, alg, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, err)
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := make([]byte, 0, len(resp.Response.AuthenticatorData)+len(clientDataHash))
+	signed = append(signed, resp.Response.AuthenticatorData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	if err := verifySignature(pub, alg, signed, resp.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// Authenticators that do not count (synced passkeys) always report zero
+	if authData.signCount != 0 || storedSignCount != 0 {
+		if authData.signCount <= storedSignCount {
+			return nil, ErrSignCountRegression
+		}
+	}
+
+	return &AssertionResult{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		BackupState:  authData.flags&flagBackupState != 0,
+	}, nil
+}
+
+func (c *Config) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidClientData
+	}
+	if data.Type != ceremony {
+		return ErrInvalidClientData
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || len(challenge) == 0 || !bytes.Equal(got, challenge) {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range c.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOriginNotAllowed
+}
+
+func (c *Config) verifyAuthenticatorFlags(authData *authenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// parseAuthenticatorData splits authenticator data (WebAuthn §6.1) into its fields
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthData
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, ErrInvalidAuthData
+		}
+		authData.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, ErrInvalidAuthData
+		}
+		authData.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, keyLen, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthData
+		}
+		authData.publicKey = rest[:keyLen]
+		rest = rest[keyLen:]
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		_, extLen, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthData
+		}
+		rest = rest[extLen:]
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrInvalidAuthData
+	}
+
+	return authData, nil
+}
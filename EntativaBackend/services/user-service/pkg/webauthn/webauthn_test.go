@@ -0,0 +1,391 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+var testConfig = &Config{
+	RPID:    "entativa.com",
+	RPName:  "Entativa",
+	Origins: []string{"https://entativa.com"},
+}
+
+// softAuthenticator is an in-memory authenticator holding a single credential
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	credentialID []byte
+	signer       crypto.Signer
+	signCount    uint32
+	flags        byte
+}
+
+func newSoftAuthenticator(t *testing.T, alg int) *softAuthenticator {
+	t.Helper()
+
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported algorithm %d", alg)
+	}
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &softAuthenticator{
+		rpID:         testConfig.RPID,
+		origin:       testConfig.Origins[0],
+		credentialID: credentialID,
+		signer:       signer,
+		flags:        flagUserPresent | flagUserVerified,
+	}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	switch pub := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		return encodeCBOR(map[int64]interface{}{
+			coseKeyType:   int64(coseKeyTypeEC2),
+			coseAlgorithm: int64(AlgES256),
+			coseCurve:     int64(coseCurveP256),
+			coseX:         x,
+			coseY:         y,
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[int64]interface{}{
+			coseKeyType:   int64(coseKeyTypeOKP),
+			coseAlgorithm: int64(AlgEdDSA),
+			coseCurve:     int64(coseCurveEd25519),
+			coseX:         []byte(pub),
+		})
+	}
+	return nil
+}
+
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedData
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...) // zero AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) create(challenge []byte) *AttestationResponse {
+	resp := &AttestationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = a.clientData("webauthn.create", challenge)
+	resp.Response.AttestationObject = encodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(true),
+	})
+	return resp
+}
+
+func (a *softAuthenticator) get(t *testing.T, challenge []byte) *AssertionResponse {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authData(false)
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	message := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var signature []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		signature, err = a.signer.Sign(rand.Reader, message, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(message)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+
+	resp := &AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = clientDataJSON
+	resp.Response.AuthenticatorData = authData
+	resp.Response.Signature = signature
+	return resp
+}
+
+// encodeCBOR encodes the handful of types the tests need, with canonical map ordering
+func encodeCBOR(value interface{}) []byte {
+	var buf bytes.Buffer
+	writeCBOR(&buf, value)
+	return buf.Bytes()
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	default:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	}
+}
+
+func writeCBOR(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case int64:
+		if v >= 0 {
+			writeCBORHead(buf, cborUnsigned, uint64(v))
+		} else {
+			writeCBORHead(buf, cborNegative, uint64(-1-v))
+		}
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case map[int64]interface{}:
+		keys := make([]int64, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		writeCBORHead(buf, cborMap, uint64(len(v)))
+		for _, k := range keys {
+			writeCBOR(buf, k)
+			writeCBOR(buf, v[k])
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeCBORHead(buf, cborMap, uint64(len(v)))
+		for _, k := range keys {
+			writeCBOR(buf, k)
+			writeCBOR(buf, v[k])
+		}
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	challenge, _ := NewChallenge()
+
+	tests := []struct {
+		name      string
+		alg       int
+		challenge []byte
+		modify    func(a *softAuthenticator)
+		requireUV bool
+		wantErr   error
+	}{
+		{name: "ES256 credential", alg: AlgES256, challenge: challenge},
+		{name: "EdDSA credential", alg: AlgEdDSA, challenge: challenge},
+		{
+			name:      "challenge from another ceremony",
+			alg:       AlgES256,
+			challenge: []byte("some-other-challenge-value-00000"),
+			wantErr:   ErrChallengeMismatch,
+		},
+		{
+			name:      "foreign origin",
+			alg:       AlgES256,
+			challenge: challenge,
+			modify:    func(a *softAuthenticator) { a.origin = "https://evil.example" },
+			wantErr:   ErrOriginNotAllowed,
+		},
+		{
+			name:      "credential scoped to another relying party",
+			alg:       AlgES256,
+			challenge: challenge,
+			modify:    func(a *softAuthenticator) { a.rpID = "evil.example" },
+			wantErr:   ErrRPIDMismatch,
+		},
+		{
+			name:      "user verification required but missing",
+			alg:       AlgES256,
+			challenge: challenge,
+			modify:    func(a *softAuthenticator) { a.flags = flagUserPresent },
+			requireUV: true,
+			wantErr:   ErrUserNotVerified,
+		},
+		{
+			name:      "user not present",
+			alg:       AlgES256,
+			challenge: challenge,
+			modify:    func(a *softAuthenticator) { a.flags = 0 },
+			wantErr:   ErrUserNotPresent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := newSoftAuthenticator(t, tt.alg)
+			if tt.modify != nil {
+				tt.modify(authenticator)
+			}
+
+			credential, err := testConfig.VerifyRegistration(challenge, authenticator.create(tt.challenge), tt.requireUV)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyRegistration() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() unexpected error: %v", err)
+			}
+			if !bytes.Equal(credential.ID, authenticator.credentialID) {
+				t.Errorf("credential ID = %x, want %x", credential.ID, authenticator.credentialID)
+			}
+			if credential.Algorithm != tt.alg {
+				t.Errorf("algorithm = %d, want %d", credential.Algorithm, tt.alg)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	tests := []struct {
+		name      string
+		alg       int
+		tamper    func(resp *AssertionResponse)
+		stored    func(count uint32) uint32
+		requireUV bool
+		wantErr   error
+	}{
+		{name: "ES256 assertion", alg: AlgES256, requireUV: true},
+		{name: "EdDSA assertion", alg: AlgEdDSA, requireUV: true},
+		{
+			name:    "tampered signature",
+			alg:     AlgES256,
+			tamper:  func(resp *AssertionResponse) { resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered client data",
+			alg:  AlgEdDSA,
+			tamper: func(resp *AssertionResponse) {
+				resp.Response.ClientDataJSON = bytes.Replace(resp.Response.ClientDataJSON, []byte(`"origin"`), []byte(`"origin" `), 1)
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "registration response replayed as assertion",
+			alg:  AlgES256,
+			tamper: func(resp *AssertionResponse) {
+				resp.Response.ClientDataJSON = bytes.Replace(resp.Response.ClientDataJSON, []byte("webauthn.get"), []byte("webauthn.create"), 1)
+			},
+			wantErr: ErrInvalidClientData,
+		},
+		{
+			name:    "signature counter did not advance",
+			alg:     AlgES256,
+			stored:  func(count uint32) uint32 { return count },
+			wantErr: ErrSignCountRegression,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := newSoftAuthenticator(t, tt.alg)
+
+			registrationChallenge, _ := NewChallenge()
+			credential, err := testConfig.VerifyRegistration(registrationChallenge, authenticator.create(registrationChallenge), false)
+			if err != nil {
+				t.Fatalf("VerifyRegistration() unexpected error: %v", err)
+			}
+
+			challenge, _ := NewChallenge()
+			resp := authenticator.get(t, challenge)
+			if tt.tamper != nil {
+				tt.tamper(resp)
+			}
+
+			storedCount := credential.SignCount
+			if tt.stored != nil {
+				storedCount = tt.stored(authenticator.signCount)
+			}
+
+			result, err := testConfig.VerifyAssertion(challenge, resp, credential.PublicKey, storedCount, tt.requireUV)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyAssertion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAssertion() unexpected error: %v", err)
+			}
+			if result.SignCount != authenticator.signCount {
+				t.Errorf("sign count = %d, want %d", result.SignCount, authenticator.signCount)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORRejectsTruncatedInput(t *testing.T) {
+	full := encodeCBOR(map[string]interface{}{"authData": make([]byte, 64)})
+
+	for i := 0; i < len(full); i++ {
+		if _, _, err := decodeCBOR(full[:i]); err == nil {
+			t.Fatalf("decodeCBOR() accepted input truncated to %d of %d bytes", i, len(full))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+#!/bin/bash
+
+# Script to generate a new Ed25519 access token signing key
+#
+# Rotation: generate a key, deploy it alongside the current one and wait for
+# verifiers to refresh /.well-known/jwks.json, then set JWT_ACTIVE_KEY_ID to it
+# (or leave it empty to use the newest). Delete the old key file once every
+# token it signed has expired (JWT_ACCESS_TOKEN_EXPIRY).
+
+set -e
+
+KEYS_DIR=${JWT_SIGNING_KEYS_DIR:-./keys}
+KID=${1:-$(date -u +%Y-%m-%d)}
+
+mkdir -p "$KEYS_DIR"
+
+if [ -f "$KEYS_DIR/$KID.pem" ]; then
+    echo "Key $KID already exists in $KEYS_DIR"
+    exit 1
+fi
+
+openssl genpkey -algorithm ed25519 -out "$KEYS_DIR/$KID.pem"
+chmod 600 "$KEYS_DIR/$KID.pem"
+
+echo "Generated signing key $KID in $KEYS_DIR"
@@ -18,6 +18,13 @@ if [ ! -f ".env" ]; then
     echo -e "${YELLOW}⚠️  Please update .env with your actual configuration${NC}"
 fi
 
+# Generate an access token signing key
+if [ -z "$(ls keys/*.pem 2>/dev/null)" ]; then
+    echo -e "${YELLOW}Generating JWT signing key...${NC}"
+    ./scripts/generate-signing-key.sh
+    echo -e "${GREEN}✓ Signing key created in keys/${NC}"
+fi
+
 # Install Go dependencies
 echo -e "${YELLOW}Installing Go dependencies...${NC}"
 go mod download
@@ -12,7 +12,8 @@ object NotificationType extends Enumeration {
   val Like, Comment, Follow, Mention, Share, TakeRemix, TrendJoin, 
       ReplyToStory, ReactionToStory, NewFollower, TaggedInPost, 
       TaggedInTake, CommentReply, QuizAnswer, PollVote, 
-      CountdownReminder, BTTCreated, TemplateUsed = Value
+      CountdownReminder, BTTCreated, TemplateUsed,
+      PublishFailed = Value // a scheduled post or Take of the user's could not be published
 }
 
 /**
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"log"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"vignette/post-service/internal/service"
 	"vignette/post-service/pkg/database"
 	"vignette/post-service/pkg/kafka"
+	"vignette/post-service/pkg/ranking"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -73,17 +75,63 @@ func main() {
 	commentRepo := repository.NewCommentRepository(db)
 	likeRepo := repository.NewLikeRepository(db)
 	saveRepo := repository.NewSaveRepository(db)
+	rankingRepo := repository.NewRankingRepository(db)
+	takesRepo := repository.NewTakesRepository(db)
+	bttRepo := repository.NewBTTRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	trendRepo := repository.NewTrendRepository(db)
+	revisionRepo := repository.NewRevisionRepository(db)
+
+	userServiceURL := getEnv("USER_SERVICE_URL", "http://localhost:8002")
+	userServiceToken := getEnv("USER_SERVICE_INTERNAL_TOKEN", "")
+
+	// Private accounts' posts are only shown to the followers the user service approves
+	privacyClient := service.NewPrivacyClient(userServiceURL, userServiceToken)
+
+	// Home timelines are built from the accounts a user follows
+	graphClient := service.NewGraphClient(userServiceURL, userServiceToken)
+
+	// Authors are told when a scheduled post or Take can't be published
+	notificationClient := service.NewNotificationClient(getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8089"))
+	timelineService := service.NewTimelineService(postRepo, graphClient, redisClient, service.TimelineConfig{
+		CelebrityThreshold: getEnvAsInt("TIMELINE_CELEBRITY_THRESHOLD", 10000),
+		MaxLength:          getEnvAsInt("TIMELINE_MAX_LENGTH", 800),
+		TTL:                getEnvAsDuration("TIMELINE_TTL", 72*time.Hour),
+	})
+
+	// Feeds are ranked by the default signals, reweighted by RANKING_WEIGHTS
+	// (e.g. "recency=1.5,affinity=2")
+	rankingWeights, err := ranking.ParseWeights(getEnv("RANKING_WEIGHTS", ""))
+	if err != nil {
+		log.Fatalf("Invalid RANKING_WEIGHTS: %v", err)
+	}
+	ranker, err := ranking.New(ranking.DefaultSignals()...).WithWeights(rankingWeights)
+	if err != nil {
+		log.Fatalf("Invalid RANKING_WEIGHTS: %v", err)
+	}
+	rankingService := service.NewRankingService(rankingRepo, ranker)
 
 	// Initialize services
-	postService := service.NewPostService(postRepo, likeRepo, commentRepo, saveRepo, redisClient, kafkaProducer)
-	commentService := service.NewCommentService(commentRepo, postRepo, redisClient, kafkaProducer)
-	likeService := service.NewLikeService(likeRepo, postRepo, commentRepo, redisClient, kafkaProducer)
+	postService := service.NewPostService(postRepo, likeRepo, commentRepo, saveRepo, timelineService, rankingService, redisClient, kafkaProducer, privacyClient, notificationClient)
+	commentService := service.NewCommentService(commentRepo, postRepo, privacyClient, redisClient, kafkaProducer)
+	likeService := service.NewLikeService(likeRepo, postRepo, commentRepo, privacyClient, redisClient, kafkaProducer)
+	revisionService := service.NewRevisionService(revisionRepo, postRepo, commentRepo, privacyClient)
+	takesService := service.NewTakesService(takesRepo, bttRepo, templateRepo, trendRepo, redisClient, kafkaProducer, notificationClient)
+
+	// Publish scheduled posts and Takes as they fall due
+	scheduleService := service.NewScheduleService(postService, takesService, getEnvAsDuration("SCHEDULER_INTERVAL", 30*time.Second))
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	go scheduleService.RunWorker(workerCtx)
+
+	log.Println("✓ Post scheduler started")
 
 	// Initialize handlers
 	postHandler := handler.NewPostHandler(postService)
 	commentHandler := handler.NewCommentHandler(commentService)
 	likeHandler := handler.NewLikeHandler(likeService)
 	saveHandler := handler.NewSaveHandler(postService)
+	draftHandler := handler.NewDraftHandler(postService, takesService)
+	revisionHandler := handler.NewRevisionHandler(revisionService)
 
 	// Setup Gin router
 	if getEnv("GIN_MODE", "debug") == "release" {
@@ -112,40 +160,75 @@ func main() {
 		{
 			posts.POST("", authMiddleware(), postHandler.CreatePost)
 			posts.GET("/feed", authMiddleware(), postHandler.GetFeed)
-			posts.GET("/explore", postHandler.GetExplorePosts)
+			posts.GET("/explore", optionalAuthMiddleware(), postHandler.GetExplorePosts)
 			posts.GET("/reels", postHandler.GetReels)
 			posts.GET("/hashtag/:hashtag", postHandler.GetPostsByHashtag)
-			posts.GET("/:post_id", postHandler.GetPost)
+			posts.GET("/:post_id", optionalAuthMiddleware(), postHandler.GetPost)
 			posts.PUT("/:post_id", authMiddleware(), postHandler.UpdatePost)
 			posts.DELETE("/:post_id", authMiddleware(), postHandler.DeletePost)
-			posts.GET("/user/:user_id", postHandler.GetUserPosts)
+			posts.GET("/:post_id/history", optionalAuthMiddleware(), revisionHandler.GetPostHistory)
+			posts.GET("/user/:user_id", optionalAuthMiddleware(), postHandler.GetUserPosts)
 
 			// Comment routes (nested)
 			posts.POST("/:post_id/comments", authMiddleware(), commentHandler.CreateComment)
-			posts.GET("/:post_id/comments", commentHandler.GetComments)
+			posts.GET("/:post_id/comments", optionalAuthMiddleware(), commentHandler.GetComments)
 
 			// Like routes (nested)
 			posts.POST("/:post_id/like", authMiddleware(), likeHandler.LikePost)
 			posts.DELETE("/:post_id/like", authMiddleware(), likeHandler.UnlikePost)
-			posts.GET("/:post_id/likes", likeHandler.GetPostLikers)
+			posts.GET("/:post_id/likes", optionalAuthMiddleware(), likeHandler.GetPostLikers)
 
 			// Save routes (nested)
 			posts.POST("/:post_id/save", authMiddleware(), saveHandler.SavePost)
 			posts.DELETE("/:post_id/save", authMiddleware(), saveHandler.UnsavePost)
+
+			// Hide routes (nested)
+			posts.POST("/:post_id/hide", authMiddleware(), postHandler.HidePost)
+			posts.DELETE("/:post_id/hide", authMiddleware(), postHandler.UnhidePost)
 		}
 
 		// Comment routes (standalone)
 		comments := v1.Group("/comments")
 		{
-			comments.GET("/:comment_id/replies", commentHandler.GetReplies)
+			comments.GET("/:comment_id/replies", optionalAuthMiddleware(), commentHandler.GetReplies)
 			comments.PUT("/:comment_id", authMiddleware(), commentHandler.UpdateComment)
 			comments.DELETE("/:comment_id", authMiddleware(), commentHandler.DeleteComment)
+			comments.GET("/:comment_id/history", optionalAuthMiddleware(), revisionHandler.GetCommentHistory)
 			comments.POST("/:comment_id/like", authMiddleware(), likeHandler.LikeComment)
 			comments.DELETE("/:comment_id/like", authMiddleware(), likeHandler.UnlikeComment)
 		}
 
 		// Saved posts
 		v1.GET("/saved", authMiddleware(), saveHandler.GetSavedPosts)
+
+		// Drafts and scheduled posts and Takes
+		drafts := v1.Group("/drafts", authMiddleware())
+		{
+			drafts.POST("/posts", draftHandler.CreatePostDraft)
+			drafts.GET("/posts", draftHandler.GetPostDrafts)
+			drafts.GET("/posts/:post_id", draftHandler.GetPostDraft)
+			drafts.PUT("/posts/:post_id", draftHandler.UpdatePostDraft)
+			drafts.DELETE("/posts/:post_id", draftHandler.DeletePostDraft)
+			drafts.PUT("/posts/:post_id/schedule", draftHandler.SchedulePost)
+			drafts.DELETE("/posts/:post_id/schedule", draftHandler.CancelPostSchedule)
+			drafts.POST("/posts/:post_id/publish", draftHandler.PublishPostDraft)
+
+			drafts.POST("/takes", draftHandler.CreateTakeDraft)
+			drafts.GET("/takes", draftHandler.GetTakeDrafts)
+			drafts.GET("/takes/:take_id", draftHandler.GetTakeDraft)
+			drafts.PUT("/takes/:take_id", draftHandler.UpdateTakeDraft)
+			drafts.DELETE("/takes/:take_id", draftHandler.DeleteTakeDraft)
+			drafts.PUT("/takes/:take_id/schedule", draftHandler.ScheduleTake)
+			drafts.DELETE("/takes/:take_id/schedule", draftHandler.CancelTakeSchedule)
+			drafts.POST("/takes/:take_id/publish", draftHandler.PublishTakeDraft)
+		}
+
+		// Service-to-service routes
+		internal := v1.Group("/internal", internalMiddleware(getEnv("INTERNAL_API_TOKEN", "")))
+		{
+			internal.GET("/posts/:post_id/history", revisionHandler.GetPostHistoryForModeration)
+			internal.GET("/comments/:comment_id/history", revisionHandler.GetCommentHistoryForModeration)
+		}
 	}
 
 	// Start server
@@ -172,6 +255,8 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -211,6 +296,37 @@ func authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// optionalAuthMiddleware identifies the viewer when they are signed in, so posts
+// from private accounts they follow can be shown; signed-out requests go through
+func optionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			if userID := c.GetHeader("X-User-ID"); userID != "" {
+				c.Set("user_id", userID)
+			}
+		}
+		c.Next()
+	}
+}
+
+// internalMiddleware only admits other services presenting the shared internal token.
+// With no token configured the internal routes are closed.
+func internalMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Internal endpoint",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -242,3 +358,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if valueStr := os.Getenv(key); valueStr != "" {
+		if value, err := time.ParseDuration(valueStr); err == nil {
+			return value
+		}
+	}
+	return defaultValue
+}
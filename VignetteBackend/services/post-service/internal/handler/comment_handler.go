@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -74,7 +75,12 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 
 	comment, err := h.commentService.CreateComment(c.Request.Context(), postID, userUUID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to create comment",
 			"message": err.Error(),
 		})
@@ -108,6 +114,12 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
+	// Get requesting user ID (optional)
+	var requestingUserID uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		requestingUserID, _ = uuid.Parse(userID.(string))
+	}
+
 	limit := 50
 	offset := 0
 	if l := c.Query("limit"); l != "" {
@@ -117,9 +129,14 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		fmt.Sscanf(o, "%d", &offset)
 	}
 
-	comments, err := h.commentService.GetComments(c.Request.Context(), postID, limit, offset)
+	comments, err := h.commentService.GetComments(c.Request.Context(), postID, requestingUserID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to get comments",
 			"message": err.Error(),
 		})
@@ -154,6 +171,12 @@ func (h *CommentHandler) GetReplies(c *gin.Context) {
 		return
 	}
 
+	// Get requesting user ID (optional)
+	var requestingUserID uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		requestingUserID, _ = uuid.Parse(userID.(string))
+	}
+
 	limit := 20
 	offset := 0
 	if l := c.Query("limit"); l != "" {
@@ -163,9 +186,14 @@ func (h *CommentHandler) GetReplies(c *gin.Context) {
 		fmt.Sscanf(o, "%d", &offset)
 	}
 
-	replies, err := h.commentService.GetReplies(c.Request.Context(), commentID, limit, offset)
+	replies, err := h.commentService.GetReplies(c.Request.Context(), commentID, requestingUserID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
 			"error":   "Failed to get replies",
 			"message": err.Error(),
 		})
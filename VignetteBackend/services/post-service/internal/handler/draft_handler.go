@@ -0,0 +1,641 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"vignette/post-service/internal/model"
+	"vignette/post-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DraftHandler serves the user's own drafts and scheduled posts and Takes
+type DraftHandler struct {
+	postService  *service.PostService
+	takesService *service.TakesService
+}
+
+func NewDraftHandler(postService *service.PostService, takesService *service.TakesService) *DraftHandler {
+	return &DraftHandler{
+		postService:  postService,
+		takesService: takesService,
+	}
+}
+
+// CreatePostDraft saves a post without publishing it
+// @Summary Create post draft
+// @Description Save a draft post, scheduled for publishing if scheduled_at is set
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param post body model.CreateDraftRequest true "Draft data"
+// @Success 201 {object} model.Post
+// @Failure 400 {object} map[string]interface{}
+// @Router /drafts/posts [post]
+func (h *DraftHandler) CreatePostDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	post, err := h.postService.CreateDraft(c.Request.Context(), userID, &req)
+	if err != nil {
+		draftError(c, "Failed to create draft", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// GetPostDrafts lists the user's unpublished posts
+// @Summary List post drafts
+// @Description Get the user's drafts, scheduled posts and posts that failed to publish
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {array} model.Post
+// @Router /drafts/posts [get]
+func (h *DraftHandler) GetPostDrafts(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	limit, offset := pagination(c)
+
+	posts, err := h.postService.GetDrafts(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		draftError(c, "Failed to get drafts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    posts,
+	})
+}
+
+// GetPostDraft retrieves one unpublished post
+// @Summary Get post draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} model.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id} [get]
+func (h *DraftHandler) GetPostDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	post, err := h.postService.GetDraft(c.Request.Context(), postID, userID)
+	if err != nil {
+		draftError(c, "Failed to get draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// UpdatePostDraft edits an unpublished post
+// @Summary Update post draft
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Param post body model.UpdatePostRequest true "Update data"
+// @Success 200 {object} model.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id} [put]
+func (h *DraftHandler) UpdatePostDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	var req model.UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	post, err := h.postService.UpdateDraft(c.Request.Context(), postID, userID, &req)
+	if err != nil {
+		draftError(c, "Failed to update draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// DeletePostDraft deletes an unpublished post
+// @Summary Delete post draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id} [delete]
+func (h *DraftHandler) DeletePostDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	if err := h.postService.DeleteDraft(c.Request.Context(), postID, userID); err != nil {
+		draftError(c, "Failed to delete draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Draft deleted successfully",
+	})
+}
+
+// SchedulePost schedules a draft post, or moves a scheduled one
+// @Summary Schedule post
+// @Description Schedule a draft for publishing, reschedule it, or retry one that failed
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Param request body model.ScheduleRequest true "Schedule"
+// @Success 200 {object} model.Post
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id}/schedule [put]
+func (h *DraftHandler) SchedulePost(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	var req model.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	post, err := h.postService.SchedulePost(c.Request.Context(), postID, userID, req.ScheduledAt)
+	if err != nil {
+		draftError(c, "Failed to schedule post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// CancelPostSchedule turns a scheduled post back into a draft
+// @Summary Cancel scheduled post
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} model.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id}/schedule [delete]
+func (h *DraftHandler) CancelPostSchedule(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	post, err := h.postService.CancelSchedule(c.Request.Context(), postID, userID)
+	if err != nil {
+		draftError(c, "Failed to cancel schedule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// PublishPostDraft publishes an unpublished post now
+// @Summary Publish post draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Success 200 {object} model.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/posts/{post_id}/publish [post]
+func (h *DraftHandler) PublishPostDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	post, err := h.postService.PublishDraft(c.Request.Context(), postID, userID)
+	if err != nil {
+		draftError(c, "Failed to publish draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    post,
+	})
+}
+
+// CreateTakeDraft saves a Take without publishing it
+// @Summary Create Take draft
+// @Description Save a draft Take, scheduled for publishing if scheduled_at is set
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param take body model.CreateTakeDraftRequest true "Draft data"
+// @Success 201 {object} model.Take
+// @Failure 400 {object} map[string]interface{}
+// @Router /drafts/takes [post]
+func (h *DraftHandler) CreateTakeDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateTakeDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	take, err := h.takesService.CreateDraft(c.Request.Context(), userID, &req)
+	if err != nil {
+		draftError(c, "Failed to create draft", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// GetTakeDrafts lists the user's unpublished Takes
+// @Summary List Take drafts
+// @Description Get the user's drafts, scheduled Takes and Takes that failed to publish
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {array} model.Take
+// @Router /drafts/takes [get]
+func (h *DraftHandler) GetTakeDrafts(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	limit, offset := pagination(c)
+
+	takes, err := h.takesService.GetDrafts(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		draftError(c, "Failed to get drafts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    takes,
+	})
+}
+
+// GetTakeDraft retrieves one unpublished Take
+// @Summary Get Take draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Success 200 {object} model.Take
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id} [get]
+func (h *DraftHandler) GetTakeDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	take, err := h.takesService.GetDraft(c.Request.Context(), takeID, userID)
+	if err != nil {
+		draftError(c, "Failed to get draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// UpdateTakeDraft edits an unpublished Take
+// @Summary Update Take draft
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Param take body model.UpdateTakeDraftRequest true "Update data"
+// @Success 200 {object} model.Take
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id} [put]
+func (h *DraftHandler) UpdateTakeDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateTakeDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	take, err := h.takesService.UpdateDraft(c.Request.Context(), takeID, userID, &req)
+	if err != nil {
+		draftError(c, "Failed to update draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// DeleteTakeDraft deletes an unpublished Take
+// @Summary Delete Take draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id} [delete]
+func (h *DraftHandler) DeleteTakeDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	if err := h.takesService.DeleteDraft(c.Request.Context(), takeID, userID); err != nil {
+		draftError(c, "Failed to delete draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Draft deleted successfully",
+	})
+}
+
+// ScheduleTake schedules a draft Take, or moves a scheduled one
+// @Summary Schedule Take
+// @Description Schedule a draft for publishing, reschedule it, or retry one that failed
+// @Tags drafts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Param request body model.ScheduleRequest true "Schedule"
+// @Success 200 {object} model.Take
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id}/schedule [put]
+func (h *DraftHandler) ScheduleTake(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	var req model.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	take, err := h.takesService.ScheduleTake(c.Request.Context(), takeID, userID, req.ScheduledAt)
+	if err != nil {
+		draftError(c, "Failed to schedule Take", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// CancelTakeSchedule turns a scheduled Take back into a draft
+// @Summary Cancel scheduled Take
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Success 200 {object} model.Take
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id}/schedule [delete]
+func (h *DraftHandler) CancelTakeSchedule(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	take, err := h.takesService.CancelSchedule(c.Request.Context(), takeID, userID)
+	if err != nil {
+		draftError(c, "Failed to cancel schedule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// PublishTakeDraft publishes an unpublished Take now
+// @Summary Publish Take draft
+// @Tags drafts
+// @Security BearerAuth
+// @Produce json
+// @Param take_id path string true "Take ID"
+// @Success 200 {object} model.Take
+// @Failure 404 {object} map[string]interface{}
+// @Router /drafts/takes/{take_id}/publish [post]
+func (h *DraftHandler) PublishTakeDraft(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	takeID, ok := pathID(c, "take_id")
+	if !ok {
+		return
+	}
+
+	take, err := h.takesService.PublishDraft(c.Request.Context(), takeID, userID)
+	if err != nil {
+		draftError(c, "Failed to publish draft", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    take,
+	})
+}
+
+// currentUser returns the signed-in user, responding 401 if there isn't one
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return uuid.Nil, false
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid user ID",
+			"message": "The user ID is not valid",
+		})
+		return uuid.Nil, false
+	}
+
+	return userUUID, true
+}
+
+// pathID parses an ID path parameter, responding 400 if it isn't one
+func pathID(c *gin.Context, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid ID",
+			"message": fmt.Sprintf("The provided %s is not valid", param),
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func pagination(c *gin.Context) (int, int) {
+	limit := 20
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if o := c.Query("offset"); o != "" {
+		fmt.Sscanf(o, "%d", &offset)
+	}
+	return limit, offset
+}
+
+func draftError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, model.ErrDraftNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, model.ErrInvalidSchedule), errors.Is(err, model.ErrInvalidAudience):
+		statusCode = http.StatusBadRequest
+	}
+
+	c.JSON(statusCode, gin.H{
+		"error":   message,
+		"message": err.Error(),
+	})
+}
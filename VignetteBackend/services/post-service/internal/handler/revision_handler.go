@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"vignette/post-service/internal/model"
+	"vignette/post-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RevisionHandler struct {
+	revisionService *service.RevisionService
+}
+
+func NewRevisionHandler(revisionService *service.RevisionService) *RevisionHandler {
+	return &RevisionHandler{
+		revisionService: revisionService,
+	}
+}
+
+// GetPostHistory returns every version of a post's caption
+// @Summary See post edit history
+// @Description Every version of a post's caption, oldest first, with when it was written and replaced
+// @Tags posts
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Param diff query bool false "Include what each edit changed"
+// @Success 200 {object} model.EditHistory
+// @Failure 404 {object} map[string]interface{}
+// @Router /posts/{post_id}/history [get]
+func (h *RevisionHandler) GetPostHistory(c *gin.Context) {
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	history, err := h.revisionService.GetPostHistory(c.Request.Context(), postID, viewer(c), c.Query("diff") == "true")
+	respondHistory(c, history, err)
+}
+
+// GetCommentHistory returns every version of a comment
+// @Summary See comment edit history
+// @Description Every version of a comment, oldest first, with when it was written and replaced
+// @Tags comments
+// @Produce json
+// @Param comment_id path string true "Comment ID"
+// @Param diff query bool false "Include what each edit changed"
+// @Success 200 {object} model.EditHistory
+// @Failure 404 {object} map[string]interface{}
+// @Router /comments/{comment_id}/history [get]
+func (h *RevisionHandler) GetCommentHistory(c *gin.Context) {
+	commentID, ok := pathID(c, "comment_id")
+	if !ok {
+		return
+	}
+
+	history, err := h.revisionService.GetCommentHistory(c.Request.Context(), commentID, viewer(c), c.Query("diff") == "true")
+	respondHistory(c, history, err)
+}
+
+// GetPostHistoryForModeration returns every version of any post's caption, deleted
+// posts included, for moderation tools. Internal only.
+// @Summary Post edit history for moderation
+// @Tags internal
+// @Produce json
+// @Param post_id path string true "Post ID"
+// @Param diff query bool false "Include what each edit changed"
+// @Success 200 {object} model.EditHistory
+// @Failure 404 {object} map[string]interface{}
+// @Router /internal/posts/{post_id}/history [get]
+func (h *RevisionHandler) GetPostHistoryForModeration(c *gin.Context) {
+	postID, ok := pathID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	history, err := h.revisionService.GetPostHistoryForModeration(c.Request.Context(), postID, c.Query("diff") == "true")
+	respondHistory(c, history, err)
+}
+
+// GetCommentHistoryForModeration returns every version of any comment, deleted
+// comments included, for moderation tools. Internal only.
+// @Summary Comment edit history for moderation
+// @Tags internal
+// @Produce json
+// @Param comment_id path string true "Comment ID"
+// @Param diff query bool false "Include what each edit changed"
+// @Success 200 {object} model.EditHistory
+// @Failure 404 {object} map[string]interface{}
+// @Router /internal/comments/{comment_id}/history [get]
+func (h *RevisionHandler) GetCommentHistoryForModeration(c *gin.Context) {
+	commentID, ok := pathID(c, "comment_id")
+	if !ok {
+		return
+	}
+
+	history, err := h.revisionService.GetCommentHistoryForModeration(c.Request.Context(), commentID, c.Query("diff") == "true")
+	respondHistory(c, history, err)
+}
+
+// viewer returns the signed-in user, or uuid.Nil for signed-out requests
+func viewer(c *gin.Context) uuid.UUID {
+	var viewerID uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		viewerID, _ = uuid.Parse(userID.(string))
+	}
+	return viewerID
+}
+
+// respondHistory writes a history, or 404 for posts and comments the caller can't see
+func respondHistory(c *gin.Context, history *model.EditHistory, err error) {
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, model.ErrHistoryNotFound) || errors.Is(err, service.ErrPostNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error":   "Failed to get edit history",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history,
+	})
+}
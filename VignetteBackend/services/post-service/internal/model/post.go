@@ -3,6 +3,10 @@ package model
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +39,60 @@ type Post struct {
 	IsReels       bool           `json:"is_reels" db:"is_reels"`
 	CommentsEnabled bool         `json:"comments_enabled" db:"comments_enabled"`
 	LikesVisible  bool           `json:"likes_visible" db:"likes_visible"`
+
+	// Audience is who may see the post and interact with it: one of the
+	// Audience constants, or "list:<list id>" for one of the author's lists
+	Audience string `json:"audience" db:"audience"`
+
+	// Status is one of the Status constants; only published posts are shown to
+	// anyone but the author. ScheduledAt is when a scheduled post is due, and
+	// PublishError why it could not be published if it failed.
+	Status       string     `json:"status" db:"status"`
+	ScheduledAt  *time.Time `json:"scheduled_at,omitempty" db:"scheduled_at"`
+	PublishError *string    `json:"publish_error,omitempty" db:"publish_error"`
+
+	// FannedOut is set when the post was pushed into followers' home timelines
+	// when it was created; otherwise timelines pull it in as they are read
+	FannedOut bool `json:"-" db:"fanned_out"`
+}
+
+// Audiences a post can be shared with. A private account's public posts are
+// still only seen by its approved followers.
+const (
+	AudiencePublic    = "public"
+	AudienceFollowers = "followers"
+	AudienceOnlyMe    = "only_me"
+
+	// AudienceListPrefix marks an audience that is one of the author's lists,
+	// such as Close Friends. Only the list's members see the post.
+	AudienceListPrefix = "list:"
+)
+
+// ErrInvalidAudience is returned for an audience that is not one of the above
+var ErrInvalidAudience = errors.New("audience must be public, followers, only_me or list:<list id>")
+
+// AudienceListID returns the list an audience refers to, if it is a list audience
+func AudienceListID(audience string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(audience, AudienceListPrefix) {
+		return uuid.Nil, false
+	}
+	listID, err := uuid.Parse(strings.TrimPrefix(audience, AudienceListPrefix))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return listID, true
+}
+
+// ValidateAudience checks an audience a post is being shared with
+func ValidateAudience(audience string) error {
+	switch audience {
+	case AudiencePublic, AudienceFollowers, AudienceOnlyMe:
+		return nil
+	}
+	if _, ok := AudienceListID(audience); ok {
+		return nil
+	}
+	return ErrInvalidAudience
 }
 
 // Comment represents a comment on a post
@@ -185,6 +243,7 @@ type CreatePostRequest struct {
 	FilterUsed       *string     `json:"filter_used,omitempty"`
 	CommentsEnabled  bool        `json:"comments_enabled" binding:"required"`
 	LikesVisible     bool        `json:"likes_visible" binding:"required"`
+	Audience         string      `json:"audience,omitempty"` // Defaults to public
 }
 
 type UpdatePostRequest struct {
@@ -193,6 +252,7 @@ type UpdatePostRequest struct {
 	Hashtags        *[]string  `json:"hashtags,omitempty"`
 	CommentsEnabled *bool      `json:"comments_enabled,omitempty"`
 	LikesVisible    *bool      `json:"likes_visible,omitempty"`
+	Audience        *string    `json:"audience,omitempty"`
 }
 
 type CreateCommentRequest struct {
@@ -253,3 +313,58 @@ type PostListResponse struct {
 	NextCursor *string        `json:"next_cursor,omitempty"`
 	HasMore    bool           `json:"has_more"`
 }
+
+// ErrInvalidFeedCursor is returned for a cursor that was not handed out by a feed
+var ErrInvalidFeedCursor = errors.New("invalid feed cursor")
+
+// FeedCursor marks a place in a feed: just after the post created at CreatedAt with
+// ID PostID. Feeds run newest first, and posts created at the same moment run in
+// descending ID order, so a cursor always points between the same two posts.
+type FeedCursor struct {
+	CreatedAt time.Time
+	PostID    uuid.UUID
+}
+
+// NewFeedCursor returns the cursor just after post
+func NewFeedCursor(post *Post) *FeedCursor {
+	return &FeedCursor{CreatedAt: post.CreatedAt, PostID: post.ID}
+}
+
+// ParseFeedCursor reads a cursor made by FeedCursor.String. An empty string is the
+// start of the feed and gives nil.
+func ParseFeedCursor(s string) (*FeedCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	micros, id, ok := strings.Cut(s, "_")
+	if !ok {
+		return nil, ErrInvalidFeedCursor
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidFeedCursor
+	}
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidFeedCursor
+	}
+
+	return &FeedCursor{CreatedAt: time.UnixMicro(createdAt), PostID: postID}, nil
+}
+
+func (c *FeedCursor) String() string {
+	return fmt.Sprintf("%d_%s", c.CreatedAt.UnixMicro(), c.PostID)
+}
+
+// Precedes reports whether post comes after the cursor in a feed. A nil cursor, the
+// start of the feed, precedes every post.
+func (c *FeedCursor) Precedes(post *Post) bool {
+	if c == nil {
+		return true
+	}
+	if !post.CreatedAt.Equal(c.CreatedAt) {
+		return post.CreatedAt.Before(c.CreatedAt)
+	}
+	return post.ID.String() < c.PostID.String()
+}
@@ -111,14 +111,33 @@ func (r *commentRepository) GetReplies(ctx context.Context, parentID uuid.UUID,
 	return r.scanComments(rows)
 }
 
+// Update saves an edit to a comment. If the content changes, the content it replaces
+// is kept in comment_revisions in the same transaction, as for posts.
 func (r *commentRepository) Update(ctx context.Context, comment *model.Comment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revision := `
+		INSERT INTO comment_revisions (comment_id, content, replaced_at)
+		SELECT id, content, $3
+		FROM comments
+		WHERE id = $1 AND deleted_at IS NULL AND content <> $2
+		FOR UPDATE
+	`
+	if _, err := tx.ExecContext(ctx, revision, comment.ID, comment.Content, comment.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+
 	query := `
 		UPDATE comments
 		SET content = $1, is_edited = $2, edited_at = $3, updated_at = $4
 		WHERE id = $5 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.ExecContext(
+	result, err := tx.ExecContext(
 		ctx, query,
 		comment.Content, comment.IsEdited, comment.EditedAt, comment.UpdatedAt, comment.ID,
 	)
@@ -136,7 +155,7 @@ func (r *commentRepository) Update(ctx context.Context, comment *model.Comment)
 		return fmt.Errorf("comment not found")
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *commentRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RankingRepository reads what feed ranking needs to know about a viewer, and
+// records the posts they hide
+type RankingRepository interface {
+	HidePost(ctx context.Context, userID, postID, authorID uuid.UUID) error
+	UnhidePost(ctx context.Context, userID, postID uuid.UUID) error
+	HiddenPostIDs(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	AuthorHides(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]int, error)
+	AuthorAffinity(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]float64, error)
+}
+
+type rankingRepository struct {
+	db *sql.DB
+}
+
+func NewRankingRepository(db *sql.DB) RankingRepository {
+	return &rankingRepository{db: db}
+}
+
+func (r *rankingRepository) HidePost(ctx context.Context, userID, postID, authorID uuid.UUID) error {
+	query := `
+		INSERT INTO post_hides (user_id, post_id, author_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, post_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, postID, authorID)
+	return err
+}
+
+func (r *rankingRepository) UnhidePost(ctx context.Context, userID, postID uuid.UUID) error {
+	query := `DELETE FROM post_hides WHERE user_id = $1 AND post_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+// HiddenPostIDs returns which of postIDs the user has hidden
+func (r *rankingRepository) HiddenPostIDs(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := make(map[uuid.UUID]bool)
+	if len(postIDs) == 0 {
+		return hidden, nil
+	}
+
+	query := `SELECT post_id FROM post_hides WHERE user_id = $1 AND post_id = ANY($2::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(idStrings(postIDs)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID uuid.UUID
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		hidden[postID] = true
+	}
+
+	return hidden, rows.Err()
+}
+
+// AuthorHides counts the posts by each of authorIDs the user has hidden since since
+func (r *rankingRepository) AuthorHides(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]int, error) {
+	hides := make(map[uuid.UUID]int)
+	if len(authorIDs) == 0 {
+		return hides, nil
+	}
+
+	query := `
+		SELECT author_id, COUNT(*)
+		FROM post_hides
+		WHERE user_id = $1 AND author_id = ANY($2::uuid[]) AND created_at > $3
+		GROUP BY author_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(idStrings(authorIDs)), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var authorID uuid.UUID
+		var count int
+		if err := rows.Scan(&authorID, &count); err != nil {
+			return nil, err
+		}
+		hides[authorID] = count
+	}
+
+	return hides, rows.Err()
+}
+
+// AuthorAffinity weighs up the user's likes and comments on posts by each of
+// authorIDs since since. A comment counts as two likes.
+func (r *rankingRepository) AuthorAffinity(ctx context.Context, userID uuid.UUID, authorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]float64, error) {
+	affinity := make(map[uuid.UUID]float64)
+	if len(authorIDs) == 0 {
+		return affinity, nil
+	}
+
+	query := `
+		SELECT author_id, SUM(weight)
+		FROM (
+			SELECT p.user_id AS author_id, 1.0 AS weight
+			FROM likes l
+			JOIN posts p ON p.id = l.post_id
+			WHERE l.user_id = $1 AND p.user_id = ANY($2::uuid[]) AND l.created_at > $3
+			UNION ALL
+			SELECT p.user_id AS author_id, 2.0 AS weight
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			WHERE c.user_id = $1 AND p.user_id = ANY($2::uuid[]) AND c.created_at > $3
+			AND c.deleted_at IS NULL
+		) interactions
+		GROUP BY author_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(idStrings(authorIDs)), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var authorID uuid.UUID
+		var weight float64
+		if err := rows.Scan(&authorID, &weight); err != nil {
+			return nil, err
+		}
+		affinity[authorID] = weight
+	}
+
+	return affinity, rows.Err()
+}
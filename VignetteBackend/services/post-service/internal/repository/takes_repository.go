@@ -33,6 +33,14 @@ type TakesRepository interface {
 	DecrementSaves(ctx context.Context, takeID uuid.UUID) error
 	IncrementRemixes(ctx context.Context, takeID uuid.UUID) error
 	GetTrending(ctx context.Context, limit int, timeWindow time.Duration) ([]model.Take, error)
+
+	// Drafts and scheduled Takes. The read methods above only return published Takes.
+	GetDraftByID(ctx context.Context, id uuid.UUID) (*model.Take, error)
+	GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Take, error)
+	SetSchedule(ctx context.Context, id uuid.UUID, status string, scheduledAt *time.Time) error
+	GetDueScheduled(ctx context.Context, limit int) ([]model.Take, error)
+	MarkPublished(ctx context.Context, take *model.Take, publishedAt time.Time) (bool, error)
+	MarkPublishFailed(ctx context.Context, take *model.Take, reason string) (bool, error)
 }
 
 type takesRepository struct {
@@ -49,10 +57,11 @@ func (r *takesRepository) Create(ctx context.Context, take *model.Take) error {
 			id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at
+			remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at,
+			status, scheduled_at, trend_keyword
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
-			$18, $19, $20, $21, $22, $23, $24, $25
+			$18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28
 		)
 		RETURNING created_at, updated_at
 	`
@@ -68,6 +77,7 @@ func (r *takesRepository) Create(ctx context.Context, take *model.Take) error {
 		take.TemplateID, take.TrendID, take.HasBTT, take.ViewsCount, take.LikesCount,
 		take.CommentsCount, take.SharesCount, take.SavesCount, take.RemixCount,
 		take.CommentsEnabled, take.RemixEnabled, take.IsSponsored, take.CreatedAt, take.UpdatedAt,
+		take.Status, take.ScheduledAt, take.TrendKeyword,
 	).Scan(&take.CreatedAt, &take.UpdatedAt)
 }
 
@@ -76,9 +86,10 @@ func (r *takesRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Tak
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND deleted_at IS NULL AND status = 'published'
 	`
 
 	take := &model.Take{}
@@ -91,6 +102,7 @@ func (r *takesRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Tak
 		&take.LikesCount, &take.CommentsCount, &take.SharesCount, &take.SavesCount,
 		&take.RemixCount, &take.CommentsEnabled, &take.RemixEnabled, &take.IsSponsored,
 		&take.CreatedAt, &take.UpdatedAt, &take.DeletedAt,
+		&take.Status, &take.ScheduledAt, &take.PublishError, &take.TrendKeyword,
 	)
 
 	if err != nil {
@@ -122,9 +134,10 @@ func (r *takesRepository) GetByUserID(ctx context.Context, userID uuid.UUID, lim
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE user_id = $1 AND deleted_at IS NULL
+		WHERE user_id = $1 AND deleted_at IS NULL AND status = 'published'
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -143,9 +156,10 @@ func (r *takesRepository) GetFeed(ctx context.Context, userID uuid.UUID, cursor
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND status = 'published'
 	`
 
 	args := []interface{}{}
@@ -187,9 +201,10 @@ func (r *takesRepository) GetByHashtag(ctx context.Context, hashtag string, limi
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND status = 'published'
 		AND hashtags ? $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
@@ -209,9 +224,10 @@ func (r *takesRepository) GetByTrendID(ctx context.Context, trendID uuid.UUID, l
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE trend_id = $1 AND deleted_at IS NULL
+		WHERE trend_id = $1 AND deleted_at IS NULL AND status = 'published'
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -230,9 +246,10 @@ func (r *takesRepository) GetByTemplateID(ctx context.Context, templateID uuid.U
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE template_id = $1 AND deleted_at IS NULL
+		WHERE template_id = $1 AND deleted_at IS NULL AND status = 'published'
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -250,8 +267,9 @@ func (r *takesRepository) Update(ctx context.Context, take *model.Take) error {
 	query := `
 		UPDATE takes
 		SET caption = $1, hashtags = $2, location = $3, has_btt = $4,
-			comments_enabled = $5, remix_enabled = $6, updated_at = $7
-		WHERE id = $8 AND deleted_at IS NULL
+			comments_enabled = $5, remix_enabled = $6, updated_at = $7, trend_keyword = $8,
+			trend_id = $9
+		WHERE id = $10 AND deleted_at IS NULL
 	`
 
 	hashtagsJSON, _ := json.Marshal(take.Hashtags)
@@ -260,7 +278,7 @@ func (r *takesRepository) Update(ctx context.Context, take *model.Take) error {
 	result, err := r.db.ExecContext(
 		ctx, query,
 		take.Caption, hashtagsJSON, locationJSON, take.HasBTT,
-		take.CommentsEnabled, take.RemixEnabled, take.UpdatedAt, take.ID,
+		take.CommentsEnabled, take.RemixEnabled, take.UpdatedAt, take.TrendKeyword, take.TrendID, take.ID,
 	)
 
 	if err != nil {
@@ -351,9 +369,10 @@ func (r *takesRepository) GetTrending(ctx context.Context, limit int, timeWindow
 		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
 			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
 			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
-			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
 		FROM takes
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND status = 'published'
 		AND created_at > $1
 		ORDER BY (
 			views_count / 100 + 
@@ -376,6 +395,150 @@ func (r *takesRepository) GetTrending(ctx context.Context, limit int, timeWindow
 	return r.scanTakes(rows)
 }
 
+// GetDraftByID returns a draft, scheduled or failed Take
+func (r *takesRepository) GetDraftByID(ctx context.Context, id uuid.UUID) (*model.Take, error) {
+	query := `
+		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
+			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
+			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
+		FROM takes
+		WHERE id = $1 AND deleted_at IS NULL AND status <> 'published'
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	takes, err := r.scanTakes(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(takes) == 0 {
+		return nil, model.ErrDraftNotFound
+	}
+	return &takes[0], nil
+}
+
+// GetDrafts returns the user's unpublished Takes, soonest scheduled first and then
+// the most recently edited drafts
+func (r *takesRepository) GetDrafts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.Take, error) {
+	query := `
+		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
+			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
+			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
+		FROM takes
+		WHERE user_id = $1 AND deleted_at IS NULL AND status <> 'published'
+		ORDER BY scheduled_at ASC NULLS LAST, updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTakes(rows)
+}
+
+// SetSchedule moves an unpublished Take to status, due at scheduledAt (nil for a
+// draft), clearing any earlier publish error
+func (r *takesRepository) SetSchedule(ctx context.Context, id uuid.UUID, status string, scheduledAt *time.Time) error {
+	query := `
+		UPDATE takes
+		SET status = $1, scheduled_at = $2, publish_error = NULL, updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL AND status <> 'published'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, status, scheduledAt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return model.ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// GetDueScheduled returns scheduled Takes whose time has come, longest overdue first
+func (r *takesRepository) GetDueScheduled(ctx context.Context, limit int) ([]model.Take, error) {
+	query := `
+		SELECT id, user_id, caption, media_id, audio_track_id, duration, thumbnail_url,
+			   hashtags, filter_used, location, tagged_user_ids, template_id, trend_id,
+			   has_btt, views_count, likes_count, comments_count, shares_count, saves_count,
+			   remix_count, comments_enabled, remix_enabled, is_sponsored, created_at, updated_at, deleted_at,
+			   status, scheduled_at, publish_error, trend_keyword
+		FROM takes
+		WHERE deleted_at IS NULL AND status = 'scheduled' AND scheduled_at <= NOW()
+		ORDER BY scheduled_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTakes(rows)
+}
+
+// MarkPublished publishes take as of publishedAt. Like PostRepository.MarkPublished
+// it only does so if the Take still has the status and scheduled time it was read
+// with, so only one caller gets true.
+func (r *takesRepository) MarkPublished(ctx context.Context, take *model.Take, publishedAt time.Time) (bool, error) {
+	query := `
+		UPDATE takes
+		SET status = 'published', publish_error = NULL, created_at = $1, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+		AND status = $3 AND scheduled_at IS NOT DISTINCT FROM $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, publishedAt, take.ID, take.Status, take.ScheduledAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// MarkPublishFailed records why a scheduled Take could not be published, on the
+// same terms as MarkPublished
+func (r *takesRepository) MarkPublishFailed(ctx context.Context, take *model.Take, reason string) (bool, error) {
+	query := `
+		UPDATE takes
+		SET status = 'failed', publish_error = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+		AND status = 'scheduled' AND scheduled_at IS NOT DISTINCT FROM $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, reason, take.ID, take.ScheduledAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
 func (r *takesRepository) scanTakes(rows *sql.Rows) ([]model.Take, error) {
 	var takes []model.Take
 
@@ -390,6 +553,7 @@ func (r *takesRepository) scanTakes(rows *sql.Rows) ([]model.Take, error) {
 			&take.LikesCount, &take.CommentsCount, &take.SharesCount, &take.SavesCount,
 			&take.RemixCount, &take.CommentsEnabled, &take.RemixEnabled, &take.IsSponsored,
 			&take.CreatedAt, &take.UpdatedAt, &take.DeletedAt,
+			&take.Status, &take.ScheduledAt, &take.PublishError, &take.TrendKeyword,
 		)
 
 		if err != nil {
@@ -17,6 +17,7 @@ import (
 type CommentService struct {
 	commentRepo repository.CommentRepository
 	postRepo    repository.PostRepository
+	privacy     *PrivacyClient
 	redis       *redis.Client
 	kafka       *kafka.Producer
 }
@@ -24,12 +25,14 @@ type CommentService struct {
 func NewCommentService(
 	commentRepo repository.CommentRepository,
 	postRepo repository.PostRepository,
+	privacy *PrivacyClient,
 	redis *redis.Client,
 	kafka *kafka.Producer,
 ) *CommentService {
 	return &CommentService{
 		commentRepo: commentRepo,
 		postRepo:    postRepo,
+		privacy:     privacy,
 		redis:       redis,
 		kafka:       kafka,
 	}
@@ -37,10 +40,13 @@ func NewCommentService(
 
 // CreateComment creates a new comment on a post
 func (s *CommentService) CreateComment(ctx context.Context, postID, userID uuid.UUID, req *model.CreateCommentRequest) (*model.Comment, error) {
-	// Verify post exists
+	// Verify post exists and the user may see it
 	post, err := s.postRepo.GetByID(ctx, postID)
 	if err != nil {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrPostNotFound
+	}
+	if err := s.privacy.CheckCanSeePost(ctx, userID, post); err != nil {
+		return nil, err
 	}
 
 	// If parent comment specified, verify it exists and belongs to same post
@@ -88,8 +94,13 @@ func (s *CommentService) CreateComment(ctx context.Context, postID, userID uuid.
 	return comment, nil
 }
 
-// GetComments retrieves comments for a post
-func (s *CommentService) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+// GetComments retrieves comments for a post, if viewerID may see it. viewerID is
+// uuid.Nil for signed-out viewers.
+func (s *CommentService) GetComments(ctx context.Context, postID, viewerID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+	if err := s.checkCanSeePost(ctx, viewerID, postID); err != nil {
+		return nil, err
+	}
+
 	// Try cache for first page
 	if offset == 0 {
 		if cachedComments, err := s.getCommentsFromCache(ctx, postID, limit); err == nil && len(cachedComments) > 0 {
@@ -111,8 +122,16 @@ func (s *CommentService) GetComments(ctx context.Context, postID uuid.UUID, limi
 	return comments, nil
 }
 
-// GetReplies retrieves replies to a comment
-func (s *CommentService) GetReplies(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+// GetReplies retrieves replies to a comment, if viewerID may see the post it is on
+func (s *CommentService) GetReplies(ctx context.Context, commentID, viewerID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+	comment, err := s.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("comment not found")
+	}
+	if err := s.checkCanSeePost(ctx, viewerID, comment.PostID); err != nil {
+		return nil, err
+	}
+
 	return s.commentRepo.GetReplies(ctx, commentID, limit, offset)
 }
 
@@ -182,6 +201,14 @@ func (s *CommentService) DeleteComment(ctx context.Context, commentID, userID uu
 	return nil
 }
 
+func (s *CommentService) checkCanSeePost(ctx context.Context, viewerID, postID uuid.UUID) error {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return ErrPostNotFound
+	}
+	return s.privacy.CheckCanSeePost(ctx, viewerID, post)
+}
+
 // Cache methods
 func (s *CommentService) cacheComments(ctx context.Context, postID uuid.UUID, comments []model.Comment) {
 	if s.redis == nil {
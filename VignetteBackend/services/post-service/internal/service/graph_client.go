@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GraphClient asks the user service who follows whom, so home timelines can be
+// built from followed accounts' posts
+type GraphClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewGraphClient(userServiceURL, internalToken string) *GraphClient {
+	return &GraphClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Audience returns the users whose home timelines authorID's posts go to: their
+// followers. If there are more than max of them, ok is false and no IDs are returned.
+func (c *GraphClient) Audience(ctx context.Context, authorID uuid.UUID, max int) ([]uuid.UUID, bool, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/followers", c.userServiceURL, authorID)
+	if max > 0 {
+		endpoint += "?" + url.Values{"max": {strconv.Itoa(max)}}.Encode()
+	}
+
+	var followers struct {
+		FollowerIDs []uuid.UUID `json:"follower_ids"`
+		Truncated   bool        `json:"truncated"`
+	}
+	if err := c.get(ctx, endpoint, &followers); err != nil {
+		return nil, false, fmt.Errorf("failed to get followers: %w", err)
+	}
+	if followers.Truncated {
+		return nil, false, nil
+	}
+	return followers.FollowerIDs, true, nil
+}
+
+// Sources returns the users whose posts belong in userID's home timeline: the
+// accounts they follow
+func (c *GraphClient) Sources(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/following", c.userServiceURL, userID)
+
+	var following struct {
+		FollowingIDs []uuid.UUID `json:"following_ids"`
+	}
+	if err := c.get(ctx, endpoint, &following); err != nil {
+		return nil, fmt.Errorf("failed to get following: %w", err)
+	}
+	return following.FollowingIDs, nil
+}
+
+// get fetches endpoint and decodes the data field of the response into data
+func (c *GraphClient) get(ctx context.Context, endpoint string, data interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	return json.NewDecoder(resp.Body).Decode(&body)
+}
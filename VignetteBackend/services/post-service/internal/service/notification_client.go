@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationClient sends notices about an author's own content straight to the
+// notification service
+type NotificationClient struct {
+	notificationServiceURL string
+	httpClient             *http.Client
+}
+
+func NewNotificationClient(notificationServiceURL string) *NotificationClient {
+	return &NotificationClient{
+		notificationServiceURL: notificationServiceURL,
+		httpClient:             &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// PostPublishFailed tells an author their scheduled post could not be published
+func (c *NotificationClient) PostPublishFailed(ctx context.Context, userID, postID uuid.UUID, reason string) {
+	c.publishFailed(ctx, userID, "Your scheduled post wasn't published", reason, "postId", postID)
+}
+
+// TakePublishFailed tells an author their scheduled Take could not be published
+func (c *NotificationClient) TakePublishFailed(ctx context.Context, userID, takeID uuid.UUID, reason string) {
+	c.publishFailed(ctx, userID, "Your scheduled Take wasn't published", reason, "takeId", takeID)
+}
+
+// publishFailed sends a PublishFailed notification. It is best effort: the
+// failure is already recorded on the draft, where the author can see it.
+func (c *NotificationClient) publishFailed(ctx context.Context, userID uuid.UUID, title, reason, idField string, id uuid.UUID) {
+	if c == nil || c.notificationServiceURL == "" {
+		return
+	}
+
+	notification := map[string]interface{}{
+		"userId":           userID.String(),
+		"notificationType": "PublishFailed",
+		"title":            title,
+		"message":          reason,
+		"data":             map[string]string{"reason": reason},
+		"deliveryChannels": []string{"InApp", "Push"},
+		"priority":         "High",
+		idField:            id.String(),
+	}
+
+	if err := c.send(ctx, notification); err != nil {
+		log.Printf("Failed to notify %s of a failed publish: %v", userID, err)
+	}
+}
+
+func (c *NotificationClient) send(ctx context.Context, notification map[string]interface{}) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.notificationServiceURL+"/api/v1/notifications", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notification service returned %d", resp.StatusCode)
+	}
+	return nil
+}
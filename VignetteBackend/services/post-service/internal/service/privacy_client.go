@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"vignette/post-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// PrivacyClient asks the user service whose posts a viewer may not see: private
+// accounts the viewer hasn't been approved to follow, and anyone either side has
+// blocked. It also says whom the viewer follows, for followers-only posts, and
+// which authors' audience lists the viewer is in, for posts shared with a list.
+type PrivacyClient struct {
+	userServiceURL string
+	internalToken  string
+	httpClient     *http.Client
+}
+
+func NewPrivacyClient(userServiceURL, internalToken string) *PrivacyClient {
+	return &PrivacyClient{
+		userServiceURL: userServiceURL,
+		internalToken:  internalToken,
+		httpClient:     &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// AuthorVisibility is how a viewer stands with a set of authors
+type AuthorVisibility struct {
+	// Hidden are the authors none of whose posts the viewer may see
+	Hidden map[uuid.UUID]bool
+
+	// Followed are the authors the viewer follows
+	Followed map[uuid.UUID]bool
+
+	// ListMembers are the authors' audience lists the viewer is in. Only filled
+	// in by VisiblePosts, for the lists its posts are shared with.
+	ListMembers map[audienceList]bool
+}
+
+// audienceList is one author's audience list
+type audienceList struct {
+	ownerID uuid.UUID
+	listID  uuid.UUID
+}
+
+// Visibility looks up how viewerID stands with each of authorIDs. viewerID is
+// uuid.Nil for a signed-out viewer.
+func (c *PrivacyClient) Visibility(ctx context.Context, viewerID uuid.UUID, authorIDs []uuid.UUID) (*AuthorVisibility, error) {
+	visibility := &AuthorVisibility{
+		Hidden:      map[uuid.UUID]bool{},
+		Followed:    map[uuid.UUID]bool{},
+		ListMembers: map[audienceList]bool{},
+	}
+	if len(authorIDs) == 0 {
+		return visibility, nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"viewer_id": viewerID,
+		"owner_ids": authorIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/internal/visibility/hidden-owners", c.userServiceURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			HiddenOwnerIDs   []uuid.UUID `json:"hidden_owner_ids"`
+			FollowedOwnerIDs []uuid.UUID `json:"followed_owner_ids"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode hidden owners: %w", err)
+	}
+
+	for _, id := range body.Data.HiddenOwnerIDs {
+		visibility.Hidden[id] = true
+	}
+	for _, id := range body.Data.FollowedOwnerIDs {
+		visibility.Followed[id] = true
+	}
+	return visibility, nil
+}
+
+// CheckCanSeePost returns ErrPostNotFound unless viewerID may see post
+func (c *PrivacyClient) CheckCanSeePost(ctx context.Context, viewerID uuid.UUID, post *model.Post) error {
+	visible, err := c.VisiblePosts(ctx, viewerID, []model.Post{*post})
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// VisiblePosts drops the posts viewerID may not see: those of hidden authors, and
+// those shared with an audience the viewer isn't in. Authors see all their own
+// posts. If the user service can't say, nothing is shown rather than risk showing
+// a private account's posts.
+func (c *PrivacyClient) VisiblePosts(ctx context.Context, viewerID uuid.UUID, posts []model.Post) ([]model.Post, error) {
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	seen := make(map[uuid.UUID]bool, len(posts))
+	for _, post := range posts {
+		if post.UserID != viewerID && !seen[post.UserID] {
+			seen[post.UserID] = true
+			authorIDs = append(authorIDs, post.UserID)
+		}
+	}
+
+	visibility, err := c.Visibility(ctx, viewerID, authorIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check post visibility: %w", err)
+	}
+	if err := c.listMemberships(ctx, viewerID, posts, visibility); err != nil {
+		return nil, fmt.Errorf("failed to check audience lists: %w", err)
+	}
+
+	visible := make([]model.Post, 0, len(posts))
+	for _, post := range posts {
+		if visibility.canSee(viewerID, &post) {
+			visible = append(visible, post)
+		}
+	}
+	return visible, nil
+}
+
+func (v *AuthorVisibility) canSee(viewerID uuid.UUID, post *model.Post) bool {
+	if post.UserID == viewerID {
+		return true
+	}
+	if v.Hidden[post.UserID] {
+		return false
+	}
+
+	switch post.Audience {
+	case model.AudiencePublic:
+		return true
+	case model.AudienceFollowers:
+		return v.Followed[post.UserID]
+	case model.AudienceOnlyMe:
+		return false
+	}
+
+	listID, ok := model.AudienceListID(post.Audience)
+	return ok && v.ListMembers[audienceList{ownerID: post.UserID, listID: listID}]
+}
+
+// listMemberships asks the user service which of the audience lists posts are
+// shared with viewerID is in, recording them in visibility. Lists of hidden
+// authors aren't asked about, and signed-out viewers are in no lists.
+func (c *PrivacyClient) listMemberships(ctx context.Context, viewerID uuid.UUID, posts []model.Post, visibility *AuthorVisibility) error {
+	type check struct {
+		OwnerID  uuid.UUID `json:"owner_id"`
+		ListID   uuid.UUID `json:"list_id"`
+		ViewerID uuid.UUID `json:"viewer_id"`
+	}
+	if viewerID == uuid.Nil {
+		return nil
+	}
+
+	seen := make(map[audienceList]bool)
+	checks := []check{}
+	for _, post := range posts {
+		listID, ok := model.AudienceListID(post.Audience)
+		if !ok || post.UserID == viewerID || visibility.Hidden[post.UserID] {
+			continue
+		}
+		list := audienceList{ownerID: post.UserID, listID: listID}
+		if !seen[list] {
+			seen[list] = true
+			checks = append(checks, check{OwnerID: post.UserID, ListID: listID, ViewerID: viewerID})
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"checks": checks})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/internal/audience-lists/check", c.userServiceURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Results []struct {
+				check
+				IsMember bool `json:"is_member"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode list memberships: %w", err)
+	}
+
+	for _, result := range body.Data.Results {
+		if result.IsMember {
+			visibility.ListMembers[audienceList{ownerID: result.OwnerID, listID: result.ListID}] = true
+		}
+	}
+	return nil
+}
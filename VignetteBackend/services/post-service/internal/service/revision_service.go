@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"vignette/post-service/internal/model"
+	"vignette/post-service/internal/repository"
+	"vignette/post-service/pkg/textdiff"
+
+	"github.com/google/uuid"
+)
+
+// RevisionService shows the edit history of posts and comments
+type RevisionService struct {
+	revisionRepo repository.RevisionRepository
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+	privacy      *PrivacyClient
+}
+
+func NewRevisionService(
+	revisionRepo repository.RevisionRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	privacy *PrivacyClient,
+) *RevisionService {
+	return &RevisionService{
+		revisionRepo: revisionRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		privacy:      privacy,
+	}
+}
+
+// GetPostHistory returns the edit history of a post viewerID may see, with what
+// each edit changed if withDiff is set
+func (s *RevisionService) GetPostHistory(ctx context.Context, postID, viewerID uuid.UUID, withDiff bool) (*model.EditHistory, error) {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	if err := s.privacy.CheckCanSeePost(ctx, viewerID, post); err != nil {
+		return nil, err
+	}
+
+	history, err := s.revisionRepo.GetPostHistory(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return addDiffs(history, withDiff), nil
+}
+
+// GetCommentHistory returns the edit history of a comment on a post viewerID may
+// see, with what each edit changed if withDiff is set
+func (s *RevisionService) GetCommentHistory(ctx context.Context, commentID, viewerID uuid.UUID, withDiff bool) (*model.EditHistory, error) {
+	comment, err := s.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, model.ErrHistoryNotFound
+	}
+	post, err := s.postRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	if err := s.privacy.CheckCanSeePost(ctx, viewerID, post); err != nil {
+		return nil, err
+	}
+
+	history, err := s.revisionRepo.GetCommentHistory(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return addDiffs(history, withDiff), nil
+}
+
+// GetPostHistoryForModeration returns the edit history of any post, including
+// posts the author has deleted. Only for the internal routes.
+func (s *RevisionService) GetPostHistoryForModeration(ctx context.Context, postID uuid.UUID, withDiff bool) (*model.EditHistory, error) {
+	history, err := s.revisionRepo.GetPostHistory(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return addDiffs(history, withDiff), nil
+}
+
+// GetCommentHistoryForModeration returns the edit history of any comment,
+// including comments the author has deleted. Only for the internal routes.
+func (s *RevisionService) GetCommentHistoryForModeration(ctx context.Context, commentID uuid.UUID, withDiff bool) (*model.EditHistory, error) {
+	history, err := s.revisionRepo.GetCommentHistory(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return addDiffs(history, withDiff), nil
+}
+
+// addDiffs sets what each edit in history changed, if withDiff is set
+func addDiffs(history *model.EditHistory, withDiff bool) *model.EditHistory {
+	if withDiff {
+		for i := 1; i < len(history.Revisions); i++ {
+			history.Revisions[i].Diff = textdiff.Words(history.Revisions[i-1].Text, history.Revisions[i].Text)
+		}
+	}
+	return history
+}
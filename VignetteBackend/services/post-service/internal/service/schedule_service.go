@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// publishBatch is how many due posts, and how many due Takes, one pass publishes
+const publishBatch = 100
+
+// ScheduleService publishes scheduled posts and Takes when they fall due
+type ScheduleService struct {
+	posts    *PostService
+	takes    *TakesService
+	interval time.Duration
+}
+
+func NewScheduleService(posts *PostService, takes *TakesService, interval time.Duration) *ScheduleService {
+	return &ScheduleService{
+		posts:    posts,
+		takes:    takes,
+		interval: interval,
+	}
+}
+
+// RunWorker publishes due posts and Takes until ctx is cancelled. Every instance
+// may run one: each post and Take is published by whichever instance gets to it
+// first, and only once.
+func (s *ScheduleService) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDue publishes what is due, going round again while full batches come back
+func (s *ScheduleService) publishDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		posts := s.posts.PublishDue(ctx, publishBatch)
+		takes := s.takes.PublishDue(ctx, publishBatch)
+		if posts > 0 || takes > 0 {
+			log.Printf("Published %d scheduled posts and %d scheduled Takes", posts, takes)
+		}
+		if posts < publishBatch && takes < publishBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,14 @@
+-- Home timelines are kept in Redis. A post is pushed into its author's followers'
+-- timelines when it is created, unless the author has too many followers for that;
+-- those posts are pulled in as timelines are read instead.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS fanned_out BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Timelines are rebuilt and paged from posts by a set of authors
+CREATE INDEX IF NOT EXISTS idx_posts_author_feed ON posts(user_id, created_at DESC, id DESC)
+    WHERE deleted_at IS NULL;
+
+-- Posts that were not fanned out are merged into every timeline read
+CREATE INDEX IF NOT EXISTS idx_posts_fan_out_on_read ON posts(user_id, created_at DESC, id DESC)
+    WHERE deleted_at IS NULL AND fanned_out = FALSE;
+
+COMMENT ON COLUMN posts.fanned_out IS 'TRUE if the post was pushed into home timelines on create; FALSE if they pull it in on read';
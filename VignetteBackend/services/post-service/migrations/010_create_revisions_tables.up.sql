@@ -0,0 +1,38 @@
+-- Earlier texts of edited posts and comments. Each edit writes the text it
+-- replaced, in the same transaction as the edit; rows are never changed after.
+-- Deleting a post or comment only soft deletes it, so its revisions stay for
+-- moderation. They go only if the row itself is removed.
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    caption TEXT NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions(post_id, replaced_at);
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment ON comment_revisions(comment_id, replaced_at);
+
+-- Revisions are immutable
+CREATE OR REPLACE FUNCTION reject_revision_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'revisions cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_revisions_immutable_trigger
+    BEFORE UPDATE ON post_revisions
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_revision_update();
+
+CREATE TRIGGER comment_revisions_immutable_trigger
+    BEFORE UPDATE ON comment_revisions
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_revision_update();
@@ -0,0 +1,109 @@
+// Package textdiff finds the words changed between two versions of a text, for
+// showing what an edit to a post or comment changed.
+package textdiff
+
+import "regexp"
+
+// Kinds of change
+const (
+	Equal  = "equal"
+	Delete = "delete"
+	Insert = "insert"
+)
+
+// maxCells bounds the work done on one diff. Texts too different to diff within
+// it come back as the whole old text deleted and the whole new one inserted.
+const maxCells = 1 << 20
+
+// Change is a run of text that is in both versions, or only in the old or new one
+type Change struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// tokens splits text into words and the whitespace between them
+var tokens = regexp.MustCompile(`\s+|\S+`)
+
+// Words diffs old against new word by word. Whitespace is kept, so the Equal and
+// Delete texts join back up to old, and the Equal and Insert texts to new.
+func Words(old, new string) []Change {
+	a := tokens.FindAllString(old, -1)
+	b := tokens.FindAllString(new, -1)
+
+	// Words shared at the start and end don't need the full comparison
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var changes []Change
+	for _, word := range a[:prefix] {
+		changes = appendChange(changes, Equal, word)
+	}
+	changes = appendMiddle(changes, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, word := range a[len(a)-suffix:] {
+		changes = appendChange(changes, Equal, word)
+	}
+	return changes
+}
+
+// appendMiddle appends the changes from a to b, by longest common subsequence
+func appendMiddle(changes []Change, a, b []string) []Change {
+	if (len(a)+1)*(len(b)+1) > maxCells {
+		for _, word := range a {
+			changes = appendChange(changes, Delete, word)
+		}
+		for _, word := range b {
+			changes = appendChange(changes, Insert, word)
+		}
+		return changes
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			changes = appendChange(changes, Equal, a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			changes = appendChange(changes, Delete, a[i])
+			i++
+		default:
+			changes = appendChange(changes, Insert, b[j])
+			j++
+		}
+	}
+	return changes
+}
+
+// appendChange adds text to the last change if it is of the same type
+func appendChange(changes []Change, kind, text string) []Change {
+	if n := len(changes); n > 0 && changes[n-1].Type == kind {
+		changes[n-1].Text += text
+		return changes
+	}
+	return append(changes, Change{Type: kind, Text: text})
+}
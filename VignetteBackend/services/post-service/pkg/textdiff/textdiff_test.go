@@ -0,0 +1,98 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// join rebuilds the old or new text from changes
+func join(changes []Change, skip string) string {
+	var b strings.Builder
+	for _, c := range changes {
+		if c.Type != skip {
+			b.WriteString(c.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestWordsReplacedWord(t *testing.T) {
+	got := Words("the cat sat down", "the dog sat down")
+	want := []Change{
+		{Type: Equal, Text: "the "},
+		{Type: Delete, Text: "cat"},
+		{Type: Insert, Text: "dog"},
+		{Type: Equal, Text: " sat down"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWordsInsertAndDelete(t *testing.T) {
+	got := Words("sunset at the beach", "sunset at the lovely beach today")
+	want := []Change{
+		{Type: Equal, Text: "sunset at the "},
+		{Type: Insert, Text: "lovely "},
+		{Type: Equal, Text: "beach"},
+		{Type: Insert, Text: " today"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	got = Words("a b c", "a c")
+	want = []Change{
+		{Type: Equal, Text: "a "},
+		{Type: Delete, Text: "b "},
+		{Type: Equal, Text: "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWordsUnchangedAndEmpty(t *testing.T) {
+	if got := Words("same text", "same text"); !reflect.DeepEqual(got, []Change{{Type: Equal, Text: "same text"}}) {
+		t.Fatalf("unchanged: got %+v", got)
+	}
+	if got := Words("", ""); len(got) != 0 {
+		t.Fatalf("empty: got %+v", got)
+	}
+	if got := Words("", "new"); !reflect.DeepEqual(got, []Change{{Type: Insert, Text: "new"}}) {
+		t.Fatalf("from empty: got %+v", got)
+	}
+	if got := Words("old", ""); !reflect.DeepEqual(got, []Change{{Type: Delete, Text: "old"}}) {
+		t.Fatalf("to empty: got %+v", got)
+	}
+}
+
+func TestWordsRebuildsBothTexts(t *testing.T) {
+	pairs := [][2]string{
+		{"Great day at the park!\n\n#sunday #family", "Great day at the park with everyone!\n#sunday"},
+		{"  leading and trailing  ", "leading, trailing "},
+		{"one two three four", "four three two one"},
+	}
+	for _, p := range pairs {
+		changes := Words(p[0], p[1])
+		if old := join(changes, Insert); old != p[0] {
+			t.Errorf("old text: got %q, want %q", old, p[0])
+		}
+		if new := join(changes, Delete); new != p[1] {
+			t.Errorf("new text: got %q, want %q", new, p[1])
+		}
+	}
+}
+
+func TestWordsTooLargeFallsBackToReplace(t *testing.T) {
+	old := strings.Repeat("a ", 1100) + "x"
+	new := "y " + strings.Repeat("b ", 1100)
+	changes := Words(old, new)
+	if len(changes) != 2 || changes[0].Type != Delete || changes[1].Type != Insert {
+		t.Fatalf("got %d changes, want a delete and an insert", len(changes))
+	}
+	if changes[0].Text != old || changes[1].Text != new {
+		t.Fatal("fallback should replace the whole text")
+	}
+}
@@ -51,8 +51,14 @@ func main() {
 	}
 	log.Println("✅ Connected to Redis")
 
+	// Block list lookups go to the user service's internal API
+	blockClient := service.NewBlockClient(
+		getEnv("USER_SERVICE_URL", "http://localhost:8002"),
+		getEnv("USER_SERVICE_INTERNAL_TOKEN", ""),
+	)
+
 	// Initialize services
-	searchService := service.NewSearchService(esClient, redisClient)
+	searchService := service.NewSearchService(esClient, redisClient, blockClient)
 	autocompleteService := service.NewAutocompleteService(esClient, redisClient)
 	indexingService := service.NewIndexingService(esClient, redisClient)
 	hashtagService := service.NewHashtagService(esClient, redisClient)
@@ -42,9 +42,13 @@ type PostDocument struct {
 	CommentsCount int64   `json:"comments_count"`
 	SharesCount int64     `json:"shares_count"`
 	ViewsCount  int64     `json:"views_count"`
+	Audience    string    `json:"audience"` // Only public posts are indexed
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// AudiencePublic is the audience of posts anyone can see, the only ones search holds
+const AudiencePublic = "public"
+
 // TakeDocument represents a Take in search index
 type TakeDocument struct {
 	ID          string    `json:"id"`
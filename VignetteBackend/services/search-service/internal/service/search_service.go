@@ -15,14 +15,16 @@ import (
 )
 
 type SearchService struct {
-	es    *elasticsearch.Client
-	redis *redis.Client
+	es     *elasticsearch.Client
+	redis  *redis.Client
+	blocks *BlockClient
 }
 
-func NewSearchService(es *elasticsearch.Client, redis *redis.Client) *SearchService {
+func NewSearchService(es *elasticsearch.Client, redis *redis.Client, blocks *BlockClient) *SearchService {
 	return &SearchService{
-		es:    es,
-		redis: redis,
+		es:     es,
+		redis:  redis,
+		blocks: blocks,
 	}
 }
 
@@ -38,6 +40,16 @@ func (s *SearchService) Search(ctx context.Context, req *model.SearchRequest) (*
 		req.Limit = 50
 	}
 
+	// Hide users blocked either way from the searcher. If the user service can't be
+	// reached, search still works but the results are not cached.
+	var blockErr error
+	if req.UserID != "" {
+		req.ExcludeUserIDs, blockErr = s.blocks.BlockedUserIDs(ctx, req.UserID)
+		if blockErr != nil {
+			log.Printf("Failed to get blocked users for %s: %v", req.UserID, blockErr)
+		}
+	}
+
 	// Get cache key
 	cacheKey := s.getCacheKey(req)
 	
@@ -77,7 +89,9 @@ func (s *SearchService) Search(ctx context.Context, req *model.SearchRequest) (*
 	}
 
 	// Cache results
-	s.cacheResults(ctx, cacheKey, response)
+	if blockErr == nil {
+		s.cacheResults(ctx, cacheKey, response)
+	}
 
 	// Record search (async)
 	go s.recordSearch(context.Background(), req, response.TotalHits)
@@ -263,6 +277,10 @@ func (s *SearchService) getSearchSuggestions(ctx context.Context, query string,
 
 // getCacheKey generates a cache key for the search request
 func (s *SearchService) getCacheKey(req *model.SearchRequest) string {
+	// Results filtered by a block list are only valid for that searcher
+	if len(req.ExcludeUserIDs) > 0 {
+		return fmt.Sprintf("search:%s:%s:%s:%d:%d", req.UserID, req.Type, req.Query, req.Limit, req.Offset)
+	}
 	return fmt.Sprintf("search:%s:%s:%d:%d", req.Type, req.Query, req.Limit, req.Offset)
 }
 
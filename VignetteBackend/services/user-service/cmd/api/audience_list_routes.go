@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"user-service/internal/handler"
+	"user-service/internal/middleware"
+	"user-service/internal/service"
+)
+
+// setupAudienceListRoutes serves Close Friends and custom audience lists, whose handlers
+// are written for gin. SetupRoutes forwards their paths here, so routes keep their full path.
+func setupAudienceListRoutes(listHandler *handler.AudienceListHandler, authService *service.AuthService, internalToken string) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	api := r.Group("/api/v1")
+
+	// Lists (protected). A list ID may be "close-friends".
+	lists := api.Group("/lists")
+	lists.Use(middleware.AuthMiddleware(authService))
+	lists.GET("", listHandler.GetLists)
+	lists.POST("", listHandler.CreateList)
+	lists.GET("/:list_id", listHandler.GetList)
+	lists.PUT("/:list_id", listHandler.RenameList)
+	lists.DELETE("/:list_id", listHandler.DeleteList)
+	lists.GET("/:list_id/members", listHandler.GetListMembers)
+	lists.POST("/:list_id/members", listHandler.AddListMembers)
+	lists.DELETE("/:list_id/members", listHandler.RemoveListMembers)
+
+	// Internal routes for the post and story services, behind INTERNAL_API_TOKEN
+	internal := api.Group("/internal")
+	internal.Use(middleware.InternalAuthMiddleware(internalToken))
+	internal.POST("/audience-lists/check", listHandler.CheckListMemberships)
+
+	return r
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"user-service/internal/handler"
+	"user-service/internal/middleware"
+	"user-service/internal/service"
+)
+
+// setupFollowRoutes serves the follow and follow request endpoints and the internal
+// follow and block graph, whose handlers are written for gin. SetupRoutes forwards
+// their paths here, so routes keep their full path.
+func setupFollowRoutes(followHandler *handler.FollowHandler, blockHandler *handler.BlockHandler, authService *service.AuthService, internalToken string) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	api := r.Group("/api/v1")
+
+	// Follows (protected)
+	follows := api.Group("")
+	follows.Use(middleware.AuthMiddleware(authService))
+	follows.POST("/users/:user_id/follow", followHandler.Follow)
+	follows.DELETE("/users/:user_id/follow", followHandler.Unfollow)
+	follows.GET("/users/:user_id/followers", followHandler.GetFollowers)
+	follows.GET("/users/:user_id/following", followHandler.GetFollowing)
+
+	// Follow requests to the signed-in user's private account
+	follows.GET("/follow-requests", followHandler.GetFollowRequests)
+	follows.POST("/follow-requests/approve", followHandler.BulkApproveFollowRequests)
+	follows.POST("/follow-requests/:requester_id/approve", followHandler.ApproveFollowRequest)
+	follows.POST("/follow-requests/:requester_id/deny", followHandler.DenyFollowRequest)
+
+	// Internal routes for other services, behind INTERNAL_API_TOKEN
+	internal := api.Group("/internal")
+	internal.Use(middleware.InternalAuthMiddleware(internalToken))
+	internal.POST("/visibility/hidden-owners", followHandler.GetHiddenOwners)
+	internal.GET("/users/:user_id/followers", followHandler.GetTimelineAudience)
+	internal.GET("/users/:user_id/following", followHandler.GetTimelineSources)
+	internal.GET("/blocks/:user_id", blockHandler.GetBlockedUsers)
+
+	return r
+}
@@ -13,6 +13,7 @@ import (
 
 	_ "github.com/lib/pq"
 	"user-service/internal/config"
+	usergrpc "user-service/internal/grpc"
 	"user-service/internal/handler"
 	"user-service/internal/logger"
 	"user-service/internal/middleware"
@@ -44,10 +45,18 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	tokenRepo := repository.NewTokenRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	audienceListRepo := repository.NewAudienceListRepository(db)
 	
 	// Initialize services
 	emailService := service.NewEmailService()
 	auditLog := service.NewAuditLog(db)
+	kafkaProducer := service.NewKafkaProducer(nil)
+	blockService := service.NewBlockService(settingsRepo, nil, kafkaProducer, 10*time.Minute)
+	authService := service.NewAuthService(userRepo, sessionRepo, cfg)
+	followService := service.NewFollowService(followRepo, userRepo, blockService, kafkaProducer)
+	audienceListService := service.NewAudienceListService(audienceListRepo)
 	
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(
@@ -59,12 +68,17 @@ func main() {
 		appLogger,
 		cfg,
 	)
+	followHandler := handler.NewFollowHandler(followService)
+	blockHandler := handler.NewBlockHandler(blockService)
+	audienceListHandler := handler.NewAudienceListHandler(audienceListService)
 	
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(userRepo, appLogger)
 	
 	// Setup routes
-	router := SetupRoutes(authHandler, authMiddleware)
+	followRouter := setupFollowRoutes(followHandler, blockHandler, authService, cfg.Security.InternalAPIToken)
+	audienceListRouter := setupAudienceListRoutes(audienceListHandler, authService, cfg.Security.InternalAPIToken)
+	router := SetupRoutes(authHandler, authMiddleware, followRouter, audienceListRouter)
 	
 	// Create HTTP server
 	server := &http.Server{
@@ -75,6 +89,14 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 	
+	// Internal gRPC API for other services
+	grpcServer := usergrpc.NewGRPCServer(cfg, userRepo, followRepo, blockService)
+	go func() {
+		if err := grpcServer.Start(); err != nil {
+			appLogger.Fatal("gRPC server error", err)
+		}
+	}()
+	
 	// Start cleanup goroutine for expired sessions and tokens
 	go cleanupExpiredData(sessionRepo, tokenRepo, appLogger)
 	
@@ -100,6 +122,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		appLogger.Error("Server forced to shutdown", err)
 	}
+	grpcServer.Stop()
 	
 	appLogger.Info("Server stopped")
 }
@@ -10,7 +10,7 @@ import (
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(authHandler *handler.AuthHandler, authMiddleware *middleware.AuthMiddleware) *mux.Router {
+func SetupRoutes(authHandler *handler.AuthHandler, authMiddleware *middleware.AuthMiddleware, followRouter, audienceListRouter http.Handler) *mux.Router {
 	r := mux.NewRouter()
 	
 	// API version prefix
@@ -39,6 +39,20 @@ func SetupRoutes(authHandler *handler.AuthHandler, authMiddleware *middleware.Au
 	authProtected.HandleFunc("/logout", authHandler.HandleLogout).Methods("POST")
 	authProtected.HandleFunc("/refresh", authHandler.HandleRefreshToken).Methods("POST")
 	
+	// Follows, follow requests and the internal follow and block graph (served by followRouter)
+	api.Handle("/users/{id}/follow", followRouter).Methods("POST", "DELETE")
+	api.Handle("/users/{id}/followers", followRouter).Methods("GET")
+	api.Handle("/users/{id}/following", followRouter).Methods("GET")
+	api.PathPrefix("/follow-requests").Handler(followRouter)
+	api.Handle("/internal/visibility/hidden-owners", followRouter).Methods("POST")
+	api.Handle("/internal/users/{id}/followers", followRouter).Methods("GET")
+	api.Handle("/internal/users/{id}/following", followRouter).Methods("GET")
+	api.Handle("/internal/blocks/{id}", followRouter).Methods("GET")
+	
+	// Close Friends and custom audience lists, and the internal membership check (served by audienceListRouter)
+	api.PathPrefix("/lists").Handler(audienceListRouter)
+	api.Handle("/internal/audience-lists/check", audienceListRouter).Methods("POST")
+	
 	// User management routes (protected)
 	users := api.PathPrefix("/users").Subrouter()
 	users.Use(authMiddleware.RequireAuth)
@@ -15,6 +15,7 @@ type Config struct {
 	Email    EmailConfig
 	Security SecurityConfig
 	Platform PlatformConfig
+	GRPC     GRPCConfig
 }
 
 // ServerConfig holds server configuration
@@ -59,6 +60,7 @@ type SecurityConfig struct {
 	PasswordMinLength         int
 	PasswordResetTokenExpiry  time.Duration
 	SessionExpiry             time.Duration
+	InternalAPIToken          string // shared with other services for internal endpoints; empty closes them
 }
 
 // PlatformConfig holds cross-platform integration settings
@@ -68,6 +70,13 @@ type PlatformConfig struct {
 	EnableCrossPlatformSSO  bool
 }
 
+// GRPCConfig configures the internal gRPC API other services call
+type GRPCConfig struct {
+	Port           string
+	DefaultTimeout time.Duration // deadline for calls that arrive without one
+	MaxTimeout     time.Duration // longer deadlines are cut to this
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -104,12 +113,18 @@ func Load() (*Config, error) {
 			PasswordMinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
 			PasswordResetTokenExpiry: getEnvAsDuration("PASSWORD_RESET_TOKEN_EXPIRY", 1*time.Hour),
 			SessionExpiry:            getEnvAsDuration("SESSION_EXPIRY", 24*time.Hour),
+			InternalAPIToken:         getEnv("INTERNAL_API_TOKEN", ""),
 		},
 		Platform: PlatformConfig{
 			EntativaAPIURL:         getEnv("ENTATIVA_API_URL", "http://localhost:8001/api/v1"),
 			VignetteAPIURL:         getEnv("VIGNETTE_API_URL", "http://localhost:8002/api/v1"),
 			EnableCrossPlatformSSO: getEnvAsBool("ENABLE_CROSS_PLATFORM_SSO", true),
 		},
+		GRPC: GRPCConfig{
+			Port:           getEnv("GRPC_PORT", "50001"),
+			DefaultTimeout: getEnvAsDuration("GRPC_DEFAULT_TIMEOUT", 5*time.Second),
+			MaxTimeout:     getEnvAsDuration("GRPC_MAX_TIMEOUT", 30*time.Second),
+		},
 	}
 	
 	// Validate required configuration
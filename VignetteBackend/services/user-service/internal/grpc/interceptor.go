@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// InternalTokenMetadataKey carries the shared internal token on every call
+const InternalTokenMetadataKey = "x-internal-token"
+
+// unaryAuthInterceptor only admits callers presenting the shared internal token.
+// With no token configured the API is closed.
+func unaryAuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuthInterceptor is unaryAuthInterceptor for streaming calls
+func streamAuthInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// unaryDeadlineInterceptor gives calls without a deadline defaultTimeout, and
+// holds calls with a longer one to maxTimeout
+func unaryDeadlineInterceptor(defaultTimeout, maxTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withDeadline(ctx, defaultTimeout, maxTimeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// streamDeadlineInterceptor is unaryDeadlineInterceptor for streaming calls
+func streamDeadlineInterceptor(defaultTimeout, maxTimeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withDeadline(ss.Context(), defaultTimeout, maxTimeout)
+		defer cancel()
+		return handler(srv, &deadlineStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authorize(ctx context.Context, token string) error {
+	if token == "" {
+		return status.Error(codes.PermissionDenied, "internal API is not configured")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	presented := md.Get(InternalTokenMetadataKey)
+	if len(presented) == 0 || subtle.ConstantTimeCompare([]byte(presented[0]), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "missing or invalid internal token")
+	}
+	return nil
+}
+
+func withDeadline(ctx context.Context, defaultTimeout, maxTimeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithTimeout(ctx, defaultTimeout)
+	}
+	if maxTimeout > 0 && time.Until(deadline) > maxTimeout {
+		return context.WithTimeout(ctx, maxTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// deadlineStream hands the handler a context carrying the applied deadline
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *deadlineStream) Context() context.Context {
+	return s.ctx
+}
@@ -2,44 +2,68 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 
+	"vignette/user-service/internal/config"
+	"vignette/user-service/internal/model"
 	"vignette/user-service/internal/repository"
 	"vignette/user-service/internal/service"
+	"vignette/user-service/internal/util"
+	pb "vignette/user-service/proto/user"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// MaxBatchGetUsers is the most users BatchGetUsers returns in one call
+const MaxBatchGetUsers = 500
+
+// Server serves the internal UserService API to other Vignette services
 type Server struct {
-	grpcServer       *grpc.Server
-	userRepo         *repository.UserRepository
-	authService      *service.AuthService
-	twoFactorService *service.TwoFactorService
-	port             string
+	pb.UnimplementedUserServiceServer
+
+	grpcServer   *grpc.Server
+	userRepo     *repository.UserRepository
+	followRepo   *repository.FollowRepository
+	blockService *service.BlockService
+	port         string
 }
 
-// NewGRPCServer creates a new gRPC server
+// NewGRPCServer creates a new gRPC server. Every call must carry the internal
+// API token and runs under the configured deadlines.
 func NewGRPCServer(
-	port string,
+	cfg *config.Config,
 	userRepo *repository.UserRepository,
-	authService *service.AuthService,
-	twoFactorService *service.TwoFactorService,
+	followRepo *repository.FollowRepository,
+	blockService *service.BlockService,
 ) *Server {
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			unaryAuthInterceptor(cfg.Security.InternalAPIToken),
+			unaryDeadlineInterceptor(cfg.GRPC.DefaultTimeout, cfg.GRPC.MaxTimeout),
+		),
+		grpc.ChainStreamInterceptor(
+			streamAuthInterceptor(cfg.Security.InternalAPIToken),
+			streamDeadlineInterceptor(cfg.GRPC.DefaultTimeout, cfg.GRPC.MaxTimeout),
+		),
+	)
 
 	server := &Server{
-		grpcServer:       grpcServer,
-		userRepo:         userRepo,
-		authService:      authService,
-		twoFactorService: twoFactorService,
-		port:             port,
+		grpcServer:   grpcServer,
+		userRepo:     userRepo,
+		followRepo:   followRepo,
+		blockService: blockService,
+		port:         cfg.GRPC.Port,
 	}
 
-	// Register services
-	// pb.RegisterUserServiceServer(grpcServer, server)
+	pb.RegisterUserServiceServer(grpcServer, server)
 
 	// Enable reflection for debugging (remove in production)
 	reflection.Register(grpcServer)
@@ -69,8 +93,210 @@ func (s *Server) Stop() {
 	s.grpcServer.GracefulStop()
 }
 
-// Example gRPC method implementation
-func (s *Server) GetUser(ctx context.Context, req interface{}) (interface{}, error) {
-	// Implementation here when proto is compiled
-	return nil, nil
+// GetUser returns one user
+func (s *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	userID, err := parseUserID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, internalError("get user", err)
+	}
+	if len(users) == 0 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return &pb.GetUserResponse{User: toProtoUser(users[0])}, nil
+}
+
+// BatchGetUsers returns many users at once, in the order asked
+func (s *Server) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	ids := make([]uuid.UUID, 0, len(req.GetUserIds()))
+	seen := make(map[uuid.UUID]bool, len(req.GetUserIds()))
+	for _, raw := range req.GetUserIds() {
+		id, err := parseUserID("user_ids", raw)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxBatchGetUsers {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d users can be fetched at once", MaxBatchGetUsers)
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, internalError("get users", err)
+	}
+
+	found := make(map[uuid.UUID]*model.User, len(users))
+	for _, user := range users {
+		found[user.ID] = user
+	}
+
+	resp := &pb.BatchGetUsersResponse{Users: make([]*pb.User, 0, len(users))}
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			resp.Users = append(resp.Users, toProtoUser(user))
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id.String())
+		}
+	}
+	return resp, nil
+}
+
+// GetFollowerCount returns how many people follow the user and how many they follow
+func (s *Server) GetFollowerCount(ctx context.Context, req *pb.GetFollowerCountRequest) (*pb.GetFollowerCountResponse, error) {
+	userID, err := parseUserID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	followers, err := s.followRepo.GetFollowersCount(ctx, userID)
+	if err != nil {
+		return nil, internalError("count followers", err)
+	}
+	following, err := s.followRepo.GetFollowingCount(ctx, userID)
+	if err != nil {
+		return nil, internalError("count following", err)
+	}
+
+	return &pb.GetFollowerCountResponse{
+		UserId:         userID.String(),
+		FollowersCount: int64(followers),
+		FollowingCount: int64(following),
+	}, nil
+}
+
+// AreFriends reports whether two users follow each other
+func (s *Server) AreFriends(ctx context.Context, req *pb.AreFriendsRequest) (*pb.AreFriendsResponse, error) {
+	userID, err := parseUserID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	otherUserID, err := parseUserID("other_user_id", req.GetOtherUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	followsOther, err := s.followRepo.IsFollowing(ctx, userID, otherUserID)
+	if err != nil {
+		return nil, internalError("check follow", err)
+	}
+	followedBack, err := s.followRepo.IsFollowing(ctx, otherUserID, userID)
+	if err != nil {
+		return nil, internalError("check follow", err)
+	}
+
+	return &pb.AreFriendsResponse{
+		AreFriends:       followsOther && followedBack,
+		UserFollowsOther: followsOther,
+		OtherFollowsUser: followedBack,
+	}, nil
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (s *Server) IsBlocked(ctx context.Context, req *pb.IsBlockedRequest) (*pb.IsBlockedResponse, error) {
+	userID, err := parseUserID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	otherUserID, err := parseUserID("other_user_id", req.GetOtherUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.blockService.IsBlocked(ctx, userID.String(), otherUserID.String())
+	if err != nil {
+		return nil, internalError("check block", err)
+	}
+
+	return &pb.IsBlockedResponse{IsBlocked: blocked}, nil
+}
+
+// ValidateToken checks an access token and that its account can still use it
+func (s *Server) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := util.ParseAccessToken(req.GetToken())
+	if err != nil {
+		return &pb.ValidateTokenResponse{Valid: false}, nil
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return &pb.ValidateTokenResponse{Valid: false}, nil
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, internalError("get user", err)
+	}
+	if len(users) == 0 || !users[0].IsActive {
+		return &pb.ValidateTokenResponse{Valid: false}, nil
+	}
+
+	resp := &pb.ValidateTokenResponse{
+		Valid:    true,
+		UserId:   userID.String(),
+		Username: users[0].Username,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return resp, nil
+}
+
+func parseUserID(field, raw string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "%s: invalid user ID %q", field, raw)
+	}
+	return id, nil
+}
+
+// internalError logs err and hides it from the caller, unless the call was
+// cancelled or ran out of time
+func internalError(action string, err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "call cancelled")
+	}
+
+	log.Printf("gRPC: failed to %s: %v", action, err)
+	return status.Errorf(codes.Internal, "failed to %s", action)
+}
+
+func toProtoUser(user *model.User) *pb.User {
+	return &pb.User{
+		Id:                user.ID.String(),
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Bio:               stringValue(user.Bio),
+		Website:           stringValue(user.Website),
+		ProfilePictureUrl: stringValue(user.ProfilePictureURL),
+		IsPrivate:         user.IsPrivate,
+		IsVerified:        user.IsVerified,
+		IsActive:          user.IsActive,
+		FollowersCount:    int64(user.FollowersCount),
+		FollowingCount:    int64(user.FollowingCount),
+		PostsCount:        int64(user.PostsCount),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vignette/user-service/internal/model"
+	"vignette/user-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxListMemberPageSize caps how many members one page of a list may hold
+const maxListMemberPageSize = 100
+
+type AudienceListHandler struct {
+	listService *service.AudienceListService
+}
+
+func NewAudienceListHandler(listService *service.AudienceListService) *AudienceListHandler {
+	return &AudienceListHandler{
+		listService: listService,
+	}
+}
+
+// GetLists returns the signed-in user's lists
+// @Summary Get audience lists
+// @Description List Close Friends and the user's own audience lists, Close Friends first
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /lists [get]
+func (h *AudienceListHandler) GetLists(c *gin.Context) {
+	lists, err := h.listService.ListLists(c.Request.Context(), viewerID(c))
+	if err != nil {
+		respondWithListError(c, err, "Failed to get lists")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lists,
+	})
+}
+
+// CreateList makes a new list
+// @Summary Create audience list
+// @Description Create an empty audience list such as "Family"
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body model.AudienceListRequest true "List name"
+// @Success 201 {object} model.AudienceList
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /lists [post]
+func (h *AudienceListHandler) CreateList(c *gin.Context) {
+	var req model.AudienceListRequest
+	if !bindListRequest(c, &req) {
+		return
+	}
+
+	list, err := h.listService.CreateList(c.Request.Context(), viewerID(c), req.Name)
+	if err != nil {
+		respondWithListError(c, err, "Failed to create list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "List created",
+		"data":    list,
+	})
+}
+
+// GetList returns one of the signed-in user's lists
+// @Summary Get audience list
+// @Description Get one list by ID, or Close Friends as "close-friends"
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Param list_id path string true "List ID or close-friends"
+// @Success 200 {object} model.AudienceList
+// @Failure 404 {object} map[string]interface{}
+// @Router /lists/{list_id} [get]
+func (h *AudienceListHandler) GetList(c *gin.Context) {
+	list, err := h.listService.GetList(c.Request.Context(), viewerID(c), c.Param("list_id"))
+	if err != nil {
+		respondWithListError(c, err, "Failed to get list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// RenameList renames one of the signed-in user's lists
+// @Summary Rename audience list
+// @Description Rename a list. Close Friends can't be renamed.
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param list_id path string true "List ID"
+// @Param request body model.AudienceListRequest true "New name"
+// @Success 200 {object} model.AudienceList
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /lists/{list_id} [put]
+func (h *AudienceListHandler) RenameList(c *gin.Context) {
+	var req model.AudienceListRequest
+	if !bindListRequest(c, &req) {
+		return
+	}
+
+	list, err := h.listService.RenameList(c.Request.Context(), viewerID(c), c.Param("list_id"), req.Name)
+	if err != nil {
+		respondWithListError(c, err, "Failed to rename list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "List renamed",
+		"data":    list,
+	})
+}
+
+// DeleteList deletes one of the signed-in user's lists
+// @Summary Delete audience list
+// @Description Delete a list. Whatever was shared with it is then seen by the owner only.
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Param list_id path string true "List ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /lists/{list_id} [delete]
+func (h *AudienceListHandler) DeleteList(c *gin.Context) {
+	if err := h.listService.DeleteList(c.Request.Context(), viewerID(c), c.Param("list_id")); err != nil {
+		respondWithListError(c, err, "Failed to delete list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "List deleted",
+	})
+}
+
+// GetListMembers lists the people in one of the signed-in user's lists
+// @Summary Get audience list members
+// @Description List a list's members, most recently added first
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Param list_id path string true "List ID or close-friends"
+// @Param limit query int false "Page size"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /lists/{list_id}/members [get]
+func (h *AudienceListHandler) GetListMembers(c *gin.Context) {
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= maxListMemberPageSize {
+		limit = l
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	members, err := h.listService.ListMembers(c.Request.Context(), viewerID(c), c.Param("list_id"), limit, offset)
+	if err != nil {
+		respondWithListError(c, err, "Failed to get list members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     members,
+		"has_more": len(members) == limit,
+	})
+}
+
+// AddListMembers adds people to one of the signed-in user's lists
+// @Summary Add audience list members
+// @Description Add up to 500 people to a list. People already in it are skipped.
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param list_id path string true "List ID or close-friends"
+// @Param request body model.AudienceListMembersRequest true "User IDs"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /lists/{list_id}/members [post]
+func (h *AudienceListHandler) AddListMembers(c *gin.Context) {
+	var req model.AudienceListMembersRequest
+	if !bindListRequest(c, &req) {
+		return
+	}
+
+	added, err := h.listService.AddMembers(c.Request.Context(), viewerID(c), c.Param("list_id"), req.UserIDs)
+	if err != nil {
+		respondWithListError(c, err, "Failed to add list members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"added": added},
+	})
+}
+
+// RemoveListMembers takes people out of one of the signed-in user's lists
+// @Summary Remove audience list members
+// @Description Remove up to 500 people from a list
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param list_id path string true "List ID or close-friends"
+// @Param request body model.AudienceListMembersRequest true "User IDs"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /lists/{list_id}/members [delete]
+func (h *AudienceListHandler) RemoveListMembers(c *gin.Context) {
+	var req model.AudienceListMembersRequest
+	if !bindListRequest(c, &req) {
+		return
+	}
+
+	removed, err := h.listService.RemoveMembers(c.Request.Context(), viewerID(c), c.Param("list_id"), req.UserIDs)
+	if err != nil {
+		respondWithListError(c, err, "Failed to remove list members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"removed": removed},
+	})
+}
+
+// CheckListMemberships answers a batch of "is the viewer in list X of owner Y"
+// checks, in the order asked.
+// Internal: the post and story services use it to decide who may see content
+// shared with a list.
+// @Router /internal/audience-lists/check [post]
+func (h *AudienceListHandler) CheckListMemberships(c *gin.Context) {
+	var req model.AudienceCheckRequest
+	if !bindListRequest(c, &req) {
+		return
+	}
+
+	memberships, err := h.listService.CheckMemberships(c.Request.Context(), req.Checks)
+	if err != nil {
+		respondWithListError(c, err, "Failed to check list memberships")
+		return
+	}
+
+	results := make([]model.AudienceCheckResult, 0, len(req.Checks))
+	for i, check := range req.Checks {
+		results = append(results, model.AudienceCheckResult{
+			AudienceMembershipCheck: check,
+			IsMember:                memberships[i],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"results": results},
+	})
+}
+
+// bindListRequest decodes a list request body, answering 400 when it is invalid
+func bindListRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// respondWithListError maps audience list service errors to responses
+func respondWithListError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAudienceListNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrAudienceListNameTaken):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidAudienceListName),
+		errors.Is(err, service.ErrTooManyAudienceLists),
+		errors.Is(err, service.ErrAudienceListFull),
+		errors.Is(err, service.ErrCloseFriendsListFixed),
+		errors.Is(err, service.ErrCannotAddSelfToList):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"vignette/user-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockHandler serves the block graph to other services, so they can hide blocked
+// users from each other in search
+type BlockHandler struct {
+	blockService *service.BlockService
+}
+
+func NewBlockHandler(blockService *service.BlockService) *BlockHandler {
+	return &BlockHandler{
+		blockService: blockService,
+	}
+}
+
+// GetBlockedUsers lists everyone a user has blocked or been blocked by.
+// Internal: the search service leaves these users out of the user's results.
+// @Router /internal/blocks/{user_id} [get]
+func (h *BlockHandler) GetBlockedUsers(c *gin.Context) {
+	userID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	blocked, err := h.blockService.BlockedUserIDs(c.Request.Context(), userID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get blocked users",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"user_id":          userID,
+			"blocked_user_ids": blocked,
+		},
+	})
+}
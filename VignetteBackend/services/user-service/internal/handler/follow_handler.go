@@ -0,0 +1,433 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vignette/user-service/internal/model"
+	"vignette/user-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxFollowPageSize caps how many users one page of a follow list may hold
+const maxFollowPageSize = 100
+
+type FollowHandler struct {
+	followService *service.FollowService
+}
+
+func NewFollowHandler(followService *service.FollowService) *FollowHandler {
+	return &FollowHandler{
+		followService: followService,
+	}
+}
+
+// Follow follows a user, or asks to follow a private account
+// @Summary Follow user
+// @Description Follow a public account, or send a follow request to a private one
+// @Tags follows
+// @Security BearerAuth
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} model.FollowResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /users/{user_id}/follow [post]
+func (h *FollowHandler) Follow(c *gin.Context) {
+	followingID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	status, err := h.followService.Follow(c.Request.Context(), viewerID(c), followingID)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to follow user")
+		return
+	}
+
+	message := "Followed successfully"
+	if status == service.FollowStatusRequested {
+		message = "Follow request sent"
+	}
+
+	c.JSON(http.StatusOK, model.FollowResponse{
+		Success:     true,
+		Message:     message,
+		IsFollowing: status == service.FollowStatusFollowing,
+		IsRequested: status == service.FollowStatusRequested,
+	})
+}
+
+// Unfollow unfollows a user, or withdraws a pending follow request
+// @Summary Unfollow user
+// @Description Unfollow a user, or withdraw a pending follow request
+// @Tags follows
+// @Security BearerAuth
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} model.FollowResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /users/{user_id}/follow [delete]
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	followingID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Unfollow(c.Request.Context(), viewerID(c), followingID); err != nil {
+		respondWithFollowError(c, err, "Failed to unfollow user")
+		return
+	}
+
+	c.JSON(http.StatusOK, model.FollowResponse{
+		Success: true,
+		Message: "Unfollowed successfully",
+	})
+}
+
+// GetFollowers lists a user's followers
+// @Summary Get followers
+// @Description List a user's followers. A private account's followers are only listed to its approved followers.
+// @Tags follows
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param limit query int false "Page size"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /users/{user_id}/followers [get]
+func (h *FollowHandler) GetFollowers(c *gin.Context) {
+	userID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+	limit, offset := followPage(c)
+
+	followers, err := h.followService.GetFollowers(c.Request.Context(), viewerID(c), userID, limit, offset)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to get followers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     followers,
+		"has_more": len(followers) == limit,
+	})
+}
+
+// GetFollowing lists the users a user follows
+// @Summary Get following
+// @Description List who a user follows. A private account's list is only shown to its approved followers.
+// @Tags follows
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param limit query int false "Page size"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /users/{user_id}/following [get]
+func (h *FollowHandler) GetFollowing(c *gin.Context) {
+	userID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+	limit, offset := followPage(c)
+
+	following, err := h.followService.GetFollowing(c.Request.Context(), viewerID(c), userID, limit, offset)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to get following")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     following,
+		"has_more": len(following) == limit,
+	})
+}
+
+// GetFollowRequests lists requests waiting for the authenticated user to approve
+// @Summary Get follow requests
+// @Description List pending requests to follow the authenticated user, newest first
+// @Tags follows
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Page size"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Router /follow-requests [get]
+func (h *FollowHandler) GetFollowRequests(c *gin.Context) {
+	limit, offset := followPage(c)
+
+	requests, err := h.followService.GetFollowRequests(c.Request.Context(), viewerID(c), limit, offset)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to get follow requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     requests,
+		"has_more": len(requests) == limit,
+	})
+}
+
+// ApproveFollowRequest lets a requester follow the authenticated user
+// @Summary Approve follow request
+// @Description Approve a pending follow request
+// @Tags follows
+// @Security BearerAuth
+// @Produce json
+// @Param requester_id path string true "Requester user ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /follow-requests/{requester_id}/approve [post]
+func (h *FollowHandler) ApproveFollowRequest(c *gin.Context) {
+	requesterID, ok := parseUserIDParam(c, "requester_id")
+	if !ok {
+		return
+	}
+
+	if err := h.followService.ApproveFollowRequest(c.Request.Context(), viewerID(c), requesterID); err != nil {
+		respondWithFollowError(c, err, "Failed to approve follow request")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Follow request approved",
+	})
+}
+
+// DenyFollowRequest turns down a pending follow request
+// @Summary Deny follow request
+// @Description Deny a pending follow request. The requester is not notified.
+// @Tags follows
+// @Security BearerAuth
+// @Produce json
+// @Param requester_id path string true "Requester user ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /follow-requests/{requester_id}/deny [post]
+func (h *FollowHandler) DenyFollowRequest(c *gin.Context) {
+	requesterID, ok := parseUserIDParam(c, "requester_id")
+	if !ok {
+		return
+	}
+
+	if err := h.followService.DenyFollowRequest(c.Request.Context(), viewerID(c), requesterID); err != nil {
+		respondWithFollowError(c, err, "Failed to deny follow request")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Follow request denied",
+	})
+}
+
+// BulkApproveFollowRequests approves several pending follow requests at once
+// @Summary Approve follow requests
+// @Description Approve the listed pending follow requests, or all of them when requester_ids is empty
+// @Tags follows
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body model.BulkApproveFollowRequestsRequest false "Requests to approve"
+// @Success 200 {object} map[string]interface{}
+// @Router /follow-requests/approve [post]
+func (h *FollowHandler) BulkApproveFollowRequests(c *gin.Context) {
+	var req model.BulkApproveFollowRequestsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	approved, err := h.followService.ApproveFollowRequests(c.Request.Context(), viewerID(c), req.RequesterIDs)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to approve follow requests")
+		return
+	}
+	if approved == nil {
+		approved = []uuid.UUID{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"approved_ids": approved,
+		},
+	})
+}
+
+// GetHiddenOwners tells other services whose content a viewer may not see, and
+// which of the owners the viewer follows.
+// Internal: the post service calls it to keep private accounts' posts from
+// anyone but their approved followers, and followers-only posts from anyone but
+// followers.
+// @Router /internal/visibility/hidden-owners [post]
+func (h *FollowHandler) GetHiddenOwners(c *gin.Context) {
+	var req model.VisibleOwnersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	hidden, err := h.followService.HiddenOwners(c.Request.Context(), req.ViewerID, req.OwnerIDs)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to check visibility")
+		return
+	}
+	if hidden == nil {
+		hidden = []uuid.UUID{}
+	}
+
+	followed, err := h.followService.FollowedOwners(c.Request.Context(), req.ViewerID, req.OwnerIDs)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to check visibility")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"hidden_owner_ids":   hidden,
+			"followed_owner_ids": followed,
+		},
+	})
+}
+
+// GetTimelineAudience lists whose home timelines a user's posts go to.
+// Internal: the post service fans posts out to these followers. With ?max=N, an
+// account with more than N followers gets only the count back.
+// @Router /internal/users/{user_id}/followers [get]
+func (h *FollowHandler) GetTimelineAudience(c *gin.Context) {
+	userID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	max := 0
+	if raw := c.Query("max"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid max",
+				"message": "max must be a positive number",
+			})
+			return
+		}
+		max = n
+	}
+
+	followers, count, err := h.followService.TimelineAudience(c.Request.Context(), userID, max)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to get followers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"user_id":         userID,
+			"followers_count": count,
+			"follower_ids":    followers,
+			"truncated":       max > 0 && count > max,
+		},
+	})
+}
+
+// GetTimelineSources lists whose posts belong in a user's home timeline.
+// Internal: the post service builds timelines from these accounts.
+// @Router /internal/users/{user_id}/following [get]
+func (h *FollowHandler) GetTimelineSources(c *gin.Context) {
+	userID, ok := parseUserIDParam(c, "user_id")
+	if !ok {
+		return
+	}
+
+	following, err := h.followService.TimelineSources(c.Request.Context(), userID)
+	if err != nil {
+		respondWithFollowError(c, err, "Failed to get following")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"user_id":       userID,
+			"following_ids": following,
+		},
+	})
+}
+
+// respondWithFollowError maps follow service errors to responses
+func respondWithFollowError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrFollowUserNotFound), errors.Is(err, service.ErrFollowRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrPrivateAccount):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Private account",
+			"message": "Follow this account to see who they follow and who follows them",
+		})
+	case errors.Is(err, service.ErrCannotFollowSelf),
+		errors.Is(err, service.ErrAlreadyFollowing),
+		errors.Is(err, service.ErrAlreadyRequested),
+		errors.Is(err, service.ErrNotFollowing):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fallback,
+			"message": err.Error(),
+		})
+	}
+}
+
+// parseUserIDParam reads a user ID path parameter, answering 400 when it isn't one
+func parseUserIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user ID",
+			"message": "The provided user ID is not valid",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// followPage reads limit and offset, defaulting to the first 20
+func followPage(c *gin.Context) (int, int) {
+	limit := 20
+	offset := 0
+
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= maxFollowPageSize {
+		limit = l
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	return limit, offset
+}
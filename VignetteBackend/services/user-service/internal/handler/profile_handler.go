@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"vignette/user-service/internal/model"
@@ -42,18 +43,13 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	// Increment profile views
-	viewerID, exists := c.Get("user_id")
-	if exists {
-		viewerUUID, _ := uuid.Parse(viewerID.(string))
-		// Only increment if viewer is not the profile owner
-		if viewerUUID != userID {
-			h.profileService.IncrementProfileViews(c.Request.Context(), userID)
-		}
-	}
-
-	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userID)
+	viewerUUID := viewerID(c)
+	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userID, viewerUUID)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileUnavailable) {
+			respondProfileUnavailable(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get profile",
 			"message": err.Error(),
@@ -61,6 +57,11 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	// Increment profile views, only if the viewer is signed in and not the profile owner
+	if viewerUUID != uuid.Nil && viewerUUID != userID {
+		h.profileService.IncrementProfileViews(c.Request.Context(), userID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    profile,
@@ -95,7 +96,7 @@ func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userUUID)
+	profile, err := h.profileService.GetProfileWithUser(c.Request.Context(), userUUID, userUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get profile",
@@ -110,6 +111,64 @@ func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
 	})
 }
 
+// ViewProfileAs previews the authenticated user's profile as another person sees it
+// @Summary View my profile as someone else
+// @Description Get the authenticated user's profile with only the fields the given viewer may see
+// @Tags profile
+// @Security BearerAuth
+// @Produce json
+// @Param viewer_id path string true "Viewer user ID"
+// @Success 200 {object} model.ProfileResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /profile/me/view-as/{viewer_id} [get]
+func (h *ProfileHandler) ViewProfileAs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	viewerUUID, err := uuid.Parse(c.Param("viewer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid viewer ID",
+			"message": "The provided viewer ID is not valid",
+		})
+		return
+	}
+
+	userUUID, _ := uuid.Parse(userID.(string))
+	profile, err := h.profileService.ViewProfileAs(c.Request.Context(), userUUID, viewerUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrViewerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Viewer not found",
+				"message": "The person you want to view your profile as does not exist",
+			})
+		case errors.Is(err, service.ErrProfileUnavailable):
+			// The preview is exactly what the viewer gets, so it can't reveal that they blocked the owner
+			respondProfileUnavailable(c)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to get profile",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profile,
+	})
+}
+
 // UpdateProfileExtended updates extended profile information
 // @Summary Update extended profile
 // @Description Update category, gender, pronouns, etc.
@@ -521,3 +580,74 @@ func (h *ProfileHandler) UpdateAvailability(c *gin.Context) {
 		"data":    profile.ToProfileResponse(),
 	})
 }
+
+// UpdateVisibility sets who can see each profile section
+// @Summary Update profile visibility
+// @Description Show each profile section to public, followers, only_me or one of your lists (list:<list id>)
+// @Tags profile
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body model.UpdateVisibilityRequest true "Section audiences"
+// @Success 200 {object} model.ProfileResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /profile/visibility [put]
+func (h *ProfileHandler) UpdateVisibility(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	var req model.UpdateVisibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userUUID, _ := uuid.Parse(userID.(string))
+	profile, err := h.profileService.UpdateVisibility(c.Request.Context(), userUUID, &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidAudience) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to update visibility",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Visibility updated successfully",
+		"data":    profile.ToProfileResponse(),
+	})
+}
+
+// respondProfileUnavailable answers for a profile hidden by a block, exactly as for one that doesn't exist
+func respondProfileUnavailable(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":   "Profile not found",
+		"message": "This profile isn't available",
+	})
+}
+
+// viewerID returns the signed-in user looking at a profile, or uuid.Nil when signed out
+func viewerID(c *gin.Context) uuid.UUID {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil
+	}
+
+	id, _ := uuid.Parse(userID.(string))
+	return id
+}
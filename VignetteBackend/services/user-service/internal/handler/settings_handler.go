@@ -6,21 +6,27 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"user-service/internal/logger"
 	"user-service/internal/repository"
+	"user-service/internal/service"
 	"user-service/internal/util"
 )
 
 type SettingsHandler struct {
-	settingsRepo *repository.SettingsRepository
-	logger       *logger.Logger
+	settingsRepo  *repository.SettingsRepository
+	blockService  *service.BlockService
+	followService *service.FollowService
+	logger        *logger.Logger
 }
 
-func NewSettingsHandler(settingsRepo *repository.SettingsRepository, logger *logger.Logger) *SettingsHandler {
+func NewSettingsHandler(settingsRepo *repository.SettingsRepository, blockService *service.BlockService, followService *service.FollowService, logger *logger.Logger) *SettingsHandler {
 	return &SettingsHandler{
-		settingsRepo: settingsRepo,
-		logger:       logger,
+		settingsRepo:  settingsRepo,
+		blockService:  blockService,
+		followService: followService,
+		logger:        logger,
 	}
 }
 
@@ -141,6 +147,15 @@ func (h *SettingsHandler) UpdatePrivacySettings(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// A public account has nothing left to approve, so whoever asked now follows
+	if !req.IsPrivateAccount {
+		if ownerID, err := uuid.Parse(user.ID); err == nil {
+			if _, err := h.followService.ApproveFollowRequests(r.Context(), ownerID, nil); err != nil {
+				h.logger.Error("Failed to approve pending follow requests", err)
+			}
+		}
+	}
+
 	util.RespondWithSuccess(w, "Privacy settings updated successfully", nil)
 }
 
@@ -247,7 +262,7 @@ func (h *SettingsHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// BlockUser blocks a user
+// BlockUser blocks a user, unfollowing them both ways
 func (h *SettingsHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
 	user, ok := r.Context().Value("user").(*repository.User)
 	if !ok {
@@ -263,7 +278,7 @@ func (h *SettingsHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.settingsRepo.BlockUser(r.Context(), user.ID, targetUserID); err != nil {
+	if err := h.blockService.Block(r.Context(), user.ID, targetUserID); err != nil {
 		h.logger.Error("Failed to block user", err)
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to block user")
 		return
@@ -283,7 +298,7 @@ func (h *SettingsHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	targetUserID := vars["userID"]
 
-	if err := h.settingsRepo.UnblockUser(r.Context(), user.ID, targetUserID); err != nil {
+	if err := h.blockService.Unblock(r.Context(), user.ID, targetUserID); err != nil {
 		h.logger.Error("Failed to unblock user", err)
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to unblock user")
 		return
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalAuthMiddleware only admits other services presenting the shared internal
+// token in X-Internal-Token. With no token configured internal routes are closed.
+func InternalAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Internal endpoint",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of audience list
+const (
+	AudienceListCloseFriends = "close_friends"
+	AudienceListCustom       = "custom"
+)
+
+// AudienceList is a named group of people a user can share with, such as Close
+// Friends for Stories or a custom list like "Family"
+type AudienceList struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	OwnerID     uuid.UUID `json:"-" db:"owner_id"`
+	Name        string    `json:"name" db:"name"`
+	Kind        string    `json:"kind" db:"kind"` // close_friends, custom
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AudienceListMember is someone in an audience list
+type AudienceListMember struct {
+	UserID            uuid.UUID `json:"user_id" db:"member_id"`
+	Username          string    `json:"username" db:"username"`
+	FullName          string    `json:"full_name" db:"full_name"`
+	ProfilePictureURL *string   `json:"profile_picture_url,omitempty" db:"profile_picture_url"`
+	AddedAt           time.Time `json:"added_at" db:"added_at"`
+}
+
+// AudienceListRequest names a list
+type AudienceListRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AudienceListMembersRequest lists people to add to or remove from a list
+type AudienceListMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1,max=500"`
+}
+
+// AudienceMembershipCheck asks whether ViewerID is in OwnerID's list ListID
+type AudienceMembershipCheck struct {
+	OwnerID  uuid.UUID `json:"owner_id"`
+	ListID   uuid.UUID `json:"list_id"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+}
+
+// AudienceCheckRequest is a batch of membership checks
+type AudienceCheckRequest struct {
+	Checks []AudienceMembershipCheck `json:"checks" binding:"max=1000"`
+}
+
+// AudienceCheckResult answers one membership check
+type AudienceCheckResult struct {
+	AudienceMembershipCheck
+	IsMember bool `json:"is_member"`
+}
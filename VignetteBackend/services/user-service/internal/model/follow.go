@@ -15,6 +15,23 @@ type Follow struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// Follow request statuses
+const (
+	FollowRequestPending  = "pending"
+	FollowRequestApproved = "approved"
+	FollowRequestDenied   = "denied"
+)
+
+// PendingFollow is a request to follow a private account
+type PendingFollow struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	RequesterID uuid.UUID  `json:"requester_id" db:"requester_id"` // Who wants to follow
+	TargetID    uuid.UUID  `json:"target_id" db:"target_id"`       // The private account
+	Status      string     `json:"status" db:"status"`             // pending, approved, denied
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+}
+
 // FollowStats represents follow statistics for a user
 type FollowStats struct {
 	UserID         uuid.UUID `json:"user_id"`
@@ -32,9 +49,22 @@ type FollowResponse struct {
 	Success       bool   `json:"success"`
 	Message       string `json:"message"`
 	IsFollowing   bool   `json:"is_following"`
+	IsRequested   bool   `json:"is_requested"` // Waiting for a private account to approve
 	FollowerCount int    `json:"follower_count,omitempty"`
 }
 
+// BulkApproveFollowRequestsRequest for API. Leaving RequesterIDs empty approves
+// every pending request.
+type BulkApproveFollowRequestsRequest struct {
+	RequesterIDs []uuid.UUID `json:"requester_ids"`
+}
+
+// VisibleOwnersRequest asks which owners' content a viewer may see
+type VisibleOwnersRequest struct {
+	ViewerID uuid.UUID   `json:"viewer_id"`
+	OwnerIDs []uuid.UUID `json:"owner_ids" binding:"required"`
+}
+
 // MutualFollowsResponse
 type MutualFollowsResponse struct {
 	MutualCount int         `json:"mutual_count"`
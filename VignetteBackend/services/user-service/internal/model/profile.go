@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,6 +29,7 @@ type Profile struct {
 	ProfileViews        int64             `json:"profile_views" db:"profile_views"`
 	ProfileViewsEnabled bool              `json:"profile_views_enabled" db:"profile_views_enabled"`
 	Availability        *Availability     `json:"availability,omitempty" db:"availability"`
+	Visibility          *ProfileVisibility `json:"visibility,omitempty" db:"visibility"`
 	CreatedAt           time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time         `json:"updated_at" db:"updated_at"`
 }
@@ -90,6 +92,68 @@ type Availability struct {
 	Message string `json:"message,omitempty"`
 }
 
+// ProfileVisibility controls who can see each profile section. Any section may
+// also be shown to one of the owner's audience lists, as "list:<list id>".
+type ProfileVisibility struct {
+	LinkInBio      string `json:"link_in_bio"`     // public, followers, only_me
+	Highlights     string `json:"highlights"`      // public, followers, only_me
+	PinnedPosts    string `json:"pinned_posts"`    // public, followers, only_me
+	ContactOptions string `json:"contact_options"` // public, followers, only_me
+	Availability   string `json:"availability"`    // public, followers, only_me
+}
+
+// Audiences a profile section can be shown to
+const (
+	AudiencePublic    = "public"
+	AudienceFollowers = "followers"
+	AudienceOnlyMe    = "only_me"
+
+	// AudienceListPrefix marks an audience that is one of the owner's lists
+	AudienceListPrefix = "list:"
+)
+
+// ListAudience is the audience for the members of an audience list
+func ListAudience(listID uuid.UUID) string {
+	return AudienceListPrefix + listID.String()
+}
+
+// AudienceListID returns the list an audience refers to, if it is a list audience
+func AudienceListID(audience string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(audience, AudienceListPrefix) {
+		return uuid.Nil, false
+	}
+	listID, err := uuid.Parse(strings.TrimPrefix(audience, AudienceListPrefix))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return listID, true
+}
+
+// ListIDs returns the audience lists the visibility settings refer to, once each
+func (p *ProfileVisibility) ListIDs() []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	listIDs := []uuid.UUID{}
+	for _, audience := range []string{p.LinkInBio, p.Highlights, p.PinnedPosts, p.ContactOptions, p.Availability} {
+		if listID, ok := AudienceListID(audience); ok && !seen[listID] {
+			seen[listID] = true
+			listIDs = append(listIDs, listID)
+		}
+	}
+	return listIDs
+}
+
+// DefaultProfileVisibility is what a new profile starts with, and what applies to
+// any section a stored ProfileVisibility leaves empty
+func DefaultProfileVisibility() *ProfileVisibility {
+	return &ProfileVisibility{
+		LinkInBio:      AudiencePublic,
+		Highlights:     AudiencePublic,
+		PinnedPosts:    AudiencePublic,
+		ContactOptions: AudiencePublic,
+		Availability:   AudiencePublic,
+	}
+}
+
 // StringArray custom type for PostgreSQL array handling
 type StringArray []string
 
@@ -203,6 +267,21 @@ func (a *Availability) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, a)
 }
 
+func (p ProfileVisibility) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *ProfileVisibility) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, p)
+}
+
 // DTOs for profile management
 
 // UpdateProfileExtendedRequest for extended profile updates
@@ -256,6 +335,16 @@ type UpdateAvailabilityRequest struct {
 	Message *string `json:"message,omitempty" binding:"omitempty,max=200"`
 }
 
+// UpdateVisibilityRequest for profile section audiences. Each field takes public,
+// followers, only_me or a list audience.
+type UpdateVisibilityRequest struct {
+	LinkInBio      *string `json:"link_in_bio,omitempty"`
+	Highlights     *string `json:"highlights,omitempty"`
+	PinnedPosts    *string `json:"pinned_posts,omitempty"`
+	ContactOptions *string `json:"contact_options,omitempty"`
+	Availability   *string `json:"availability,omitempty"`
+}
+
 // ProfileResponse for API responses
 type ProfileResponse struct {
 	ID                  uuid.UUID        `json:"id"`
@@ -275,6 +364,7 @@ type ProfileResponse struct {
 	ProfileViews        int64            `json:"profile_views"`
 	ProfileViewsEnabled bool             `json:"profile_views_enabled"`
 	Availability        *Availability    `json:"availability,omitempty"`
+	Visibility          *ProfileVisibility `json:"visibility,omitempty"` // owner only
 	CreatedAt           time.Time        `json:"created_at"`
 	UpdatedAt           time.Time        `json:"updated_at"`
 }
@@ -298,6 +388,7 @@ func (p *Profile) ToProfileResponse() *ProfileResponse {
 		ProfileViews:        p.ProfileViews,
 		ProfileViewsEnabled: p.ProfileViewsEnabled,
 		Availability:        p.Availability,
+		Visibility:          p.Visibility,
 		CreatedAt:           p.CreatedAt,
 		UpdatedAt:           p.UpdatedAt,
 	}
@@ -55,7 +55,7 @@ type AuthResponse struct {
 type UserResponse struct {
 	ID                uuid.UUID  `json:"id"`
 	Username          string     `json:"username"`
-	Email             string     `json:"email"`
+	Email             string     `json:"email,omitempty"`
 	FullName          string     `json:"full_name"`
 	PhoneNumber       *string    `json:"phone_number,omitempty"`
 	Bio               *string    `json:"bio,omitempty"`
@@ -6,6 +6,7 @@ import (
 
 	"github.com/entativa/vignette/user-service/internal/model"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type FollowRepository struct {
@@ -106,6 +107,18 @@ func (r *FollowRepository) GetFollowing(ctx context.Context, userID uuid.UUID, l
 	return following, nil
 }
 
+// GetAllFollowerIDs - Get every active follower of a user
+func (r *FollowRepository) GetAllFollowerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT follower_id FROM follows WHERE following_id = $1 AND status = 'active'`
+	return r.queryIDs(ctx, query, userID)
+}
+
+// GetAllFollowingIDs - Get every user a user actively follows
+func (r *FollowRepository) GetAllFollowingIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT following_id FROM follows WHERE follower_id = $1 AND status = 'active'`
+	return r.queryIDs(ctx, query, userID)
+}
+
 // GetFollowersCount - Count followers
 func (r *FollowRepository) GetFollowersCount(ctx context.Context, userID uuid.UUID) (int, error) {
 	query := `
@@ -157,3 +170,203 @@ func (r *FollowRepository) GetMutualFollows(ctx context.Context, userID1, userID
 
 	return mutuals, nil
 }
+
+// CreateRequest - Ask to follow a private account. Asking again after a denial
+// reopens the request.
+func (r *FollowRepository) CreateRequest(ctx context.Context, requesterID, targetID uuid.UUID) error {
+	query := `
+		INSERT INTO follow_requests (requester_id, target_id, status, created_at)
+		VALUES ($1, $2, 'pending', NOW())
+		ON CONFLICT (requester_id, target_id) DO UPDATE
+		SET status = 'pending', created_at = NOW(), responded_at = NULL
+		WHERE follow_requests.status != 'pending'
+	`
+
+	_, err := r.db.ExecContext(ctx, query, requesterID, targetID)
+	return err
+}
+
+// CancelRequest - Withdraw a pending follow request. Reports whether there was one.
+func (r *FollowRepository) CancelRequest(ctx context.Context, requesterID, targetID uuid.UUID) (bool, error) {
+	query := `DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2 AND status = 'pending'`
+
+	result, err := r.db.ExecContext(ctx, query, requesterID, targetID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// HasPendingRequest - Check if user A is waiting for user B to approve a follow
+func (r *FollowRepository) HasPendingRequest(ctx context.Context, requesterID, targetID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM follow_requests
+			WHERE requester_id = $1 AND target_id = $2 AND status = 'pending'
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, requesterID, targetID).Scan(&exists)
+	return exists, err
+}
+
+// GetPendingRequests - Get requests waiting for a private account to respond, newest first
+func (r *FollowRepository) GetPendingRequests(ctx context.Context, targetID uuid.UUID, limit, offset int) ([]*model.PendingFollow, error) {
+	query := `
+		SELECT id, requester_id, target_id, status, created_at, responded_at
+		FROM follow_requests
+		WHERE target_id = $1 AND status = 'pending'
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, targetID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*model.PendingFollow{}
+	for rows.Next() {
+		request := &model.PendingFollow{}
+		if err := rows.Scan(
+			&request.ID, &request.RequesterID, &request.TargetID,
+			&request.Status, &request.CreatedAt, &request.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
+
+// ApproveRequests - Approve pending requests to follow targetID and create the
+// follows, in one statement. With no requesterIDs every pending request is
+// approved. Returns the requesters now following.
+func (r *FollowRepository) ApproveRequests(ctx context.Context, targetID uuid.UUID, requesterIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		WITH approved AS (
+			UPDATE follow_requests
+			SET status = 'approved', responded_at = NOW()
+			WHERE target_id = $1 AND status = 'pending'
+			  AND (cardinality($2::uuid[]) = 0 OR requester_id = ANY($2::uuid[]))
+			RETURNING requester_id
+		)
+		INSERT INTO follows (follower_id, following_id, status, created_at)
+		SELECT requester_id, $1, 'active', NOW() FROM approved
+		ON CONFLICT (follower_id, following_id) DO UPDATE SET status = 'active'
+		RETURNING follower_id
+	`
+
+	ids := make([]string, 0, len(requesterIDs))
+	for _, id := range requesterIDs {
+		ids = append(ids, id.String())
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, targetID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approved []uuid.UUID
+	for rows.Next() {
+		var followerID uuid.UUID
+		if err := rows.Scan(&followerID); err != nil {
+			return nil, err
+		}
+		approved = append(approved, followerID)
+	}
+
+	return approved, rows.Err()
+}
+
+// DenyRequest - Deny a pending follow request. Reports whether there was one.
+func (r *FollowRepository) DenyRequest(ctx context.Context, targetID, requesterID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE follow_requests
+		SET status = 'denied', responded_at = NOW()
+		WHERE target_id = $1 AND requester_id = $2 AND status = 'pending'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, targetID, requesterID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetHiddenOwners - Of ownerIDs, get the private accounts viewerID does not follow.
+// A signed-out viewer (uuid.Nil) follows no one.
+func (r *FollowRepository) GetHiddenOwners(ctx context.Context, viewerID uuid.UUID, ownerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT u.id FROM users u
+		WHERE u.id = ANY($2::uuid[]) AND u.is_private AND u.id != $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM follows f
+			WHERE f.follower_id = $1 AND f.following_id = u.id AND f.status = 'active'
+		  )
+	`
+
+	ids := make([]string, 0, len(ownerIDs))
+	for _, id := range ownerIDs {
+		ids = append(ids, id.String())
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, viewerID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hidden []uuid.UUID
+	for rows.Next() {
+		var ownerID uuid.UUID
+		if err := rows.Scan(&ownerID); err != nil {
+			return nil, err
+		}
+		hidden = append(hidden, ownerID)
+	}
+
+	return hidden, rows.Err()
+}
+
+// GetFollowedOwners - Of ownerIDs, get the accounts viewerID actively follows
+func (r *FollowRepository) GetFollowedOwners(ctx context.Context, viewerID uuid.UUID, ownerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT following_id FROM follows
+		WHERE follower_id = $1 AND following_id = ANY($2::uuid[]) AND status = 'active'
+	`
+
+	ids := make([]string, 0, len(ownerIDs))
+	for _, id := range ownerIDs {
+		ids = append(ids, id.String())
+	}
+
+	return r.queryIDs(ctx, query, viewerID, pq.Array(ids))
+}
+
+func (r *FollowRepository) queryIDs(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
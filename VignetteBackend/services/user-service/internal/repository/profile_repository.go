@@ -36,9 +36,9 @@ func (r *ProfileRepository) CreateProfile(ctx context.Context, profile *model.Pr
 			id, user_id, category, category_type, gender, pronouns,
 			link_in_bio, highlights, pinned_posts, profile_badges,
 			contact_options, creator_insights, business_info,
-			profile_views, profile_views_enabled, availability,
+			profile_views, profile_views_enabled, availability, visibility,
 			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
 	linkInBioJSON, _ := json.Marshal(profile.LinkInBio)
@@ -49,13 +49,14 @@ func (r *ProfileRepository) CreateProfile(ctx context.Context, profile *model.Pr
 	creatorInsightsJSON, _ := json.Marshal(profile.CreatorInsights)
 	businessInfoJSON, _ := json.Marshal(profile.BusinessInfo)
 	availabilityJSON, _ := json.Marshal(profile.Availability)
+	visibilityJSON, _ := json.Marshal(profile.Visibility)
 
 	_, err := r.db.ExecContext(ctx, query,
 		profile.ID, profile.UserID, profile.Category, profile.CategoryType,
 		profile.Gender, profile.Pronouns, linkInBioJSON, highlightsJSON,
 		pinnedPostsJSON, profileBadgesJSON, contactOptionsJSON, creatorInsightsJSON,
 		businessInfoJSON, profile.ProfileViews, profile.ProfileViewsEnabled,
-		availabilityJSON, profile.CreatedAt, profile.UpdatedAt,
+		availabilityJSON, visibilityJSON, profile.CreatedAt, profile.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create profile: %w", err)
@@ -81,7 +82,7 @@ func (r *ProfileRepository) GetProfileByUserID(ctx context.Context, userID uuid.
 		SELECT id, user_id, category, category_type, gender, pronouns,
 		       link_in_bio, highlights, pinned_posts, profile_badges,
 		       contact_options, creator_insights, business_info,
-		       profile_views, profile_views_enabled, availability,
+		       profile_views, profile_views_enabled, availability, visibility,
 		       created_at, updated_at
 		FROM profiles
 		WHERE user_id = $1
@@ -89,13 +90,14 @@ func (r *ProfileRepository) GetProfileByUserID(ctx context.Context, userID uuid.
 
 	var linkInBioJSON, highlightsJSON, pinnedPostsJSON, profileBadgesJSON []byte
 	var contactOptionsJSON, creatorInsightsJSON, businessInfoJSON, availabilityJSON []byte
+	var visibilityJSON []byte
 
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&profile.ID, &profile.UserID, &profile.Category, &profile.CategoryType,
 		&profile.Gender, &profile.Pronouns, &linkInBioJSON, &highlightsJSON,
 		&pinnedPostsJSON, &profileBadgesJSON, &contactOptionsJSON, &creatorInsightsJSON,
 		&businessInfoJSON, &profile.ProfileViews, &profile.ProfileViewsEnabled,
-		&availabilityJSON, &profile.CreatedAt, &profile.UpdatedAt,
+		&availabilityJSON, &visibilityJSON, &profile.CreatedAt, &profile.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -130,6 +132,9 @@ func (r *ProfileRepository) GetProfileByUserID(ctx context.Context, userID uuid.
 	if len(availabilityJSON) > 0 {
 		json.Unmarshal(availabilityJSON, &profile.Availability)
 	}
+	if len(visibilityJSON) > 0 {
+		json.Unmarshal(visibilityJSON, &profile.Visibility)
+	}
 
 	// Cache the profile
 	r.cache.Set(ctx, cacheKey, &profile, 10*time.Minute)
@@ -147,7 +152,7 @@ func (r *ProfileRepository) UpdateProfile(ctx context.Context, profile *model.Pr
 			link_in_bio = $6, highlights = $7, pinned_posts = $8, profile_badges = $9,
 			contact_options = $10, creator_insights = $11, business_info = $12,
 			profile_views = $13, profile_views_enabled = $14, availability = $15,
-			updated_at = $16
+			visibility = $16, updated_at = $17
 		WHERE user_id = $1
 	`
 
@@ -159,13 +164,14 @@ func (r *ProfileRepository) UpdateProfile(ctx context.Context, profile *model.Pr
 	creatorInsightsJSON, _ := json.Marshal(profile.CreatorInsights)
 	businessInfoJSON, _ := json.Marshal(profile.BusinessInfo)
 	availabilityJSON, _ := json.Marshal(profile.Availability)
+	visibilityJSON, _ := json.Marshal(profile.Visibility)
 
 	_, err := r.db.ExecContext(ctx, query,
 		profile.UserID, profile.Category, profile.CategoryType, profile.Gender,
 		profile.Pronouns, linkInBioJSON, highlightsJSON, pinnedPostsJSON,
 		profileBadgesJSON, contactOptionsJSON, creatorInsightsJSON, businessInfoJSON,
 		profile.ProfileViews, profile.ProfileViewsEnabled, availabilityJSON,
-		profile.UpdatedAt,
+		visibilityJSON, profile.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update profile: %w", err)
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -200,7 +201,8 @@ func (r *SettingsRepository) UpdateAccountSettings(ctx context.Context, userID s
 	return err
 }
 
-// UpdatePrivacySettings updates privacy settings
+// UpdatePrivacySettings updates privacy settings, keeping users.is_private (which
+// follows are checked against) in step with is_private_account
 func (r *SettingsRepository) UpdatePrivacySettings(ctx context.Context, userID string, req interface{}) error {
 	reqMap := req.(*struct {
 		IsPrivateAccount          bool   `json:"is_private_account"`
@@ -244,7 +246,13 @@ func (r *SettingsRepository) UpdatePrivacySettings(ctx context.Context, userID s
 			include_in_recommendations = $11
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query,
 		userID,
 		reqMap.IsPrivateAccount,
 		reqMap.ShowActivityStatus,
@@ -257,8 +265,16 @@ func (r *SettingsRepository) UpdatePrivacySettings(ctx context.Context, userID s
 		reqMap.SimilarAccountSuggestions,
 		reqMap.IncludeInRecommendations,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	_, err = tx.ExecContext(ctx, `UPDATE users SET is_private = $2, updated_at = NOW() WHERE id = $1`, userID, reqMap.IsPrivateAccount)
+	if err != nil {
+		return fmt.Errorf("failed to update account privacy: %w", err)
+	}
+
+	return tx.Commit()
 }
 
 // UpdateNotificationSettings updates notification preferences
@@ -402,11 +418,40 @@ func (r *SettingsRepository) GetBlockedUsers(ctx context.Context, userID string)
 	return blockedUsers, nil
 }
 
-// BlockUser blocks a user
+// BlockUser blocks a user, ending any friendship, pending friend request, follow or
+// follow request between the two in the same transaction
 func (r *SettingsRepository) BlockUser(ctx context.Context, userID, targetUserID string) error {
-	query := `INSERT INTO blocked_users (user_id, blocked_user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-	_, err := r.db.ExecContext(ctx, query, userID, targetUserID)
-	return err
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"block", `INSERT INTO blocked_users (user_id, blocked_user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`},
+		{"friendship", `DELETE FROM friendships WHERE user_id_1 = LEAST($1::uuid, $2::uuid) AND user_id_2 = GREATEST($1::uuid, $2::uuid)`},
+		{"friend requests", `
+			DELETE FROM friend_requests
+			WHERE status = 'pending'
+			  AND ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))`},
+		{"follows", `
+			DELETE FROM follows
+			WHERE (follower_id = $1 AND following_id = $2) OR (follower_id = $2 AND following_id = $1)`},
+		{"follow requests", `
+			DELETE FROM follow_requests
+			WHERE (requester_id = $1 AND target_id = $2) OR (requester_id = $2 AND target_id = $1)`},
+	}
+
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, userID, targetUserID); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", step.name, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // UnblockUser unblocks a user
@@ -416,6 +461,47 @@ func (r *SettingsRepository) UnblockUser(ctx context.Context, userID, targetUser
 	return err
 }
 
+// IsBlockedEitherWay reports whether either user has blocked the other
+func (r *SettingsRepository) IsBlockedEitherWay(ctx context.Context, userID, otherUserID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM blocked_users
+			WHERE (user_id = $1 AND blocked_user_id = $2)
+			   OR (user_id = $2 AND blocked_user_id = $1)
+		)
+	`
+
+	var blocked bool
+	err := r.db.QueryRowContext(ctx, query, userID, otherUserID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlockRelations returns the IDs of everyone the user has blocked or been blocked by
+func (r *SettingsRepository) GetBlockRelations(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT blocked_user_id FROM blocked_users WHERE user_id = $1
+		UNION
+		SELECT user_id FROM blocked_users WHERE blocked_user_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
 // VerifyPassword verifies the user's current password
 func (r *SettingsRepository) VerifyPassword(ctx context.Context, userID, password string) (bool, error) {
 	var hashedPassword string
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"vignette/user-service/internal/model"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var (
@@ -107,6 +109,69 @@ func (r *UserRepository) FindByID(id uuid.UUID) (*model.User, error) {
 	return user, nil
 }
 
+// FindByIDs finds the users with the given IDs, in no particular order. IDs with
+// no user, or a deleted one, are left out. Follower and following counts are
+// counted from active follows rather than read from the users table.
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT u.id, u.username, u.email, u.full_name, u.password_hash, u.phone_number,
+		       u.bio, u.website, u.profile_picture_url, u.is_private, u.is_verified,
+		       u.is_active, u.is_deleted,
+		       (SELECT COUNT(*) FROM follows f WHERE f.following_id = u.id AND f.status = 'active'),
+		       (SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id AND f.status = 'active'),
+		       u.posts_count, u.last_login_at, u.created_at, u.updated_at
+		FROM users u
+		WHERE u.id = ANY($1::uuid[]) AND u.is_deleted = false
+	`
+
+	idStrings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrings = append(idStrings, id.String())
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(idStrings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.FullName,
+			&user.Password,
+			&user.PhoneNumber,
+			&user.Bio,
+			&user.Website,
+			&user.ProfilePictureURL,
+			&user.IsPrivate,
+			&user.IsVerified,
+			&user.IsActive,
+			&user.IsDeleted,
+			&user.FollowersCount,
+			&user.FollowingCount,
+			&user.PostsCount,
+			&user.LastLoginAt,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 // FindByEmail finds a user by email
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 	query := `
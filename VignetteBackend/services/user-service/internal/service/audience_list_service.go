@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"vignette/user-service/internal/model"
+	"vignette/user-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxAudienceListsPerUser   = 50
+	maxAudienceListMembers    = 5000
+	maxAudienceListNameLength = 50
+
+	// CloseFriendsListAlias may be used in place of the Close Friends list's ID;
+	// the list is created the first time it is used
+	CloseFriendsListAlias = "close-friends"
+	closeFriendsListName  = "Close Friends"
+)
+
+var (
+	ErrAudienceListNotFound    = errors.New("audience list not found")
+	ErrAudienceListNameTaken   = errors.New("you already have a list with this name")
+	ErrInvalidAudienceListName = errors.New("list name must be between 1 and 50 characters")
+	ErrTooManyAudienceLists    = errors.New("you can have at most 50 lists")
+	ErrAudienceListFull        = errors.New("a list can have at most 5000 members")
+	ErrCloseFriendsListFixed   = errors.New("the Close Friends list can't be renamed or deleted")
+	ErrCannotAddSelfToList     = errors.New("you can't add yourself to a list")
+)
+
+// AudienceListService manages who a user shares with beyond their followers:
+// Close Friends for Stories, and lists of their own such as "Family". A list's
+// ID can be the audience of a post, a story or a profile section; other
+// services ask CheckMemberships whether a viewer is in it. Members are never
+// told which lists they are in.
+type AudienceListService struct {
+	listRepo *repository.AudienceListRepository
+}
+
+func NewAudienceListService(listRepo *repository.AudienceListRepository) *AudienceListService {
+	return &AudienceListService{listRepo: listRepo}
+}
+
+// ListLists - All of the user's lists, Close Friends first
+func (s *AudienceListService) ListLists(ctx context.Context, ownerID uuid.UUID) ([]*model.AudienceList, error) {
+	return s.listRepo.ListLists(ctx, ownerID)
+}
+
+// GetList - One of the user's lists, by ID or CloseFriendsListAlias
+func (s *AudienceListService) GetList(ctx context.Context, ownerID uuid.UUID, listRef string) (*model.AudienceList, error) {
+	return s.resolveList(ctx, ownerID, listRef)
+}
+
+// CreateList - Make a new, empty list
+func (s *AudienceListService) CreateList(ctx context.Context, ownerID uuid.UUID, name string) (*model.AudienceList, error) {
+	name, err := s.validateName(ctx, ownerID, name, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.listRepo.CountLists(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAudienceListsPerUser {
+		return nil, ErrTooManyAudienceLists
+	}
+
+	list := &model.AudienceList{
+		ID:        uuid.New(),
+		OwnerID:   ownerID,
+		Name:      name,
+		Kind:      model.AudienceListCustom,
+		CreatedAt: time.Now(),
+	}
+	list.UpdatedAt = list.CreatedAt
+
+	if err := s.listRepo.CreateList(ctx, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// RenameList - Rename one of the user's lists. Close Friends keeps its name.
+func (s *AudienceListService) RenameList(ctx context.Context, ownerID uuid.UUID, listRef, name string) (*model.AudienceList, error) {
+	list, err := s.resolveList(ctx, ownerID, listRef)
+	if err != nil {
+		return nil, err
+	}
+	if list.Kind == model.AudienceListCloseFriends {
+		return nil, ErrCloseFriendsListFixed
+	}
+
+	name, err = s.validateName(ctx, ownerID, name, list.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listRepo.RenameList(ctx, list.ID, ownerID, name); err != nil {
+		return nil, mapAudienceListError(err)
+	}
+
+	list.Name = name
+	list.UpdatedAt = time.Now()
+	return list, nil
+}
+
+// DeleteList - Delete one of the user's lists. Anything shared with it is then
+// seen by no one but the owner.
+func (s *AudienceListService) DeleteList(ctx context.Context, ownerID uuid.UUID, listRef string) error {
+	list, err := s.resolveList(ctx, ownerID, listRef)
+	if err != nil {
+		return err
+	}
+	if list.Kind == model.AudienceListCloseFriends {
+		return ErrCloseFriendsListFixed
+	}
+
+	return mapAudienceListError(s.listRepo.DeleteList(ctx, list.ID, ownerID))
+}
+
+// ListMembers - A page of a list's members
+func (s *AudienceListService) ListMembers(ctx context.Context, ownerID uuid.UUID, listRef string, limit, offset int) ([]*model.AudienceListMember, error) {
+	list, err := s.resolveList(ctx, ownerID, listRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listRepo.ListMembers(ctx, list.ID, limit, offset)
+}
+
+// AddMembers - Add people to a list. People already in it, and accounts that no
+// longer exist, are skipped. Returns how many were added.
+func (s *AudienceListService) AddMembers(ctx context.Context, ownerID uuid.UUID, listRef string, memberIDs []uuid.UUID) (int, error) {
+	for _, memberID := range memberIDs {
+		if memberID == ownerID {
+			return 0, ErrCannotAddSelfToList
+		}
+	}
+
+	list, err := s.resolveList(ctx, ownerID, listRef)
+	if err != nil {
+		return 0, err
+	}
+	if list.MemberCount+len(memberIDs) > maxAudienceListMembers {
+		return 0, ErrAudienceListFull
+	}
+
+	return s.listRepo.AddMembers(ctx, list.ID, memberIDs)
+}
+
+// RemoveMembers - Take people out of a list. Returns how many were removed.
+func (s *AudienceListService) RemoveMembers(ctx context.Context, ownerID uuid.UUID, listRef string, memberIDs []uuid.UUID) (int, error) {
+	list, err := s.resolveList(ctx, ownerID, listRef)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.listRepo.RemoveMembers(ctx, list.ID, memberIDs)
+}
+
+// CheckMemberships - Answer a batch of "is the viewer in list X of owner Y"
+// questions for other services, in the order asked. An owner is treated as being
+// in their own lists.
+func (s *AudienceListService) CheckMemberships(ctx context.Context, checks []model.AudienceMembershipCheck) ([]bool, error) {
+	results, err := s.listRepo.CheckMemberships(ctx, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, check := range checks {
+		if check.ViewerID == check.OwnerID {
+			results[i] = true
+		}
+	}
+	return results, nil
+}
+
+// MemberOfLists - Which of the owner's lists viewerID is in
+func (s *AudienceListService) MemberOfLists(ctx context.Context, ownerID, viewerID uuid.UUID, listIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	checks := make([]model.AudienceMembershipCheck, 0, len(listIDs))
+	for _, listID := range listIDs {
+		checks = append(checks, model.AudienceMembershipCheck{OwnerID: ownerID, ListID: listID, ViewerID: viewerID})
+	}
+
+	results, err := s.CheckMemberships(ctx, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	member := make(map[uuid.UUID]bool, len(listIDs))
+	for i, listID := range listIDs {
+		member[listID] = results[i]
+	}
+	return member, nil
+}
+
+// CheckOwnership - ErrAudienceListNotFound unless every one of listIDs is the
+// owner's, so nobody can share with someone else's list
+func (s *AudienceListService) CheckOwnership(ctx context.Context, ownerID uuid.UUID, listIDs []uuid.UUID) error {
+	if len(listIDs) == 0 {
+		return nil
+	}
+
+	owned, err := s.listRepo.OwnedListIDs(ctx, ownerID, listIDs)
+	if err != nil {
+		return err
+	}
+
+	ownedSet := make(map[uuid.UUID]bool, len(owned))
+	for _, id := range owned {
+		ownedSet[id] = true
+	}
+	for _, listID := range listIDs {
+		if !ownedSet[listID] {
+			return ErrAudienceListNotFound
+		}
+	}
+	return nil
+}
+
+// resolveList finds one of the owner's lists by ID, or their Close Friends list by
+// CloseFriendsListAlias, creating it if they have never used it
+func (s *AudienceListService) resolveList(ctx context.Context, ownerID uuid.UUID, listRef string) (*model.AudienceList, error) {
+	if listRef != CloseFriendsListAlias {
+		listID, err := uuid.Parse(listRef)
+		if err != nil {
+			return nil, ErrAudienceListNotFound
+		}
+		list, err := s.listRepo.GetList(ctx, listID, ownerID)
+		return list, mapAudienceListError(err)
+	}
+
+	list, err := s.listRepo.GetCloseFriendsList(ctx, ownerID)
+	if !errors.Is(err, repository.ErrAudienceListNotFound) {
+		return list, err
+	}
+
+	list = &model.AudienceList{
+		ID:        uuid.New(),
+		OwnerID:   ownerID,
+		Name:      closeFriendsListName,
+		Kind:      model.AudienceListCloseFriends,
+		CreatedAt: time.Now(),
+	}
+	list.UpdatedAt = list.CreatedAt
+	if err := s.listRepo.CreateList(ctx, list); err != nil {
+		// Lost a race with another request creating it
+		if existing, getErr := s.listRepo.GetCloseFriendsList(ctx, ownerID); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *AudienceListService) validateName(ctx context.Context, ownerID uuid.UUID, name string, excludeID uuid.UUID) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxAudienceListNameLength {
+		return "", ErrInvalidAudienceListName
+	}
+	if strings.EqualFold(name, closeFriendsListName) {
+		return "", ErrAudienceListNameTaken
+	}
+
+	taken, err := s.listRepo.NameExists(ctx, ownerID, name, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrAudienceListNameTaken
+	}
+	return name, nil
+}
+
+func mapAudienceListError(err error) error {
+	if errors.Is(err, repository.ErrAudienceListNotFound) {
+		return ErrAudienceListNotFound
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"user-service/internal/repository"
+)
+
+// Events published on "user-events" when a block is created or lifted, so other
+// services can drop cached feeds and search results
+const (
+	UserBlockedEvent   = "user.blocked"
+	UserUnblockedEvent = "user.unblocked"
+)
+
+// blockCacheMarker is kept in every cached block set, so that a user who has
+// no blocks can be told apart from one whose blocks are not cached
+const blockCacheMarker = "-"
+
+// BlockService is the one place that answers whether two users have blocked each
+// other. A block works both ways: neither user can follow or find the other. Each user's block relations are cached in Redis as a set; without Redis
+// every check goes to the database.
+type BlockService struct {
+	settingsRepo *repository.SettingsRepository
+	redis        *redis.Client
+	kafka        *KafkaProducer
+	cacheTTL     time.Duration
+}
+
+// NewBlockService creates a new block service. redis may be nil.
+func NewBlockService(settingsRepo *repository.SettingsRepository, redis *redis.Client, kafka *KafkaProducer, cacheTTL time.Duration) *BlockService {
+	return &BlockService{
+		settingsRepo: settingsRepo,
+		redis:        redis,
+		kafka:        kafka,
+		cacheTTL:     cacheTTL,
+	}
+}
+
+// Block blocks targetUserID for userID, ending any follow between them
+func (s *BlockService) Block(ctx context.Context, userID, targetUserID string) error {
+	if err := s.settingsRepo.BlockUser(ctx, userID, targetUserID); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID, targetUserID)
+	s.publish(UserBlockedEvent, userID, targetUserID)
+	return nil
+}
+
+// Unblock lifts userID's block on targetUserID. A block the other way stays.
+func (s *BlockService) Unblock(ctx context.Context, userID, targetUserID string) error {
+	if err := s.settingsRepo.UnblockUser(ctx, userID, targetUserID); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID, targetUserID)
+	s.publish(UserUnblockedEvent, userID, targetUserID)
+	return nil
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (s *BlockService) IsBlocked(ctx context.Context, userID, otherUserID string) (bool, error) {
+	if userID == otherUserID {
+		return false, nil
+	}
+	if s.redis == nil {
+		return s.settingsRepo.IsBlockedEitherWay(ctx, userID, otherUserID)
+	}
+
+	blocked, err := s.FilterBlocked(ctx, userID, []string{otherUserID})
+	if err != nil {
+		return false, err
+	}
+	return len(blocked) > 0, nil
+}
+
+// FilterBlocked returns those of otherUserIDs that userID has blocked or been blocked by
+func (s *BlockService) FilterBlocked(ctx context.Context, userID string, otherUserIDs []string) ([]string, error) {
+	related, err := s.BlockedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	relatedSet := make(map[string]bool, len(related))
+	for _, id := range related {
+		relatedSet[id] = true
+	}
+
+	blocked := []string{}
+	for _, id := range otherUserIDs {
+		if relatedSet[id] {
+			blocked = append(blocked, id)
+		}
+	}
+	return blocked, nil
+}
+
+// BlockedUserIDs returns everyone userID has blocked or been blocked by
+func (s *BlockService) BlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	key := blockCacheKey(userID)
+
+	if s.redis != nil {
+		members, err := s.redis.SMembers(ctx, key).Result()
+		if err != nil {
+			log.Printf("Failed to read cached blocks for %s: %v", userID, err)
+		} else if len(members) > 0 {
+			userIDs := make([]string, 0, len(members)-1)
+			for _, member := range members {
+				if member != blockCacheMarker {
+					userIDs = append(userIDs, member)
+				}
+			}
+			return userIDs, nil
+		}
+	}
+
+	userIDs, err := s.settingsRepo.GetBlockRelations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+
+	s.cache(ctx, key, userIDs)
+	return userIDs, nil
+}
+
+func (s *BlockService) cache(ctx context.Context, key string, userIDs []string) {
+	if s.redis == nil {
+		return
+	}
+
+	members := make([]interface{}, 0, len(userIDs)+1)
+	members = append(members, blockCacheMarker)
+	for _, id := range userIDs {
+		members = append(members, id)
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, s.cacheTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to cache blocks: %v", err)
+	}
+}
+
+// invalidate drops both users' cached block sets; a failure here leaves them stale
+// until they expire
+func (s *BlockService) invalidate(ctx context.Context, userID, otherUserID string) {
+	if s.redis == nil {
+		return
+	}
+	if err := s.redis.Del(ctx, blockCacheKey(userID), blockCacheKey(otherUserID)).Err(); err != nil {
+		log.Printf("Failed to invalidate cached blocks for %s and %s: %v", userID, otherUserID, err)
+	}
+}
+
+func (s *BlockService) publish(eventType, userID, targetUserID string) {
+	event := map[string]interface{}{
+		"event_type":      eventType,
+		"user_id":         userID,
+		"blocked_user_id": targetUserID,
+		"timestamp":       time.Now(),
+	}
+
+	if err := s.kafka.PublishEvent("user-events", event); err != nil {
+		log.Printf("Failed to publish %s for %s: %v", eventType, userID, err)
+	}
+}
+
+func blockCacheKey(userID string) string {
+	return "blocks:" + userID
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/entativa/vignette/user-service/internal/model"
@@ -10,44 +12,99 @@ import (
 	"github.com/google/uuid"
 )
 
+// Events published on "user-events" as follows change. follow.approved means a
+// private account accepted a request, and the requester now follows it.
+const (
+	UserFollowedEvent    = "user.followed"
+	UserUnfollowedEvent  = "user.unfollowed"
+	FollowRequestedEvent = "follow.requested"
+	FollowApprovedEvent  = "follow.approved"
+)
+
+// What Follow did: followed straight away, or asked a private account
+const (
+	FollowStatusFollowing = "following"
+	FollowStatusRequested = "requested"
+)
+
+var (
+	ErrCannotFollowSelf      = errors.New("cannot follow yourself")
+	ErrFollowUserNotFound    = errors.New("user not found")
+	ErrAlreadyFollowing      = errors.New("already following this user")
+	ErrAlreadyRequested      = errors.New("follow request already sent")
+	ErrNotFollowing          = errors.New("not following this user")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+	ErrPrivateAccount        = errors.New("this account is private")
+)
+
 type FollowService struct {
-	followRepo *repository.FollowRepository
-	userRepo   *repository.UserRepository
-	kafka      *KafkaProducer
+	followRepo   *repository.FollowRepository
+	userRepo     *repository.UserRepository
+	blockService *BlockService
+	kafka        *KafkaProducer
 }
 
 func NewFollowService(
 	followRepo *repository.FollowRepository,
 	userRepo *repository.UserRepository,
+	blockService *BlockService,
 	kafka *KafkaProducer,
 ) *FollowService {
 	return &FollowService{
-		followRepo: followRepo,
-		userRepo:   userRepo,
-		kafka:      kafka,
+		followRepo:   followRepo,
+		userRepo:     userRepo,
+		blockService: blockService,
+		kafka:        kafka,
 	}
 }
 
-// Follow - Follow a user (Instagram-style, instant)
-func (s *FollowService) Follow(ctx context.Context, followerID, followingID uuid.UUID) error {
+// Follow - Follow a user. Public accounts are followed instantly (Instagram-style);
+// following a private account sends a request its owner has to approve.
+func (s *FollowService) Follow(ctx context.Context, followerID, followingID uuid.UUID) (string, error) {
 	// Validate: Can't follow yourself
 	if followerID == followingID {
-		return fmt.Errorf("cannot follow yourself")
+		return "", ErrCannotFollowSelf
 	}
 
 	// Check if user exists
-	_, err := s.userRepo.GetByID(ctx, followingID)
+	target, err := s.userRepo.FindByID(followingID)
 	if err != nil {
-		return fmt.Errorf("user not found")
+		return "", ErrFollowUserNotFound
+	}
+
+	// Blocked users can't find each other, so they look the same as a missing user
+	blocked, err := s.blockService.IsBlocked(ctx, followerID.String(), followingID.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return "", ErrFollowUserNotFound
 	}
 
 	// Check if already following
 	isFollowing, err := s.followRepo.IsFollowing(ctx, followerID, followingID)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if isFollowing {
-		return fmt.Errorf("already following this user")
+		return "", ErrAlreadyFollowing
+	}
+
+	if target.IsPrivate {
+		requested, err := s.followRepo.HasPendingRequest(ctx, followerID, followingID)
+		if err != nil {
+			return "", err
+		}
+		if requested {
+			return "", ErrAlreadyRequested
+		}
+
+		if err := s.followRepo.CreateRequest(ctx, followerID, followingID); err != nil {
+			return "", err
+		}
+
+		s.publish(FollowRequestedEvent, followerID, followingID)
+		return FollowStatusRequested, nil
 	}
 
 	// Create follow relationship
@@ -61,16 +118,15 @@ func (s *FollowService) Follow(ctx context.Context, followerID, followingID uuid
 
 	err = s.followRepo.Create(ctx, follow)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Publish Kafka event
-	s.kafka.PublishFollowEvent(followerID, followingID, "followed")
+	s.publish(UserFollowedEvent, followerID, followingID)
 
-	return nil
+	return FollowStatusFollowing, nil
 }
 
-// Unfollow - Unfollow a user
+// Unfollow - Unfollow a user, or withdraw a request that is still pending
 func (s *FollowService) Unfollow(ctx context.Context, followerID, followingID uuid.UUID) error {
 	// Check if following
 	isFollowing, err := s.followRepo.IsFollowing(ctx, followerID, followingID)
@@ -78,7 +134,14 @@ func (s *FollowService) Unfollow(ctx context.Context, followerID, followingID uu
 		return err
 	}
 	if !isFollowing {
-		return fmt.Errorf("not following this user")
+		cancelled, err := s.followRepo.CancelRequest(ctx, followerID, followingID)
+		if err != nil {
+			return err
+		}
+		if !cancelled {
+			return ErrNotFollowing
+		}
+		return nil
 	}
 
 	// Remove follow
@@ -87,22 +150,157 @@ func (s *FollowService) Unfollow(ctx context.Context, followerID, followingID uu
 		return err
 	}
 
-	// Publish Kafka event
-	s.kafka.PublishFollowEvent(followerID, followingID, "unfollowed")
+	s.publish(UserUnfollowedEvent, followerID, followingID)
 
 	return nil
 }
 
-// GetFollowers - Get list of followers
-func (s *FollowService) GetFollowers(ctx context.Context, userID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+// GetFollowers - Get list of followers, as seen by viewerID (uuid.Nil when signed out)
+func (s *FollowService) GetFollowers(ctx context.Context, viewerID, userID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+	if err := s.checkCanView(ctx, viewerID, userID); err != nil {
+		return nil, err
+	}
 	return s.followRepo.GetFollowers(ctx, userID, limit, offset)
 }
 
-// GetFollowing - Get list of users being followed
-func (s *FollowService) GetFollowing(ctx context.Context, userID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+// GetFollowing - Get list of users being followed, as seen by viewerID (uuid.Nil when signed out)
+func (s *FollowService) GetFollowing(ctx context.Context, viewerID, userID uuid.UUID, limit, offset int) ([]uuid.UUID, error) {
+	if err := s.checkCanView(ctx, viewerID, userID); err != nil {
+		return nil, err
+	}
 	return s.followRepo.GetFollowing(ctx, userID, limit, offset)
 }
 
+// GetFollowRequests - Get requests waiting for the user to approve, newest first
+func (s *FollowService) GetFollowRequests(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.PendingFollow, error) {
+	return s.followRepo.GetPendingRequests(ctx, userID, limit, offset)
+}
+
+// ApproveFollowRequest - Let requesterID follow the user
+func (s *FollowService) ApproveFollowRequest(ctx context.Context, userID, requesterID uuid.UUID) error {
+	approved, err := s.ApproveFollowRequests(ctx, userID, []uuid.UUID{requesterID})
+	if err != nil {
+		return err
+	}
+	if len(approved) == 0 {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+// ApproveFollowRequests - Approve several pending requests at once, or all of them
+// when requesterIDs is empty. Returns who now follows the user; IDs without a
+// pending request are skipped.
+func (s *FollowService) ApproveFollowRequests(ctx context.Context, userID uuid.UUID, requesterIDs []uuid.UUID) ([]uuid.UUID, error) {
+	approved, err := s.followRepo.ApproveRequests(ctx, userID, requesterIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, followerID := range approved {
+		s.publish(FollowApprovedEvent, followerID, userID)
+	}
+
+	return approved, nil
+}
+
+// DenyFollowRequest - Turn down requesterID. They are not told, and may ask again.
+func (s *FollowService) DenyFollowRequest(ctx context.Context, userID, requesterID uuid.UUID) error {
+	denied, err := s.followRepo.DenyRequest(ctx, userID, requesterID)
+	if err != nil {
+		return err
+	}
+	if !denied {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+// HiddenOwners - Of ownerIDs, get those whose posts and follow lists viewerID may
+// not see: private accounts the viewer doesn't follow, and anyone either side has
+// blocked. viewerID is uuid.Nil for a signed-out viewer.
+func (s *FollowService) HiddenOwners(ctx context.Context, viewerID uuid.UUID, ownerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	hidden, err := s.followRepo.GetHiddenOwners(ctx, viewerID, ownerIDs)
+	if err != nil {
+		return nil, err
+	}
+	if viewerID == uuid.Nil {
+		return hidden, nil
+	}
+
+	ids := make([]string, 0, len(ownerIDs))
+	for _, id := range ownerIDs {
+		ids = append(ids, id.String())
+	}
+	blocked, err := s.blockService.FilterBlocked(ctx, viewerID.String(), ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blocks: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(hidden))
+	for _, id := range hidden {
+		seen[id] = true
+	}
+	for _, id := range blocked {
+		ownerID, err := uuid.Parse(id)
+		if err != nil || seen[ownerID] {
+			continue
+		}
+		seen[ownerID] = true
+		hidden = append(hidden, ownerID)
+	}
+
+	return hidden, nil
+}
+
+// FollowedOwners - Of ownerIDs, get those viewerID follows, whose followers-only
+// posts they may see. A signed-out viewer follows no one.
+func (s *FollowService) FollowedOwners(ctx context.Context, viewerID uuid.UUID, ownerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if viewerID == uuid.Nil || len(ownerIDs) == 0 {
+		return []uuid.UUID{}, nil
+	}
+	return s.followRepo.GetFollowedOwners(ctx, viewerID, ownerIDs)
+}
+
+// checkCanView - Anyone may see a public account's follow lists; a private
+// account's are for its owner and approved followers
+func (s *FollowService) checkCanView(ctx context.Context, viewerID, ownerID uuid.UUID) error {
+	if viewerID == ownerID {
+		return nil
+	}
+
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return ErrFollowUserNotFound
+	}
+
+	if viewerID != uuid.Nil {
+		blocked, err := s.blockService.IsBlocked(ctx, viewerID.String(), ownerID.String())
+		if err != nil {
+			return fmt.Errorf("failed to check blocks: %w", err)
+		}
+		if blocked {
+			return ErrFollowUserNotFound
+		}
+	}
+
+	if !owner.IsPrivate {
+		return nil
+	}
+	if viewerID == uuid.Nil {
+		return ErrPrivateAccount
+	}
+
+	following, err := s.followRepo.IsFollowing(ctx, viewerID, ownerID)
+	if err != nil {
+		return err
+	}
+	if !following {
+		return ErrPrivateAccount
+	}
+	return nil
+}
+
 // GetFollowStats - Get follower/following counts
 func (s *FollowService) GetFollowStats(ctx context.Context, userID uuid.UUID) (*model.FollowStats, error) {
 	followersCount, err := s.followRepo.GetFollowersCount(ctx, userID)
@@ -122,6 +320,32 @@ func (s *FollowService) GetFollowStats(ctx context.Context, userID uuid.UUID) (*
 	}, nil
 }
 
+// TimelineAudience - Get the followers whose home timelines a user's posts go to,
+// and how many followers they have. Once that is more than max only the count is
+// returned, so other services can treat big accounts differently without paging
+// through all their followers.
+func (s *FollowService) TimelineAudience(ctx context.Context, userID uuid.UUID, max int) ([]uuid.UUID, int, error) {
+	count, err := s.followRepo.GetFollowersCount(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if max > 0 && count > max {
+		return []uuid.UUID{}, count, nil
+	}
+
+	followers, err := s.followRepo.GetAllFollowerIDs(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return followers, count, nil
+}
+
+// TimelineSources - Get everyone whose posts belong in a user's home timeline:
+// the accounts they follow, private ones only once approved
+func (s *FollowService) TimelineSources(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return s.followRepo.GetAllFollowingIDs(ctx, userID)
+}
+
 // IsFollowing - Check if user A follows user B
 func (s *FollowService) IsFollowing(ctx context.Context, followerID, followingID uuid.UUID) (bool, error) {
 	return s.followRepo.IsFollowing(ctx, followerID, followingID)
@@ -137,3 +361,16 @@ func (s *FollowService) RemoveFollower(ctx context.Context, userID, followerID u
 	// Remove the follow relationship
 	return s.followRepo.Delete(ctx, followerID, userID)
 }
+
+func (s *FollowService) publish(eventType string, followerID, followingID uuid.UUID) {
+	event := map[string]interface{}{
+		"event_type":   eventType,
+		"follower_id":  followerID.String(),
+		"following_id": followingID.String(),
+		"timestamp":    time.Now(),
+	}
+
+	if err := s.kafka.PublishEvent("user-events", event); err != nil {
+		log.Printf("Failed to publish %s for %s: %v", eventType, followerID, err)
+	}
+}
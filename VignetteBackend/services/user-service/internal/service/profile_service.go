@@ -13,14 +13,26 @@ import (
 )
 
 type ProfileService struct {
-	profileRepo *repository.ProfileRepository
-	userRepo    *repository.UserRepository
+	profileRepo   *repository.ProfileRepository
+	userRepo      *repository.UserRepository
+	blockService  *BlockService
+	followService *FollowService
+	audienceLists *AudienceListService
 }
 
-func NewProfileService(profileRepo *repository.ProfileRepository, userRepo *repository.UserRepository) *ProfileService {
+func NewProfileService(
+	profileRepo *repository.ProfileRepository,
+	userRepo *repository.UserRepository,
+	blockService *BlockService,
+	followService *FollowService,
+	audienceLists *AudienceListService,
+) *ProfileService {
 	return &ProfileService{
-		profileRepo: profileRepo,
-		userRepo:    userRepo,
+		profileRepo:   profileRepo,
+		userRepo:      userRepo,
+		blockService:  blockService,
+		followService: followService,
+		audienceLists: audienceLists,
 	}
 }
 
@@ -44,22 +56,14 @@ func (s *ProfileService) GetOrCreateProfile(ctx context.Context, userID uuid.UUI
 func (s *ProfileService) createDefaultProfile(ctx context.Context, userID uuid.UUID) (*model.Profile, error) {
 	now := time.Now()
 	profile := &model.Profile{
-		ID:        uuid.New(),
-		UserID:    userID,
-		Languages: []string{},
-		Work:      []model.WorkExperience{},
-		Education: []model.EducationEntry{},
-		Visibility: &model.ProfileVisibility{
-			Bio:              "public",
-			Work:             "public",
-			Education:        "public",
-			ContactInfo:      "friends",
-			RelationshipInfo: "friends",
-			Hometown:         "public",
-			Birthday:         "friends",
-		},
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:         uuid.New(),
+		UserID:     userID,
+		Languages:  []string{},
+		Work:       []model.WorkExperience{},
+		Education:  []model.EducationEntry{},
+		Visibility: model.DefaultProfileVisibility(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
 	if err := s.profileRepo.CreateProfile(ctx, profile); err != nil {
@@ -309,8 +313,12 @@ func (s *ProfileService) UpdateSocialLinks(ctx context.Context, userID uuid.UUID
 	return profile, nil
 }
 
-// UpdateVisibility updates profile visibility settings
+// UpdateVisibility updates who can see each profile section
 func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID, req *model.UpdateVisibilityRequest) (*model.Profile, error) {
+	if err := s.validateAudiences(ctx, userID, req); err != nil {
+		return nil, err
+	}
+
 	profile, err := s.GetOrCreateProfile(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -318,38 +326,24 @@ func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID,
 
 	// Initialize if nil
 	if profile.Visibility == nil {
-		profile.Visibility = &model.ProfileVisibility{
-			Bio:              "public",
-			Work:             "public",
-			Education:        "public",
-			ContactInfo:      "friends",
-			RelationshipInfo: "friends",
-			Hometown:         "public",
-			Birthday:         "friends",
-		}
+		profile.Visibility = model.DefaultProfileVisibility()
 	}
 
 	// Update fields
-	if req.Bio != nil {
-		profile.Visibility.Bio = *req.Bio
-	}
-	if req.Work != nil {
-		profile.Visibility.Work = *req.Work
+	if req.LinkInBio != nil {
+		profile.Visibility.LinkInBio = *req.LinkInBio
 	}
-	if req.Education != nil {
-		profile.Visibility.Education = *req.Education
+	if req.Highlights != nil {
+		profile.Visibility.Highlights = *req.Highlights
 	}
-	if req.ContactInfo != nil {
-		profile.Visibility.ContactInfo = *req.ContactInfo
+	if req.PinnedPosts != nil {
+		profile.Visibility.PinnedPosts = *req.PinnedPosts
 	}
-	if req.RelationshipInfo != nil {
-		profile.Visibility.RelationshipInfo = *req.RelationshipInfo
+	if req.ContactOptions != nil {
+		profile.Visibility.ContactOptions = *req.ContactOptions
 	}
-	if req.Hometown != nil {
-		profile.Visibility.Hometown = *req.Hometown
-	}
-	if req.Birthday != nil {
-		profile.Visibility.Birthday = *req.Birthday
+	if req.Availability != nil {
+		profile.Visibility.Availability = *req.Availability
 	}
 
 	if err := s.profileRepo.UpdateProfile(ctx, profile); err != nil {
@@ -359,8 +353,17 @@ func (s *ProfileService) UpdateVisibility(ctx context.Context, userID uuid.UUID,
 	return profile, nil
 }
 
-// GetProfileWithUser gets profile along with user information
-func (s *ProfileService) GetProfileWithUser(ctx context.Context, userID uuid.UUID) (*model.ProfileResponse, error) {
+// GetProfileWithUser gets profile along with user information, as viewerID may see it.
+// Owner-only and hidden contact fields are stripped, as are sections shown to an
+// audience the viewer isn't in and everything but the header of a private account
+// the viewer doesn't follow; users who have blocked one another
+// get ErrProfileUnavailable. A zero viewerID is a signed-out viewer.
+func (s *ProfileService) GetProfileWithUser(ctx context.Context, userID, viewerID uuid.UUID) (*model.ProfileResponse, error) {
+	relationship, err := s.resolveViewer(ctx, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
 	profile, err := s.GetOrCreateProfile(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -372,8 +375,23 @@ func (s *ProfileService) GetProfileWithUser(ctx context.Context, userID uuid.UUI
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	memberOf, err := s.listMemberships(ctx, userID, viewerID, profile.Visibility, relationship)
+	if err != nil {
+		return nil, err
+	}
+
 	response := profile.ToProfileResponse()
 	response.User = user.ToUserResponse()
+	applyProfileVisibility(response, profile.Visibility, relationship, memberOf)
 
 	return response, nil
 }
+
+// ViewProfileAs shows the owner their profile as viewerID would see it
+func (s *ProfileService) ViewProfileAs(ctx context.Context, ownerID, viewerID uuid.UUID) (*model.ProfileResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, viewerID); err != nil {
+		return nil, ErrViewerNotFound
+	}
+
+	return s.GetProfileWithUser(ctx, ownerID, viewerID)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vignette/user-service/internal/model"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrProfileUnavailable means the viewer and the owner have blocked one another.
+	// Handlers report it exactly like a profile that does not exist.
+	ErrProfileUnavailable = errors.New("profile not available")
+	ErrViewerNotFound     = errors.New("viewer not found")
+	ErrInvalidAudience    = errors.New("audience must be public, followers, only_me or one of your lists")
+)
+
+// viewerRelationship is how the person looking at a profile relates to its owner
+type viewerRelationship int
+
+const (
+	viewerStranger viewerRelationship = iota
+	viewerFollower
+	viewerOwner
+)
+
+// resolveViewer works out what viewerID is to the profile's owner
+func (s *ProfileService) resolveViewer(ctx context.Context, ownerID, viewerID uuid.UUID) (viewerRelationship, error) {
+	if viewerID == ownerID {
+		return viewerOwner, nil
+	}
+	if viewerID == uuid.Nil {
+		return viewerStranger, nil
+	}
+
+	blocked, err := s.blockService.IsBlocked(ctx, ownerID.String(), viewerID.String())
+	if err != nil {
+		return viewerStranger, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return viewerStranger, ErrProfileUnavailable
+	}
+
+	following, err := s.followService.IsFollowing(ctx, viewerID, ownerID)
+	if err != nil {
+		return viewerStranger, fmt.Errorf("failed to check follow: %w", err)
+	}
+	if following {
+		return viewerFollower, nil
+	}
+
+	return viewerStranger, nil
+}
+
+// listMemberships works out which of the audience lists used by visibility the
+// viewer is in. Only needed for viewers who are signed in and not the owner.
+func (s *ProfileService) listMemberships(ctx context.Context, ownerID, viewerID uuid.UUID, visibility *model.ProfileVisibility, relationship viewerRelationship) (map[uuid.UUID]bool, error) {
+	if visibility == nil || relationship == viewerOwner || viewerID == uuid.Nil {
+		return nil, nil
+	}
+
+	listIDs := visibility.ListIDs()
+	if len(listIDs) == 0 {
+		return nil, nil
+	}
+
+	memberOf, err := s.audienceLists.MemberOfLists(ctx, ownerID, viewerID, listIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audience lists: %w", err)
+	}
+	return memberOf, nil
+}
+
+// validateAudiences checks each audience in req is one a profile section can be
+// shown to, and that any lists belong to the owner
+func (s *ProfileService) validateAudiences(ctx context.Context, ownerID uuid.UUID, req *model.UpdateVisibilityRequest) error {
+	sections := []*string{req.LinkInBio, req.Highlights, req.PinnedPosts, req.ContactOptions, req.Availability}
+
+	var listIDs []uuid.UUID
+	for _, audience := range sections {
+		if audience == nil {
+			continue
+		}
+		switch *audience {
+		case model.AudiencePublic, model.AudienceFollowers, model.AudienceOnlyMe:
+			continue
+		}
+		listID, ok := model.AudienceListID(*audience)
+		if !ok {
+			return ErrInvalidAudience
+		}
+		listIDs = append(listIDs, listID)
+	}
+
+	if err := s.audienceLists.CheckOwnership(ctx, ownerID, listIDs); err != nil {
+		if errors.Is(err, ErrAudienceListNotFound) {
+			return ErrInvalidAudience
+		}
+		return err
+	}
+	return nil
+}
+
+// canSee reports whether a viewer may see a section shown to audience. An empty
+// audience is public; a list audience is seen by the list's members; unknown
+// audiences are treated as only_me.
+func (r viewerRelationship) canSee(audience string, memberOf map[uuid.UUID]bool) bool {
+	switch audience {
+	case "", model.AudiencePublic:
+		return true
+	case model.AudienceFollowers:
+		return r >= viewerFollower
+	}
+
+	if listID, ok := model.AudienceListID(audience); ok && memberOf[listID] {
+		return true
+	}
+	return r == viewerOwner
+}
+
+// applyProfileVisibility strips the fields of response the viewer may not see.
+// Insights, view counts and private contact details are for the owner only, each
+// section is shown only to its audience (memberOf holds the owner's lists the
+// viewer is in), and a private account shows non-followers no more than its header.
+func applyProfileVisibility(response *model.ProfileResponse, visibility *model.ProfileVisibility, relationship viewerRelationship, memberOf map[uuid.UUID]bool) {
+	if relationship == viewerOwner {
+		return
+	}
+
+	response.CreatorInsights = nil
+	response.ProfileViews = 0
+	response.Visibility = nil
+
+	if visibility == nil {
+		visibility = model.DefaultProfileVisibility()
+	}
+	if !relationship.canSee(visibility.LinkInBio, memberOf) {
+		response.LinkInBio = nil
+	}
+	if !relationship.canSee(visibility.Highlights, memberOf) {
+		response.Highlights = nil
+	}
+	if !relationship.canSee(visibility.PinnedPosts, memberOf) {
+		response.PinnedPosts = nil
+	}
+	if !relationship.canSee(visibility.ContactOptions, memberOf) {
+		response.ContactOptions = nil
+	}
+	if !relationship.canSee(visibility.Availability, memberOf) {
+		response.Availability = nil
+	}
+
+	if options := response.ContactOptions; options != nil {
+		visible := *options
+		if !options.ShowEmail {
+			visible.Email = nil
+		}
+		if !options.ShowPhone {
+			visible.PhoneNumber = nil
+		}
+		if !options.ShowAddress {
+			visible.AddressStreet = nil
+			visible.AddressCity = nil
+			visible.AddressZip = nil
+		}
+		response.ContactOptions = &visible
+	}
+
+	if response.User == nil {
+		return
+	}
+	response.User.Email = ""
+	response.User.PhoneNumber = nil
+	response.User.LastLoginAt = nil
+
+	if response.User.IsPrivate && relationship < viewerFollower {
+		response.Highlights = nil
+		response.PinnedPosts = nil
+	}
+}
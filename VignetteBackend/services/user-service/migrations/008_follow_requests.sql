@@ -0,0 +1,30 @@
+-- Follow requests: following a private account (users.is_private) needs the
+-- owner's approval. Approving a request creates the follow.
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Who wants to follow
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,    -- The private account
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+
+    -- One request per pair; asking again after a denial reopens it
+    UNIQUE(requester_id, target_id),
+    CHECK (requester_id != target_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_pending ON follow_requests(target_id, created_at DESC) WHERE status = 'pending';
+
+-- users.is_private is the flag follows are checked against; bring it in step with
+-- the privacy setting, which is kept in sync from here on
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users u
+SET is_private = COALESCE(s.is_private_account, FALSE)
+FROM user_settings s
+WHERE s.user_id = u.id AND u.is_private != COALESCE(s.is_private_account, FALSE);
+
+COMMENT ON TABLE follow_requests IS 'Requests to follow private accounts, pending until the owner approves or denies them';
+COMMENT ON COLUMN follow_requests.requester_id IS 'User asking to follow';
+COMMENT ON COLUMN follow_requests.target_id IS 'Private account being asked';
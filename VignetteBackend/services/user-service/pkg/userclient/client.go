@@ -0,0 +1,125 @@
+// Package userclient is the Go client other Vignette services use to call the
+// user service's internal gRPC API.
+package userclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	pb "vignette/user-service/proto/user"
+)
+
+// DefaultTimeout bounds each call unless the caller's context has an earlier deadline
+const DefaultTimeout = 5 * time.Second
+
+// Client is a gRPC client for the user service
+type Client struct {
+	conn   *grpc.ClientConn
+	client pb.UserServiceClient
+	token  string
+}
+
+// NewClient creates a new user service client. token is the shared internal API
+// token; it is sent with every call.
+func NewClient(address, token string) (*Client, error) {
+	conn, err := grpc.Dial(
+		address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to user service: %w", err)
+	}
+
+	return &Client{
+		conn:   conn,
+		client: pb.NewUserServiceClient(conn),
+		token:  token,
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *Client) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// GetUser returns one user
+func (c *Client) GetUser(ctx context.Context, userID string) (*pb.User, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.client.GetUser(ctx, &pb.GetUserRequest{UserId: userID})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetUser(), nil
+}
+
+// BatchGetUsers returns up to 500 users in the order asked, and the IDs with no user
+func (c *Client) BatchGetUsers(ctx context.Context, userIDs []string) ([]*pb.User, []string, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.client.BatchGetUsers(ctx, &pb.BatchGetUsersRequest{UserIds: userIDs})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.GetUsers(), resp.GetMissingIds(), nil
+}
+
+// GetFollowerCount returns how many people follow the user and how many they follow
+func (c *Client) GetFollowerCount(ctx context.Context, userID string) (followers, following int64, err error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.client.GetFollowerCount(ctx, &pb.GetFollowerCountRequest{UserId: userID})
+	if err != nil {
+		return 0, 0, err
+	}
+	return resp.GetFollowersCount(), resp.GetFollowingCount(), nil
+}
+
+// AreFriends reports whether two users follow each other
+func (c *Client) AreFriends(ctx context.Context, userID, otherUserID string) (bool, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.client.AreFriends(ctx, &pb.AreFriendsRequest{UserId: userID, OtherUserId: otherUserID})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetAreFriends(), nil
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (c *Client) IsBlocked(ctx context.Context, userID, otherUserID string) (bool, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	resp, err := c.client.IsBlocked(ctx, &pb.IsBlockedRequest{UserId: userID, OtherUserId: otherUserID})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetIsBlocked(), nil
+}
+
+// ValidateToken checks an access token. A token that isn't valid is not an error;
+// check the response's Valid field.
+func (c *Client) ValidateToken(ctx context.Context, token string) (*pb.ValidateTokenResponse, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	return c.client.ValidateToken(ctx, &pb.ValidateTokenRequest{Token: token})
+}
+
+// callContext adds the internal token and the default timeout to ctx
+func (c *Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-internal-token", c.token)
+	return context.WithTimeout(ctx, DefaultTimeout)
+}